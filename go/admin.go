@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/tutorial/httpclient"
+	"example.com/tutorial/humanize"
+)
+
+// adminTimeout bounds how long admin waits for the server.
+const adminTimeout = 10 * time.Second
+
+// adminKeys are the settings admin set changes, each with how it puts
+// its value into the PATCH /admin/settings body.
+var adminKeys = map[string]func(body map[string]any, value string) error{
+	"log-level": func(body map[string]any, value string) error {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(value)); err != nil {
+			return err
+		}
+		body["log_level"] = level
+		return nil
+	},
+	"rate": func(body map[string]any, value string) error {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		rateLimit(body)["rate"] = rate
+		return nil
+	},
+	"burst": func(body map[string]any, value string) error {
+		burst, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		rateLimit(body)["burst"] = burst
+		return nil
+	},
+	"cache-bytes": func(body map[string]any, value string) error {
+		n, err := humanize.ParseBytes(value)
+		if err != nil {
+			return err
+		}
+		body["cache_bytes"] = n
+		return nil
+	},
+	"job-workers": func(body map[string]any, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		body["job_workers"] = n
+		return nil
+	},
+}
+
+// rateLimit returns the rate_limit object of body, adding it if missing.
+func rateLimit(body map[string]any) map[string]any {
+	rl, ok := body["rate_limit"].(map[string]any)
+	if !ok {
+		rl = map[string]any{}
+		body["rate_limit"] = rl
+	}
+	return rl
+}
+
+// runAdmin implements the "admin" subcommand: it prints, as JSON, the
+// runtime settings of a running server's /admin/settings, after changing
+// those of set, all at once or, if the server rejects one, none. It
+// authenticates with the serve.admin.token setting the server has too.
+func runAdmin(ctx context.Context, cfg serveSettings, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("admin", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: admin [-server url] [show | set name=value...]")
+		fmt.Fprintln(stderr, "names: burst, cache-bytes, job-workers, log-level, rate")
+		fs.PrintDefaults()
+	}
+	server := fs.String("server", cfg.Addr, "the server at `url`, such as localhost:8080")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	method, body := http.MethodGet, map[string]any(nil)
+	switch cmd := fs.Arg(0); {
+	case fs.NArg() == 0, cmd == "show" && fs.NArg() == 1:
+	case cmd == "set" && fs.NArg() > 1:
+		method, body = http.MethodPatch, map[string]any{}
+		for _, arg := range fs.Args()[1:] {
+			name, value, _ := strings.Cut(arg, "=")
+			set, ok := adminKeys[name]
+			if !ok {
+				fs.Usage()
+				return 2
+			}
+			if err := set(body, value); err != nil {
+				fmt.Fprintf(stderr, "admin: %s: %v\n", name, err)
+				return 2
+			}
+		}
+	default:
+		fs.Usage()
+		return 2
+	}
+	if cfg.Admin.Token == "" {
+		fmt.Fprintln(stderr, "admin: no token: set serve.admin.token in the config file or TUTORIAL_SERVE_ADMIN_TOKEN")
+		return 2
+	}
+	if err := adminRequest(ctx, *server, cfg.Admin.Token, method, body, stdout); err != nil {
+		fmt.Fprintln(stderr, "admin:", err)
+		return 1
+	}
+	return 0
+}
+
+// adminRequest sends body, if not nil, to the /admin/settings of the
+// server at base with method, and writes the settings it answers to w.
+// A base without a scheme is taken to be http, and one without a host
+// to be on localhost.
+func adminRequest(ctx context.Context, base, token, method string, body map[string]any, w io.Writer) error {
+	if strings.HasPrefix(base, ":") {
+		base = "localhost" + base
+	}
+	if !strings.Contains(base, "://") {
+		base = "http://" + base
+	}
+	url := strings.TrimSuffix(base, "/") + "/admin/settings"
+	ctx, cancel := context.WithTimeout(ctx, adminTimeout)
+	defer cancel()
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := httpclient.New(nil, httpclient.Config{}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	answer, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: %s: %s", method, url, resp.Status, bytes.TrimSpace(answer))
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, answer, "", "  "); err != nil {
+		return err
+	}
+	_, err = w.Write(out.Bytes())
+	return err
+}
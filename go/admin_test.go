@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"example.com/tutorial/logging"
+)
+
+func TestRunAdmin(t *testing.T) {
+	level := new(slog.LevelVar)
+	ctx, cancel := context.WithCancel(logging.NewLevelContext(context.Background(), level))
+	cfg := defaultSettings().Serve
+	cfg.Admin.Token = "s3cret"
+	addr, done, stderr := startServeWith(t, ctx, cfg, "-rate", "100")
+	defer func() {
+		cancel()
+		if code := <-done; code != 0 {
+			t.Errorf("serve: exit %d: %s", code, stderr.String())
+		}
+	}()
+	cfg.Addr = addr
+	run := func(cfg serveSettings, args ...string) (string, int) {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		code := runAdmin(ctx, cfg, args, &stdout, &stderr)
+		return stdout.String() + stderr.String(), code
+	}
+
+	out, code := run(cfg, "set", "log-level=debug", "burst=20", "cache-bytes=1MiB", "job-workers=3")
+	var got struct {
+		LogLevel  string `json:"log_level"`
+		RateLimit struct {
+			Rate  float64 `json:"rate"`
+			Burst int     `json:"burst"`
+		} `json:"rate_limit"`
+		CacheBytes int64 `json:"cache_bytes"`
+		JobWorkers int   `json:"job_workers"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); code != 0 || err != nil {
+		t.Fatalf("set: exit %d: %s", code, out)
+	}
+	if got.LogLevel != "DEBUG" || got.RateLimit.Rate != 100 || got.RateLimit.Burst != 20 || got.CacheBytes != 1<<20 || got.JobWorkers != 3 {
+		t.Errorf("set: got %+v", got)
+	}
+	if level.Level() != slog.LevelDebug {
+		t.Errorf("log level %v, want DEBUG", level.Level())
+	}
+	if out, code := run(cfg, "show"); code != 0 || !strings.Contains(out, `"job_workers": 3`) {
+		t.Errorf("show: exit %d: %s", code, out)
+	}
+
+	var info bytes.Buffer
+	if err := fetchDiag(ctx, addr, &info); err != nil || !strings.Contains(info.String(), `"job_workers": 3`) {
+		t.Errorf("/debug/info: %v: %s", err, info.String())
+	}
+
+	// A rejected setting leaves the others as they were.
+	if out, code := run(cfg, "set", "job-workers=1", "cache-bytes=0"); code != 1 || !strings.Contains(out, "422") {
+		t.Errorf("invalid setting: exit %d: %s", code, out)
+	}
+	if out, _ := run(cfg, "show"); !strings.Contains(out, `"job_workers": 3`) {
+		t.Errorf("after a rejected set: %s", out)
+	}
+
+	cfg.Admin.Token = "wrong"
+	if out, code := run(cfg); code != 1 || !strings.Contains(out, "401") {
+		t.Errorf("wrong token: exit %d: %s", code, out)
+	}
+}
+
+func TestRunAdminUsage(t *testing.T) {
+	cfg := defaultSettings().Serve
+	cfg.Admin.Token = "s3cret"
+	for _, args := range [][]string{{"set"}, {"set", "bogus=1"}, {"set", "rate=fast"}, {"set", "log-level=loud"}, {"show", "extra"}, {"restart"}} {
+		var stdout, stderr bytes.Buffer
+		if code := runAdmin(context.Background(), cfg, args, &stdout, &stderr); code != 2 {
+			t.Errorf("%q: exit %d, want 2", args, code)
+		}
+	}
+	var stdout, stderr bytes.Buffer
+	if code := runAdmin(context.Background(), defaultSettings().Serve, []string{"show"}, &stdout, &stderr); code != 2 || !strings.Contains(stderr.String(), "no token") {
+		t.Errorf("no token: exit %d: %s", code, stderr.String())
+	}
+}
@@ -0,0 +1,157 @@
+// Package algorithms implements sorting and selection over word
+// frequencies, to show when something more specialized than sort.Slice
+// pays off. All of them order words as text.TopWords does, by count
+// descending and then alphabetically, except CountingSort, which orders
+// by count alone.
+//
+// For the top n of many words, QuickSelect finds them in O(len) on
+// average and sorts only those n; MergeSort is a stable O(n log n) sort
+// that allocates a buffer; CountingSort runs in O(len + largest count)
+// without comparing words at all. BenchmarkTopN compares them with
+// sort.Slice.
+package algorithms
+
+import "example.com/tutorial/text"
+
+// Before reports whether a ranks before b: a higher count, or the same
+// count and a word that sorts first.
+func Before(a, b text.WordFreq) bool {
+	if a.Count != b.Count {
+		return a.Count > b.Count
+	}
+	return a.Word < b.Word
+}
+
+// QuickSelect reorders freqs so that its first k elements are the k that
+// rank first, in no particular order, and the rest come after them. It
+// takes O(len(freqs)) time on average. A k outside [0, len(freqs)] is
+// clamped to it.
+func QuickSelect(freqs []text.WordFreq, k int) {
+	k = max(0, min(k, len(freqs)))
+	lo, hi := 0, len(freqs)
+	for hi-lo > 1 && k > lo && k < hi {
+		p := partition(freqs[lo:hi]) + lo
+		switch {
+		case k <= p:
+			hi = p
+		default:
+			lo = p + 1
+		}
+	}
+}
+
+// partition moves the elements of s that rank before a pivot in front
+// of it and the others after it, and returns the pivot's index. The
+// pivot is the median of the first, middle and last elements, so sorted
+// input does not make QuickSelect quadratic.
+func partition(s []text.WordFreq) int {
+	last := len(s) - 1
+	mid := last / 2
+	if Before(s[mid], s[0]) {
+		s[mid], s[0] = s[0], s[mid]
+	}
+	if Before(s[last], s[0]) {
+		s[last], s[0] = s[0], s[last]
+	}
+	if Before(s[mid], s[last]) {
+		s[mid], s[last] = s[last], s[mid]
+	}
+	// The median is now last.
+	pivot, i := s[last], 0
+	for j := 0; j < last; j++ {
+		if Before(s[j], pivot) {
+			s[i], s[j] = s[j], s[i]
+			i++
+		}
+	}
+	s[i], s[last] = s[last], s[i]
+	return i
+}
+
+// TopN returns the n words of freqs that rank first, in order, as
+// text.TopWords would: QuickSelect followed by MergeSort of the n. It
+// reorders freqs; a negative n returns them all.
+func TopN(freqs []text.WordFreq, n int) []text.WordFreq {
+	if n < 0 || n > len(freqs) {
+		n = len(freqs)
+	}
+	QuickSelect(freqs, n)
+	top := freqs[:n:n]
+	MergeSort(top)
+	return top
+}
+
+// MergeSort sorts freqs in rank order. It is stable, although with
+// distinct words no two elements are equal, and uses a buffer the size
+// of freqs.
+func MergeSort(freqs []text.WordFreq) {
+	if len(freqs) < 2 {
+		return
+	}
+	buf := make([]text.WordFreq, len(freqs))
+	mergeSort(freqs, buf)
+}
+
+// mergeSort sorts s using buf, of the same length, as scratch space.
+func mergeSort(s, buf []text.WordFreq) {
+	if len(s) <= 12 {
+		insertionSort(s)
+		return
+	}
+	mid := len(s) / 2
+	mergeSort(s[:mid], buf[:mid])
+	mergeSort(s[mid:], buf[mid:])
+	if !Before(s[mid], s[mid-1]) {
+		return // already in order
+	}
+	copy(buf, s)
+	i, j, k := 0, mid, 0
+	for i < mid && j < len(s) {
+		if Before(buf[j], buf[i]) {
+			s[k] = buf[j]
+			j++
+		} else {
+			s[k] = buf[i]
+			i++
+		}
+		k++
+	}
+	k += copy(s[k:], buf[i:mid])
+	copy(s[k:], buf[j:])
+}
+
+func insertionSort(s []text.WordFreq) {
+	for i := 1; i < len(s); i++ {
+		for j := i; j > 0 && Before(s[j], s[j-1]); j-- {
+			s[j], s[j-1] = s[j-1], s[j]
+		}
+	}
+}
+
+// CountingSort returns freqs ordered by count descending, words with the
+// same count in the order freqs gives them; given words in alphabetical
+// order, that is rank order. It takes O(len(freqs) + m) time and space,
+// where m is the largest count, and so suits the many small counts of a
+// natural vocabulary rather than a few huge ones. Counts below zero
+// count as zero.
+func CountingSort(freqs []text.WordFreq) []text.WordFreq {
+	largest := 0
+	for _, wf := range freqs {
+		largest = max(largest, wf.Count)
+	}
+	// starts[c] is where the words counted c times begin, highest first.
+	starts := make([]int, largest+2)
+	for _, wf := range freqs {
+		starts[largest-max(wf.Count, 0)+1]++
+	}
+	for c := 1; c < len(starts); c++ {
+		starts[c] += starts[c-1]
+	}
+	out := make([]text.WordFreq, len(freqs))
+	for _, wf := range freqs {
+		c := largest - max(wf.Count, 0)
+		out[starts[c]] = wf
+		starts[c]++
+	}
+	return out
+}
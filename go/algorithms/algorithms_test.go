@@ -0,0 +1,167 @@
+package algorithms
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"example.com/tutorial/text"
+)
+
+// freqsOf returns n distinct words with counts in [1, maxCount], in
+// random order.
+func freqsOf(n, maxCount int, seed int64) []text.WordFreq {
+	r := rand.New(rand.NewSource(seed))
+	freqs := make([]text.WordFreq, n)
+	for i := range freqs {
+		freqs[i] = text.WordFreq{Word: fmt.Sprintf("w%07d", i), Count: 1 + r.Intn(maxCount)}
+	}
+	r.Shuffle(n, func(i, j int) { freqs[i], freqs[j] = freqs[j], freqs[i] })
+	return freqs
+}
+
+// sortSlice is the baseline: a full sort.Slice in rank order.
+func sortSlice(freqs []text.WordFreq) {
+	sort.Slice(freqs, func(i, j int) bool { return Before(freqs[i], freqs[j]) })
+}
+
+func sorted(freqs []text.WordFreq) []text.WordFreq {
+	out := append([]text.WordFreq(nil), freqs...)
+	sortSlice(out)
+	return out
+}
+
+func TestMergeSort(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 12, 13, 100, 5000} {
+		freqs := freqsOf(n, 50, int64(n))
+		want := sorted(freqs)
+		MergeSort(freqs)
+		if len(freqs) != len(want) || n > 0 && !reflect.DeepEqual(freqs, want) {
+			t.Fatalf("MergeSort of %d words differs from sort.Slice", n)
+		}
+	}
+	// Sorted and reversed input.
+	freqs := sorted(freqsOf(1000, 10, 1))
+	want := append([]text.WordFreq(nil), freqs...)
+	for i, j := 0, len(freqs)-1; i < j; i, j = i+1, j-1 {
+		freqs[i], freqs[j] = freqs[j], freqs[i]
+	}
+	MergeSort(freqs)
+	if !reflect.DeepEqual(freqs, want) {
+		t.Fatal("MergeSort of reversed input")
+	}
+}
+
+func TestQuickSelect(t *testing.T) {
+	freqs := freqsOf(2000, 30, 1)
+	want := sorted(freqs)
+	for _, k := range []int{0, 1, 2, 10, 999, 1000, 1999, 2000} {
+		QuickSelect(freqs, k)
+		top := sorted(freqs[:k])
+		if k > 0 && !reflect.DeepEqual(top, want[:k]) {
+			t.Fatalf("QuickSelect(%d) picked the wrong words", k)
+		}
+		for _, wf := range freqs[k:] {
+			if k > 0 && Before(wf, top[k-1]) {
+				t.Fatalf("QuickSelect(%d) left %v behind", k, wf)
+			}
+		}
+	}
+	QuickSelect(freqs, -1) // clamped, no panic
+	QuickSelect(freqs, 5000)
+	QuickSelect(nil, 3)
+
+	// Sorted input and all-equal counts stay linear and correct.
+	for _, freqs := range [][]text.WordFreq{sorted(freqsOf(3000, 5, 2)), freqsOf(3000, 1, 3)} {
+		want := sorted(freqs)
+		QuickSelect(freqs, 7)
+		if !reflect.DeepEqual(sorted(freqs[:7]), want[:7]) {
+			t.Fatal("QuickSelect on sorted or constant counts")
+		}
+	}
+}
+
+func TestTopN(t *testing.T) {
+	counts := text.WordCount("b a c b a b d e e e e")
+	for _, n := range []int{-1, 0, 1, 3, 5, 9} {
+		freqs := text.TopWords(counts, -1)
+		rand.New(rand.NewSource(int64(n+2))).Shuffle(len(freqs), func(i, j int) { freqs[i], freqs[j] = freqs[j], freqs[i] })
+		got := TopN(freqs, n)
+		if want := text.TopWords(counts, n); !reflect.DeepEqual(got, want) {
+			t.Errorf("TopN(%d) = %v, want %v", n, got, want)
+		}
+	}
+	// The result is capped at n, so appending leaves freqs alone.
+	freqs := freqsOf(10, 5, 1)
+	top := TopN(freqs, 3)
+	_ = append(top, text.WordFreq{})
+	if freqs[3].Word == "" {
+		t.Fatal("append to TopN overwrote freqs")
+	}
+}
+
+func TestCountingSort(t *testing.T) {
+	// Given in word order, counting sort yields rank order.
+	freqs := sorted(freqsOf(5000, 40, 4))
+	sort.Slice(freqs, func(i, j int) bool { return freqs[i].Word < freqs[j].Word })
+	if got := CountingSort(freqs); !reflect.DeepEqual(got, sorted(freqs)) {
+		t.Fatal("CountingSort of words in order differs from rank order")
+	}
+	// Stable: equal counts keep their input order.
+	in := []text.WordFreq{{Word: "z", Count: 1}, {Word: "y", Count: 2}, {Word: "x", Count: 1}, {Word: "w", Count: 0}, {Word: "v", Count: -3}}
+	want := []text.WordFreq{{Word: "y", Count: 2}, {Word: "z", Count: 1}, {Word: "x", Count: 1}, {Word: "w", Count: 0}, {Word: "v", Count: -3}}
+	if got := CountingSort(in); !reflect.DeepEqual(got, want) {
+		t.Fatalf("CountingSort = %v", got)
+	}
+	if got := CountingSort(nil); len(got) != 0 {
+		t.Fatalf("CountingSort(nil) = %v", got)
+	}
+}
+
+// BenchmarkTopN finds the top ten words with each algorithm and with a
+// full sort.Slice, on vocabularies of natural-looking counts:
+//
+//	go test -run '^$' -bench . ./algorithms
+//
+// QuickSelect, and the priority queue of text.TopWords, beat the full
+// sorts by a widening margin as the vocabulary grows; CountingSort beats
+// sort.Slice when counts are small, but still orders every word.
+func BenchmarkTopN(b *testing.B) {
+	for _, size := range []int{1 << 10, 1 << 14, 1 << 18} {
+		input := freqsOf(size, 1000, 1)
+		byWord := sorted(input)
+		sort.Slice(byWord, func(i, j int) bool { return byWord[i].Word < byWord[j].Word })
+		counts := make(map[string]int, size)
+		for _, wf := range input {
+			counts[wf.Word] = wf.Count
+		}
+		for _, impl := range []struct {
+			name string
+			top  func(freqs []text.WordFreq) []text.WordFreq
+		}{
+			{"sort.Slice", func(freqs []text.WordFreq) []text.WordFreq { sortSlice(freqs); return freqs[:10] }},
+			{"mergesort", func(freqs []text.WordFreq) []text.WordFreq { MergeSort(freqs); return freqs[:10] }},
+			{"quickselect", func(freqs []text.WordFreq) []text.WordFreq { return TopN(freqs, 10) }},
+			{"countingsort", func(freqs []text.WordFreq) []text.WordFreq { return CountingSort(freqs)[:10] }},
+		} {
+			src := input
+			if impl.name == "countingsort" {
+				src = byWord
+			}
+			work := make([]text.WordFreq, size)
+			b.Run(fmt.Sprintf("%d/%s", size, impl.name), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					copy(work, src)
+					impl.top(work)
+				}
+			})
+		}
+		b.Run(fmt.Sprintf("%d/text.TopWords", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				text.TopWords(counts, 10)
+			}
+		})
+	}
+}
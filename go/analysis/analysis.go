@@ -0,0 +1,115 @@
+// Package analysis holds the analyzers that split text into the words
+// to count, each registered under a name so that a command can select
+// one with a flag or setting. An analyzer is a Tokenizer followed by
+// filters. Packages add their own from an init function, as database/sql
+// drivers do, and are linked in with a blank import:
+//
+//	import _ "example.com/tutorial/analysis/german"
+//
+// This package registers "english", "french", "german", "spanish" and
+// "whitespace", and "auto", which detects the language of each document
+// with Detect and analyzes it as the analyzer of that language does.
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"example.com/tutorial/errs"
+	"example.com/tutorial/text"
+)
+
+// Tokenizer splits text into tokens. text.Tokenizer is one.
+type Tokenizer interface {
+	Tokens(s string) []string
+}
+
+// TokenizerFunc adapts an ordinary function, such as strings.Fields, to
+// the Tokenizer interface.
+type TokenizerFunc func(s string) []string
+
+// Tokens calls f(s).
+func (f TokenizerFunc) Tokens(s string) []string { return f(s) }
+
+// Analyzer turns text into the words to count. It is text.Analyzer, so
+// any Analyzer can be passed to text.WithAnalyzer.
+type Analyzer = text.Analyzer
+
+// New returns an Analyzer that splits text with tok and runs each token
+// through filters in order, dropping those a filter maps to "".
+func New(tok Tokenizer, filters ...text.Normalizer) Analyzer {
+	filter := text.Chain(filters...)
+	return text.AnalyzerFunc(func(s string) []string {
+		toks := tok.Tokens(s)
+		if len(filters) == 0 {
+			return toks
+		}
+		words := toks[:0]
+		for _, t := range toks {
+			if w := filter.Normalize(t); w != "" {
+				words = append(words, w)
+			}
+		}
+		return words
+	})
+}
+
+// StopFilter is a filter for New that drops the words in sw.
+func StopFilter(sw text.Stopwords) text.Normalizer {
+	return text.NormalizerFunc(func(tok string) string {
+		if sw.Contains(tok) {
+			return ""
+		}
+		return tok
+	})
+}
+
+// ErrUnknown is returned by Lookup for a name nothing registered.
+var ErrUnknown = errs.New(errs.ErrNotFound, "analysis: unknown analyzer")
+
+var (
+	mu        sync.RWMutex
+	analyzers = map[string]Analyzer{}
+)
+
+// Register makes a available under name. It is meant to be called from
+// an init function, and panics if name is empty or taken or a is nil.
+func Register(name string, a Analyzer) {
+	mu.Lock()
+	defer mu.Unlock()
+	switch {
+	case name == "":
+		panic("analysis: Register with an empty name")
+	case a == nil:
+		panic("analysis: Register of a nil analyzer " + name)
+	case analyzers[name] != nil:
+		panic("analysis: Register called twice for " + name)
+	}
+	analyzers[name] = a
+}
+
+// Lookup returns the analyzer registered under name, or an error naming
+// the registered ones that wraps ErrUnknown.
+func Lookup(name string) (Analyzer, error) {
+	mu.RLock()
+	a := analyzers[name]
+	mu.RUnlock()
+	if a == nil {
+		return nil, fmt.Errorf("%w %q (have %s)", ErrUnknown, name, strings.Join(Names(), ", "))
+	}
+	return a, nil
+}
+
+// Names returns the names of the registered analyzers, sorted.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(analyzers))
+	for name := range analyzers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,84 @@
+package analysis
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"example.com/tutorial/errs"
+	"example.com/tutorial/text"
+)
+
+func TestNew(t *testing.T) {
+	a := New(TokenizerFunc(strings.Fields), text.Lowercase, StopFilter(text.NewStopwords("the")))
+	if got := a.Analyze("The Cat saw THE dog"); !reflect.DeepEqual(got, []string{"cat", "saw", "dog"}) {
+		t.Fatalf("Analyze = %q", got)
+	}
+	if got := New(text.Tokenizer{}).Analyze("Hello, world"); !reflect.DeepEqual(got, []string{"hello", "world"}) {
+		t.Fatalf("Analyze without filters = %q", got)
+	}
+	if got := a.Analyze("the THE"); len(got) != 0 {
+		t.Fatalf("Analyze of stopwords = %q", got)
+	}
+}
+
+func TestReferenceAnalyzers(t *testing.T) {
+	for _, tc := range []struct {
+		name, in string
+		want     []string
+	}{
+		{"english", "The runners were running to the café", []string{"runner", "run", "café"}},
+		{"french", "Le chat et les chiens", []string{"chat", "chiens"}},
+		{"german", "Der Hund und die Katze", []string{"hund", "katze"}},
+		{"spanish", "El perro y los gatos", []string{"perro", "gatos"}},
+		{"whitespace", "Go,  go-kit\tC++\n", []string{"Go,", "go-kit", "C++"}},
+	} {
+		a, err := Lookup(tc.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := a.Analyze(tc.in); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: Analyze = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	upper := text.AnalyzerFunc(func(s string) []string { return strings.Fields(strings.ToUpper(s)) })
+	Register("test-upper", upper)
+	defer func() {
+		mu.Lock()
+		delete(analyzers, "test-upper")
+		mu.Unlock()
+	}()
+	if got := Names(); !reflect.DeepEqual(got, []string{"auto", "english", "french", "german", "spanish", "test-upper", "whitespace"}) {
+		t.Fatalf("Names = %q", got)
+	}
+	a, err := Lookup("test-upper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := text.WordCount("go Go", text.WithAnalyzer(a)); !reflect.DeepEqual(got, map[string]int{"GO": 2}) {
+		t.Fatalf("WordCount with the analyzer = %v", got)
+	}
+
+	_, err = Lookup("klingon")
+	if !errors.Is(err, ErrUnknown) || !errors.Is(err, errs.ErrNotFound) {
+		t.Fatalf("Lookup(klingon) = %v", err)
+	}
+	if want := `analysis: unknown analyzer "klingon" (have auto, english, french, german, spanish, test-upper, whitespace)`; err.Error() != want {
+		t.Fatalf("error = %q, want %q", err, want)
+	}
+
+	for name, a := range map[string]Analyzer{"": upper, "nil": nil, "english": upper} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Register(%q, %v) did not panic", name, a)
+				}
+			}()
+			Register(name, a)
+		}()
+	}
+}
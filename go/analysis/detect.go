@@ -0,0 +1,206 @@
+package analysis
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"example.com/tutorial/text"
+)
+
+// Language detection compares the n-grams of a text with those of a
+// sample of each registered language, as Cavnar and Trenkle do: the
+// letter sequences of one to three runes inside words, padded with a
+// space on each side, ranked by their frequency. The language whose
+// ranking is nearest wins.
+const (
+	// profileSize is the number of n-grams a Profile ranks.
+	profileSize = 300
+	// maxGram is the longest n-gram, in runes.
+	maxGram = 3
+	// detectSample is how much of a text Detect reads: the start of a
+	// document tells its language as well as all of it.
+	detectSample = 8 << 10
+	// minLetters is the fewest letters Detect guesses a language from.
+	minLetters = 20
+)
+
+// Profile ranks the most frequent n-grams of a text, for Detect to
+// compare with those of each language.
+type Profile struct {
+	ranks map[string]int
+}
+
+// NewProfile returns the profile of sample. A language's sample should
+// be a few paragraphs of ordinary prose.
+func NewProfile(sample string) Profile {
+	counts := map[string]int{}
+	eachGram(sample, func(g string) { counts[g]++ })
+	grams := make([]string, 0, len(counts))
+	for g := range counts {
+		grams = append(grams, g)
+	}
+	sort.Slice(grams, func(i, j int) bool {
+		if ci, cj := counts[grams[i]], counts[grams[j]]; ci != cj {
+			return ci > cj
+		}
+		return grams[i] < grams[j]
+	})
+	if len(grams) > profileSize {
+		grams = grams[:profileSize]
+	}
+	p := Profile{ranks: make(map[string]int, len(grams))}
+	for i, g := range grams {
+		p.ranks[g] = i
+	}
+	return p
+}
+
+// eachGram calls fn with every n-gram of the words of s.
+func eachGram(s string, fn func(gram string)) {
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		runes := []rune(" " + w + " ")
+		for n := 1; n <= maxGram; n++ {
+			for i := 0; i+n <= len(runes); i++ {
+				if g := string(runes[i : i+n]); g != " " {
+					fn(g)
+				}
+			}
+		}
+	}
+}
+
+// distance is the out-of-place measure of p from lang: how far each
+// n-gram of p is from its rank in lang, or profileSize if lang lacks it.
+func (p Profile) distance(lang Profile) int {
+	d := 0
+	for g, r := range p.ranks {
+		if q, ok := lang.ranks[g]; ok {
+			d += abs(r - q)
+		} else {
+			d += profileSize
+		}
+	}
+	return d
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+var languages = map[string]Profile{}
+
+// RegisterLanguage makes the language called name, whose text looks
+// like sample, one Detect can guess. Name it as the analyzer for its
+// text, so that Auto selects that analyzer. Like Register, it is meant
+// to be called from an init function, and panics if name is empty or
+// taken.
+func RegisterLanguage(name, sample string) {
+	p := NewProfile(sample)
+	mu.Lock()
+	defer mu.Unlock()
+	switch {
+	case name == "":
+		panic("analysis: RegisterLanguage with an empty name")
+	case languages[name].ranks != nil:
+		panic("analysis: RegisterLanguage called twice for " + name)
+	}
+	languages[name] = p
+}
+
+// Languages returns the names of the languages Detect can guess, sorted.
+func Languages() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return sortedLanguages()
+}
+
+// Detection is the language Detect guessed for a text.
+type Detection struct {
+	// Language is the name the language was registered under, or ""
+	// if the text is too short to tell.
+	Language string `json:"language"`
+	// Confidence runs from 0, for a text as near another language, to
+	// 1, for one nearer no other language at all.
+	Confidence float64 `json:"confidence"`
+}
+
+// Detect guesses the language of s from its first 8 KiB, among those
+// registered with RegisterLanguage. This package registers "english",
+// "french", "german" and "spanish".
+func Detect(s string) Detection {
+	if len(s) > detectSample {
+		s = s[:detectSample]
+	}
+	letters := 0
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	if letters < minLetters {
+		return Detection{}
+	}
+	p := NewProfile(s)
+	mu.RLock()
+	defer mu.RUnlock()
+	var best Detection
+	first, second := math.MaxInt, math.MaxInt
+	for _, name := range sortedLanguages() { // so that ties go to the first
+		switch d := p.distance(languages[name]); {
+		case d < first:
+			best.Language, first, second = name, d, first
+		case d < second:
+			second = d
+		}
+	}
+	switch {
+	case best.Language == "":
+	case second == math.MaxInt:
+		best.Confidence = 1
+	case second > 0:
+		best.Confidence = float64(second-first) / float64(second)
+	}
+	return best
+}
+
+// sortedLanguages is Languages for a caller holding mu.
+func sortedLanguages() []string {
+	names := make([]string, 0, len(languages))
+	for name := range languages {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Auto detects the language of its text with Detect and analyzes it
+// with the analyzer registered under that language's name, so that
+// each document has its language's stopwords dropped; text it cannot
+// tell the language of, or of a language with no analyzer, is split
+// with text.Tokenizer alone. It is a text.Selector, so that the
+// functions of package text that read a stream detect the language of
+// each stream from its start rather than of each line.
+var Auto Analyzer = auto{}
+
+var plain = New(text.Tokenizer{})
+
+type auto struct{}
+
+func (a auto) Analyze(s string) []string { return a.Select(s).Analyze(s) }
+
+func (auto) Select(sample string) text.Analyzer {
+	if an, err := Lookup(Detect(sample).Language); err == nil {
+		return an
+	}
+	return plain
+}
+
+func init() {
+	Register("auto", Auto)
+}
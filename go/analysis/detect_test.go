@@ -0,0 +1,81 @@
+package analysis
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"example.com/tutorial/text"
+)
+
+func TestDetect(t *testing.T) {
+	for _, tc := range []struct{ lang, in string }{
+		{"english", "The quick brown fox jumps over the lazy dog while the farmer watches from his house."},
+		{"french", "Le renard brun saute par-dessus le chien paresseux pendant que le fermier regarde depuis sa maison."},
+		{"german", "Der schnelle braune Fuchs springt über den faulen Hund, während der Bauer aus seinem Haus zusieht."},
+		{"spanish", "El rápido zorro marrón salta sobre el perro perezoso mientras el granjero mira desde su casa."},
+	} {
+		d := Detect(tc.in)
+		if d.Language != tc.lang || d.Confidence <= 0 || d.Confidence > 1 {
+			t.Errorf("Detect(%q) = %+v, want %s", tc.in, d, tc.lang)
+		}
+	}
+	for _, in := range []string{"", "hello world", "1234 5678 !!! ???"} {
+		if d := Detect(in); d != (Detection{}) {
+			t.Errorf("Detect(%q) = %+v, want nothing", in, d)
+		}
+	}
+	if got := Languages(); !reflect.DeepEqual(got, []string{"english", "french", "german", "spanish"}) {
+		t.Fatalf("Languages = %q", got)
+	}
+}
+
+func TestAuto(t *testing.T) {
+	en := "The runners were running to the café, and the dogs were running after them."
+	fr := "Les enfants jouent dans le jardin avec le chien de la voisine et les chats."
+	if got := Auto.Analyze(en); got[0] != "runner" || got[1] != "run" {
+		t.Errorf("Analyze(english) = %q", got)
+	}
+	if got, want := Auto.Analyze(fr), French.Analyze(fr); !reflect.DeepEqual(got, want) {
+		t.Errorf("Analyze(french) = %q, want %q", got, want)
+	}
+	if got := Auto.Analyze("The Cats"); !reflect.DeepEqual(got, []string{"the", "cats"}) {
+		t.Errorf("Analyze of too little text = %q", got)
+	}
+
+	// A stream is analyzed in the language of its start, however short
+	// its lines.
+	got, err := text.WordCountReader(strings.NewReader(strings.ReplaceAll(fr, " ", "\n")), text.WithAnalyzer(Auto))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["les"] != 0 || got["enfants"] != 1 {
+		t.Errorf("WordCountReader = %v", got)
+	}
+}
+
+func TestRegisterLanguage(t *testing.T) {
+	RegisterLanguage("test-gibberish", strings.Repeat("zxq qxz zzxq xqqz ", 20))
+	defer func() {
+		mu.Lock()
+		delete(languages, "test-gibberish")
+		mu.Unlock()
+	}()
+	if d := Detect("zxqz qxzq zqx xzq zzq qqx zxqz"); d.Language != "test-gibberish" {
+		t.Fatalf("Detect = %+v", d)
+	}
+	// With no analyzer of its name, its text is split by the Tokenizer.
+	if got := Auto.Analyze("Zxqz qxzq zqx xzq zzq qqx zxqz"); got[0] != "zxqz" {
+		t.Fatalf("Analyze = %q", got)
+	}
+	for _, name := range []string{"", "english"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterLanguage(%q) did not panic", name)
+				}
+			}()
+			RegisterLanguage(name, "sample")
+		}()
+	}
+}
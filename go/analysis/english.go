@@ -0,0 +1,20 @@
+package analysis
+
+import (
+	_ "embed"
+
+	"example.com/tutorial/text"
+)
+
+//go:embed profiles/english.txt
+var englishSample string
+
+// English splits text with text.Tokenizer, drops text.EnglishStopwords
+// and stems what is left with text.PorterStemmer, so that "The runners
+// were running" gives "runner" and "run".
+var English = New(text.Tokenizer{}, StopFilter(text.EnglishStopwords), text.PorterStemmer)
+
+func init() {
+	Register("english", English)
+	RegisterLanguage("english", englishSample)
+}
@@ -0,0 +1,30 @@
+package analysis_test
+
+import (
+	"fmt"
+	"strings"
+
+	"example.com/tutorial/analysis"
+	"example.com/tutorial/text"
+)
+
+func ExampleLookup() {
+	a, err := analysis.Lookup("english")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(text.WordCount("The runners were running; a runner runs.", text.WithAnalyzer(a)))
+	// Output: map[run:2 runner:2]
+}
+
+func ExampleNew() {
+	// Split at commas, for lists of tags. A package providing it to
+	// commands would register it from init:
+	//
+	//	func init() { analysis.Register("tags", tags) }
+	tags := analysis.New(
+		analysis.TokenizerFunc(func(s string) []string { return strings.Split(s, ",") }),
+		text.NormalizerFunc(strings.TrimSpace))
+	fmt.Printf("%q\n", tags.Analyze("go, web servers,,  cli "))
+	// Output: ["go" "web servers" "cli"]
+}
@@ -0,0 +1,18 @@
+package analysis
+
+import (
+	_ "embed"
+
+	"example.com/tutorial/text"
+)
+
+//go:embed profiles/french.txt
+var frenchSample string
+
+// French splits text with text.Tokenizer and drops text.FrenchStopwords.
+var French = New(text.Tokenizer{}, StopFilter(text.FrenchStopwords))
+
+func init() {
+	Register("french", French)
+	RegisterLanguage("french", frenchSample)
+}
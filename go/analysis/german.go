@@ -0,0 +1,18 @@
+package analysis
+
+import (
+	_ "embed"
+
+	"example.com/tutorial/text"
+)
+
+//go:embed profiles/german.txt
+var germanSample string
+
+// German splits text with text.Tokenizer and drops text.GermanStopwords.
+var German = New(text.Tokenizer{}, StopFilter(text.GermanStopwords))
+
+func init() {
+	Register("german", German)
+	RegisterLanguage("german", germanSample)
+}
@@ -0,0 +1,18 @@
+package analysis
+
+import (
+	_ "embed"
+
+	"example.com/tutorial/text"
+)
+
+//go:embed profiles/spanish.txt
+var spanishSample string
+
+// Spanish splits text with text.Tokenizer and drops text.SpanishStopwords.
+var Spanish = New(text.Tokenizer{}, StopFilter(text.SpanishStopwords))
+
+func init() {
+	Register("spanish", Spanish)
+	RegisterLanguage("spanish", spanishSample)
+}
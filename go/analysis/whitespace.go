@@ -0,0 +1,16 @@
+package analysis
+
+import (
+	"strings"
+
+	"example.com/tutorial/text"
+)
+
+// Whitespace splits text at white space and nothing else, keeping case
+// and punctuation, for input whose words are already separated, such as
+// lists of tags or identifiers.
+var Whitespace Analyzer = text.AnalyzerFunc(strings.Fields)
+
+func init() {
+	Register("whitespace", Whitespace)
+}
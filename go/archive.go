@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+
+	"example.com/tutorial/archive"
+	"example.com/tutorial/fileutil"
+)
+
+// runArchive implements the "archive" subcommand, with its "create" and
+// "extract" actions, and returns the process exit code. A dry run prints
+// the files it would write.
+func runArchive(cfg archiveSettings, dryRun bool, args []string, stdout, stderr io.Writer) int {
+	usage := func() {
+		fmt.Fprintln(stderr, "usage: archive create [-include glob] [-exclude glob] file.tar.gz|file.zip dir")
+		fmt.Fprintln(stderr, "       archive extract [-max-file-size size] [-max-size size] [-max-files n] file.tar.gz|file.zip [dir]")
+	}
+	if len(args) == 0 {
+		usage()
+		return 2
+	}
+	switch args[0] {
+	case "create":
+		return runArchiveCreate(cfg, newExecutor(dryRun, stdout), args[1:], stderr, usage)
+	case "extract":
+		return runArchiveExtract(cfg, newExecutor(dryRun, stdout), args[1:], stderr, usage)
+	}
+	usage()
+	return 2
+}
+
+func runArchiveCreate(cfg archiveSettings, files *fileutil.Executor, args []string, stderr io.Writer, usage func()) int {
+	fs := flag.NewFlagSet("archive create", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		usage()
+		fs.PrintDefaults()
+	}
+	opts := archive.CreateOptions{Include: slices.Clone(cfg.Include), Exclude: slices.Clone(cfg.Exclude), Files: files}
+	fs.Var((*patternList)(&opts.Include), "include", "pack only files matching `glob` (repeatable)")
+	fs.Var((*patternList)(&opts.Exclude), "exclude", "skip files and directories matching `glob` (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+	if err := archive.CreateFile(fs.Arg(0), fs.Arg(1), opts); err != nil {
+		fmt.Fprintln(stderr, "archive:", err)
+		return 1
+	}
+	return 0
+}
+
+func runArchiveExtract(cfg archiveSettings, files *fileutil.Executor, args []string, stderr io.Writer, usage func()) int {
+	fs := flag.NewFlagSet("archive extract", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		usage()
+		fs.PrintDefaults()
+	}
+	opts := archive.ExtractOptions{Files: files}
+	maxFile, maxTotal := cfg.MaxFileSize, cfg.MaxSize
+	fs.TextVar(&maxFile, "max-file-size", cfg.MaxFileSize, "refuse files larger than `size`, such as 100MiB")
+	fs.TextVar(&maxTotal, "max-size", cfg.MaxSize, "refuse to extract more than `size` in all")
+	fs.IntVar(&opts.MaxFiles, "max-files", cfg.MaxFiles, "refuse archives of more than `n` entries")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	opts.MaxFileBytes, opts.MaxTotalBytes = int64(maxFile), int64(maxTotal)
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return 2
+	}
+	dest := "."
+	if fs.NArg() == 2 {
+		dest = fs.Arg(1)
+	}
+	if err := archive.ExtractFile(fs.Arg(0), dest, opts); err != nil {
+		fmt.Fprintln(stderr, "archive:", err)
+		return 1
+	}
+	return 0
+}
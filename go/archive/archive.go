@@ -0,0 +1,411 @@
+// Package archive packs directory trees into tar.gz and zip archives
+// and unpacks them again, refusing entries that would land outside the
+// destination or exceed size limits.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"example.com/tutorial/fileutil"
+	"example.com/tutorial/fsutil"
+)
+
+// Format is an archive format.
+type Format int
+
+const (
+	TarGz Format = iota + 1
+	Zip
+)
+
+func (f Format) String() string {
+	switch f {
+	case TarGz:
+		return "tar.gz"
+	case Zip:
+		return "zip"
+	}
+	return "unknown"
+}
+
+// Errors returned by this package.
+var (
+	ErrUnknownFormat = errors.New("archive: unknown format")
+	ErrUnsafePath    = errors.New("archive: entry path outside the destination")
+	ErrUnsupported   = errors.New("archive: unsupported entry type")
+	ErrTooLarge      = errors.New("archive: size limit exceeded")
+)
+
+// FormatOf returns the format named by a file name's extension: .tar.gz
+// or .tgz for TarGz, .zip for Zip.
+func FormatOf(name string) (Format, error) {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return TarGz, nil
+	case strings.HasSuffix(lower, ".zip"):
+		return Zip, nil
+	}
+	return 0, fmt.Errorf("%w: %s", ErrUnknownFormat, name)
+}
+
+// CreateOptions selects what Create packs.
+type CreateOptions struct {
+	// Include, if not empty, packs only the files matching one of its
+	// patterns; directories are packed either way. Exclude skips the
+	// files and directories matching one of its patterns. Patterns are
+	// matched as by fsutil.MatchAny.
+	Include, Exclude []string
+	// Files writes the archive file for CreateFile; nil means writing it
+	// directly. A dry run still reads the tree.
+	Files *fileutil.Executor
+}
+
+// entry is a file or directory to pack.
+type entry struct {
+	name string // slash-separated, relative to the root
+	path string
+	info fs.FileInfo
+}
+
+// Create writes an archive of the tree at root to w, with entry names
+// relative to root, in path order. Symbolic links and other special
+// files are skipped.
+func Create(w io.Writer, format Format, root string, opts CreateOptions) error {
+	if err := fsutil.CheckPatterns(opts.Include, opts.Exclude); err != nil {
+		return err
+	}
+	var entries []entry
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if fsutil.MatchAny(opts.Exclude, rel) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && (!d.Type().IsRegular() || len(opts.Include) > 0 && !fsutil.MatchAny(opts.Include, rel)) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{name: rel, path: path, info: info})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	switch format {
+	case TarGz:
+		return createTarGz(w, entries)
+	case Zip:
+		return createZip(w, entries)
+	}
+	return ErrUnknownFormat
+}
+
+func createTarGz(w io.Writer, entries []entry) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr, err := tar.FileInfoHeader(e.info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = e.name
+		if e.info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !e.info.IsDir() {
+			if err := copyFile(tw, e.path); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func createZip(w io.Writer, entries []entry) error {
+	zw := zip.NewWriter(w)
+	for _, e := range entries {
+		hdr, err := zip.FileInfoHeader(e.info)
+		if err != nil {
+			return err
+		}
+		hdr.Name = e.name
+		if e.info.IsDir() {
+			hdr.Name += "/"
+		} else {
+			hdr.Method = zip.Deflate
+		}
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if !e.info.IsDir() {
+			if err := copyFile(fw, e.path); err != nil {
+				return err
+			}
+		}
+	}
+	return zw.Close()
+}
+
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// CreateFile writes an archive of the tree at root to the file at path,
+// in the format its name calls for. The file is removed if Create fails.
+func CreateFile(path, root string, opts CreateOptions) error {
+	format, err := FormatOf(path)
+	if err != nil {
+		return err
+	}
+	f, err := opts.Files.Create(path)
+	if err != nil {
+		return err
+	}
+	err = Create(f, format, root, opts)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		opts.Files.Remove(path)
+	}
+	return err
+}
+
+// Defaults for the zero fields of ExtractOptions.
+const (
+	DefaultMaxFileBytes  = 1 << 30
+	DefaultMaxTotalBytes = 4 << 30
+	DefaultMaxFiles      = 100_000
+)
+
+// ExtractOptions limits what Extract writes, guarding against archives
+// that expand far beyond their own size.
+type ExtractOptions struct {
+	// MaxFileBytes bounds each extracted file; zero means
+	// DefaultMaxFileBytes.
+	MaxFileBytes int64
+	// MaxTotalBytes bounds all extracted files together; zero means
+	// DefaultMaxTotalBytes.
+	MaxTotalBytes int64
+	// MaxFiles bounds the number of entries; zero means
+	// DefaultMaxFiles.
+	MaxFiles int
+	// Files makes the directories and files; nil means making them
+	// directly. A dry run still reads and checks every entry.
+	Files *fileutil.Executor
+}
+
+// extractor writes entries below dest within the limits.
+type extractor struct {
+	dest    string
+	opts    ExtractOptions
+	entries int
+	total   int64
+}
+
+func newExtractor(dest string, opts ExtractOptions) *extractor {
+	if opts.MaxFileBytes <= 0 {
+		opts.MaxFileBytes = DefaultMaxFileBytes
+	}
+	if opts.MaxTotalBytes <= 0 {
+		opts.MaxTotalBytes = DefaultMaxTotalBytes
+	}
+	if opts.MaxFiles <= 0 {
+		opts.MaxFiles = DefaultMaxFiles
+	}
+	return &extractor{dest: dest, opts: opts}
+}
+
+// target returns where the entry named name goes, or ErrUnsafePath if
+// that is not below dest.
+func (x *extractor) target(name string) (string, error) {
+	rel := filepath.FromSlash(strings.TrimSuffix(name, "/"))
+	if strings.Contains(name, `\`) || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("%w: %q", ErrUnsafePath, name)
+	}
+	x.entries++
+	if x.entries > x.opts.MaxFiles {
+		return "", fmt.Errorf("%w: more than %d entries", ErrTooLarge, x.opts.MaxFiles)
+	}
+	return filepath.Join(x.dest, rel), nil
+}
+
+func (x *extractor) dir(name string) error {
+	path, err := x.target(name)
+	if err != nil {
+		return err
+	}
+	return x.opts.Files.MkdirAll(path, 0o755)
+}
+
+// file writes r to the entry named name, counting the bytes actually
+// read rather than trusting the size the archive claims.
+func (x *extractor) file(name string, mode fs.FileMode, r io.Reader) error {
+	path, err := x.target(name)
+	if err != nil {
+		return err
+	}
+	if err := x.opts.Files.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	lr := &limitReader{r: r, n: min(x.opts.MaxFileBytes, x.opts.MaxTotalBytes-x.total), name: name}
+	n, err := x.opts.Files.WriteFile(path, lr, mode.Perm()|0o200)
+	x.total += n
+	return err
+}
+
+// limitReader reads r until more than n bytes have been read, then fails
+// with ErrTooLarge.
+type limitReader struct {
+	r    io.Reader
+	n    int64
+	name string
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, fmt.Errorf("%w: %s", ErrTooLarge, l.name)
+	}
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, fmt.Errorf("%w: %s", ErrTooLarge, l.name)
+	}
+	return n, err
+}
+
+// ExtractTarGz unpacks a tar.gz archive read from r into the directory
+// dest, creating it if needed. Only directories and regular files are
+// supported; links and other entries fail with ErrUnsupported. Entries
+// whose names would escape dest fail with ErrUnsafePath, and exceeding
+// a limit in opts with ErrTooLarge. What was extracted before an error
+// is left in place.
+func ExtractTarGz(r io.Reader, dest string, opts ExtractOptions) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	x := newExtractor(dest, opts)
+	if err := opts.Files.MkdirAll(dest, 0o755); err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = x.dir(hdr.Name)
+		case tar.TypeReg:
+			err = x.file(hdr.Name, hdr.FileInfo().Mode(), tr)
+		default:
+			err = fmt.Errorf("%w: %q", ErrUnsupported, hdr.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ExtractZip unpacks the zip archive of the given size read from r into
+// dest, with the same checks as ExtractTarGz.
+func ExtractZip(r io.ReaderAt, size int64, dest string, opts ExtractOptions) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+	x := newExtractor(dest, opts)
+	if err := opts.Files.MkdirAll(dest, 0o755); err != nil {
+		return err
+	}
+	for _, zf := range zr.File {
+		mode := zf.Mode()
+		switch {
+		case mode.IsDir():
+			err = x.dir(zf.Name)
+		case mode.IsRegular():
+			err = extractZipFile(x, zf)
+		default:
+			err = fmt.Errorf("%w: %q", ErrUnsupported, zf.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractZipFile(x *extractor, zf *zip.File) error {
+	rc, err := zf.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return x.file(zf.Name, zf.Mode(), rc)
+}
+
+// ExtractFile unpacks the archive at path into dest, in the format its
+// name calls for.
+func ExtractFile(path, dest string, opts ExtractOptions) error {
+	format, err := FormatOf(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if format == TarGz {
+		return ExtractTarGz(f, dest, opts)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return ExtractZip(f, info.Size(), dest, opts)
+}
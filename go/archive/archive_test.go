@@ -0,0 +1,227 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"example.com/tutorial/fileutil"
+)
+
+func makeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// contents returns the files below root and their contents.
+func contents(t *testing.T, root string) map[string]string {
+	t.Helper()
+	m := map[string]string{}
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Type().IsRegular() {
+			data, _ := os.ReadFile(path)
+			rel, _ := filepath.Rel(root, path)
+			m[filepath.ToSlash(rel)] = string(data)
+		}
+		return nil
+	})
+	return m
+}
+
+func TestRoundTrip(t *testing.T) {
+	src := makeTree(t, map[string]string{
+		"main.go": "package main", "lib/util.go": "package lib", "lib/notes.txt": "notes",
+		"vendor/x.go": "package x", "README": "read me",
+	})
+	os.Mkdir(filepath.Join(src, "empty"), 0o755)
+	opts := CreateOptions{Include: []string{"*.go"}, Exclude: []string{"vendor"}}
+	for _, name := range []string{"out.tar.gz", "out.zip"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := CreateFile(path, src, opts); err != nil {
+				t.Fatal(err)
+			}
+			dest := filepath.Join(t.TempDir(), "dest")
+			if err := ExtractFile(path, dest, ExtractOptions{}); err != nil {
+				t.Fatal(err)
+			}
+			want := map[string]string{"main.go": "package main", "lib/util.go": "package lib"}
+			if got := contents(t, dest); !reflect.DeepEqual(got, want) {
+				t.Fatalf("extracted %v", got)
+			}
+			if info, err := os.Stat(filepath.Join(dest, "empty")); err != nil || !info.IsDir() {
+				t.Fatalf("empty directory not restored: %v", err)
+			}
+		})
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	for name, want := range map[string]Format{"a.tar.gz": TarGz, "A.TGZ": TarGz, "b.zip": Zip} {
+		if got, err := FormatOf(name); err != nil || got != want {
+			t.Errorf("FormatOf(%q) = %v, %v", name, got, err)
+		}
+	}
+	if _, err := FormatOf("c.rar"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("FormatOf(c.rar) = %v", err)
+	}
+	if err := CreateFile(filepath.Join(t.TempDir(), "x.7z"), ".", CreateOptions{}); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("CreateFile(x.7z) = %v", err)
+	}
+}
+
+type tarEntry struct {
+	hdr  tar.Header
+	body string
+}
+
+func tarGz(t *testing.T, entries ...tarEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr := e.hdr
+		if hdr.Typeflag == 0 {
+			hdr.Typeflag = tar.TypeReg
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			hdr.Size = int64(len(e.body))
+		}
+		hdr.Mode = 0o644
+		if err := tw.WriteHeader(&hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(e.body))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func zipOf(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	names := make([]string, 0, len(files))
+	for n := range files {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		w, err := zw.Create(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(files[n]))
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestExtractUnsafe(t *testing.T) {
+	for _, name := range []string{"../evil", "a/../../evil", "/etc/evil", `..\evil`} {
+		dest := filepath.Join(t.TempDir(), "dest")
+		err := ExtractTarGz(bytes.NewReader(tarGz(t, tarEntry{tar.Header{Name: name}, "x"})), dest, ExtractOptions{})
+		if !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("tar %q: %v", name, err)
+		}
+		z := zipOf(t, map[string]string{name: "x"})
+		if err := ExtractZip(bytes.NewReader(z), int64(len(z)), dest, ExtractOptions{}); !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("zip %q: %v", name, err)
+		}
+		if _, err := os.Stat(filepath.Join(filepath.Dir(dest), "evil")); err == nil {
+			t.Errorf("%q escaped the destination", name)
+		}
+	}
+
+	link := tarEntry{tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}, ""}
+	if err := ExtractTarGz(bytes.NewReader(tarGz(t, link)), t.TempDir(), ExtractOptions{}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("symlink: %v", err)
+	}
+}
+
+func TestExtractLimits(t *testing.T) {
+	big := tarGz(t, tarEntry{tar.Header{Name: "a"}, "12345"}, tarEntry{tar.Header{Name: "b"}, "67890"})
+	for _, tc := range []struct {
+		name string
+		opts ExtractOptions
+	}{
+		{"file", ExtractOptions{MaxFileBytes: 4}},
+		{"total", ExtractOptions{MaxTotalBytes: 8}},
+		{"count", ExtractOptions{MaxFiles: 1}},
+	} {
+		dest := t.TempDir()
+		if err := ExtractTarGz(bytes.NewReader(big), dest, tc.opts); !errors.Is(err, ErrTooLarge) {
+			t.Errorf("%s limit: %v", tc.name, err)
+		}
+	}
+	dest := t.TempDir()
+	if err := ExtractTarGz(bytes.NewReader(big), dest, ExtractOptions{MaxFileBytes: 5, MaxTotalBytes: 10, MaxFiles: 2}); err != nil {
+		t.Fatalf("at the limits: %v", err)
+	}
+	if got := contents(t, dest); !reflect.DeepEqual(got, map[string]string{"a": "12345", "b": "67890"}) {
+		t.Fatalf("extracted %v", got)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	src := makeTree(t, map[string]string{"a.txt": "a", "sub/b.txt": "bb"})
+	out := t.TempDir()
+	var log bytes.Buffer
+	files := &fileutil.Executor{DryRun: true, Log: &log}
+	path := filepath.Join(out, "out.tar.gz")
+	if err := CreateFile(path, src, CreateOptions{Files: files}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("dry run created the archive: %v", err)
+	}
+
+	// Extract a real archive in a dry run.
+	if err := CreateFile(path, src, CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(out, "dest")
+	if err := ExtractFile(path, dest, ExtractOptions{Files: files}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dest); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("dry run extracted: %v", err)
+	}
+	want := "would write " + path + "\n" +
+		"would mkdir " + dest + "\n" +
+		"would write " + filepath.Join(dest, "a.txt") + "\n" +
+		"would mkdir " + filepath.Join(dest, "sub") + "\n" +
+		"would write " + filepath.Join(dest, "sub", "b.txt") + "\n"
+	if log.String() != want {
+		t.Errorf("log:\n%s\nwant:\n%s", log.String(), want)
+	}
+
+	// Limits are still checked.
+	err := ExtractFile(path, dest, ExtractOptions{MaxFileBytes: 1, Files: files})
+	if !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("dry run past the limit: %v", err)
+	}
+}
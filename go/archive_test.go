@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunArchive(t *testing.T) {
+	src := writeTree(t, map[string]string{"a.go": "package a", "b.txt": "bee", "tmp/c.go": "package c"})
+	out := filepath.Join(t.TempDir(), "src.tgz")
+	dest := filepath.Join(t.TempDir(), "dest")
+	var stdout, stderr bytes.Buffer
+	if code := runArchive(defaultSettings().Archive, false, []string{"create", "-exclude", "tmp", out, src}, &stdout, &stderr); code != 0 {
+		t.Fatalf("create: exit %d: %s", code, stderr.String())
+	}
+	if code := runArchive(defaultSettings().Archive, false, []string{"extract", out, dest}, &stdout, &stderr); code != 0 {
+		t.Fatalf("extract: exit %d: %s", code, stderr.String())
+	}
+	if data, err := os.ReadFile(filepath.Join(dest, "b.txt")); err != nil || string(data) != "bee" {
+		t.Fatalf("b.txt = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "tmp")); err == nil {
+		t.Fatal("excluded directory extracted")
+	}
+
+	stdout.Reset()
+	dry := filepath.Join(t.TempDir(), "dry")
+	if code := runArchive(defaultSettings().Archive, true, []string{"extract", out, dry}, &stdout, &stderr); code != 0 {
+		t.Fatalf("dry run: exit %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "would write "+filepath.Join(dry, "b.txt")+"\n") {
+		t.Fatalf("dry run printed %q", stdout.String())
+	}
+	if _, err := os.Stat(dry); !os.IsNotExist(err) {
+		t.Fatalf("dry run extracted: %v", err)
+	}
+
+	if code := runArchive(defaultSettings().Archive, false, []string{"extract", "-max-file-size", "3B", out, t.TempDir()}, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "size limit") {
+		t.Fatalf("limit: exit %d: %s", code, stderr.String())
+	}
+	if code := runArchive(defaultSettings().Archive, false, []string{"extract", "-max-size", "lots", out, t.TempDir()}, &stdout, &stderr); code != 2 || !strings.Contains(stderr.String(), "not a size") {
+		t.Fatalf("bad size: exit %d: %s", code, stderr.String())
+	}
+}
+
+func TestRunArchiveUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	for _, args := range [][]string{nil, {"list"}, {"create", "only.zip"}, {"extract"}} {
+		if code := runArchive(defaultSettings().Archive, false, args, &stdout, &stderr); code != 2 {
+			t.Errorf("%v: exit %d", args, code)
+		}
+	}
+	if code := runArchive(defaultSettings().Archive, false, []string{"create", filepath.Join(t.TempDir(), "x.rar"), "."}, &stdout, &stderr); code != 1 {
+		t.Errorf("unknown format: exit %d", code)
+	}
+}
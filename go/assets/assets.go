@@ -0,0 +1,80 @@
+// Package assets keeps the binary self-contained. It holds the sample
+// corpus the demo and tests count, and Override, through which files on
+// disk replace the assets other packages build in with embed: the
+// server's static files and templates, the stopword lists, the SQLite
+// migrations and the sample corpus itself.
+package assets
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+)
+
+//go:embed sample
+var files embed.FS
+
+// Sample returns the sample corpus: a few short English texts.
+func Sample() fs.FS {
+	sub, err := fs.Sub(files, "sample")
+	if err != nil {
+		panic(err) // the directory is embedded, so this cannot happen
+	}
+	return sub
+}
+
+// Override returns fsys with the files of the directory dir in front of
+// it: a name that exists in dir is opened there, any other in fsys, and
+// fs.ReadDir lists the entries of both. A missing dir overrides nothing,
+// so a program can look for overrides in a fixed place; an empty dir
+// returns fsys itself.
+func Override(fsys fs.FS, dir string) fs.FS {
+	if dir == "" {
+		return fsys
+	}
+	return overlay{upper: os.DirFS(dir), lower: fsys}
+}
+
+// overlay reads from upper and falls back to lower for names upper
+// does not have.
+type overlay struct {
+	upper, lower fs.FS
+}
+
+func (o overlay) Open(name string) (fs.File, error) {
+	f, err := o.upper.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return o.lower.Open(name)
+	}
+	return f, err
+}
+
+// ReadDir merges the entries of name in both, upper's winning where the
+// two have the same one, and sorts them by name as fs.ReadDir does.
+func (o overlay) ReadDir(name string) ([]fs.DirEntry, error) {
+	upper, uerr := fs.ReadDir(o.upper, name)
+	lower, lerr := fs.ReadDir(o.lower, name)
+	if uerr != nil && lerr != nil {
+		if errors.Is(uerr, fs.ErrNotExist) {
+			return nil, lerr
+		}
+		return nil, uerr
+	}
+	if uerr != nil && !errors.Is(uerr, fs.ErrNotExist) {
+		return nil, uerr
+	}
+	seen := make(map[string]bool, len(upper))
+	entries := upper
+	for _, e := range upper {
+		seen[e.Name()] = true
+	}
+	for _, e := range lower {
+		if !seen[e.Name()] {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
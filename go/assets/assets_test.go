@@ -0,0 +1,68 @@
+package assets
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSample(t *testing.T) {
+	if err := fstest.TestFS(Sample(), "aesop.txt", "gophers.txt", "proverbs.txt"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOverride(t *testing.T) {
+	base := fstest.MapFS{
+		"a.txt":     {Data: []byte("built-in a")},
+		"b.txt":     {Data: []byte("built-in b")},
+		"sub/c.txt": {Data: []byte("built-in c")},
+	}
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "b.txt"), []byte("disk b"), 0o644)
+	os.MkdirAll(filepath.Join(dir, "sub"), 0o755)
+	os.WriteFile(filepath.Join(dir, "sub", "d.txt"), []byte("disk d"), 0o644)
+
+	fsys := Override(base, dir)
+	for name, want := range map[string]string{
+		"a.txt":     "built-in a",
+		"b.txt":     "disk b",
+		"sub/c.txt": "built-in c",
+		"sub/d.txt": "disk d",
+	} {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v; want %q", name, data, err, want)
+		}
+	}
+	var names []string
+	fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, path)
+		return nil
+	})
+	if got := strings.Join(names, " "); got != ". a.txt b.txt sub sub/c.txt sub/d.txt" {
+		t.Fatalf("walked %s", got)
+	}
+	if _, err := fsys.Open("missing.txt"); !os.IsNotExist(err) {
+		t.Fatalf("Open of a missing file: %v", err)
+	}
+	if _, err := fs.ReadDir(fsys, "missing"); !os.IsNotExist(err) {
+		t.Fatalf("ReadDir of a missing directory: %v", err)
+	}
+}
+
+func TestOverrideMissingDir(t *testing.T) {
+	fsys := Override(Sample(), filepath.Join(t.TempDir(), "none"))
+	if err := fstest.TestFS(fsys, "aesop.txt", "gophers.txt", "proverbs.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Override(Sample(), "").(overlay); ok {
+		t.Fatal("empty dir did not return fsys")
+	}
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"example.com/tutorial/user"
+)
+
+func TestMemorySessions(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := NewMemorySessions(time.Hour)
+	m.now = func() time.Time { return now }
+
+	s, err := m.Create(ctx, "7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Token) < 40 || s.UserID != "7" || !s.Expires.Equal(now.Add(time.Hour)) {
+		t.Fatalf("bad session %+v", s)
+	}
+	other, _ := m.Create(ctx, "7")
+	if other.Token == s.Token {
+		t.Fatal("tokens are not unique")
+	}
+	if got, err := m.Lookup(ctx, s.Token); err != nil || got != s {
+		t.Fatalf("Lookup = %+v, %v", got, err)
+	}
+
+	m.Revoke(ctx, s.Token)
+	if _, err := m.Lookup(ctx, s.Token); !errors.Is(err, ErrNoSession) {
+		t.Fatalf("revoked Lookup error = %v", err)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := m.Lookup(ctx, other.Token); !errors.Is(err, ErrNoSession) {
+		t.Fatalf("expired Lookup error = %v", err)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	ctx := context.Background()
+	users := user.NewMemoryStore()
+	ada := user.User{Name: "Ada"}
+	if err := ada.SetPassword("analytical engine"); err != nil {
+		t.Fatal(err)
+	}
+	ada, _ = users.Create(ctx, ada)
+	users.Create(ctx, user.User{Name: "Adam"}) // no password
+	sessions := NewMemorySessions(time.Hour)
+
+	s, err := Login(ctx, users, sessions, "Ada", "analytical engine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.UserID != ada.ID {
+		t.Fatalf("session for user %q, want %q", s.UserID, ada.ID)
+	}
+	if _, err := sessions.Lookup(ctx, s.Token); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range [][2]string{{"Ada", "wrong"}, {"Adam", ""}, {"Nobody", "analytical engine"}, {"Ad", "analytical engine"}} {
+		if _, err := Login(ctx, users, sessions, c[0], c[1]); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("Login(%q, %q) error = %v, want ErrInvalidCredentials", c[0], c[1], err)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"example.com/tutorial/id"
+)
+
+// Errors returned by JWT.Verify. ErrTokenExpired is distinct so clients
+// can be told to log in again rather than that their token is bad.
+var (
+	ErrInvalidToken = errors.New("auth: invalid token")
+	ErrTokenExpired = errors.New("auth: token expired")
+)
+
+// Claims is the payload of the tokens issued by JWT. Times are Unix
+// seconds, as RFC 7519 specifies.
+type Claims struct {
+	Subject   string `json:"sub"` // the user ID
+	Name      string `json:"name,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// UserID returns the subject, checked to be a user ID.
+func (c Claims) UserID() (string, error) {
+	if !id.Valid(c.Subject) {
+		return "", fmt.Errorf("%w: subject %q is not a user ID", ErrInvalidToken, c.Subject)
+	}
+	return c.Subject, nil
+}
+
+// jwtHeader is the only header JWT issues or accepts; in particular
+// tokens claiming "alg":"none" or another algorithm are rejected.
+const jwtHeader = `{"alg":"HS256","typ":"JWT"}`
+
+var b64 = base64.RawURLEncoding
+
+// JWT issues and verifies HS256 JSON Web Tokens with a shared secret. It
+// is safe for concurrent use.
+type JWT struct {
+	secret []byte
+	ttl    time.Duration
+	issuer string
+	now    func() time.Time
+}
+
+// NewJWT returns a JWT signing with secret, which should be at least 32
+// random bytes, whose tokens last ttl. A non-empty issuer is written to
+// and required of every token.
+func NewJWT(secret []byte, ttl time.Duration, issuer string) *JWT {
+	if len(secret) == 0 {
+		panic("auth: empty JWT secret")
+	}
+	return &JWT{secret: secret, ttl: ttl, issuer: issuer, now: time.Now}
+}
+
+// Issue returns a signed token for userID and the claims it carries.
+func (j *JWT) Issue(userID string, name string) (string, Claims, error) {
+	now := j.now()
+	c := Claims{
+		Subject:   userID,
+		Name:      name,
+		Issuer:    j.issuer,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(j.ttl).Unix(),
+	}
+	token, err := j.Sign(c)
+	return token, c, err
+}
+
+// Sign encodes and signs c as is.
+func (j *JWT) Sign(c Claims) (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	signed := b64.EncodeToString([]byte(jwtHeader)) + "." + b64.EncodeToString(payload)
+	return signed + "." + b64.EncodeToString(j.mac(signed)), nil
+}
+
+// Verify checks token's signature, algorithm, issuer and expiry and
+// returns its claims. All failures wrap ErrInvalidToken except expiry,
+// which is ErrTokenExpired.
+func (j *JWT) Verify(token string) (Claims, error) {
+	header, rest, ok1 := strings.Cut(token, ".")
+	payload, sig, ok2 := strings.Cut(rest, ".")
+	if !ok1 || !ok2 {
+		return Claims{}, fmt.Errorf("%w: malformed", ErrInvalidToken)
+	}
+	gotSig, err := b64.DecodeString(sig)
+	if err != nil || !hmac.Equal(gotSig, j.mac(header+"."+payload)) {
+		return Claims{}, fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+	// The signature covers the header, but check it anyway so that a
+	// token made for another algorithm is never taken as HS256.
+	h, err := b64.DecodeString(header)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+	var hdr struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(h, &hdr); err != nil || hdr.Alg != "HS256" {
+		return Claims{}, fmt.Errorf("%w: unsupported algorithm", ErrInvalidToken)
+	}
+	p, err := b64.DecodeString(payload)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: malformed payload", ErrInvalidToken)
+	}
+	var c Claims
+	if err := json.Unmarshal(p, &c); err != nil {
+		return Claims{}, fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+	if j.issuer != "" && c.Issuer != j.issuer {
+		return Claims{}, fmt.Errorf("%w: wrong issuer %q", ErrInvalidToken, c.Issuer)
+	}
+	if c.ExpiresAt == 0 {
+		return Claims{}, fmt.Errorf("%w: no expiry", ErrInvalidToken)
+	}
+	if j.now().Unix() >= c.ExpiresAt {
+		return Claims{}, ErrTokenExpired
+	}
+	return c, nil
+}
+
+func (j *JWT) mac(signed string) []byte {
+	m := hmac.New(sha256.New, j.secret)
+	m.Write([]byte(signed))
+	return m.Sum(nil)
+}
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testJWT() (*JWT, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	j := NewJWT([]byte("0123456789abcdef0123456789abcdef"), time.Hour, "tutorial")
+	j.now = func() time.Time { return now }
+	return j, &now
+}
+
+func TestJWTRoundTrip(t *testing.T) {
+	j, now := testJWT()
+	token, claims, err := j.Issue("7", "Ada")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(token, ".") != 2 || claims.ExpiresAt != now.Add(time.Hour).Unix() {
+		t.Fatalf("token %q, claims %+v", token, claims)
+	}
+	got, err := j.Verify(token)
+	if err != nil || got != claims {
+		t.Fatalf("Verify = %+v, %v", got, err)
+	}
+	if id, err := got.UserID(); err != nil || id != "7" {
+		t.Fatalf("UserID = %q, %v", id, err)
+	}
+	for _, sub := range []string{"", "../7"} {
+		if _, err := (Claims{Subject: sub}).UserID(); !errors.Is(err, ErrInvalidToken) {
+			t.Fatalf("subject %q: %v", sub, err)
+		}
+	}
+}
+
+func TestJWTExpiry(t *testing.T) {
+	j, now := testJWT()
+	token, _, _ := j.Issue("7", "Ada")
+	*now = now.Add(time.Hour - time.Second)
+	if _, err := j.Verify(token); err != nil {
+		t.Fatalf("just before expiry: %v", err)
+	}
+	*now = now.Add(time.Second)
+	if _, err := j.Verify(token); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("at expiry: %v", err)
+	}
+	noExp, _ := j.Sign(Claims{Subject: "7", Issuer: "tutorial"})
+	if _, err := j.Verify(noExp); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("token without exp: %v", err)
+	}
+}
+
+func TestJWTTampering(t *testing.T) {
+	j, _ := testJWT()
+	token, _, _ := j.Issue("7", "Ada")
+	parts := strings.Split(token, ".")
+	enc := base64.RawURLEncoding.EncodeToString
+
+	other := NewJWT([]byte("another secret, just as long...."), time.Hour, "tutorial")
+	other.now = j.now
+	foreign, _, _ := other.Issue("7", "Ada")
+	wrongIssuer := NewJWT(j.secret, time.Hour, "elsewhere")
+	wrongIssuer.now = j.now
+	misissued, _, _ := wrongIssuer.Issue("7", "Ada")
+
+	forged := enc([]byte(`{"sub":"1","iss":"tutorial","iat":0,"exp":9999999999}`))
+	tests := map[string]string{
+		"empty":          "",
+		"two parts":      parts[0] + "." + parts[1],
+		"payload swap":   parts[0] + "." + forged + "." + parts[2],
+		"signature flip": parts[0] + "." + parts[1] + "." + strings.ToUpper(parts[2]),
+		"alg none":       enc([]byte(`{"alg":"none","typ":"JWT"}`)) + "." + parts[1] + ".",
+		"other secret":   foreign,
+		"other issuer":   misissued,
+		"bad base64":     parts[0] + "." + parts[1] + ".!!!",
+	}
+	for name, tok := range tests {
+		if _, err := j.Verify(tok); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: Verify error = %v", name, err)
+		}
+	}
+}
+
+func TestRequireJWT(t *testing.T) {
+	j, now := testJWT()
+	h := RequireJWT(j)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, ok := ClaimsFrom(r.Context())
+		if !ok {
+			t.Error("no claims in context")
+		}
+		w.Write([]byte(c.Name))
+	}))
+	token, _, _ := j.Issue("7", "Ada")
+	do := func(authz string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/users/7", nil)
+		if authz != "" {
+			req.Header.Set("Authorization", authz)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do("Bearer " + token); rec.Code != http.StatusOK || rec.Body.String() != "Ada" {
+		t.Fatalf("valid token: %d %q", rec.Code, rec.Body)
+	}
+	if rec := do("bearer " + token); rec.Code != http.StatusOK {
+		t.Fatalf("lower-case scheme: %d", rec.Code)
+	}
+	tests := []struct {
+		authz     string
+		challenge string
+	}{
+		{"", "Bearer"},
+		{"Basic YWRhOnB3", "Bearer"},
+		{"Bearer garbage", `Bearer error="invalid_token"`},
+	}
+	for _, tt := range tests {
+		rec := do(tt.authz)
+		if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") != tt.challenge ||
+			!strings.Contains(rec.Body.String(), `"error"`) {
+			t.Errorf("%q: %d %q %q", tt.authz, rec.Code, rec.Header().Get("WWW-Authenticate"), rec.Body)
+		}
+	}
+	*now = now.Add(2 * time.Hour)
+	if rec := do("Bearer " + token); rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "expired") {
+		t.Fatalf("expired token: %d %q", rec.Code, rec.Body)
+	}
+	if _, ok := ClaimsFrom(httptest.NewRequest("GET", "/", nil).Context()); ok {
+		t.Fatal("claims in a bare context")
+	}
+}
+
+func TestRequireToken(t *testing.T) {
+	h := RequireToken("s3cret")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("in"))
+	}))
+	for authz, want := range map[string]int{
+		"Bearer s3cret":  http.StatusOK,
+		"bearer  s3cret": http.StatusOK,
+		"":               http.StatusUnauthorized,
+		"Bearer s3cre":   http.StatusUnauthorized,
+		"Basic s3cret":   http.StatusUnauthorized,
+	} {
+		req := httptest.NewRequest("GET", "/admin/config", nil)
+		if authz != "" {
+			req.Header.Set("Authorization", authz)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != want || (want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "") {
+			t.Errorf("%q: %d %q", authz, rec.Code, rec.Body)
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("RequireToken(\"\") did not panic")
+		}
+	}()
+	RequireToken("")
+}
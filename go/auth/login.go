@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"example.com/tutorial/user"
+)
+
+// ErrInvalidCredentials is returned by Login for an unknown name or a
+// wrong password; the two are deliberately indistinguishable.
+var ErrInvalidCredentials = errors.New("auth: invalid credentials")
+
+// Login checks password against the users called name and, on success,
+// starts a session for the first (lowest ID) user it matches.
+func Login(ctx context.Context, users user.Store, sessions SessionStore, name, password string) (Session, error) {
+	u, err := Authenticate(ctx, users, name, password)
+	if err != nil {
+		return Session{}, err
+	}
+	return sessions.Create(ctx, u.ID)
+}
+
+// Authenticate returns the first (lowest ID) user called name whose
+// password is password, or ErrInvalidCredentials.
+func Authenticate(ctx context.Context, users user.Store, name, password string) (user.User, error) {
+	page, err := users.List(ctx, user.ListQuery{NamePrefix: name})
+	if err != nil {
+		return user.User{}, err
+	}
+	for _, u := range page.Users {
+		if u.Name == name && u.CheckPassword(password) {
+			return u, nil
+		}
+	}
+	return user.User{}, ErrInvalidCredentials
+}
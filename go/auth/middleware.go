@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"example.com/tutorial/middleware"
+)
+
+type claimsKey struct{}
+
+// ClaimsFrom returns the claims RequireJWT stored in ctx.
+func ClaimsFrom(ctx context.Context) (Claims, bool) {
+	c, ok := ctx.Value(claimsKey{}).(Claims)
+	return c, ok
+}
+
+// RequireJWT rejects requests without a valid "Authorization: Bearer"
+// token from j with 401 Unauthorized and a JSON error, and passes the
+// token's claims to the handler in the request context.
+func RequireJWT(j *JWT) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearer(r)
+			if !ok {
+				unauthorized(w, "", errors.New("missing bearer token"))
+				return
+			}
+			c, err := j.Verify(token)
+			if err != nil {
+				unauthorized(w, "invalid_token", err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, c)))
+		})
+	}
+}
+
+// RequireToken rejects requests whose "Authorization: Bearer" token is
+// not token, a shared secret such as an operator's, with 401
+// Unauthorized and a JSON error, as RequireJWT does. It panics if token
+// is empty, which would let anyone in.
+func RequireToken(token string) middleware.Middleware {
+	if token == "" {
+		panic("auth: empty token")
+	}
+	want := sha256.Sum256([]byte(token))
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, ok := bearer(r)
+			if !ok {
+				unauthorized(w, "", errors.New("missing bearer token"))
+				return
+			}
+			// Comparing digests takes the same time whatever the length
+			// of the token offered.
+			if sum := sha256.Sum256([]byte(got)); subtle.ConstantTimeCompare(sum[:], want[:]) != 1 {
+				unauthorized(w, "invalid_token", ErrInvalidToken)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// bearer returns the token of the request's "Authorization: Bearer"
+// header.
+func bearer(r *http.Request) (string, bool) {
+	scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	token = strings.TrimSpace(token)
+	return token, strings.EqualFold(scheme, "Bearer") && token != ""
+}
+
+// unauthorized answers 401 with the RFC 6750 challenge for code, which
+// is empty when no token was offered.
+func unauthorized(w http.ResponseWriter, code string, err error) {
+	challenge := "Bearer"
+	if code != "" {
+		challenge += ` error="` + code + `"`
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
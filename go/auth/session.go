@@ -0,0 +1,95 @@
+// Package auth authenticates users and manages their sessions and
+// bearer tokens.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNoSession is returned for unknown, revoked or expired tokens.
+var ErrNoSession = errors.New("auth: no such session")
+
+// Session is an authenticated login.
+type Session struct {
+	Token   string
+	UserID  string
+	Expires time.Time
+}
+
+// SessionStore keeps sessions by token. Implementations must be safe for
+// concurrent use.
+type SessionStore interface {
+	// Create starts a session for userID and returns it.
+	Create(ctx context.Context, userID string) (Session, error)
+	// Lookup returns the live session for token or ErrNoSession.
+	Lookup(ctx context.Context, token string) (Session, error)
+	// Revoke ends the session for token. Revoking an unknown token is
+	// not an error.
+	Revoke(ctx context.Context, token string) error
+}
+
+// NewToken returns a random, URL-safe session token with 256 bits of
+// entropy.
+func NewToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// MemorySessions is a SessionStore held in a map. Expired sessions are
+// removed when they are looked up.
+type MemorySessions struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu       sync.Mutex
+	sessions map[string]Session
+}
+
+// NewMemorySessions returns an empty store whose sessions last ttl.
+func NewMemorySessions(ttl time.Duration) *MemorySessions {
+	return &MemorySessions{ttl: ttl, now: time.Now, sessions: map[string]Session{}}
+}
+
+// Create implements SessionStore.
+func (m *MemorySessions) Create(ctx context.Context, userID string) (Session, error) {
+	token, err := NewToken()
+	if err != nil {
+		return Session{}, err
+	}
+	s := Session{Token: token, UserID: userID, Expires: m.now().Add(m.ttl)}
+	m.mu.Lock()
+	m.sessions[token] = s
+	m.mu.Unlock()
+	return s, nil
+}
+
+// Lookup implements SessionStore.
+func (m *MemorySessions) Lookup(ctx context.Context, token string) (Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[token]
+	if !ok {
+		return Session{}, ErrNoSession
+	}
+	if !m.now().Before(s.Expires) {
+		delete(m.sessions, token)
+		return Session{}, ErrNoSession
+	}
+	return s, nil
+}
+
+// Revoke implements SessionStore.
+func (m *MemorySessions) Revoke(ctx context.Context, token string) error {
+	m.mu.Lock()
+	delete(m.sessions, token)
+	m.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,141 @@
+// Package bloom implements a Bloom filter: a compact set of strings that
+// answers "maybe" or "no" for membership. A filter never misses a string
+// added to it, but may claim one that was not, at a rate fixed when it
+// is created. Strings cannot be removed.
+//
+// Each string is hashed once, with 64-bit FNV-1a, and the k bit
+// positions are derived from the two halves of a remix of that hash by
+// double hashing (Kirsch and Mitzenmacher), which performs as well as k
+// independent hashes. The hashes are fixed, so a filter written by
+// MarshalBinary reads back the same in any process.
+package bloom
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// DefaultFalsePositiveRate is the rate New uses for a rate outside
+// (0, 1).
+const DefaultFalsePositiveRate = 0.01
+
+// Filter is a Bloom filter. The zero value is not usable; create one
+// with New. A Filter is not safe for concurrent mutation.
+type Filter struct {
+	bits []uint64
+	m    uint64 // number of bits
+	k    uint64 // number of hashes
+	n    uint64 // strings added
+}
+
+// New returns a filter sized for n strings claiming others at about the
+// rate p. Adding more than n raises the rate; see FalsePositiveRate.
+func New(n int, p float64) *Filter {
+	if p <= 0 || p >= 1 {
+		p = DefaultFalsePositiveRate
+	}
+	n = max(n, 1)
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	k := uint64(math.Round(float64(m) / float64(n) * math.Ln2))
+	return newFilter(max(m, 64), max(k, 1))
+}
+
+func newFilter(m, k uint64) *Filter {
+	return &Filter{bits: make([]uint64, (m+63)/64), m: m, k: k}
+}
+
+// Add adds s.
+func (f *Filter) Add(s string) {
+	h1, h2 := hashes(s)
+	for i := uint64(0); i < f.k; i++ {
+		bit := (h1 + i*h2) % f.m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+	f.n++
+}
+
+// MayContain reports whether s may have been added: false means it
+// certainly was not.
+func (f *Filter) MayContain(s string) bool {
+	h1, h2 := hashes(s)
+	for i := uint64(0); i < f.k; i++ {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Count returns the number of calls to Add, repeats included.
+func (f *Filter) Count() int { return int(f.n) }
+
+// FalsePositiveRate estimates the rate at which MayContain claims
+// strings that were not added, given Count strings added.
+func (f *Filter) FalsePositiveRate() float64 {
+	return math.Pow(1-math.Exp(-float64(f.k)*float64(f.n)/float64(f.m)), float64(f.k))
+}
+
+// hashes returns the two hashes whose combinations give the bit
+// positions. h2 is odd, so that it never makes every position the same.
+func hashes(s string) (uint64, uint64) {
+	// FNV-1a, inline so as not to allocate.
+	sum := uint64(14695981039346656037)
+	for i := 0; i < len(s); i++ {
+		sum ^= uint64(s[i])
+		sum *= 1099511628211
+	}
+	// splitmix64's finalizer, for a second hash independent enough.
+	z := sum + 0x9e3779b97f4a7c15
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	z ^= z >> 31
+	return sum, z | 1
+}
+
+// magic starts the encoding of a filter, with its version.
+var magic = [4]byte{'B', 'L', 'M', 1}
+
+// ErrCorrupt is returned by UnmarshalBinary for data that MarshalBinary
+// did not write.
+var ErrCorrupt = errors.New("bloom: corrupt filter")
+
+// MarshalBinary encodes f: a header of the magic "BLM\x01" and the
+// number of bits, hashes and strings added, each a big-endian uint64,
+// then the bits in 64-bit big-endian words.
+func (f *Filter) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 0, 4+3*8+8*len(f.bits))
+	data = append(data, magic[:]...)
+	data = binary.BigEndian.AppendUint64(data, f.m)
+	data = binary.BigEndian.AppendUint64(data, f.k)
+	data = binary.BigEndian.AppendUint64(data, f.n)
+	for _, w := range f.bits {
+		data = binary.BigEndian.AppendUint64(data, w)
+	}
+	return data, nil
+}
+
+// UnmarshalBinary replaces f with the filter MarshalBinary encoded in
+// data. f is left alone if data is not such a filter.
+func (f *Filter) UnmarshalBinary(data []byte) error {
+	const header = 4 + 3*8
+	if len(data) < header || [4]byte(data[:4]) != magic {
+		return ErrCorrupt
+	}
+	m := binary.BigEndian.Uint64(data[4:])
+	k := binary.BigEndian.Uint64(data[12:])
+	n := binary.BigEndian.Uint64(data[20:])
+	words := (m + 63) / 64
+	if m == 0 || k == 0 || uint64(len(data)-header) != 8*words {
+		return fmt.Errorf("%w: %d bytes for %d bits", ErrCorrupt, len(data), m)
+	}
+	g := newFilter(m, k)
+	g.n = n
+	for i := range g.bits {
+		g.bits[i] = binary.BigEndian.Uint64(data[header+8*i:])
+	}
+	*f = *g
+	return nil
+}
@@ -0,0 +1,134 @@
+package bloom
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"testing"
+)
+
+func TestMembership(t *testing.T) {
+	f := New(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		f.Add(fmt.Sprintf("word%d", i))
+	}
+	for i := 0; i < 1000; i++ {
+		if !f.MayContain(fmt.Sprintf("word%d", i)) {
+			t.Fatalf("word%d was added but is missing", i)
+		}
+	}
+	if f.Count() != 1000 {
+		t.Fatalf("Count = %d", f.Count())
+	}
+	// The measured rate of false positives is near the one asked for.
+	fp := 0
+	const trials = 100000
+	for i := 0; i < trials; i++ {
+		if f.MayContain(fmt.Sprintf("other%d", i)) {
+			fp++
+		}
+	}
+	if rate := float64(fp) / trials; rate > 0.02 {
+		t.Fatalf("false positive rate %.4f, want about 0.01", rate)
+	}
+	if est := f.FalsePositiveRate(); est < 0.005 || est > 0.015 {
+		t.Fatalf("FalsePositiveRate = %.4f", est)
+	}
+	if f.m != 9586 || f.k != 7 {
+		t.Fatalf("sized %d bits, %d hashes", f.m, f.k)
+	}
+}
+
+func TestNewDefaults(t *testing.T) {
+	for _, p := range []float64{0, -1, 1, 2} {
+		if f, want := New(100, p), New(100, DefaultFalsePositiveRate); f.m != want.m || f.k != want.k {
+			t.Errorf("New(100, %v) sized %d/%d, want the default rate's %d/%d", p, f.m, f.k, want.m, want.k)
+		}
+	}
+	f := New(0, 0.5)
+	if f.m < 64 || f.k < 1 || f.MayContain("x") {
+		t.Fatalf("tiny filter: %d bits, %d hashes", f.m, f.k)
+	}
+	f.Add("x")
+	if !f.MayContain("x") {
+		t.Fatal("tiny filter lost x")
+	}
+}
+
+func TestOverfull(t *testing.T) {
+	f := New(10, 0.01)
+	before := f.FalsePositiveRate()
+	for i := 0; i < 1000; i++ {
+		f.Add(fmt.Sprint(i))
+	}
+	if after := f.FalsePositiveRate(); after <= before || after < 0.9 {
+		t.Fatalf("rate %v after overfilling, %v before", after, before)
+	}
+}
+
+func TestHashIsFNV(t *testing.T) {
+	h := fnv.New64a()
+	h.Write([]byte("gopher"))
+	if got, _ := hashes("gopher"); got != h.Sum64() {
+		t.Fatalf("hash %x, FNV-1a %x", got, h.Sum64())
+	}
+}
+
+func TestMarshalBinary(t *testing.T) {
+	f := New(500, 0.001)
+	for _, w := range []string{"go", "rust", "gophers"} {
+		f.Add(w)
+	}
+	data, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var g Filter
+	if err := g.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if g.m != f.m || g.k != f.k || g.Count() != 3 || !g.MayContain("rust") || g.MayContain("zebra") {
+		t.Fatalf("decoded filter %d/%d/%d", g.m, g.k, g.n)
+	}
+	if again, _ := g.MarshalBinary(); !bytes.Equal(again, data) {
+		t.Fatal("re-encoding differs")
+	}
+
+	// Through gob, which uses MarshalBinary.
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(f); err != nil {
+		t.Fatal(err)
+	}
+	var h *Filter
+	if err := gob.NewDecoder(&buf).Decode(&h); err != nil || !h.MayContain("gophers") {
+		t.Fatalf("gob round trip: %v", err)
+	}
+
+	for name, bad := range map[string][]byte{
+		"empty":     nil,
+		"magic":     append([]byte("XXXX"), data[4:]...),
+		"truncated": data[:len(data)-1],
+		"no bits":   append(append([]byte{}, data[:4]...), make([]byte, 24)...),
+	} {
+		g := *f
+		if err := g.UnmarshalBinary(bad); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+		if g.m != f.m || !g.MayContain("go") {
+			t.Errorf("%s: failed decode changed the filter", name)
+		}
+	}
+}
+
+func BenchmarkMayContain(b *testing.B) {
+	f := New(1<<16, 0.01)
+	for i := 0; i < 1<<16; i++ {
+		f.Add(fmt.Sprint(i))
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		f.MayContain("goroutines")
+	}
+}
@@ -0,0 +1,248 @@
+// Package breaker implements a circuit breaker. A Breaker watches the
+// outcome of calls to a dependency over a rolling window; when too many
+// fail it opens and rejects calls outright, giving the dependency time
+// to recover, then lets a few trial calls through before closing again.
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned instead of making a call while the breaker is open,
+// or half-open with its trial calls already in flight.
+var ErrOpen = errors.New("breaker: open")
+
+// State is the position of a Breaker.
+type State int
+
+const (
+	// Closed lets every call through and counts the failures.
+	Closed State = iota
+	// Open rejects every call until OpenTimeout has passed.
+	Open
+	// HalfOpen lets HalfOpenCalls trial calls through: one failure opens
+	// the breaker again, and that many successes close it.
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// Defaults used for zero Config fields.
+const (
+	DefaultWindow        = 10 * time.Second
+	DefaultBuckets       = 10
+	DefaultMinCalls      = 5
+	DefaultFailureRatio  = 0.5
+	DefaultOpenTimeout   = 5 * time.Second
+	DefaultHalfOpenCalls = 1
+)
+
+// Config tunes a Breaker.
+type Config struct {
+	// Window is how far back outcomes count, tracked in Buckets slices
+	// that expire one at a time.
+	Window  time.Duration
+	Buckets int
+	// The breaker opens once the window holds at least MinCalls
+	// outcomes and the share of failures reaches FailureRatio.
+	MinCalls     int
+	FailureRatio float64
+	// OpenTimeout is how long the breaker stays open before going
+	// half-open.
+	OpenTimeout time.Duration
+	// HalfOpenCalls is how many trial calls may run at once when
+	// half-open, and how many must succeed to close.
+	HalfOpenCalls int
+	// OnStateChange, if set, is called after every transition. It runs
+	// with the breaker locked, so it must not call back into it.
+	OnStateChange func(from, to State)
+}
+
+// bucket counts the outcomes in one slice of the window.
+type bucket struct {
+	start               time.Time
+	successes, failures int
+}
+
+// Breaker is a circuit breaker. It is safe for concurrent use.
+type Breaker struct {
+	cfg   Config
+	width time.Duration // of one bucket
+	now   func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	gen      uint64 // advanced on every transition
+	openedAt time.Time
+	buckets  []bucket
+	inFlight int // trial calls running while half-open
+	trialsOK int
+}
+
+// New returns a closed Breaker.
+func New(cfg Config) *Breaker {
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultWindow
+	}
+	if cfg.Buckets <= 0 {
+		cfg.Buckets = DefaultBuckets
+	}
+	if cfg.MinCalls <= 0 {
+		cfg.MinCalls = DefaultMinCalls
+	}
+	if cfg.FailureRatio <= 0 {
+		cfg.FailureRatio = DefaultFailureRatio
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = DefaultOpenTimeout
+	}
+	if cfg.HalfOpenCalls <= 0 {
+		cfg.HalfOpenCalls = DefaultHalfOpenCalls
+	}
+	return &Breaker{
+		cfg:     cfg,
+		width:   cfg.Window / time.Duration(cfg.Buckets),
+		now:     time.Now,
+		buckets: make([]bucket, cfg.Buckets),
+	}
+}
+
+// State returns the breaker's position, moving from open to half-open
+// if OpenTimeout has passed.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tick(b.now())
+	return b.state
+}
+
+// Allow asks to make a call. It returns ErrOpen if the call must not be
+// made; otherwise the caller makes it and reports the outcome with done,
+// exactly once, passing whether it failed.
+func (b *Breaker) Allow() (done func(failed bool), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tick(b.now())
+	switch b.state {
+	case Open:
+		return nil, ErrOpen
+	case HalfOpen:
+		if b.inFlight >= b.cfg.HalfOpenCalls {
+			return nil, ErrOpen
+		}
+		b.inFlight++
+	}
+	gen := b.gen
+	var once sync.Once
+	return func(failed bool) {
+		once.Do(func() { b.record(gen, failed) })
+	}, nil
+}
+
+// Do calls fn unless the breaker is open, counting a non-nil error as a
+// failure, and returns fn's error or ErrOpen.
+func (b *Breaker) Do(fn func() error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	done(err != nil)
+	return err
+}
+
+// record counts the outcome of a call allowed in generation gen. Calls
+// that straddle a transition are ignored: their outcome says nothing
+// about the new state.
+func (b *Breaker) record(gen uint64, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	b.tick(now)
+	if gen != b.gen {
+		return
+	}
+	switch b.state {
+	case Closed:
+		bk := b.bucket(now)
+		if failed {
+			bk.failures++
+		} else {
+			bk.successes++
+		}
+		calls, failures := b.totals(now)
+		if calls >= b.cfg.MinCalls && float64(failures) >= b.cfg.FailureRatio*float64(calls) {
+			b.setState(Open, now)
+		}
+	case HalfOpen:
+		b.inFlight--
+		if failed {
+			b.setState(Open, now)
+			return
+		}
+		b.trialsOK++
+		if b.trialsOK >= b.cfg.HalfOpenCalls {
+			b.setState(Closed, now)
+		}
+	}
+}
+
+// tick moves an open breaker to half-open once its timeout is up.
+func (b *Breaker) tick(now time.Time) {
+	if b.state == Open && !now.Before(b.openedAt.Add(b.cfg.OpenTimeout)) {
+		b.setState(HalfOpen, now)
+	}
+}
+
+func (b *Breaker) setState(to State, now time.Time) {
+	from := b.state
+	b.state = to
+	b.gen++
+	b.inFlight, b.trialsOK = 0, 0
+	switch to {
+	case Open:
+		b.openedAt = now
+	case Closed:
+		clear(b.buckets)
+	}
+	if b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(from, to)
+	}
+}
+
+// bucket returns the bucket for now, resetting it if it last held an
+// older slice of time.
+func (b *Breaker) bucket(now time.Time) *bucket {
+	start := now.Truncate(b.width)
+	bk := &b.buckets[int(start.UnixNano()/int64(b.width))%len(b.buckets)]
+	if !bk.start.Equal(start) {
+		*bk = bucket{start: start}
+	}
+	return bk
+}
+
+// totals sums the buckets that are still inside the window.
+func (b *Breaker) totals(now time.Time) (calls, failures int) {
+	oldest := now.Truncate(b.width).Add(-b.cfg.Window + b.width)
+	for _, bk := range b.buckets {
+		if bk.start.Before(oldest) {
+			continue
+		}
+		calls += bk.successes + bk.failures
+		failures += bk.failures
+	}
+	return calls, failures
+}
@@ -0,0 +1,144 @@
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock returns a Breaker whose time only moves when told to, and
+// the list of its transitions.
+func fakeClock(cfg Config) (*Breaker, func(time.Duration), *[]string) {
+	var changes []string
+	cfg.OnStateChange = func(from, to State) { changes = append(changes, fmt.Sprintf("%v->%v", from, to)) }
+	b := New(cfg)
+	now := time.Unix(1000, 0)
+	b.now = func() time.Time { return now }
+	return b, func(d time.Duration) { now = now.Add(d) }, &changes
+}
+
+var errDown = errors.New("down")
+
+func call(b *Breaker, err error) error {
+	return b.Do(func() error { return err })
+}
+
+func TestOpensOnFailureRatio(t *testing.T) {
+	b, _, changes := fakeClock(Config{MinCalls: 4, FailureRatio: 0.5})
+	call(b, nil)
+	call(b, nil)
+	call(b, errDown)
+	if b.State() != Closed {
+		t.Fatal("opened before MinCalls")
+	}
+	call(b, errDown) // 2 of 4 failed
+	if b.State() != Open {
+		t.Fatalf("state %v after half the calls failed", b.State())
+	}
+	if err := call(b, nil); err != ErrOpen {
+		t.Fatalf("call while open = %v, want ErrOpen", err)
+	}
+	if got := strings.Join(*changes, " "); got != "closed->open" {
+		t.Fatalf("transitions %q", got)
+	}
+}
+
+func TestRollingWindow(t *testing.T) {
+	b, advance, _ := fakeClock(Config{Window: 10 * time.Second, Buckets: 5, MinCalls: 3, FailureRatio: 0.5})
+	call(b, errDown)
+	call(b, errDown)
+	advance(11 * time.Second) // both failures expire
+	call(b, nil)
+	call(b, errDown)
+	if b.State() != Closed {
+		t.Fatal("expired failures still counted")
+	}
+	advance(4 * time.Second) // still inside the window
+	call(b, errDown)
+	if b.State() != Open {
+		t.Fatalf("state %v with 2 of 3 recent calls failed", b.State())
+	}
+}
+
+func TestHalfOpenCloses(t *testing.T) {
+	b, advance, changes := fakeClock(Config{MinCalls: 1, OpenTimeout: time.Second, HalfOpenCalls: 2})
+	call(b, errDown)
+	advance(999 * time.Millisecond)
+	if b.State() != Open {
+		t.Fatal("half-open before OpenTimeout")
+	}
+	advance(time.Millisecond)
+	if b.State() != HalfOpen {
+		t.Fatalf("state %v after OpenTimeout", b.State())
+	}
+	done1, err1 := b.Allow()
+	done2, err2 := b.Allow()
+	if err1 != nil || err2 != nil {
+		t.Fatal("trial calls rejected")
+	}
+	if _, err := b.Allow(); err != ErrOpen {
+		t.Fatalf("third concurrent trial = %v, want ErrOpen", err)
+	}
+	done1(false)
+	done1(true) // reported twice: ignored
+	if b.State() != HalfOpen {
+		t.Fatal("closed after one of two trials")
+	}
+	done2(false)
+	if b.State() != Closed {
+		t.Fatalf("state %v after the trials succeeded", b.State())
+	}
+	want := "closed->open open->half-open half-open->closed"
+	if got := strings.Join(*changes, " "); got != want {
+		t.Fatalf("transitions %q, want %q", got, want)
+	}
+	// The failures before it opened are forgotten.
+	call(b, nil)
+	if b.State() != Closed {
+		t.Fatal("old failures counted after closing")
+	}
+}
+
+func TestHalfOpenReopens(t *testing.T) {
+	b, advance, changes := fakeClock(Config{MinCalls: 1, OpenTimeout: time.Second})
+	call(b, errDown)
+	advance(time.Second)
+	if err := call(b, errDown); err != errDown {
+		t.Fatalf("trial = %v", err)
+	}
+	if b.State() != Open {
+		t.Fatalf("state %v after a failed trial", b.State())
+	}
+	advance(500 * time.Millisecond)
+	if b.State() != Open {
+		t.Fatal("open timer not restarted")
+	}
+	want := "closed->open open->half-open half-open->open"
+	if got := strings.Join(*changes, " "); got != want {
+		t.Fatalf("transitions %q, want %q", got, want)
+	}
+}
+
+func TestStaleOutcomeIgnored(t *testing.T) {
+	b, advance, _ := fakeClock(Config{MinCalls: 1, OpenTimeout: time.Second})
+	slow, _ := b.Allow() // started while closed
+	call(b, errDown)
+	advance(time.Second)
+	if b.State() != HalfOpen {
+		t.Fatal("not half-open")
+	}
+	slow(false) // finishes during half-open: says nothing about recovery
+	if b.State() != HalfOpen {
+		t.Fatalf("state %v after a stale success", b.State())
+	}
+}
+
+func TestStateString(t *testing.T) {
+	for s, want := range map[State]string{Closed: "closed", Open: "open", HalfOpen: "half-open", 7: "State(7)"} {
+		if s.String() != want {
+			t.Errorf("%d.String() = %q", int(s), s.String())
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Package buildinfo describes the running binary: its version, the commit
+// and date it was built from, and the Go toolchain and platform. Release
+// builds set the version, commit and date with the linker, as in
+//
+//	go build -ldflags "-X example.com/tutorial/buildinfo.Version=v1.2.0
+//		-X example.com/tutorial/buildinfo.Commit=$(git rev-parse HEAD)
+//		-X example.com/tutorial/buildinfo.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Otherwise they come from what the go command records in the binary,
+// which for a build inside a git checkout includes the commit.
+package buildinfo
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+// Set with -ldflags "-X ..."; empty means unknown.
+var (
+	Version string
+	Commit  string
+	Date    string
+)
+
+// Devel is the version of a build that has none.
+const Devel = "devel"
+
+// Info is what is known about the build.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	Modified  bool   `json:"modified,omitempty"` // built with uncommitted changes
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"` // GOOS/GOARCH
+}
+
+// Get returns the Info of the running binary.
+func Get() Info {
+	bi, _ := debug.ReadBuildInfo()
+	return read(bi)
+}
+
+// read fills in Info from the linker variables, falling back to bi,
+// which may be nil.
+func read(bi *debug.BuildInfo) Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if bi != nil {
+		if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		if info.Commit == "" {
+			for _, s := range bi.Settings {
+				switch s.Key {
+				case "vcs.revision":
+					info.Commit = s.Value
+				case "vcs.time":
+					if info.Date == "" {
+						info.Date = s.Value
+					}
+				case "vcs.modified":
+					info.Modified = s.Value == "true"
+				}
+			}
+		}
+	}
+	if info.Version == "" {
+		info.Version = Devel
+	}
+	return info
+}
+
+// ShortCommit returns the first 12 characters of i.Commit, enough to
+// name a git commit.
+func (i Info) ShortCommit() string {
+	if len(i.Commit) > 12 {
+		return i.Commit[:12]
+	}
+	return i.Commit
+}
+
+// String returns i on one line, as in
+// "v1.2.0 (commit 0123456789ab, 2026-01-02T15:04:05Z) go1.21.0 linux/amd64".
+func (i Info) String() string {
+	s := i.Version
+	if i.Commit != "" {
+		s += " (commit " + i.ShortCommit()
+		if i.Modified {
+			s += "+modified"
+		}
+		if i.Date != "" {
+			s += ", " + i.Date
+		}
+		s += ")"
+	} else if i.Date != "" {
+		s += " (" + i.Date + ")"
+	}
+	return s + " " + i.GoVersion + " " + i.Platform
+}
+
+// Handler returns the /version handler, which answers with the Info of
+// the running binary as JSON.
+func Handler() http.Handler {
+	info := Get()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(info)
+	})
+}
@@ -0,0 +1,67 @@
+package buildinfo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	bi := &debug.BuildInfo{
+		Main: debug.Module{Version: "v0.3.1"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0123456789abcdef0123"},
+			{Key: "vcs.time", Value: "2026-01-02T15:04:05Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+	info := read(bi)
+	platform := runtime.GOOS + "/" + runtime.GOARCH
+	want := Info{Version: "v0.3.1", Commit: "0123456789abcdef0123", Date: "2026-01-02T15:04:05Z", Modified: true,
+		GoVersion: runtime.Version(), Platform: platform}
+	if info != want {
+		t.Fatalf("read = %+v, want %+v", info, want)
+	}
+	if got, want := info.String(), "v0.3.1 (commit 0123456789ab+modified, 2026-01-02T15:04:05Z) "+runtime.Version()+" "+platform; got != want {
+		t.Errorf("String:\ngot  %q\nwant %q", got, want)
+	}
+
+	if info := read(&debug.BuildInfo{Main: debug.Module{Version: "(devel)"}}); info.Version != Devel || info.Commit != "" {
+		t.Errorf("devel build: %+v", info)
+	}
+	if info := read(nil); info.Version != Devel || !strings.HasSuffix(info.String(), platform) {
+		t.Errorf("no build info: %+v", info)
+	}
+}
+
+func TestReadLinkerVariables(t *testing.T) {
+	Version, Commit, Date = "v1.0.0", "abc", "2026-02-03"
+	defer func() { Version, Commit, Date = "", "", "" }()
+	bi := &debug.BuildInfo{
+		Main:     debug.Module{Version: "v0.0.1"},
+		Settings: []debug.BuildSetting{{Key: "vcs.revision", Value: "ffff"}, {Key: "vcs.modified", Value: "true"}},
+	}
+	info := read(bi)
+	if info.Version != "v1.0.0" || info.Commit != "abc" || info.Date != "2026-02-03" || info.Modified {
+		t.Fatalf("read = %+v", info)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+		t.Fatalf("status %d, type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var info Info
+	if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil {
+		t.Fatal(err)
+	}
+	if info != Get() {
+		t.Fatalf("served %+v, want %+v", info, Get())
+	}
+}
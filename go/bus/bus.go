@@ -0,0 +1,200 @@
+// Package bus is an in-memory publish/subscribe message bus. Messages
+// are published to a named topic and delivered to every subscriber of
+// that topic through its own buffered queue, so one slow subscriber
+// need not hold up the others.
+package bus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrClosed is returned by Publish once the bus is closed.
+var ErrClosed = errors.New("bus: closed")
+
+// Policy says what Publish does when a subscriber's queue is full.
+type Policy int
+
+const (
+	// Drop discards the message for that subscriber and counts it in
+	// Dropped; the publisher never waits.
+	Drop Policy = iota
+	// Block makes the publisher wait for room in the queue, until its
+	// context is done.
+	Block
+)
+
+// DefaultBuffer is the queue length of a subscription whose
+// SubscribeConfig.Buffer is zero.
+const DefaultBuffer = 16
+
+// SubscribeConfig tunes one subscription.
+type SubscribeConfig struct {
+	// Buffer is the length of the subscriber's queue; zero means
+	// DefaultBuffer.
+	Buffer int
+	// Policy applies when the queue is full.
+	Policy Policy
+}
+
+// Bus delivers messages of type T. It is safe for concurrent use.
+type Bus[T any] struct {
+	mu     sync.RWMutex
+	topics map[string]map[*Subscription[T]]struct{}
+	closed bool
+}
+
+// New returns an empty, open bus.
+func New[T any]() *Bus[T] {
+	return &Bus[T]{topics: map[string]map[*Subscription[T]]struct{}{}}
+}
+
+// Subscription is one subscriber's queue of messages from a topic.
+type Subscription[T any] struct {
+	bus     *Bus[T]
+	topic   string
+	policy  Policy
+	ch      chan T
+	done    chan struct{} // closed first, to release blocked publishers
+	once    sync.Once
+	mu      sync.RWMutex // held for reading while sending on ch
+	closed  bool
+	dropped atomic.Uint64
+}
+
+// Subscribe starts a subscription to topic. On a closed bus the
+// subscription's channel is closed straight away.
+func (b *Bus[T]) Subscribe(topic string, cfg SubscribeConfig) *Subscription[T] {
+	if cfg.Buffer <= 0 {
+		cfg.Buffer = DefaultBuffer
+	}
+	s := &Subscription[T]{
+		bus:    b,
+		topic:  topic,
+		policy: cfg.Policy,
+		ch:     make(chan T, cfg.Buffer),
+		done:   make(chan struct{}),
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		s.close()
+		return s
+	}
+	if b.topics[topic] == nil {
+		b.topics[topic] = map[*Subscription[T]]struct{}{}
+	}
+	b.topics[topic][s] = struct{}{}
+	return s
+}
+
+// Publish delivers msg to every current subscriber of topic. For Block
+// subscribers it waits for room; if ctx is done first it stops and
+// returns ctx's error, and the subscribers not yet reached miss the
+// message.
+func (b *Bus[T]) Publish(ctx context.Context, topic string, msg T) error {
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return ErrClosed
+	}
+	subs := make([]*Subscription[T], 0, len(b.topics[topic]))
+	for s := range b.topics[topic] {
+		subs = append(subs, s)
+	}
+	b.mu.RUnlock()
+
+	for _, s := range subs {
+		if err := s.deliver(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subscribers returns the number of subscribers of topic.
+func (b *Bus[T]) Subscribers(topic string) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.topics[topic])
+}
+
+// Close ends every subscription and refuses further publishing.
+// Messages already queued can still be received before each channel
+// reports closed, and publishers blocked on a full queue are released.
+func (b *Bus[T]) Close() {
+	b.mu.Lock()
+	b.closed = true
+	topics := b.topics
+	b.topics = map[string]map[*Subscription[T]]struct{}{}
+	b.mu.Unlock()
+	for _, subs := range topics {
+		for s := range subs {
+			s.close()
+		}
+	}
+}
+
+// C returns the channel messages arrive on. It is closed by
+// Unsubscribe or when the bus is closed.
+func (s *Subscription[T]) C() <-chan T {
+	return s.ch
+}
+
+// Dropped returns how many messages the Drop policy has discarded.
+func (s *Subscription[T]) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Unsubscribe ends the subscription and closes its channel. It is safe
+// to call more than once.
+func (s *Subscription[T]) Unsubscribe() {
+	b := s.bus
+	b.mu.Lock()
+	if subs := b.topics[s.topic]; subs != nil {
+		delete(subs, s)
+		if len(subs) == 0 {
+			delete(b.topics, s.topic)
+		}
+	}
+	b.mu.Unlock()
+	s.close()
+}
+
+// close releases any publisher blocked on s, then closes the channel
+// once no publisher is sending on it.
+func (s *Subscription[T]) close() {
+	s.once.Do(func() {
+		close(s.done)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.closed = true
+		close(s.ch)
+	})
+}
+
+func (s *Subscription[T]) deliver(ctx context.Context, msg T) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return nil
+	}
+	if s.policy == Drop {
+		select {
+		case s.ch <- msg:
+		default:
+			s.dropped.Add(1)
+		}
+		return nil
+	}
+	select {
+	case s.ch <- msg:
+		return nil
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
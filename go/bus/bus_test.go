@@ -0,0 +1,152 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPublishSubscribe(t *testing.T) {
+	b := New[string]()
+	a1 := b.Subscribe("a", SubscribeConfig{})
+	a2 := b.Subscribe("a", SubscribeConfig{})
+	other := b.Subscribe("b", SubscribeConfig{})
+	ctx := context.Background()
+	for _, m := range []string{"x", "y"} {
+		if err := b.Publish(ctx, "a", m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, s := range []*Subscription[string]{a1, a2} {
+		if got := <-s.C(); got != "x" {
+			t.Fatalf("first message %q", got)
+		}
+		if got := <-s.C(); got != "y" {
+			t.Fatalf("second message %q", got)
+		}
+	}
+	select {
+	case m := <-other.C():
+		t.Fatalf("other topic got %q", m)
+	default:
+	}
+	if n := b.Subscribers("a"); n != 2 {
+		t.Fatalf("Subscribers = %d", n)
+	}
+}
+
+func TestDropPolicy(t *testing.T) {
+	b := New[int]()
+	s := b.Subscribe("t", SubscribeConfig{Buffer: 2, Policy: Drop})
+	for i := 0; i < 5; i++ {
+		if err := b.Publish(context.Background(), "t", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := s.Dropped(); got != 3 {
+		t.Fatalf("Dropped = %d, want 3", got)
+	}
+	if a, b := <-s.C(), <-s.C(); a != 0 || b != 1 {
+		t.Fatalf("kept %d, %d; want the oldest two", a, b)
+	}
+}
+
+func TestBlockPolicy(t *testing.T) {
+	b := New[int]()
+	s := b.Subscribe("t", SubscribeConfig{Buffer: 1, Policy: Block})
+	b.Publish(context.Background(), "t", 1)
+	done := make(chan error)
+	go func() { done <- b.Publish(context.Background(), "t", 2) }()
+	select {
+	case <-done:
+		t.Fatal("Publish did not wait for a full queue")
+	case <-time.After(20 * time.Millisecond):
+	}
+	<-s.C()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got := <-s.C(); got != 2 {
+		t.Fatalf("got %d", got)
+	}
+
+	b.Publish(context.Background(), "t", 3)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.Publish(ctx, "t", 4); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Publish = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	b := New[int]()
+	s := b.Subscribe("t", SubscribeConfig{Buffer: 1, Policy: Block})
+	b.Publish(context.Background(), "t", 1)
+	done := make(chan error)
+	go func() { done <- b.Publish(context.Background(), "t", 2) }()
+	time.Sleep(10 * time.Millisecond)
+	// Unsubscribing releases the blocked publisher.
+	s.Unsubscribe()
+	s.Unsubscribe()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := <-s.C(); !ok || got != 1 {
+		t.Fatalf("queued message %d, %v", got, ok)
+	}
+	if _, ok := <-s.C(); ok {
+		t.Fatal("channel not closed")
+	}
+	if n := b.Subscribers("t"); n != 0 {
+		t.Fatalf("Subscribers = %d", n)
+	}
+}
+
+func TestClose(t *testing.T) {
+	b := New[int]()
+	s := b.Subscribe("t", SubscribeConfig{})
+	b.Publish(context.Background(), "t", 1)
+	b.Close()
+	if got, ok := <-s.C(); !ok || got != 1 {
+		t.Fatal("queued message lost on Close")
+	}
+	if _, ok := <-s.C(); ok {
+		t.Fatal("channel open after Close")
+	}
+	if err := b.Publish(context.Background(), "t", 2); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Publish after Close = %v", err)
+	}
+	if _, ok := <-b.Subscribe("t", SubscribeConfig{}).C(); ok {
+		t.Fatal("subscription on a closed bus is open")
+	}
+	s.Unsubscribe()
+}
+
+func TestConcurrent(t *testing.T) {
+	b := New[int]()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s := b.Subscribe("t", SubscribeConfig{Policy: Block})
+			for n := 0; n < 10; n++ {
+				<-s.C()
+			}
+			s.Unsubscribe()
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 100; n++ {
+				b.Publish(context.Background(), "t", n)
+			}
+		}()
+	}
+	wg.Wait()
+	b.Close()
+}
@@ -0,0 +1,82 @@
+//go:build stress
+
+package bus
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	"example.com/tutorial/stress"
+)
+
+// TestStress publishes from many goroutines while others subscribe and
+// unsubscribe, with both policies, and closes the bus halfway through,
+// so that publishing, delivery and closing all race each other.
+func TestStress(t *testing.T) {
+	b := New[int]()
+	// A long-lived subscriber reads everything until the bus is closed.
+	all := b.Subscribe("t0", SubscribeConfig{Buffer: 64, Policy: Drop})
+	var read sync.WaitGroup
+	read.Add(1)
+	received := 0
+	go func() {
+		defer read.Done()
+		for range all.C() {
+			received++
+		}
+	}()
+
+	cfg := stress.Config{}
+	half := stress.Total(t, cfg) / 2
+	var mu sync.Mutex
+	calls, published := 0, 0
+	stress.Run(t, cfg, func(g, i int) {
+		mu.Lock()
+		calls++
+		closing := calls == half
+		mu.Unlock()
+		if closing {
+			b.Close()
+		}
+		topic := "t" + strconv.Itoa(i%4)
+		switch i % 8 {
+		case 0:
+			policy := Drop
+			if g%2 == 1 {
+				policy = Block
+			}
+			s := b.Subscribe(topic, SubscribeConfig{Buffer: 2, Policy: policy})
+			for n := 0; n < 2; n++ {
+				select {
+				case <-s.C():
+				default:
+				}
+			}
+			s.Dropped()
+			s.Unsubscribe()
+			s.Unsubscribe()
+		case 1:
+			b.Subscribers(topic)
+		default:
+			err := b.Publish(context.Background(), topic, i)
+			if err != nil && !errors.Is(err, ErrClosed) {
+				t.Errorf("Publish = %v", err)
+			}
+			if err == nil && topic == "t0" {
+				mu.Lock()
+				published++
+				mu.Unlock()
+			}
+		}
+	})
+	read.Wait()
+	if got := received + int(all.Dropped()); got > published {
+		t.Fatalf("%d messages received or dropped of %d published", got, published)
+	}
+	if err := b.Publish(context.Background(), "t0", 0); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Publish after Close = %v", err)
+	}
+}
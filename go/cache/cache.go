@@ -0,0 +1,215 @@
+// Package cache is an in-memory LRU cache bounded by entry count and
+// total size, with optional expiry of entries.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"example.com/tutorial/clock"
+	"example.com/tutorial/metrics"
+)
+
+// Config bounds a Cache. Zero fields place no bound.
+type Config struct {
+	// MaxEntries is the most entries kept.
+	MaxEntries int
+	// MaxBytes is the most total size kept, as measured by the size
+	// function given to New. An entry larger than MaxBytes is not
+	// stored at all.
+	MaxBytes int64
+	// TTL is how long entries stored with Set live.
+	TTL time.Duration
+	// Clock tells when entries expire; nil means clock.System.
+	Clock clock.Clock
+}
+
+// Stats counts how lookups were served and what was evicted.
+type Stats struct {
+	Hits      uint64
+	Misses    uint64 // including expired entries
+	Evictions uint64 // entries dropped to make room, not expired ones
+}
+
+// HitRatio returns the fraction of lookups that hit, or 0 before the
+// first lookup.
+func (s Stats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// Store is the part of a Cache that code caching through it uses, so
+// that a cache shared between processes, such as a redisstore.Cache,
+// can stand in for it. Implementations must be safe for concurrent use.
+type Store[K comparable, V any] interface {
+	// Get returns the value stored under key.
+	Get(key K) (V, bool)
+	// Set stores value under key, replacing any previous value.
+	Set(key K, value V)
+	// Delete removes key.
+	Delete(key K)
+}
+
+var _ Store[string, int] = (*Cache[string, int])(nil)
+
+// Cache maps keys to values, evicting the least recently used entries
+// once a bound is exceeded. It is safe for concurrent use.
+type Cache[K comparable, V any] struct {
+	cfg  Config
+	size func(V) int64
+
+	mu      sync.Mutex
+	entries map[K]*list.Element // of *entry[K, V]
+	lru     list.List           // most recently used at the front
+	bytes   int64
+	stats   Stats
+}
+
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	size    int64
+	expires time.Time // zero for never
+}
+
+// New returns an empty cache. size measures values for MaxBytes; nil
+// counts every value as 0 bytes.
+func New[K comparable, V any](cfg Config, size func(V) int64) *Cache[K, V] {
+	if size == nil {
+		size = func(V) int64 { return 0 }
+	}
+	cfg.Clock = clock.OrSystem(cfg.Clock)
+	return &Cache[K, V]{cfg: cfg, size: size, entries: map[K]*list.Element{}}
+}
+
+// Get returns the value stored under key and marks it recently used.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if ok {
+		e := el.Value.(*entry[K, V])
+		if e.expires.IsZero() || c.cfg.Clock.Now().Before(e.expires) {
+			c.lru.MoveToFront(el)
+			c.stats.Hits++
+			return e.value, true
+		}
+		c.remove(el)
+	}
+	c.stats.Misses++
+	var zero V
+	return zero, false
+}
+
+// Set stores value under key for the configured TTL.
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.cfg.TTL)
+}
+
+// SetWithTTL stores value under key for ttl, or with no expiry if ttl
+// is zero, replacing any previous value.
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	e := &entry[K, V]{key: key, value: value, size: c.size(value)}
+	if c.cfg.MaxBytes > 0 && e.size > c.cfg.MaxBytes {
+		return
+	}
+	if ttl > 0 {
+		e.expires = c.cfg.Clock.Now().Add(ttl)
+	}
+	c.entries[key] = c.lru.PushFront(e)
+	c.bytes += e.size
+	for c.over() {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+// over reports whether a bound is exceeded.
+func (c *Cache[K, V]) over() bool {
+	return (c.cfg.MaxEntries > 0 && c.lru.Len() > c.cfg.MaxEntries) ||
+		(c.cfg.MaxBytes > 0 && c.bytes > c.cfg.MaxBytes)
+}
+
+func (c *Cache[K, V]) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*entry[K, V])
+	delete(c.entries, e.key)
+	c.bytes -= e.size
+}
+
+// Delete removes key.
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+}
+
+// Purge removes every entry; the statistics are kept.
+func (c *Cache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+	c.lru.Init()
+	c.bytes = 0
+}
+
+// Resize replaces the MaxEntries and MaxBytes bounds, evicting the
+// least recently used entries until the cache is within the new ones.
+func (c *Cache[K, V]) Resize(maxEntries int, maxBytes int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cfg.MaxEntries, c.cfg.MaxBytes = maxEntries, maxBytes
+	for c.over() {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+// Limits returns the MaxEntries and MaxBytes bounds.
+func (c *Cache[K, V]) Limits() (maxEntries int, maxBytes int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cfg.MaxEntries, c.cfg.MaxBytes
+}
+
+// Len returns the number of entries, including expired ones not yet
+// looked up.
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Bytes returns the total size of the entries.
+func (c *Cache[K, V]) Bytes() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bytes
+}
+
+// Stats returns the counts so far.
+func (c *Cache[K, V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Register reports the cache's statistics, entry count and size in reg,
+// under names starting with prefix, such as "wordcount_result_cache".
+// They are read at every scrape.
+func (c *Cache[K, V]) Register(reg *metrics.Registry, prefix string) {
+	reg.CounterFunc(prefix+"_hits_total", "Cache lookups that found an entry.", func() float64 { return float64(c.Stats().Hits) })
+	reg.CounterFunc(prefix+"_misses_total", "Cache lookups that found no live entry.", func() float64 { return float64(c.Stats().Misses) })
+	reg.CounterFunc(prefix+"_evictions_total", "Cache entries dropped to make room.", func() float64 { return float64(c.Stats().Evictions) })
+	reg.GaugeFunc(prefix+"_entries", "Cache entries.", func() float64 { return float64(c.Len()) })
+	reg.GaugeFunc(prefix+"_bytes", "Estimated size of the cache entries in bytes.", func() float64 { return float64(c.Bytes()) })
+}
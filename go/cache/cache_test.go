@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/tutorial/clock"
+	"example.com/tutorial/metrics"
+)
+
+// fakeClock returns a cache whose time only moves when told to.
+func fakeClock[V any](cfg Config, size func(V) int64) (*Cache[string, V], func(time.Duration)) {
+	clk := clock.NewFake(time.Unix(1000, 0))
+	cfg.Clock = clk
+	return New[string](cfg, size), clk.Advance
+}
+
+func TestLRUEviction(t *testing.T) {
+	c := New[string, int](Config{MaxEntries: 2}, nil)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a") // b is now least recently used
+	c.Set("c", 3)
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("least recently used entry kept")
+	}
+	for k, want := range map[string]int{"a": 1, "c": 3} {
+		if v, ok := c.Get(k); !ok || v != want {
+			t.Fatalf("Get(%q) = %d, %v", k, v, ok)
+		}
+	}
+	if s := c.Stats(); s.Evictions != 1 || s.Hits != 3 || s.Misses != 1 {
+		t.Fatalf("stats %+v", s)
+	}
+	if r := c.Stats().HitRatio(); r != 0.75 {
+		t.Fatalf("HitRatio = %v", r)
+	}
+}
+
+func TestMaxBytes(t *testing.T) {
+	c := New[string](Config{MaxBytes: 10}, func(s string) int64 { return int64(len(s)) })
+	c.Set("a", "aaaa")
+	c.Set("b", "bbbb")
+	c.Set("c", "cccc") // 12 bytes: a goes
+	if c.Len() != 2 || c.Bytes() != 8 {
+		t.Fatalf("Len %d, Bytes %d", c.Len(), c.Bytes())
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("oldest entry kept over MaxBytes")
+	}
+	c.Set("big", "far more than ten bytes")
+	if _, ok := c.Get("big"); ok || c.Len() != 2 {
+		t.Fatal("entry larger than MaxBytes stored")
+	}
+	c.Set("b", "bb") // replacing updates the size
+	if c.Bytes() != 6 {
+		t.Fatalf("Bytes = %d after replacing", c.Bytes())
+	}
+}
+
+func TestResize(t *testing.T) {
+	c := New[string, string](Config{MaxBytes: 10}, func(v string) int64 { return int64(len(v)) })
+	for _, k := range []string{"a", "b", "c"} {
+		c.Set(k, "xxx")
+	}
+	c.Get("a") // b is now least recently used
+	c.Resize(0, 6)
+	if _, ok := c.Get("b"); ok || c.Len() != 2 || c.Bytes() != 6 {
+		t.Fatalf("after shrinking: %d entries, %d bytes", c.Len(), c.Bytes())
+	}
+	c.Resize(1, 100)
+	if n, b := c.Limits(); n != 1 || b != 100 || c.Len() != 1 {
+		t.Fatalf("Limits = %d, %d with %d entries", n, b, c.Len())
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("most recently used entry evicted")
+	}
+	c.Set("big", strings.Repeat("x", 50))
+	if _, ok := c.Get("big"); !ok {
+		t.Fatal("entry within the raised bound not stored")
+	}
+	if s := c.Stats(); s.Evictions != 3 {
+		t.Fatalf("stats %+v", s)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	c, advance := fakeClock[int](Config{TTL: time.Minute}, nil)
+	c.Set("a", 1)
+	c.SetWithTTL("b", 2, time.Hour)
+	c.SetWithTTL("c", 3, 0)
+	advance(59 * time.Second)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("expired early")
+	}
+	advance(time.Second)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("expired entry returned")
+	}
+	advance(2 * time.Hour)
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("per-entry TTL ignored")
+	}
+	if _, ok := c.Get("c"); !ok {
+		t.Fatal("entry without expiry expired")
+	}
+	if c.Len() != 1 || c.Stats().Evictions != 0 {
+		t.Fatalf("Len %d, stats %+v", c.Len(), c.Stats())
+	}
+}
+
+func TestDeletePurge(t *testing.T) {
+	c := New[int](Config{}, func(s string) int64 { return int64(len(s)) })
+	c.Set(1, "one")
+	c.Set(2, "two")
+	c.Delete(1)
+	c.Delete(3)
+	if _, ok := c.Get(1); ok || c.Len() != 1 || c.Bytes() != 3 {
+		t.Fatal("Delete did not remove the entry")
+	}
+	c.Purge()
+	if c.Len() != 0 || c.Bytes() != 0 {
+		t.Fatal("Purge left entries")
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	c := New[string, int](Config{MaxEntries: 50}, nil)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				k := fmt.Sprint(i % 80)
+				if _, ok := c.Get(k); !ok {
+					c.Set(k, i)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if n := c.Len(); n > 50 {
+		t.Fatalf("Len = %d over MaxEntries", n)
+	}
+	s := c.Stats()
+	if s.Hits+s.Misses != 8*200 {
+		t.Fatalf("stats %+v", s)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	reg := metrics.NewRegistry()
+	c := New[string](Config{MaxEntries: 1}, func(s string) int64 { return int64(len(s)) })
+	c.Register(reg, "test_cache")
+	c.Set("a", "aaa")
+	c.Set("b", "bb")
+	c.Get("a")
+	c.Get("b")
+	var b strings.Builder
+	reg.WriteText(&b)
+	for _, want := range []string{
+		"test_cache_hits_total 1",
+		"test_cache_misses_total 1",
+		"test_cache_evictions_total 1",
+		"test_cache_entries 1",
+		"test_cache_bytes 2",
+	} {
+		if !strings.Contains(b.String(), want+"\n") {
+			t.Errorf("missing %s in:\n%s", want, b.String())
+		}
+	}
+}
@@ -0,0 +1,63 @@
+//go:build stress
+
+package cache
+
+import (
+	"io"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"example.com/tutorial/clock"
+	"example.com/tutorial/metrics"
+	"example.com/tutorial/stress"
+)
+
+// TestStress mixes every operation on a cache bounded by entries and
+// bytes, with entries expiring as other goroutines move the clock on,
+// then checks that the bounds and the lookup counts held.
+func TestStress(t *testing.T) {
+	clk := clock.NewFake(time.Unix(1000, 0))
+	c := New[string](Config{MaxEntries: 64, MaxBytes: 4096, TTL: time.Second, Clock: clk},
+		func(v []byte) int64 { return int64(len(v)) })
+	reg := metrics.NewRegistry()
+	c.Register(reg, "stress_cache")
+	var gets atomic.Uint64
+	stress.Run(t, stress.Config{}, func(g, i int) {
+		key := strconv.Itoa((g*7 + i) % 100)
+		switch i % 10 {
+		case 0, 1, 2:
+			c.Set(key, make([]byte, i%128))
+		case 3:
+			c.SetWithTTL(key, []byte(key), time.Duration(i%3)*time.Second)
+		case 4:
+			c.Delete(key)
+		case 5:
+			clk.Advance(100 * time.Millisecond)
+		case 6:
+			if c.Len() > 64 || c.Bytes() > 4096 {
+				t.Errorf("%d entries of %d bytes over the bounds", c.Len(), c.Bytes())
+			}
+			if i%100 == 6 {
+				reg.WriteText(io.Discard)
+			}
+		case 7:
+			if g == 0 && i%500 == 7 {
+				c.Purge()
+			}
+			c.Stats()
+		default:
+			gets.Add(1)
+			if v, ok := c.Get(key); ok && len(v) > 128 {
+				t.Errorf("Get(%s) = %d bytes", key, len(v))
+			}
+		}
+	})
+	if s := c.Stats(); s.Hits+s.Misses != gets.Load() {
+		t.Fatalf("%d hits and %d misses for %d lookups", s.Hits, s.Misses, gets.Load())
+	}
+	if c.Len() > 64 || c.Bytes() > 4096 {
+		t.Fatalf("%d entries of %d bytes over the bounds", c.Len(), c.Bytes())
+	}
+}
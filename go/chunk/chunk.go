@@ -0,0 +1,206 @@
+// Package chunk splits files into fixed-size chunks, each with its own
+// SHA-256, described by a manifest, and reassembles them, verifying
+// every chunk and the whole. A transfer can then resend only the chunks
+// that are missing or damaged.
+package chunk
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"example.com/tutorial/fileutil"
+)
+
+// DefaultSize is the chunk size used when none is given.
+const DefaultSize = 1 << 20
+
+// ManifestFile is the name of the manifest SplitFile writes next to the
+// chunks.
+const ManifestFile = "manifest.json"
+
+// ErrCorrupt is wrapped by the errors reporting data that does not
+// match its manifest.
+var ErrCorrupt = errors.New("chunk: data does not match the manifest")
+
+// Chunk describes one piece of a file.
+type Chunk struct {
+	Index  int    `json:"index"`
+	Offset int64  `json:"offset"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest describes a file split into chunks. Every chunk but the last
+// is ChunkSize bytes long.
+type Manifest struct {
+	Name      string  `json:"name"`
+	Size      int64   `json:"size"`
+	ChunkSize int     `json:"chunk_size"`
+	SHA256    string  `json:"sha256"` // of the whole file
+	Chunks    []Chunk `json:"chunks"`
+}
+
+// Split reads r to the end in chunks of size bytes, or DefaultSize if
+// size is not positive, and calls emit with each chunk and its data,
+// which is only valid during the call. It returns the manifest of what
+// it read, without a Name.
+func Split(r io.Reader, size int, emit func(c Chunk, data []byte) error) (*Manifest, error) {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	m := &Manifest{ChunkSize: size, Chunks: []Chunk{}}
+	whole := sha256.New()
+	buf := make([]byte, size)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			data := buf[:n]
+			sum := sha256.Sum256(data)
+			c := Chunk{Index: len(m.Chunks), Offset: m.Size, Size: n, SHA256: hex.EncodeToString(sum[:])}
+			if err := emit(c, data); err != nil {
+				return nil, err
+			}
+			whole.Write(data)
+			m.Chunks = append(m.Chunks, c)
+			m.Size += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	m.SHA256 = hex.EncodeToString(whole.Sum(nil))
+	return m, nil
+}
+
+// chunkPath names the file holding c below dir: its hash, so identical
+// chunks share a file.
+func chunkPath(dir string, c Chunk) string {
+	return filepath.Join(dir, c.SHA256)
+}
+
+// SplitFile splits the file at path into chunks written to dir, which
+// is created if needed, along with the manifest as ManifestFile.
+func SplitFile(path, dir string, size int) (*Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	m, err := Split(f, size, func(c Chunk, data []byte) error {
+		return fileutil.WriteAtomic(chunkPath(dir, c), data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.Name = filepath.Base(path)
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := fileutil.WriteAtomic(filepath.Join(dir, ManifestFile), append(data, '\n')); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ReadManifest reads the manifest SplitFile wrote to dir. Chunk hashes
+// name files, so ones that are not hex SHA-256 digests are refused.
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("chunk: read manifest: %w", err)
+	}
+	for _, c := range m.Chunks {
+		if b, err := hex.DecodeString(c.SHA256); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("%w: chunk %d has hash %q", ErrCorrupt, c.Index, c.SHA256)
+		}
+	}
+	return &m, nil
+}
+
+// verify checks data against c.
+func verify(c Chunk, data []byte) error {
+	sum := sha256.Sum256(data)
+	if len(data) != c.Size || hex.EncodeToString(sum[:]) != c.SHA256 {
+		return fmt.Errorf("%w: chunk %d", ErrCorrupt, c.Index)
+	}
+	return nil
+}
+
+// Assemble writes the file described by m to w, reading each chunk with
+// load. It verifies every chunk before writing it and the whole file at
+// the end, failing with an error wrapping ErrCorrupt at the first
+// mismatch; w then holds the chunks verified so far.
+func Assemble(w io.Writer, m *Manifest, load func(c Chunk) ([]byte, error)) error {
+	whole := sha256.New()
+	var offset int64
+	for i, c := range m.Chunks {
+		if c.Index != i || c.Offset != offset {
+			return fmt.Errorf("%w: chunk %d out of place", ErrCorrupt, i)
+		}
+		data, err := load(c)
+		if err != nil {
+			return err
+		}
+		if err := verify(c, data); err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+		whole.Write(data)
+		offset += int64(c.Size)
+	}
+	if offset != m.Size || hex.EncodeToString(whole.Sum(nil)) != m.SHA256 {
+		return fmt.Errorf("%w: file checksum", ErrCorrupt)
+	}
+	return nil
+}
+
+// AssembleFile rebuilds the file split into dir by SplitFile and writes
+// it to path, streaming the chunks through fileutil.WriteAtomicReader,
+// so the file at path is replaced only if every check passes.
+func AssembleFile(dir, path string) error {
+	m, err := ReadManifest(dir)
+	if err != nil {
+		return err
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(Assemble(pw, m, func(c Chunk) ([]byte, error) {
+			return os.ReadFile(chunkPath(dir, c))
+		}))
+	}()
+	err = fileutil.WriteAtomicReader(path, pr)
+	pr.CloseWithError(err) // unblocks Assemble if the write failed
+	return err
+}
+
+// Missing returns the indexes of the chunks of m that dir lacks or holds
+// damaged, in order: the ones a resumed transfer still has to send.
+func (m *Manifest) Missing(dir string) []int {
+	var missing []int
+	for _, c := range m.Chunks {
+		data, err := os.ReadFile(chunkPath(dir, c))
+		if err != nil || verify(c, data) != nil {
+			missing = append(missing, c.Index)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,119 @@
+package chunk
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	data := []byte("abcdefghij")
+	var pieces []string
+	m, err := Split(bytes.NewReader(data), 4, func(c Chunk, b []byte) error {
+		pieces = append(pieces, string(b))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pieces, []string{"abcd", "efgh", "ij"}) {
+		t.Fatalf("pieces %q", pieces)
+	}
+	if m.Size != 10 || m.ChunkSize != 4 || len(m.Chunks) != 3 || m.Chunks[2].Offset != 8 || m.Chunks[2].Size != 2 {
+		t.Fatalf("manifest %+v", m)
+	}
+
+	// Exact multiples and empty input.
+	if m, _ := Split(bytes.NewReader(data[:8]), 4, func(Chunk, []byte) error { return nil }); len(m.Chunks) != 2 {
+		t.Fatalf("8 bytes in %d chunks", len(m.Chunks))
+	}
+	if m, _ := Split(bytes.NewReader(nil), 4, func(Chunk, []byte) error { return nil }); len(m.Chunks) != 0 || m.Size != 0 {
+		t.Fatalf("empty input: %+v", m)
+	}
+	boom := errors.New("boom")
+	if _, err := Split(bytes.NewReader(data), 4, func(Chunk, []byte) error { return boom }); err != boom {
+		t.Fatalf("emit error: %v", err)
+	}
+}
+
+func TestAssemble(t *testing.T) {
+	data := []byte("the quick brown fox jumps")
+	chunks := map[int][]byte{}
+	m, _ := Split(bytes.NewReader(data), 7, func(c Chunk, b []byte) error {
+		chunks[c.Index] = append([]byte(nil), b...)
+		return nil
+	})
+	load := func(c Chunk) ([]byte, error) { return chunks[c.Index], nil }
+	var out bytes.Buffer
+	if err := Assemble(&out, m, load); err != nil || out.String() != string(data) {
+		t.Fatalf("Assemble = %q, %v", out.String(), err)
+	}
+
+	chunks[1] = []byte("QUICK b")
+	if err := Assemble(&bytes.Buffer{}, m, load); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("corrupt chunk: %v", err)
+	}
+	chunks[1] = []byte("quick b")
+	m.Chunks[0], m.Chunks[1] = m.Chunks[1], m.Chunks[0]
+	if err := Assemble(&bytes.Buffer{}, m, load); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("reordered chunks: %v", err)
+	}
+}
+
+func TestSplitFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "big.bin")
+	data := make([]byte, 10_000)
+	rand.New(rand.NewSource(1)).Read(data)
+	copy(data[4096:], data[:1024]) // a repeated chunk shares a file
+	os.WriteFile(src, data, 0o644)
+
+	chunks := filepath.Join(dir, "chunks")
+	m, err := SplitFile(src, chunks, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Name != "big.bin" || len(m.Chunks) != 10 {
+		t.Fatalf("manifest %+v", m)
+	}
+	if entries, _ := os.ReadDir(chunks); len(entries) != 10 { // 9 distinct chunks and the manifest
+		t.Fatalf("%d files in the chunk directory", len(entries))
+	}
+	if got, err := ReadManifest(chunks); err != nil || !reflect.DeepEqual(got, m) {
+		t.Fatalf("ReadManifest = %+v, %v", got, err)
+	}
+	if missing := m.Missing(chunks); missing != nil {
+		t.Fatalf("missing %v", missing)
+	}
+
+	dest := filepath.Join(dir, "copy.bin")
+	if err := AssembleFile(chunks, dest); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, data) {
+		t.Fatal("reassembled file differs")
+	}
+
+	// Damage one chunk and lose another: both need resending, and the
+	// existing copy is left alone.
+	os.WriteFile(filepath.Join(chunks, m.Chunks[2].SHA256), []byte("junk"), 0o644)
+	os.Remove(filepath.Join(chunks, m.Chunks[9].SHA256))
+	if missing := m.Missing(chunks); !reflect.DeepEqual(missing, []int{2, 9}) {
+		t.Fatalf("missing %v", missing)
+	}
+	if err := AssembleFile(chunks, dest); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("AssembleFile with a damaged chunk: %v", err)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, data) {
+		t.Fatal("failed reassembly changed the destination")
+	}
+
+	os.WriteFile(filepath.Join(chunks, ManifestFile), []byte(`{"chunks":[{"sha256":"../big.bin"}]}`), 0o644)
+	if _, err := ReadManifest(chunks); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("manifest with a path for a hash: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+// Package clock abstracts the passing of time, so that code waiting on
+// timers can be tested without waiting. Production code takes a Clock
+// and uses System; tests pass a Fake and move its time by hand:
+//
+//	c := clock.NewFake(time.Unix(0, 0))
+//	go worker(c) // waits with c.Sleep
+//	c.BlockUntil(1)
+//	c.Advance(time.Minute)
+package clock
+
+import (
+	"context"
+	"time"
+)
+
+// Clock tells the time and makes timers that fire as it passes.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After returns a channel that receives the time once d has passed.
+	After(d time.Duration) <-chan time.Time
+	// NewTimer returns a timer that fires once d has passed.
+	NewTimer(d time.Duration) Timer
+	// Sleep waits for d to pass, or for ctx to be done, in which case it
+	// returns ctx.Err().
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// Timer is the part of a time.Timer that Clock users need.
+type Timer interface {
+	// C returns the channel the timer sends the time on when it fires.
+	C() <-chan time.Time
+	// Stop keeps the timer from firing and reports whether it was still
+	// pending.
+	Stop() bool
+	// Reset makes the timer fire after d from now and reports whether it
+	// was still pending.
+	Reset(d time.Duration) bool
+}
+
+// System is the real clock, of the time package.
+var System Clock = systemClock{}
+
+// OrSystem returns c, or System if c is nil, for Config fields where nil
+// means the real clock.
+func OrSystem(c Clock) Clock {
+	if c == nil {
+		return System
+	}
+	return c
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (systemClock) NewTimer(d time.Duration) Timer         { return systemTimer{time.NewTimer(d)} }
+
+func (systemClock) Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type systemTimer struct{ t *time.Timer }
+
+func (t systemTimer) C() <-chan time.Time        { return t.t.C }
+func (t systemTimer) Stop() bool                 { return t.t.Stop() }
+func (t systemTimer) Reset(d time.Duration) bool { return t.t.Reset(d) }
@@ -0,0 +1,122 @@
+package clock
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var start = time.Unix(1000, 0)
+
+func TestFakeTimersFireInOrder(t *testing.T) {
+	f := NewFake(start)
+	late := f.NewTimer(2 * time.Second)
+	early := f.NewTimer(time.Second)
+	if n := f.Pending(); n != 2 {
+		t.Fatalf("Pending = %d", n)
+	}
+	f.Advance(999 * time.Millisecond)
+	select {
+	case <-early.C():
+		t.Fatal("fired early")
+	default:
+	}
+	f.Advance(5 * time.Second)
+	if got := <-early.C(); !got.Equal(start.Add(5999 * time.Millisecond)) {
+		t.Fatalf("fired with %v", got)
+	}
+	<-late.C()
+	if f.Pending() != 0 || late.Stop() {
+		t.Fatal("fired timers still pending")
+	}
+}
+
+func TestFakeStopReset(t *testing.T) {
+	f := NewFake(start)
+	tm := f.NewTimer(time.Second)
+	if !tm.Stop() || tm.Stop() {
+		t.Fatal("Stop of a pending timer, then of a stopped one")
+	}
+	f.Advance(time.Hour)
+	select {
+	case <-tm.C():
+		t.Fatal("stopped timer fired")
+	default:
+	}
+	if tm.Reset(time.Minute) {
+		t.Fatal("Reset reported a stopped timer pending")
+	}
+	if !tm.Reset(2 * time.Minute) {
+		t.Fatal("Reset did not report the timer pending")
+	}
+	f.Advance(time.Minute)
+	if f.Pending() != 1 {
+		t.Fatal("Reset kept the earlier deadline")
+	}
+	f.Advance(time.Minute)
+	<-tm.C()
+
+	select {
+	case <-f.After(0):
+	default:
+		t.Fatal("a zero timer did not fire at once")
+	}
+}
+
+func TestFakeSleep(t *testing.T) {
+	f := NewFake(start)
+	done := make(chan error)
+	go func() { done <- f.Sleep(context.Background(), time.Minute) }()
+	f.BlockUntil(1)
+	f.Advance(time.Minute)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got := f.Now(); !got.Equal(start.Add(time.Minute)) {
+		t.Fatalf("Now = %v", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { done <- f.Sleep(ctx, time.Minute) }()
+	f.BlockUntil(1)
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Sleep = %v, want context.Canceled", err)
+	}
+	if n := f.Pending(); n != 0 {
+		t.Fatalf("canceled sleep left %d timers", n)
+	}
+}
+
+func TestFakeSetBackwards(t *testing.T) {
+	f := NewFake(start)
+	tm := f.NewTimer(time.Second)
+	f.Set(start.Add(-time.Hour))
+	if !f.Now().Equal(start) || f.Pending() != 1 {
+		t.Fatal("moving back changed the clock")
+	}
+	f.Set(start.Add(time.Second))
+	<-tm.C()
+}
+
+func TestSystem(t *testing.T) {
+	if OrSystem(nil) != System {
+		t.Fatal("OrSystem(nil) is not System")
+	}
+	if d := time.Since(System.Now()); d < 0 || d > time.Minute {
+		t.Fatalf("System.Now is %v off", d)
+	}
+	if err := System.Sleep(context.Background(), time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := System.Sleep(ctx, time.Hour); err != context.Canceled {
+		t.Fatalf("Sleep = %v", err)
+	}
+	tm := System.NewTimer(time.Hour)
+	if !tm.Stop() {
+		t.Fatal("Stop of a pending timer")
+	}
+	<-System.After(time.Millisecond)
+}
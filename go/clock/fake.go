@@ -0,0 +1,174 @@
+package clock
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fake is a Clock whose time only moves when Advance or Set is called.
+// Timers fire, in the order of their deadlines, as soon as the time
+// reaches them. It is safe for concurrent use.
+type Fake struct {
+	mu      sync.Mutex
+	changed *sync.Cond // broadcast when timers are added or removed
+	now     time.Time
+	timers  []*fakeTimer // pending, in no particular order
+}
+
+// NewFake returns a Fake clock showing start.
+func NewFake(start time.Time) *Fake {
+	f := &Fake{now: start}
+	f.changed = sync.NewCond(&f.mu)
+	return f
+}
+
+// Now returns the fake time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// After returns the channel of a new timer.
+func (f *Fake) After(d time.Duration) <-chan time.Time { return f.NewTimer(d).C() }
+
+// NewTimer returns a timer that fires when the time has moved on by d;
+// one with d <= 0 fires at once.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{f: f, c: make(chan time.Time, 1)}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.schedule(t, d)
+	return t
+}
+
+// Sleep waits until the time has moved on by d, or until ctx is done.
+func (f *Fake) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	t := f.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Advance moves the time on by d, firing the timers it reaches.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.set(f.now.Add(d))
+}
+
+// Set moves the time to t, firing the timers it reaches. Moving it back
+// fires nothing.
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.set(t)
+}
+
+// Pending returns the number of timers waiting to fire, sleepers
+// included.
+func (f *Fake) Pending() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.timers)
+}
+
+// BlockUntil waits until at least n timers are pending. Tests call it
+// before Advance, to be sure that the code under test has started the
+// wait that Advance is meant to end.
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.timers) < n {
+		f.changed.Wait()
+	}
+}
+
+// schedule makes t fire after d; f.mu must be held.
+func (f *Fake) schedule(t *fakeTimer, d time.Duration) {
+	t.when = f.now.Add(d)
+	if d <= 0 {
+		t.fire(f.now)
+		return
+	}
+	f.timers = append(f.timers, t)
+	f.changed.Broadcast()
+}
+
+// set moves the time to now and fires the timers due by then; f.mu must
+// be held.
+func (f *Fake) set(now time.Time) {
+	if now.After(f.now) {
+		f.now = now
+	}
+	var due, rest []*fakeTimer
+	for _, t := range f.timers {
+		if t.when.After(f.now) {
+			rest = append(rest, t)
+		} else {
+			due = append(due, t)
+		}
+	}
+	if len(due) == 0 {
+		return
+	}
+	f.timers = rest
+	sort.SliceStable(due, func(i, j int) bool { return due[i].when.Before(due[j].when) })
+	for _, t := range due {
+		t.fire(f.now)
+	}
+	f.changed.Broadcast()
+}
+
+// remove drops t from the pending timers and reports whether it was
+// there; f.mu must be held.
+func (f *Fake) remove(t *fakeTimer) bool {
+	for i, p := range f.timers {
+		if p == t {
+			f.timers = append(f.timers[:i], f.timers[i+1:]...)
+			f.changed.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	f    *Fake
+	c    chan time.Time
+	when time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	return t.f.remove(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	pending := t.f.remove(t)
+	t.f.schedule(t, d)
+	return pending
+}
+
+// fire sends now without blocking; like a time.Timer's, the channel
+// holds one value, and a value nobody received is dropped.
+func (t *fakeTimer) fire(now time.Time) {
+	select {
+	case t.c <- now:
+	default:
+	}
+}
@@ -0,0 +1,106 @@
+package codec
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"example.com/tutorial/text"
+)
+
+// binaryVersion is the first byte of the binary format.
+const binaryVersion = 1
+
+// ErrCorrupt is returned when binary data is truncated or malformed.
+var ErrCorrupt = errors.New("codec: corrupt binary data")
+
+// binaryCodec is the hand-rolled format: a version byte, then as
+// unsigned varints the words, the unique words and the number of top
+// words, then for each the length of the word, its bytes and its count.
+// Counts are never negative, so unsigned varints hold them all.
+type binaryCodec struct{}
+
+func (binaryCodec) Name() string { return "binary" }
+
+func (binaryCodec) Marshal(s text.Summary) ([]byte, error) {
+	if s.Words < 0 || s.Unique < 0 {
+		return nil, errors.New("codec: binary: negative count")
+	}
+	size := 1 + 3*binary.MaxVarintLen64
+	for _, wf := range s.Top {
+		size += len(wf.Word) + 2*binary.MaxVarintLen64
+	}
+	b := make([]byte, 0, size)
+	b = append(b, binaryVersion)
+	b = binary.AppendUvarint(b, uint64(s.Words))
+	b = binary.AppendUvarint(b, uint64(s.Unique))
+	b = binary.AppendUvarint(b, uint64(len(s.Top)))
+	for _, wf := range s.Top {
+		if wf.Count < 0 {
+			return nil, fmt.Errorf("codec: binary: negative count of %q", wf.Word)
+		}
+		b = binary.AppendUvarint(b, uint64(len(wf.Word)))
+		b = append(b, wf.Word...)
+		b = binary.AppendUvarint(b, uint64(wf.Count))
+	}
+	return b, nil
+}
+
+func (binaryCodec) Unmarshal(data []byte) (text.Summary, error) {
+	if len(data) == 0 || data[0] != binaryVersion {
+		return text.Summary{}, fmt.Errorf("%w: not version %d", ErrCorrupt, binaryVersion)
+	}
+	r := reader{data: data[1:]}
+	s := text.Summary{Words: r.int(), Unique: r.int()}
+	// Each word takes at least two bytes, which bounds what a corrupt
+	// count can make Unmarshal allocate.
+	if n := r.int(); n > 0 && n <= len(r.data)/2 {
+		s.Top = make([]text.WordFreq, n)
+		for i := range s.Top {
+			s.Top[i] = text.WordFreq{Word: string(r.bytes(r.int())), Count: r.int()}
+		}
+	} else if n != 0 {
+		r.err = ErrCorrupt
+	}
+	if r.err == nil && len(r.data) > 0 {
+		r.err = fmt.Errorf("%w: %d bytes after the data", ErrCorrupt, len(r.data))
+	}
+	if r.err != nil {
+		return text.Summary{}, r.err
+	}
+	return s, nil
+}
+
+// reader reads the fields of the binary format, remembering the first
+// error, after which it returns zeros.
+type reader struct {
+	data []byte
+	err  error
+}
+
+// int reads an unsigned varint that must fit in an int.
+func (r *reader) int() int {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 || v > uint64(int(^uint(0)>>1)) {
+		r.err = ErrCorrupt
+		return 0
+	}
+	r.data = r.data[n:]
+	return int(v)
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > len(r.data) {
+		r.err = ErrCorrupt
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
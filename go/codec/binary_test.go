@@ -0,0 +1,41 @@
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"example.com/tutorial/text"
+)
+
+func TestBinaryFormat(t *testing.T) {
+	data, err := Binary.Marshal(text.Summary{Words: 300, Unique: 1, Top: []text.WordFreq{{Word: "go", Count: 300}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{binaryVersion, 0xac, 0x02, 1, 1, 2, 'g', 'o', 0xac, 0x02}
+	if !bytes.Equal(data, want) {
+		t.Fatalf("got % x, want % x", data, want)
+	}
+	if _, err := Binary.Marshal(text.Summary{Top: []text.WordFreq{{Word: "x", Count: -1}}}); err == nil {
+		t.Error("negative count encoded")
+	}
+}
+
+func TestBinaryCorrupt(t *testing.T) {
+	good, _ := Binary.Marshal(text.Summary{Words: 2, Unique: 2, Top: []text.WordFreq{{Word: "a", Count: 1}, {Word: "b", Count: 1}}})
+	for name, data := range map[string][]byte{
+		"empty":       nil,
+		"version":     {2, 0, 0, 0},
+		"truncated":   good[:len(good)-2],
+		"trailing":    append(append([]byte{}, good...), 0),
+		"huge count":  {binaryVersion, 0, 0, 0xff, 0xff, 0xff, 0xff, 0x0f},
+		"long word":   {binaryVersion, 0, 0, 1, 9, 'a', 0},
+		"bad varint":  {binaryVersion, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"int too big": {binaryVersion, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01, 0, 0},
+	} {
+		if _, err := Binary.Unmarshal(data); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
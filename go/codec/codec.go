@@ -0,0 +1,99 @@
+// Package codec serializes word-count summaries in one of several
+// formats behind a common interface, so that callers can pick one by
+// name and the benchmarks can compare them on the same data: JSON, as
+// the HTTP API sends it, gob, protobuf, as the gRPC services send it, and
+// a compact hand-rolled binary format.
+package codec
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"example.com/tutorial/pbcodec"
+	"example.com/tutorial/text"
+)
+
+// Codec encodes a text.Summary to bytes and decodes it back. Decoding
+// what Marshal returned gives an equal Summary, except that an empty Top
+// may come back nil. Codecs are safe for concurrent use.
+type Codec interface {
+	// Name is the name Lookup knows the codec by.
+	Name() string
+	Marshal(s text.Summary) ([]byte, error)
+	Unmarshal(data []byte) (text.Summary, error)
+}
+
+// The codecs of the package.
+var (
+	JSON   Codec = jsonCodec{}
+	Gob    Codec = gobCodec{}
+	Proto  Codec = protoCodec{}
+	Binary Codec = binaryCodec{}
+)
+
+// All lists the codecs of the package.
+var All = []Codec{JSON, Gob, Proto, Binary}
+
+// ErrUnknown is returned by Lookup for a name no codec has.
+var ErrUnknown = errors.New("codec: unknown codec")
+
+// Lookup returns the codec of All named name, ignoring case.
+func Lookup(name string) (Codec, error) {
+	for _, c := range All {
+		if strings.EqualFold(c.Name(), name) {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknown, name)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return "json" }
+
+func (jsonCodec) Marshal(s text.Summary) ([]byte, error) { return json.Marshal(s) }
+
+func (jsonCodec) Unmarshal(data []byte) (text.Summary, error) {
+	var s text.Summary
+	if err := json.Unmarshal(data, &s); err != nil {
+		return text.Summary{}, fmt.Errorf("codec: json: %w", err)
+	}
+	return s, nil
+}
+
+// gobCodec writes a self-describing gob stream per summary, type
+// information included.
+type gobCodec struct{}
+
+func (gobCodec) Name() string { return "gob" }
+
+func (gobCodec) Marshal(s text.Summary) ([]byte, error) {
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(s); err != nil {
+		return nil, fmt.Errorf("codec: gob: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte) (text.Summary, error) {
+	var s text.Summary
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&s); err != nil {
+		return text.Summary{}, fmt.Errorf("codec: gob: %w", err)
+	}
+	return s, nil
+}
+
+// protoCodec encodes a pb.WordCount.
+type protoCodec struct{}
+
+func (protoCodec) Name() string { return "proto" }
+
+func (protoCodec) Marshal(s text.Summary) ([]byte, error) { return pbcodec.MarshalSummary(s) }
+
+func (protoCodec) Unmarshal(data []byte) (text.Summary, error) {
+	return pbcodec.UnmarshalSummary(data)
+}
@@ -0,0 +1,88 @@
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"example.com/tutorial/text"
+)
+
+// dataset returns the summary of a text of n distinct words, the i-th
+// occurring n-i times.
+func dataset(n int) text.Summary {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			fmt.Fprintf(&b, "word%d ", i)
+		}
+	}
+	counts := text.WordCount(b.String())
+	s := text.Summary{Unique: len(counts), Top: text.TopWords(counts, -1)}
+	for _, c := range counts {
+		s.Words += c
+	}
+	return s
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, c := range All {
+		for _, s := range []text.Summary{dataset(50), {Words: 7, Unique: 3}, {Top: []text.WordFreq{{Word: "日本語", Count: 1}}}} {
+			data, err := c.Marshal(s)
+			if err != nil {
+				t.Fatalf("%s: %v", c.Name(), err)
+			}
+			got, err := c.Unmarshal(data)
+			if err != nil {
+				t.Fatalf("%s: %v", c.Name(), err)
+			}
+			if !reflect.DeepEqual(got, s) {
+				t.Errorf("%s: got %+v, want %+v", c.Name(), got, s)
+			}
+		}
+		if _, err := c.Unmarshal([]byte("\xff\xff\xff")); err == nil {
+			t.Errorf("%s: garbage decoded", c.Name())
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for _, c := range All {
+		if got, err := Lookup(strings.ToUpper(c.Name())); err != nil || got != c {
+			t.Errorf("Lookup(%q) = %v, %v", c.Name(), got, err)
+		}
+	}
+	if _, err := Lookup("xml"); !errors.Is(err, ErrUnknown) {
+		t.Errorf("xml: %v", err)
+	}
+}
+
+// BenchmarkCodecs round-trips the same summary of 1000 words through
+// every codec, reporting the size of its encoding.
+func BenchmarkCodecs(b *testing.B) {
+	s := dataset(1000)
+	for _, c := range All {
+		data, err := c.Marshal(s)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(c.Name()+"/marshal", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				c.Marshal(s)
+			}
+			b.ReportMetric(float64(len(data)), "payload-bytes")
+		})
+		b.Run(c.Name()+"/unmarshal", func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				if _, err := c.Unmarshal(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
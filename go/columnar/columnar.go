@@ -0,0 +1,183 @@
+// Package columnar exports word-frequency tables in layouts that
+// analytics tools load as a dataset: CSV with typed headers, or Parquet,
+// with the rows of each table sorted and the tables partitioned by the
+// file they came from.
+//
+// Export writes one file per table into a Hive-style partition
+// directory, so that DuckDB, Spark, Athena or pandas read the directory
+// as one table with a source column:
+//
+//	out/source=README.md/part-00000.parquet
+//	out/source=docs%2Fintro.md/part-00000.parquet
+//
+// The partition column is in the directory name only, as the layout
+// has it. Within a file the rows are ordered by descending count, then
+// by word, and ranked from 1.
+package columnar
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"example.com/tutorial/fileutil"
+	"example.com/tutorial/sliceutil"
+	"example.com/tutorial/text"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Format is the file format of an export.
+type Format string
+
+const (
+	// CSV is RFC 4180 CSV whose header gives each column a type, as
+	// "word:string,count:int64,rank:int32".
+	CSV Format = "csv"
+	// Parquet is Apache Parquet, one row group per file, compressed
+	// with Snappy.
+	Parquet Format = "parquet"
+)
+
+// Formats lists the supported formats.
+var Formats = []Format{CSV, Parquet}
+
+// ParseFormat converts a format name such as a flag value into a Format.
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("columnar: unknown format %q (want csv or parquet)", s)
+}
+
+// PartitionColumn names the column the partition directories hold.
+const PartitionColumn = "source"
+
+// Row is a row of an exported table.
+type Row struct {
+	Word  string `parquet:"word"`
+	Count int64  `parquet:"count"`
+	Rank  int32  `parquet:"rank"`
+}
+
+// header is the CSV header of Row's columns.
+var header = []string{"word:string", "count:int64", "rank:int32"}
+
+// Table is the frequency table of one source file.
+type Table struct {
+	Source string
+	Freqs  []text.WordFreq
+}
+
+// Rows returns the rows of freqs sorted by descending count, then by
+// word, and ranked.
+func Rows(freqs []text.WordFreq) []Row {
+	rows := sliceutil.Map(freqs, func(f text.WordFreq) Row { return Row{Word: f.Word, Count: int64(f.Count)} })
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Count != rows[j].Count {
+			return rows[i].Count > rows[j].Count
+		}
+		return rows[i].Word < rows[j].Word
+	})
+	for i := range rows {
+		rows[i].Rank = int32(i + 1)
+	}
+	return rows
+}
+
+// WriteCSV writes freqs to w as CSV sorted rows under a typed header.
+func WriteCSV(w io.Writer, freqs []text.WordFreq) error {
+	cw := csv.NewWriter(w)
+	cw.Write(header)
+	for _, r := range Rows(freqs) {
+		cw.Write([]string{r.Word, strconv.FormatInt(r.Count, 10), strconv.Itoa(int(r.Rank))})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteParquet writes freqs to w as a Parquet file of sorted rows.
+func WriteParquet(w io.Writer, freqs []text.WordFreq) error {
+	return parquet.Write(w, Rows(freqs), parquet.Compression(&parquet.Snappy))
+}
+
+// Write writes freqs to w in format f.
+func Write(w io.Writer, freqs []text.WordFreq, f Format) error {
+	switch f {
+	case CSV:
+		return WriteCSV(w, freqs)
+	case Parquet:
+		return WriteParquet(w, freqs)
+	}
+	return fmt.Errorf("columnar: unknown format %q", f)
+}
+
+// PartitionPath returns the slash-separated path, relative to the
+// export directory, of the file of the table of source in format f.
+func PartitionPath(source string, f Format) string {
+	return path.Join(PartitionColumn+"="+escapePartition(source), "part-00000."+string(f))
+}
+
+// escapePartition percent-encodes what may not appear in a partition
+// directory's value, as Hive does: the path separators, "=", "%" and
+// control and other unsafe characters.
+func escapePartition(s string) string {
+	if s == "" {
+		return "__HIVE_DEFAULT_PARTITION__"
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c == 0x7f || strings.IndexByte(`"#%'*/:=?\{[]^`, c) >= 0 {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// Config configures Export.
+type Config struct {
+	// Format is the format of the files; empty means CSV.
+	Format Format
+	// Files makes the directories and files, or in a dry run reports
+	// them. Nil writes them.
+	Files *fileutil.Executor
+}
+
+// Export writes each table to its partition of the directory dir, made
+// if missing, and returns the paths it wrote. Tables of the same source
+// are written to the same file, the last one winning.
+func Export(dir string, tables []Table, cfg Config) ([]string, error) {
+	f := cfg.Format
+	if f == "" {
+		f = CSV
+	}
+	if _, err := ParseFormat(string(f)); err != nil {
+		return nil, err
+	}
+	var written []string
+	for _, t := range tables {
+		p := filepath.Join(dir, filepath.FromSlash(PartitionPath(t.Source, f)))
+		if err := cfg.Files.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			return written, fmt.Errorf("columnar: %w", err)
+		}
+		pr, pw := io.Pipe()
+		go func() { pw.CloseWithError(Write(pw, t.Freqs, f)) }()
+		err := cfg.Files.WriteAtomicReader(p, pr)
+		pr.CloseWithError(err) // stop the encoder if writing failed
+		if err != nil {
+			return written, fmt.Errorf("columnar: %s: %w", t.Source, err)
+		}
+		written = append(written, p)
+	}
+	return written, nil
+}
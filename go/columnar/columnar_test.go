@@ -0,0 +1,114 @@
+package columnar
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"example.com/tutorial/fileutil"
+	"example.com/tutorial/text"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+var freqs = []text.WordFreq{{Word: "cat", Count: 2}, {Word: "the", Count: 3}, {Word: "a", Count: 2}, {Word: "hat", Count: 1}}
+
+func TestRows(t *testing.T) {
+	want := []Row{{"the", 3, 1}, {"a", 2, 2}, {"cat", 2, 3}, {"hat", 1, 4}}
+	if got := Rows(freqs); !reflect.DeepEqual(got, want) {
+		t.Errorf("Rows = %v, want %v", got, want)
+	}
+	if freqs[0].Word != "cat" {
+		t.Error("Rows reordered its argument")
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteCSV(&out, freqs); err != nil {
+		t.Fatal(err)
+	}
+	want := "word:string,count:int64,rank:int32\nthe,3,1\na,2,2\ncat,2,3\nhat,1,4\n"
+	if out.String() != want {
+		t.Errorf("CSV:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestWriteParquet(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteParquet(&out, freqs); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := parquet.Read[Row](bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rows, Rows(freqs)) {
+		t.Errorf("read back %v", rows)
+	}
+	f, err := parquet.OpenFile(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.Schema().String(); !strings.Contains(got, "required binary word (STRING)") || !strings.Contains(got, "required int64 count") || !strings.Contains(got, "required int32 rank") {
+		t.Errorf("schema:\n%s", got)
+	}
+}
+
+func TestPartitionPath(t *testing.T) {
+	for source, want := range map[string]string{
+		"README.md":       "source=README.md/part-00000.csv",
+		"docs/intro.md":   "source=docs%2Fintro.md/part-00000.csv",
+		"a=b%c.txt":       "source=a%3Db%25c.txt/part-00000.csv",
+		"x.tgz:in/ä.txt":  "source=x.tgz%3Ain%2Fä.txt/part-00000.csv",
+		"":                "source=__HIVE_DEFAULT_PARTITION__/part-00000.csv",
+		"line\nbreak.txt": "source=line%0Abreak.txt/part-00000.csv",
+	} {
+		if got := PartitionPath(source, CSV); got != want {
+			t.Errorf("PartitionPath(%q) = %q, want %q", source, got, want)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	tables := []Table{{"a.txt", freqs}, {"docs/b.txt", freqs[:1]}}
+	files, err := Export(dir, tables, Config{Format: Parquet})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(dir, "source=a.txt", "part-00000.parquet"),
+		filepath.Join(dir, "source=docs%2Fb.txt", "part-00000.parquet"),
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("files %q, want %q", files, want)
+	}
+	rows, err := parquet.ReadFile[Row](want[1])
+	if err != nil || len(rows) != 1 || rows[0] != (Row{"cat", 2, 1}) {
+		t.Errorf("b.txt: %v, %v", rows, err)
+	}
+
+	if _, err := Export(dir, tables, Config{Format: "xlsx"}); err == nil {
+		t.Error("unknown format exported")
+	}
+}
+
+func TestExportDryRun(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	var log bytes.Buffer
+	files, err := Export(dir, []Table{{"a.txt", freqs}}, Config{Files: &fileutil.Executor{DryRun: true, Log: &log}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(dir, "source=a.txt", "part-00000.csv")
+	if len(files) != 1 || files[0] != p || !strings.Contains(log.String(), "would write "+p) {
+		t.Errorf("files %q, log:\n%s", files, log.String())
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("dry run made %s", dir)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"example.com/tutorial/analysis"
+	"example.com/tutorial/output"
+	"example.com/tutorial/text"
+)
+
+// runCompare implements the "compare" subcommand: it prints how the word
+// frequencies of after differ from those of before, each either a
+// count result, as wordcount -top writes in any -format or POST
+// /wordcount answers, or a directory whose files it counts. The changes
+// come largest first; -top keeps the first n.
+func runCompare(ctx context.Context, cfg compareSettings, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: compare [-top n] [-format f] [-analyzer name] before after")
+		fs.PrintDefaults()
+	}
+	top := fs.Int("top", cfg.Top, "list the `n` largest changes (-1 for all)")
+	format := fs.String("format", cfg.Format, "output `format`: text, json or csv")
+	analyzer := fs.String("analyzer", cfg.Analyzer, "split the words of directories with the analyzer called `name`: "+strings.Join(analysis.Names(), ", "))
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 || *top < -1 {
+		fs.Usage()
+		return 2
+	}
+	f, err := text.ParseFormat(*format)
+	if err != nil {
+		fmt.Fprintln(stderr, "compare:", err)
+		return 2
+	}
+	var opts []text.Option
+	if *analyzer != "" {
+		a, err := analysis.Lookup(*analyzer)
+		if err != nil {
+			fmt.Fprintln(stderr, "compare:", err)
+			return 2
+		}
+		opts = append(opts, text.WithAnalyzer(a))
+	}
+	var sides [2]text.Summary
+	for i, name := range fs.Args() {
+		if sides[i], err = loadCounts(ctx, name, opts); err != nil {
+			fmt.Fprintf(stderr, "compare: %s: %v\n", name, err)
+			return 1
+		}
+	}
+	d := text.DiffSummaries(sides[0], sides[1])
+	if *top >= 0 && *top < len(d.Changes) {
+		d.Changes = d.Changes[:*top]
+	}
+	if f != text.FormatText {
+		err = text.EncodeDiff(stdout, d, f)
+	} else {
+		var b strings.Builder
+		text.EncodeDiff(&b, d, f)
+		err = output.For(stdout).WriteTable(b.String())
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "compare:", err)
+		return 1
+	}
+	return 0
+}
+
+// loadCounts returns the word counts of name: those of the files below
+// it, counted with opts, if it is a directory, or else the count result
+// it holds.
+func loadCounts(ctx context.Context, name string, opts []text.Option) (text.Summary, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return text.Summary{}, err
+	}
+	if !info.IsDir() {
+		f, err := os.Open(name)
+		if err != nil {
+			return text.Summary{}, err
+		}
+		defer f.Close()
+		return text.DecodeFrequencies(f)
+	}
+	var paths []string
+	err = filepath.WalkDir(name, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			paths = append(paths, path)
+		}
+		return err
+	})
+	if err != nil {
+		return text.Summary{}, err
+	}
+	counts, err := text.CountFiles(ctx, paths, runtime.GOMAXPROCS(0), opts...)
+	if err != nil {
+		return text.Summary{}, err
+	}
+	s := text.Summary{Unique: len(counts), Top: text.TopWords(counts, -1)}
+	for _, n := range counts {
+		s.Words += n
+	}
+	return s, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"example.com/tutorial/text"
+)
+
+func TestRunCompare(t *testing.T) {
+	before := writeTree(t, map[string]string{"a.txt": "go go gopher", "sub/b.txt": "rust"})
+	after := writeTree(t, map[string]string{"a.txt": "go go go go gopher", "c.txt": "zig zig"})
+	run := func(args ...string) (string, int) {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		code := runCompare(context.Background(), defaultSettings().Compare, args, &stdout, &stderr)
+		return stdout.String() + stderr.String(), code
+	}
+
+	out, code := run(before, after)
+	want := "" +
+		"WORD  CHANGE   BEFORE  AFTER  DELTA  RELATIVE\n" +
+		"go    changed       2      4     +2   +100.0%\n" +
+		"zig   added         0      2     +2       new\n" +
+		"rust  removed       1      0     -1   -100.0%\n" +
+		"\n" +
+		"words 4 -> 7, unique 3 -> 3: 1 added, 1 removed, 1 changed, 1 unchanged\n"
+	if code != 0 || out != want {
+		t.Fatalf("exit %d:\n%s", code, out)
+	}
+
+	out, code = run("-top", "1", "-format", "json", before, after)
+	var d text.CountDiff
+	if err := json.Unmarshal([]byte(out), &d); code != 0 || err != nil || len(d.Changes) != 1 || d.Changes[0].Word != "go" || d.WordsAfter != 7 {
+		t.Fatalf("json: exit %d: %s", code, out)
+	}
+
+	// A count result written by wordcount -top compares with a directory.
+	var counts bytes.Buffer
+	if code := runWordCount(context.Background(), defaultSettings().WordCount, []string{"-top", "-1", "-format", "csv", filepath.Join(before, "a.txt")}, nil, &counts, &bytes.Buffer{}); code != 0 {
+		t.Fatalf("wordcount: exit %d", code)
+	}
+	saved := writeTree(t, map[string]string{"before.csv": counts.String()})
+	out, code = run("-format", "csv", filepath.Join(saved, "before.csv"), after)
+	if code != 0 || out != "word,change,before,after,delta,relative\ngo,changed,2,4,2,1\nzig,added,0,2,2,\n" {
+		t.Fatalf("from a count result: exit %d:\n%s", code, out)
+	}
+
+	if out, code := run(before, filepath.Join(before, "a.txt")); code != 1 || !strings.Contains(out, "not a frequency table") {
+		t.Errorf("text file as a count result: exit %d: %s", code, out)
+	}
+	if out, code := run(before, filepath.Join(before, "missing")); code != 1 || !strings.Contains(out, "missing") {
+		t.Errorf("missing file: exit %d: %s", code, out)
+	}
+}
+
+func TestRunCompareUsage(t *testing.T) {
+	for _, args := range [][]string{nil, {"a"}, {"a", "b", "c"}, {"-format", "xml", "a", "b"}, {"-top", "-2", "a", "b"}, {"-analyzer", "bogus", "a", "b"}} {
+		var stdout, stderr bytes.Buffer
+		if code := runCompare(context.Background(), defaultSettings().Compare, args, &stdout, &stderr); code != 2 {
+			t.Errorf("%q: exit %d, want 2", args, code)
+		}
+	}
+}
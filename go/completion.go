@@ -0,0 +1,389 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// runCompletion implements the "completion" subcommand: it prints a
+// script that completes the commands, their flags and the values of
+// file and directory flags in the given shell. The script is generated
+// from the usage messages of the commands, so it stays current as they
+// change.
+func runCompletion(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("completion", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: completion bash|zsh|fish")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	write := map[string]func(io.Writer, cmdSpec, []cmdSpec){
+		"bash": writeBashCompletion,
+		"zsh":  writeZshCompletion,
+		"fish": writeFishCompletion,
+	}[fs.Arg(0)]
+	if write == nil {
+		fmt.Fprintf(stderr, "completion: unknown shell %q\n", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+	global, cmds := commandSpecs(ctx)
+	write(stdout, global, cmds)
+	return 0
+}
+
+// cmdSpec is what a completion script knows of a command.
+type cmdSpec struct {
+	name, summary string
+	flags         []flagSpec
+	// actions are the words that may follow the flags, as list and add
+	// follow those of users.
+	actions []string
+	// files reports whether the command takes arguments other than
+	// actions, which are completed as file names.
+	files bool
+}
+
+// flagSpec is a flag as flag.PrintDefaults describes it.
+type flagSpec struct {
+	name  string
+	arg   string // the name of its value, empty for a boolean flag
+	usage string // the first line of its usage
+}
+
+// completes returns what the value of f names: "file", "dir", or ""
+// for a value that is not completed.
+func (f flagSpec) completes() string {
+	switch f.arg {
+	case "file", "path":
+		return "file"
+	case "dir", "directory":
+		return "dir"
+	}
+	return ""
+}
+
+// repeatable reports whether f may be given more than once.
+func (f flagSpec) repeatable() bool { return strings.Contains(f.usage, "(repeatable)") }
+
+// commandSpecs returns the specs of the global flags, as a command with
+// an empty name, and of the commands, including help. It reads them from
+// the usage each command prints on -help.
+func commandSpecs(ctx context.Context) (cmdSpec, []cmdSpec) {
+	s := defaultSettings()
+	fs := flag.NewFlagSet(progName, flag.ContinueOnError)
+	new(globalFlags).register(fs, &s)
+	var defaults bytes.Buffer
+	fs.SetOutput(&defaults)
+	fs.PrintDefaults()
+	global := cmdSpec{flags: parseFlagDefaults(defaults.String())}
+
+	var cmds []cmdSpec
+	var names []string
+	for _, c := range commands() {
+		var usage bytes.Buffer
+		s := defaultSettings()
+		c.run(ctx, &s, []string{"-help"}, strings.NewReader(""), io.Discard, &usage)
+		spec := parseUsage(c.name, usage.String())
+		spec.summary = c.summary
+		cmds = append(cmds, spec)
+		names = append(names, c.name)
+	}
+	cmds = append(cmds, cmdSpec{name: "help", summary: helpSummary, actions: names})
+	global.actions = append(names, "help")
+	return global, cmds
+}
+
+// flagDefault matches the first line of a flag in the output of
+// flag.PrintDefaults: "  -name value" and the usage on the next line,
+// or "  -x\tusage" for a short boolean flag.
+var flagDefault = regexp.MustCompile(`^  -(\S+)(?: (\S+))?(?:\t(.*))?$`)
+
+func parseFlagDefaults(defaults string) []flagSpec {
+	var flags []flagSpec
+	lines := strings.Split(defaults, "\n")
+	for i, line := range lines {
+		m := flagDefault.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		f := flagSpec{name: m[1], arg: m[2], usage: m[3]}
+		if f.usage == "" && i+1 < len(lines) {
+			f.usage = strings.TrimSpace(lines[i+1])
+		}
+		flags = append(flags, f)
+	}
+	return flags
+}
+
+// parseUsage reads the spec of the command name from its usage: the
+// synopsis lines, which start with "usage: name" or, after the first,
+// with spaces and name, followed by the flag defaults. The first
+// argument of a synopsis is an action if the command has several
+// synopsis lines or if it lists alternatives, as in "bash|zsh|fish".
+func parseUsage(name, usage string) cmdSpec {
+	spec := cmdSpec{name: name, flags: parseFlagDefaults(usage)}
+	var synopses [][]string
+	for _, line := range strings.Split(usage, "\n") {
+		rest, ok := strings.CutPrefix(line, "usage: ")
+		if !ok && len(synopses) > 0 {
+			rest, ok = strings.TrimLeft(line, " "), strings.HasPrefix(line, "  ") && !strings.HasPrefix(line, "  -")
+		}
+		if ok && strings.HasPrefix(rest+" ", name+" ") {
+			synopses = append(synopses, arguments(strings.Fields(rest)[1:]))
+		}
+	}
+	for _, args := range synopses {
+		if len(args) > 0 && (len(synopses) > 1 || strings.Contains(args[0], "|")) {
+			spec.actions = append(spec.actions, strings.Split(args[0], "|")...)
+			args = args[1:]
+		}
+		spec.files = spec.files || len(args) > 0
+	}
+	return spec
+}
+
+// arguments returns the words of a synopsis that stand for arguments
+// rather than for flags and their values: for "[-i] [-C n] regexp
+// [path ...]", regexp, path and "...".
+func arguments(words []string) []string {
+	var args []string
+	var groups []bool // whether each open bracket holds flags
+	inFlags := func() bool {
+		for _, flags := range groups {
+			if flags {
+				return true
+			}
+		}
+		return false
+	}
+	for _, w := range words {
+		for strings.HasPrefix(w, "[") {
+			w = w[1:]
+			groups = append(groups, strings.HasPrefix(w, "-"))
+		}
+		closed := len(w) - len(strings.TrimRight(w, "]"))
+		w = w[:len(w)-closed]
+		if w != "" && w != "|" && !strings.HasPrefix(w, "-") && !inFlags() {
+			args = append(args, w)
+		}
+		groups = groups[:max(0, len(groups)-closed)]
+	}
+	return args
+}
+
+func writeBashCompletion(w io.Writer, global cmdSpec, cmds []cmdSpec) {
+	fn := "_" + progName
+	fmt.Fprintf(w, "# bash completion for %s, generated by '%s completion bash'.\n", progName, progName)
+	fmt.Fprintf(w, "# Source it, or save it as %s in a bash-completion directory.\n\n", progName)
+	fmt.Fprintf(w, "%s() {\n", fn)
+	fmt.Fprintln(w, "\tlocal cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]}")
+	fmt.Fprintln(w, "\tlocal cmd= i")
+	fmt.Fprintln(w, "\tfor ((i = 1; i < COMP_CWORD; i++)); do")
+	fmt.Fprintln(w, "\t\tcase ${COMP_WORDS[i]} in")
+	if valued := bashFlags(global.flags, func(f flagSpec) bool { return f.arg != "" }); valued != "" {
+		fmt.Fprintf(w, "\t\t%s) ((i++)) ;;\n", strings.ReplaceAll(valued, " ", "|"))
+	}
+	fmt.Fprintln(w, "\t\t-*) ;;")
+	fmt.Fprintln(w, "\t\t*) cmd=${COMP_WORDS[i]}; break ;;")
+	fmt.Fprintln(w, "\t\tesac")
+	fmt.Fprintln(w, "\tdone")
+	fmt.Fprintln(w, "\tlocal flags= words= files=")
+	fmt.Fprintln(w, "\tcase $cmd in")
+	for _, c := range append([]cmdSpec{global}, cmds...) {
+		if c.name == "" {
+			fmt.Fprintln(w, "\t'')")
+		} else {
+			fmt.Fprintf(w, "\t%s)\n", c.name)
+		}
+		writeBashValues(w, c.flags)
+		if len(c.flags) > 0 {
+			fmt.Fprintf(w, "\t\tflags='%s'\n", bashFlags(c.flags, func(flagSpec) bool { return true }))
+		}
+		if len(c.actions) > 0 {
+			fmt.Fprintf(w, "\t\twords='%s'\n", strings.Join(c.actions, " "))
+		}
+		if c.files {
+			fmt.Fprintln(w, "\t\tfiles=1")
+		}
+		fmt.Fprintln(w, "\t\t;;")
+	}
+	fmt.Fprintln(w, "\tesac")
+	fmt.Fprintln(w, "\tif [[ $cur == -* ]]; then")
+	fmt.Fprintln(w, "\t\tCOMPREPLY=($(compgen -W \"$flags\" -- \"$cur\"))")
+	fmt.Fprintln(w, "\telif [[ -n $files ]]; then")
+	fmt.Fprintln(w, "\t\tCOMPREPLY=($(compgen -W \"$words\" -- \"$cur\") $(compgen -f -- \"$cur\"))")
+	fmt.Fprintln(w, "\telse")
+	fmt.Fprintln(w, "\t\tCOMPREPLY=($(compgen -W \"$words\" -- \"$cur\"))")
+	fmt.Fprintln(w, "\tfi")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintf(w, "complete -o filenames -F %s %s\n", fn, progName)
+}
+
+// writeBashValues writes the case that completes the value of the flag
+// before the cursor, if it takes one.
+func writeBashValues(w io.Writer, flags []flagSpec) {
+	arms := []struct{ kind, reply string }{
+		{"file", "COMPREPLY=($(compgen -f -- \"$cur\"))"},
+		{"dir", "COMPREPLY=($(compgen -d -- \"$cur\"))"},
+		{"", "COMPREPLY=()"},
+	}
+	var b strings.Builder
+	for _, arm := range arms {
+		names := bashFlags(flags, func(f flagSpec) bool { return f.arg != "" && f.completes() == arm.kind })
+		if names != "" {
+			fmt.Fprintf(&b, "\t\t%s) %s; return ;;\n", strings.ReplaceAll(names, " ", "|"), arm.reply)
+		}
+	}
+	if b.Len() > 0 {
+		fmt.Fprintf(w, "\t\tcase $prev in\n%s\t\tesac\n", b.String())
+	}
+}
+
+// bashFlags returns the names, with their dash, of the flags that keep
+// returns true, separated by spaces.
+func bashFlags(flags []flagSpec, keep func(flagSpec) bool) string {
+	var names []string
+	for _, f := range flags {
+		if keep(f) {
+			names = append(names, "-"+f.name)
+		}
+	}
+	return strings.Join(names, " ")
+}
+
+func writeZshCompletion(w io.Writer, global cmdSpec, cmds []cmdSpec) {
+	fn := "_" + progName
+	fmt.Fprintf(w, "#compdef %s\n\n", progName)
+	fmt.Fprintf(w, "# zsh completion for %s, generated by '%s completion zsh'.\n", progName, progName)
+	fmt.Fprintf(w, "# Save it as %s in a directory of $fpath, or source it.\n\n", fn)
+	fmt.Fprintf(w, "%s() {\n", fn)
+	fmt.Fprintln(w, "\tlocal curcontext=$curcontext state line ret=1")
+	fmt.Fprintln(w, "\tlocal -a commands=(")
+	for _, c := range cmds {
+		fmt.Fprintf(w, "\t\t%s\n", zshQuote(c.name+":"+c.summary))
+	}
+	fmt.Fprintln(w, "\t)")
+	fmt.Fprintln(w, "\t_arguments -C \\")
+	for _, f := range global.flags {
+		fmt.Fprintf(w, "\t\t%s \\\n", zshFlag(f))
+	}
+	fmt.Fprintln(w, "\t\t'1:command:->command' \\")
+	fmt.Fprintln(w, "\t\t'*::argument:->argument' && ret=0")
+	fmt.Fprintln(w, "\tcase $state in")
+	fmt.Fprintln(w, "\tcommand)")
+	fmt.Fprintln(w, "\t\t_describe -t commands command commands && ret=0 ;;")
+	fmt.Fprintln(w, "\targument)")
+	fmt.Fprintf(w, "\t\tcurcontext=${curcontext%%:*:*}:%s-$line[1]:\n", progName)
+	fmt.Fprintln(w, "\t\tcase $line[1] in")
+	for _, c := range cmds {
+		fmt.Fprintf(w, "\t\t%s)\n", c.name)
+		if c.name == "help" {
+			fmt.Fprintln(w, "\t\t\t_describe -t commands command commands && ret=0 ;;")
+			continue
+		}
+		var specs []string
+		for _, f := range c.flags {
+			specs = append(specs, zshFlag(f))
+		}
+		if len(c.actions) > 0 {
+			specs = append(specs, zshQuote("1:action:("+strings.Join(c.actions, " ")+")"))
+		}
+		if c.files {
+			specs = append(specs, "'*:file:_files'")
+		}
+		if len(specs) == 0 {
+			fmt.Fprintln(w, "\t\t\t;;")
+			continue
+		}
+		fmt.Fprint(w, "\t\t\t_arguments")
+		for _, spec := range specs {
+			fmt.Fprintf(w, " \\\n\t\t\t\t%s", spec)
+		}
+		fmt.Fprintln(w, " && ret=0 ;;")
+	}
+	fmt.Fprintln(w, "\t\tesac ;;")
+	fmt.Fprintln(w, "\tesac")
+	fmt.Fprintln(w, "\treturn ret")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "if [[ $funcstack[1] == %s ]]; then\n\t%s \"$@\"\nelse\n\tcompdef %s %s\nfi\n", fn, fn, fn, progName)
+}
+
+// zshFlag returns the _arguments spec of f, quoted.
+func zshFlag(f flagSpec) string {
+	usage := strings.NewReplacer(`[`, `\[`, `]`, `\]`, `:`, `\:`).Replace(f.usage)
+	spec := "-" + f.name + "[" + usage + "]"
+	if f.repeatable() {
+		spec = "*" + spec
+	}
+	switch {
+	case f.arg == "":
+	case f.completes() == "file":
+		spec += ":" + f.arg + ":_files"
+	case f.completes() == "dir":
+		spec += ":" + f.arg + ":_files -/"
+	default:
+		spec += ":" + f.arg + ": "
+	}
+	return zshQuote(spec)
+}
+
+// zshQuote quotes s for zsh and bash in single quotes.
+func zshQuote(s string) string { return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'" }
+
+func writeFishCompletion(w io.Writer, global cmdSpec, cmds []cmdSpec) {
+	fmt.Fprintf(w, "# fish completion for %s, generated by '%s completion fish'.\n", progName, progName)
+	fmt.Fprintf(w, "# Source it, or save it as %s.fish in ~/.config/fish/completions.\n\n", progName)
+	fmt.Fprintf(w, "complete -c %s -f\n", progName)
+	for _, f := range global.flags {
+		fmt.Fprintf(w, "complete -c %s -n __fish_use_subcommand %s\n", progName, fishFlag(f))
+	}
+	for _, c := range cmds {
+		fmt.Fprintf(w, "complete -c %s -n __fish_use_subcommand -a %s -d %s\n", progName, c.name, fishQuote(c.summary))
+	}
+	for _, c := range cmds {
+		cond := fishQuote("__fish_seen_subcommand_from " + c.name)
+		for _, f := range c.flags {
+			fmt.Fprintf(w, "complete -c %s -n %s %s\n", progName, cond, fishFlag(f))
+		}
+		if len(c.actions) > 0 {
+			fmt.Fprintf(w, "complete -c %s -n %s -a %s\n", progName, cond, fishQuote(strings.Join(c.actions, " ")))
+		}
+		if c.files {
+			fmt.Fprintf(w, "complete -c %s -n %s -F\n", progName, cond)
+		}
+	}
+}
+
+// fishFlag returns the options of complete that describe f.
+func fishFlag(f flagSpec) string {
+	opts := "-o " + f.name
+	switch {
+	case f.arg == "":
+	case f.completes() == "file":
+		opts += " -r -F"
+	case f.completes() == "dir":
+		opts += " -x -a '(__fish_complete_directories)'"
+	default:
+		opts += " -x"
+	}
+	return opts + " -d " + fishQuote(f.usage)
+}
+
+// fishQuote quotes s for fish in single quotes.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
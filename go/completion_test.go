@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseUsage(t *testing.T) {
+	usage := "usage: users [-file path] list [-sort id|name]\n" +
+		"       users [-file path] get|delete id ...\n" +
+		"  -file file\n    \tthe JSON user file (default \"users.json\")\n" +
+		"  -q\tbe quiet\n"
+	got := parseUsage("users", usage)
+	want := cmdSpec{
+		name: "users",
+		flags: []flagSpec{
+			{name: "file", arg: "file", usage: `the JSON user file (default "users.json")`},
+			{name: "q", usage: "be quiet"},
+		},
+		actions: []string{"list", "get", "delete"},
+		files:   true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v\nwant %+v", got, want)
+	}
+
+	for _, tt := range []struct {
+		usage   string
+		actions []string
+		files   bool
+	}{
+		{"usage: search [-i] [-C n] regexp [path ...]", nil, true},
+		{"usage: wordcount [-top n [-format f]]", nil, false},
+		{"usage: config [-json | -env]", nil, false},
+		{"usage: completion bash|zsh|fish", []string{"bash", "zsh", "fish"}, false},
+	} {
+		name := strings.Fields(tt.usage)[1]
+		got := parseUsage(name, tt.usage)
+		if !reflect.DeepEqual(got.actions, tt.actions) || got.files != tt.files {
+			t.Errorf("%q: actions %q, files %v", tt.usage, got.actions, got.files)
+		}
+	}
+}
+
+func TestRunCompletion(t *testing.T) {
+	global, cmds := commandSpecs(context.Background())
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		var out, errOut bytes.Buffer
+		if code := runCompletion(context.Background(), []string{shell}, &out, &errOut); code != 0 {
+			t.Fatalf("%s: exit %d: %s", shell, code, errOut.String())
+		}
+		script := out.String()
+		for _, c := range append([]cmdSpec{global}, cmds...) {
+			if !strings.Contains(script, c.name) {
+				t.Errorf("%s: no command %s", shell, c.name)
+			}
+			for _, f := range c.flags {
+				if !strings.Contains(script, f.name) {
+					t.Errorf("%s: no flag -%s of %q", shell, f.name, c.name)
+				}
+			}
+		}
+		// The shells that are installed check the syntax.
+		if _, err := exec.LookPath(shell); err != nil {
+			continue
+		}
+		path := filepath.Join(t.TempDir(), "completion")
+		os.WriteFile(path, out.Bytes(), 0o644)
+		if msg, err := exec.Command(shell, "-n", path).CombinedOutput(); err != nil {
+			t.Errorf("%s -n: %v\n%s", shell, err, msg)
+		}
+	}
+
+	var out, errOut bytes.Buffer
+	if code := runCompletion(context.Background(), []string{"tcsh"}, &out, &errOut); code != 2 || !strings.Contains(errOut.String(), "unknown shell") {
+		t.Fatalf("tcsh: exit %d, %q", code, errOut.String())
+	}
+}
+
+func TestCommandSpecs(t *testing.T) {
+	global, cmds := commandSpecs(context.Background())
+	if len(global.flags) != 11 || len(global.actions) != len(commands())+1 {
+		t.Fatalf("global: %+v", global)
+	}
+	specs := map[string]cmdSpec{}
+	for _, c := range cmds {
+		specs[c.name] = c
+	}
+	if got := specs["users"].actions; !reflect.DeepEqual(got, []string{"list", "add", "get", "delete", "import", "export"}) {
+		t.Errorf("users actions = %q", got)
+	}
+	if du := specs["du"]; len(du.flags) == 0 || !du.files {
+		t.Errorf("du: %+v", du)
+	}
+	if specs["help"].files || len(specs["help"].actions) != len(commands()) {
+		t.Errorf("help: %+v", specs["help"])
+	}
+}
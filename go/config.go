@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"example.com/tutorial/config"
+)
+
+// runConfig implements the "config" subcommand: it prints s, the
+// effective settings, as a configuration file that -config reads back,
+// or as the environment variables that set them.
+func runConfig(s settings, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: config [-json | -env]")
+		fs.PrintDefaults()
+	}
+	asJSON := fs.Bool("json", false, "print the settings as JSON instead of YAML")
+	asEnv := fs.Bool("env", false, "print the settings as environment variable assignments")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 || *asJSON && *asEnv {
+		fs.Usage()
+		return 2
+	}
+	if *asEnv {
+		for _, setting := range config.Settings(s, envPrefix) {
+			fmt.Fprintf(stdout, "%s=%s\n", setting.Env, setting.Value)
+		}
+		return 0
+	}
+	format := config.YAML
+	if *asJSON {
+		format = config.JSON
+	}
+	if err := config.Write(stdout, s, format); err != nil {
+		fmt.Fprintln(stderr, "config:", err)
+		return 1
+	}
+	return 0
+}
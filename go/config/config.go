@@ -0,0 +1,476 @@
+// Package config loads a program's settings in layers, each overriding
+// the last: the defaults already held by a settings struct, a YAML or
+// JSON file, then environment variables. Flags are the final layer: the
+// program registers them with the loaded values as their defaults, so
+// that a flag given on the command line wins.
+//
+// Settings are the exported fields of the struct with a `config` tag
+// naming them. A field of struct type is a section whose settings are
+// named section.setting, unless its type implements
+// encoding.TextUnmarshaler, as slog.Level does. Other settings may be
+// strings, booleans, integers, floats, time.Duration, which also takes
+// days such as "2d" as humanize.ParseDuration does, or []string. A tag
+// such as `config:"token,secret"` marks a password or token, which Write
+// and Settings print as Redacted when it is set.
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"example.com/tutorial/humanize"
+	"example.com/tutorial/orderedmap"
+)
+
+// Errors returned by Load.
+var (
+	ErrUnknownKey    = errors.New("config: unknown setting")
+	ErrDuplicateKey  = errors.New("config: duplicate setting")
+	ErrUnknownFormat = errors.New("config: unknown file format")
+)
+
+// Redacted is what Write and Settings print for a secret setting that
+// is set, so that their output can be shown without giving it away.
+const Redacted = "<redacted>"
+
+// Format is a configuration file format.
+type Format int
+
+const (
+	YAML Format = iota
+	JSON
+)
+
+// FormatOf returns the format of a file by its extension: .yaml, .yml
+// or .json.
+func FormatOf(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return YAML, nil
+	case ".json":
+		return JSON, nil
+	}
+	return 0, fmt.Errorf("%w: %s", ErrUnknownFormat, path)
+}
+
+// Options selects the layers Load reads.
+type Options struct {
+	// File is the path of the configuration file, whose format FormatOf
+	// tells; empty means none.
+	File string
+	// EnvPrefix prefixes the environment variable of every setting: the
+	// variable of setting "serve.addr" with prefix "APP" is
+	// APP_SERVE_ADDR. Empty means the environment is not read.
+	EnvPrefix string
+	// Getenv looks up environment variables; nil means os.Getenv.
+	Getenv func(string) string
+}
+
+// Load overrides the settings of dst, a pointer to a struct, with those
+// of the file and then of the environment. Settings absent from both
+// keep their values. Every unknown or repeated key and malformed value
+// is reported, joined into one error; those of the file are FileErrors
+// giving their line.
+func Load(dst any, opts Options) error {
+	root := reflect.ValueOf(dst)
+	if root.Kind() != reflect.Pointer || root.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: Load needs a pointer to a struct, not %T", dst)
+	}
+	settings := map[string]reflect.Value{}
+	walk(root.Elem(), "", func(key string, v reflect.Value, _ bool) { settings[key] = v })
+
+	var errs []error
+	if opts.File != "" {
+		errs = append(errs, loadFile(opts.File, settings)...)
+	}
+	if opts.EnvPrefix != "" {
+		getenv := opts.Getenv
+		if getenv == nil {
+			getenv = os.Getenv
+		}
+		walk(root.Elem(), "", func(key string, v reflect.Value, _ bool) {
+			name := EnvName(opts.EnvPrefix, key)
+			if s := getenv(name); s != "" {
+				if err := setString(v, s); err != nil {
+					errs = append(errs, fmt.Errorf("config: $%s: %w", name, err))
+				}
+			}
+		})
+	}
+	return errors.Join(errs...)
+}
+
+// EnvName returns the environment variable of the setting key.
+func EnvName(prefix, key string) string {
+	return prefix + "_" + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
+
+var textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// isSection reports whether values of t hold settings of their own.
+func isSection(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && !reflect.PointerTo(t).Implements(textUnmarshaler)
+}
+
+// settingTag returns the name the config tag of f gives it and whether
+// the tag marks it secret; ok is false if f is not a setting.
+func settingTag(f reflect.StructField) (name string, secret, ok bool) {
+	tag, ok := f.Tag.Lookup("config")
+	name, opts, _ := strings.Cut(tag, ",")
+	if !ok || name == "-" || !f.IsExported() {
+		return "", false, false
+	}
+	return name, opts == "secret", true
+}
+
+// redacted reports whether v is a secret setting that is set, which
+// Write and Settings print as Redacted.
+func redacted(v reflect.Value, secret bool) bool {
+	return secret && !v.IsZero()
+}
+
+// walk calls visit with the key and value of every setting of the
+// struct v, in field order, naming them below prefix, and whether the
+// setting is secret.
+func walk(v reflect.Value, prefix string, visit func(key string, v reflect.Value, secret bool)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, secret, ok := settingTag(t.Field(i))
+		if !ok {
+			continue
+		}
+		key := prefix + name
+		if isSection(t.Field(i).Type) {
+			walk(v.Field(i), key+".", visit)
+			continue
+		}
+		visit(key, v.Field(i), secret)
+	}
+}
+
+func loadFile(path string, settings map[string]reflect.Value) []error {
+	format, err := FormatOf(path)
+	if err != nil {
+		return []error{err}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []error{fmt.Errorf("config: %w", err)}
+	}
+	var doc *node
+	switch format {
+	case YAML:
+		doc, err = parseYAML(path, data)
+	case JSON:
+		doc, err = parseJSON(path, data)
+	}
+	if err != nil {
+		return []error{err}
+	}
+	if doc == nil {
+		return nil
+	}
+	if doc.kind != mappingNode {
+		return []error{&FileError{Path: path, Line: doc.line, Err: errors.New("not a mapping of settings")}}
+	}
+	var errs []error
+	var apply func(m *node, prefix string)
+	apply = func(m *node, prefix string) {
+		seen := map[string]bool{}
+		for i, name := range m.keys {
+			val, key := m.values[i], prefix+name
+			if seen[name] {
+				errs = append(errs, &FileError{Path: path, Line: val.line, Err: fmt.Errorf("%w %q", ErrDuplicateKey, key)})
+				continue
+			}
+			seen[name] = true
+			v, ok := settings[key]
+			if val.kind == mappingNode && !ok {
+				apply(val, key+".")
+				continue
+			}
+			if !ok {
+				errs = append(errs, &FileError{Path: path, Line: val.line, Err: fmt.Errorf("%w %q", ErrUnknownKey, key)})
+				continue
+			}
+			if err := setValue(v, val); err != nil {
+				errs = append(errs, &FileError{Path: path, Line: val.line, Err: fmt.Errorf("%s: %w", key, err)})
+			}
+		}
+	}
+	apply(doc, "")
+	return errs
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setValue sets v to the value n of a file.
+func setValue(v reflect.Value, n *node) error {
+	switch n.kind {
+	case listNode:
+		if v.Kind() != reflect.Slice {
+			return errors.New("is not a list")
+		}
+		items := make([]string, len(n.items))
+		for i, item := range n.items {
+			if item.kind != scalarNode || item.value == nil {
+				return errors.New("is not a list of values")
+			}
+			items[i] = fmt.Sprint(item.value)
+		}
+		v.Set(reflect.ValueOf(items))
+		return nil
+	case mappingNode:
+		return errors.New("is not a section")
+	}
+	if n.value == nil {
+		return errors.New("has no value")
+	}
+	return setString(v, fmt.Sprint(n.value))
+}
+
+// setString parses s into v.
+func setString(v reflect.Value, s string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	if v.Type() == durationType {
+		d, err := humanize.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as 10s or 2d", s)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", s)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a non-negative integer", s)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a number", s)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// Validate calls the Validate method of v, a struct or a pointer to one,
+// and of each of its sections that has one, and joins the errors, each
+// prefixed with the section it came from.
+func Validate(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+	return errors.Join(validate(rv, "")...)
+}
+
+type validator interface{ Validate() error }
+
+func validate(v reflect.Value, section string) []error {
+	var errs []error
+	if val, ok := v.Interface().(validator); ok {
+		if err := val.Validate(); err != nil {
+			if section != "" {
+				err = fmt.Errorf("%s: %w", section, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, ok := settingTag(f)
+		if ok && isSection(f.Type) {
+			if section != "" {
+				name = section + "." + name
+			}
+			errs = append(errs, validate(v.Field(i), name)...)
+		}
+	}
+	return errs
+}
+
+// Setting is one setting of a struct, as Settings lists them.
+type Setting struct {
+	Key   string
+	Env   string // the variable Load reads, or empty without a prefix
+	Value string // as a file or variable would give it
+}
+
+// Settings returns the settings of v, a struct or a pointer to one, in
+// field order, the secret ones that are set as Redacted.
+func Settings(v any, envPrefix string) []Setting {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+	var list []Setting
+	walk(rv, "", func(key string, v reflect.Value, secret bool) {
+		s := Setting{Key: key, Value: format(v)}
+		if redacted(v, secret) {
+			s.Value = Redacted
+		}
+		if envPrefix != "" {
+			s.Env = EnvName(envPrefix, key)
+		}
+		list = append(list, s)
+	})
+	return list
+}
+
+// format returns the text of v that setString parses back.
+func format(v reflect.Value) string {
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		text, _ := m.MarshalText()
+		return string(text)
+	}
+	if list, ok := v.Interface().([]string); ok {
+		return strings.Join(list, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// Write writes the settings of v, a struct or a pointer to one, as a
+// file of format f that Load reads back to the same values, but for
+// the secret ones that are set, which it writes as Redacted.
+func Write(w io.Writer, v any, f Format) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+	if f == JSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(jsonValue(rv))
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(yamlNode(rv)); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// jsonValue returns the settings of the struct v as nested maps in
+// field order, the order yamlNode writes them in.
+func jsonValue(v reflect.Value) *orderedmap.Map[string, any] {
+	m := orderedmap.New[string, any]()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, secret, ok := settingTag(f)
+		if !ok {
+			continue
+		}
+		fv := v.Field(i)
+		switch {
+		case redacted(fv, secret):
+			m.Set(name, Redacted)
+		case isSection(f.Type):
+			m.Set(name, jsonValue(fv))
+		case f.Type == durationType:
+			m.Set(name, format(fv))
+		case fv.Kind() == reflect.Slice:
+			list, _ := fv.Interface().([]string)
+			if list == nil {
+				list = []string{}
+			}
+			m.Set(name, list)
+		default:
+			if _, isText := fv.Interface().(encoding.TextMarshaler); isText {
+				m.Set(name, format(fv))
+			} else {
+				m.Set(name, fv.Interface())
+			}
+		}
+	}
+	return m
+}
+
+// yamlNode returns the settings of the struct v as a mapping in field
+// order.
+func yamlNode(v reflect.Value) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, secret, ok := settingTag(f)
+		if !ok {
+			continue
+		}
+		fv := v.Field(i)
+		var val *yaml.Node
+		switch {
+		case redacted(fv, secret):
+			val = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: Redacted}
+		case isSection(f.Type):
+			val = yamlNode(fv)
+		case fv.Kind() == reflect.Slice:
+			val = &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+			list, _ := fv.Interface().([]string)
+			for _, item := range list {
+				val.Content = append(val.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: item})
+			}
+		default:
+			tag := "!!str"
+			if _, isText := fv.Interface().(encoding.TextMarshaler); !isText && f.Type != durationType {
+				switch fv.Kind() {
+				case reflect.Bool:
+					tag = "!!bool"
+				case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+					reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+					tag = "!!int"
+				case reflect.Float32, reflect.Float64:
+					tag = "!!float"
+				}
+			}
+			val = &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: format(fv)}
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, val)
+	}
+	return node
+}
@@ -0,0 +1,204 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type server struct {
+	Addr    string        `config:"addr"`
+	Grace   time.Duration `config:"grace"`
+	TLS     bool          `config:"tls"`
+	Origins []string      `config:"origins"`
+}
+
+func (s server) Validate() error {
+	if s.Grace <= 0 {
+		return errors.New("grace must be positive")
+	}
+	return nil
+}
+
+type settings struct {
+	Level   slog.Level `config:"log-level"`
+	Workers int        `config:"workers"`
+	Ratio   float64    `config:"ratio"`
+	Server  server     `config:"server"`
+	Ignored string
+}
+
+func defaults() settings {
+	return settings{Workers: 4, Ratio: 0.5, Server: server{Addr: ":8080", Grace: 10 * time.Second}}
+}
+
+func env(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := writeFile(t, "app.yaml", "workers: 8\nlog-level: debug\nserver:\n  addr: :9000\n  grace: 1m\n  origins: [a.example, b.example]\n")
+	cfg := defaults()
+	err := Load(&cfg, Options{File: file, EnvPrefix: "APP", Getenv: env(map[string]string{
+		"APP_SERVER_ADDR": ":9100", "APP_SERVER_TLS": "true", "APP_RATIO": "0.25",
+	})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := settings{
+		Level: slog.LevelDebug, Workers: 8, Ratio: 0.25,
+		Server: server{Addr: ":9100", Grace: time.Minute, TLS: true, Origins: []string{"a.example", "b.example"}},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Fatalf("got  %+v\nwant %+v", cfg, want)
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	file := writeFile(t, "app.json", `{"workers": 1000000, "server": {"grace": "5s", "origins": "x, y"}}`)
+	cfg := defaults()
+	if err := Load(&cfg, Options{File: file}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Workers != 1000000 || cfg.Server.Grace != 5*time.Second || !reflect.DeepEqual(cfg.Server.Origins, []string{"x", "y"}) {
+		t.Fatalf("got %+v", cfg)
+	}
+}
+
+func TestLoadDays(t *testing.T) {
+	file := writeFile(t, "app.yaml", "server:\n  grace: 1d12h\n")
+	cfg := defaults()
+	if err := Load(&cfg, Options{File: file}); err != nil || cfg.Server.Grace != 36*time.Hour {
+		t.Fatalf("grace %v, %v", cfg.Server.Grace, err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	file := writeFile(t, "app.yml", "workers: many\nserver:\n  adr: :1\nbogus: 1\n")
+	cfg := defaults()
+	err := Load(&cfg, Options{File: file, EnvPrefix: "APP", Getenv: env(map[string]string{"APP_SERVER_GRACE": "soon"})})
+	if !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("err = %v", err)
+	}
+	for _, want := range []string{`"server.adr"`, `"bogus"`, `workers: "many" is not an integer`, `$APP_SERVER_GRACE: "soon" is not a duration`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error lacks %q:\n%v", want, err)
+		}
+	}
+	if err := Load(&cfg, Options{File: "app.toml"}); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("toml: %v", err)
+	}
+	if err := Load(&cfg, Options{File: filepath.Join(t.TempDir(), "missing.yaml")}); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing file: %v", err)
+	}
+	if err := Load(cfg, Options{}); err == nil {
+		t.Error("Load of a non-pointer succeeded")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := defaults()
+	if err := Validate(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Server.Grace = 0
+	if err := Validate(cfg); err == nil || err.Error() != "server: grace must be positive" {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	cfg := defaults()
+	cfg.Level = slog.LevelWarn
+	cfg.Server.Origins = []string{"a", "true"}
+	for _, f := range []Format{YAML, JSON} {
+		var buf bytes.Buffer
+		if err := Write(&buf, cfg, f); err != nil {
+			t.Fatal(err)
+		}
+		name := map[Format]string{YAML: "out.yaml", JSON: "out.json"}[f]
+		got := settings{}
+		if err := Load(&got, Options{File: writeFile(t, name, buf.String())}); err != nil {
+			t.Fatalf("%s:\n%s\n%v", name, buf.String(), err)
+		}
+		if !reflect.DeepEqual(got, cfg) {
+			t.Errorf("%s: read back %+v\nfrom:\n%s", name, got, buf.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	Write(&buf, defaults(), YAML)
+	want := "log-level: INFO\nworkers: 4\nratio: 0.5\nserver:\n  addr: :8080\n  grace: 10s\n  tls: false\n  origins: []\n"
+	if buf.String() != want {
+		t.Errorf("YAML:\n%s\nwant:\n%s", buf.String(), want)
+	}
+	// JSON keeps the same field order.
+	buf.Reset()
+	Write(&buf, defaults(), JSON)
+	want = `{
+  "log-level": "INFO",
+  "workers": 4,
+  "ratio": 0.5,
+  "server": {
+    "addr": ":8080",
+    "grace": "10s",
+    "tls": false,
+    "origins": []
+  }
+}
+`
+	if buf.String() != want {
+		t.Errorf("JSON:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestSettings(t *testing.T) {
+	got := Settings(defaults(), "APP")
+	if len(got) != 7 || got[3] != (Setting{Key: "server.addr", Env: "APP_SERVER_ADDR", Value: ":8080"}) {
+		t.Fatalf("got %+v", got)
+	}
+}
+
+func TestWriteRedactsSecrets(t *testing.T) {
+	type secrets struct {
+		User  string `config:"user"`
+		Token string `config:"token,secret"`
+		Unset string `config:"unset,secret"`
+	}
+	v := secrets{User: "ada", Token: "hunter2"}
+	var buf bytes.Buffer
+	if err := Write(&buf, v, YAML); err != nil || buf.String() != "user: ada\ntoken: <redacted>\nunset: \"\"\n" {
+		t.Errorf("YAML: %v:\n%s", err, buf.String())
+	}
+	buf.Reset()
+	var m map[string]string
+	if err := Write(&buf, v, JSON); err != nil || json.Unmarshal(buf.Bytes(), &m) != nil || m["token"] != Redacted || m["user"] != "ada" {
+		t.Errorf("JSON: %v:\n%s", err, buf.String())
+	}
+	if got := Settings(v, "APP"); got[1].Value != Redacted || got[2].Value != "" {
+		t.Errorf("Settings: %+v", got)
+	}
+
+	// The tag's option does not change the setting's name.
+	var got secrets
+	err := Load(&got, Options{EnvPrefix: "APP", Getenv: env(map[string]string{"APP_TOKEN": "s3cret"})})
+	if err != nil || got.Token != "s3cret" {
+		t.Fatalf("Load: %v, %+v", err, got)
+	}
+}
@@ -0,0 +1,200 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileError reports a problem at a line of a configuration file.
+type FileError struct {
+	Path string
+	Line int // 1-based; 0 if not known
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	msg := strings.TrimPrefix(e.Err.Error(), "config: ")
+	if e.Line == 0 {
+		return fmt.Sprintf("config: %s: %s", e.Path, msg)
+	}
+	return fmt.Sprintf("config: %s:%d: %s", e.Path, e.Line, msg)
+}
+
+func (e *FileError) Unwrap() error { return e.Err }
+
+type nodeKind int
+
+const (
+	scalarNode nodeKind = iota
+	listNode
+	mappingNode
+)
+
+// node is a value of a configuration file and the line it starts on,
+// which for the value of a mapping is that of its key.
+type node struct {
+	kind nodeKind
+	line int
+	// value is a scalar as decoded from the file: a string, number or
+	// boolean, or nil for null.
+	value any
+	items []*node
+	// keys and values are the entries of a mapping, in file order.
+	keys   []string
+	values []*node
+}
+
+// parseYAML parses a YAML file, returning nil if it is empty.
+func parseYAML(path string, data []byte) (*node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, &FileError{Path: path, Err: err}
+	}
+	if doc.Kind == 0 || len(doc.Content) == 0 {
+		return nil, nil
+	}
+	n, err := fromYAML(doc.Content[0])
+	if err != nil {
+		return nil, &FileError{Path: path, Line: lineOf(err), Err: err}
+	}
+	return n, nil
+}
+
+// yamlError is an error at a line of a YAML file.
+type yamlError struct {
+	line int
+	err  error
+}
+
+func (e *yamlError) Error() string { return e.err.Error() }
+
+func (e *yamlError) Unwrap() error { return e.err }
+
+func lineOf(err error) int {
+	var ye *yamlError
+	if errors.As(err, &ye) {
+		return ye.line
+	}
+	return 0
+}
+
+func fromYAML(y *yaml.Node) (*node, error) {
+	n := &node{line: y.Line}
+	switch y.Kind {
+	case yaml.AliasNode:
+		a, err := fromYAML(y.Alias)
+		if err != nil {
+			return nil, err
+		}
+		a.line = y.Line
+		return a, nil
+	case yaml.MappingNode:
+		n.kind = mappingNode
+		for i := 0; i+1 < len(y.Content); i += 2 {
+			k, v := y.Content[i], y.Content[i+1]
+			if k.Kind != yaml.ScalarNode {
+				return nil, &yamlError{k.Line, errors.New("setting names must be strings")}
+			}
+			val, err := fromYAML(v)
+			if err != nil {
+				return nil, err
+			}
+			val.line = k.Line
+			n.keys = append(n.keys, k.Value)
+			n.values = append(n.values, val)
+		}
+	case yaml.SequenceNode:
+		n.kind = listNode
+		for _, item := range y.Content {
+			val, err := fromYAML(item)
+			if err != nil {
+				return nil, err
+			}
+			n.items = append(n.items, val)
+		}
+	default:
+		if err := y.Decode(&n.value); err != nil {
+			return nil, &yamlError{y.Line, err}
+		}
+	}
+	return n, nil
+}
+
+// parseJSON parses a JSON file, returning nil if it is empty.
+func parseJSON(path string, data []byte) (*node, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	line := func(offset int64) int { return 1 + bytes.Count(data[:offset], []byte("\n")) }
+	fail := func(err error) error {
+		var serr *json.SyntaxError
+		if errors.As(err, &serr) {
+			return &FileError{Path: path, Line: line(serr.Offset), Err: err}
+		}
+		return &FileError{Path: path, Line: line(dec.InputOffset()), Err: err}
+	}
+
+	var value func() (*node, error)
+	value = func() (*node, error) {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		n := &node{line: line(dec.InputOffset())}
+		switch tok {
+		case json.Delim('{'):
+			n.kind = mappingNode
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				keyLine := line(dec.InputOffset())
+				val, err := value()
+				if err != nil {
+					return nil, err
+				}
+				val.line = keyLine
+				n.keys = append(n.keys, key.(string))
+				n.values = append(n.values, val)
+			}
+		case json.Delim('['):
+			n.kind = listNode
+			for dec.More() {
+				val, err := value()
+				if err != nil {
+					return nil, err
+				}
+				n.items = append(n.items, val)
+			}
+		default:
+			n.value = tok
+			return n, nil
+		}
+		// The closing delimiter.
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return n, nil
+	}
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	doc, err := value()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, fail(err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fail(errors.New("data after the settings"))
+	}
+	return doc, nil
+}
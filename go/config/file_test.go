@@ -0,0 +1,111 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fileErrors returns the FileErrors joined in err.
+func fileErrors(t *testing.T, err error) []*FileError {
+	t.Helper()
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		joined = errorList{err}
+	}
+	var list []*FileError
+	for _, e := range joined.Unwrap() {
+		var fe *FileError
+		if !errors.As(e, &fe) {
+			t.Fatalf("%v is not a FileError", e)
+		}
+		list = append(list, fe)
+	}
+	return list
+}
+
+type errorList []error
+
+func (l errorList) Error() string   { return "" }
+func (l errorList) Unwrap() []error { return l }
+
+func TestLoadErrorLines(t *testing.T) {
+	for name, content := range map[string]string{
+		"app.yaml": "workers: 2\n\nserver:\n  addr: :1\n  adr: :2\n  grace: soon\nworkers: 3\n",
+		"app.json": "{\n  \"workers\": 2,\n\n  \"server\": {\n    \"addr\": \":1\",\n    \"adr\": \":2\",\n    \"grace\": \"soon\"\n  },\n  \"workers\": 3\n}\n",
+	} {
+		cfg := defaults()
+		err := Load(&cfg, Options{File: writeFile(t, name, content)})
+		list := fileErrors(t, err)
+		want := []struct {
+			line int
+			msg  string
+			is   error
+		}{
+			{5, `unknown setting "server.adr"`, ErrUnknownKey},
+			{6, `server.grace: "soon" is not a duration`, nil},
+			{7, `duplicate setting "workers"`, ErrDuplicateKey},
+		}
+		if name == "app.json" {
+			want[0].line, want[1].line, want[2].line = 6, 7, 9
+		}
+		if len(list) != len(want) {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for i, w := range want {
+			fe := list[i]
+			if fe.Line != w.line || !strings.Contains(fe.Error(), fmt.Sprintf(":%d: %s", w.line, w.msg)) || w.is != nil && !errors.Is(fe, w.is) {
+				t.Errorf("%s: error %d = %q (line %d), want line %d: %s", name, i, fe, fe.Line, w.line, w.msg)
+			}
+		}
+		if cfg.Workers != 2 || cfg.Server.Addr != ":1" {
+			t.Errorf("%s: loaded %+v", name, cfg)
+		}
+	}
+}
+
+func TestLoadSyntaxErrors(t *testing.T) {
+	for name, line := range map[string]int{
+		"bad.json":   3,
+		"trail.json": 2,
+		"short.json": 2,
+		"list.yaml":  1,
+	} {
+		content := map[string]string{
+			"bad.json":   "{\n  \"workers\": 2\n  \"ratio\": 1\n}",
+			"trail.json": "{}\n{}",
+			"short.json": "{\n\"workers\": ",
+			"list.yaml":  "- workers\n",
+		}[name]
+		cfg := defaults()
+		err := Load(&cfg, Options{File: writeFile(t, name, content)})
+		var fe *FileError
+		if !errors.As(err, &fe) || fe.Line != line {
+			t.Errorf("%s: %v, want an error at line %d", name, err, line)
+		}
+	}
+	cfg := defaults()
+	if err := Load(&cfg, Options{File: writeFile(t, "tab.yaml", "server:\n\taddr: x\n")}); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("yaml syntax: %v", err)
+	}
+	for _, name := range []string{"empty.yaml", "empty.json"} {
+		if err := Load(&cfg, Options{File: writeFile(t, name, "\n")}); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestLoadYAMLAnchors(t *testing.T) {
+	file := writeFile(t, "app.yaml", "base: &grace 30s\nserver:\n  grace: *grace\n  origins: [&o a.example, *o]\n")
+	cfg := defaults()
+	err := Load(&cfg, Options{File: file})
+	// "base" is not a setting, but the alias to it still resolves.
+	if list := fileErrors(t, err); len(list) != 1 || list[0].Line != 1 {
+		t.Fatalf("err = %v", err)
+	}
+	if cfg.Server.Grace != 30*time.Second || len(cfg.Server.Origins) != 2 || cfg.Server.Origins[1] != "a.example" {
+		t.Fatalf("got %+v", cfg.Server)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunSettingsLayers(t *testing.T) {
+	dir := writeTree(t, map[string]string{"a/b/c.txt": "x", "a/d.go": "y"})
+	file := filepath.Join(t.TempDir(), "tutorial.yaml")
+	os.WriteFile(file, []byte("tree:\n  depth: 1\n  exclude: [\"*.go\"]\n"), 0o644)
+	tree := func(args ...string) string {
+		t.Helper()
+		var out, errOut bytes.Buffer
+		if code := run(context.Background(), args, nil, &out, &errOut); code != 0 {
+			t.Fatalf("%v: exit %d: %s", args, code, errOut.String())
+		}
+		return out.String()
+	}
+
+	if got := tree("-config", file, "tree", dir); got != dir+"\n└── a\n\n1 directory, 0 files\n" {
+		t.Errorf("file: got %q", got)
+	}
+	t.Setenv(envConfig, file)
+	t.Setenv("TUTORIAL_TREE_DEPTH", "2")
+	if got := tree("tree", dir); got != dir+"\n└── a\n    └── b\n\n2 directories, 0 files\n" {
+		t.Errorf("environment over file: got %q", got)
+	}
+	if got := tree("tree", "-depth", "0", dir); !strings.Contains(got, "c.txt") || strings.Contains(got, "d.go") {
+		t.Errorf("flag over environment: got %q", got)
+	}
+}
+
+func TestRunSettingsErrors(t *testing.T) {
+	for _, tc := range []struct {
+		env, want string
+	}{
+		{"TUTORIAL_WATCH_INTERVAL=soon", `$TUTORIAL_WATCH_INTERVAL: "soon" is not a duration`},
+		{"TUTORIAL_TREE_DEPTH=-1", "tree: invalid: depth: must not be negative"},
+		{"TUTORIAL_WORDCOUNT_ANALYZER=klingon", "wordcount: invalid: analyzer: must be one of auto, english, french, german, spanish, whitespace"},
+		{"TUTORIAL_SERVE_CORPUS_REINDEX=often", "serve.corpus: invalid: reindex: must be a cron spec"},
+		{"TUTORIAL_SERVE_CACHE_BACKEND=disk", "serve.cache: invalid: backend: must be memory or redis"},
+		{"TUTORIAL_SERVE_REDIS_POOL_SIZE=-1", "serve.redis: invalid: pool-size: must not be negative"},
+		{"TUTORIAL_CONFIG=missing.yaml", "missing.yaml"},
+	} {
+		t.Run(tc.env, func(t *testing.T) {
+			key, value, _ := strings.Cut(tc.env, "=")
+			t.Setenv(key, value)
+			var out, errOut bytes.Buffer
+			if code := run(context.Background(), []string{"tree"}, nil, &out, &errOut); code != 2 || !strings.Contains(errOut.String(), tc.want) {
+				t.Errorf("exit %d: %s", code, errOut.String())
+			}
+		})
+	}
+}
+
+func TestRunSettingsSections(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tutorial.yaml")
+	os.WriteFile(file, []byte("logging:\n  level: debug\nserve:\n  tls:\n    enabled: true\n    cer: x.pem\n"), 0o644)
+	var out, errOut bytes.Buffer
+	if code := run(context.Background(), []string{"-config", file, "config"}, nil, &out, &errOut); code != 2 {
+		t.Fatalf("exit %d", code)
+	}
+	if want := file + `:6: unknown setting "serve.tls.cer"`; !strings.Contains(errOut.String(), want) {
+		t.Errorf("errors lack %q:\n%s", want, errOut.String())
+	}
+
+	os.WriteFile(file, []byte("logging:\n  level: debug\nserve:\n  tls:\n    enabled: true\n  corpus:\n    dir: docs\n"), 0o644)
+	errOut.Reset()
+	if code := run(context.Background(), []string{"-config", file, "config", "-env"}, nil, &out, &errOut); code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+	for _, want := range []string{"TUTORIAL_LOGGING_LEVEL=DEBUG\n", "TUTORIAL_SERVE_TLS_ENABLED=true\n", "TUTORIAL_SERVE_CORPUS_DIR=docs\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRunConfig(t *testing.T) {
+	t.Setenv("TUTORIAL_SERVE_ADDR", ":9000")
+	var out, errOut bytes.Buffer
+	if code := run(context.Background(), []string{"-timeout", "1m", "config"}, nil, &out, &errOut); code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+	for _, want := range []string{"timeout: 1m0s\n", "logging:\n  level: INFO\n  format: text\n", "serve:\n  addr: :9000\n", "users:\n  file: users.json\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+
+	// The printed settings make a configuration file.
+	file := filepath.Join(t.TempDir(), "tutorial.yaml")
+	os.WriteFile(file, out.Bytes(), 0o644)
+	t.Setenv("TUTORIAL_SERVE_ADDR", "")
+	var again bytes.Buffer
+	if code := run(context.Background(), []string{"-config", file, "config"}, nil, &again, &errOut); code != 0 || again.String() != out.String() {
+		t.Fatalf("exit %d, read back:\n%s\nwant:\n%s%s", code, again.String(), out.String(), errOut.String())
+	}
+
+	out.Reset()
+	if code := runConfig(defaultSettings(), []string{"-env"}, &out, &errOut); code != 0 || !strings.Contains(out.String(), "TUTORIAL_DEDUPE_MIN_SIZE=1B\n") {
+		t.Fatalf("-env: exit %d:\n%s", code, out.String())
+	}
+	if code := runConfig(defaultSettings(), []string{"-env", "-json"}, &out, &errOut); code != 2 {
+		t.Fatalf("-env -json: exit %d", code)
+	}
+}
+
+func TestRunConfigRedactsSecrets(t *testing.T) {
+	secrets := map[string]string{
+		"TUTORIAL_SERVE_ADMIN_TOKEN":    "hunter2",
+		"TUTORIAL_SERVE_REDIS_PASSWORD": "swordfish",
+		"TUTORIAL_SERVE_JWT_SECRET":     "open sesame",
+	}
+	for name, value := range secrets {
+		t.Setenv(name, value)
+	}
+	for _, format := range []string{"-json", "-env", ""} {
+		args := []string{"config"}
+		if format != "" {
+			args = append(args, format)
+		}
+		var out, errOut bytes.Buffer
+		if code := run(context.Background(), args, nil, &out, &errOut); code != 0 {
+			t.Fatalf("%q: exit %d: %s", args, code, errOut.String())
+		}
+		for _, value := range secrets {
+			if strings.Contains(out.String(), value) {
+				t.Errorf("%q prints the secret %q:\n%s", args, value, out.String())
+			}
+		}
+		if !strings.Contains(out.String(), "redacted") {
+			t.Errorf("%q: nothing redacted:\n%s", args, out.String())
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Package corpus scores the terms of a document set with TF-IDF.
+package corpus
+
+import (
+	"math"
+	"sort"
+
+	"example.com/tutorial/text"
+)
+
+// TermScore is a term together with its TF-IDF weight in one document.
+type TermScore struct {
+	Term  string
+	Score float64
+}
+
+type document struct {
+	counts map[string]int
+	total  int
+}
+
+// Corpus is a named set of documents. Scores change as documents are
+// added, since inverse document frequency depends on the whole set.
+type Corpus struct {
+	docs  map[string]document
+	names []string
+	df    map[string]int // number of documents containing each term
+}
+
+// New returns an empty corpus.
+func New() *Corpus {
+	return &Corpus{docs: map[string]document{}, df: map[string]int{}}
+}
+
+// Add counts the words of s and stores them under name, replacing any
+// document previously added with that name.
+func (c *Corpus) Add(name, s string, opts ...text.Option) {
+	c.AddCounts(name, text.WordCount(s, opts...))
+}
+
+// AddCounts stores precomputed word counts under name, replacing any
+// document previously added with that name.
+func (c *Corpus) AddCounts(name string, counts map[string]int) {
+	if old, ok := c.docs[name]; ok {
+		for w := range old.counts {
+			c.df[w]--
+			if c.df[w] == 0 {
+				delete(c.df, w)
+			}
+		}
+	} else {
+		c.names = append(c.names, name)
+	}
+	d := document{counts: make(map[string]int, len(counts))}
+	for w, n := range counts {
+		if n <= 0 {
+			continue
+		}
+		d.counts[w] = n
+		d.total += n
+		c.df[w]++
+	}
+	c.docs[name] = d
+}
+
+// Len reports the number of documents.
+func (c *Corpus) Len() int { return len(c.docs) }
+
+// Names returns the document names in insertion order.
+func (c *Corpus) Names() []string {
+	return append([]string(nil), c.names...)
+}
+
+// IDF returns the smoothed inverse document frequency of term,
+// ln((1+N)/(1+df)) + 1, which stays positive for terms found in every
+// document and is defined for terms found in none.
+func (c *Corpus) IDF(term string) float64 {
+	n := float64(len(c.docs))
+	return math.Log((1+n)/(1+float64(c.df[term]))) + 1
+}
+
+// TFIDF returns the weight of every term of the named document, where the
+// term frequency is the term count divided by the document length. It
+// returns nil if there is no such document.
+func (c *Corpus) TFIDF(name string) map[string]float64 {
+	d, ok := c.docs[name]
+	if !ok {
+		return nil
+	}
+	scores := make(map[string]float64, len(d.counts))
+	for w, n := range d.counts {
+		scores[w] = float64(n) / float64(d.total) * c.IDF(w)
+	}
+	return scores
+}
+
+// TopTerms returns the n highest-scoring terms of the named document,
+// ordered by score descending and then alphabetically. A negative n
+// returns every term.
+func (c *Corpus) TopTerms(name string, n int) []TermScore {
+	scores := c.TFIDF(name)
+	terms := make([]TermScore, 0, len(scores))
+	for w, s := range scores {
+		terms = append(terms, TermScore{Term: w, Score: s})
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].Score != terms[j].Score {
+			return terms[i].Score > terms[j].Score
+		}
+		return terms[i].Term < terms[j].Term
+	})
+	if n >= 0 && n < len(terms) {
+		terms = terms[:n]
+	}
+	return terms
+}
@@ -0,0 +1,55 @@
+package corpus
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTFIDF(t *testing.T) {
+	c := New()
+	c.Add("a", "the cat sat on the mat")
+	c.Add("b", "the dog sat")
+	c.Add("c", "the end")
+
+	if c.Len() != 3 {
+		t.Fatalf("Len = %d, want 3", c.Len())
+	}
+	scores := c.TFIDF("a")
+	// "cat" appears only in a; "the" appears everywhere.
+	if scores["cat"] <= scores["sat"] || scores["sat"] <= scores["the"]/2 {
+		t.Fatalf("unexpected ordering: %v", scores)
+	}
+	want := 1.0 / 6 * (math.Log(4.0/2.0) + 1)
+	if got := scores["cat"]; math.Abs(got-want) > 1e-12 {
+		t.Fatalf("tfidf(cat) = %v, want %v", got, want)
+	}
+	if c.TFIDF("missing") != nil {
+		t.Fatal("expected nil scores for unknown document")
+	}
+}
+
+func TestTopTerms(t *testing.T) {
+	c := New()
+	c.Add("a", "go go gophers rust")
+	c.Add("b", "rust rust crabs")
+	top := c.TopTerms("a", 2)
+	if len(top) != 2 || top[0].Term != "go" || top[1].Term != "gophers" {
+		t.Fatalf("TopTerms = %v", top)
+	}
+	if all := c.TopTerms("a", -1); len(all) != 3 {
+		t.Fatalf("TopTerms(-1) = %v", all)
+	}
+}
+
+func TestAddReplacesDocument(t *testing.T) {
+	c := New()
+	c.Add("a", "go")
+	c.Add("b", "go")
+	c.Add("a", "rust")
+	if c.Len() != 2 || len(c.Names()) != 2 {
+		t.Fatalf("Len = %d, names = %v", c.Len(), c.Names())
+	}
+	if got, want := c.IDF("go"), math.Log(3.0/2.0)+1; math.Abs(got-want) > 1e-12 {
+		t.Fatalf("IDF(go) = %v, want %v", got, want)
+	}
+}
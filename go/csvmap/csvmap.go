@@ -0,0 +1,456 @@
+// Package csvmap maps CSV records to and from structs. A header record
+// names the columns, which are matched to struct fields through their
+// "csv" tags:
+//
+//	type Row struct {
+//		ID    int       `csv:"id"`
+//		Name  string    `csv:"name,required"`
+//		Seen  time.Time `csv:"seen"`
+//		Notes string    `csv:"-"`
+//	}
+//
+// A field without a tag is the column of its name; "-" leaves the field
+// out and the "required" option makes a header without the column an
+// error. Columns match case-insensitively and may come in any order.
+//
+// Fields may be strings, booleans, integers, floats, time.Durations,
+// types implementing encoding.TextMarshaler and TextUnmarshaler, or
+// pointers to any of these, which an empty cell leaves nil. An empty
+// cell decodes to the zero value, and whitespace around numbers,
+// booleans and durations is ignored.
+package csvmap
+
+import (
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Errors returned for a header or type that cannot be mapped; they are
+// wrapped with the column or field in question.
+var (
+	ErrMissingHeader   = errors.New("csvmap: missing header")
+	ErrMissingColumn   = errors.New("csvmap: missing required column")
+	ErrUnknownColumn   = errors.New("csvmap: unknown column")
+	ErrDuplicateColumn = errors.New("csvmap: duplicate column")
+	ErrUnsupportedType = errors.New("csvmap: unsupported type")
+)
+
+// Options configures a Decoder.
+type Options struct {
+	// DisallowUnknown makes a header column that matches no field an
+	// error; otherwise such columns are ignored.
+	DisallowUnknown bool
+	// Comma is the field delimiter; zero means ','.
+	Comma rune
+}
+
+var (
+	textMarshaler   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType    = reflect.TypeOf(time.Duration(0))
+)
+
+// field is a struct field mapped to a column.
+type field struct {
+	column   string
+	index    int
+	required bool
+}
+
+// fieldsOf returns the mapped fields of struct type t, in field order.
+func fieldsOf(t reflect.Type) ([]field, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w %s: not a struct", ErrUnsupportedType, t)
+	}
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("csv")
+		if !sf.IsExported() || tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = sf.Name
+		}
+		if !supported(sf.Type) {
+			return nil, fmt.Errorf("%w %s of field %s", ErrUnsupportedType, sf.Type, sf.Name)
+		}
+		fields = append(fields, field{column: name, index: i, required: opts == "required"})
+	}
+	return fields, nil
+}
+
+func supported(t reflect.Type) bool {
+	if t.Implements(textMarshaler) && reflect.PointerTo(t).Implements(textUnmarshaler) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Pointer:
+		return t.Elem().Kind() != reflect.Pointer && supported(t.Elem())
+	}
+	return false
+}
+
+// RowError reports a record that could not be decoded.
+type RowError struct {
+	Line int // 1-based line number in the input
+	Err  error
+}
+
+func (e *RowError) Error() string { return fmt.Sprintf("line %d: %v", e.Line, e.Err) }
+
+func (e *RowError) Unwrap() error { return e.Err }
+
+// RowErrors lists the rejected records of an input, as DecodeAll
+// returns them.
+type RowErrors []*RowError
+
+func (e RowErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, re := range e {
+		msgs[i] = re.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// FieldError reports a cell that could not be converted to its field.
+type FieldError struct {
+	Column string
+	Value  string
+	Err    error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s %q %v", e.Column, e.Value, e.Err)
+}
+
+func (e *FieldError) Unwrap() error { return e.Err }
+
+// Decoder reads structs of type T from CSV, one record at a time.
+type Decoder[T any] struct {
+	r      *csv.Reader
+	header []string
+	// cols holds the field index for each column, or -1.
+	cols   []int
+	fields []field
+	line   int
+}
+
+// NewDecoder reads the header from r and returns a Decoder for the
+// records that follow it. It fails if the header is missing, repeats a
+// column or lacks a required one, or with Options.DisallowUnknown names
+// a column that T has no field for.
+func NewDecoder[T any](r io.Reader, opts Options) (*Decoder[T], error) {
+	fields, err := fieldsOf(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	if opts.Comma != 0 {
+		cr.Comma = opts.Comma
+	}
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, ErrMissingHeader
+	}
+	if err != nil {
+		return nil, fmt.Errorf("csvmap: %w", err)
+	}
+	d := &Decoder[T]{r: cr, header: header, cols: make([]int, len(header)), fields: fields, line: 1}
+	seen := make([]bool, len(fields))
+	for i, h := range header {
+		h = strings.TrimSpace(h)
+		d.cols[i] = -1
+		for j, f := range fields {
+			if strings.EqualFold(f.column, h) {
+				d.cols[i] = j
+				break
+			}
+		}
+		switch j := d.cols[i]; {
+		case j < 0 && opts.DisallowUnknown:
+			return nil, fmt.Errorf("%w %q", ErrUnknownColumn, h)
+		case j < 0:
+		case seen[j]:
+			return nil, fmt.Errorf("%w %q", ErrDuplicateColumn, h)
+		default:
+			seen[j] = true
+		}
+	}
+	for j, f := range fields {
+		if f.required && !seen[j] {
+			return nil, fmt.Errorf("%w %q", ErrMissingColumn, f.column)
+		}
+	}
+	return d, nil
+}
+
+// Header returns the columns of the header record as given.
+func (d *Decoder[T]) Header() []string { return d.header }
+
+// Line returns the line on which the last record read began.
+func (d *Decoder[T]) Line() int { return d.line }
+
+// Decode returns the next record as a T, or io.EOF after the last. A
+// record that is malformed, has the wrong number of fields or has a cell
+// that does not convert is reported as a *RowError, and decoding may go
+// on with the next record; any other error ends the input.
+func (d *Decoder[T]) Decode() (T, error) {
+	var v T
+	rec, err := d.r.Read()
+	if err == io.EOF {
+		return v, io.EOF
+	}
+	d.line, _ = d.r.FieldPos(0)
+	if err != nil {
+		var perr *csv.ParseError
+		if errors.As(err, &perr) {
+			d.line = perr.Line
+			return v, &RowError{Line: perr.Line, Err: perr.Err}
+		}
+		return v, fmt.Errorf("csvmap: %w", err)
+	}
+	if len(rec) != len(d.header) {
+		return v, &RowError{Line: d.line, Err: fmt.Errorf("got %d fields, want %d", len(rec), len(d.header))}
+	}
+	rv := reflect.ValueOf(&v).Elem()
+	for i, cell := range rec {
+		j := d.cols[i]
+		if j < 0 {
+			continue
+		}
+		f := d.fields[j]
+		if err := setField(rv.Field(f.index), cell); err != nil {
+			return v, &RowError{Line: d.line, Err: &FieldError{Column: f.column, Value: cell, Err: err}}
+		}
+	}
+	return v, nil
+}
+
+// DecodeAll decodes every record of r. Records that fail are skipped and
+// reported together as RowErrors, alongside the values that were
+// decoded; a bad header fails the whole input.
+func DecodeAll[T any](r io.Reader, opts Options) ([]T, error) {
+	d, err := NewDecoder[T](r, opts)
+	if err != nil {
+		return nil, err
+	}
+	var vals []T
+	var rowErrs RowErrors
+	for {
+		v, err := d.Decode()
+		if err == io.EOF {
+			break
+		}
+		var re *RowError
+		if errors.As(err, &re) {
+			rowErrs = append(rowErrs, re)
+			continue
+		}
+		if err != nil {
+			return vals, err
+		}
+		vals = append(vals, v)
+	}
+	if len(rowErrs) > 0 {
+		return vals, rowErrs
+	}
+	return vals, nil
+}
+
+// Conversion errors, reported within a FieldError.
+var (
+	errNotInteger  = errors.New("is not an integer")
+	errNotNumber   = errors.New("is not a number")
+	errNotBool     = errors.New("is not a boolean")
+	errNotDuration = errors.New("is not a duration")
+	errOutOfRange  = errors.New("is out of range")
+)
+
+func setField(v reflect.Value, cell string) error {
+	if v.Kind() == reflect.Pointer {
+		if cell == "" {
+			v.SetZero()
+			return nil
+		}
+		p := reflect.New(v.Type().Elem())
+		if err := setField(p.Elem(), cell); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+	if v.Addr().Type().Implements(textUnmarshaler) {
+		if cell == "" {
+			v.SetZero()
+			return nil
+		}
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(cell))
+	}
+	if v.Kind() == reflect.String {
+		v.SetString(cell)
+		return nil
+	}
+	s := strings.TrimSpace(cell)
+	if s == "" {
+		v.SetZero()
+		return nil
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return errNotDuration
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return errNotBool
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return rangeErr(err, errNotInteger)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return rangeErr(err, errNotInteger)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return rangeErr(err, errNotNumber)
+		}
+		v.SetFloat(n)
+	}
+	return nil
+}
+
+// rangeErr returns errOutOfRange for a strconv range error and syntax
+// otherwise.
+func rangeErr(err, syntax error) error {
+	if errors.Is(err, strconv.ErrRange) {
+		return errOutOfRange
+	}
+	return syntax
+}
+
+// Encoder writes structs of type T as CSV records under a header of
+// their columns, in field order.
+type Encoder[T any] struct {
+	w       *csv.Writer
+	fields  []field
+	started bool
+	rec     []string
+}
+
+// NewEncoder returns an Encoder writing to w. The header is written with
+// the first record, or by Flush if there is none.
+func NewEncoder[T any](w io.Writer) (*Encoder[T], error) {
+	fields, err := fieldsOf(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+	return &Encoder[T]{w: csv.NewWriter(w), fields: fields, rec: make([]string, len(fields))}, nil
+}
+
+func (e *Encoder[T]) writeHeader() error {
+	if e.started {
+		return nil
+	}
+	e.started = true
+	for i, f := range e.fields {
+		e.rec[i] = f.column
+	}
+	return e.w.Write(e.rec)
+}
+
+// Encode writes v as a record. Output is buffered until Flush.
+func (e *Encoder[T]) Encode(v T) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(v)
+	for i, f := range e.fields {
+		s, err := formatField(rv.Field(f.index))
+		if err != nil {
+			return fmt.Errorf("csvmap: column %s: %w", f.column, err)
+		}
+		e.rec[i] = s
+	}
+	return e.w.Write(e.rec)
+}
+
+// Flush writes any buffered records, and the header if nothing was
+// encoded, and returns the first error writing them.
+func (e *Encoder[T]) Flush() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// EncodeAll writes vals to w under their header.
+func EncodeAll[T any](w io.Writer, vals []T) error {
+	e, err := NewEncoder[T](w)
+	if err != nil {
+		return err
+	}
+	for _, v := range vals {
+		if err := e.Encode(v); err != nil {
+			return err
+		}
+	}
+	return e.Flush()
+}
+
+func formatField(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	if v.Type().Implements(textMarshaler) {
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err
+	}
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String(), nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	default:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	}
+}
@@ -0,0 +1,140 @@
+package csvmap
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type row struct {
+	ID      int           `csv:"id"`
+	Name    string        `csv:"name,required"`
+	Score   float64       `csv:"score"`
+	Active  bool          `csv:"active"`
+	Timeout time.Duration `csv:"timeout"`
+	Seen    time.Time     `csv:"seen"`
+	Parent  *uint8        `csv:"parent"`
+	Label   string
+	Skipped string `csv:"-"`
+	hidden  string
+}
+
+func TestRoundTrip(t *testing.T) {
+	one := uint8(1)
+	rows := []row{
+		{ID: 1, Name: "Ada, Countess", Score: 9.5, Active: true, Timeout: 90 * time.Second, Seen: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), Label: "a"},
+		{ID: 2, Name: `"Grace"`, Parent: &one},
+	}
+	var b strings.Builder
+	if err := EncodeAll(&b, rows); err != nil {
+		t.Fatal(err)
+	}
+	want := "id,name,score,active,timeout,seen,parent,Label\n" +
+		"1,\"Ada, Countess\",9.5,true,1m30s,2024-03-01T12:00:00Z,,a\n" +
+		"2,\"\"\"Grace\"\"\",0,false,0s,0001-01-01T00:00:00Z,1,\n"
+	if b.String() != want {
+		t.Fatalf("encoded:\n%s\nwant:\n%s", b.String(), want)
+	}
+	got, err := DecodeAll[row](strings.NewReader(b.String()), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, rows) {
+		t.Fatalf("decoded %+v, want %+v", got, rows)
+	}
+
+	b.Reset()
+	if err := EncodeAll[row](&b, nil); err != nil || b.String() != "id,name,score,active,timeout,seen,parent,Label\n" {
+		t.Fatalf("empty: %q, %v", b.String(), err)
+	}
+}
+
+func TestDecodeColumns(t *testing.T) {
+	in := "Label;extra; NAME ;id\nx;?;Ada; 7 \n;;Bob;\n"
+	got, err := DecodeAll[row](strings.NewReader(in), Options{Comma: ';'})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []row{{ID: 7, Name: "Ada", Label: "x"}, {Name: "Bob"}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestHeaderErrors(t *testing.T) {
+	for in, want := range map[string]error{
+		"":                  ErrMissingHeader,
+		"id\n1\n":           ErrMissingColumn,
+		"name,email\nA,a\n": ErrUnknownColumn,
+		"name,NAME\nA,B\n":  ErrDuplicateColumn,
+	} {
+		if _, err := NewDecoder[row](strings.NewReader(in), Options{DisallowUnknown: true}); !errors.Is(err, want) {
+			t.Errorf("%q: %v, want %v", in, err, want)
+		}
+	}
+	if _, err := NewDecoder[row](strings.NewReader("\"name\n"), Options{}); err == nil {
+		t.Error("malformed header accepted")
+	}
+	type bad struct{ C chan int }
+	if _, err := NewDecoder[bad](strings.NewReader("C\n"), Options{}); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("chan field: %v", err)
+	}
+	if _, err := NewEncoder[int](io.Discard); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("int: %v", err)
+	}
+}
+
+func TestRowErrors(t *testing.T) {
+	in := "id,name,active,parent\n" +
+		"1,Ada,true,\n" +
+		"x,Bad,,\n" +
+		"3,Few\n" +
+		"4,Bool,maybe,\n" +
+		"5,Big,,300\n" +
+		"6,\"open,,\n"
+	got, err := DecodeAll[row](strings.NewReader(in), Options{})
+	if len(got) != 1 || got[0].Name != "Ada" {
+		t.Fatalf("decoded %+v", got)
+	}
+	var rowErrs RowErrors
+	if !errors.As(err, &rowErrs) || len(rowErrs) != 5 {
+		t.Fatalf("err %v", err)
+	}
+	wants := []string{
+		`line 3: id "x" is not an integer`,
+		"line 4: got 2 fields, want 4",
+		`line 5: active "maybe" is not a boolean`,
+		`line 6: parent "300" is out of range`,
+		"line 7: ",
+	}
+	for i, want := range wants {
+		if !strings.HasPrefix(rowErrs[i].Error(), want) {
+			t.Errorf("error %d = %q, want %q", i, rowErrs[i], want)
+		}
+	}
+	var ferr *FieldError
+	if !errors.As(rowErrs[0], &ferr) || ferr.Column != "id" || ferr.Value != "x" {
+		t.Fatalf("FieldError %+v", ferr)
+	}
+}
+
+func TestDecoderLine(t *testing.T) {
+	d, err := NewDecoder[row](strings.NewReader("name\nAda\n\n\"multi\nline\"\nBob\n"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []int
+	for {
+		if _, err := d.Decode(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, d.Line())
+	}
+	if !reflect.DeepEqual(lines, []int{2, 4, 6}) || !reflect.DeepEqual(d.Header(), []string{"name"}) {
+		t.Fatalf("lines %v, header %v", lines, d.Header())
+	}
+}
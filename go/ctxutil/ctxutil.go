@@ -0,0 +1,125 @@
+// Package ctxutil has helpers for the context patterns that come up
+// around deadlines and cancellation: waiting on two contexts at once,
+// cancelling on a signal, outliving a request, and sleeping.
+package ctxutil
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// merged is done when either of two contexts is, takes its deadline
+// from whichever is sooner and its values from both, the first taking
+// precedence.
+type merged struct {
+	context.Context // derived from first
+	first, second   context.Context
+}
+
+// Merge returns a context that is done as soon as ctx1 or ctx2 is, with
+// that context's error and cause, and that looks values up in ctx1 and
+// then ctx2. A typical use is bounding work started on behalf of a
+// request by the server's own lifetime as well. The returned cancel
+// must be called to release the resources tied to ctx2.
+func Merge(ctx1, ctx2 context.Context) (context.Context, context.CancelFunc) {
+	inner, cancel := context.WithCancelCause(ctx1)
+	stop := context.AfterFunc(ctx2, func() { cancel(context.Cause(ctx2)) })
+	return &merged{Context: inner, first: ctx1, second: ctx2}, func() {
+		stop()
+		cancel(context.Canceled)
+	}
+}
+
+func (m *merged) Deadline() (time.Time, bool) {
+	d1, ok1 := m.first.Deadline()
+	d2, ok2 := m.second.Deadline()
+	switch {
+	case !ok1:
+		return d2, ok2
+	case !ok2 || d1.Before(d2):
+		return d1, true
+	default:
+		return d2, true
+	}
+}
+
+// Err reports ctx2's error, rather than context.Canceled, when it was
+// ctx2 that ended the merged context.
+func (m *merged) Err() error {
+	err := m.Context.Err()
+	if err == nil || m.first.Err() != nil {
+		return err
+	}
+	if err2 := m.second.Err(); err2 != nil {
+		return err2
+	}
+	return err
+}
+
+func (m *merged) Value(key any) any {
+	if v := m.Context.Value(key); v != nil {
+		return v
+	}
+	return m.second.Value(key)
+}
+
+func (m *merged) String() string {
+	return fmt.Sprintf("ctxutil.Merge(%v, %v)", m.first, m.second)
+}
+
+// SignalError is the cause of a context cancelled by WithSignal.
+type SignalError struct {
+	Signal os.Signal
+}
+
+func (e SignalError) Error() string {
+	return "received signal " + e.Signal.String()
+}
+
+// WithSignal returns a context that is cancelled when the process
+// receives one of signals, or os.Interrupt if none are given. Unlike
+// signal.NotifyContext it records the signal as the context's cause, to
+// be read with context.Cause. Once the context is done the signals are
+// no longer caught, so a second one has its default effect; calling
+// cancel also stops catching them.
+func WithSignal(ctx context.Context, signals ...os.Signal) (context.Context, context.CancelFunc) {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt}
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+	go func() {
+		defer signal.Stop(ch)
+		select {
+		case sig := <-ch:
+			cancel(SignalError{sig})
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() { cancel(context.Canceled) }
+}
+
+// Detach returns a context with ctx's values but none of its deadline or
+// cancellation, for work that must finish even though the request that
+// started it has ended, such as writing an audit record. Bound such work
+// with a timeout of its own.
+func Detach(ctx context.Context) context.Context {
+	return context.WithoutCancel(ctx)
+}
+
+// Sleep pauses for d or until ctx is done, whichever comes first, and
+// returns ctx's error in the latter case.
+func Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
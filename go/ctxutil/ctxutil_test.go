@@ -0,0 +1,105 @@
+package ctxutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type key string
+
+func done(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return true
+	case <-time.After(time.Second):
+		return false
+	}
+}
+
+func TestMergeFirstCancelled(t *testing.T) {
+	c1, cancel1 := context.WithCancel(context.WithValue(context.Background(), key("a"), 1))
+	c2 := context.WithValue(context.Background(), key("b"), 2)
+	ctx, cancel := Merge(c1, c2)
+	defer cancel()
+	if ctx.Value(key("a")) != 1 || ctx.Value(key("b")) != 2 || ctx.Value(key("c")) != nil {
+		t.Fatal("values not looked up in both contexts")
+	}
+	if ctx.Err() != nil {
+		t.Fatal("done too early")
+	}
+	cancel1()
+	if !done(ctx) || !errors.Is(ctx.Err(), context.Canceled) {
+		t.Fatalf("after cancelling ctx1: err = %v", ctx.Err())
+	}
+}
+
+func TestMergeSecondDone(t *testing.T) {
+	c2, cancel2 := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel2()
+	cause := errors.New("server stopping")
+	c1, cancel1 := context.WithCancelCause(context.Background())
+	defer cancel1(nil)
+	ctx, cancel := Merge(c1, c2)
+	defer cancel()
+	if d, ok := ctx.Deadline(); !ok || time.Until(d) > 10*time.Millisecond {
+		t.Fatalf("Deadline = %v, %v; want ctx2's", d, ok)
+	}
+	if !done(ctx) || ctx.Err() != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want ctx2's DeadlineExceeded", ctx.Err())
+	}
+	if !errors.Is(context.Cause(ctx), context.DeadlineExceeded) {
+		t.Fatalf("cause = %v", context.Cause(ctx))
+	}
+
+	// The cause of ctx2 carries through.
+	c3, cancel3 := context.WithCancelCause(context.Background())
+	ctx, cancel = Merge(context.Background(), c3)
+	defer cancel()
+	cancel3(cause)
+	if !done(ctx) || context.Cause(ctx) != cause {
+		t.Fatalf("cause = %v, want %v", context.Cause(ctx), cause)
+	}
+}
+
+func TestMergeCancel(t *testing.T) {
+	ctx, cancel := Merge(context.Background(), context.Background())
+	if _, ok := ctx.Deadline(); ok {
+		t.Fatal("deadline without either having one")
+	}
+	cancel()
+	if !done(ctx) || ctx.Err() != context.Canceled {
+		t.Fatalf("err = %v", ctx.Err())
+	}
+}
+
+func TestDetach(t *testing.T) {
+	parent, cancel := context.WithTimeout(context.WithValue(context.Background(), key("id"), "req-1"), time.Millisecond)
+	cancel()
+	ctx := Detach(parent)
+	if ctx.Err() != nil || ctx.Done() != nil {
+		t.Fatal("detached context is cancelled with its parent")
+	}
+	if _, ok := ctx.Deadline(); ok {
+		t.Fatal("detached context kept the deadline")
+	}
+	if ctx.Value(key("id")) != "req-1" {
+		t.Fatal("values not kept")
+	}
+}
+
+func TestSleep(t *testing.T) {
+	if err := Sleep(context.Background(), time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	start := time.Now()
+	if err := Sleep(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("Sleep returned after %v", d)
+	}
+}
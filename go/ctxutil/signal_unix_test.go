@@ -0,0 +1,32 @@
+//go:build unix
+
+package ctxutil
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestWithSignal(t *testing.T) {
+	ctx, cancel := WithSignal(context.Background(), syscall.SIGUSR1)
+	defer cancel()
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	if !done(ctx) {
+		t.Fatal("not cancelled by the signal")
+	}
+	var se SignalError
+	if !errors.As(context.Cause(ctx), &se) || se.Signal != syscall.SIGUSR1 {
+		t.Fatalf("cause = %v", context.Cause(ctx))
+	}
+
+	ctx, cancel = WithSignal(context.Background(), syscall.SIGUSR1)
+	cancel()
+	if !done(ctx) || context.Cause(ctx) != context.Canceled {
+		t.Fatalf("cause after cancel = %v", context.Cause(ctx))
+	}
+}
@@ -0,0 +1,186 @@
+// Package debounce coalesces bursts of calls. A Debouncer runs its
+// function once a burst is over, with every value from the burst, as
+// re-indexing after a flurry of file-change events wants; a Throttler
+// runs its function at most once per interval, with the latest value.
+//
+// Both run their function from their own timer goroutines, but never
+// more than one call at a time, and both are safe for concurrent use.
+package debounce
+
+import (
+	"sync"
+	"time"
+)
+
+// Debouncer collects values and hands them to its function once wait
+// has passed without another Call.
+type Debouncer[T any] struct {
+	wait time.Duration
+	fn   func([]T)
+	run  sync.Mutex // held while fn runs
+
+	mu      sync.Mutex
+	pending []T
+	timer   *time.Timer
+	gen     uint64 // advanced by every Call, so stale timers do nothing
+	stopped bool
+}
+
+// Debounce returns a Debouncer running fn wait after the last of a burst
+// of calls.
+func Debounce[T any](wait time.Duration, fn func(batch []T)) *Debouncer[T] {
+	return &Debouncer[T]{wait: wait, fn: fn}
+}
+
+// Call adds v to the pending batch and restarts the wait. Calls after
+// Stop are ignored.
+func (d *Debouncer[T]) Call(v T) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopped {
+		return
+	}
+	d.pending = append(d.pending, v)
+	d.gen++
+	gen := d.gen
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.timer = time.AfterFunc(d.wait, func() { d.fire(gen) })
+}
+
+// fire runs the batch if no Call has come since the timer for gen was
+// set.
+func (d *Debouncer[T]) fire(gen uint64) {
+	d.run.Lock()
+	defer d.run.Unlock()
+	d.mu.Lock()
+	if gen != d.gen || len(d.pending) == 0 {
+		d.mu.Unlock()
+		return
+	}
+	batch := d.take()
+	d.mu.Unlock()
+	d.fn(batch)
+}
+
+// take removes the pending batch; d.mu must be held.
+func (d *Debouncer[T]) take() []T {
+	batch := d.pending
+	d.pending = nil
+	d.gen++
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	return batch
+}
+
+// Flush runs the pending batch now, if there is one, and returns once it
+// is done.
+func (d *Debouncer[T]) Flush() {
+	d.run.Lock()
+	defer d.run.Unlock()
+	d.mu.Lock()
+	if len(d.pending) == 0 {
+		d.mu.Unlock()
+		return
+	}
+	batch := d.take()
+	d.mu.Unlock()
+	d.fn(batch)
+}
+
+// Stop flushes the pending batch and makes later calls no-ops. It
+// returns once no call of fn is running.
+func (d *Debouncer[T]) Stop() {
+	d.mu.Lock()
+	d.stopped = true
+	d.mu.Unlock()
+	d.Flush()
+}
+
+// Throttler runs its function with the first value at once and then at
+// most once per interval, with the latest value received meanwhile.
+type Throttler[T any] struct {
+	interval time.Duration
+	fn       func(T)
+	run      sync.Mutex // held while fn runs
+
+	mu      sync.Mutex
+	timer   *time.Timer // set while an interval is running
+	latest  T
+	pending bool
+	stopped bool
+}
+
+// Throttle returns a Throttler running fn at most once per interval.
+func Throttle[T any](interval time.Duration, fn func(T)) *Throttler[T] {
+	return &Throttler[T]{interval: interval, fn: fn}
+}
+
+// Call runs fn(v) straight away, in the caller's goroutine, if no call
+// has run within the interval; otherwise v replaces any value waiting
+// for the interval to end. Calls after Stop are ignored.
+func (t *Throttler[T]) Call(v T) {
+	t.mu.Lock()
+	if t.stopped {
+		t.mu.Unlock()
+		return
+	}
+	if t.timer != nil {
+		t.latest, t.pending = v, true
+		t.mu.Unlock()
+		return
+	}
+	t.timer = time.AfterFunc(t.interval, t.tick)
+	t.mu.Unlock()
+
+	t.run.Lock()
+	defer t.run.Unlock()
+	t.fn(v)
+}
+
+// tick ends an interval, starting the next one with the waiting value
+// if there is one.
+func (t *Throttler[T]) tick() {
+	t.run.Lock()
+	defer t.run.Unlock()
+	t.mu.Lock()
+	if !t.pending || t.stopped {
+		t.timer = nil
+		t.mu.Unlock()
+		return
+	}
+	v := t.take()
+	t.timer = time.AfterFunc(t.interval, t.tick)
+	t.mu.Unlock()
+	t.fn(v)
+}
+
+// take removes the waiting value; t.mu must be held.
+func (t *Throttler[T]) take() T {
+	v := t.latest
+	var zero T
+	t.latest, t.pending = zero, false
+	return v
+}
+
+// Stop runs the waiting value, if any, without waiting for the interval
+// to end, and makes later calls no-ops. It returns once no call of fn is
+// running.
+func (t *Throttler[T]) Stop() {
+	t.run.Lock()
+	defer t.run.Unlock()
+	t.mu.Lock()
+	t.stopped = true
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	if !t.pending {
+		t.mu.Unlock()
+		return
+	}
+	v := t.take()
+	t.mu.Unlock()
+	t.fn(v)
+}
@@ -0,0 +1,157 @@
+package debounce
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("function not called")
+	}
+	var zero T
+	return zero
+}
+
+func none[T any](t *testing.T, ch <-chan T, d time.Duration) {
+	t.Helper()
+	select {
+	case v := <-ch:
+		t.Fatalf("unexpected call with %v", v)
+	case <-time.After(d):
+	}
+}
+
+func TestDebounceCoalesces(t *testing.T) {
+	batches := make(chan []string, 4)
+	d := Debounce(30*time.Millisecond, func(b []string) { batches <- b })
+	defer d.Stop()
+	for _, p := range []string{"a.txt", "b.txt", "a.txt"} {
+		d.Call(p)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := receive(t, batches); !reflect.DeepEqual(got, []string{"a.txt", "b.txt", "a.txt"}) {
+		t.Fatalf("batch %v", got)
+	}
+	none(t, batches, 60*time.Millisecond)
+
+	d.Call("c.txt")
+	if got := receive(t, batches); !reflect.DeepEqual(got, []string{"c.txt"}) {
+		t.Fatalf("second batch %v", got)
+	}
+}
+
+func TestDebounceRestartsWait(t *testing.T) {
+	var calls atomic.Int32
+	d := Debounce(40*time.Millisecond, func([]int) { calls.Add(1) })
+	defer d.Stop()
+	// Calls closer together than the wait keep postponing the run.
+	for i := 0; i < 8; i++ {
+		d.Call(i)
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := calls.Load(); n != 0 {
+		t.Fatalf("ran %d times during the burst", n)
+	}
+}
+
+func TestDebounceFlushAndStop(t *testing.T) {
+	var mu sync.Mutex
+	var got [][]int
+	d := Debounce(time.Hour, func(b []int) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, b)
+	})
+	d.Flush() // nothing pending
+	d.Call(1)
+	d.Call(2)
+	d.Flush()
+	d.Call(3)
+	d.Stop()
+	d.Call(4) // ignored
+	d.Flush()
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(got, [][]int{{1, 2}, {3}}) {
+		t.Fatalf("batches %v", got)
+	}
+}
+
+func TestDebounceNoOverlap(t *testing.T) {
+	var running, overlaps atomic.Int32
+	d := Debounce(time.Millisecond, func([]int) {
+		if running.Add(1) > 1 {
+			overlaps.Add(1)
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+	})
+	for i := 0; i < 30; i++ {
+		d.Call(i)
+		time.Sleep(time.Millisecond)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.Flush()
+		}()
+	}
+	wg.Wait()
+	d.Stop()
+	if n := overlaps.Load(); n != 0 {
+		t.Fatalf("%d overlapping calls", n)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	vals := make(chan int, 8)
+	th := Throttle(40*time.Millisecond, func(v int) { vals <- v })
+	defer th.Stop()
+	th.Call(1) // runs at once
+	if got := receive(t, vals); got != 1 {
+		t.Fatalf("leading call %d", got)
+	}
+	th.Call(2)
+	th.Call(3) // replaces 2
+	none(t, vals, 10*time.Millisecond)
+	if got := receive(t, vals); got != 3 {
+		t.Fatalf("trailing call %d, want the latest value", got)
+	}
+	// An interval follows the trailing call too; after a quiet one the
+	// next call runs at once again.
+	time.Sleep(100 * time.Millisecond)
+	start := time.Now()
+	th.Call(4)
+	if got := receive(t, vals); got != 4 || time.Since(start) > 30*time.Millisecond {
+		t.Fatalf("call after a quiet interval = %d after %v", got, time.Since(start))
+	}
+}
+
+func TestThrottleStop(t *testing.T) {
+	var mu sync.Mutex
+	var got []int
+	th := Throttle(time.Hour, func(v int) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, v)
+	})
+	th.Call(1)
+	th.Call(2)
+	th.Stop() // runs the waiting 2 without waiting an hour
+	th.Call(3)
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Fatalf("calls %v", got)
+	}
+}
@@ -0,0 +1,99 @@
+// Package dedup shares one computation among concurrent callers asking
+// for the same thing, in the manner of singleflight, while letting each
+// caller give up on its own.
+package dedup
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Group deduplicates calls by key. The zero value is ready to use.
+type Group[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*call[V]
+}
+
+type call[V any] struct {
+	done    chan struct{} // closed once val and err are set
+	val     V
+	err     error
+	waiters int  // callers still waiting
+	shared  bool // more than one caller waited
+	cancel  context.CancelFunc
+}
+
+// Do returns the result of fn for key, calling fn only if no call for
+// key is in flight and otherwise waiting for that call's result. shared
+// reports whether the result went to more than one caller.
+//
+// fn runs in its own goroutine with a context that is not canceled when
+// the caller that started it goes away, but only once every caller
+// waiting for it has: a caller whose ctx is done returns ctx.Err() at
+// once, and the others keep waiting. Results are not kept: a call for
+// key after fn returns runs fn again. A panic in fn is returned to the
+// waiters as an error.
+func (g *Group[K, V]) Do(ctx context.Context, key K, fn func(ctx context.Context) (V, error)) (v V, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[K]*call[V]{}
+	}
+	c, ok := g.calls[key]
+	if ok {
+		c.waiters++
+		c.shared = true
+	} else {
+		var fctx context.Context
+		c = &call[V]{done: make(chan struct{}), waiters: 1}
+		fctx, c.cancel = context.WithCancel(context.WithoutCancel(ctx))
+		g.calls[key] = c
+		go g.run(fctx, key, c, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.val, c.err, c.shared
+	case <-ctx.Done():
+	}
+	g.mu.Lock()
+	c.waiters--
+	if c.waiters == 0 {
+		// Nobody wants the result any more: stop fn, and let the next
+		// caller start afresh rather than join a canceled call.
+		c.cancel()
+		g.forget(key, c)
+	}
+	g.mu.Unlock()
+	var zero V
+	return zero, ctx.Err(), false
+}
+
+func (g *Group[K, V]) run(ctx context.Context, key K, c *call[V], fn func(context.Context) (V, error)) {
+	defer func() {
+		if p := recover(); p != nil {
+			c.err = fmt.Errorf("dedup: call panicked: %v", p)
+		}
+		g.mu.Lock()
+		g.forget(key, c)
+		g.mu.Unlock()
+		close(c.done)
+		c.cancel()
+	}()
+	c.val, c.err = fn(ctx)
+}
+
+// forget removes c if it is still the call for key; g.mu must be held.
+func (g *Group[K, V]) forget(key K, c *call[V]) {
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+}
+
+// InFlight returns the number of keys with a call running.
+func (g *Group[K, V]) InFlight() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.calls)
+}
@@ -0,0 +1,156 @@
+package dedup
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSharesOneCall(t *testing.T) {
+	var g Group[string, int]
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fn := func(context.Context) (int, error) {
+		calls.Add(1)
+		<-release
+		return 42, nil
+	}
+	const n = 5
+	var wg sync.WaitGroup
+	var sharedCount atomic.Int32
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err, shared := g.Do(context.Background(), "doc", fn)
+			if v != 42 || err != nil {
+				t.Errorf("Do = %d, %v", v, err)
+			}
+			if shared {
+				sharedCount.Add(1)
+			}
+		}()
+	}
+	waitFor(t, func() bool { return waiters(&g, "doc") == n })
+	close(release)
+	wg.Wait()
+	if c := calls.Load(); c != 1 {
+		t.Fatalf("fn called %d times", c)
+	}
+	if sharedCount.Load() != n {
+		t.Fatalf("%d of %d callers saw shared", sharedCount.Load(), n)
+	}
+	if g.InFlight() != 0 {
+		t.Fatal("call kept after it finished")
+	}
+
+	// Afterwards the next call runs fn again, unshared.
+	release = make(chan struct{})
+	close(release)
+	if _, _, shared := g.Do(context.Background(), "doc", fn); shared || calls.Load() != 2 {
+		t.Fatalf("second round: shared %v, %d calls", shared, calls.Load())
+	}
+}
+
+func TestErrorsAndPanicsBroadcast(t *testing.T) {
+	var g Group[int, string]
+	boom := errors.New("boom")
+	if _, err, _ := g.Do(context.Background(), 1, func(context.Context) (string, error) { return "", boom }); err != boom {
+		t.Fatalf("error = %v", err)
+	}
+	_, err, _ := g.Do(context.Background(), 1, func(context.Context) (string, error) { panic("bad input") })
+	if err == nil || !strings.Contains(err.Error(), "bad input") {
+		t.Fatalf("panic reported as %v", err)
+	}
+}
+
+func TestCallerCancellation(t *testing.T) {
+	var g Group[string, int]
+	started, release := make(chan struct{}), make(chan struct{})
+	var fnCtx context.Context
+	fn := func(ctx context.Context) (int, error) {
+		fnCtx = ctx
+		close(started)
+		<-release
+		return 7, nil
+	}
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err, _ := g.Do(ctx1, "k", fn)
+		first <- err
+	}()
+	<-started
+	second := make(chan int, 1)
+	go func() {
+		v, _, _ := g.Do(context.Background(), "k", fn)
+		second <- v
+	}()
+	waitFor(t, func() bool { return waiters(&g, "k") == 2 })
+
+	// The caller that started fn leaves; fn and the other caller carry on.
+	cancel1()
+	if err := <-first; err != context.Canceled {
+		t.Fatalf("canceled caller got %v", err)
+	}
+	if fnCtx.Err() != nil {
+		t.Fatal("fn canceled while a caller still waits")
+	}
+	close(release)
+	if v := <-second; v != 7 {
+		t.Fatalf("remaining caller got %d", v)
+	}
+}
+
+func TestLastCallerLeavingCancelsFn(t *testing.T) {
+	var g Group[string, int]
+	canceled := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err, _ := g.Do(ctx, "k", func(ctx context.Context) (int, error) {
+			<-ctx.Done()
+			close(canceled)
+			return 0, ctx.Err()
+		})
+		done <- err
+	}()
+	waitFor(t, func() bool { return waiters(&g, "k") == 1 })
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Do = %v", err)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("fn not canceled after every caller left")
+	}
+	// A new caller does not join the abandoned call.
+	if v, err, _ := g.Do(context.Background(), "k", func(context.Context) (int, error) { return 1, nil }); v != 1 || err != nil {
+		t.Fatalf("fresh call = %d, %v", v, err)
+	}
+}
+
+func waiters[K comparable, V any](g *Group[K, V], key K) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if c, ok := g.calls[key]; ok {
+		return c.waiters
+	}
+	return 0
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not reached")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"example.com/tutorial/dupes"
+	"example.com/tutorial/fileutil"
+	"example.com/tutorial/humanize"
+	"example.com/tutorial/output"
+	"example.com/tutorial/progress"
+)
+
+// runDedupe implements the "dedupe" subcommand and returns the process
+// exit code. It searches the current directory when given no argument.
+// With -delete it then keeps the first file of each group and removes
+// the others, printing each, or in a dry run what it would remove.
+func runDedupe(ctx context.Context, cfg dedupeSettings, dryRun bool, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("dedupe", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: dedupe [-min-size size] [-h] [-json] [-delete] [-progress mode] [dir]")
+		fs.PrintDefaults()
+	}
+	minSize := cfg.MinSize
+	fs.TextVar(&minSize, "min-size", cfg.MinSize, "ignore files smaller than `size`, such as 4KiB")
+	human := fs.Bool("h", cfg.Human, "print sizes in human-readable units (1.5K, 12M)")
+	asJSON := fs.Bool("json", false, "print the duplicate groups as JSON")
+	remove := fs.Bool("delete", false, "remove all but the first file of each group")
+	mode := cfg.Progress
+	fs.TextVar(&mode, "progress", cfg.Progress, progressUsage)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+	status := 0
+	opts := dupes.Options{MinSize: int64(minSize), Progress: progress.NewTracker()}
+	rep := progress.Start(stderr, opts.Progress, progress.Options{Mode: mode, Label: "dedupe"})
+	r, err := dupes.Find(ctx, dir, opts)
+	rep.Stop()
+	if err != nil {
+		fmt.Fprintln(stderr, "dedupe:", err)
+		status = 1
+		if r == nil {
+			return status
+		}
+	}
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(r)
+	} else {
+		var b strings.Builder
+		r.WriteTable(&b, *human)
+		err = output.For(stdout).WriteTable(b.String())
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "dedupe:", err)
+		return 1
+	}
+	if *remove {
+		// The removals follow the table, or go to stderr beside JSON.
+		log := stdout
+		if *asJSON {
+			log = stderr
+		}
+		files := newExecutor(dryRun, log)
+		if files == nil {
+			files = &fileutil.Executor{Log: log}
+		}
+		freed, err := r.RemoveDuplicates(files)
+		if err != nil {
+			fmt.Fprintln(stderr, "dedupe:", err)
+			status = 1
+		}
+		size := strconv.FormatInt(freed, 10)
+		if *human {
+			size = humanize.CompactBytes(freed)
+		}
+		verb := "freed"
+		if dryRun {
+			verb = "would free"
+		}
+		fmt.Fprintf(log, "%s %s\n", verb, size)
+	}
+	return status
+}
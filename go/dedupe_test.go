@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"example.com/tutorial/dupes"
+)
+
+func dedupeDir(t *testing.T) string {
+	t.Helper()
+	return writeTree(t, map[string]string{"a": "same", "sub/b": "same", "c": "diff"})
+}
+
+func TestRunDedupe(t *testing.T) {
+	dir := dedupeDir(t)
+	var out, errOut bytes.Buffer
+	if code := runDedupe(context.Background(), defaultSettings().Dedupe, false, []string{dir}, &out, &errOut); code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+	want := "SIZE  COPIES  RECLAIMABLE  PATH\n" +
+		"   4       2            4  " + filepath.Join(dir, "a") + "\n" +
+		"                           " + filepath.Join(dir, "sub", "b") + "\n" +
+		"\n1 duplicate groups in 3 files, 4 reclaimable\n"
+	if got := out.String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	out.Reset()
+	if code := runDedupe(context.Background(), defaultSettings().Dedupe, false, []string{"-json", "-min-size", "5", dir}, &out, &errOut); code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+	var r dupes.Report
+	if err := json.Unmarshal(out.Bytes(), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Files != 3 || len(r.Groups) != 0 || !strings.Contains(out.String(), `"groups": []`) {
+		t.Fatalf("json:\n%s", out.String())
+	}
+}
+
+func TestRunDedupeErrors(t *testing.T) {
+	var out, errOut bytes.Buffer
+	if code := runDedupe(context.Background(), defaultSettings().Dedupe, false, []string{filepath.Join(t.TempDir(), "missing")}, &out, &errOut); code != 1 {
+		t.Fatalf("missing dir: exit %d", code)
+	}
+	if code := runDedupe(context.Background(), defaultSettings().Dedupe, false, []string{"a", "b"}, &out, &errOut); code != 2 {
+		t.Fatalf("two dirs: exit %d", code)
+	}
+}
+
+func TestRunDedupeDelete(t *testing.T) {
+	dir := dedupeDir(t)
+	b := filepath.Join(dir, "sub", "b")
+	var out, errOut bytes.Buffer
+	if code := runDedupe(context.Background(), defaultSettings().Dedupe, true, []string{"-delete", dir}, &out, &errOut); code != 0 {
+		t.Fatalf("dry run: exit %d: %s", code, errOut.String())
+	}
+	if !strings.HasSuffix(out.String(), "reclaimable\nwould remove "+b+"\nwould free 4\n") {
+		t.Fatalf("dry run:\n%s", out.String())
+	}
+	if _, err := os.Stat(b); err != nil {
+		t.Fatalf("dry run removed %s: %v", b, err)
+	}
+
+	out.Reset()
+	if code := runDedupe(context.Background(), defaultSettings().Dedupe, false, []string{"-delete", "-json", dir}, &out, &errOut); code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+	if !json.Valid(out.Bytes()) || !strings.HasSuffix(errOut.String(), "remove "+b+"\nfreed 4\n") {
+		t.Fatalf("stdout %q, stderr %q", out.String(), errOut.String())
+	}
+	if _, err := os.Stat(b); !os.IsNotExist(err) {
+		t.Fatalf("%s not removed: %v", b, err)
+	}
+}
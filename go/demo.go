@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"example.com/tutorial/assets"
+	"example.com/tutorial/ctxutil"
+	"example.com/tutorial/fsutil"
+	"example.com/tutorial/middleware"
+	"example.com/tutorial/output"
+	"example.com/tutorial/text"
+	"example.com/tutorial/user"
+)
+
+// runDemo implements the "demo" subcommand: a tour of a few of the
+// tutorial's packages. It counts the built-in sample corpus, or the one
+// in the sample directory of assetsDir.
+func runDemo(ctx context.Context, assetsDir string, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("demo", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: demo")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+
+	fmt.Fprintln(stdout, "=== Go demo ===")
+
+	u := user.User{ID: "1", Name: "Ada"}
+	fmt.Fprintf(stdout, "User: %+v\n", u)
+
+	counts := text.WordCount("Go go gophers!")
+	fmt.Fprintln(stdout, "WordCount:", counts)
+	fmt.Fprintln(stdout, "TopWords:", text.TopWords(counts, 1))
+	fmt.Fprint(stdout, text.RenderHistogram(counts, 30, text.HistogramColor(output.For(stdout).Color())))
+	fmt.Fprintln(stdout, "Bigrams:", text.NGramCount("Go go gophers!", 2))
+
+	sample := assets.Override(assets.Sample(), assetDir(assetsDir, "sample"))
+	tree, err := fsutil.Tree(sample, ".", fsutil.TreeOptions{})
+	if err != nil {
+		fmt.Fprintln(stderr, "demo:", err)
+		return 1
+	}
+	tree.Name = "sample"
+	fmt.Fprintln(stdout, "Sample corpus:")
+	tree.WriteText(stdout, true)
+	sampleCounts, err := demoCountFS(sample)
+	if err != nil {
+		fmt.Fprintln(stderr, "demo:", err)
+		return 1
+	}
+	fmt.Fprintln(stdout, "Sample TopWords:", text.TopWords(sampleCounts, 3))
+
+	// A handler that takes 1ms, given 10ms by the timeout middleware.
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ctxutil.Sleep(r.Context(), 1*time.Millisecond) == nil {
+			fmt.Fprintln(w, "timer fired")
+		}
+	})
+	rec := httptest.NewRecorder()
+	middleware.Timeout(middleware.TimeoutConfig{Default: 10 * time.Millisecond})(slow).
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	if rec.Code == http.StatusGatewayTimeout {
+		fmt.Fprintln(stdout, "timeout")
+	} else {
+		fmt.Fprint(stdout, rec.Body)
+	}
+	return 0
+}
+
+// demoCountFS counts the words of the files of fsys, leaving out
+// English stopwords.
+func demoCountFS(fsys fs.FS) (map[string]int, error) {
+	total := map[string]int{}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		f, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		counts, err := text.WordCountReader(f, text.WithStopwords(text.EnglishStopwords))
+		for word, c := range counts {
+			total[word] += c
+		}
+		return err
+	})
+	return total, err
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"example.com/tutorial/diag"
+	"example.com/tutorial/httpclient"
+)
+
+// diagTimeout bounds how long diag -server waits for the server.
+const diagTimeout = 10 * time.Second
+
+// runDiag implements the "diag" subcommand: it prints, as JSON, the
+// runtime diagnostics of package diag, either of this process or, with
+// -server, of a running server's /debug/info, for attaching to reports
+// of performance problems.
+func runDiag(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("diag", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: diag [-server url]")
+		fs.PrintDefaults()
+	}
+	server := fs.String("server", "", "report on the server at `url`, such as localhost:8080, instead of this process")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+	if *server != "" {
+		if err := fetchDiag(ctx, *server, stdout); err != nil {
+			fmt.Fprintln(stderr, "diag:", err)
+			return 1
+		}
+		return 0
+	}
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(diag.Collect()); err != nil {
+		fmt.Fprintln(stderr, "diag:", err)
+		return 1
+	}
+	return 0
+}
+
+// fetchDiag copies the /debug/info of the server at base to w. A base
+// without a scheme is taken to be http.
+func fetchDiag(ctx context.Context, base string, w io.Writer) error {
+	if !strings.Contains(base, "://") {
+		base = "http://" + base
+	}
+	url := strings.TrimSuffix(base, "/") + "/debug/info"
+	ctx, cancel := context.WithTimeout(ctx, diagTimeout)
+	defer cancel()
+	resp, err := httpclient.New(nil, httpclient.Config{}).Get(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
@@ -0,0 +1,168 @@
+// Package diag reports the state of the running process for diagnosing
+// performance problems: the scheduler's view (GOMAXPROCS, CPUs,
+// goroutines), the heap, recent garbage collections, open file
+// descriptors and the build, as one JSON document for the "diag" command
+// and the /debug/info endpoint.
+package diag
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"runtime"
+	"time"
+
+	"example.com/tutorial/buildinfo"
+)
+
+// started is when the process started, near enough.
+var started = time.Now()
+
+// recentPauses is how many of the latest GC pauses Info lists.
+const recentPauses = 10
+
+// Info is a snapshot of the process.
+type Info struct {
+	Time       time.Time      `json:"time"`
+	UptimeMS   float64        `json:"uptime_ms"`
+	PID        int            `json:"pid"`
+	GOMAXPROCS int            `json:"gomaxprocs"`
+	NumCPU     int            `json:"num_cpu"`
+	Goroutines int            `json:"goroutines"`
+	CgoCalls   int64          `json:"cgo_calls"`
+	OpenFiles  int            `json:"open_files"` // -1 if the platform does not say
+	Heap       Heap           `json:"heap"`
+	GC         GC             `json:"gc"`
+	Build      buildinfo.Info `json:"build"`
+	// Settings are those the process can change while it runs, such as
+	// a server's log level, as NewHandler's caller reports them; nil
+	// elsewhere.
+	Settings any `json:"settings,omitempty"`
+}
+
+// Heap is the memory the Go runtime holds, in bytes unless named
+// otherwise; see runtime.MemStats for the meaning of each.
+type Heap struct {
+	Alloc      uint64 `json:"alloc"`       // bytes of live and not yet freed objects
+	TotalAlloc uint64 `json:"total_alloc"` // bytes allocated over the process's life
+	Sys        uint64 `json:"sys"`         // bytes obtained from the OS, for all uses
+	HeapSys    uint64 `json:"heap_sys"`
+	HeapIdle   uint64 `json:"heap_idle"`
+	HeapInuse  uint64 `json:"heap_inuse"`
+	Released   uint64 `json:"heap_released"`
+	Objects    uint64 `json:"objects"`
+	Mallocs    uint64 `json:"mallocs"`
+	Frees      uint64 `json:"frees"`
+	StackInuse uint64 `json:"stack_inuse"`
+	NextGC     uint64 `json:"next_gc"` // the heap size that triggers the next collection
+}
+
+// GC describes the garbage collections so far.
+type GC struct {
+	NumGC        uint32     `json:"num_gc"`
+	NumForced    uint32     `json:"num_forced"`
+	Last         *time.Time `json:"last,omitempty"` // nil before the first
+	PauseTotalMS float64    `json:"pause_total_ms"`
+	// RecentPausesMS are the stop-the-world pauses of the latest
+	// collections, most recent first.
+	RecentPausesMS []float64 `json:"recent_pauses_ms"`
+	CPUFraction    float64   `json:"cpu_fraction"` // of the CPU time available since start
+}
+
+// Collect returns the Info of the running process. It reads the memory
+// statistics, which stops the world for a moment, so it is for calling
+// on request rather than in a loop.
+func Collect() Info {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	now := time.Now()
+	info := Info{
+		Time:       now,
+		UptimeMS:   ms(now.Sub(started)),
+		PID:        os.Getpid(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		NumCPU:     runtime.NumCPU(),
+		Goroutines: runtime.NumGoroutine(),
+		CgoCalls:   runtime.NumCgoCall(),
+		OpenFiles:  openFiles(),
+		Heap: Heap{
+			Alloc:      m.HeapAlloc,
+			TotalAlloc: m.TotalAlloc,
+			Sys:        m.Sys,
+			HeapSys:    m.HeapSys,
+			HeapIdle:   m.HeapIdle,
+			HeapInuse:  m.HeapInuse,
+			Released:   m.HeapReleased,
+			Objects:    m.HeapObjects,
+			Mallocs:    m.Mallocs,
+			Frees:      m.Frees,
+			StackInuse: m.StackInuse,
+			NextGC:     m.NextGC,
+		},
+		GC: GC{
+			NumGC:          m.NumGC,
+			NumForced:      m.NumForcedGC,
+			PauseTotalMS:   ms(time.Duration(m.PauseTotalNs)),
+			RecentPausesMS: pauses(&m, recentPauses),
+			CPUFraction:    m.GCCPUFraction,
+		},
+		Build: buildinfo.Get(),
+	}
+	if m.LastGC != 0 {
+		last := time.Unix(0, int64(m.LastGC))
+		info.GC.Last = &last
+	}
+	return info
+}
+
+// pauses returns up to n of the latest GC pauses in m, most recent
+// first. m.PauseNs is a ring buffer whose latest entry is at
+// (NumGC+255)%256.
+func pauses(m *runtime.MemStats, n int) []float64 {
+	if uint32(n) > m.NumGC {
+		n = int(m.NumGC)
+	}
+	if n > len(m.PauseNs) {
+		n = len(m.PauseNs)
+	}
+	out := make([]float64, n)
+	for i := range out {
+		j := (int(m.NumGC) - 1 - i + len(m.PauseNs)) % len(m.PauseNs)
+		out[i] = ms(time.Duration(m.PauseNs[j]))
+	}
+	return out
+}
+
+// openFiles counts the process's open file descriptors from the
+// directory Linux (/proc/self/fd) or the BSDs and macOS (/dev/fd) list
+// them in, or returns -1 where neither exists.
+func openFiles() int {
+	for _, dir := range []string{"/proc/self/fd", "/dev/fd"} {
+		if entries, err := os.ReadDir(dir); err == nil {
+			return len(entries) - 1 // less the descriptor reading dir
+		}
+	}
+	return -1
+}
+
+func ms(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+
+// Handler returns the /debug/info handler, which answers with the Info
+// of the process at the time of each request, as JSON.
+func Handler() http.Handler { return NewHandler(nil) }
+
+// NewHandler is Handler with the Settings of each Info from settings,
+// if it is not nil.
+func NewHandler(settings func() any) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := Collect()
+		if settings != nil {
+			info.Settings = settings()
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(info)
+	})
+}
@@ -0,0 +1,84 @@
+package diag
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCollect(t *testing.T) {
+	runtime.GC()
+	info := Collect()
+	if info.GOMAXPROCS != runtime.GOMAXPROCS(0) || info.NumCPU != runtime.NumCPU() || info.PID != os.Getpid() {
+		t.Errorf("scheduler: %+v", info)
+	}
+	if info.Goroutines < 1 || info.UptimeMS <= 0 || info.Build.GoVersion != runtime.Version() {
+		t.Errorf("process: %+v", info)
+	}
+	if info.Heap.Alloc == 0 || info.Heap.Sys < info.Heap.HeapSys || info.Heap.TotalAlloc < info.Heap.Alloc {
+		t.Errorf("heap: %+v", info.Heap)
+	}
+	if info.GC.NumGC == 0 || info.GC.NumForced == 0 || info.GC.Last == nil || len(info.GC.RecentPausesMS) == 0 {
+		t.Errorf("gc after runtime.GC: %+v", info.GC)
+	}
+	if _, err := os.Stat("/proc/self/fd"); err == nil && info.OpenFiles < 3 {
+		t.Errorf("open files = %d", info.OpenFiles)
+	}
+}
+
+func TestPauses(t *testing.T) {
+	var m runtime.MemStats
+	if got := pauses(&m, 10); len(got) != 0 {
+		t.Errorf("no collections: %v", got)
+	}
+	m.NumGC = 258 // the ring buffer has wrapped
+	for i := range m.PauseNs {
+		m.PauseNs[i] = uint64(i) * 1e6
+	}
+	got := pauses(&m, 3)
+	if len(got) != 3 || got[0] != 1 || got[1] != 0 || got[2] != 255 {
+		t.Errorf("pauses = %v, want [1 0 255]", got)
+	}
+	m.NumGC = 2
+	if got := pauses(&m, 10); len(got) != 2 || got[0] != 1 || got[1] != 0 {
+		t.Errorf("two collections: %v", got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/info", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+		t.Fatalf("status %d, type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"gomaxprocs", "goroutines", "open_files", "heap", "gc", "build"} {
+		if _, ok := fields[k]; !ok {
+			t.Errorf("no %q in %s", k, rec.Body)
+		}
+	}
+}
+
+func TestNewHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewHandler(func() any { return map[string]int{"workers": 3} }).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/info", nil))
+	var info struct {
+		Settings map[string]int `json:"settings"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil || info.Settings["workers"] != 3 {
+		t.Fatalf("%s: %v", rec.Body, err)
+	}
+
+	rec = httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/info", nil))
+	if strings.Contains(rec.Body.String(), `"settings"`) {
+		t.Fatalf("settings without NewHandler: %s", rec.Body)
+	}
+}
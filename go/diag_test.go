@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"example.com/tutorial/diag"
+)
+
+func TestRunDiag(t *testing.T) {
+	var out, errOut bytes.Buffer
+	if code := run(context.Background(), []string{"diag"}, nil, &out, &errOut); code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+	var info diag.Info
+	if err := json.Unmarshal(out.Bytes(), &info); err != nil {
+		t.Fatalf("%v: %s", err, out.String())
+	}
+	if info.GOMAXPROCS != runtime.GOMAXPROCS(0) || info.Goroutines == 0 || info.Heap.Sys == 0 {
+		t.Errorf("got %+v", info)
+	}
+
+	if code := run(context.Background(), []string{"diag", "extra"}, nil, &out, &errOut); code != 2 {
+		t.Errorf("extra argument: exit %d", code)
+	}
+}
+
+func TestRunDiagServer(t *testing.T) {
+	srv := httptest.NewServer(diag.Handler())
+	defer srv.Close()
+	var out, errOut bytes.Buffer
+	if code := runDiag(context.Background(), []string{"-server", strings.TrimPrefix(srv.URL, "http://")}, &out, &errOut); code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), `"gomaxprocs"`) {
+		t.Errorf("got %s", out.String())
+	}
+
+	notFound := httptest.NewServer(http.NotFoundHandler())
+	defer notFound.Close()
+	errOut.Reset()
+	if code := runDiag(context.Background(), []string{"-server", notFound.URL + "/"}, &out, &errOut); code != 1 || !strings.Contains(errOut.String(), "/debug/info: 404") {
+		t.Errorf("no /debug/info: exit %d: %s", code, errOut.String())
+	}
+}
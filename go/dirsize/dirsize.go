@@ -0,0 +1,217 @@
+// Package dirsize computes how much space directory trees take up, in
+// the manner of du(1), reading directories concurrently.
+package dirsize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"example.com/tutorial/humanize"
+)
+
+// Options tunes Compute.
+type Options struct {
+	// Workers bounds the directories read at once; zero means
+	// runtime.GOMAXPROCS(0).
+	Workers int
+	// FollowSymlinks counts what symbolic links point to, descending
+	// into linked directories, instead of the links themselves. Every
+	// directory is counted once however many links lead to it, so
+	// cycles end.
+	FollowSymlinks bool
+}
+
+// Dir is the usage of one directory.
+type Dir struct {
+	Path  string // the root joined with the path below it
+	Depth int    // levels below the root, which is 0
+	Size  int64  // apparent size in bytes of the files below, recursively
+	Files int    // number of files below, recursively
+}
+
+// Usage is the result of Compute: every directory of the tree, in path
+// order unless sorted otherwise.
+type Usage struct {
+	Dirs []Dir
+}
+
+// node collects one directory's counts during the walk.
+type node struct {
+	path   string
+	depth  int
+	parent *node
+
+	mu    sync.Mutex
+	size  int64 // own files only until the walk ends
+	files int
+}
+
+type walk struct {
+	opts  Options
+	ctx   context.Context
+	slots chan struct{}
+	wg    sync.WaitGroup
+
+	mu    sync.Mutex
+	nodes []*node
+	seen  map[string]bool // real paths of the directories entered
+	errs  []error
+}
+
+// Compute walks the tree at root. Directories that cannot be read are
+// reported in the returned error, joined with errors.Join, and left out
+// of the totals, but do not stop the walk; the Usage is returned
+// either way, unless root itself cannot be read or ctx ends.
+func Compute(ctx context.Context, root string, opts Options) (*Usage, error) {
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.GOMAXPROCS(0)
+	}
+	stat := os.Lstat
+	if opts.FollowSymlinks {
+		stat = os.Stat
+	}
+	info, err := stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return &Usage{Dirs: []Dir{{Path: root, Size: info.Size(), Files: 1}}}, nil
+	}
+	w := &walk{opts: opts, ctx: ctx, slots: make(chan struct{}, opts.Workers), seen: map[string]bool{}}
+	rootNode := &node{path: root}
+	w.enter(rootNode)
+	w.wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Add every directory's totals to its parent's, deepest first.
+	sort.Slice(w.nodes, func(i, j int) bool { return w.nodes[i].depth > w.nodes[j].depth })
+	for _, n := range w.nodes {
+		if n.parent != nil {
+			n.parent.size += n.size
+			n.parent.files += n.files
+		}
+	}
+	u := &Usage{Dirs: make([]Dir, len(w.nodes))}
+	for i, n := range w.nodes {
+		u.Dirs[i] = Dir{Path: n.path, Depth: n.depth, Size: n.size, Files: n.files}
+	}
+	sort.Slice(u.Dirs, func(i, j int) bool { return u.Dirs[i].Path < u.Dirs[j].Path })
+	return u, errors.Join(w.errs...)
+}
+
+// enter records n, unless it is a directory already entered through
+// another link, and reads it in a new goroutine.
+func (w *walk) enter(n *node) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.opts.FollowSymlinks {
+		real, err := filepath.EvalSymlinks(n.path)
+		if err != nil {
+			w.errs = append(w.errs, err)
+			return
+		}
+		if w.seen[real] {
+			return
+		}
+		w.seen[real] = true
+	}
+	w.nodes = append(w.nodes, n)
+	w.wg.Add(1)
+	go w.read(n)
+}
+
+func (w *walk) read(n *node) {
+	defer w.wg.Done()
+	select {
+	case w.slots <- struct{}{}:
+	case <-w.ctx.Done():
+		return
+	}
+	entries, err := os.ReadDir(n.path)
+	if err != nil {
+		<-w.slots
+		w.fail(err)
+		return
+	}
+	var subdirs []*node
+	for _, e := range entries {
+		path := filepath.Join(n.path, e.Name())
+		info, err := e.Info()
+		if err == nil && w.opts.FollowSymlinks && e.Type()&fs.ModeSymlink != 0 {
+			info, err = os.Stat(path)
+		}
+		if err != nil {
+			w.fail(err)
+			continue
+		}
+		if info.IsDir() {
+			subdirs = append(subdirs, &node{path: path, depth: n.depth + 1, parent: n})
+			continue
+		}
+		n.mu.Lock()
+		n.size += info.Size()
+		n.files++
+		n.mu.Unlock()
+	}
+	<-w.slots
+	for _, c := range subdirs {
+		if w.ctx.Err() != nil {
+			return
+		}
+		w.enter(c)
+	}
+}
+
+func (w *walk) fail(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.errs = append(w.errs, err)
+}
+
+// Total returns the usage of the root.
+func (u *Usage) Total() Dir {
+	for _, d := range u.Dirs {
+		if d.Depth == 0 {
+			return d
+		}
+	}
+	return Dir{}
+}
+
+// SortBySize orders the directories largest first, ties by path.
+func (u *Usage) SortBySize() {
+	sort.SliceStable(u.Dirs, func(i, j int) bool {
+		if u.Dirs[i].Size != u.Dirs[j].Size {
+			return u.Dirs[i].Size > u.Dirs[j].Size
+		}
+		return u.Dirs[i].Path < u.Dirs[j].Path
+	})
+}
+
+// Format formats the usage like du: a size and a path per line, with
+// sizes in bytes or, if human is set, as by humanize.CompactBytes.
+// Directories deeper than maxDepth are left out unless it is negative.
+func (u *Usage) Format(human bool, maxDepth int) string {
+	var b strings.Builder
+	for _, d := range u.Dirs {
+		if maxDepth >= 0 && d.Depth > maxDepth {
+			continue
+		}
+		size := fmt.Sprint(d.Size)
+		if human {
+			size = humanize.CompactBytes(d.Size)
+		}
+		fmt.Fprintf(&b, "%s\t%s\n", size, d.Path)
+	}
+	return b.String()
+}
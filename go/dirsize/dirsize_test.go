@@ -0,0 +1,103 @@
+package dirsize
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// makeTree writes files of the given sizes below a new directory.
+func makeTree(t *testing.T, files map[string]int) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, size := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func sizes(u *Usage, root string) map[string]int64 {
+	m := map[string]int64{}
+	for _, d := range u.Dirs {
+		rel, _ := filepath.Rel(root, d.Path)
+		m[filepath.ToSlash(rel)] = d.Size
+	}
+	return m
+}
+
+func TestCompute(t *testing.T) {
+	root := makeTree(t, map[string]int{
+		"a.txt": 100, "src/main.go": 2000, "src/lib/x.go": 30, "src/lib/y.go": 70, "docs/readme": 5,
+	})
+	os.Mkdir(filepath.Join(root, "empty"), 0o755)
+	u, err := Compute(context.Background(), root, Options{Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int64{".": 2205, "src": 2100, "src/lib": 100, "docs": 5, "empty": 0}
+	got := sizes(u, root)
+	if len(got) != len(want) {
+		t.Fatalf("dirs %v", got)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s: size %d, want %d", k, got[k], v)
+		}
+	}
+	if tot := u.Total(); tot.Path != root || tot.Files != 5 || tot.Depth != 0 {
+		t.Fatalf("Total = %+v", tot)
+	}
+	for i := 1; i < len(u.Dirs); i++ {
+		if u.Dirs[i-1].Path > u.Dirs[i].Path {
+			t.Fatal("not in path order")
+		}
+	}
+
+	u.SortBySize()
+	var order []string
+	for _, d := range u.Dirs {
+		rel, _ := filepath.Rel(root, d.Path)
+		order = append(order, filepath.ToSlash(rel))
+	}
+	if got := strings.Join(order, " "); got != ". src src/lib docs empty" {
+		t.Fatalf("by size: %s", got)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	u := &Usage{Dirs: []Dir{
+		{Path: "r", Size: 3 << 20},
+		{Path: "r/a", Depth: 1, Size: 1536},
+		{Path: "r/a/b", Depth: 2, Size: 10},
+	}}
+	if got, want := u.Format(false, -1), "3145728\tr\n1536\tr/a\n10\tr/a/b\n"; got != want {
+		t.Fatalf("bytes: %q, want %q", got, want)
+	}
+	if got, want := u.Format(true, 1), "3.0M\tr\n1.5K\tr/a\n"; got != want {
+		t.Fatalf("human, depth 1: %q, want %q", got, want)
+	}
+}
+
+func TestComputeFileAndErrors(t *testing.T) {
+	root := makeTree(t, map[string]int{"f": 42})
+	u, err := Compute(context.Background(), filepath.Join(root, "f"), Options{})
+	if err != nil || u.Total().Size != 42 || u.Total().Files != 1 {
+		t.Fatalf("file root: %+v, %v", u, err)
+	}
+	if _, err := Compute(context.Background(), filepath.Join(root, "missing"), Options{}); err == nil {
+		t.Fatal("missing root accepted")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Compute(ctx, root, Options{}); err != context.Canceled {
+		t.Fatalf("canceled: %v", err)
+	}
+}
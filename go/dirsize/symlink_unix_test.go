@@ -0,0 +1,50 @@
+//go:build unix
+
+package dirsize
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSymlinks(t *testing.T) {
+	root := makeTree(t, map[string]int{"data/big": 1000, "small": 1})
+	// A link to the data, and a cycle back to the root.
+	if err := os.Symlink(filepath.Join(root, "data"), filepath.Join(root, "alias")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(root, filepath.Join(root, "data", "loop")); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := Compute(context.Background(), root, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A link's own size is the length of its target.
+	links := int64(len(filepath.Join(root, "data")) + len(root))
+	if s := sizes(u, root); len(s) != 2 || s["."] != 1001+links {
+		t.Fatalf("links not counted as links: %v", s)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		u, err = Compute(context.Background(), root, Options{FollowSymlinks: true})
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("cycle not broken")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	// data is counted once, through whichever path reached it first.
+	if tot := u.Total(); tot.Size != 1001 || tot.Files != 2 {
+		t.Fatalf("followed total %+v", tot)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"example.com/tutorial/dirsize"
+)
+
+// runDu implements the "du" subcommand and returns the process exit
+// code. It summarizes the current directory when given no argument.
+func runDu(ctx context.Context, cfg duSettings, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("du", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: du [-h] [-s | -d n] [-sort] [-L] [dir ...]")
+		fs.PrintDefaults()
+	}
+	human := fs.Bool("h", cfg.Human, "print sizes in human-readable units (1.5K, 12M)")
+	summary := fs.Bool("s", false, "print only the total of each argument")
+	depth := fs.Int("d", -1, "print directories at most `n` levels deep, -1 for all")
+	bySize := fs.Bool("sort", false, "print the largest directories first")
+	follow := fs.Bool("L", cfg.FollowSymlinks, "follow symbolic links")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *summary {
+		*depth = 0
+	}
+	dirs := fs.Args()
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+	status := 0
+	for _, dir := range dirs {
+		u, err := dirsize.Compute(ctx, dir, dirsize.Options{FollowSymlinks: *follow})
+		if err != nil {
+			fmt.Fprintln(stderr, "du:", err)
+			status = 1
+			if u == nil {
+				continue
+			}
+		}
+		if *bySize {
+			u.SortBySize()
+		}
+		io.WriteString(stdout, u.Format(*human, *depth))
+	}
+	return status
+}
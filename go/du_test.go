@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunDu(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "small"), 0o755)
+	os.MkdirAll(filepath.Join(dir, "big", "deeper"), 0o755)
+	os.WriteFile(filepath.Join(dir, "small", "f"), make([]byte, 10), 0o644)
+	os.WriteFile(filepath.Join(dir, "big", "deeper", "g"), make([]byte, 3000), 0o644)
+
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{dir}, "3010\t" + dir + "\n3000\t" + filepath.Join(dir, "big") + "\n3000\t" + filepath.Join(dir, "big", "deeper") + "\n10\t" + filepath.Join(dir, "small") + "\n"},
+		{[]string{"-s", "-h", dir}, "2.9K\t" + dir + "\n"},
+		{[]string{"-d", "1", "-sort", dir}, "3010\t" + dir + "\n3000\t" + filepath.Join(dir, "big") + "\n10\t" + filepath.Join(dir, "small") + "\n"},
+	} {
+		var out, errOut bytes.Buffer
+		if code := runDu(context.Background(), defaultSettings().Du, tc.args, &out, &errOut); code != 0 {
+			t.Fatalf("%v: exit %d: %s", tc.args, code, errOut.String())
+		}
+		if got := out.String(); got != tc.want {
+			t.Errorf("%v:\ngot  %q\nwant %q", tc.args, got, tc.want)
+		}
+	}
+
+	var out, errOut bytes.Buffer
+	if code := runDu(context.Background(), defaultSettings().Du, []string{filepath.Join(dir, "missing"), dir}, &out, &errOut); code != 1 || out.Len() == 0 {
+		t.Fatalf("missing dir: exit %d, output %q", code, out.String())
+	}
+}
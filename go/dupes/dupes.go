@@ -0,0 +1,257 @@
+// Package dupes finds files with identical contents in a directory tree.
+// Only files sharing a size are read, and those are hashed with SHA-256,
+// several at once.
+package dupes
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"example.com/tutorial/fileutil"
+	"example.com/tutorial/fsutil"
+	"example.com/tutorial/humanize"
+	"example.com/tutorial/progress"
+)
+
+// Options tunes Find.
+type Options struct {
+	// Workers bounds the directories read and files hashed at once;
+	// zero means runtime.GOMAXPROCS(0).
+	Workers int
+	// MinSize leaves out files smaller than MinSize bytes. Empty files
+	// are always left out.
+	MinSize int64
+	// Progress, if not nil, counts the files visited and the bytes
+	// hashed.
+	Progress *progress.Tracker
+}
+
+// Group is a set of files with the same contents.
+type Group struct {
+	Size  int64    `json:"size"`  // size of each file in bytes
+	Hash  string   `json:"hash"`  // hex SHA-256 of the contents
+	Paths []string `json:"paths"` // in path order, at least two
+}
+
+// Reclaimable returns the bytes freed by keeping only one of the files.
+func (g Group) Reclaimable() int64 {
+	return g.Size * int64(len(g.Paths)-1)
+}
+
+// Report is the result of Find.
+type Report struct {
+	Files       int     `json:"files"`       // regular files considered
+	Hashed      int     `json:"hashed"`      // files read because their size was shared
+	Groups      []Group `json:"groups"`      // most reclaimable first, ties by first path
+	Reclaimable int64   `json:"reclaimable"` // total over the groups
+}
+
+// Find walks the tree at root and groups its duplicate regular files.
+// Symbolic links are not followed. Files and directories that cannot be
+// read are reported in the returned error, joined with errors.Join, and
+// left out, but do not stop the search; the Report is returned either
+// way, unless root itself cannot be read or ctx ends.
+func Find(ctx context.Context, root string, opts Options) (*Report, error) {
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.GOMAXPROCS(0)
+	}
+	if opts.MinSize < 1 {
+		opts.MinSize = 1
+	}
+	if _, err := os.Lstat(root); err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	bySize := map[int64][]string{}
+	files := 0
+	w := fsutil.Walker{Workers: opts.Workers, AllErrors: true, Progress: opts.Progress}
+	walkErr := w.Walk(ctx, root, func(_ context.Context, path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		files++
+		if info.Size() >= opts.MinSize {
+			bySize[info.Size()] = append(bySize[info.Size()], path)
+		}
+		return nil
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	hashed, hashErr := hashAll(ctx, bySize, opts.Workers, opts.Progress)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r := &Report{Files: files, Groups: []Group{}}
+	for key, paths := range hashed {
+		r.Hashed += len(paths)
+		if len(paths) < 2 {
+			continue
+		}
+		sort.Strings(paths)
+		g := Group{Size: key.size, Hash: hex.EncodeToString(key.sum[:]), Paths: paths}
+		r.Groups = append(r.Groups, g)
+		r.Reclaimable += g.Reclaimable()
+	}
+	sort.Slice(r.Groups, func(i, j int) bool {
+		a, b := r.Groups[i], r.Groups[j]
+		if a.Reclaimable() != b.Reclaimable() {
+			return a.Reclaimable() > b.Reclaimable()
+		}
+		return a.Paths[0] < b.Paths[0]
+	})
+	return r, errors.Join(walkErr, hashErr)
+}
+
+type content struct {
+	size int64
+	sum  [sha256.Size]byte
+}
+
+// hashAll hashes, with the given number of goroutines, the files whose
+// size is shared with another, and groups them by contents.
+func hashAll(ctx context.Context, bySize map[int64][]string, workers int, t *progress.Tracker) (map[content][]string, error) {
+	type job struct {
+		size int64
+		path string
+	}
+	jobs := make(chan job)
+	go func() {
+		defer close(jobs)
+		for size, paths := range bySize {
+			if len(paths) < 2 {
+				continue
+			}
+			for _, p := range paths {
+				select {
+				case jobs <- job{size, p}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	var (
+		mu   sync.Mutex
+		out  = map[content][]string{}
+		errs []error
+		wg   sync.WaitGroup
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				sum, err := hashFile(j.path, t)
+				mu.Lock()
+				if err != nil {
+					errs = append(errs, err)
+				} else {
+					k := content{j.size, sum}
+					out[k] = append(out[k], j.path)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return out, errors.Join(errs...)
+}
+
+func hashFile(path string, t *progress.Tracker) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	f, err := os.Open(path)
+	if err != nil {
+		return sum, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, t.Reader(f)); err != nil {
+		return sum, fmt.Errorf("%s: %w", path, err)
+	}
+	h.Sum(sum[:0])
+	return sum, nil
+}
+
+// RemoveDuplicates removes every file of each group but the first,
+// through files, which may be a dry run, and returns the bytes freed.
+// Files that cannot be removed are reported in the returned error,
+// joined with errors.Join, and do not stop the others. The files are
+// not read again, so r should be fresh.
+func (r *Report) RemoveDuplicates(files *fileutil.Executor) (int64, error) {
+	var freed int64
+	var errs []error
+	for _, g := range r.Groups {
+		for _, p := range g.Paths[1:] {
+			if err := files.Remove(p); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			freed += g.Size
+		}
+	}
+	return freed, errors.Join(errs...)
+}
+
+// WriteTable writes the groups as a table, a row per file with the
+// group's size, copies and reclaimable bytes on its first row, followed
+// by a summary line. Sizes are in bytes or, if human is set, as by
+// humanize.CompactBytes.
+func (r *Report) WriteTable(w io.Writer, human bool) error {
+	size := func(n int64) string {
+		if human {
+			return humanize.CompactBytes(n)
+		}
+		return strconv.FormatInt(n, 10)
+	}
+	header := [3]string{"SIZE", "COPIES", "RECLAIMABLE"}
+	widths := [3]int{len(header[0]), len(header[1]), len(header[2])}
+	cells := make([][3]string, len(r.Groups))
+	for i, g := range r.Groups {
+		cells[i] = [3]string{size(g.Size), strconv.Itoa(len(g.Paths)), size(g.Reclaimable())}
+		for j, c := range cells[i] {
+			widths[j] = max(widths[j], len(c))
+		}
+	}
+	var b strings.Builder
+	row := func(c [3]string, path string) {
+		for j := range c {
+			fmt.Fprintf(&b, "%*s  ", widths[j], c[j])
+		}
+		b.WriteString(path)
+		b.WriteByte('\n')
+	}
+	row(header, "PATH")
+	for i, g := range r.Groups {
+		for k, p := range g.Paths {
+			if k == 0 {
+				row(cells[i], p)
+			} else {
+				row([3]string{}, p)
+			}
+		}
+	}
+	fmt.Fprintf(&b, "\n%d duplicate groups in %d files, %s reclaimable\n", len(r.Groups), r.Files, size(r.Reclaimable))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
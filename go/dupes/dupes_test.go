@@ -0,0 +1,170 @@
+package dupes
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"example.com/tutorial/fileutil"
+	"example.com/tutorial/progress"
+)
+
+func makeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func rel(t *testing.T, root string, paths []string) []string {
+	t.Helper()
+	out := make([]string, len(paths))
+	for i, p := range paths {
+		r, err := filepath.Rel(root, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out[i] = filepath.ToSlash(r)
+	}
+	return out
+}
+
+func TestFind(t *testing.T) {
+	root := makeTree(t, map[string]string{
+		"a.txt":       "hello world",
+		"sub/b.txt":   "hello world",
+		"sub/c/d.txt": "hello world",
+		"same-size":   "HELLO WORLD", // same size, other contents
+		"x.bin":       "xy",
+		"y.bin":       "xy",
+		"unique":      "only one of these",
+		"empty1":      "",
+		"empty2":      "",
+	})
+	r, err := Find(context.Background(), root, Options{Workers: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Files != 9 || r.Hashed != 6 {
+		t.Fatalf("files %d, hashed %d", r.Files, r.Hashed)
+	}
+	if len(r.Groups) != 2 {
+		t.Fatalf("groups %+v", r.Groups)
+	}
+	if got := rel(t, root, r.Groups[0].Paths); !reflect.DeepEqual(got, []string{"a.txt", "sub/b.txt", "sub/c/d.txt"}) {
+		t.Fatalf("first group %v", got)
+	}
+	if g := r.Groups[0]; g.Size != 11 || g.Reclaimable() != 22 || len(g.Hash) != 64 {
+		t.Fatalf("first group %+v", g)
+	}
+	if got := rel(t, root, r.Groups[1].Paths); !reflect.DeepEqual(got, []string{"x.bin", "y.bin"}) {
+		t.Fatalf("second group %v", got)
+	}
+	if r.Reclaimable != 24 {
+		t.Fatalf("reclaimable %d", r.Reclaimable)
+	}
+
+	r, err = Find(context.Background(), root, Options{MinSize: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Groups) != 1 || r.Reclaimable != 22 {
+		t.Fatalf("MinSize 5: %+v", r.Groups)
+	}
+}
+
+func TestFindErrors(t *testing.T) {
+	if _, err := Find(context.Background(), filepath.Join(t.TempDir(), "missing"), Options{}); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("missing root: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Find(ctx, t.TempDir(), Options{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled: %v", err)
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	r := &Report{
+		Files: 5,
+		Groups: []Group{
+			{Size: 2048, Paths: []string{"a", "b", "c"}},
+			{Size: 10, Paths: []string{"d", "e"}},
+		},
+		Reclaimable: 4106,
+	}
+	var b strings.Builder
+	if err := r.WriteTable(&b, false); err != nil {
+		t.Fatal(err)
+	}
+	want := `SIZE  COPIES  RECLAIMABLE  PATH
+2048       3         4096  a
+                           b
+                           c
+  10       2           10  d
+                           e
+
+2 duplicate groups in 5 files, 4106 reclaimable
+`
+	if b.String() != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", b.String(), want)
+	}
+	b.Reset()
+	r.WriteTable(&b, true)
+	if !strings.Contains(b.String(), "4.0K") {
+		t.Fatalf("human:\n%s", b.String())
+	}
+}
+
+func TestFindProgress(t *testing.T) {
+	root := makeTree(t, map[string]string{"a": "same", "b": "same", "c": "other size"})
+	tr := progress.NewTracker()
+	if _, err := Find(context.Background(), root, Options{Progress: tr}); err != nil {
+		t.Fatal(err)
+	}
+	// Only the files sharing a size are hashed.
+	if s := tr.Stats(); s.Files != 3 || s.Bytes != 8 {
+		t.Fatalf("counted %d files, %d bytes", s.Files, s.Bytes)
+	}
+}
+
+func TestRemoveDuplicates(t *testing.T) {
+	root := makeTree(t, map[string]string{"a": "same", "b": "same", "c": "same", "d": "diff"})
+	r, err := Find(context.Background(), root, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log strings.Builder
+	freed, err := r.RemoveDuplicates(&fileutil.Executor{DryRun: true, Log: &log})
+	if err != nil || freed != 8 {
+		t.Fatalf("dry run: freed %d, %v", freed, err)
+	}
+	if want := "would remove " + filepath.Join(root, "b") + "\nwould remove " + filepath.Join(root, "c") + "\n"; log.String() != want {
+		t.Errorf("log %q, want %q", log.String(), want)
+	}
+	if entries, _ := os.ReadDir(root); len(entries) != 4 {
+		t.Fatalf("dry run removed files: %v", entries)
+	}
+
+	os.Remove(filepath.Join(root, "c"))
+	freed, err = r.RemoveDuplicates(nil)
+	if freed != 4 || !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("freed %d, %v", freed, err)
+	}
+	if entries, _ := os.ReadDir(root); len(entries) != 2 {
+		t.Fatalf("left %v", entries)
+	}
+}
@@ -0,0 +1,178 @@
+// Package errs classifies errors by kind, so that code far from where an
+// error arose can tell what went wrong without knowing which package it
+// came from. A kind is one of the sentinels ErrNotFound, ErrConflict and
+// ErrInvalid; package sentinels made with New, errors wrapped with Wrap
+// and the errors gathered in a Multi all report their kind to errors.Is.
+// HTTPStatus maps kinds to status codes for the server's handlers.
+package errs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// The kinds of error.
+var (
+	// ErrNotFound means the thing asked for does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict means the request clashes with the current state, as
+	// creating something that already exists does.
+	ErrConflict = errors.New("conflict")
+	// ErrInvalid means the input was rejected as malformed or out of
+	// range.
+	ErrInvalid = errors.New("invalid")
+)
+
+var kinds = []error{ErrInvalid, ErrNotFound, ErrConflict}
+
+// New returns an error with the given text that is of kind, for package
+// sentinels such as user.ErrNotFound:
+//
+//	var ErrNotFound = errs.New(errs.ErrNotFound, "user: not found")
+func New(kind error, text string) error {
+	return &kindError{kind: kind, text: text}
+}
+
+type kindError struct {
+	kind error
+	text string
+}
+
+func (e *kindError) Error() string { return e.text }
+func (e *kindError) Unwrap() error { return e.kind }
+
+// Kind returns the kind of err, nil if it has none. Of a Multi whose
+// errors differ in kind, it returns the first kind found in the order
+// ErrInvalid, ErrNotFound, ErrConflict.
+func Kind(err error) error {
+	for _, k := range kinds {
+		if errors.Is(err, k) {
+			return k
+		}
+	}
+	return nil
+}
+
+// Error is an error with the operation that failed and the entity it
+// acted on, such as "update" and "user 42".
+type Error struct {
+	Op     string // what was being done
+	Entity string // what to, or empty
+	Err    error
+}
+
+// Error returns the operation, the entity and the error, as in
+// "update user 42: user: not found".
+func (e *Error) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Op)
+	if e.Entity != "" {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(e.Entity)
+	}
+	if b.Len() > 0 {
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Err.Error())
+	return b.String()
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Wrap returns err with the operation and entity attached, or nil if err
+// is nil, so that it can wrap a call's result directly:
+//
+//	return errs.Wrap(s.store.Delete(ctx, id), "delete", "user "+strconv.Itoa(id))
+func Wrap(err error, op, entity string) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Op: op, Entity: entity, Err: err}
+}
+
+// Multi gathers the errors of a batch operation that goes on past its
+// failures. The zero value is an empty Multi ready to use. errors.Is and
+// errors.As look through every error gathered.
+type Multi struct {
+	errs []error
+}
+
+// Add adds err, unless it is nil.
+func (m *Multi) Add(err error) {
+	if err != nil {
+		m.errs = append(m.errs, err)
+	}
+}
+
+// Len returns the number of errors added.
+func (m *Multi) Len() int { return len(m.errs) }
+
+// Errors returns the errors added, in order.
+func (m *Multi) Errors() []error { return append([]error(nil), m.errs...) }
+
+// Err returns m as an error, or nil if no error was added.
+func (m *Multi) Err() error {
+	if len(m.errs) == 0 {
+		return nil
+	}
+	return m
+}
+
+// Error lists the errors, one per line after a count when there are
+// several.
+func (m *Multi) Error() string {
+	if len(m.errs) == 1 {
+		return m.errs[0].Error()
+	}
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(len(m.errs)))
+	b.WriteString(" errors:")
+	for _, err := range m.errs {
+		b.WriteString("\n\t")
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+func (m *Multi) Unwrap() []error { return m.errs }
+
+// HTTPStatus returns the status code for a response failing with err:
+// 404 for ErrNotFound, 409 for ErrConflict, 422 for ErrInvalid, 504 for
+// an expired deadline and 500 for any other error. A Multi gets the
+// status of its errors if they agree, 400 if they are all of some kind
+// but differ, and 500 otherwise. A nil err is 200.
+func HTTPStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	var m *Multi
+	if errors.As(err, &m) && len(m.errs) > 1 {
+		status := HTTPStatus(m.errs[0])
+		for _, e := range m.errs[1:] {
+			if s := HTTPStatus(e); s != status {
+				if s >= 500 || status >= 500 {
+					return http.StatusInternalServerError
+				}
+				status = http.StatusBadRequest
+			}
+		}
+		return status
+	}
+	switch Kind(err) {
+	case ErrNotFound:
+		return http.StatusNotFound
+	case ErrConflict:
+		return http.StatusConflict
+	case ErrInvalid:
+		return http.StatusUnprocessableEntity
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,105 @@
+package errs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	err := New(ErrNotFound, "user: not found")
+	if err.Error() != "user: not found" || !errors.Is(err, ErrNotFound) || errors.Is(err, ErrConflict) {
+		t.Fatalf("New = %v", err)
+	}
+	if New(ErrNotFound, "x") == New(ErrNotFound, "x") {
+		t.Fatal("New returned the same error twice")
+	}
+	wrapped := fmt.Errorf("loading: %w", err)
+	if !errors.Is(wrapped, err) || Kind(wrapped) != ErrNotFound {
+		t.Fatalf("wrapped sentinel: kind %v", Kind(wrapped))
+	}
+}
+
+func TestWrap(t *testing.T) {
+	base := New(ErrConflict, "user: already exists")
+	err := Wrap(base, "create", "user 7")
+	if err.Error() != "create user 7: user: already exists" {
+		t.Fatalf("Error() = %q", err)
+	}
+	if !errors.Is(err, base) || !errors.Is(err, ErrConflict) {
+		t.Fatal("Wrap hid the error")
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Op != "create" || e.Entity != "user 7" {
+		t.Fatalf("As = %+v", e)
+	}
+	if Wrap(nil, "create", "user 7") != nil {
+		t.Fatal("Wrap(nil) is not nil")
+	}
+	if got := Wrap(fs.ErrNotExist, "open", "").Error(); got != "open: file does not exist" {
+		t.Fatalf("without an entity: %q", got)
+	}
+	if got := Wrap(fs.ErrNotExist, "", "").Error(); got != "file does not exist" {
+		t.Fatalf("with nothing: %q", got)
+	}
+}
+
+func TestMulti(t *testing.T) {
+	var m Multi
+	if m.Err() != nil || m.Len() != 0 {
+		t.Fatal("empty Multi is an error")
+	}
+	m.Add(nil)
+	m.Add(Wrap(New(ErrNotFound, "doc: not found"), "reindex", "a.txt"))
+	if err := m.Err(); err == nil || err.Error() != "reindex a.txt: doc: not found" {
+		t.Fatalf("one error: %v", err)
+	}
+	m.Add(fs.ErrPermission)
+	err := m.Err()
+	if err.Error() != "2 errors:\n\treindex a.txt: doc: not found\n\tpermission denied" {
+		t.Fatalf("Error() = %q", err)
+	}
+	if !errors.Is(err, ErrNotFound) || !errors.Is(err, fs.ErrPermission) || errors.Is(err, ErrConflict) {
+		t.Fatal("Is does not look through the errors")
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Entity != "a.txt" {
+		t.Fatal("As does not look through the errors")
+	}
+	if got := m.Errors(); len(got) != 2 || got[1] != fs.ErrPermission {
+		t.Fatalf("Errors = %v", got)
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	multi := func(errs ...error) error {
+		var m Multi
+		for _, err := range errs {
+			m.Add(err)
+		}
+		return m.Err()
+	}
+	notFound, conflict := New(ErrNotFound, "gone"), New(ErrConflict, "taken")
+	for _, tc := range []struct {
+		err  error
+		want int
+	}{
+		{nil, http.StatusOK},
+		{notFound, http.StatusNotFound},
+		{Wrap(conflict, "create", "user 1"), http.StatusConflict},
+		{fmt.Errorf("name: %w", ErrInvalid), http.StatusUnprocessableEntity},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout},
+		{errors.New("disk on fire"), http.StatusInternalServerError},
+		{multi(notFound, Wrap(notFound, "get", "b")), http.StatusNotFound},
+		{multi(notFound, conflict), http.StatusBadRequest},
+		{multi(notFound, errors.New("disk on fire")), http.StatusInternalServerError},
+		{multi(conflict), http.StatusConflict},
+	} {
+		if got := HTTPStatus(tc.err); got != tc.want {
+			t.Errorf("HTTPStatus(%v) = %d, want %d", tc.err, got, tc.want)
+		}
+	}
+}
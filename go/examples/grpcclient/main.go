@@ -0,0 +1,46 @@
+// Command grpcclient creates and lists users through the UserService of
+// a running example server.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"example.com/tutorial/pb"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:9090", "gRPC server address")
+	name := flag.String("name", "Ada", "name of the user to create")
+	flag.Parse()
+
+	conn, err := grpc.NewClient(*addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+	client := pb.NewUserServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	u, err := client.CreateUser(ctx, &pb.CreateUserRequest{Name: *name})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("created user %s: %s\n", u.GetId(), u.GetName())
+
+	list, err := client.ListUsers(ctx, &pb.ListUsersRequest{Sort: "name"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%d users:\n", list.GetTotal())
+	for _, u := range list.GetUsers() {
+		fmt.Printf("  %s\t%s\n", u.GetId(), u.GetName())
+	}
+}
@@ -2,18 +2,212 @@ package main
 
 import (
 	"context"
-	"fmt"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"strings"
+	"syscall"
 	"time"
+
+	"google.golang.org/grpc"
+
+	"example.com/tutorial/auth"
+	"example.com/tutorial/ctxutil"
+	"example.com/tutorial/health"
+	"example.com/tutorial/logging"
+	"example.com/tutorial/metrics"
+	"example.com/tutorial/middleware"
+	"example.com/tutorial/pb"
+	"example.com/tutorial/rpc"
+	"example.com/tutorial/rungroup"
+	"example.com/tutorial/scheduler"
+	"example.com/tutorial/server"
+	"example.com/tutorial/user"
+	"example.com/tutorial/watch"
 )
 
 func main() {
-	srv := &http.Server{Addr: ":0"}
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintln(w, "ok")
+	serve := server.DefaultServeConfig()
+	if err := serve.FromEnv(os.Getenv); err != nil {
+		log.Fatal(err)
+	}
+	serve.RegisterFlags(flag.CommandLine)
+	staticDir := flag.String("static", "", "`directory` served under /static/ (default: built-in assets)")
+	listings := flag.Bool("static-listings", false, "list directories without an index.html under /static/")
+	rate := flag.Float64("rate", 20, "requests per second allowed per client IP")
+	burst := flag.Int("burst", 40, "requests a client may make at once")
+	jwtTTL := flag.Duration("jwt-ttl", time.Hour, "lifetime of the tokens issued by /login")
+	var logLevel slog.Level
+	flag.TextVar(&logLevel, "log-level", slog.LevelInfo, "minimum `level` logged: debug, info, warn or error")
+	logFormat := logging.JSON
+	flag.TextVar(&logFormat, "log-format", logging.JSON, "log `format`: text or json")
+	corpusDir := flag.String("corpus", "", "`directory` of text files searchable with GET /search")
+	reindex := flag.String("reindex", "@every 5m", "cron `spec` on which the -corpus directory is re-indexed")
+	watchCorpus := flag.Duration("watch", 0, "rescan the -corpus directory every `interval` and re-index changed files, 0 to rely on -reindex only")
+	corsOrigins := flag.String("cors-origins", "", "comma-separated browser `origins` allowed to call the JSON API")
+	flag.Parse()
+	if err := serve.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	reindexSched, err := scheduler.ParseCron(*reindex)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Access logs and everything sent through package log go to stderr,
+	// as JSON lines unless -log-format says otherwise. Records logged
+	// while handling a request carry its ID.
+	logger := logging.New(os.Stderr, logging.Options{Level: logLevel, Format: logFormat})
+	slog.SetDefault(logger)
+
+	// Tokens are signed with $JWT_SECRET, or a random key that makes them
+	// invalid after a restart.
+	secret := []byte(os.Getenv("JWT_SECRET"))
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatal(err)
+		}
+		logger.Warn("JWT_SECRET not set; tokens will not survive a restart")
+	}
+
+	reg := metrics.NewRegistry()
+	cfg := server.Config{
+		Auth:           auth.NewJWT(secret, *jwtTTL, "tutorial"),
+		StaticListings: *listings,
+		Metrics:        reg,
+		Checks:         map[string]health.Checker{"tmpdir": health.DirWritable(os.TempDir())},
+		CorpusDir:      *corpusDir,
+		Logger:         logger,
+	}
+	if *staticDir != "" {
+		cfg.Static = os.DirFS(*staticDir)
+	}
+
+	users := user.NewMemoryStore()
+
+	// The gRPC API shares the HTTP API's store.
+	grpcSrv := grpc.NewServer()
+	pb.RegisterUserServiceServer(grpcSrv, rpc.NewUserService(users))
+	grpcLis, err := net.Listen("tcp", serve.GRPCAddr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	lis, err := net.Listen("tcp", serve.Addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	scheme := "HTTP"
+	if serve.UseTLS() {
+		tlsCfg, err := server.TLSConfig(serve.CertFile, serve.KeyFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		lis = tls.NewListener(lis, tlsCfg)
+		scheme = "HTTPS"
+	}
+	logger.Info("serving", "scheme", scheme, "addr", lis.Addr().String(), "grpc_addr", grpcLis.Addr().String())
+	api := server.NewWithConfig(users, cfg)
+
+	var origins []string
+	if *corsOrigins != "" {
+		origins = strings.Split(*corsOrigins, ",")
+	}
+	apiCORS := middleware.CORSPolicy{
+		AllowedOrigins: origins,
+		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE"},
+		AllowedHeaders: []string{"Content-Type", "Authorization"},
+		ExposedHeaders: []string{"Location", middleware.RequestIDHeader},
+		MaxAge:         time.Hour,
+	}
+	handler := middleware.Chain(
+		middleware.RequestID(),
+		middleware.Metrics(reg),
+		middleware.Logger(logging.Component(logger, "http")),
+		middleware.Recover(logger),
+		middleware.CORS(middleware.CORSConfig{
+			Routes: map[string]middleware.CORSPolicy{
+				"/users": apiCORS, "/login": apiCORS, "/wordcount": apiCORS, "/upload": apiCORS, "/search": apiCORS,
+			},
+		}),
+		middleware.Gzip(0),
+		middleware.RateLimit(middleware.NewLimiters(*rate, *burst, 0), nil),
+		middleware.Timeout(middleware.TimeoutConfig{
+			Default: 30 * time.Second,
+			// Long-lived streams end when the client says so.
+			Routes: map[string]time.Duration{"/ws": 0, "/wordcount/events": 0},
+		}),
+	)(api)
+	srv := serve.HTTPServer(handler)
+	srv.RegisterOnShutdown(api.Close)
+
+	// The corpus is indexed once before serving and then kept fresh in
+	// the background.
+	sched := scheduler.New(scheduler.Config{Jitter: 10 * time.Second, Logger: logger})
+	if *corpusDir != "" {
+		if err := api.Reindex(context.Background()); err != nil {
+			log.Fatal(err)
+		}
+		sched.Add("reindex", reindexSched, api.Reindex)
+	}
+
+	// Everything stops on SIGINT or SIGTERM, or when one part fails: the
+	// HTTP server first, then gRPC, then the scheduler, each given the
+	// grace period. A second signal kills the process as usual.
+	ctx, stop := ctxutil.WithSignal(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+	g := rungroup.Group{StopTimeout: serve.ShutdownGrace}
+	g.Add("scheduler", func(ctx context.Context) error {
+		sched.Start(ctx)
+		return nil
+	}, sched.Stop)
+	if *corpusDir != "" && *watchCorpus > 0 {
+		w := watch.New(*corpusDir, watch.Config{Interval: *watchCorpus})
+		changes := w.Subscribe()
+		go func() {
+			for batch := range changes.C() {
+				if err := api.ApplyChanges(batch); err != nil {
+					logging.Component(logger, "watch").Warn("corpus update failed", "err", err)
+				}
+			}
+		}()
+		g.Add("watch", w.Run, nil)
+	}
+	g.Add("grpc", func(context.Context) error { return grpcSrv.Serve(grpcLis) }, func(ctx context.Context) error {
+		stopped := make(chan struct{})
+		go func() {
+			grpcSrv.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+			return nil
+		case <-ctx.Done():
+			grpcSrv.Stop()
+			return ctx.Err()
+		}
+	})
+	g.Add("http", func(context.Context) error {
+		if err := srv.Serve(lis); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}, func(ctx context.Context) error {
+		if err := srv.Shutdown(ctx); err != nil {
+			srv.Close()
+			return err
+		}
+		return nil
 	})
-	go srv.ListenAndServe()
-	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
-	defer cancel()
-	_ = srv.Shutdown(ctx)
-}
\ No newline at end of file
+	if err := g.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
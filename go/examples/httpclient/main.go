@@ -0,0 +1,83 @@
+// Command httpclient talks to a running example server through the
+// retrying httpclient.Client: it waits for /readyz, creates a user and
+// lists the users, logging every retry.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"example.com/tutorial/httpclient"
+)
+
+type userJSON struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func main() {
+	base := flag.String("url", "http://localhost:8080", "base `URL` of the example server")
+	name := flag.String("name", "Ada", "name of the user to create")
+	attempts := flag.Int("attempts", 5, "attempts per request")
+	flag.Parse()
+
+	c := httpclient.New(nil, httpclient.Config{
+		MaxAttempts:    *attempts,
+		BaseDelay:      200 * time.Millisecond,
+		AttemptTimeout: 2 * time.Second,
+		OnRetry: func(attempt int, delay time.Duration, err error) {
+			log.Printf("attempt %d failed (%v); retrying in %v", attempt, err, delay.Round(time.Millisecond))
+		},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	resp, err := c.Get(ctx, *base+"/readyz")
+	check(resp, err, http.StatusOK)
+	resp.Body.Close()
+
+	// POST is not idempotent, so this is sent once.
+	body := fmt.Sprintf(`{"name":%q}`, *name)
+	resp, err = c.Post(ctx, *base+"/users", "application/json", strings.NewReader(body))
+	check(resp, err, http.StatusCreated)
+	var created userJSON
+	decode(resp, &created)
+	fmt.Printf("created user %s: %s\n", created.ID, created.Name)
+
+	resp, err = c.Get(ctx, *base+"/users?sort=name")
+	check(resp, err, http.StatusOK)
+	var page struct {
+		Total int        `json:"total"`
+		Users []userJSON `json:"users"`
+	}
+	decode(resp, &page)
+	fmt.Printf("%d users:\n", page.Total)
+	for _, u := range page.Users {
+		fmt.Printf("  %s\t%s\n", u.ID, u.Name)
+	}
+}
+
+// check exits unless the request succeeded with the wanted status.
+func check(resp *http.Response, err error, want int) {
+	if err != nil {
+		log.Fatal(err)
+	}
+	if resp.StatusCode != want {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		log.Fatalf("%s %s: %s: %s", resp.Request.Method, resp.Request.URL, resp.Status, strings.TrimSpace(string(msg)))
+	}
+}
+
+func decode(resp *http.Response, v any) {
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		log.Fatal(err)
+	}
+}
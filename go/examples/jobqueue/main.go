@@ -0,0 +1,109 @@
+// Command jobqueue counts the words of files as jobs on a queue: a
+// producer submits a job per file and consumer workers count them,
+// printing each result as it is done. With -fail the workers fail that
+// fraction of their attempts, as a flaky result store would, to show
+// the jobs retried with backoff; a job failing -attempts times is set
+// aside on a dead-letter queue and listed at the end.
+//
+// The queue is package jobs' in-memory one; a NATS JetStream or Kafka
+// client implementing jobs.Queue would spread the same workers over
+// processes.
+//
+//	go run ./examples/jobqueue -workers 2 README.md main.go
+//	go run ./examples/jobqueue -fail 0.5 -attempts 3 *.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"example.com/tutorial/ctxutil"
+	"example.com/tutorial/jobs"
+	"example.com/tutorial/logging"
+)
+
+func main() {
+	workers := flag.Int("workers", jobs.DefaultWorkers, "jobs counted at once")
+	attempts := flag.Int("attempts", jobs.DefaultMaxAttempts, "deliveries of a job before it is dead-lettered")
+	fail := flag.Float64("fail", 0, "fraction of attempts that fail, from 0 to 1")
+	top := flag.Int("top", 5, "how many of the most frequent words to print per file")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		log.Fatal("usage: jobqueue [-workers n] [-attempts n] [-fail fraction] [-top n] file ...")
+	}
+
+	ctx, stop := ctxutil.WithSignal(context.Background())
+	defer stop()
+	queue := jobs.NewMemory(jobs.MemoryConfig{})
+	dead := jobs.NewMemory(jobs.MemoryConfig{})
+
+	// A job may be delivered again after it was counted, so results are
+	// kept by job ID and printed once.
+	var mu sync.Mutex
+	done := map[string]bool{}
+	deliver := func(ctx context.Context, r jobs.Result) error {
+		if rand.Float64() < *fail {
+			return errors.New("result store unavailable")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if !done[r.JobID] {
+			done[r.JobID] = true
+			fmt.Printf("%s: %d words, %d unique, top %v\n", r.Name, r.Words, r.Unique, r.Top)
+		}
+		return nil
+	}
+	consumer := jobs.NewConsumer(queue, jobs.WordCount(deliver), jobs.Config{
+		Workers:     *workers,
+		MaxAttempts: *attempts,
+		BaseDelay:   20 * time.Millisecond,
+		MaxDelay:    time.Second,
+		DeadLetter:  dead,
+		Logger:      slog.New(logging.ContextHandler(slog.NewTextHandler(os.Stderr, nil))),
+	})
+	errc := make(chan error, 1)
+	go func() { errc <- consumer.Run(ctx) }()
+
+	for _, name := range flag.Args() {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if _, err := jobs.Submit(ctx, queue, jobs.Job{Name: name, Text: string(b), Top: *top}); err != nil {
+			log.Fatal(err)
+		}
+	}
+	// The queue is empty once every job is acknowledged, counted or
+	// given up.
+	tick := time.NewTicker(10 * time.Millisecond)
+	for queue.Len() > 0 && ctx.Err() == nil {
+		<-tick.C
+	}
+	tick.Stop()
+	queue.Close()
+	if err := <-errc; err != nil {
+		log.Fatal(err)
+	}
+
+	s := consumer.Stats()
+	fmt.Fprintf(os.Stderr, "%d done, %d retried, %d dead-lettered\n", s.Done, s.Retried, s.Dead)
+	for dead.Len() > 0 {
+		d, err := dead.Receive(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var job jobs.Job
+		json.Unmarshal(d.Body(), &job)
+		fmt.Fprintf(os.Stderr, "dead letter: %s\n", job.Name)
+		d.Ack(ctx)
+	}
+}
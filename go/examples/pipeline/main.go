@@ -0,0 +1,129 @@
+// Command pipeline counts the words of files through a three-stage
+// pipeline: one goroutine reads lines, several tokenize them and one
+// counts the words. With -rate the lines are read at most that many a
+// second, as a job sharing a slow source would. Markdown, CSV, JSON and
+// XML files are read for their text, as package ingest extracts it;
+// -columns, -fields and -elements pick the CSV columns, JSON fields and
+// XML elements to count. Progress is
+// shown on stderr when it is a terminal, or as JSON lines with -progress
+// json.
+//
+//	go run ./examples/pipeline -top 5 README.md main.go
+//	go run ./examples/pipeline -columns title,body posts.csv
+//	go run ./examples/pipeline -elements body articles.xml
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"example.com/tutorial/ctxutil"
+	"example.com/tutorial/ingest"
+	"example.com/tutorial/pipeline"
+	"example.com/tutorial/progress"
+	"example.com/tutorial/ratelimit"
+	"example.com/tutorial/text"
+)
+
+func main() {
+	workers := flag.Int("workers", 4, "tokenizer goroutines")
+	top := flag.Int("top", 10, "how many of the most frequent words to print")
+	rate := flag.Float64("rate", 0, "lines read per second; 0 means unlimited")
+	columns := flag.String("columns", "", "comma-separated CSV columns to count; empty means all")
+	fields := flag.String("fields", "", "comma-separated JSON fields to count; empty means all")
+	elements := flag.String("elements", "", "comma-separated XML elements whose text to count; empty means all")
+	var mode progress.Mode
+	flag.TextVar(&mode, "progress", progress.Auto, "report progress on stderr: auto, on, off or json")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		log.Fatal("usage: pipeline [-workers n] [-top n] [-rate n] [-columns list] [-fields list] [-elements list] [-progress mode] file ...")
+	}
+	opts := ingest.Options{Columns: splitList(*columns), Fields: splitList(*fields), Elements: splitList(*elements)}
+	var lim *ratelimit.Limiter
+	if *rate > 0 {
+		lim = ratelimit.NewLimiter(*rate, 1)
+	}
+
+	ctx, stop := ctxutil.WithSignal(context.Background())
+	defer stop()
+	p := pipeline.New(ctx, pipeline.WithWorkers(*workers))
+	t := progress.NewTracker()
+	t.SetTotal(flag.NArg(), 0)
+	rep := progress.Start(os.Stderr, t, progress.Options{Mode: mode, Label: "pipeline"})
+
+	lines := pipeline.Generate(p, pipeline.StageConfig{Buffer: 64}, func(ctx context.Context, emit pipeline.Emit[string]) error {
+		for _, name := range flag.Args() {
+			if err := readLines(ctx, name, opts, lim, t, emit); err != nil {
+				return err
+			}
+			t.AddFiles(1)
+		}
+		return nil
+	})
+	words := pipeline.Transform(p, lines, pipeline.StageConfig{Buffer: 256},
+		func(ctx context.Context, line string, emit pipeline.Emit[string]) error {
+			for _, w := range text.Words(line) {
+				if !emit(w) {
+					break
+				}
+			}
+			return nil
+		})
+	counts := map[string]int{}
+	pipeline.Sink(p, words, pipeline.StageConfig{}, func(ctx context.Context, w string) error {
+		counts[w]++
+		return nil
+	})
+
+	err := p.Wait()
+	rep.Stop()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := text.EncodeFrequencies(os.Stdout, text.TopWords(counts, *top), text.FormatText); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprintf(os.Stderr, "%d distinct words\n", len(counts))
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// readLines emits the lines of the text of the named file, waiting on lim
+// if it is not nil, until it ends or the pipeline stops. t counts the
+// bytes of text read.
+func readLines(ctx context.Context, name string, opts ingest.Options, lim *ratelimit.Limiter, t *progress.Tracker, emit pipeline.Emit[string]) error {
+	r, _, err := ingest.Open(name, opts)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	sc := bufio.NewScanner(t.Reader(r))
+	for sc.Scan() {
+		if lim != nil {
+			if err := lim.Wait(ctx); err != nil {
+				return nil // cancelled, which the pipeline reports
+			}
+		}
+		if !emit(sc.Text()) {
+			return nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
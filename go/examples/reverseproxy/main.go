@@ -0,0 +1,68 @@
+// Command reverseproxy serves the word-count API under /api/ through a
+// reverse proxy. Without -upstream it starts the API itself on a local
+// port; with it, it fronts an already running example server. A circuit
+// breaker stops forwarding for a while once most requests to the upstream
+// fail.
+//
+//	go run ./examples/reverseproxy
+//	curl --data-binary @README.md localhost:8000/api/wordcount
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"example.com/tutorial/breaker"
+	"example.com/tutorial/proxy"
+	"example.com/tutorial/server"
+	"example.com/tutorial/user"
+)
+
+func main() {
+	addr := flag.String("addr", ":8000", "proxy listen `address`")
+	upstream := flag.String("upstream", "", "upstream base `URL` (default: start one on a local port)")
+	prefix := flag.String("prefix", "/api", "path `prefix` stripped before forwarding")
+	interval := flag.Duration("health-interval", proxy.DefaultHealthInterval, "time between upstream health checks")
+	openFor := flag.Duration("breaker-open", breaker.DefaultOpenTimeout, "how long the breaker stays open before trying the upstream again")
+	flag.Parse()
+
+	if *upstream == "" {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			log.Fatal(err)
+		}
+		api := &http.Server{Handler: server.New(user.NewMemoryStore()), ReadHeaderTimeout: 5 * time.Second}
+		go api.Serve(lis)
+		*upstream = "http://" + lis.Addr().String()
+	}
+	target, err := url.Parse(*upstream)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	b := breaker.New(breaker.Config{
+		OpenTimeout: *openFor,
+		OnStateChange: func(from, to breaker.State) {
+			log.Printf("breaker: %v -> %v", from, to)
+		},
+	})
+	p := proxy.New(target, proxy.Config{StripPrefix: *prefix, HealthInterval: *interval, Breaker: b})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.Watch(ctx)
+
+	lis, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("proxying %s%s/ to %s", lis.Addr(), *prefix, target)
+	srv := &http.Server{Handler: p, ReadHeaderTimeout: 5 * time.Second}
+	if err := server.Run(ctx, srv, lis, server.DefaultGracePeriod); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"example.com/tutorial/columnar"
+	"example.com/tutorial/text"
+)
+
+// runExport implements the "export" subcommand, which writes the word
+// frequencies of each file to its own partition of a directory, as CSV
+// or Parquet for analytics tools to load, and returns the process exit
+// code. Archives among the files are extracted and their files exported
+// as "archive:path", as wordcount counts them.
+func runExport(ctx context.Context, cfg exportSettings, dryRun bool, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: export [-format csv|parquet] [-top n] [-o dir] file|archive ...")
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", cfg.Format, "file `format`: csv or parquet")
+	top := fs.Int("top", cfg.Top, "export the `n` most frequent words of each file (-1 for all)")
+	dir := fs.String("o", cfg.Dir, "write the partitions into `dir`, made if missing")
+	maxExtract := cfg.MaxExtract
+	fs.TextVar(&maxExtract, "max-extract", cfg.MaxExtract, "extract at most `size` from archives in all, such as 512MiB")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	f, err := columnar.ParseFormat(*format)
+	if err != nil {
+		fmt.Fprintln(stderr, "export:", err)
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	inputs, cleanup, status := wcInputs(ctx, fs.Args(), int64(maxExtract), stderr)
+	defer cleanup()
+	var tables []columnar.Table
+	for _, in := range inputs {
+		counts, err := exportCount(in)
+		if err != nil {
+			fmt.Fprintln(stderr, "export:", err)
+			status = 1
+			continue
+		}
+		tables = append(tables, columnar.Table{Source: in.name, Freqs: text.TopWords(counts, *top)})
+	}
+	if _, err := columnar.Export(*dir, tables, columnar.Config{Format: f, Files: newExecutor(dryRun, stdout)}); err != nil {
+		fmt.Fprintln(stderr, "export:", err)
+		return 1
+	}
+	return status
+}
+
+// exportCount returns the word frequencies of the input.
+func exportCount(in wcInput) (map[string]int, error) {
+	f, err := os.Open(in.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	counts, err := text.WordCountReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", in.name, err)
+	}
+	return counts, nil
+}
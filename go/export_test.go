@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"example.com/tutorial/columnar"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestRunExport(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	os.WriteFile(a, []byte("the cat and the hat"), 0o644)
+	out := filepath.Join(dir, "out")
+	cfg := defaultSettings().Export
+	cfg.Dir = out
+	run := func(dryRun bool, args ...string) (string, int) {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		code := runExport(context.Background(), cfg, dryRun, args, &stdout, &stderr)
+		return stdout.String() + stderr.String(), code
+	}
+	part := func(f columnar.Format) string {
+		return filepath.Join(out, filepath.FromSlash(columnar.PartitionPath(a, f)))
+	}
+
+	if log, code := run(true, a); code != 0 || !strings.Contains(log, "would write "+part(columnar.CSV)) {
+		t.Fatalf("dry run: exit %d:\n%s", code, log)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Fatalf("dry run made %s", out)
+	}
+
+	if log, code := run(false, "-top", "2", a); code != 0 || log != "" {
+		t.Fatalf("csv: exit %d:\n%s", code, log)
+	}
+	data, err := os.ReadFile(part(columnar.CSV))
+	if want := "word:string,count:int64,rank:int32\nthe,2,1\nand,1,2\n"; err != nil || string(data) != want {
+		t.Errorf("csv: %q, %v; want %q", data, err, want)
+	}
+
+	if log, code := run(false, "-format", "parquet", a, filepath.Join(dir, "missing.txt")); code != 1 || !strings.Contains(log, "no such file") {
+		t.Fatalf("parquet: exit %d:\n%s", code, log)
+	}
+	rows, err := parquet.ReadFile[columnar.Row](part(columnar.Parquet))
+	if err != nil || len(rows) != 4 || rows[0] != (columnar.Row{Word: "the", Count: 2, Rank: 1}) {
+		t.Errorf("parquet: %v, %v", rows, err)
+	}
+}
+
+func TestRunExportUsage(t *testing.T) {
+	for _, args := range [][]string{nil, {"-format", "xlsx", "a.txt"}, {"-bogus"}} {
+		var stdout, stderr bytes.Buffer
+		if code := runExport(context.Background(), defaultSettings().Export, false, args, &stdout, &stderr); code != 2 {
+			t.Errorf("%q: exit %d, want 2", args, code)
+		}
+	}
+}
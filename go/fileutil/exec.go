@@ -0,0 +1,146 @@
+package fileutil
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Executor makes the changes to files that code asks of it or, in a dry
+// run, only says what they would be, so that a command can show what it
+// would do before doing it. It is safe for concurrent use, and a nil
+// *Executor makes every change without a word.
+type Executor struct {
+	// DryRun reports changes instead of making them. Reading what would
+	// be written still happens, so that its errors show.
+	DryRun bool
+	// Log, if set, gets a line for each change, such as "write a.txt",
+	// or "would write a.txt" in a dry run.
+	Log io.Writer
+
+	mu   sync.Mutex
+	dirs map[string]bool // made in a dry run
+}
+
+// report logs the change verb path and returns whether to make it.
+func (e *Executor) report(verb, path string) bool {
+	if e == nil {
+		return true
+	}
+	if e.Log != nil {
+		e.mu.Lock()
+		if e.DryRun {
+			fmt.Fprintf(e.Log, "would %s %s\n", verb, path)
+		} else {
+			fmt.Fprintf(e.Log, "%s %s\n", verb, path)
+		}
+		e.mu.Unlock()
+	}
+	return !e.DryRun
+}
+
+// WriteAtomic is like the function WriteAtomic.
+func (e *Executor) WriteAtomic(path string, data []byte) error {
+	if !e.report("write", path) {
+		return nil
+	}
+	return WriteAtomic(path, data)
+}
+
+// WriteAtomicReader is like the function WriteAtomicReader. A dry run
+// reads r to the end.
+func (e *Executor) WriteAtomicReader(path string, r io.Reader) error {
+	if !e.report("write", path) {
+		_, err := io.Copy(io.Discard, r)
+		return err
+	}
+	return WriteAtomicReader(path, r)
+}
+
+// WriteChecksummed is like the function WriteChecksummed.
+func (e *Executor) WriteChecksummed(path string, data []byte) error {
+	if !e.report("write", path) {
+		return nil
+	}
+	return WriteChecksummed(path, data)
+}
+
+// WriteFile writes what it reads from r to the file at path, created with
+// perm or truncated, and returns the number of bytes written. Unlike
+// WriteAtomic it writes in place; if it fails the file is removed. A dry
+// run reads r to the end and returns the bytes read.
+func (e *Executor) WriteFile(path string, r io.Reader, perm fs.FileMode) (int64, error) {
+	if !e.report("write", path) {
+		return io.Copy(io.Discard, r)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return n, err
+}
+
+// Create creates or truncates the file at path for writing, as os.Create
+// does. In a dry run what is written is discarded.
+func (e *Executor) Create(path string) (io.WriteCloser, error) {
+	if !e.report("write", path) {
+		return nopCloser{io.Discard}, nil
+	}
+	return os.Create(path)
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// MkdirAll makes the directory path and any missing parents, as
+// os.MkdirAll does. Only a directory that does not exist yet is
+// reported, once.
+func (e *Executor) MkdirAll(path string, perm fs.FileMode) error {
+	if e == nil {
+		return os.MkdirAll(path, perm)
+	}
+	path = filepath.Clean(path)
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return nil
+	}
+	if e.DryRun {
+		e.mu.Lock()
+		made := e.dirs[path]
+		if e.dirs == nil {
+			e.dirs = map[string]bool{}
+		}
+		// Parents are made along with path.
+		for p := path; !e.dirs[p]; p = filepath.Dir(p) {
+			e.dirs[p] = true
+		}
+		e.mu.Unlock()
+		if made {
+			return nil
+		}
+	}
+	if !e.report("mkdir", path) {
+		return nil
+	}
+	return os.MkdirAll(path, perm)
+}
+
+// Remove removes the file or empty directory at path. A dry run checks
+// that it exists.
+func (e *Executor) Remove(path string) error {
+	if !e.report("remove", path) {
+		_, err := os.Lstat(path)
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,108 @@
+package fileutil
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExecutorDryRun(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "keep.txt")
+	os.WriteFile(existing, []byte("old"), 0o644)
+	var log bytes.Buffer
+	e := &Executor{DryRun: true, Log: &log}
+
+	if err := e.WriteAtomic(existing, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.WriteChecksummed(filepath.Join(dir, "sum.json"), []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(dir, "a", "b")
+	e.MkdirAll(sub, 0o755)
+	e.MkdirAll(filepath.Join(dir, "a"), 0o755) // made along with a/b
+	e.MkdirAll(dir, 0o755)                     // exists
+	n, err := e.WriteFile(filepath.Join(sub, "c.txt"), strings.NewReader("12345"), 0o644)
+	if err != nil || n != 5 {
+		t.Fatalf("WriteFile = %d, %v", n, err)
+	}
+	w, _ := e.Create(filepath.Join(dir, "out.zip"))
+	io.WriteString(w, "discarded")
+	w.Close()
+	if err := e.Remove(existing); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Remove(filepath.Join(dir, "missing")); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Remove of a missing file: %v", err)
+	}
+
+	want := strings.Join([]string{
+		"would write " + existing,
+		"would write " + filepath.Join(dir, "sum.json"),
+		"would mkdir " + sub,
+		"would write " + filepath.Join(sub, "c.txt"),
+		"would write " + filepath.Join(dir, "out.zip"),
+		"would remove " + existing,
+		"would remove " + filepath.Join(dir, "missing"),
+	}, "\n") + "\n"
+	if log.String() != want {
+		t.Errorf("log:\n%s\nwant:\n%s", log.String(), want)
+	}
+	entries, _ := os.ReadDir(dir)
+	if data, _ := os.ReadFile(existing); len(entries) != 1 || string(data) != "old" {
+		t.Fatalf("dry run changed %s: %v, %q", dir, entries, data)
+	}
+}
+
+func TestExecutorReal(t *testing.T) {
+	dir := t.TempDir()
+	var log bytes.Buffer
+	for _, e := range []*Executor{nil, {Log: &log}} {
+		sub := filepath.Join(dir, "x", "y")
+		if err := e.MkdirAll(sub, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(sub, "f.txt")
+		if n, err := e.WriteFile(path, strings.NewReader("abc"), 0o600); err != nil || n != 3 {
+			t.Fatalf("WriteFile = %d, %v", n, err)
+		}
+		if err := e.WriteAtomicReader(path+".2", strings.NewReader("def")); err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := os.ReadFile(path); string(data) != "abc" {
+			t.Fatalf("wrote %q", data)
+		}
+		if err := e.Remove(path); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("not removed: %v", err)
+		}
+		os.RemoveAll(filepath.Join(dir, "x"))
+	}
+	if !strings.HasPrefix(log.String(), "mkdir "+filepath.Join(dir, "x", "y")+"\nwrite ") {
+		t.Errorf("log %q", log.String())
+	}
+}
+
+func TestExecutorWriteFileFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f.txt")
+	boom := errors.New("boom")
+	_, err := (*Executor)(nil).WriteFile(path, io.MultiReader(strings.NewReader("part"), errReader{boom}), 0o644)
+	if !errors.Is(err, boom) {
+		t.Fatalf("err = %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("partial file left: %v", err)
+	}
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
@@ -0,0 +1,122 @@
+// Package fileutil writes files so that a crash or power loss leaves
+// either their old or their new contents, never a mix, and reads them
+// back with a check that the contents are what was written.
+package fileutil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrChecksum is returned by ReadChecksummed for a file whose contents
+// do not match its checksum.
+var ErrChecksum = errors.New("fileutil: checksum mismatch")
+
+// ChecksumSuffix is appended to a file's path to name the file holding
+// its checksum, in the format of sha256sum(1).
+const ChecksumSuffix = ".sha256"
+
+// WriteAtomic replaces the file at path with data, as WriteAtomicReader
+// does.
+func WriteAtomic(path string, data []byte) error {
+	return WriteAtomicReader(path, bytes.NewReader(data))
+}
+
+// WriteAtomicReader replaces the file at path with the contents of r. It
+// writes them to a temporary file in the same directory, syncs it to
+// disk and renames it over path, then syncs the directory so the rename
+// survives a crash too. If anything fails, the file at path is left as
+// it was and the temporary file is removed. A new file gets mode 0644;
+// a replaced one keeps its mode.
+func WriteAtomicReader(path string, r io.Reader) error {
+	mode := fs.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir flushes a directory's entries to disk. Some systems cannot
+// open directories for syncing; there the rename is as durable as the
+// file system makes it.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return nil
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil && !errors.Is(err, errors.ErrUnsupported) && !errors.Is(err, fs.ErrInvalid) {
+		return err
+	}
+	return nil
+}
+
+// WriteChecksummed writes data to path with WriteAtomic, then its
+// SHA-256 to path+ChecksumSuffix. The checksum is written second, so a
+// crash between the two is caught by ReadChecksummed as a mismatch
+// rather than passing off a half-updated file.
+func WriteChecksummed(path string, data []byte) error {
+	if err := WriteAtomic(path, data); err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	line := hex.EncodeToString(sum[:]) + "  " + filepath.Base(path) + "\n"
+	return WriteAtomic(path+ChecksumSuffix, []byte(line))
+}
+
+// ReadChecksummed reads the file at path and checks it against the
+// checksum that WriteChecksummed stored next to it, returning an error
+// wrapping ErrChecksum if they differ. A file without a checksum file is
+// returned as it is, so files written before checksums were kept can
+// still be read.
+func ReadChecksummed(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	line, err := os.ReadFile(path + ChecksumSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return data, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	want, _, _ := strings.Cut(strings.TrimSpace(string(line)), " ")
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, want) {
+		return nil, fmt.Errorf("%w: %s has sha256 %s, want %s", ErrChecksum, path, got, want)
+	}
+	return data, nil
+}
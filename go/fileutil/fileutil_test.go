@@ -0,0 +1,109 @@
+package fileutil
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// leftovers returns the names in dir other than the given ones.
+func leftovers(t *testing.T, dir string, want ...string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var extra []string
+outer:
+	for _, e := range entries {
+		for _, w := range want {
+			if e.Name() == w {
+				continue outer
+			}
+		}
+		extra = append(extra, e.Name())
+	}
+	return extra
+}
+
+func TestWriteAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f.json")
+	if err := WriteAtomic(path, []byte("one")); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0o644 {
+		t.Fatalf("new file: %v, %v", info, err)
+	}
+
+	os.Chmod(path, 0o600)
+	if err := WriteAtomicReader(path, strings.NewReader("two")); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "two" {
+		t.Fatalf("contents %q", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Fatalf("mode not kept: %v", info.Mode())
+	}
+	if extra := leftovers(t, dir, "f.json"); extra != nil {
+		t.Fatalf("left behind %v", extra)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("boom") }
+
+func TestWriteAtomicFailure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f")
+	WriteAtomic(path, []byte("old"))
+	if err := WriteAtomicReader(path, failingReader{}); err == nil || err.Error() != "boom" {
+		t.Fatalf("err = %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "old" {
+		t.Fatalf("contents %q after a failed write", data)
+	}
+	if extra := leftovers(t, dir, "f"); extra != nil {
+		t.Fatalf("left behind %v", extra)
+	}
+	if err := WriteAtomic(filepath.Join(dir, "missing", "f"), nil); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("missing dir: %v", err)
+	}
+}
+
+func TestChecksummed(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index.json")
+	if err := WriteChecksummed(path, []byte(`{"a":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	sum, _ := os.ReadFile(path + ChecksumSuffix)
+	if !strings.HasSuffix(string(sum), "  index.json\n") || len(sum) != 64+2+len("index.json")+1 {
+		t.Fatalf("checksum file %q", sum)
+	}
+	data, err := ReadChecksummed(path)
+	if err != nil || string(data) != `{"a":1}` {
+		t.Fatalf("ReadChecksummed = %q, %v", data, err)
+	}
+
+	// A change behind the checksum's back is caught.
+	os.WriteFile(path, []byte(`{"a":2}`), 0o644)
+	if _, err := ReadChecksummed(path); !errors.Is(err, ErrChecksum) {
+		t.Fatalf("corrupt file: %v", err)
+	}
+
+	// Files without a checksum are read as they are.
+	os.Remove(path + ChecksumSuffix)
+	if data, err := ReadChecksummed(path); err != nil || string(data) != `{"a":2}` {
+		t.Fatalf("no checksum: %q, %v", data, err)
+	}
+	if _, err := ReadChecksummed(filepath.Join(dir, "missing")); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("missing file: %v", err)
+	}
+}
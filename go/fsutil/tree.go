@@ -0,0 +1,188 @@
+package fsutil
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"example.com/tutorial/humanize"
+)
+
+// TreeOptions selects what Tree includes.
+type TreeOptions struct {
+	// MaxDepth is the number of levels below the root included; zero
+	// means no limit. Directories at the limit are listed without
+	// their contents.
+	MaxDepth int
+	// Include, if not empty, keeps only the files matching one of its
+	// patterns; directories are kept either way. Exclude drops the
+	// files and directories matching one of its patterns. Patterns use
+	// path.Match syntax and are matched against the base name, or
+	// against the slash-separated path below the root if they contain
+	// a slash.
+	Include, Exclude []string
+}
+
+// TreeNode is a file or directory in the tree returned by Tree.
+type TreeNode struct {
+	Name string `json:"name"`
+	Dir  bool   `json:"dir,omitempty"`
+	// Size is the file size, or for a directory the total size of the
+	// files included below it, up to MaxDepth.
+	Size     int64       `json:"size"`
+	Children []*TreeNode `json:"children,omitempty"`
+}
+
+// Tree reads the hierarchy below root in fsys, with directory entries in
+// name order. Symbolic links are listed but not followed.
+func Tree(fsys fs.FS, root string, opts TreeOptions) (*TreeNode, error) {
+	if err := CheckPatterns(opts.Include, opts.Exclude); err != nil {
+		return nil, err
+	}
+	info, err := fs.Stat(fsys, root)
+	if err != nil {
+		return nil, err
+	}
+	n := &TreeNode{Name: root, Dir: info.IsDir(), Size: info.Size()}
+	if n.Dir {
+		n.Size = 0
+		if err := buildTree(fsys, root, "", 1, opts, n); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+func buildTree(fsys fs.FS, dir, rel string, depth int, opts TreeOptions, n *TreeNode) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		erel := path.Join(rel, e.Name())
+		if MatchAny(opts.Exclude, erel) {
+			continue
+		}
+		c := &TreeNode{Name: e.Name(), Dir: e.IsDir()}
+		if c.Dir {
+			if opts.MaxDepth <= 0 || depth < opts.MaxDepth {
+				if err := buildTree(fsys, path.Join(dir, e.Name()), erel, depth+1, opts, c); err != nil {
+					return err
+				}
+			}
+		} else {
+			if len(opts.Include) > 0 && !MatchAny(opts.Include, erel) {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil {
+				return err
+			}
+			c.Size = info.Size()
+		}
+		n.Size += c.Size
+		n.Children = append(n.Children, c)
+	}
+	return nil
+}
+
+// CheckPatterns reports the first malformed pattern in the lists.
+func CheckPatterns(lists ...[]string) error {
+	for _, l := range lists {
+		for _, pat := range l {
+			if _, err := path.Match(pat, ""); err != nil {
+				return fmt.Errorf("fsutil: pattern %q: %w", pat, err)
+			}
+		}
+	}
+	return nil
+}
+
+// MatchAny reports whether rel, a slash-separated path below a root,
+// matches one of the patterns: as with TreeOptions, a pattern matches
+// the base name, or the whole path if it contains a slash.
+func MatchAny(patterns []string, rel string) bool {
+	for _, pat := range patterns {
+		name := path.Base(rel)
+		if strings.Contains(pat, "/") {
+			name = rel
+		}
+		if ok, _ := path.Match(pat, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Counts returns the number of directories and files below n.
+func (n *TreeNode) Counts() (dirs, files int) {
+	for _, c := range n.Children {
+		if c.Dir {
+			d, f := c.Counts()
+			dirs += d + 1
+			files += f
+		} else {
+			files++
+		}
+	}
+	return dirs, files
+}
+
+// WriteText draws the tree in the style of tree(1), with human-readable
+// sizes before the names if sizes is set, followed by a summary line.
+func (n *TreeNode) WriteText(w io.Writer, sizes bool) error {
+	ew := &errWriter{w: w}
+	label := func(c *TreeNode) string {
+		if sizes {
+			return fmt.Sprintf("[%6s]  %s", humanize.CompactBytes(c.Size), c.Name)
+		}
+		return c.Name
+	}
+	fmt.Fprintln(ew, label(n))
+	var draw func(n *TreeNode, prefix string)
+	draw = func(n *TreeNode, prefix string) {
+		for i, c := range n.Children {
+			branch, indent := "├── ", "│   "
+			if i == len(n.Children)-1 {
+				branch, indent = "└── ", "    "
+			}
+			fmt.Fprintln(ew, prefix+branch+label(c))
+			draw(c, prefix+indent)
+		}
+	}
+	draw(n, "")
+	dirs, files := n.Counts()
+	fmt.Fprintf(ew, "\n%s, %s\n", plural(dirs, "directory", "directories"), plural(files, "file", "files"))
+	return ew.err
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return "1 " + one
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
+
+// FormatSize formats a byte count with a binary unit suffix, as in
+// "512", "1.5K" or "12M", in the style of ls -h. It is
+// humanize.CompactBytes.
+func FormatSize(n int64) string {
+	return humanize.CompactBytes(n)
+}
+
+// errWriter remembers the first write error so callers can check once.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	n, err := e.w.Write(p)
+	e.err = err
+	return n, err
+}
@@ -0,0 +1,127 @@
+package fsutil
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func treeFS() fstest.MapFS {
+	return fstest.MapFS{
+		"README.md":           {Data: make([]byte, 100)},
+		"main.go":             {Data: make([]byte, 2048)},
+		"cmd/tool/tool.go":    {Data: make([]byte, 10)},
+		"cmd/tool/notes.txt":  {Data: make([]byte, 5)},
+		"vendor/x/x.go":       {Data: make([]byte, 1)},
+		"docs/guide/intro.md": {Data: make([]byte, 20)},
+	}
+}
+
+func render(t *testing.T, n *TreeNode, sizes bool) string {
+	t.Helper()
+	var b strings.Builder
+	if err := n.WriteText(&b, sizes); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestTree(t *testing.T) {
+	n, err := Tree(treeFS(), ".", TreeOptions{Exclude: []string{"vendor"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `.
+├── README.md
+├── cmd
+│   └── tool
+│       ├── notes.txt
+│       └── tool.go
+├── docs
+│   └── guide
+│       └── intro.md
+└── main.go
+
+4 directories, 5 files
+`
+	if got := render(t, n, false); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if n.Size != 2183 {
+		t.Fatalf("root size %d", n.Size)
+	}
+}
+
+func TestTreeDepthAndSizes(t *testing.T) {
+	n, err := Tree(treeFS(), ".", TreeOptions{MaxDepth: 1, Include: []string{"*.go"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[  2.0K]  .
+├── [     0]  cmd
+├── [     0]  docs
+├── [  2.0K]  main.go
+└── [     0]  vendor
+
+3 directories, 1 file
+`
+	if got := render(t, n, true); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTreeIncludePaths(t *testing.T) {
+	n, err := Tree(treeFS(), "cmd", TreeOptions{Include: []string{"tool/*.go"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := render(t, n, false); !strings.Contains(got, "tool.go") || strings.Contains(got, "notes.txt") {
+		t.Fatalf("path pattern not applied:\n%s", got)
+	}
+}
+
+func TestTreeErrors(t *testing.T) {
+	if _, err := Tree(treeFS(), ".", TreeOptions{Exclude: []string{"["}}); err == nil {
+		t.Fatal("bad pattern accepted")
+	}
+	if _, err := Tree(treeFS(), "missing", TreeOptions{}); err == nil {
+		t.Fatal("missing root accepted")
+	}
+	n, err := Tree(treeFS(), "main.go", TreeOptions{})
+	if err != nil || n.Dir || n.Size != 2048 {
+		t.Fatalf("file root = %+v, %v", n, err)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for n, want := range map[int64]string{
+		0: "0", 1023: "1023", 1024: "1.0K", 1536: "1.5K", 10 << 10: "10K",
+		5 << 20: "5.0M", 300 << 30: "300G",
+	} {
+		if got := FormatSize(n); got != want {
+			t.Errorf("FormatSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestMatchAny(t *testing.T) {
+	for _, tc := range []struct {
+		patterns []string
+		rel      string
+		want     bool
+	}{
+		{[]string{"*.go"}, "cmd/tool/main.go", true},
+		{[]string{"*.go"}, "notes.txt", false},
+		{[]string{"cmd/*/main.go"}, "cmd/tool/main.go", true},
+		{[]string{"cmd/*.go"}, "cmd/tool/main.go", false},
+		{[]string{"*.txt", "vendor"}, "vendor", true},
+		{nil, "a", false},
+	} {
+		if got := MatchAny(tc.patterns, tc.rel); got != tc.want {
+			t.Errorf("MatchAny(%q, %q) = %v", tc.patterns, tc.rel, got)
+		}
+	}
+	if err := CheckPatterns([]string{"*.go"}, []string{"a/[b"}); err == nil || !strings.Contains(err.Error(), "a/[b") {
+		t.Fatalf("CheckPatterns = %v", err)
+	}
+}
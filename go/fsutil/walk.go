@@ -0,0 +1,180 @@
+// Package fsutil has file-system helpers: concurrent tree walks for the
+// indexing code and directory listings for the command line.
+package fsutil
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"example.com/tutorial/progress"
+)
+
+// WalkFunc is called for every file and directory visited by a Walker,
+// with the same meaning of its arguments and results as fs.WalkDirFunc:
+// err is non-nil when path could not be read, fs.SkipDir returned for a
+// directory skips its contents, and fs.SkipAll ends the walk without an
+// error. fs.SkipDir returned for a file is ignored, since its siblings
+// may already be in progress. A WalkFunc is called from several
+// goroutines at once.
+type WalkFunc func(ctx context.Context, path string, d fs.DirEntry, err error) error
+
+// Walker walks directory trees with several goroutines.
+type Walker struct {
+	// Workers bounds the goroutines calling the WalkFunc and reading
+	// directories; zero means runtime.GOMAXPROCS(0).
+	Workers int
+	// AllErrors keeps walking after an error and returns every error,
+	// joined with errors.Join, instead of stopping at the first.
+	AllErrors bool
+	// Progress, if not nil, counts the regular files visited.
+	Progress *progress.Tracker
+}
+
+// WalkConcurrent walks the tree rooted at root with a default Walker,
+// stopping at the first error.
+func WalkConcurrent(ctx context.Context, root string, fn WalkFunc) error {
+	return (&Walker{}).Walk(ctx, root, fn)
+}
+
+// walkState is the work queue shared by the goroutines of one walk.
+type walkState struct {
+	w  *Walker
+	fn WalkFunc
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []task
+	pending int // tasks queued or in progress
+	stopped bool
+	errs    []error
+}
+
+type task struct {
+	path string
+	d    fs.DirEntry
+}
+
+// Walk calls fn for root and everything below it, visiting directories
+// before their contents but otherwise in no particular order. Symbolic
+// links are not followed. Cancelling ctx stops the walk, and Walk then
+// returns ctx's error unless fn failed first.
+func (w *Walker) Walk(ctx context.Context, root string, fn WalkFunc) error {
+	info, err := os.Lstat(root)
+	if err != nil {
+		err = fn(ctx, root, nil, err)
+		if errors.Is(err, fs.SkipDir) || errors.Is(err, fs.SkipAll) {
+			return nil
+		}
+		return err
+	}
+	st := &walkState{w: w, fn: fn, queue: []task{{root, fs.FileInfoToDirEntry(info)}}, pending: 1}
+	st.cond = sync.NewCond(&st.mu)
+	stop := context.AfterFunc(ctx, func() { st.stop(nil) })
+	defer stop()
+
+	workers := w.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			st.work(ctx)
+		}()
+	}
+	wg.Wait()
+
+	if len(st.errs) == 0 {
+		return ctx.Err()
+	}
+	if w.AllErrors {
+		return errors.Join(st.errs...)
+	}
+	return st.errs[0]
+}
+
+// work runs tasks until the queue is drained or the walk stops.
+func (st *walkState) work(ctx context.Context) {
+	for {
+		st.mu.Lock()
+		for len(st.queue) == 0 && st.pending > 0 && !st.stopped {
+			st.cond.Wait()
+		}
+		// The AfterFunc wakes waiting workers on cancellation, but a busy
+		// one checks ctx itself so that no task starts after it.
+		if st.stopped || st.pending == 0 || ctx.Err() != nil {
+			st.stopped = true
+			st.cond.Broadcast()
+			st.mu.Unlock()
+			return
+		}
+		t := st.queue[len(st.queue)-1]
+		st.queue = st.queue[:len(st.queue)-1]
+		st.mu.Unlock()
+
+		children := st.visit(ctx, t)
+
+		st.mu.Lock()
+		st.queue = append(st.queue, children...)
+		st.pending += len(children) - 1
+		st.cond.Broadcast()
+		st.mu.Unlock()
+	}
+}
+
+// visit calls fn for one entry and, for a directory, returns its
+// contents to be visited next.
+func (st *walkState) visit(ctx context.Context, t task) []task {
+	err := st.fn(ctx, t.path, t.d, nil)
+	if t.d.Type().IsRegular() {
+		st.w.Progress.AddFiles(1)
+	}
+	if err != nil || !t.d.IsDir() {
+		st.result(err)
+		return nil
+	}
+	entries, err := os.ReadDir(t.path)
+	if err != nil {
+		// As with filepath.WalkDir, fn sees the directory again with
+		// the error.
+		st.result(st.fn(ctx, t.path, t.d, err))
+	}
+	children := make([]task, len(entries))
+	for i, e := range entries {
+		children[i] = task{filepath.Join(t.path, e.Name()), e}
+	}
+	return children
+}
+
+// result handles what fn returned.
+func (st *walkState) result(err error) {
+	switch {
+	case err == nil, errors.Is(err, fs.SkipDir):
+	case errors.Is(err, fs.SkipAll):
+		st.stop(nil)
+	default:
+		st.stop(err)
+	}
+}
+
+// stop records err, if any, and ends the walk unless all errors are
+// being collected.
+func (st *walkState) stop(err error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if err != nil {
+		st.errs = append(st.errs, err)
+		if st.w.AllErrors {
+			return
+		}
+	}
+	st.stopped = true
+	st.cond.Broadcast()
+}
@@ -0,0 +1,232 @@
+package fsutil
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"example.com/tutorial/progress"
+)
+
+// tree creates the files under a temporary directory and returns it.
+func tree(t *testing.T, files ...string) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, f := range files {
+		p := filepath.Join(root, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(f), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// collector records the paths visited, relative to root.
+type collector struct {
+	root  string
+	mu    sync.Mutex
+	paths []string
+}
+
+func (c *collector) add(path string) {
+	rel, _ := filepath.Rel(c.root, path)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paths = append(c.paths, filepath.ToSlash(rel))
+}
+
+func (c *collector) sorted() string {
+	sort.Strings(c.paths)
+	return strings.Join(c.paths, " ")
+}
+
+var files = []string{"a.txt", "b/c.txt", "b/d/e.txt", "b/d/f.txt", "g/h.txt"}
+
+func TestWalkVisitsAll(t *testing.T) {
+	root := tree(t, files...)
+	c := &collector{root: root}
+	err := WalkConcurrent(context.Background(), root, func(_ context.Context, path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		c.add(path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ". a.txt b b/c.txt b/d b/d/e.txt b/d/f.txt g g/h.txt"
+	if got := c.sorted(); got != want {
+		t.Fatalf("visited %q, want %q", got, want)
+	}
+}
+
+func TestWalkSkipDir(t *testing.T) {
+	root := tree(t, files...)
+	c := &collector{root: root}
+	err := WalkConcurrent(context.Background(), root, func(_ context.Context, path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		c.add(path)
+		if d.IsDir() && d.Name() == "d" {
+			return fs.SkipDir
+		}
+		if d.Name() == "a.txt" {
+			return fs.SkipDir // ignored for files
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ". a.txt b b/c.txt b/d g g/h.txt"
+	if got := c.sorted(); got != want {
+		t.Fatalf("visited %q, want %q", got, want)
+	}
+}
+
+func TestWalkSkipAll(t *testing.T) {
+	root := tree(t, files...)
+	var n atomic.Int32
+	err := (&Walker{Workers: 1}).Walk(context.Background(), root, func(_ context.Context, path string, d fs.DirEntry, err error) error {
+		n.Add(1)
+		if path != root {
+			return fs.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Load() != 2 {
+		t.Fatalf("%d calls after SkipAll with one worker, want 2", n.Load())
+	}
+}
+
+func TestWalkFirstError(t *testing.T) {
+	root := tree(t, files...)
+	boom := errors.New("boom")
+	var after atomic.Int32
+	var failed atomic.Bool
+	err := (&Walker{Workers: 1}).Walk(context.Background(), root, func(_ context.Context, path string, d fs.DirEntry, err error) error {
+		if failed.Load() {
+			after.Add(1)
+		}
+		if !d.IsDir() {
+			failed.Store(true)
+			return boom
+		}
+		return nil
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want boom", err)
+	}
+	if after.Load() != 0 {
+		t.Fatalf("%d calls after the error with one worker", after.Load())
+	}
+}
+
+func TestWalkAllErrors(t *testing.T) {
+	root := tree(t, files...)
+	err := (&Walker{Workers: 3, AllErrors: true}).Walk(context.Background(), root, func(_ context.Context, path string, d fs.DirEntry, err error) error {
+		if !d.IsDir() {
+			return errors.New(d.Name())
+		}
+		return nil
+	})
+	if err == nil {
+		t.Fatal("no error")
+	}
+	var got []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		got = append(got, e.Error())
+	}
+	sort.Strings(got)
+	if s := strings.Join(got, " "); s != "a.txt c.txt e.txt f.txt h.txt" {
+		t.Fatalf("errors %q", s)
+	}
+}
+
+func TestWalkMissingRoot(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "missing")
+	var called bool
+	err := WalkConcurrent(context.Background(), root, func(_ context.Context, path string, d fs.DirEntry, err error) error {
+		called = true
+		if path != root || d != nil || err == nil {
+			t.Errorf("fn(%q, %v, %v)", path, d, err)
+		}
+		return err
+	})
+	if !called || !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("called %v, err = %v", called, err)
+	}
+}
+
+func TestWalkCancel(t *testing.T) {
+	root := tree(t, files...)
+	ctx, cancel := context.WithCancel(context.Background())
+	var n atomic.Int32
+	err := (&Walker{Workers: 1}).Walk(ctx, root, func(_ context.Context, path string, d fs.DirEntry, err error) error {
+		if n.Add(1) == 2 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if n.Load() != 2 {
+		t.Fatalf("%d calls, want 2", n.Load())
+	}
+}
+
+func TestWalkBoundsWorkers(t *testing.T) {
+	var names []string
+	for i := 0; i < 20; i++ {
+		names = append(names, filepath.Join(string(rune('a'+i%4)), string(rune('a'+i))+".txt"))
+	}
+	root := tree(t, names...)
+	var cur, peak atomic.Int32
+	err := (&Walker{Workers: 3}).Walk(context.Background(), root, func(_ context.Context, path string, d fs.DirEntry, err error) error {
+		n := cur.Add(1)
+		defer cur.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := peak.Load(); p > 3 || p < 2 {
+		t.Fatalf("peak concurrency %d, want 2 or 3", p)
+	}
+}
+
+func TestWalkProgress(t *testing.T) {
+	root := tree(t, files...)
+	tr := progress.NewTracker()
+	w := Walker{Workers: 3, Progress: tr}
+	if err := w.Walk(context.Background(), root, func(context.Context, string, fs.DirEntry, error) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if got := tr.Stats().Files; got != int64(len(files)) {
+		t.Fatalf("counted %d files, want %d", got, len(files))
+	}
+}
@@ -0,0 +1,115 @@
+package golden
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around a change.
+const diffContext = 2
+
+// maxDiffCells bounds the table Diff builds, the product of the line
+// counts; larger inputs show their first differing line only.
+const maxDiffCells = 1 << 22
+
+// Diff returns the lines that differ between want and got, prefixed "-"
+// for want's and "+" for got's, with unchanged lines around them
+// prefixed " " and gaps marked "...".
+func Diff(want, got string) string {
+	a, b := splitLines(want), splitLines(got)
+	if len(a)*len(b) > maxDiffCells {
+		return firstDiff(a, b)
+	}
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	type line struct {
+		op   byte
+		text string
+	}
+	var lines []line
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, line{' ', a[i]})
+			i, j = i+1, j+1
+		case j < len(b) && (i == len(a) || lcs[i][j+1] > lcs[i+1][j]):
+			lines = append(lines, line{'+', b[j]})
+			j++
+		default:
+			lines = append(lines, line{'-', a[i]})
+			i++
+		}
+	}
+
+	var sb strings.Builder
+	last := -1 // the last line written
+	for k, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		from := max(k-diffContext, last+1)
+		if last >= 0 && from > last+1 || last < 0 && from > 0 {
+			sb.WriteString("...\n")
+		}
+		for ; from <= k; from++ {
+			writeLine(&sb, lines[from].op, lines[from].text)
+		}
+		last = k
+		// The unchanged lines after the change, up to the next one.
+		for n := 1; n <= diffContext && k+n < len(lines) && lines[k+n].op == ' '; n++ {
+			writeLine(&sb, ' ', lines[k+n].text)
+			last = k + n
+		}
+	}
+	if last >= 0 && last < len(lines)-1 {
+		sb.WriteString("...\n")
+	}
+	return sb.String()
+}
+
+// splitLines splits s after each newline.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func writeLine(sb *strings.Builder, op byte, text string) {
+	sb.WriteByte(op)
+	sb.WriteString(text)
+	if !strings.HasSuffix(text, "\n") {
+		sb.WriteString("\n\\ no newline at end\n")
+	}
+}
+
+// firstDiff describes the first line where a and b differ.
+func firstDiff(a, b []string) string {
+	for i := 0; ; i++ {
+		var x, y string
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			return fmt.Sprintf("line %d:\n-%q\n+%q\n", i+1, x, y)
+		}
+	}
+}
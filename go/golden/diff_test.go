@@ -0,0 +1,35 @@
+package golden
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	for _, tt := range []struct {
+		name, want, got, diff string
+	}{
+		{"changed line", "a\nb\nc\n", "a\nB\nc\n", " a\n-b\n+B\n c\n"},
+		{"added line", "a\nb\n", "a\nb\nc\n", " a\n b\n+c\n"},
+		{"removed line", "a\nb\nc\n", "a\nc\n", " a\n-b\n c\n"},
+		{"no final newline", "a\n", "a", "-a\n+a\n\\ no newline at end\n"},
+		{
+			"gaps",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			"1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			"...\n 3\n 4\n-5\n+five\n 6\n 7\n...\n",
+		},
+	} {
+		if got := Diff(tt.want, tt.got); got != tt.diff {
+			t.Errorf("%s: Diff =\n%s\nwant\n%s", tt.name, got, tt.diff)
+		}
+	}
+}
+
+func TestDiffLarge(t *testing.T) {
+	a := strings.Repeat("same\n", 3000)
+	got := Diff(a+"old\n", a+"new\n")
+	if got != "line 3001:\n-\"old\\n\"\n+\"new\\n\"\n" {
+		t.Fatalf("Diff = %q", got)
+	}
+}
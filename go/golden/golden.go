@@ -0,0 +1,124 @@
+// Package golden compares the output of tests with golden files under
+// testdata. Normalizers first replace what differs from run to run, such
+// as times and temporary paths, with placeholders. Running the tests
+// with -update writes the golden files from the current output instead,
+// to be reviewed with git diff:
+//
+//	go test -run TestGolden -update .
+package golden
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "write the golden files under testdata from the current output")
+
+// Dir holds the golden files, relative to the directory of the package
+// under test.
+const Dir = "testdata"
+
+// root is the directory the test started in, that of its package; tests
+// may change directories before their outputs are compared.
+var root, _ = os.Getwd()
+
+// Ext ends the names of golden files.
+const Ext = ".golden"
+
+// A Normalizer rewrites output before it is compared or written.
+type Normalizer func(string) string
+
+// Replace replaces old with placeholder.
+func Replace(old, placeholder string) Normalizer {
+	return func(s string) string { return strings.ReplaceAll(s, old, placeholder) }
+}
+
+// Regexp replaces the matches of the regular expression expr with repl,
+// which may refer to submatches as regexp.ReplaceAllString does.
+func Regexp(expr, repl string) Normalizer {
+	re := regexp.MustCompile(expr)
+	return func(s string) string { return re.ReplaceAllString(s, repl) }
+}
+
+// Path replaces dir, as given, with symbolic links resolved and with
+// slashes, with placeholder. Paths below dir keep their tail, so that a
+// temporary directory "/tmp/TestX123/001" given as "$TMP" turns
+// "/tmp/TestX123/001/a.txt" into "$TMP/a.txt". Separators after the
+// placeholder are turned into slashes, so that golden files are the
+// same on every platform.
+func Path(dir, placeholder string) Normalizer {
+	forms := []string{dir, filepath.ToSlash(dir)}
+	if real, err := filepath.EvalSymlinks(dir); err == nil {
+		forms = append(forms, real, filepath.ToSlash(real))
+	}
+	// The longest first, so that a form's prefix cannot leave its tail.
+	sort.Slice(forms, func(i, j int) bool { return len(forms[i]) > len(forms[j]) })
+	var quoted []string
+	for _, f := range forms {
+		quoted = append(quoted, regexp.QuoteMeta(f))
+	}
+	re := regexp.MustCompile(`(?:` + strings.Join(quoted, "|") + `)((?:[\\/][^\s\\/"',:]+)*)`)
+	return func(s string) string {
+		return re.ReplaceAllStringFunc(s, func(m string) string {
+			tail := re.FindStringSubmatch(m)[1]
+			return placeholder + strings.ReplaceAll(tail, `\`, "/")
+		})
+	}
+}
+
+// Timestamps replaces times in RFC 3339 and similar layouts, such as
+// "2024-05-01T12:30:00Z" and "2024-05-01 12:30:00.123 +0200", with
+// <TIME>, and dates on their own with <DATE>.
+var Timestamps Normalizer = func(s string) string {
+	s = timestampRE.ReplaceAllString(s, "<TIME>")
+	return dateRE.ReplaceAllString(s, "<DATE>")
+}
+
+var (
+	timestampRE = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}(?::\d{2}(?:\.\d+)?)?(?:Z|\s?[+-]\d{2}:?\d{2}(?:\s[A-Z]{3,4})?|\s[A-Z]{3,4})?`)
+	dateRE      = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}\b`)
+)
+
+// Durations replaces durations as time.Duration prints them, such as
+// "1.5ms", "2m3s" and "850µs", with <DURATION>.
+var Durations Normalizer = Regexp(`\b(?:\d+(?:\.\d+)?(?:h|m|s|ms|µs|us|ns))+\b`, "<DURATION>")
+
+// Assert compares got, normalized by norms in order, with the golden
+// file testdata/name.golden, where name may have slash-separated
+// directories. With -update it writes the file instead. Line endings are
+// compared as "\n".
+func Assert(t testing.TB, name, got string, norms ...Normalizer) {
+	t.Helper()
+	got = strings.ReplaceAll(got, "\r\n", "\n")
+	for _, n := range norms {
+		got = n(got)
+	}
+	rel := filepath.Join(Dir, filepath.FromSlash(name)+Ext)
+	path := filepath.Join(root, rel)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("golden: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("golden: %v", err)
+		}
+		return
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("golden: %s does not exist; run the test with -update to write it", rel)
+	}
+	if err != nil {
+		t.Fatalf("golden: %v", err)
+	}
+	if want := strings.ReplaceAll(string(data), "\r\n", "\n"); got != want {
+		t.Errorf("golden: output differs from %s (-want +got; run with -update to accept it):\n%s", rel, Diff(want, got))
+	}
+}
@@ -0,0 +1,91 @@
+package golden
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNormalizers(t *testing.T) {
+	dir := t.TempDir()
+	for _, tt := range []struct {
+		norm    Normalizer
+		in, out string
+	}{
+		{Timestamps, "at 2024-05-01T12:30:00Z.", "at <TIME>."},
+		{Timestamps, "at 2024-05-01T12:30:00.123456+02:00", "at <TIME>"},
+		{Timestamps, "_Generated 2024-05-01 12:30 UTC._", "_Generated <TIME>._"},
+		{Timestamps, "2024-05-01 12:30:00 +0200 CEST", "<TIME>"},
+		{Timestamps, "on 2024-05-01", "on <DATE>"},
+		{Durations, "took 1.5ms, then 2m3s and 850µs", "took <DURATION>, then <DURATION> and <DURATION>"},
+		{Durations, "5 files", "5 files"},
+		{Path(dir, "$TMP"), dir + "/a/b.txt:3: x", "$TMP/a/b.txt:3: x"},
+		{Path(dir, "$TMP"), `"` + filepath.Join(dir, "c.txt") + `"`, `"$TMP/c.txt"`},
+		{Path(dir, "$TMP"), "elsewhere/a.txt", "elsewhere/a.txt"},
+		{Replace("v1.2.3", "<VERSION>"), "tutorial v1.2.3", "tutorial <VERSION>"},
+		{Regexp(`id=(\w+)`, "id=<$1>"), "id=abc", "id=<abc>"},
+	} {
+		if got := tt.norm(tt.in); got != tt.out {
+			t.Errorf("%q normalized to %q, want %q", tt.in, got, tt.out)
+		}
+	}
+}
+
+// recorder records the failures of Assert; Fatalf stops Assert with a
+// panic that run recovers.
+type recorder struct {
+	testing.TB
+	errs []string
+}
+
+type fatal struct{}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+	panic(fatal{})
+}
+
+func (r *recorder) run(fn func()) {
+	defer func() {
+		if p := recover(); p != nil && p != (fatal{}) {
+			panic(p)
+		}
+	}()
+	fn()
+}
+
+func TestAssert(t *testing.T) {
+	got := "report of 2024-05-01 12:30 UTC\r\nfiles in /data/a.txt\r\n"
+	Assert(t, "normalized", got, Timestamps, Path("/data", "$TMP"))
+
+	r := &recorder{TB: t}
+	r.run(func() { Assert(r, "normalized", "report of yesterday\nfiles in $TMP/a.txt\n") })
+	if len(r.errs) != 1 || !strings.Contains(r.errs[0], "-report of <TIME>\n+report of yesterday\n") {
+		t.Errorf("mismatch reported as %q", r.errs)
+	}
+
+	r = &recorder{TB: t}
+	r.run(func() { Assert(r, "missing/file", "x") })
+	if len(r.errs) != 1 || !strings.Contains(r.errs[0], filepath.Join("testdata", "missing", "file.golden")+" does not exist") {
+		t.Errorf("missing file reported as %q", r.errs)
+	}
+}
+
+func TestAssertUpdate(t *testing.T) {
+	saved := root
+	t.Cleanup(func() { *update, root = false, saved })
+	*update, root = true, t.TempDir()
+	Assert(t, "sub/out", "ran at 2024-05-01T00:00:00Z\n", Timestamps)
+	data, err := os.ReadFile(filepath.Join(root, "testdata", "sub", "out.golden"))
+	if err != nil || string(data) != "ran at <TIME>\n" {
+		t.Fatalf("wrote %q, %v", data, err)
+	}
+}
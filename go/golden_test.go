@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"example.com/tutorial/golden"
+)
+
+// cli runs the command line args with stdin and returns its output;
+// the test fails unless it exits 0.
+func cli(t *testing.T, stdin string, args ...string) string {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	if code := run(context.Background(), args, strings.NewReader(stdin), &out, &errOut); code != 0 {
+		t.Fatalf("%v: exit %d: %s", args, code, errOut.String())
+	}
+	return out.String()
+}
+
+// TestGolden compares the output of the commands with the files in
+// testdata; go test -run TestGolden -update . rewrites them.
+func TestGolden(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"README.md":       "# Gophers\n\nGophers love Go. Go loves gophers!\n",
+		"docs/guide.txt":  "The guide: go build, go test, go vet.\nThen go run.\n",
+		"docs/notes.txt":  "notes\n",
+		"data/words.csv":  "word,count\ngo,3\n",
+		"data/empty.json": "",
+	})
+	inDir := func(name string) string { return filepath.Join(dir, filepath.FromSlash(name)) }
+	tmp := golden.Path(dir, "$DIR")
+
+	for _, tt := range []struct {
+		name  string
+		args  []string
+		norms []golden.Normalizer
+	}{
+		{"wordcount/counts", []string{"wordcount", inDir("README.md"), inDir("docs/guide.txt")}, nil},
+		{"wordcount/top", []string{"wordcount", "-top", "5", inDir("README.md"), inDir("docs/guide.txt")}, nil},
+		{"wordcount/top-json", []string{"wordcount", "-top", "3", "-format", "json", inDir("docs/guide.txt")}, nil},
+		{"wordcount/top-csv", []string{"wordcount", "-top", "3", "-format", "csv", inDir("docs/guide.txt")}, nil},
+		{"tree/sizes", []string{"tree", "-s", dir}, nil},
+		{"tree/json", []string{"tree", "-json", dir}, nil},
+		{"du/sorted", []string{"du", "-sort", dir}, nil},
+		{"search/context", []string{"search", "-i", "-C", "1", "gophers?", dir}, nil},
+		{"report/markdown", []string{"report", "-top", "3", inDir("README.md"), inDir("docs/guide.txt")}, []golden.Normalizer{golden.Timestamps}},
+		{"report/html", []string{"report", "-format", "html", "-top", "2", inDir("docs/guide.txt")}, []golden.Normalizer{golden.Timestamps}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			golden.Assert(t, tt.name, cli(t, "", tt.args...), append([]golden.Normalizer{tmp}, tt.norms...)...)
+		})
+	}
+
+	t.Run("wordcount/stdin", func(t *testing.T) {
+		golden.Assert(t, "wordcount/stdin", cli(t, "one two\nthree\n", "wordcount"))
+	})
+	t.Run("version/json", func(t *testing.T) {
+		golden.Assert(t, "version/json", cli(t, "", "version", "-json"),
+			// Commit, date and modified are there only in stamped
+			// builds.
+			golden.Regexp(`\n\s*"(commit|date|modified)": [^\n]*,`, ""),
+			golden.Regexp(`"(version|go_version|platform)": "[^"]*"`, `"$1": "<$1>"`))
+	})
+	t.Run("demo", func(t *testing.T) {
+		golden.Assert(t, "demo", cli(t, "", "demo"),
+			golden.Regexp(`(?m)^(timer fired|timeout)$`, "<timer>"))
+	})
+}
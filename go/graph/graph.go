@@ -0,0 +1,258 @@
+// Package graph provides Graph, a generic directed graph, with
+// breadth-first and depth-first traversal, cycle detection and
+// topological sorting. Nodes and each node's edges are kept in the order
+// they were added, and every traversal follows that order, so the same
+// graph is always walked the same way.
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"example.com/tutorial/pqueue"
+)
+
+// Graph is a directed graph over nodes of type N, without parallel
+// edges. Create one with New. A Graph is not safe for concurrent
+// mutation.
+type Graph[N comparable] struct {
+	ids   map[N]int // node -> index in nodes
+	nodes []N
+	out   [][]int // successors of each node, in the order added
+	in    []int   // number of predecessors of each node
+	edges map[[2]int]struct{}
+}
+
+// New returns an empty graph.
+func New[N comparable]() *Graph[N] {
+	return &Graph[N]{ids: map[N]int{}, edges: map[[2]int]struct{}{}}
+}
+
+// Len returns the number of nodes in g.
+func (g *Graph[N]) Len() int { return len(g.nodes) }
+
+// Edges returns the number of edges in g.
+func (g *Graph[N]) Edges() int { return len(g.edges) }
+
+// AddNode adds n, reporting false if g already had it.
+func (g *Graph[N]) AddNode(n N) bool {
+	if _, ok := g.ids[n]; ok {
+		return false
+	}
+	g.id(n)
+	return true
+}
+
+// id returns the index of n, adding it if need be.
+func (g *Graph[N]) id(n N) int {
+	if id, ok := g.ids[n]; ok {
+		return id
+	}
+	id := len(g.nodes)
+	g.ids[n] = id
+	g.nodes = append(g.nodes, n)
+	g.out = append(g.out, nil)
+	g.in = append(g.in, 0)
+	return id
+}
+
+// AddEdge adds an edge from one node to another, adding the nodes g
+// lacks, and reports false if g already had the edge. An edge from a
+// node to itself is a cycle.
+func (g *Graph[N]) AddEdge(from, to N) bool {
+	f, t := g.id(from), g.id(to)
+	if _, ok := g.edges[[2]int{f, t}]; ok {
+		return false
+	}
+	g.edges[[2]int{f, t}] = struct{}{}
+	g.out[f] = append(g.out[f], t)
+	g.in[t]++
+	return true
+}
+
+// Has reports whether n is a node of g.
+func (g *Graph[N]) Has(n N) bool {
+	_, ok := g.ids[n]
+	return ok
+}
+
+// HasEdge reports whether g has an edge from one node to the other.
+func (g *Graph[N]) HasEdge(from, to N) bool {
+	f, okf := g.ids[from]
+	t, okt := g.ids[to]
+	if !okf || !okt {
+		return false
+	}
+	_, ok := g.edges[[2]int{f, t}]
+	return ok
+}
+
+// Nodes returns the nodes of g in the order they were added. The result
+// is never nil.
+func (g *Graph[N]) Nodes() []N {
+	return append([]N{}, g.nodes...)
+}
+
+// Successors returns the nodes n has edges to, in the order the edges
+// were added, or nil if n is not in g.
+func (g *Graph[N]) Successors(n N) []N {
+	id, ok := g.ids[n]
+	if !ok {
+		return nil
+	}
+	return g.names(g.out[id])
+}
+
+func (g *Graph[N]) names(ids []int) []N {
+	out := make([]N, len(ids))
+	for i, id := range ids {
+		out[i] = g.nodes[id]
+	}
+	return out
+}
+
+// BFS visits the nodes reachable from start, start first, in
+// breadth-first order, until visit returns false. It does nothing if
+// start is not in g.
+func (g *Graph[N]) BFS(start N, visit func(n N, depth int) bool) {
+	id, ok := g.ids[start]
+	if !ok {
+		return
+	}
+	seen := make([]bool, len(g.nodes))
+	seen[id] = true
+	type item struct{ id, depth int }
+	queue := []item{{id, 0}}
+	for len(queue) > 0 {
+		it := queue[0]
+		queue = queue[1:]
+		if !visit(g.nodes[it.id], it.depth) {
+			return
+		}
+		for _, next := range g.out[it.id] {
+			if !seen[next] {
+				seen[next] = true
+				queue = append(queue, item{next, it.depth + 1})
+			}
+		}
+	}
+}
+
+// DFS visits the nodes reachable from start in depth-first preorder,
+// following each node's edges in order, until visit returns false. It
+// does nothing if start is not in g.
+func (g *Graph[N]) DFS(start N, visit func(n N) bool) {
+	id, ok := g.ids[start]
+	if !ok {
+		return
+	}
+	seen := make([]bool, len(g.nodes))
+	// An explicit stack, so deep graphs cannot overflow the goroutine's;
+	// successors are pushed in reverse to be visited in order.
+	stack := []int{id}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if !visit(g.nodes[id]) {
+			return
+		}
+		for i := len(g.out[id]) - 1; i >= 0; i-- {
+			if next := g.out[id][i]; !seen[next] {
+				stack = append(stack, next)
+			}
+		}
+	}
+}
+
+// CycleError is the error of TopologicalSort for a graph with a cycle.
+type CycleError[N comparable] struct {
+	Cycle []N // the nodes around the cycle, the first repeated at the end
+}
+
+func (e *CycleError[N]) Error() string {
+	parts := make([]string, len(e.Cycle))
+	for i, n := range e.Cycle {
+		parts[i] = fmt.Sprint(n)
+	}
+	return "graph: cycle " + strings.Join(parts, " -> ")
+}
+
+// FindCycle returns the nodes around a cycle of g, the first repeated at
+// the end, such as [a b a], or nil if g has none. Of several cycles it
+// returns the first a depth-first search in node order finds.
+func (g *Graph[N]) FindCycle() []N {
+	const (
+		unseen = iota
+		onPath
+		done
+	)
+	state := make([]int, len(g.nodes))
+	type frame struct{ id, next int } // next: the index of the edge to follow
+	for root := range g.nodes {
+		if state[root] != unseen {
+			continue
+		}
+		path := []frame{{root, 0}}
+		state[root] = onPath
+		for len(path) > 0 {
+			top := &path[len(path)-1]
+			if top.next == len(g.out[top.id]) {
+				state[top.id] = done
+				path = path[:len(path)-1]
+				continue
+			}
+			next := g.out[top.id][top.next]
+			top.next++
+			switch state[next] {
+			case unseen:
+				state[next] = onPath
+				path = append(path, frame{next, 0})
+			case onPath:
+				var cycle []int
+				for i := len(path) - 1; i >= 0; i-- {
+					if path[i].id == next {
+						for _, f := range path[i:] {
+							cycle = append(cycle, f.id)
+						}
+						break
+					}
+				}
+				return g.names(append(cycle, next))
+			}
+		}
+	}
+	return nil
+}
+
+// TopologicalSort returns the nodes of g ordered so that every edge
+// goes from an earlier node to a later one. Of the nodes that could come
+// next, the one added first does, so the order is always the same. If g
+// has a cycle there is no such order, and the error is a *CycleError.
+func (g *Graph[N]) TopologicalSort() ([]N, error) {
+	in := append([]int(nil), g.in...)
+	// The nodes without predecessors left, first added first.
+	ready := pqueue.New(func(a, b int) bool { return a < b })
+	for id, n := range in {
+		if n == 0 {
+			ready.Push(id)
+		}
+	}
+	order := make([]N, 0, len(g.nodes))
+	for ready.Len() > 0 {
+		id, _ := ready.Pop()
+		order = append(order, g.nodes[id])
+		for _, next := range g.out[id] {
+			if in[next]--; in[next] == 0 {
+				ready.Push(next)
+			}
+		}
+	}
+	if len(order) < len(g.nodes) {
+		return nil, &CycleError[N]{Cycle: g.FindCycle()}
+	}
+	return order, nil
+}
@@ -0,0 +1,154 @@
+package graph
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// build returns a graph of the given edges, written "a>b".
+func build(edges ...string) *Graph[string] {
+	g := New[string]()
+	for _, e := range edges {
+		for i := 0; i < len(e); i++ {
+			if e[i] == '>' {
+				g.AddEdge(e[:i], e[i+1:])
+			}
+		}
+	}
+	return g
+}
+
+func TestAdd(t *testing.T) {
+	g := New[string]()
+	if !g.AddNode("x") || g.AddNode("x") {
+		t.Fatal("AddNode")
+	}
+	if !g.AddEdge("a", "b") || g.AddEdge("a", "b") || !g.AddEdge("b", "a") || !g.AddEdge("a", "c") {
+		t.Fatal("AddEdge")
+	}
+	if g.Len() != 4 || g.Edges() != 3 {
+		t.Fatalf("Len %d, Edges %d", g.Len(), g.Edges())
+	}
+	if got := g.Nodes(); !reflect.DeepEqual(got, []string{"x", "a", "b", "c"}) {
+		t.Fatalf("Nodes = %q", got)
+	}
+	if got := g.Successors("a"); !reflect.DeepEqual(got, []string{"b", "c"}) {
+		t.Fatalf("Successors(a) = %q", got)
+	}
+	if g.Successors("missing") != nil || len(g.Successors("x")) != 0 {
+		t.Fatal("Successors of a missing or isolated node")
+	}
+	if !g.HasEdge("b", "a") || g.HasEdge("c", "a") || g.HasEdge("zz", "a") || !g.Has("c") || g.Has("zz") {
+		t.Fatal("HasEdge or Has")
+	}
+}
+
+func TestBFS(t *testing.T) {
+	g := build("a>b", "a>c", "b>d", "c>d", "d>a", "c>e", "x>a")
+	var got []string
+	g.BFS("a", func(n string, depth int) bool {
+		got = append(got, n+strconv.Itoa(depth))
+		return true
+	})
+	if !reflect.DeepEqual(got, []string{"a0", "b1", "c1", "d2", "e2"}) {
+		t.Fatalf("BFS = %q", got)
+	}
+	got = nil
+	g.BFS("a", func(n string, _ int) bool {
+		got = append(got, n)
+		return n != "b"
+	})
+	if !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("BFS stopped after %q", got)
+	}
+	g.BFS("missing", func(string, int) bool { t.Fatal("visited"); return true })
+}
+
+func TestDFS(t *testing.T) {
+	g := build("a>b", "a>c", "b>d", "d>a", "c>e", "c>d")
+	var got []string
+	g.DFS("a", func(n string) bool {
+		got = append(got, n)
+		return true
+	})
+	if !reflect.DeepEqual(got, []string{"a", "b", "d", "c", "e"}) {
+		t.Fatalf("DFS = %q", got)
+	}
+	got = nil
+	g.DFS("c", func(n string) bool {
+		got = append(got, n)
+		return len(got) < 3
+	})
+	if !reflect.DeepEqual(got, []string{"c", "e", "d"}) {
+		t.Fatalf("DFS from c = %q", got)
+	}
+
+	// A long chain does not overflow anything.
+	chain := New[int]()
+	for i := 0; i < 100000; i++ {
+		chain.AddEdge(i, i+1)
+	}
+	n := 0
+	chain.DFS(0, func(int) bool { n++; return true })
+	if n != 100001 {
+		t.Fatalf("DFS of a chain visited %d nodes", n)
+	}
+}
+
+func TestFindCycle(t *testing.T) {
+	for _, tc := range []struct {
+		edges []string
+		want  []string
+	}{
+		{[]string{"a>b", "b>c"}, nil},
+		{[]string{"a>b", "b>c", "c>a"}, []string{"a", "b", "c", "a"}},
+		{[]string{"x>y", "a>b", "b>c", "c>b"}, []string{"b", "c", "b"}},
+		{[]string{"a>a"}, []string{"a", "a"}},
+		{[]string{"a>b", "a>c", "c>b"}, nil}, // a diamond is no cycle
+	} {
+		if got := build(tc.edges...).FindCycle(); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("FindCycle(%q) = %q, want %q", tc.edges, got, tc.want)
+		}
+	}
+}
+
+func TestTopologicalSort(t *testing.T) {
+	g := build("shirt>tie", "tie>jacket", "trousers>shoes", "trousers>belt", "belt>jacket", "shirt>belt", "socks>shoes")
+	g.AddNode("watch")
+	got, err := g.TopologicalSort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Of the nodes ready, the first added goes first.
+	want := []string{"shirt", "tie", "trousers", "belt", "jacket", "socks", "shoes", "watch"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("TopologicalSort = %q", got)
+	}
+	pos := map[string]int{}
+	for i, n := range got {
+		pos[n] = i
+	}
+	for _, n := range g.Nodes() {
+		for _, s := range g.Successors(n) {
+			if pos[n] >= pos[s] {
+				t.Fatalf("%s comes after %s", n, s)
+			}
+		}
+	}
+
+	g.AddEdge("jacket", "shirt")
+	_, err = g.TopologicalSort()
+	var ce *CycleError[string]
+	if !errors.As(err, &ce) || !reflect.DeepEqual(ce.Cycle, []string{"shirt", "tie", "jacket", "shirt"}) {
+		t.Fatalf("err = %v", err)
+	}
+	if err.Error() != "graph: cycle shirt -> tie -> jacket -> shirt" {
+		t.Fatalf("Error() = %q", err)
+	}
+
+	if got, err := New[int]().TopologicalSort(); err != nil || got == nil || len(got) != 0 {
+		t.Fatalf("empty graph: %v, %v", got, err)
+	}
+}
@@ -0,0 +1,186 @@
+// Package grep searches the files of a directory tree for lines matching
+// a regular expression, reading several files at once.
+package grep
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+
+	"example.com/tutorial/fsutil"
+	"example.com/tutorial/progress"
+	"example.com/tutorial/text"
+)
+
+// MaxLineBytes bounds the lines Search reads; a file with a longer line
+// is reported as an error.
+const MaxLineBytes = 1 << 20
+
+// Options tunes Search.
+type Options struct {
+	// Include, if not empty, searches only the files matching one of
+	// its patterns. Exclude skips the files and directories matching
+	// one of its patterns. Patterns are matched as by fsutil.MatchAny.
+	Include, Exclude []string
+	// Context is the number of lines reported before and after each
+	// matching line.
+	Context int
+	// Words matches the expression against each word of a line, as
+	// split by text.Tokenizer without lower-casing, instead of against
+	// the line: a line matches if the expression matches one of its
+	// words in full.
+	Words bool
+	// Workers bounds the files read at once; zero means
+	// runtime.GOMAXPROCS(0).
+	Workers int
+	// Progress, if not nil, counts the files visited and the bytes read.
+	Progress *progress.Tracker
+}
+
+// Line is a line of a file, numbered from 1. Match is false for the
+// context lines around the matches.
+type Line struct {
+	Num   int    `json:"num"`
+	Text  string `json:"text"`
+	Match bool   `json:"match"`
+}
+
+// File is a file with at least one match.
+type File struct {
+	Path    string `json:"path"`
+	Matches int    `json:"matches"` // number of matching lines
+	Lines   []Line `json:"lines"`   // matches and context, in order
+}
+
+// Search reports the files below root with lines matching re, in path
+// order; root may also be a single file, searched whatever the patterns
+// say. Binary files, detected by a NUL byte among their first 512
+// bytes, are skipped, and symbolic links are not followed. Files and
+// directories that cannot be read are reported in the returned error,
+// joined with errors.Join, but do not stop the search unless ctx ends.
+func Search(ctx context.Context, root string, re *regexp.Regexp, opts Options) ([]File, error) {
+	if err := fsutil.CheckPatterns(opts.Include, opts.Exclude); err != nil {
+		return nil, err
+	}
+	if opts.Words {
+		re = regexp.MustCompile(`^(?:` + re.String() + `)$`)
+	}
+	var (
+		mu    sync.Mutex
+		files []File
+	)
+	w := fsutil.Walker{Workers: opts.Workers, AllErrors: true, Progress: opts.Progress}
+	err := w.Walk(ctx, root, func(ctx context.Context, path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel != "." && fsutil.MatchAny(opts.Exclude, rel) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || rel != "." && len(opts.Include) > 0 && !fsutil.MatchAny(opts.Include, rel) {
+			return nil
+		}
+		f, err := searchFile(path, re, opts)
+		if err != nil || f == nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		files = append(files, *f)
+		return nil
+	})
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, err
+}
+
+// searchFile returns the matches in the file at path, or nil if there
+// are none or it is binary.
+func searchFile(path string, re *regexp.Regexp, opts Options) (*File, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	br := bufio.NewReader(opts.Progress.Reader(fh))
+	if head, _ := br.Peek(512); bytes.IndexByte(head, 0) >= 0 {
+		return nil, nil
+	}
+
+	f := &File{Path: path}
+	var before []Line // context lines not yet reported
+	after := 0        // context lines still to report
+	sc := bufio.NewScanner(br)
+	sc.Buffer(nil, MaxLineBytes)
+	for n := 1; sc.Scan(); n++ {
+		l := Line{Num: n, Text: sc.Text()}
+		switch {
+		case matches(re, l.Text, opts.Words):
+			l.Match = true
+			f.Matches++
+			f.Lines = append(append(f.Lines, before...), l)
+			before = before[:0]
+			after = opts.Context
+		case after > 0:
+			f.Lines = append(f.Lines, l)
+			after--
+		case opts.Context > 0:
+			if len(before) == opts.Context {
+				before = append(before[:0], before[1:]...)
+			}
+			before = append(before, l)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if f.Matches == 0 {
+		return nil, nil
+	}
+	return f, nil
+}
+
+func matches(re *regexp.Regexp, line string, words bool) bool {
+	if !words {
+		return re.MatchString(line)
+	}
+	for _, w := range (text.Tokenizer{KeepCase: true}).Tokens(line) {
+		if re.MatchString(w) {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteText writes the lines of f in the style of grep: "path:num:text"
+// for matches, "path-num-text" for context lines, and "--" between runs
+// of lines that are not adjacent.
+func (f *File) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for i, l := range f.Lines {
+		if i > 0 && l.Num != f.Lines[i-1].Num+1 {
+			bw.WriteString("--\n")
+		}
+		sep := "-"
+		if l.Match {
+			sep = ":"
+		}
+		fmt.Fprintf(bw, "%s%s%d%s%s\n", f.Path, sep, l.Num, sep, l.Text)
+	}
+	return bw.Flush()
+}
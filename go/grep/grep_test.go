@@ -0,0 +1,138 @@
+package grep
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"example.com/tutorial/progress"
+)
+
+func makeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func paths(t *testing.T, root string, files []File) []string {
+	t.Helper()
+	var out []string
+	for _, f := range files {
+		rel, _ := filepath.Rel(root, f.Path)
+		out = append(out, filepath.ToSlash(rel))
+	}
+	return out
+}
+
+func TestSearch(t *testing.T) {
+	root := makeTree(t, map[string]string{
+		"a.go":          "package a\n// TODO: fix\nfunc A() {}\n",
+		"b.txt":         "nothing\nTODO later\n",
+		"vendor/c.go":   "// TODO vendored\n",
+		"sub/d.go":      "no match here\n",
+		"bin/e.go":      "TODO\x00binary",
+		"sub/deep/f.go": "x\nTODO\n",
+	})
+	re := regexp.MustCompile(`TODO`)
+	files, err := Search(context.Background(), root, re, Options{Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := paths(t, root, files), []string{"a.go", "b.txt", "sub/deep/f.go", "vendor/c.go"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("all: %v, want %v", got, want)
+	}
+	if f := files[0]; f.Matches != 1 || !reflect.DeepEqual(f.Lines, []Line{{2, "// TODO: fix", true}}) {
+		t.Fatalf("a.go: %+v", f)
+	}
+
+	files, err = Search(context.Background(), root, re, Options{Include: []string{"*.go"}, Exclude: []string{"vendor"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := paths(t, root, files), []string{"a.go", "sub/deep/f.go"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("filtered: %v, want %v", got, want)
+	}
+
+	files, err = Search(context.Background(), filepath.Join(root, "b.txt"), re, Options{Include: []string{"*.go"}})
+	if err != nil || len(files) != 1 {
+		t.Fatalf("single file: %v, %v", files, err)
+	}
+
+	if _, err := Search(context.Background(), root, re, Options{Exclude: []string{"["}}); err == nil {
+		t.Fatal("bad pattern accepted")
+	}
+	if _, err := Search(context.Background(), filepath.Join(root, "missing"), re, Options{}); err == nil {
+		t.Fatal("missing root accepted")
+	}
+}
+
+func TestSearchContext(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 12; i++ {
+		lines = append(lines, "line")
+	}
+	lines[2], lines[4], lines[10] = "hit", "hit", "hit" // lines 3, 5 and 11
+	root := makeTree(t, map[string]string{"f": strings.Join(lines, "\n") + "\n"})
+	files, err := Search(context.Background(), root, regexp.MustCompile("hit"), Options{Context: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nums []int
+	for _, l := range files[0].Lines {
+		nums = append(nums, l.Num)
+	}
+	if want := []int{2, 3, 4, 5, 6, 10, 11, 12}; !reflect.DeepEqual(nums, want) {
+		t.Fatalf("lines %v, want %v", nums, want)
+	}
+	if files[0].Matches != 3 {
+		t.Fatalf("matches %d", files[0].Matches)
+	}
+
+	var b strings.Builder
+	f := File{Path: "f", Lines: []Line{{2, "a", false}, {3, "hit", true}, {7, "hit", true}}}
+	if err := f.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	if want := "f-2-a\nf:3:hit\n--\nf:7:hit\n"; b.String() != want {
+		t.Fatalf("WriteText:\n%s", b.String())
+	}
+}
+
+func TestSearchWords(t *testing.T) {
+	root := makeTree(t, map[string]string{"f": "gopher\ngophers unite\nthe Go team\n"})
+	files, err := Search(context.Background(), root, regexp.MustCompile(`gopher|Go`), Options{Words: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int
+	for _, l := range files[0].Lines {
+		got = append(got, l.Num)
+	}
+	if !reflect.DeepEqual(got, []int{1, 3}) {
+		t.Fatalf("word matches on lines %v", got)
+	}
+}
+
+func TestSearchProgress(t *testing.T) {
+	root := makeTree(t, map[string]string{"a.txt": "TODO\n", "sub/b.txt": "nothing here\n", "sub/c.bin": "x\x00y"})
+	tr := progress.NewTracker()
+	if _, err := Search(context.Background(), root, regexp.MustCompile(`TODO`), Options{Progress: tr}); err != nil {
+		t.Fatal(err)
+	}
+	if s := tr.Stats(); s.Files != 3 || s.Bytes != 5+13+3 {
+		t.Fatalf("counted %d files, %d bytes", s.Files, s.Bytes)
+	}
+}
@@ -0,0 +1,32 @@
+package health
+
+import (
+	"context"
+	"os"
+
+	"example.com/tutorial/user"
+)
+
+// StoreReachable checks that s answers a minimal List query.
+func StoreReachable(s user.Store) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		_, err := s.List(ctx, user.ListQuery{Limit: 1})
+		return err
+	})
+}
+
+// DirWritable checks that a file can be created in dir.
+func DirWritable(dir string) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		f, err := os.CreateTemp(dir, ".readyz-*")
+		if err != nil {
+			return err
+		}
+		name := f.Name()
+		err = f.Close()
+		if rmErr := os.Remove(name); err == nil {
+			err = rmErr
+		}
+		return err
+	})
+}
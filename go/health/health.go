@@ -0,0 +1,157 @@
+// Package health serves liveness and readiness endpoints. Readiness is
+// the combined result of pluggable checks, reported per check with its
+// latency.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Checker reports whether a dependency is usable. Check should return
+// promptly once ctx is done.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to Checker.
+type CheckerFunc func(ctx context.Context) error
+
+// Check implements Checker.
+func (f CheckerFunc) Check(ctx context.Context) error { return f(ctx) }
+
+// DefaultTimeout bounds each readiness check when the Registry has no
+// timeout of its own.
+const DefaultTimeout = 2 * time.Second
+
+// Registry holds the named readiness checks. It is safe for concurrent
+// use; the zero value has no checks and uses DefaultTimeout.
+type Registry struct {
+	// Timeout bounds each check; zero means DefaultTimeout.
+	Timeout time.Duration
+
+	mu     sync.RWMutex
+	checks map[string]Checker
+}
+
+// Register adds or replaces the check called name.
+func (reg *Registry) Register(name string, c Checker) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if reg.checks == nil {
+		reg.checks = map[string]Checker{}
+	}
+	reg.checks[name] = c
+}
+
+// Status values of a Report and its results.
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Result is the outcome of one check.
+type Result struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of all checks. Status is StatusOK only if every
+// check passed.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Run runs every check concurrently, each under the registry's timeout.
+func (reg *Registry) Run(ctx context.Context) Report {
+	reg.mu.RLock()
+	names := make([]string, 0, len(reg.checks))
+	for name := range reg.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checks := make([]Checker, len(names))
+	for i, name := range names {
+		checks[i] = reg.checks[name]
+	}
+	reg.mu.RUnlock()
+
+	timeout := reg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c Checker) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, c, timeout)
+		}(i, c)
+	}
+	wg.Wait()
+
+	rep := Report{Status: StatusOK, Checks: make(map[string]Result, len(names))}
+	for i, name := range names {
+		rep.Checks[name] = results[i]
+		if results[i].Status != StatusOK {
+			rep.Status = StatusFail
+		}
+	}
+	return rep
+}
+
+// runCheck runs c, giving up when the timeout expires even if c ignores
+// its context.
+func runCheck(ctx context.Context, c Checker, timeout time.Duration) Result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- c.Check(ctx) }()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	res := Result{Status: StatusOK, LatencyMS: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		res.Status, res.Error = StatusFail, err.Error()
+	}
+	return res
+}
+
+// Live returns the /healthz handler: it answers 200 whenever the process
+// can serve HTTP at all, without running any checks.
+func Live() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, Report{Status: StatusOK, Checks: map[string]Result{}})
+	})
+}
+
+// Ready returns the /readyz handler: it runs the checks and answers 200
+// if all pass and 503 otherwise, with the Report as the body either way.
+func (reg *Registry) Ready() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rep := reg.Run(r.Context())
+		status := http.StatusOK
+		if rep.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, rep)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,86 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"example.com/tutorial/user"
+)
+
+func TestReadyAllPass(t *testing.T) {
+	var reg Registry
+	reg.Register("store", StoreReachable(user.NewMemoryStore()))
+	reg.Register("disk", DirWritable(t.TempDir()))
+
+	rec := httptest.NewRecorder()
+	reg.Ready().ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var rep Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &rep); err != nil {
+		t.Fatal(err)
+	}
+	if rep.Status != StatusOK || len(rep.Checks) != 2 || rep.Checks["disk"].Status != StatusOK {
+		t.Fatalf("report = %+v", rep)
+	}
+}
+
+func TestReadyFailure(t *testing.T) {
+	var reg Registry
+	reg.Register("ok", CheckerFunc(func(context.Context) error { return nil }))
+	reg.Register("broken", CheckerFunc(func(context.Context) error { return errors.New("db down") }))
+
+	rec := httptest.NewRecorder()
+	reg.Ready().ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status %d", rec.Code)
+	}
+	var rep Report
+	json.Unmarshal(rec.Body.Bytes(), &rep)
+	if rep.Status != StatusFail || rep.Checks["broken"].Error != "db down" || rep.Checks["ok"].Status != StatusOK {
+		t.Fatalf("report = %+v", rep)
+	}
+}
+
+func TestCheckTimeout(t *testing.T) {
+	reg := Registry{Timeout: 20 * time.Millisecond}
+	block := make(chan struct{})
+	defer close(block)
+	reg.Register("hung", CheckerFunc(func(context.Context) error { <-block; return nil }))
+	start := time.Now()
+	rep := reg.Run(context.Background())
+	if time.Since(start) > time.Second {
+		t.Fatal("Run waited for a check that ignores its context")
+	}
+	if res := rep.Checks["hung"]; res.Status != StatusFail || res.LatencyMS < 20 {
+		t.Fatalf("result = %+v", res)
+	}
+}
+
+func TestLive(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Live().ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"status":"ok","checks":{}}`+"\n" {
+		t.Fatalf("healthz = %d %q", rec.Code, rec.Body)
+	}
+}
+
+func TestDirWritableFails(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	if err := DirWritable(missing).Check(context.Background()); err == nil {
+		t.Fatal("missing directory reported writable")
+	}
+	dir := t.TempDir()
+	DirWritable(dir).Check(context.Background())
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("probe file left behind: %v", entries)
+	}
+}
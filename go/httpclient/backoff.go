@@ -0,0 +1,54 @@
+package httpclient
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"example.com/tutorial/retry"
+)
+
+// Backoff returns the delay before retry number attempt, as
+// retry.Backoff does; Client waits that long between attempts.
+func Backoff(attempt int, base, max time.Duration, rnd func() float64) time.Duration {
+	return retry.Backoff(attempt, base, max, rnd)
+}
+
+// Idempotent reports whether req may be sent again without changing the
+// outcome: its method is idempotent per RFC 9110, or it carries an
+// Idempotency-Key header asking the server to deduplicate it.
+func Idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// retryableStatus reports whether a response with the given status is a
+// transient failure worth retrying.
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses the Retry-After header of resp, in seconds or as an
+// HTTP date, returning 0 when it is absent or malformed.
+func retryAfter(resp *http.Response, now time.Time) time.Duration {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
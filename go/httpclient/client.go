@@ -0,0 +1,187 @@
+// Package httpclient wraps http.Client with retries: transient failures
+// are retried with exponential backoff and jitter, each attempt may have
+// its own timeout, and requests that are not safe to repeat are sent
+// once.
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"example.com/tutorial/ctxutil"
+	"example.com/tutorial/retry"
+)
+
+// Defaults used for zero Config fields.
+const (
+	DefaultMaxAttempts = retry.DefaultMaxAttempts
+	DefaultBaseDelay   = retry.DefaultBaseDelay
+	DefaultMaxDelay    = retry.DefaultMaxDelay
+)
+
+// Config tunes a Client. The zero value retries idempotent requests up
+// to DefaultMaxAttempts times with no per-attempt timeout.
+type Config struct {
+	// MaxAttempts bounds the number of times a request is sent,
+	// including the first; 1 disables retries.
+	MaxAttempts int
+	// BaseDelay and MaxDelay shape the backoff between attempts; see
+	// Backoff. A Retry-After header lengthens the delay up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// AttemptTimeout limits each attempt, including reading the
+	// response body; zero leaves only the request's own context.
+	AttemptTimeout time.Duration
+	// RetryNonIdempotent retries requests for which Idempotent is
+	// false, such as a POST without an Idempotency-Key.
+	RetryNonIdempotent bool
+	// OnRetry, if set, is called before each retry with the number of
+	// the attempt that failed, the delay before the next one and why.
+	OnRetry func(attempt int, delay time.Duration, err error)
+}
+
+// Client sends requests through an http.Client, retrying transient
+// failures. It is safe for concurrent use.
+type Client struct {
+	hc    *http.Client
+	cfg   Config
+	rnd   func() float64
+	now   func() time.Time
+	sleep func(context.Context, time.Duration) error
+}
+
+// New returns a Client sending through hc, or http.DefaultClient if hc
+// is nil.
+func New(hc *http.Client, cfg Config) *Client {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = DefaultBaseDelay
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = DefaultMaxDelay
+	}
+	return &Client{hc: hc, cfg: cfg, rnd: rand.Float64, now: time.Now, sleep: ctxutil.Sleep}
+}
+
+// Get issues a GET to url.
+func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// Post issues a POST to url. It is retried only if the Config allows
+// non-idempotent retries; use Do with an Idempotency-Key header
+// otherwise.
+func (c *Client) Post(ctx context.Context, url, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return c.Do(req)
+}
+
+// Do sends req, retrying on transport errors, per-attempt timeouts and
+// 429, 502, 503 and 504 responses while attempts remain and req's
+// context is live. A request is retried only if it is Idempotent (or the
+// Config says otherwise) and its body can be replayed through GetBody,
+// which http.NewRequest sets for in-memory bodies.
+//
+// When the attempts run out on a retryable status, the last response is
+// returned as is; a transport error on the last attempt is returned
+// wrapped with the attempt count.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	attempts := c.cfg.MaxAttempts
+	if !c.cfg.RetryNonIdempotent && !Idempotent(req) {
+		attempts = 1
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		attempts = 1
+	}
+	policy := retry.Policy{
+		MaxAttempts: attempts,
+		BaseDelay:   c.cfg.BaseDelay,
+		MaxDelay:    c.cfg.MaxDelay,
+		OnRetry:     c.cfg.OnRetry,
+		Jitter:      c.rnd,
+		Sleep:       c.sleep,
+	}
+	var resp *http.Response
+	attempt := 0
+	err := retry.Do(ctx, policy, func(ctx context.Context) error {
+		attempt++
+		r, err := c.send(req, attempt)
+		if err != nil {
+			return err
+		}
+		// The last response is returned as is, whatever its status.
+		if !retryableStatus(r.StatusCode) || attempt == attempts || ctx.Err() != nil {
+			resp = r
+			return nil
+		}
+		ra := retryAfter(r, c.now())
+		discard(r)
+		return retry.After(errors.New(r.Status), ra)
+	})
+	if err != nil && err != ctx.Err() && attempt > 1 {
+		err = fmt.Errorf("httpclient: %d attempts: %w", attempt, err)
+	}
+	return resp, err
+}
+
+// send makes one attempt at req.
+func (c *Client) send(req *http.Request, attempt int) (*http.Response, error) {
+	ctx, cancel := req.Context(), context.CancelFunc(func() {})
+	if c.cfg.AttemptTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.cfg.AttemptTimeout)
+	}
+	r := req.Clone(ctx)
+	if attempt > 1 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		r.Body = body
+	}
+	resp, err := c.hc.Do(r)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody releases an attempt's context once its body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// discard drains a little of resp's body, so the connection can be
+// reused, and closes it.
+func discard(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+	resp.Body.Close()
+}
@@ -0,0 +1,207 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flaky serves fail for the first n requests, then 200 with the request
+// body echoed back.
+func flaky(t *testing.T, n int32, fail func(w http.ResponseWriter)) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= n {
+			fail(w)
+			return
+		}
+		io.Copy(w, r.Body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func unavailable(w http.ResponseWriter) { w.WriteHeader(http.StatusServiceUnavailable) }
+
+// newTestClient returns a Client that records its delays instead of
+// sleeping.
+func newTestClient(cfg Config) (*Client, *[]time.Duration) {
+	c := New(nil, cfg)
+	c.rnd = func() float64 { return 0.5 }
+	var delays []time.Duration
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return ctx.Err()
+	}
+	return c, &delays
+}
+
+func TestRetriesTransientStatus(t *testing.T) {
+	srv, calls := flaky(t, 2, unavailable)
+	var retried []int
+	c, delays := newTestClient(Config{OnRetry: func(attempt int, _ time.Duration, err error) {
+		retried = append(retried, attempt)
+		if !strings.Contains(err.Error(), "503") {
+			t.Errorf("OnRetry err = %v", err)
+		}
+	}})
+	resp, err := c.Get(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls.Load() != 3 {
+		t.Fatalf("status %d after %d calls", resp.StatusCode, calls.Load())
+	}
+	if want := []time.Duration{50 * time.Millisecond, 100 * time.Millisecond}; len(*delays) != 2 || (*delays)[0] != want[0] || (*delays)[1] != want[1] {
+		t.Fatalf("delays = %v, want %v", *delays, want)
+	}
+	if len(retried) != 2 || retried[0] != 1 || retried[1] != 2 {
+		t.Fatalf("OnRetry attempts = %v", retried)
+	}
+}
+
+func TestGivesUp(t *testing.T) {
+	srv, calls := flaky(t, 10, unavailable)
+	c, _ := newTestClient(Config{MaxAttempts: 4})
+	resp, err := c.Get(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || calls.Load() != 4 {
+		t.Fatalf("status %d after %d calls", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestNoRetry(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		req  func(url string) *http.Request
+	}{
+		{"post", Config{}, func(url string) *http.Request {
+			r, _ := http.NewRequest("POST", url, strings.NewReader("x"))
+			return r
+		}},
+		{"unreplayable body", Config{}, func(url string) *http.Request {
+			r, _ := http.NewRequest("PUT", url, io.NopCloser(strings.NewReader("x")))
+			return r
+		}},
+		{"one attempt", Config{MaxAttempts: 1}, func(url string) *http.Request {
+			r, _ := http.NewRequest("GET", url, nil)
+			return r
+		}},
+	}
+	for _, tt := range tests {
+		srv, calls := flaky(t, 1, unavailable)
+		c, _ := newTestClient(tt.cfg)
+		resp, err := c.Do(tt.req(srv.URL))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		resp.Body.Close()
+		if calls.Load() != 1 {
+			t.Errorf("%s: %d calls", tt.name, calls.Load())
+		}
+	}
+
+	srv, calls := flaky(t, 1, func(w http.ResponseWriter) { w.WriteHeader(http.StatusInternalServerError) })
+	c, _ := newTestClient(Config{})
+	resp, err := c.Get(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError || calls.Load() != 1 {
+		t.Fatalf("500: status %d after %d calls", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestRetryReplaysBody(t *testing.T) {
+	srv, calls := flaky(t, 1, unavailable)
+	c, _ := newTestClient(Config{})
+	req, _ := http.NewRequest("POST", srv.URL, strings.NewReader("payload"))
+	req.Header.Set("Idempotency-Key", "k1")
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "payload" || calls.Load() != 2 {
+		t.Fatalf("body %q after %d calls", body, calls.Load())
+	}
+
+	srv, calls = flaky(t, 1, unavailable)
+	c, _ = newTestClient(Config{RetryNonIdempotent: true})
+	resp, err = c.Post(context.Background(), srv.URL, "text/plain", strings.NewReader("again"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if body, _ := io.ReadAll(resp.Body); string(body) != "again" || calls.Load() != 2 {
+		t.Fatalf("body %q after %d calls", body, calls.Load())
+	}
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	srv, _ := flaky(t, 1, func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "2")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	c, delays := newTestClient(Config{MaxDelay: time.Minute})
+	resp, err := c.Get(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(*delays) != 1 || (*delays)[0] != 2*time.Second {
+		t.Fatalf("delays = %v", *delays)
+	}
+}
+
+func TestAttemptTimeout(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			<-r.Context().Done()
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+	c, _ := newTestClient(Config{AttemptTimeout: 50 * time.Millisecond})
+	resp, err := c.Get(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	// The body is read after Do returns, under the attempt's deadline.
+	if body, err := io.ReadAll(resp.Body); err != nil || string(body) != "ok" || calls.Load() != 2 {
+		t.Fatalf("body %q, %v after %d calls", body, err, calls.Load())
+	}
+}
+
+func TestTransportErrors(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+	c, delays := newTestClient(Config{MaxAttempts: 3})
+	_, err := c.Get(context.Background(), url)
+	if err == nil || !strings.Contains(err.Error(), "3 attempts") || len(*delays) != 2 {
+		t.Fatalf("err = %v after delays %v", err, *delays)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c, _ = newTestClient(Config{OnRetry: func(int, time.Duration, error) { cancel() }})
+	if _, err := c.Get(ctx, url); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled err = %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+// Package humanize formats durations, byte sizes and rates for people,
+// as in "1m32s ago", "4.2 MiB" and "12k words/s", and parses the sizes
+// and durations people type, such as "2GiB" and "10m", for flags and
+// settings.
+package humanize
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// binaryUnits are the suffixes of Bytes, each 1024 times the last.
+var binaryUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+
+// Bytes formats a byte count with the binary unit that keeps it below
+// 1024, as in "512 B", "4.2 MiB" or "12 GiB": one decimal below ten,
+// none above.
+func Bytes(n int64) string {
+	if n < 1024 && n > -1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+	v, unit := scale(float64(n), 1024, len(binaryUnits)-1)
+	return short(v) + " " + binaryUnits[unit]
+}
+
+// CompactBytes formats a byte count in the style of ls -h, as in "512",
+// "1.5K" or "12M", for columns of sizes.
+func CompactBytes(n int64) string {
+	if n < 1024 && n > -1024 {
+		return strconv.FormatInt(n, 10)
+	}
+	v, unit := scale(float64(n), 1024, len(binaryUnits)-1)
+	return short(v) + binaryUnits[unit][:1]
+}
+
+// siUnits are the suffixes of Count, each 1000 times the last.
+var siUnits = []string{"", "k", "M", "G", "T", "P", "E"}
+
+// Count formats a number of things with a decimal unit, as in "999",
+// "1.5k" or "12M".
+func Count(n float64) string {
+	if math.Abs(n) < 1000 {
+		return strconv.FormatFloat(math.Round(n), 'f', -1, 64)
+	}
+	v, unit := scale(n, 1000, len(siUnits)-1)
+	return short(v) + siUnits[unit]
+}
+
+// Rate formats n things done in d as a rate per second, as in "12k
+// words/s". A d of zero or less gives a rate of zero.
+func Rate(n float64, d time.Duration, unit string) string {
+	return Count(perSecond(n, d)) + " " + unit + "/s"
+}
+
+// ByteRate formats n bytes moved in d as a rate per second, as in
+// "4.2 MiB/s".
+func ByteRate(n int64, d time.Duration) string {
+	return Bytes(int64(perSecond(float64(n), d))) + "/s"
+}
+
+func perSecond(n float64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return n / d.Seconds()
+}
+
+// scale divides v by base until it is below base or the units run out,
+// and returns the result and the number of divisions.
+func scale(v, base float64, maxUnit int) (float64, int) {
+	unit := 0
+	for math.Abs(v) >= base && unit < maxUnit {
+		v /= base
+		unit++
+	}
+	return v, unit
+}
+
+// short formats v with one decimal below ten and none above, taking
+// care that 9.97 becomes "10" and not "10.0".
+func short(v float64) string {
+	if math.Abs(v) < 9.95 {
+		return strconv.FormatFloat(v, 'f', 1, 64)
+	}
+	return strconv.FormatFloat(v, 'f', 0, 64)
+}
+
+// durationUnits are the units of Duration, largest first.
+var durationUnits = []struct {
+	d    time.Duration
+	name string
+}{
+	{24 * time.Hour, "d"},
+	{time.Hour, "h"},
+	{time.Minute, "m"},
+	{time.Second, "s"},
+}
+
+// Duration formats d in its two largest units, rounded, as in "1m32s",
+// "2h5m" or "3d4h". Durations under a second are given in milliseconds,
+// as in "850ms", and those under a millisecond as time.Duration prints
+// them.
+func Duration(d time.Duration) string {
+	if d < 0 {
+		return "-" + Duration(-d)
+	}
+	if d < time.Millisecond {
+		return d.String()
+	}
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Round(time.Millisecond)/time.Millisecond)
+	}
+	for i, u := range durationUnits {
+		if d < u.d {
+			continue
+		}
+		if i == len(durationUnits)-1 {
+			return fmt.Sprintf("%d%s", d.Round(u.d)/u.d, u.name)
+		}
+		next := durationUnits[i+1]
+		d = d.Round(next.d)
+		s := fmt.Sprintf("%d%s", d/u.d, u.name)
+		if rest := d % u.d / next.d; rest > 0 {
+			s += fmt.Sprintf("%d%s", rest, next.name)
+		}
+		return s
+	}
+	panic("unreachable")
+}
+
+// Ago formats the time from t to now as Duration does, as in "1m32s
+// ago", or "in 5s" for a t after now. Less than a second either way is
+// "just now".
+func Ago(t, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d > -time.Second && d < time.Second:
+		return "just now"
+	case d < 0:
+		return "in " + Duration(-d)
+	}
+	return Duration(d) + " ago"
+}
@@ -0,0 +1,89 @@
+package humanize
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBytes(t *testing.T) {
+	for n, want := range map[int64]string{
+		0:                 "0 B",
+		512:               "512 B",
+		1024:              "1.0 KiB",
+		1536:              "1.5 KiB",
+		4404019:           "4.2 MiB",
+		12 << 30:          "12 GiB",
+		-2048:             "-2.0 KiB",
+		1<<63 - 1:         "8.0 EiB",
+		10*1024*1024 - 1:  "10 MiB",
+		999 * 1024 * 1024: "999 MiB",
+	} {
+		if got := Bytes(n); got != want {
+			t.Errorf("Bytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestCompactBytes(t *testing.T) {
+	for n, want := range map[int64]string{0: "0", 1023: "1023", 1536: "1.5K", 12 << 20: "12M", 3 << 40: "3.0T"} {
+		if got := CompactBytes(n); got != want {
+			t.Errorf("CompactBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestCount(t *testing.T) {
+	for n, want := range map[float64]string{0: "0", 999: "999", 999.6: "1000", 1500: "1.5k", 12000: "12k", 3.4e6: "3.4M", -2500: "-2.5k"} {
+		if got := Count(n); got != want {
+			t.Errorf("Count(%g) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestRate(t *testing.T) {
+	if got := Rate(24000, 2*time.Second, "words"); got != "12k words/s" {
+		t.Errorf("Rate = %q", got)
+	}
+	if got := Rate(5, 0, "files"); got != "0 files/s" {
+		t.Errorf("Rate over no time = %q", got)
+	}
+	if got := ByteRate(8808038, 2*time.Second); got != "4.2 MiB/s" {
+		t.Errorf("ByteRate = %q", got)
+	}
+}
+
+func TestDuration(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		0:                                     "0s",
+		500 * time.Microsecond:                "500µs",
+		850 * time.Millisecond:                "850ms",
+		time.Second:                           "1s",
+		42*time.Second + 400*time.Millisecond: "42s",
+		92 * time.Second:                      "1m32s",
+		2 * time.Minute:                       "2m",
+		2*time.Hour + 5*time.Minute + 20*time.Second: "2h5m",
+		76 * time.Hour:    "3d4h",
+		-90 * time.Second: "-1m30s",
+	} {
+		if got := Duration(d); got != want {
+			t.Errorf("Duration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestAgo(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		t    time.Time
+		want string
+	}{
+		{now.Add(-92 * time.Second), "1m32s ago"},
+		{now.Add(5 * time.Second), "in 5s"},
+		{now.Add(-300 * time.Millisecond), "just now"},
+		{now.Add(-49 * time.Hour), "2d1h ago"},
+	} {
+		if got := Ago(tt.t, now); got != tt.want {
+			t.Errorf("Ago(%v) = %q, want %q", now.Sub(tt.t), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package humanize
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sizeUnits are the multipliers of the units ParseBytes accepts, by
+// lower-case name.
+var sizeUnits = map[string]float64{"": 1, "b": 1}
+
+func init() {
+	for i, letter := range "kmgtpe" {
+		binary := math.Pow(1024, float64(i+1))
+		sizeUnits[string(letter)] = binary
+		sizeUnits[string(letter)+"ib"] = binary
+		sizeUnits[string(letter)+"b"] = math.Pow(1000, float64(i+1))
+	}
+}
+
+// ParseBytes parses a size such as "512", "2GiB", "1.5 M" or "10MB".
+// Units are case-insensitive: KiB, MiB and so on, and the bare K, M, G
+// that CompactBytes prints, are powers of 1024, while kB, MB, GB and so
+// on are powers of 1000. A number without a unit is in bytes.
+func ParseBytes(s string) (int64, error) {
+	num := strings.TrimSpace(s)
+	i := strings.IndexFunc(num, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '-' && r != '+'
+	})
+	unit := ""
+	if i >= 0 {
+		num, unit = strings.TrimSpace(num[:i]), strings.TrimSpace(num[i:])
+	}
+	mult, ok := sizeUnits[strings.ToLower(unit)]
+	v, err := strconv.ParseFloat(num, 64)
+	if !ok || err != nil {
+		return 0, fmt.Errorf("humanize: %q is not a size such as 512, 10MB or 2GiB", s)
+	}
+	v *= mult
+	if v < 0 || v >= math.MaxInt64 {
+		return 0, fmt.Errorf("humanize: size %q out of range", s)
+	}
+	return int64(v), nil
+}
+
+// dayUnits are the units ParseDuration accepts beyond those of
+// time.ParseDuration.
+var dayUnits = map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+
+// ParseDuration parses a duration as time.ParseDuration does, such as
+// "10m" or "1h30m", and also accepts days and weeks, as in "1d12h" or
+// "2w".
+func ParseDuration(s string) (time.Duration, error) {
+	rest := strings.TrimSpace(s)
+	sign := time.Duration(1)
+	if strings.HasPrefix(rest, "-") {
+		sign, rest = -1, rest[1:]
+	} else {
+		rest = strings.TrimPrefix(rest, "+")
+	}
+	var total time.Duration
+	days := false
+	for {
+		i := strings.IndexFunc(rest, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+		if i <= 0 || dayUnits[rest[i]] == 0 {
+			break
+		}
+		n, err := strconv.ParseFloat(rest[:i], 64)
+		if err != nil {
+			return 0, durationError(s)
+		}
+		total += time.Duration(n * float64(dayUnits[rest[i]]))
+		rest, days = rest[i+1:], true
+	}
+	if rest != "" || !days {
+		d, err := time.ParseDuration(rest)
+		if err != nil || strings.HasPrefix(rest, "-") || strings.HasPrefix(rest, "+") {
+			return 0, durationError(s)
+		}
+		total += d
+	}
+	return sign * total, nil
+}
+
+func durationError(s string) error {
+	return fmt.Errorf("humanize: %q is not a duration such as 10m, 1h30m or 2d", s)
+}
+
+// Size is a byte count that flags and settings take in the units of
+// ParseBytes. Use it with flag.TextVar; its text is exact, such as
+// "1GiB" or "1500B", so that it parses back to the same value.
+type Size int64
+
+// String formats s in the largest binary unit it is a whole multiple
+// of.
+func (s Size) String() string {
+	n, unit := int64(s), 0
+	for n != 0 && n%1024 == 0 && unit < len(binaryUnits)-1 {
+		n /= 1024
+		unit++
+	}
+	return strconv.FormatInt(n, 10) + binaryUnits[unit]
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Size) MarshalText() ([]byte, error) { return []byte(s.String()), nil }
+
+// UnmarshalText implements encoding.TextUnmarshaler with ParseBytes.
+func (s *Size) UnmarshalText(text []byte) error {
+	n, err := ParseBytes(string(text))
+	if err != nil {
+		return err
+	}
+	*s = Size(n)
+	return nil
+}
@@ -0,0 +1,79 @@
+package humanize
+
+import (
+	"flag"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestParseBytes(t *testing.T) {
+	for s, want := range map[string]int64{
+		"512":    512,
+		"0":      0,
+		"2GiB":   2 << 30,
+		"2gib":   2 << 30,
+		"1.5 M":  3 << 19,
+		"1.5K":   1536,
+		"10MB":   10e6,
+		"1kB":    1000,
+		"100 B":  100,
+		" 4KiB ": 4096,
+	} {
+		if got, err := ParseBytes(s); err != nil || got != want {
+			t.Errorf("ParseBytes(%q) = %d, %v; want %d", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "MiB", "12 bytes", "1..5K", "-1K", "1XB", "9EiB"} {
+		if n, err := ParseBytes(s); err == nil {
+			t.Errorf("ParseBytes(%q) = %d, want an error", s, n)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	for s, want := range map[string]time.Duration{
+		"10m":    10 * time.Minute,
+		"1h30m":  90 * time.Minute,
+		"0":      0,
+		"2d":     48 * time.Hour,
+		"1d12h":  36 * time.Hour,
+		"1.5d":   36 * time.Hour,
+		"2w":     14 * 24 * time.Hour,
+		"1w1d1s": 8*24*time.Hour + time.Second,
+		"-1d":    -24 * time.Hour,
+		"+5s":    5 * time.Second,
+	} {
+		if got, err := ParseDuration(s); err != nil || got != want {
+			t.Errorf("ParseDuration(%q) = %v, %v; want %v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "soon", "d", "1d-5h", "5", "1d 2h", "--1d"} {
+		if d, err := ParseDuration(s); err == nil {
+			t.Errorf("ParseDuration(%q) = %v, want an error", s, d)
+		}
+	}
+}
+
+func TestSize(t *testing.T) {
+	for n, want := range map[Size]string{0: "0B", 1500: "1500B", 1536: "1536B", 1 << 30: "1GiB", 3 << 20: "3MiB"} {
+		if got := n.String(); got != want {
+			t.Errorf("Size(%d) = %q, want %q", int64(n), got, want)
+		}
+		var back Size
+		if err := back.UnmarshalText([]byte(want)); err != nil || back != n {
+			t.Errorf("%q parsed back to %d, %v", want, int64(back), err)
+		}
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	max := Size(1 << 20)
+	fs.TextVar(&max, "max", max, "")
+	if err := fs.Parse([]string{"-max", "2GiB"}); err != nil || max != 2<<30 {
+		t.Fatalf("-max 2GiB: %d, %v", int64(max), err)
+	}
+	if err := fs.Parse([]string{"-max", "lots"}); err == nil {
+		t.Fatal("-max lots parsed")
+	}
+}
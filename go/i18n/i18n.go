@@ -0,0 +1,176 @@
+// Package i18n translates the messages that the commands print and the
+// server's error responses carry. Its catalogs, one JSON file per
+// locale in catalogs/, map a message key, the English text with its fmt
+// verbs, to the text of the locale, or to one text per plural form for
+// messages with a count:
+//
+//	"%d users added": {"one": "%d utilisateur ajouté", "other": "%d utilisateurs ajoutés"}
+//
+// A Printer formats the messages of one locale, falling back to English
+// and then to the key for messages its catalog lacks. Match picks the
+// locale from $LANG, a -lang flag or an Accept-Language header.
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"golang.org/x/text/language"
+)
+
+// catalog maps message keys to their texts by plural form.
+type catalog map[string]map[string]string
+
+//go:embed catalogs/*.json
+var catalogFiles embed.FS
+
+// catalogs holds the catalog of every supported locale, by tag.
+var catalogs = func() map[language.Tag]catalog {
+	out := map[language.Tag]catalog{}
+	for _, tag := range Supported {
+		cat, err := readCatalog(tag)
+		if err != nil {
+			panic(err) // the catalogs are embedded; tests read them all
+		}
+		out[tag] = cat
+	}
+	return out
+}()
+
+// readCatalog parses the catalog file of tag, whose entries are a text
+// or an object of texts by plural form.
+func readCatalog(tag language.Tag) (catalog, error) {
+	name := path.Join("catalogs", tag.String()+".json")
+	data, err := catalogFiles.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("i18n: %w", err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("i18n: %s: %w", name, err)
+	}
+	cat := catalog{}
+	for key, v := range raw {
+		var text string
+		if json.Unmarshal(v, &text) == nil {
+			cat[key] = map[string]string{other: text}
+			continue
+		}
+		var forms map[string]string
+		if err := json.Unmarshal(v, &forms); err != nil || forms[other] == "" {
+			return nil, fmt.Errorf("i18n: %s: %q needs a text or an %q form", name, key, other)
+		}
+		cat[key] = forms
+	}
+	return cat, nil
+}
+
+// Printer formats messages in one locale. It is safe for concurrent use.
+type Printer struct {
+	tag    language.Tag
+	cat    catalog
+	plural func(n int) string
+}
+
+// English prints the messages in English, the text of their keys.
+var English = NewPrinter(language.English)
+
+// NewPrinter returns a Printer for the supported locale closest to tag,
+// English if none is close.
+func NewPrinter(tag language.Tag) *Printer {
+	_, i, conf := matcher.Match(tag)
+	if conf == language.No {
+		i = 0
+	}
+	tag = Supported[i]
+	return &Printer{tag: tag, cat: catalogs[tag], plural: pluralRule(tag)}
+}
+
+// For returns a Printer for Match(prefs...).
+func For(prefs ...string) *Printer {
+	return NewPrinter(Match(prefs...))
+}
+
+// Tag returns the locale of p.
+func (p *Printer) Tag() language.Tag { return p.tag }
+
+// text returns the text of key in the given plural form, or in the form
+// "other" if p's catalog has no such form.
+func (p *Printer) text(key, form string) string {
+	forms, ok := p.cat[key]
+	if !ok {
+		forms, ok = catalogs[language.English][key]
+	}
+	if !ok {
+		return key
+	}
+	if t, ok := forms[form]; ok {
+		return t
+	}
+	return forms[other]
+}
+
+// Sprintf formats the message key with args, as fmt.Sprintf formats a
+// format string.
+func (p *Printer) Sprintf(key string, args ...any) string {
+	return fmt.Sprintf(p.text(key, other), args...)
+}
+
+// Plural formats the plural form of the message key that the count n
+// takes, with args, or with n alone if there are none:
+//
+//	p.Plural("%d words", 1)              // "1 word"
+//	p.Plural("%d of %d users", 9, 2, 9)  // "2 of 9 users"
+func (p *Printer) Plural(key string, n int, args ...any) string {
+	if len(args) == 0 {
+		args = []any{n}
+	}
+	return fmt.Sprintf(p.text(key, p.plural(n)), args...)
+}
+
+// Error returns the text of err in p's locale if it is an error made by
+// NewError, and err.Error() otherwise. Errors that wrap one keep their
+// own text: what they add is not in the catalogs.
+func (p *Printer) Error(err error) string {
+	if e, ok := err.(*Error); ok {
+		return p.Sprintf(e.Key, e.Args...)
+	}
+	return err.Error()
+}
+
+// Error is an error whose text a Printer translates. Its Error method
+// gives the English text.
+type Error struct {
+	// Kind is the error that errors.Is finds under e, such as
+	// errs.ErrNotFound, or nil.
+	Kind error
+	Key  string
+	Args []any
+}
+
+// NewError returns an error of kind with the message key formatted with
+// args.
+func NewError(kind error, key string, args ...any) error {
+	return &Error{Kind: kind, Key: key, Args: args}
+}
+
+func (e *Error) Error() string { return English.Sprintf(e.Key, e.Args...) }
+func (e *Error) Unwrap() error { return e.Kind }
+
+type printerKey struct{}
+
+// NewContext returns a context carrying p.
+func NewContext(ctx context.Context, p *Printer) context.Context {
+	return context.WithValue(ctx, printerKey{}, p)
+}
+
+// FromContext returns the Printer that ctx carries, or English.
+func FromContext(ctx context.Context) *Printer {
+	if p, ok := ctx.Value(printerKey{}).(*Printer); ok {
+		return p
+	}
+	return English
+}
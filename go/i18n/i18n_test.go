@@ -0,0 +1,104 @@
+package i18n
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+// verbs matches the fmt verbs of a message, with their flags.
+var verbs = regexp.MustCompile(`%[-+# 0-9.\[\]]*[a-zA-Z%]`)
+
+func TestCatalogs(t *testing.T) {
+	en := catalogs[language.English]
+	for _, tag := range Supported {
+		cat, err := readCatalog(tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for key, forms := range cat {
+			if _, ok := en[key]; !ok {
+				t.Errorf("%s: %q is not in the English catalog", tag, key)
+			}
+			want := verbs.FindAllString(key, -1)
+			for form, text := range forms {
+				if got := verbs.FindAllString(text, -1); !slices.Equal(got, want) {
+					t.Errorf("%s: %q (%s) has verbs %q, want %q", tag, key, form, got, want)
+				}
+			}
+		}
+		for key := range en {
+			if _, ok := cat[key]; !ok {
+				t.Errorf("%s: no translation of %q", tag, key)
+			}
+		}
+	}
+}
+
+func TestPrinter(t *testing.T) {
+	fr := NewPrinter(language.MustParse("fr-BE"))
+	if fr.Tag() != language.French {
+		t.Fatalf("Tag = %v", fr.Tag())
+	}
+	for _, tc := range []struct {
+		p    *Printer
+		n    int
+		want string
+	}{
+		{English, 0, "0 words"},
+		{English, 1, "1 word"},
+		{English, 2, "2 words"},
+		{fr, 0, "0 mot"},
+		{fr, 1, "1 mot"},
+		{fr, 2, "2 mots"},
+	} {
+		if got := tc.p.Plural("%d words", tc.n); got != tc.want {
+			t.Errorf("%v: Plural(%d) = %q, want %q", tc.p.Tag(), tc.n, got, tc.want)
+		}
+	}
+	if got := fr.Plural("%d of %d users", 9, 2, 9); got != "2 utilisateurs sur 9" {
+		t.Errorf("Plural with args = %q", got)
+	}
+	if got := fr.Sprintf("supported types: %s", "text/plain"); got != "types acceptés : text/plain" {
+		t.Errorf("Sprintf = %q", got)
+	}
+	// Messages missing from every catalog print their keys.
+	if got := fr.Sprintf("%d ducks", 3); got != "3 ducks" {
+		t.Errorf("Sprintf of an unknown key = %q", got)
+	}
+	if got := NewPrinter(language.Japanese).Tag(); got != language.English {
+		t.Errorf("NewPrinter(ja).Tag() = %v, want English", got)
+	}
+}
+
+func TestError(t *testing.T) {
+	kind := errors.New("not found")
+	err := NewError(kind, "supported types: %s", "text/csv")
+	if err.Error() != "supported types: text/csv" || !errors.Is(err, kind) {
+		t.Fatalf("err = %v", err)
+	}
+	fr := For("fr")
+	if got := fr.Error(err); got != "types acceptés : text/csv" {
+		t.Fatalf("Error = %q", got)
+	}
+	// A wrapping error keeps its own, English text.
+	wrapped := fmt.Errorf("upload: %w", err)
+	if got := fr.Error(wrapped); got != wrapped.Error() {
+		t.Fatalf("Error of a wrapper = %q", got)
+	}
+}
+
+func TestContext(t *testing.T) {
+	if FromContext(context.Background()) != English {
+		t.Fatal("FromContext without a printer is not English")
+	}
+	fr := For("fr")
+	if FromContext(NewContext(context.Background(), fr)) != fr {
+		t.Fatal("FromContext lost the printer")
+	}
+}
@@ -0,0 +1,61 @@
+package i18n
+
+import (
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Supported lists the locales that have a catalog, English first; it is
+// the fallback of every other.
+var Supported = []language.Tag{language.English, language.French}
+
+var matcher = language.NewMatcher(Supported)
+
+// Match returns the supported locale that best suits prefs, in order of
+// preference, or English if none does. Each one is a locale name such as
+// "fr-CA" or, as $LANG gives it, "fr_CA.UTF-8", or an Accept-Language
+// header listing several with their weights. Malformed and empty ones
+// are skipped.
+func Match(prefs ...string) language.Tag {
+	var tags []language.Tag
+	for _, s := range prefs {
+		if ts, _, err := language.ParseAcceptLanguage(posixLocale(s)); err == nil {
+			tags = append(tags, ts...)
+		}
+	}
+	if len(tags) == 0 {
+		return language.English
+	}
+	_, i, conf := matcher.Match(tags...)
+	if conf == language.No {
+		return language.English
+	}
+	return Supported[i]
+}
+
+// Env returns the locale of the environment that getenv reads: the first
+// of $LC_ALL, $LC_MESSAGES and $LANG that is set, or "".
+func Env(getenv func(string) string) string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// posixLocale turns a POSIX locale name such as "fr_FR.UTF-8" into a
+// BCP 47 tag, "fr-FR", and "C" and "POSIX" into "". Accept-Language
+// lists, which have commas or weights, are returned as they are.
+func posixLocale(s string) string {
+	if strings.ContainsAny(s, ",;") {
+		return s
+	}
+	s, _, _ = strings.Cut(s, ".")
+	s, _, _ = strings.Cut(s, "@")
+	if s == "C" || s == "POSIX" {
+		return ""
+	}
+	return strings.ReplaceAll(s, "_", "-")
+}
@@ -0,0 +1,50 @@
+package i18n
+
+import (
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func TestMatch(t *testing.T) {
+	for _, tc := range []struct {
+		prefs []string
+		want  language.Tag
+	}{
+		{nil, language.English},
+		{[]string{""}, language.English},
+		{[]string{"fr"}, language.French},
+		{[]string{"fr_CA.UTF-8"}, language.French},
+		{[]string{"fr_FR@euro"}, language.French},
+		{[]string{"C.UTF-8"}, language.English},
+		{[]string{"POSIX", "fr"}, language.French},
+		{[]string{"de-CH,fr;q=0.8,en;q=0.5"}, language.French},
+		{[]string{"en-US,fr;q=0.5"}, language.English},
+		{[]string{"ja"}, language.English},
+		{[]string{"not a locale!", "fr-BE"}, language.French},
+		{[]string{"", "fr"}, language.French},
+	} {
+		if got := Match(tc.prefs...); got != tc.want {
+			t.Errorf("Match(%q) = %v, want %v", tc.prefs, got, tc.want)
+		}
+	}
+}
+
+func TestEnv(t *testing.T) {
+	env := map[string]string{"LANG": "fr_FR.UTF-8"}
+	getenv := func(k string) string { return env[k] }
+	if got := Env(getenv); got != "fr_FR.UTF-8" {
+		t.Fatalf("Env = %q", got)
+	}
+	env["LC_MESSAGES"] = "C"
+	if got := Env(getenv); got != "C" {
+		t.Fatalf("Env = %q, want $LC_MESSAGES", got)
+	}
+	env["LC_ALL"] = "en_GB"
+	if got := Env(getenv); got != "en_GB" {
+		t.Fatalf("Env = %q, want $LC_ALL", got)
+	}
+	if got := Env(func(string) string { return "" }); got != "" {
+		t.Fatalf("Env of an empty environment = %q", got)
+	}
+}
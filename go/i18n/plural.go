@@ -0,0 +1,37 @@
+package i18n
+
+import "golang.org/x/text/language"
+
+// The plural forms of the catalogs, named as in the Unicode CLDR plural
+// rules. A locale that needs "few" or "many" adds its rule below.
+const (
+	one   = "one"
+	other = "other"
+)
+
+// pluralRules return the plural form a count takes, by language.
+var pluralRules = map[language.Base]func(n int) string{
+	// English: 1 word, 0 words, 2 words.
+	language.MustParseBase("en"): func(n int) string {
+		if n == 1 {
+			return one
+		}
+		return other
+	},
+	// French: 0 mot, 1 mot, 2 mots.
+	language.MustParseBase("fr"): func(n int) string {
+		if n == 0 || n == 1 {
+			return one
+		}
+		return other
+	},
+}
+
+// pluralRule returns the plural rule of tag's language, or English's.
+func pluralRule(tag language.Tag) func(n int) string {
+	base, _ := tag.Base()
+	if r, ok := pluralRules[base]; ok {
+		return r
+	}
+	return pluralRules[language.MustParseBase("en")]
+}
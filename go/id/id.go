@@ -0,0 +1,196 @@
+// Package id generates unique IDs for users, requests and jobs: random
+// UUIDs (version 4), and sortable IDs in the style of ULIDs, which start
+// with their creation time so that IDs sort as strings in the order they
+// were made.
+package id
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// Len is the length of the IDs that New returns.
+const Len = 26
+
+// alphabet is Crockford's base 32, which leaves out I, L, O and U so
+// that IDs cannot be misread or spell words.
+const alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var std Generator
+
+// New returns a sortable ID from the default generator: 26 characters
+// of Crockford's base 32 encoding 48 bits of Unix time in milliseconds
+// followed by 80 random bits, as in "01HQ3K5V9ZJ8X4N2WMRT6PBC7E". IDs
+// made by one process never repeat and always increase, even within a
+// millisecond or when the clock steps back.
+func New() string { return std.New() }
+
+// Generator makes sortable IDs. The zero value is ready to use, and a
+// Generator is safe for concurrent use.
+type Generator struct {
+	// Now returns the time to stamp IDs with; nil means time.Now.
+	Now func() time.Time
+	// Rand is the source of the random bits; nil means crypto/rand.
+	Rand io.Reader
+
+	mu   sync.Mutex
+	last [16]byte // the previous ID, big-endian
+}
+
+// New returns an ID greater than any g made before. Within the
+// millisecond of the previous ID, or before it, the ID is the previous
+// one plus one, so that order is kept without waiting for the clock.
+func (g *Generator) New() string {
+	now := time.Now
+	if g.Now != nil {
+		now = g.Now
+	}
+	r := g.Rand
+	if r == nil {
+		r = rand.Reader
+	}
+	ms := uint64(now().UnixMilli())
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if ms <= timestamp(g.last) && g.last != [16]byte{} {
+		increment(&g.last)
+	} else {
+		var b [16]byte
+		for i := 0; i < 6; i++ {
+			b[i] = byte(ms >> (40 - 8*i))
+		}
+		if _, err := io.ReadFull(r, b[6:]); err != nil {
+			panic(err) // crypto/rand does not fail on supported platforms
+		}
+		g.last = b
+	}
+	return encode(g.last)
+}
+
+// timestamp returns the milliseconds in the first six bytes of b.
+func timestamp(b [16]byte) uint64 {
+	var ms uint64
+	for i := 0; i < 6; i++ {
+		ms = ms<<8 | uint64(b[i])
+	}
+	return ms
+}
+
+// increment adds one to b, carrying from the random bits into the time.
+func increment(b *[16]byte) {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return
+		}
+	}
+}
+
+// encode writes b in base 32, five bits to a character. The 26
+// characters hold 130 bits, so the first one is always 0 to 7.
+func encode(b [16]byte) string {
+	var hi, lo uint64
+	for i := 0; i < 8; i++ {
+		hi = hi<<8 | uint64(b[i])
+		lo = lo<<8 | uint64(b[8+i])
+	}
+	var dst [Len]byte
+	for i := Len - 1; i >= 0; i-- {
+		dst[i] = alphabet[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(dst[:])
+}
+
+// ErrNotSortable is returned by Time for strings that New could not have
+// returned.
+var ErrNotSortable = errors.New("id: not a sortable ID")
+
+// Time returns the time, to the millisecond, at which New made s.
+// Lower-case letters are accepted, as Crockford's encoding allows.
+func Time(s string) (time.Time, error) {
+	if len(s) != Len || s[0] > '7' {
+		return time.Time{}, ErrNotSortable
+	}
+	var ms int64
+	for i := 0; i < len(s); i++ {
+		v := decode(s[i])
+		if v < 0 {
+			return time.Time{}, ErrNotSortable
+		}
+		if i < 10 {
+			ms = ms<<5 | int64(v)
+		}
+	}
+	return time.UnixMilli(ms), nil
+}
+
+// decode returns the value of the base 32 digit c, or -1.
+func decode(c byte) int {
+	if 'a' <= c && c <= 'z' {
+		c -= 'a' - 'A'
+	}
+	for i := 0; i < len(alphabet); i++ {
+		if alphabet[i] == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// NewUUID returns a random UUID (version 4) in its usual form, as in
+// "0f8fad5b-d9cb-469f-a165-70867728950e", for systems that expect one.
+// Unlike those of New, UUIDs do not sort by age.
+func NewUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err) // crypto/rand does not fail on supported platforms
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // the variant of RFC 4122
+	var dst [36]byte
+	hex.Encode(dst[0:8], b[0:4])
+	hex.Encode(dst[9:13], b[4:6])
+	hex.Encode(dst[14:18], b[6:8])
+	hex.Encode(dst[19:23], b[8:10])
+	hex.Encode(dst[24:], b[10:])
+	dst[8], dst[13], dst[18], dst[23] = '-', '-', '-', '-'
+	return string(dst[:])
+}
+
+// MaxLen is the longest ID that Valid accepts.
+const MaxLen = 64
+
+// Valid reports whether s may be used as an ID: 1 to MaxLen ASCII
+// letters, digits, hyphens and underscores. That covers the IDs of New
+// and NewUUID and the decimal IDs of older data, and keeps IDs safe in
+// URL paths, file names and logs.
+func Valid(s string) bool {
+	if s == "" || len(s) > MaxLen {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// Keys is a repo.KeyGen that hands out IDs from New, for repositories
+// keyed by ID. New IDs are unique without looking at the stored ones, so
+// Observe does nothing.
+type Keys struct{}
+
+// Next implements repo.KeyGen.
+func (Keys) Next() string { return New() }
+
+// Observe implements repo.KeyGen.
+func (Keys) Observe(string) {}
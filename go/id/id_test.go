@@ -0,0 +1,137 @@
+package id
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	before := time.Now().Truncate(time.Millisecond)
+	s := New()
+	if len(s) != Len || strings.Trim(s, alphabet) != "" {
+		t.Fatalf("New() = %q", s)
+	}
+	got, err := Time(s)
+	if err != nil || got.Before(before) || got.After(time.Now()) {
+		t.Fatalf("Time(%q) = %v, %v; want about %v", s, got, err, before)
+	}
+}
+
+func TestGeneratorMonotonic(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	g := Generator{Now: func() time.Time { return now }}
+	var ids []string
+	for i := 0; i < 100; i++ {
+		ids = append(ids, g.New())
+		if i == 50 {
+			now = now.Add(-time.Hour) // the clock steps back
+		}
+	}
+	if !sort.StringsAreSorted(ids) {
+		t.Fatalf("IDs not increasing: %v", ids)
+	}
+	for i := 1; i < len(ids); i++ {
+		if ids[i] == ids[i-1] {
+			t.Fatalf("%q made twice", ids[i])
+		}
+	}
+	now = now.Add(2 * time.Hour)
+	later := g.New()
+	if later <= ids[len(ids)-1] {
+		t.Fatalf("%q after the clock moved on is not greater than %q", later, ids[len(ids)-1])
+	}
+	if ts, _ := Time(later); !ts.Equal(now) {
+		t.Fatalf("Time = %v, want %v", ts, now)
+	}
+}
+
+func TestGeneratorCarry(t *testing.T) {
+	ones := bytes.Repeat([]byte{0xff}, 10)
+	g := Generator{Now: func() time.Time { return time.UnixMilli(1) }, Rand: bytes.NewReader(ones)}
+	first := g.New()
+	if first != "0000000001ZZZZZZZZZZZZZZZZ" {
+		t.Fatalf("first = %q", first)
+	}
+	// The random bits are all ones, so the next ID moves to the next
+	// millisecond.
+	next := g.New()
+	if next != "0000000002"+strings.Repeat("0", 16) {
+		t.Fatalf("next = %q", next)
+	}
+}
+
+func TestGeneratorConcurrent(t *testing.T) {
+	var g Generator
+	var mu sync.Mutex
+	seen := map[string]bool{}
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				s := g.New()
+				mu.Lock()
+				if seen[s] {
+					t.Errorf("%q made twice", s)
+				}
+				seen[s] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestTime(t *testing.T) {
+	want := time.UnixMilli(1700000000123)
+	g := Generator{Now: func() time.Time { return want }}
+	s := g.New()
+	for _, in := range []string{s, strings.ToLower(s)} {
+		if got, err := Time(in); err != nil || !got.Equal(want) {
+			t.Errorf("Time(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "42", "8ZZZZZZZZZZZZZZZZZZZZZZZZZ", "01HQ3K5V9ZJ8X4N2WMRT6PBC7U", NewUUID()} {
+		if _, err := Time(bad); !errors.Is(err, ErrNotSortable) {
+			t.Errorf("Time(%q): %v", bad, err)
+		}
+	}
+}
+
+var uuidRE = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNewUUID(t *testing.T) {
+	a, b := NewUUID(), NewUUID()
+	if !uuidRE.MatchString(a) || !uuidRE.MatchString(b) || a == b {
+		t.Fatalf("NewUUID gave %q and %q", a, b)
+	}
+}
+
+func TestValid(t *testing.T) {
+	for _, s := range []string{"1", "42", New(), NewUUID(), "user_7", strings.Repeat("a", MaxLen)} {
+		if !Valid(s) {
+			t.Errorf("Valid(%q) = false", s)
+		}
+	}
+	for _, s := range []string{"", "a b", "a/b", "../x", "é", "a\n", strings.Repeat("a", MaxLen+1)} {
+		if Valid(s) {
+			t.Errorf("Valid(%q) = true", s)
+		}
+	}
+}
+
+func TestKeys(t *testing.T) {
+	var k Keys
+	k.Observe("7")
+	a, b := k.Next(), k.Next()
+	if a == b || !Valid(a) || a > b {
+		t.Fatalf("Next gave %q and %q", a, b)
+	}
+}
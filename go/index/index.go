@@ -0,0 +1,384 @@
+// Package index builds an inverted index over a tree of text files and
+// answers boolean queries against it. Update brings an index up to date
+// with its tree again, reindexing only the files that changed.
+package index
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"example.com/tutorial/bloom"
+	"example.com/tutorial/fileutil"
+	"example.com/tutorial/text"
+	"example.com/tutorial/trie"
+)
+
+// Index maps each word to the documents containing it and the word
+// positions (0-based, in words) at which it occurs. Document names are
+// slash-separated paths relative to the indexed root.
+//
+// An Index is not safe for concurrent mutation.
+type Index struct {
+	opts     []text.Option
+	docs     map[string]int // document -> number of indexed words
+	postings map[string]map[string][]int
+	words    map[string][]string    // document -> the words it has, for Remove
+	files    map[string]Fingerprint // document -> the file it was read from
+	vocab    *trie.Trie             // word -> occurrences in all documents
+	seen     *bloom.Filter
+	seenCap  int // the number of words seen is sized for
+}
+
+// The filter of the words seen is sized for seenWords words to begin
+// with, and rebuilt twice the size of the vocabulary when it outgrows
+// that. At a rate of seenRate, a word the index lacks is still looked
+// up in the postings about once in a hundred times.
+const (
+	seenWords = 1 << 12
+	seenRate  = 0.01
+)
+
+// New returns an empty index. The text options control tokenization of
+// both documents and queries, so the two always match.
+func New(opts ...text.Option) *Index {
+	ix := &Index{opts: opts, docs: map[string]int{}, postings: map[string]map[string][]int{},
+		words: map[string][]string{}, files: map[string]Fingerprint{}, vocab: trie.New()}
+	ix.resetSeen(seenWords)
+	return ix
+}
+
+// Build indexes every regular file below root, reading several files at
+// once.
+func Build(root string, opts ...text.Option) (*Index, error) {
+	return BuildContext(context.Background(), root, opts...)
+}
+
+// BuildContext is like Build but gives up when ctx is done.
+func BuildContext(ctx context.Context, root string, opts ...text.Option) (*Index, error) {
+	ix := New(opts...)
+	if _, err := ix.Update(ctx, root); err != nil {
+		return nil, err
+	}
+	return ix, nil
+}
+
+// AddFile indexes the file at path under the document name doc,
+// remembering its fingerprint for Update.
+func (ix *Index) AddFile(doc, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fp, err := fingerprint(f)
+	if err != nil {
+		return fmt.Errorf("index %s: %w", doc, err)
+	}
+	n, pos, err := ix.scan(doc, f)
+	if err != nil {
+		return err
+	}
+	ix.insert(doc, n, pos)
+	ix.files[doc] = fp
+	return nil
+}
+
+// Add indexes the words read from r under the document name doc,
+// replacing any previous content of doc. With no file to compare, Update
+// reads doc's file again even if it has not changed.
+func (ix *Index) Add(doc string, r io.Reader) error {
+	n, pos, err := ix.scan(doc, r)
+	if err != nil {
+		return err
+	}
+	ix.insert(doc, n, pos)
+	return nil
+}
+
+// scan reads the words of doc from r, returning their count and the
+// positions of each. It does not touch the index, so several documents
+// may be scanned at once.
+func (ix *Index) scan(doc string, r io.Reader) (int, map[string][]int, error) {
+	pos := map[string][]int{}
+	n := 0
+	err := text.ScanWords(r, func(w string) {
+		pos[w] = append(pos[w], n)
+		n++
+	}, ix.opts...)
+	if err != nil {
+		return 0, nil, fmt.Errorf("index %s: %w", doc, err)
+	}
+	return n, pos, nil
+}
+
+// insert replaces doc's postings with the result of scan.
+func (ix *Index) insert(doc string, n int, pos map[string][]int) {
+	ix.Remove(doc)
+	ix.docs[doc] = n
+	words := make([]string, 0, len(pos))
+	for w, p := range pos {
+		words = append(words, w)
+		if ix.postings[w] == nil {
+			ix.postings[w] = map[string][]int{}
+		}
+		ix.postings[w][doc] = p
+		ix.vocab.Add(w, len(p))
+		ix.remember(w)
+	}
+	ix.words[doc] = words
+}
+
+// remember adds w to the filter of the words seen, rebuilding it larger
+// once it holds more words than it was sized for.
+func (ix *Index) remember(w string) {
+	if ix.seen.MayContain(w) {
+		return
+	}
+	ix.seen.Add(w)
+	if ix.seen.Count() > ix.seenCap {
+		ix.resetSeen(2 * max(ix.seenCap, ix.vocab.Len()))
+	}
+}
+
+// resetSeen replaces the filter of the words seen with one sized for
+// capacity words holding the vocabulary, which also forgets the words of
+// removed documents.
+func (ix *Index) resetSeen(capacity int) {
+	ix.seen, ix.seenCap = bloom.New(capacity, seenRate), capacity
+	ix.vocab.Walk("", func(w string, _ int) bool {
+		ix.seen.Add(w)
+		return true
+	})
+}
+
+// MayContain reports whether term may occur in the index. False means no
+// document has it, found without looking at the postings: the index
+// keeps a Bloom filter of the words it has seen.
+func (ix *Index) MayContain(term string) bool {
+	w := ix.normalize(term)
+	return w != "" && ix.seen.MayContain(w)
+}
+
+// Remove drops doc from the index, touching only the postings of its
+// words.
+func (ix *Index) Remove(doc string) {
+	if _, ok := ix.docs[doc]; !ok {
+		return
+	}
+	for _, w := range ix.words[doc] {
+		docs := ix.postings[w]
+		ix.vocab.Add(w, -len(docs[doc]))
+		delete(docs, doc)
+		if len(docs) == 0 {
+			delete(ix.postings, w)
+		}
+	}
+	delete(ix.docs, doc)
+	delete(ix.words, doc)
+	delete(ix.files, doc)
+}
+
+// Docs returns the indexed document names in sorted order.
+func (ix *Index) Docs() []string {
+	names := make([]string, 0, len(ix.docs))
+	for d := range ix.docs {
+		names = append(names, d)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Positions returns the word positions of term in doc.
+func (ix *Index) Positions(term, doc string) []int {
+	w := ix.normalize(term)
+	if w == "" || !ix.seen.MayContain(w) {
+		return nil
+	}
+	return ix.postings[w][doc]
+}
+
+// normalize maps a query term to the form stored in the index, or "" if
+// the tokenizer options drop it entirely.
+func (ix *Index) normalize(term string) string {
+	words := text.Words(term, ix.opts...)
+	if len(words) == 0 {
+		return ""
+	}
+	return words[0]
+}
+
+// lookup returns the set of documents containing term, or for a term
+// ending in '*', any word starting with the rest of it.
+func (ix *Index) lookup(term string) map[string]bool {
+	set := map[string]bool{}
+	if prefix, ok := strings.CutSuffix(term, "*"); ok && prefix != "" {
+		if prefix = ix.normalize(prefix); prefix == "" {
+			return set
+		}
+		ix.vocab.Walk(prefix, func(w string, _ int) bool {
+			for d := range ix.postings[w] {
+				set[d] = true
+			}
+			return true
+		})
+		return set
+	}
+	w := ix.normalize(term)
+	if !ix.seen.MayContain(w) {
+		return set
+	}
+	for d := range ix.postings[w] {
+		set[d] = true
+	}
+	return set
+}
+
+// Complete returns the n indexed words most often found that start with
+// prefix, as text.TopWords ranks them; a negative n returns all of
+// them. The prefix is matched as given, so it should be in the form the
+// index stores words in, lower case unless the tokenizer keeps case.
+func (ix *Index) Complete(prefix string, n int) []text.WordFreq {
+	counts := map[string]int{}
+	ix.vocab.Walk(prefix, func(w string, c int) bool {
+		counts[w] = c
+		return true
+	})
+	return text.TopWords(counts, n)
+}
+
+// countVocabulary rebuilds the vocabulary, the words of each document
+// and the filter of the words seen from the postings, after they were
+// loaded as a whole.
+func (ix *Index) countVocabulary() {
+	ix.vocab = trie.New()
+	ix.words = map[string][]string{}
+	for w, docs := range ix.postings {
+		for d, p := range docs {
+			ix.vocab.Add(w, len(p))
+			ix.words[d] = append(ix.words[d], w)
+		}
+	}
+	ix.resetSeen(max(seenWords, 2*ix.vocab.Len()))
+}
+
+// And returns the documents containing every term, sorted.
+func (ix *Index) And(terms ...string) []string {
+	if len(terms) == 0 {
+		return nil
+	}
+	set := ix.lookup(terms[0])
+	for _, t := range terms[1:] {
+		set = intersect(set, ix.lookup(t))
+	}
+	return sorted(set)
+}
+
+// Or returns the documents containing any of the terms, sorted.
+func (ix *Index) Or(terms ...string) []string {
+	set := map[string]bool{}
+	for _, t := range terms {
+		for d := range ix.lookup(t) {
+			set[d] = true
+		}
+	}
+	return sorted(set)
+}
+
+func intersect(a, b map[string]bool) map[string]bool {
+	out := map[string]bool{}
+	for d := range a {
+		if b[d] {
+			out[d] = true
+		}
+	}
+	return out
+}
+
+func sorted(set map[string]bool) []string {
+	if len(set) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(set))
+	for d := range set {
+		out = append(out, d)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// fileFormat is the on-disk representation written by Save.
+type fileFormat struct {
+	Version  int                         `json:"version"`
+	Docs     map[string]int              `json:"docs"`
+	Postings map[string]map[string][]int `json:"postings"`
+	Files    map[string]Fingerprint      `json:"files,omitempty"`
+}
+
+// formatVersion is the version Save and Snapshot write. Version 1 had
+// no fingerprints, so the first Update after loading it reads every
+// file again.
+const formatVersion = 2
+
+// supportedVersion reports whether Load and Restore read version v.
+func supportedVersion(v int) bool { return v == 1 || v == formatVersion }
+
+// Save writes the index to w as JSON. Tokenizer options are not saved;
+// pass the same ones to Load.
+func (ix *Index) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(fileFormat{
+		Version:  formatVersion,
+		Docs:     ix.docs,
+		Postings: ix.postings,
+		Files:    ix.files,
+	})
+}
+
+// Load reads an index written by Save.
+func Load(r io.Reader, opts ...text.Option) (*Index, error) {
+	var f fileFormat
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("load index: %w", err)
+	}
+	if !supportedVersion(f.Version) {
+		return nil, fmt.Errorf("load index: unsupported version %d", f.Version)
+	}
+	ix := New(opts...)
+	if f.Docs != nil {
+		ix.docs = f.Docs
+	}
+	if f.Postings != nil {
+		ix.postings = f.Postings
+	}
+	if f.Files != nil {
+		ix.files = f.Files
+	}
+	ix.countVocabulary()
+	return ix, nil
+}
+
+// SaveFile writes the index to the file at path with
+// fileutil.WriteChecksummed, so a crash never leaves a partial index and
+// LoadFile notices later damage.
+func (ix *Index) SaveFile(path string) error {
+	var buf bytes.Buffer
+	if err := ix.Save(&buf); err != nil {
+		return err
+	}
+	return fileutil.WriteChecksummed(path, buf.Bytes())
+}
+
+// LoadFile reads an index saved by SaveFile, checking it against its
+// checksum.
+func LoadFile(path string, opts ...text.Option) (*Index, error) {
+	data, err := fileutil.ReadChecksummed(path)
+	if err != nil {
+		return nil, err
+	}
+	return Load(bytes.NewReader(data), opts...)
+}
@@ -0,0 +1,221 @@
+package index
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"example.com/tutorial/fileutil"
+	"example.com/tutorial/text"
+)
+
+func buildTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"go.txt":         "Go has goroutines. Go has channels.",
+		"rust.txt":       "Rust has ownership and lifetimes.",
+		"sub/both.txt":   "Go and Rust both have generics.",
+		"sub/deep/x.txt": "nothing relevant here",
+	}
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestBuildAndQuery(t *testing.T) {
+	ix, err := Build(buildTree(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ix.Docs(); !reflect.DeepEqual(got, []string{"go.txt", "rust.txt", "sub/both.txt", "sub/deep/x.txt"}) {
+		t.Fatalf("Docs = %v", got)
+	}
+	if got := ix.And("go", "rust"); !reflect.DeepEqual(got, []string{"sub/both.txt"}) {
+		t.Fatalf("And = %v", got)
+	}
+	if got := ix.Or("goroutines", "ownership"); !reflect.DeepEqual(got, []string{"go.txt", "rust.txt"}) {
+		t.Fatalf("Or = %v", got)
+	}
+	if got := ix.And("go", "missing"); got != nil {
+		t.Fatalf("And with missing term = %v", got)
+	}
+	if got := ix.Positions("Go", "go.txt"); !reflect.DeepEqual(got, []int{0, 3}) {
+		t.Fatalf("Positions = %v", got)
+	}
+}
+
+func TestBuildContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := BuildContext(ctx, buildTree(t)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("BuildContext = %v, want context.Canceled", err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	ix, err := Build(buildTree(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"go", []string{"go.txt", "sub/both.txt"}},
+		{"go rust", []string{"sub/both.txt"}},
+		{"go AND rust", []string{"sub/both.txt"}},
+		{"goroutines OR lifetimes", []string{"go.txt", "rust.txt"}},
+		{"go AND channels OR rust AND generics", []string{"go.txt", "sub/both.txt"}},
+		{"GO", []string{"go.txt", "sub/both.txt"}},
+		{"gor*", []string{"go.txt"}},
+		{"G*", []string{"go.txt", "sub/both.txt"}},
+		{"own* OR gen*", []string{"rust.txt", "sub/both.txt"}},
+		{"go* lifetimes", nil},
+		{"zz*", nil},
+		{"*", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got, err := ix.Search(tt.query)
+		if err != nil {
+			t.Fatalf("Search(%q): %v", tt.query, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+	for _, bad := range []string{"AND go", "go OR", "go AND OR rust"} {
+		if _, err := ix.Search(bad); err == nil {
+			t.Errorf("Search(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestAddReplacesAndRemove(t *testing.T) {
+	ix := New()
+	ix.Add("a", strings.NewReader("alpha beta"))
+	ix.Add("a", strings.NewReader("gamma"))
+	if got := ix.Or("alpha", "beta"); got != nil {
+		t.Fatalf("stale postings: %v", got)
+	}
+	ix.Remove("a")
+	if len(ix.Docs()) != 0 || len(ix.postings) != 0 {
+		t.Fatalf("index not empty after Remove: %v %v", ix.docs, ix.postings)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	ix, err := Build(buildTree(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []text.WordFreq{{Word: "go", Count: 3}, {Word: "generics", Count: 1}, {Word: "goroutines", Count: 1}}
+	if got := ix.Complete("g", -1); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Complete(g) = %v", got)
+	}
+	if got := ix.Complete("ha", 1); !reflect.DeepEqual(got, []text.WordFreq{{Word: "has", Count: 3}}) {
+		t.Fatalf("Complete(ha, 1) = %v", got)
+	}
+	if got := ix.Complete("zz", 5); len(got) != 0 {
+		t.Fatalf("Complete(zz) = %v", got)
+	}
+
+	// Replacing and removing documents takes their words out.
+	ix.Remove("go.txt")
+	ix.Add("sub/both.txt", strings.NewReader("gophers"))
+	if got := ix.Complete("g", -1); !reflect.DeepEqual(got, []text.WordFreq{{Word: "gophers", Count: 1}}) {
+		t.Fatalf("Complete(g) after changes = %v", got)
+	}
+	ix.Remove("sub/both.txt")
+	ix.Remove("rust.txt")
+	ix.Remove("sub/deep/x.txt")
+	if got := ix.Complete("", -1); len(got) != 0 || ix.vocab.Len() != 0 {
+		t.Fatalf("empty index completes %v", got)
+	}
+}
+
+func TestMayContain(t *testing.T) {
+	ix, err := Build(buildTree(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range []string{"Go", "goroutines", "lifetimes"} {
+		if !ix.MayContain(w) {
+			t.Errorf("MayContain(%q) = false for an indexed word", w)
+		}
+	}
+	if ix.MayContain("zebra") || ix.MayContain("") {
+		t.Fatal("MayContain of words never indexed")
+	}
+
+	// The filter grows with the vocabulary and never loses a word.
+	var b strings.Builder
+	for i := 0; i < 3*seenWords; i++ {
+		fmt.Fprintf(&b, "w%d ", i)
+	}
+	ix.Add("many.txt", strings.NewReader(b.String()))
+	if ix.seenCap < 3*seenWords {
+		t.Fatalf("filter sized for %d words after adding %d", ix.seenCap, 3*seenWords)
+	}
+	for i := 0; i < 3*seenWords; i++ {
+		if w := fmt.Sprintf("w%d", i); !ix.MayContain(w) {
+			t.Fatalf("lost %s", w)
+		}
+	}
+	if got := ix.Or("w7", "channels"); !reflect.DeepEqual(got, []string{"go.txt", "many.txt"}) {
+		t.Fatalf("Or = %v", got)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	opts := []text.Option{text.WithNormalizers(text.PorterStemmer)}
+	ix, err := Build(buildTree(t), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "index.json")
+	if err := ix.SaveFile(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadFile(path, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.docs, ix.docs) || !reflect.DeepEqual(loaded.postings, ix.postings) {
+		t.Fatal("loaded index differs from saved one")
+	}
+	if got, want := loaded.Complete("", -1), ix.Complete("", -1); !reflect.DeepEqual(got, want) {
+		t.Fatalf("loaded vocabulary %v, want %v", got, want)
+	}
+	if !loaded.MayContain("rust") {
+		t.Fatal("loaded index forgot the words seen")
+	}
+	// Query terms are stemmed with the same options.
+	if got := loaded.Or("channel"); !reflect.DeepEqual(got, []string{"go.txt"}) {
+		t.Fatalf("Or(channel) = %v", got)
+	}
+	if _, err := Load(bytes.NewReader([]byte(`{"version":99}`))); err == nil {
+		t.Fatal("expected error for unknown version")
+	}
+
+	// A damaged file is refused rather than loaded.
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, bytes.Replace(data, []byte(`"go.txt"`), []byte(`"GO.txt"`), 1), 0o644)
+	if _, err := LoadFile(path, opts...); !errors.Is(err, fileutil.ErrChecksum) {
+		t.Fatalf("damaged file: %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package index
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Search evaluates a boolean query and returns the matching documents in
+// sorted order. A query is a list of terms joined by the operators AND
+// and OR, which must be written in upper case; AND binds tighter than OR
+// and adjacent terms are implicitly ANDed. A term ending in '*' matches
+// any word starting with the rest of it:
+//
+//	gopher rust            documents containing both words
+//	gopher OR rust         documents containing either
+//	go AND concurrency OR rust AND ownership
+//	goroutine* channel     "goroutine" or "goroutines", and "channel"
+func (ix *Index) Search(query string) ([]string, error) {
+	var groups [][]string // OR of ANDs
+	var cur []string
+	expectTerm := true
+	for _, f := range strings.Fields(query) {
+		switch f {
+		case "AND":
+			if expectTerm {
+				return nil, fmt.Errorf("search %q: unexpected AND", query)
+			}
+			expectTerm = true
+		case "OR":
+			if expectTerm {
+				return nil, fmt.Errorf("search %q: unexpected OR", query)
+			}
+			groups = append(groups, cur)
+			cur = nil
+			expectTerm = true
+		default:
+			cur = append(cur, f)
+			expectTerm = false
+		}
+	}
+	if expectTerm && (len(groups) > 0 || len(cur) > 0) {
+		return nil, fmt.Errorf("search %q: missing term after operator", query)
+	}
+	if len(cur) > 0 {
+		groups = append(groups, cur)
+	}
+
+	set := map[string]bool{}
+	for _, g := range groups {
+		for _, d := range ix.And(g...) {
+			set[d] = true
+		}
+	}
+	return sorted(set), nil
+}
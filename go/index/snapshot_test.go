@@ -0,0 +1,62 @@
+package index
+
+import (
+	"bytes"
+	"encoding/gob"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	ix, err := Build(buildTree(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := ix.Snapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	restored := New()
+	restored.Add("stale.txt", strings.NewReader("old words"))
+	if err := restored.Restore(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(restored.docs, ix.docs) || !reflect.DeepEqual(restored.postings, ix.postings) {
+		t.Fatal("restored index differs from the snapshot")
+	}
+	if got, want := restored.Complete("", -1), ix.Complete("", -1); !reflect.DeepEqual(got, want) {
+		t.Fatalf("restored vocabulary %v, want %v", got, want)
+	}
+	if got := restored.And("go", "rust"); !reflect.DeepEqual(got, []string{"sub/both.txt"}) {
+		t.Fatalf("And(go, rust) = %v", got)
+	}
+
+	// An empty index round-trips to one that can still be added to.
+	buf.Reset()
+	if err := New().Snapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.Restore(&buf); err != nil || len(restored.Docs()) != 0 {
+		t.Fatalf("restore empty: %v, docs %v", err, restored.Docs())
+	}
+	restored.Add("a", strings.NewReader("alpha"))
+	if got := restored.Or("alpha"); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Fatalf("Or(alpha) after restore = %v", got)
+	}
+}
+
+func TestRestoreRejects(t *testing.T) {
+	ix := New()
+	ix.Add("a", strings.NewReader("alpha"))
+	var buf bytes.Buffer
+	gob.NewEncoder(&buf).Encode(snapshot{Version: 99})
+	for name, data := range map[string][]byte{"version": buf.Bytes(), "garbage": []byte("not gob")} {
+		if err := ix.Restore(bytes.NewReader(data)); err == nil {
+			t.Errorf("%s: restored", name)
+		}
+	}
+	if got := ix.Docs(); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("failed restores changed the index: %v", got)
+	}
+}
@@ -0,0 +1,187 @@
+package index
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"example.com/tutorial/fsutil"
+)
+
+// Fingerprint identifies the contents of an indexed file, so that Update
+// can tell whether it changed. The size and modification time are
+// compared first, as make and git do, and only when they differ is the
+// file read to compare its SHA-256; a file touched but not changed is
+// then not reindexed.
+type Fingerprint struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	SHA256  string    `json:"sha256"`
+}
+
+// fingerprint returns the Fingerprint of f, reading it to the end, and
+// leaves f at its start again.
+func fingerprint(f *os.File) (Fingerprint, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return Fingerprint{}, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return Fingerprint{}, err
+	}
+	return Fingerprint{Size: info.Size(), ModTime: info.ModTime(), SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// sameStat reports whether info has the size and modification time of
+// fp.
+func (fp Fingerprint) sameStat(info fs.FileInfo) bool {
+	return fp.Size == info.Size() && fp.ModTime.Equal(info.ModTime())
+}
+
+// Changes are what Diff found between an index and its tree. Apply
+// makes them; the lists say what they were, in sorted order.
+type Changes struct {
+	Added     []string // files not in the index
+	Modified  []string // indexed files whose contents changed
+	Removed   []string // indexed documents whose files are gone
+	Unchanged int      // files whose contents are as indexed
+
+	scans   map[string]docScan     // the new contents of Added and Modified
+	touched map[string]Fingerprint // unchanged files with a new time
+}
+
+// docScan is a file read for Apply to insert.
+type docScan struct {
+	n   int
+	pos map[string][]int
+	fp  Fingerprint
+}
+
+// Empty reports whether c changes nothing in the index.
+func (c *Changes) Empty() bool {
+	return len(c.Added) == 0 && len(c.Modified) == 0 && len(c.Removed) == 0 && len(c.touched) == 0
+}
+
+// Update brings the index up to date with the regular files below root,
+// as Build would index them, reading only the files whose fingerprints
+// changed and touching only the postings of the documents added,
+// modified or removed. It returns what changed; on error the index is
+// left as it was.
+func (ix *Index) Update(ctx context.Context, root string) (*Changes, error) {
+	c, err := ix.Diff(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	ix.Apply(c)
+	return c, nil
+}
+
+// Diff is the first half of Update: it compares the index with the
+// files below root, several at once, and reads the new and changed
+// ones, without changing the index. Only reading it, Diff may run
+// alongside searches, leaving only Apply to exclude them.
+func (ix *Index) Diff(ctx context.Context, root string) (*Changes, error) {
+	c := &Changes{scans: map[string]docScan{}, touched: map[string]Fingerprint{}}
+	seen := map[string]bool{}
+	var mu sync.Mutex
+	err := fsutil.WalkConcurrent(ctx, root, func(_ context.Context, path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		doc := filepath.ToSlash(rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		old, known := ix.files[doc]
+		_, indexed := ix.docs[doc]
+		mu.Lock()
+		seen[doc] = true
+		mu.Unlock()
+		if known && old.sameStat(info) {
+			mu.Lock()
+			c.Unchanged++
+			mu.Unlock()
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		fp, err := fingerprint(f)
+		if err != nil {
+			return fmt.Errorf("index %s: %w", doc, err)
+		}
+		if known && fp.SHA256 == old.SHA256 {
+			mu.Lock()
+			defer mu.Unlock()
+			c.Unchanged++
+			c.touched[doc] = fp
+			return nil
+		}
+		n, pos, err := ix.scan(doc, f)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		c.scans[doc] = docScan{n, pos, fp}
+		if indexed {
+			c.Modified = append(c.Modified, doc)
+		} else {
+			c.Added = append(c.Added, doc)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for doc := range ix.docs {
+		if !seen[doc] {
+			c.Removed = append(c.Removed, doc)
+		}
+	}
+	sort.Strings(c.Added)
+	sort.Strings(c.Modified)
+	sort.Strings(c.Removed)
+	return c, nil
+}
+
+// Apply makes the changes Diff found. If the index changed in between,
+// as by AddFile, the changes still apply, and the next Update sets
+// right whatever they got wrong.
+func (ix *Index) Apply(c *Changes) {
+	for _, doc := range c.Removed {
+		ix.Remove(doc)
+	}
+	for doc, s := range c.scans {
+		ix.insert(doc, s.n, s.pos)
+		ix.files[doc] = s.fp
+	}
+	for doc, fp := range c.touched {
+		if _, ok := ix.docs[doc]; ok {
+			ix.files[doc] = fp
+		}
+	}
+}
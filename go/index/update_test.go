@@ -0,0 +1,150 @@
+package index
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// changes summarizes c for comparing in tests.
+func changes(c *Changes) string {
+	return strings.Join([]string{
+		"+" + strings.Join(c.Added, ","),
+		"~" + strings.Join(c.Modified, ","),
+		"-" + strings.Join(c.Removed, ","),
+	}, " ")
+}
+
+func TestUpdate(t *testing.T) {
+	root := buildTree(t)
+	ctx := context.Background()
+	ix, err := Build(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := ix.Update(ctx, root)
+	if err != nil || !c.Empty() || c.Unchanged != 4 {
+		t.Fatalf("update of an unchanged tree: %+v, %v", c, err)
+	}
+
+	write := func(name, content string, mtime time.Time) {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(p, mtime, mtime)
+	}
+	later := time.Now().Add(time.Hour)
+	write("go.txt", "Go has goroutines and Python.", later)
+	write("new.txt", "Python is new here", later)
+	os.Remove(filepath.Join(root, "rust.txt"))
+	// Touched but not changed: read, not reindexed.
+	os.Chtimes(filepath.Join(root, "sub/deep/x.txt"), later, later)
+
+	c, err = ix.Update(ctx, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := changes(c); got != "+new.txt ~go.txt -rust.txt" || c.Unchanged != 2 {
+		t.Fatalf("changes %s, %d unchanged", got, c.Unchanged)
+	}
+	if got := ix.Or("python"); !reflect.DeepEqual(got, []string{"go.txt", "new.txt"}) {
+		t.Errorf("Or(python) = %v", got)
+	}
+	if got := ix.Or("channels", "ownership"); got != nil {
+		t.Errorf("words of the old files still found in %v", got)
+	}
+
+	// The result is what a full build gives.
+	fresh, err := Build(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ix.docs, fresh.docs) || !reflect.DeepEqual(ix.postings, fresh.postings) {
+		t.Fatalf("updated index differs from a rebuilt one:\n%v\n%v", ix.postings, fresh.postings)
+	}
+	if got, want := ix.Complete("", -1), fresh.Complete("", -1); !reflect.DeepEqual(got, want) {
+		t.Fatalf("vocabulary %v, want %v", got, want)
+	}
+
+	// The touched file's new time was kept, so it is not read again.
+	if c, err := ix.Update(ctx, root); err != nil || !c.Empty() || c.Unchanged != 4 {
+		t.Fatalf("second update: %+v, %v", c, err)
+	}
+}
+
+func TestUpdateAfterAdd(t *testing.T) {
+	root := buildTree(t)
+	ix := New()
+	ix.Add("go.txt", strings.NewReader("stale"))
+	ix.Add("gone.txt", strings.NewReader("gone"))
+	if err := ix.AddFile("rust.txt", filepath.Join(root, "rust.txt")); err != nil {
+		t.Fatal(err)
+	}
+	c, err := ix.Update(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Documents added from readers have no fingerprint to compare.
+	if got := changes(c); got != "+sub/both.txt,sub/deep/x.txt ~go.txt -gone.txt" || c.Unchanged != 1 {
+		t.Fatalf("changes %s, %d unchanged", got, c.Unchanged)
+	}
+	if got := ix.Or("stale", "gone"); got != nil {
+		t.Errorf("Or(stale, gone) = %v", got)
+	}
+}
+
+func TestUpdateError(t *testing.T) {
+	root := buildTree(t)
+	ix, _ := Build(root)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	os.Remove(filepath.Join(root, "go.txt"))
+	if _, err := ix.Update(ctx, root); err == nil {
+		t.Fatal("no error from a canceled update")
+	}
+	if got := ix.Docs(); len(got) != 4 {
+		t.Errorf("failed update changed the index: %v", got)
+	}
+}
+
+func TestFingerprintsSaved(t *testing.T) {
+	root := buildTree(t)
+	ix, _ := Build(root)
+	var buf bytes.Buffer
+	ix.Save(&buf)
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, err := loaded.Update(context.Background(), root); err != nil || !c.Empty() || c.Unchanged != 4 {
+		t.Fatalf("update after Load: %+v, %v", c, err)
+	}
+	buf.Reset()
+	ix.Snapshot(&buf)
+	restored := New()
+	if err := restored.Restore(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if c, err := restored.Update(context.Background(), root); err != nil || !c.Empty() {
+		t.Fatalf("update after Restore: %+v, %v", c, err)
+	}
+
+	// An index saved before fingerprints reads every file again.
+	old, err := Load(strings.NewReader(`{"version":1,"docs":{"go.txt":1},"postings":{"old":{"go.txt":[0]}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := old.Update(context.Background(), root)
+	if err != nil || changes(c) != "+rust.txt,sub/both.txt,sub/deep/x.txt ~go.txt -" {
+		t.Fatalf("update of a version 1 index: %+v, %v", c, err)
+	}
+	if got := old.Or("old"); got != nil {
+		t.Errorf("Or(old) = %v", got)
+	}
+}
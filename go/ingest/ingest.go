@@ -0,0 +1,347 @@
+// Package ingest turns documents of the formats a corpus is made of
+// into the plain text the counting code reads: Markdown loses its
+// syntax, CSV and JSON give up the text of their fields and XML that of
+// its elements. Formats are told by file name or, failing that, sniffed
+// from the first bytes.
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Format is a document format.
+type Format int
+
+const (
+	Text Format = iota
+	Markdown
+	CSV
+	JSON
+	XML
+)
+
+func (f Format) String() string {
+	switch f {
+	case Text:
+		return "text"
+	case Markdown:
+		return "markdown"
+	case CSV:
+		return "csv"
+	case JSON:
+		return "json"
+	case XML:
+		return "xml"
+	}
+	return "unknown"
+}
+
+// SniffLen is how many leading bytes Sniff looks at.
+const SniffLen = 512
+
+// ErrNoColumn is wrapped by the error for a CSV document lacking a
+// column selected by Options.Columns.
+var ErrNoColumn = errors.New("ingest: no such column")
+
+// Options tunes the extraction.
+type Options struct {
+	// Columns, if not empty, extracts only the named columns of CSV
+	// documents, whose first record is then taken as the header naming
+	// them and not extracted. Names match case-insensitively. Otherwise
+	// every field of every record is extracted.
+	Columns []string
+	// Fields, if not empty, extracts from JSON documents only the
+	// strings that are the values of the named object keys, or inside
+	// arrays that are. Otherwise every string value is extracted.
+	Fields []string
+	// Elements, if not empty, extracts from XML documents only the text
+	// inside the elements of these local names, such as "body" for the
+	// articles of a feed. Names match case-insensitively. Otherwise all
+	// the text is extracted.
+	Elements []string
+}
+
+var extensions = map[string]Format{
+	".txt": Text, ".text": Text,
+	".md": Markdown, ".markdown": Markdown,
+	".csv":  CSV,
+	".json": JSON, ".jsonl": JSON, ".ndjson": JSON,
+	".xml": XML,
+}
+
+// FormatOf returns the format a file name's extension stands for.
+func FormatOf(name string) (Format, bool) {
+	f, ok := extensions[strings.ToLower(filepath.Ext(name))]
+	return f, ok
+}
+
+// ForMediaType returns the format of a MIME media type without
+// parameters, such as "text/markdown".
+func ForMediaType(mt string) (Format, bool) {
+	switch mt {
+	case "text/plain":
+		return Text, true
+	case "text/markdown", "text/x-markdown":
+		return Markdown, true
+	case "text/csv":
+		return CSV, true
+	case "application/json", "application/x-ndjson":
+		return JSON, true
+	case "application/xml", "text/xml":
+		return XML, true
+	}
+	return Text, false
+}
+
+// Sniff guesses the format of a document from its first bytes: JSON if
+// it starts with an object or array, XML if with an XML declaration,
+// Markdown if it has headings, fences or links, CSV if its first lines
+// have the same number of commas.
+func Sniff(head []byte) Format {
+	head = bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+	trimmed := bytes.TrimLeft(head, " \t\r\n")
+	if looksLikeJSONPrefix(trimmed) {
+		return JSON
+	}
+	if bytes.HasPrefix(trimmed, []byte("<?xml")) {
+		return XML
+	}
+	lines := strings.Split(string(head), "\n")
+	if len(lines) > 1 && len(head) == SniffLen {
+		lines = lines[:len(lines)-1] // the last one may be cut short
+	}
+	commas, consistent := -1, len(lines) > 1
+	for _, l := range lines {
+		l = strings.TrimSpace(l)
+		if strings.HasPrefix(l, "# ") || strings.HasPrefix(l, "## ") || strings.HasPrefix(l, "```") || strings.Contains(l, "](") {
+			return Markdown
+		}
+		if l == "" {
+			continue
+		}
+		n := strings.Count(l, ",")
+		if commas == -1 {
+			commas = n
+		} else if n != commas {
+			consistent = false
+		}
+	}
+	if consistent && commas > 0 {
+		return CSV
+	}
+	return Text
+}
+
+// looksLikeJSONPrefix reports whether head, possibly cut short, opens a
+// JSON object with a quoted key or an array.
+func looksLikeJSONPrefix(head []byte) bool {
+	if len(head) == 0 {
+		return false
+	}
+	rest := bytes.TrimLeft(head[1:], " \t\r\n")
+	switch head[0] {
+	case '{':
+		return len(rest) > 0 && (rest[0] == '"' || rest[0] == '}')
+	case '[':
+		return len(rest) > 0 && bytes.IndexByte([]byte(`[]{"-0123456789tfn`), rest[0]) >= 0
+	}
+	return false
+}
+
+// Detect returns the format of the document called name that starts
+// with head: by its extension if known, else by Sniff.
+func Detect(name string, head []byte) Format {
+	if f, ok := FormatOf(name); ok {
+		return f
+	}
+	return Sniff(head)
+}
+
+// NewReader returns the plain text of the document of format f read from
+// r. Errors in the document surface from Read. Close the reader if it is
+// abandoned before the end.
+func NewReader(r io.Reader, f Format, opts Options) io.ReadCloser {
+	var extract func(w io.Writer, r io.Reader, opts Options) error
+	switch f {
+	case Markdown:
+		extract = extractMarkdown
+	case CSV:
+		extract = extractCSV
+	case JSON:
+		extract = extractJSON
+	case XML:
+		extract = extractXML
+	default:
+		return io.NopCloser(r)
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		bw := bufio.NewWriter(pw)
+		err := extract(bw, r, opts)
+		if err == nil {
+			err = bw.Flush()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
+
+// Open opens the file at path and returns its plain text and format,
+// detected as by Detect.
+func Open(path string, opts Options) (io.ReadCloser, Format, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, Text, err
+	}
+	br := bufio.NewReaderSize(f, SniffLen)
+	head, err := br.Peek(SniffLen)
+	if err != nil && err != io.EOF {
+		f.Close()
+		return nil, Text, err
+	}
+	format := Detect(path, head)
+	return &fileReader{NewReader(br, format, opts), f}, format, nil
+}
+
+// fileReader closes both the extraction and the file beneath it.
+type fileReader struct {
+	io.ReadCloser
+	f *os.File
+}
+
+func (r *fileReader) Close() error {
+	r.ReadCloser.Close()
+	return r.f.Close()
+}
+
+func extractCSV(w io.Writer, r io.Reader, opts Options) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.ReuseRecord = true
+	var keep []int
+	if len(opts.Columns) > 0 {
+		header, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("ingest: csv: %w", err)
+		}
+		for _, col := range opts.Columns {
+			i := indexFold(header, col)
+			if i < 0 {
+				return fmt.Errorf("%w: %q", ErrNoColumn, col)
+			}
+			keep = append(keep, i)
+		}
+	}
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("ingest: csv: %w", err)
+		}
+		if keep == nil {
+			if _, err := io.WriteString(w, strings.Join(rec, " ")+"\n"); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, i := range keep {
+			if i < len(rec) {
+				if _, err := io.WriteString(w, rec[i]+"\n"); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func indexFold(list []string, s string) int {
+	for i, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), s) {
+			return i
+		}
+	}
+	return -1
+}
+
+// jsonFrame is an object or array being decoded.
+type jsonFrame struct {
+	object    bool
+	expectKey bool   // for an object: the next string is a key
+	key       string // for an object: the key of the current value
+}
+
+func extractJSON(w io.Writer, r io.Reader, opts Options) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var stack []jsonFrame
+	// wanted reports whether a string value at this point is extracted.
+	wanted := func() bool {
+		if len(opts.Fields) == 0 {
+			return true
+		}
+		for i := len(stack) - 1; i >= 0; i-- {
+			if stack[i].object {
+				return indexFold(opts.Fields, stack[i].key) >= 0
+			}
+		}
+		return false
+	}
+	// valueDone moves an enclosing object on to its next key.
+	valueDone := func() {
+		if n := len(stack); n > 0 && stack[n-1].object {
+			stack[n-1].expectKey = true
+		}
+	}
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			if len(stack) > 0 {
+				return fmt.Errorf("ingest: json: %w", io.ErrUnexpectedEOF)
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("ingest: json: %w", err)
+		}
+		switch t := tok.(type) {
+		case json.Delim:
+			switch t {
+			case '{':
+				stack = append(stack, jsonFrame{object: true, expectKey: true})
+			case '[':
+				stack = append(stack, jsonFrame{})
+			default:
+				stack = stack[:len(stack)-1]
+				valueDone()
+			}
+		case string:
+			if n := len(stack); n > 0 && stack[n-1].object && stack[n-1].expectKey {
+				stack[n-1].key, stack[n-1].expectKey = t, false
+				continue
+			}
+			if wanted() {
+				if _, err := io.WriteString(w, t+"\n"); err != nil {
+					return err
+				}
+			}
+			valueDone()
+		default:
+			valueDone()
+		}
+	}
+}
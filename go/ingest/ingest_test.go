@@ -0,0 +1,164 @@
+package ingest
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func extract(t *testing.T, in string, f Format, opts Options) (string, error) {
+	t.Helper()
+	r := NewReader(strings.NewReader(in), f, opts)
+	defer r.Close()
+	out, err := io.ReadAll(r)
+	return string(out), err
+}
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		head string
+		want Format
+	}{
+		{`{"title": "a"}`, JSON},
+		{"  [1, 2", JSON},
+		{"\xef\xbb\xbf{\"a\": 1}\n{\"a\": 2}\n", JSON},
+		{"# Title\n\nSome prose.\n", Markdown},
+		{"See [the docs](http://example.com).\n", Markdown},
+		{"name,age\nann,30\nbob,41\n", CSV},
+		{"Hello, world.\nHow are you?\n", Text},
+		{"[citation needed]\n", Text},
+		{"<?xml version=\"1.0\"?>\n<feed/>", XML},
+		{"<b>bold</b> claims\n", Text},
+		{"", Text},
+	}
+	for _, tt := range tests {
+		if got := Sniff([]byte(tt.head)); got != tt.want {
+			t.Errorf("Sniff(%q) = %v, want %v", tt.head, got, tt.want)
+		}
+	}
+	if got := Detect("notes.MD", []byte("plain")); got != Markdown {
+		t.Errorf("Detect by extension = %v", got)
+	}
+	if got := Detect("notes", []byte("a,b\nc,d\n")); got != CSV {
+		t.Errorf("Detect by content = %v", got)
+	}
+	if f, ok := ForMediaType("application/x-ndjson"); !ok || f != JSON {
+		t.Errorf("ForMediaType = %v, %v", f, ok)
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	in := "# The *Title* #\n" +
+		"\n" +
+		"> A **bold** quote with `code` and snake_case_names.\n" +
+		"\n" +
+		"- first [link](http://example.com/x)\n" +
+		"2. second ![an image](pic.png) <b>tag</b> <https://example.com>\n" +
+		"---\n" +
+		"```go\n" +
+		"func hidden() {}\n" +
+		"```\n" +
+		"| col | other |\n" +
+		"|-----|:-----:|\n" +
+		"| one | two |\n" +
+		"[ref]: http://example.com/ref\n"
+	got, err := extract(t, in, Markdown, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"The Title\n", "A bold quote with code and snake_case_names.\n", "first link\n", "second an image tag\n", "one   two"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"#", "*", "`", "http", "hidden", "---", "|", ">", "<"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("%q left in:\n%s", unwanted, got)
+		}
+	}
+}
+
+func TestCSV(t *testing.T) {
+	in := "id,Title,body\n1,Gophers,\"dig, and dig\"\n2,Go,runs\n"
+	got, err := extract(t, in, CSV, Options{})
+	if err != nil || got != "id Title body\n1 Gophers dig, and dig\n2 Go runs\n" {
+		t.Fatalf("all columns: %q, %v", got, err)
+	}
+	got, err = extract(t, in, CSV, Options{Columns: []string{"title", "BODY"}})
+	if err != nil || got != "Gophers\ndig, and dig\nGo\nruns\n" {
+		t.Fatalf("selected columns: %q, %v", got, err)
+	}
+	if _, err := extract(t, in, CSV, Options{Columns: []string{"author"}}); !errors.Is(err, ErrNoColumn) {
+		t.Fatalf("missing column: %v", err)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	in := `{"id": "x1", "title": "Gophers", "tags": ["go", "fun"], "meta": {"title": "nested", "n": 3}}` + "\n" +
+		`{"id": "x2", "title": "Go", "tags": [], "ok": true, "none": null}`
+	got, err := extract(t, in, JSON, Options{})
+	if err != nil || got != "x1\nGophers\ngo\nfun\nnested\nx2\nGo\n" {
+		t.Fatalf("all strings: %q, %v", got, err)
+	}
+	got, err = extract(t, in, JSON, Options{Fields: []string{"Title", "tags"}})
+	if err != nil || got != "Gophers\ngo\nfun\nnested\nGo\n" {
+		t.Fatalf("selected fields: %q, %v", got, err)
+	}
+	if _, err := extract(t, `{"a": ["b"`, JSON, Options{}); err == nil {
+		t.Fatal("truncated document accepted")
+	}
+}
+
+func TestXML(t *testing.T) {
+	in := `<?xml version="1.0" encoding="UTF-8"?>
+<!-- a feed -->
+<feed>
+  <title>Gopher news</title>
+  <entry id="1">
+    <title>Go 1.21</title>
+    <body><p>Generics &amp; <em>more</em>&nbsp;now.</p><![CDATA[<raw> text]]></body>
+  </entry>
+  <entry><Body>Second</Body></entry>
+</feed>`
+	got, err := extract(t, in, XML, Options{})
+	if want := "Gopher news\nGo 1.21\nGenerics &\nmore\nnow.\n<raw> text\nSecond\n"; err != nil || got != want {
+		t.Fatalf("all text: %q, %v", got, err)
+	}
+	got, err = extract(t, in, XML, Options{Elements: []string{"body"}})
+	if want := "Generics &\nmore\nnow.\n<raw> text\nSecond\n"; err != nil || got != want {
+		t.Fatalf("selected elements: %q, %v", got, err)
+	}
+	for _, bad := range []string{"<a><b></a>", "<a>text", "</a>"} {
+		if _, err := extract(t, bad, XML, Options{}); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+	if f, ok := ForMediaType("text/xml"); !ok || f != XML {
+		t.Errorf("ForMediaType = %v, %v", f, ok)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "doc")
+	if err := os.WriteFile(path, []byte("## Heading\n\nSome _words_ here.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r, f, err := Open(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if f != Markdown {
+		t.Fatalf("format = %v", f)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil || string(out) != "Heading\n\nSome words here.\n" {
+		t.Fatalf("got %q, %v", out, err)
+	}
+	if _, _, err := Open(filepath.Join(t.TempDir(), "missing.txt"), Options{}); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("missing file: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package ingest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	// Block syntax at the start of a line.
+	mdHeading    = regexp.MustCompile(`^\s{0,3}#{1,6}(\s+|$)`)
+	mdQuote      = regexp.MustCompile(`^\s{0,3}(>\s?)+`)
+	mdListItem   = regexp.MustCompile(`^\s*([-*+]|\d+[.)])\s+(\[[ xX]\]\s+)?`)
+	mdRule       = regexp.MustCompile(`^\s{0,3}([-*_]\s*){3,}$`)
+	mdTableRule  = regexp.MustCompile(`^\s*\|?(\s*:?-+:?\s*\|)+\s*:?-*:?\s*$`)
+	mdSetextRule = regexp.MustCompile(`^\s{0,3}(=+|-+)\s*$`)
+	mdFence      = regexp.MustCompile("^\\s{0,3}(```|~~~)")
+
+	// Inline syntax, rewritten in this order.
+	mdImage    = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink     = regexp.MustCompile(`\[([^\]]*)\](\([^)]*\)|\[[^\]]*\])`)
+	mdLinkDef  = regexp.MustCompile(`^\s{0,3}\[[^\]]+\]:\s+\S+.*$`)
+	mdAutolink = regexp.MustCompile(`<(https?|mailto):[^>]*>`)
+	mdHTML     = regexp.MustCompile(`</?[A-Za-z][^>]*>`)
+	mdCode     = regexp.MustCompile("`+([^`]*)`+")
+	mdEmphasis = regexp.MustCompile(`(^|\W)(\*{1,3}|_{1,3}|~~)([^*_~]+)(\*{1,3}|_{1,3}|~~)(\W|$)`)
+)
+
+// extractMarkdown writes the prose of a Markdown document: headings,
+// paragraphs, list items, quotes and table cells without their markup,
+// link and image text without the targets. Fenced code blocks, rules
+// and link definitions are left out.
+func extractMarkdown(w io.Writer, r io.Reader, _ Options) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	var fence string // the marker of the open code block, if any
+	for sc.Scan() {
+		line := sc.Text()
+		if m := mdFence.FindStringSubmatch(line); m != nil {
+			switch {
+			case fence == "":
+				fence = m[1]
+			case m[1] == fence:
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+		if _, err := io.WriteString(w, markdownLine(line)+"\n"); err != nil {
+			return err
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("ingest: markdown: %w", err)
+	}
+	return nil
+}
+
+// markdownLine strips the markup from one line outside code blocks.
+func markdownLine(line string) string {
+	if mdRule.MatchString(line) || mdSetextRule.MatchString(line) || mdTableRule.MatchString(line) || mdLinkDef.MatchString(line) {
+		return ""
+	}
+	line = mdQuote.ReplaceAllString(line, "")
+	line = mdHeading.ReplaceAllString(line, "")
+	line = mdListItem.ReplaceAllString(line, "")
+	line = mdImage.ReplaceAllString(line, "$1")
+	line = mdLink.ReplaceAllString(line, "$1")
+	line = mdAutolink.ReplaceAllString(line, "")
+	line = mdHTML.ReplaceAllString(line, "")
+	line = mdCode.ReplaceAllString(line, "$1")
+	for prev := ""; prev != line; {
+		prev = line
+		line = mdEmphasis.ReplaceAllString(line, "$1$3$5")
+	}
+	line = strings.ReplaceAll(line, "|", " ")
+	return strings.TrimSpace(strings.TrimSuffix(line, " #"))
+}
@@ -0,0 +1,57 @@
+package ingest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// extractXML writes the character data of an XML document, one line per
+// run of text between tags, read a token at a time so that documents of
+// any size stream through. With Options.Elements only the text inside
+// the named elements is written, nested elements included. Comments,
+// processing instructions and attributes are left out; HTML entities
+// such as &nbsp; are understood.
+func extractXML(w io.Writer, r io.Reader, opts Options) error {
+	dec := xml.NewDecoder(r)
+	dec.Entity = xml.HTMLEntity
+	// inside counts the open elements named by Options.Elements, and
+	// named tells for each open element whether it is one.
+	inside := 0
+	var named []bool
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("ingest: xml: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := indexFold(opts.Elements, t.Name.Local) >= 0
+			if n {
+				inside++
+			}
+			named = append(named, n)
+		case xml.EndElement:
+			if named[len(named)-1] {
+				inside--
+			}
+			named = named[:len(named)-1]
+		case xml.CharData:
+			if len(opts.Elements) > 0 && inside == 0 {
+				continue
+			}
+			if text := bytes.TrimSpace(t); len(text) > 0 {
+				if _, err := w.Write(text); err != nil {
+					return err
+				}
+				if _, err := io.WriteString(w, "\n"); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,338 @@
+//go:build integration
+
+// These tests boot the serve command on a free port, over an SQLite user
+// database and a corpus in a temporary directory, and drive it over HTTP
+// as a client would. Run them with "go test -tags integration ." or
+// "make test-integration".
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/tutorial/user"
+	"example.com/tutorial/user/sqlite"
+)
+
+// liveServer is a serve command running in the test over the database
+// and corpus of its directory.
+type liveServer struct {
+	t      *testing.T
+	url    string
+	client *http.Client
+	stop   func()
+	// token, if set, is sent as the bearer token of every request.
+	token string
+}
+
+// newLiveDir returns a temporary directory holding a corpus of two
+// documents, removed once the test and its servers are done.
+func newLiveDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	corpus := filepath.Join(dir, "corpus")
+	if err := os.Mkdir(corpus, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"go.txt":   "gophers love goroutines and channels",
+		"rust.txt": "crabs love ownership and lifetimes",
+	} {
+		if err := os.WriteFile(filepath.Join(corpus, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// startLive starts serve over dir/users.db and dir/corpus, with a JWT
+// secret that keeps its tokens valid across restarts. The server is
+// stopped by stop or at the end of the test, before the directory is
+// removed; either way it must exit cleanly and release its port.
+func startLive(t *testing.T, dir string) *liveServer {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	cfg := defaultSettings().Serve
+	cfg.JWT.Secret = "integration secret"
+	addr, done, stderr := startServeWith(t, ctx, cfg, "-db", filepath.Join(dir, "users.db"), "-corpus", filepath.Join(dir, "corpus"))
+	s := &liveServer{t: t, url: "http://" + addr, client: &http.Client{Timeout: 10 * time.Second}}
+	var once sync.Once
+	s.stop = func() {
+		once.Do(func() {
+			cancel()
+			select {
+			case code := <-done:
+				if code != 0 {
+					t.Errorf("serve exited %d: %s", code, stderr.String())
+				}
+			case <-time.After(10 * time.Second):
+				t.Errorf("serve did not stop: %s", stderr.String())
+			}
+			s.client.CloseIdleConnections()
+			if conn, err := net.DialTimeout("tcp", addr, time.Second); err == nil {
+				conn.Close()
+				t.Errorf("%s still accepts connections after shutdown", addr)
+			}
+		})
+	}
+	t.Cleanup(s.stop)
+	return s
+}
+
+// do sends a request, with a body of contentType unless that is empty,
+// and returns the response and its body.
+func (s *liveServer) do(method, path, contentType string, body io.Reader) (*http.Response, string) {
+	s.t.Helper()
+	req, err := http.NewRequest(method, s.url+path, body)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		s.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		s.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	return resp, string(data)
+}
+
+// json sends in as JSON, unless it is nil, checks the status and
+// decodes the response into out, unless that is nil.
+func (s *liveServer) json(method, path string, in any, status int, out any) {
+	s.t.Helper()
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		body = bytes.NewReader(data)
+	}
+	resp, text := s.do(method, path, "application/json", body)
+	if resp.StatusCode != status {
+		s.t.Fatalf("%s %s: %s, want %d: %s", method, path, resp.Status, status, text)
+	}
+	if out != nil {
+		if err := json.Unmarshal([]byte(text), out); err != nil {
+			s.t.Fatalf("%s %s: decode %q: %v", method, path, text, err)
+		}
+	}
+}
+
+type liveUser struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// login returns a token of POST /login for the user called name.
+func (s *liveServer) login(name, password string) string {
+	s.t.Helper()
+	var tok struct {
+		Token string `json:"token"`
+	}
+	s.json(http.MethodPost, "/login", map[string]string{"name": name, "password": password}, http.StatusOK, &tok)
+	return tok.Token
+}
+
+func TestIntegrationUsers(t *testing.T) {
+	dir := newLiveDir(t)
+	s := startLive(t, dir)
+	var ada, grace liveUser
+	s.json(http.MethodPost, "/users", map[string]string{"name": "Ada", "password": "analytical engine"}, http.StatusCreated, &ada)
+	s.json(http.MethodPost, "/users", map[string]string{"name": "Grace", "password": "first compiler"}, http.StatusCreated, &grace)
+	s.json(http.MethodPost, "/users", map[string]string{"name": ""}, http.StatusUnprocessableEntity, nil)
+	// Changing a user takes a token of that user.
+	s.json(http.MethodPut, "/users/"+ada.ID, map[string]string{"name": "Ada Lovelace"}, http.StatusUnauthorized, nil)
+	s.token = s.login("Ada", "analytical engine")
+	s.json(http.MethodPut, "/users/"+grace.ID, map[string]string{"name": "Ada"}, http.StatusForbidden, nil)
+	s.json(http.MethodPut, "/users/"+ada.ID, map[string]string{"name": "Ada Lovelace"}, http.StatusOK, nil)
+	graceToken := s.login("Grace", "first compiler")
+	var page struct {
+		Users []liveUser `json:"users"`
+		Total int        `json:"total"`
+	}
+	s.json(http.MethodGet, "/users", nil, http.StatusOK, &page)
+	if page.Total != 2 || page.Users[0].Name != "Ada Lovelace" || page.Users[1] != grace {
+		t.Fatalf("GET /users = %+v", page)
+	}
+	s.stop()
+
+	// The server wrote the users to the database, password hash and all.
+	db, err := sqlite.Open(context.Background(), filepath.Join(dir, "users.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := db.Get(context.Background(), ada.ID)
+	db.Close()
+	if err != nil || u.Name != "Ada Lovelace" || u.PasswordHash == "" {
+		t.Fatalf("stored user %+v, %v", u, err)
+	}
+
+	// A new server over the same database serves them, and changes them
+	// with the tokens of the last.
+	s = startLive(t, dir)
+	s.token = graceToken
+	var got liveUser
+	s.json(http.MethodGet, "/users/"+ada.ID, nil, http.StatusOK, &got)
+	if got.Name != "Ada Lovelace" {
+		t.Fatalf("after restart GET /users/%s = %+v", ada.ID, got)
+	}
+	s.json(http.MethodDelete, "/users/"+grace.ID, nil, http.StatusNoContent, nil)
+	s.json(http.MethodGet, "/users/"+grace.ID, nil, http.StatusNotFound, nil)
+	s.stop()
+
+	db, err = sqlite.Open(context.Background(), filepath.Join(dir, "users.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Get(context.Background(), grace.ID); !errors.Is(err, user.ErrNotFound) {
+		t.Fatalf("deleted user still stored: %v", err)
+	}
+}
+
+// TestIntegrationWordCount uploads a file to be counted, counts the same
+// text again through the result cache, and queries what the server
+// knows: the job events, the corpus and the metrics.
+func TestIntegrationWordCount(t *testing.T) {
+	s := startLive(t, newLiveDir(t))
+	events := s.jobEvents()
+	const doc = "the cat sat on the mat\nthe end\n"
+
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	fw, _ := mw.CreateFormFile("file", "notes.txt")
+	io.WriteString(fw, doc)
+	mw.Close()
+	resp, body := s.do(http.MethodPost, "/upload?top=1", mw.FormDataContentType(), &form)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST /upload: %s: %s", resp.Status, body)
+	}
+	var up struct {
+		Files []struct {
+			Name  string `json:"name"`
+			Words int    `json:"words"`
+		} `json:"files"`
+		Words int `json:"words"`
+		Top   []struct {
+			Word  string `json:"word"`
+			Count int    `json:"count"`
+		} `json:"top"`
+	}
+	if err := json.Unmarshal([]byte(body), &up); err != nil {
+		t.Fatal(err)
+	}
+	if len(up.Files) != 1 || up.Files[0].Name != "notes.txt" || up.Words != 8 || len(up.Top) != 1 || up.Top[0].Word != "the" || up.Top[0].Count != 3 {
+		t.Fatalf("POST /upload = %s", body)
+	}
+
+	var first string
+	for _, cache := range []string{"MISS", "HIT"} {
+		resp, body := s.do(http.MethodPost, "/wordcount?top=2", "text/plain", strings.NewReader(doc))
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Cache") != cache {
+			t.Fatalf("POST /wordcount: %s, X-Cache %q, want %s: %s", resp.Status, resp.Header.Get("X-Cache"), cache, body)
+		}
+		if first == "" {
+			first = body
+		} else if body != first {
+			t.Fatalf("cached count %s, first %s", body, first)
+		}
+	}
+	if !strings.Contains(first, `"words":8`) || !strings.Contains(first, `{"word":"the","count":3}`) {
+		t.Fatalf("POST /wordcount = %s", first)
+	}
+
+	// Every job was announced to the listener, in order.
+	for _, want := range []string{"/upload", "/wordcount", "/wordcount"} {
+		select {
+		case ev := <-events:
+			if ev.Endpoint != want || ev.Words != 8 || ev.Error != "" {
+				t.Fatalf("job event %+v, want %s for the text", ev, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no job event for %s", want)
+		}
+	}
+
+	var found struct {
+		Docs []string `json:"docs"`
+	}
+	s.json(http.MethodGet, "/search?q=love+AND+gophers", nil, http.StatusOK, &found)
+	if len(found.Docs) != 1 || found.Docs[0] != "go.txt" {
+		t.Fatalf("GET /search = %+v", found)
+	}
+	_, metricsText := s.do(http.MethodGet, "/metrics", "", nil)
+	if !strings.Contains(metricsText, `wordcount_jobs_total{result="ok"} 3`) {
+		t.Fatalf("GET /metrics lacks the jobs:\n%s", metricsText)
+	}
+}
+
+type liveJobEvent struct {
+	Endpoint string `json:"endpoint"`
+	Words    int64  `json:"words"`
+	Error    string `json:"error"`
+}
+
+// jobEvents listens to GET /wordcount/events until the test ends and
+// returns the job events received. The server has subscribed by the
+// time it returns.
+func (s *liveServer) jobEvents() <-chan liveJobEvent {
+	s.t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	s.t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url+"/wordcount/events", nil)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	// The stream outlives the client's timeout.
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		s.t.Fatalf("GET /wordcount/events: %s", resp.Status)
+	}
+	events := make(chan liveJobEvent, 16)
+	go func() {
+		defer resp.Body.Close()
+		sc := bufio.NewScanner(resp.Body)
+		event := ""
+		for sc.Scan() {
+			line := sc.Text()
+			if name, ok := strings.CutPrefix(line, "event: "); ok {
+				event = name
+			} else if data, ok := strings.CutPrefix(line, "data: "); ok && event == "job" {
+				var ev liveJobEvent
+				if json.Unmarshal([]byte(data), &ev) == nil {
+					events <- ev
+				}
+			}
+		}
+	}()
+	return events
+}
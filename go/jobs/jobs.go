@@ -0,0 +1,344 @@
+// Package jobs queues word-count jobs for consumer workers. A Queue
+// delivers each message at least once: until a consumer acknowledges
+// it, it comes back, after a failed attempt or once the consumer has
+// held it too long, so a crashed worker loses nothing but a job may run
+// twice. Handlers should therefore be idempotent, as storing a result
+// under the job's ID is.
+//
+// Queue is the shape of a NATS JetStream pull consumer or of a Kafka
+// consumer group committing offsets after processing; Memory implements
+// it in process, for tests and single-binary deployments. Service runs
+// a Consumer over a Memory queue and keeps the status and result of
+// each job, on disk if asked, for an API where clients submit a job and
+// come back for its result.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"example.com/tutorial/id"
+	"example.com/tutorial/logging"
+	"example.com/tutorial/retry"
+)
+
+// Errors of Queue and Delivery implementations.
+var (
+	// ErrClosed is returned by Publish and Receive once the queue is
+	// closed.
+	ErrClosed = errors.New("jobs: queue closed")
+	// ErrExpired is returned by Ack and Nack for a delivery held past
+	// the queue's ack timeout, which has been or will be delivered
+	// again.
+	ErrExpired = errors.New("jobs: delivery expired")
+)
+
+// Job is a document whose words to count.
+type Job struct {
+	// ID names the job to its result and in logs; Submit sets a random
+	// one if it is empty.
+	ID string `json:"id"`
+	// Name is where the text came from, such as a file name.
+	Name string `json:"name,omitempty"`
+	Text string `json:"text"`
+	// Top is how many of the most frequent words the result lists; 0
+	// means DefaultTop and -1 every word.
+	Top int `json:"top,omitempty"`
+}
+
+// Queue carries messages from producers to consumers. Implementations
+// must be safe for concurrent use.
+type Queue interface {
+	// Publish adds a message with body to the queue.
+	Publish(ctx context.Context, body []byte) error
+	// Receive waits for the next message that is due and hands it out
+	// until it is acknowledged, nacked or its ack timeout passes.
+	Receive(ctx context.Context) (Delivery, error)
+}
+
+// Delivery is a message handed out by Receive.
+type Delivery interface {
+	Body() []byte
+	// Attempt counts the deliveries of the message, starting at 1.
+	Attempt() int
+	// Ack removes the message from the queue.
+	Ack(ctx context.Context) error
+	// Nack returns the message to the queue, to be delivered again
+	// after delay.
+	Nack(ctx context.Context, delay time.Duration) error
+}
+
+// Submit publishes job to q as JSON, first giving it an ID if it has
+// none, and returns the ID.
+func Submit(ctx context.Context, q Queue, job Job) (string, error) {
+	if job.ID == "" {
+		job.ID = id.New()
+	}
+	body, err := json.Marshal(job)
+	if err != nil {
+		return "", err
+	}
+	if err := q.Publish(ctx, body); err != nil {
+		return "", fmt.Errorf("jobs: submit %s: %w", job.ID, err)
+	}
+	return job.ID, nil
+}
+
+// Handler processes a job. An error makes the consumer try the job
+// again later, unless it is marked with retry.Permanent.
+type Handler func(ctx context.Context, job Job) error
+
+// Defaults used for zero Config fields.
+const (
+	DefaultWorkers     = 4
+	DefaultMaxAttempts = 5
+)
+
+// Config tunes a Consumer.
+type Config struct {
+	// Workers is how many jobs are handled at once; zero means
+	// DefaultWorkers.
+	Workers int
+	// MaxAttempts bounds the deliveries of a job, the first included;
+	// zero means DefaultMaxAttempts. A job still failing then is given
+	// up: published to DeadLetter, if set, and acknowledged.
+	MaxAttempts int
+	// BaseDelay and MaxDelay shape the backoff before a failed job is
+	// delivered again, as retry.Backoff computes it; zero means
+	// retry.DefaultBaseDelay and retry.DefaultMaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// DeadLetter, if set, receives the bodies of the jobs given up and
+	// of messages that are not jobs.
+	DeadLetter Queue
+	// OnGiveUp, if set, is called with the body of each job given up,
+	// once it is dead-lettered, and the error of its last attempt.
+	OnGiveUp func(ctx context.Context, body []byte, err error)
+	// Logger receives failed attempts and given-up jobs; nil means
+	// slog.Default(). Its records have component "jobs" and carry the
+	// job_id in their context.
+	Logger *slog.Logger
+}
+
+// Stats counts what a Consumer did with the messages it received.
+type Stats struct {
+	Done    uint64 // handled and acknowledged
+	Retried uint64 // failed and returned to the queue
+	Dead    uint64 // given up
+}
+
+// PanicError is the error of a handler that panicked.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string { return fmt.Sprintf("jobs: handler panicked: %v", e.Value) }
+
+// Consumer runs a Handler on the jobs of a Queue.
+type Consumer struct {
+	q       Queue
+	handler Handler
+	cfg     Config
+	logger  *slog.Logger
+
+	done, retried, dead atomic.Uint64
+	// workers is the number of workers Run keeps, which SetWorkers
+	// changes, waking Run through resize.
+	workers atomic.Int64
+	resize  chan struct{}
+}
+
+// NewConsumer returns a consumer of q's jobs; Run starts it.
+func NewConsumer(q Queue, h Handler, cfg Config) *Consumer {
+	if cfg.Workers <= 0 {
+		cfg.Workers = DefaultWorkers
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.BaseDelay <= 0 {
+		cfg.BaseDelay = retry.DefaultBaseDelay
+	}
+	if cfg.MaxDelay <= 0 {
+		cfg.MaxDelay = retry.DefaultMaxDelay
+	}
+	c := &Consumer{q: q, handler: h, cfg: cfg, logger: logging.Component(cfg.Logger, "jobs"), resize: make(chan struct{}, 1)}
+	c.workers.Store(int64(cfg.Workers))
+	return c
+}
+
+// SetWorkers changes how many jobs are handled at once, also while Run
+// runs: it starts more workers at once, and fewer as soon as enough of
+// them finish the jobs they have. It panics if n is not positive.
+func (c *Consumer) SetWorkers(n int) {
+	if n <= 0 {
+		panic("jobs: SetWorkers needs a positive number of workers")
+	}
+	c.workers.Store(int64(n))
+	select {
+	case c.resize <- struct{}{}:
+	default: // Run has yet to see an earlier change, and will see this one
+	}
+}
+
+// Workers returns how many jobs are handled at once.
+func (c *Consumer) Workers() int { return int(c.workers.Load()) }
+
+// Stats returns the counts so far.
+func (c *Consumer) Stats() Stats {
+	return Stats{Done: c.done.Load(), Retried: c.retried.Load(), Dead: c.dead.Load()}
+}
+
+// Run handles jobs with the configured number of workers, or as many
+// as SetWorkers last set, until the queue is closed, when it returns
+// nil, or ctx is done, when it returns ctx's error. Either way it waits
+// for the jobs being handled; those cut short by ctx are returned to
+// the queue at once. Another error from Receive stops Run and is
+// returned.
+func (c *Consumer) Run(ctx context.Context) error {
+	// When one worker finds the queue closed or failing, the others
+	// stop waiting for messages, but finish the jobs they have. A worker
+	// retired by SetWorkers stops the same way, alone.
+	receive, stop := context.WithCancel(ctx)
+	defer stop()
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		errs  []error
+		quits []context.CancelFunc
+	)
+	worker := func(quit context.Context) {
+		defer wg.Done()
+		for {
+			d, err := c.q.Receive(quit)
+			if err != nil {
+				if receive.Err() == nil && quit.Err() != nil {
+					return // retired
+				}
+				if !errors.Is(err, ErrClosed) {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+				stop()
+				return
+			}
+			c.handle(ctx, d)
+		}
+	}
+	for receive.Err() == nil {
+		n := c.Workers()
+		for len(quits) < n {
+			quit, cancel := context.WithCancel(receive)
+			quits = append(quits, cancel)
+			wg.Add(1)
+			go worker(quit)
+		}
+		for len(quits) > n {
+			quits[len(quits)-1]()
+			quits = quits[:len(quits)-1]
+		}
+		select {
+		case <-c.resize:
+		case <-receive.Done():
+		}
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return err
+		}
+	}
+	return nil
+}
+
+// handle runs the handler on one delivery and acknowledges it, returns
+// it to the queue or gives it up.
+func (c *Consumer) handle(ctx context.Context, d Delivery) {
+	// Settling a delivery must outlast a cancelled ctx, or the job
+	// would wait out the ack timeout before another consumer got it.
+	settle := context.WithoutCancel(ctx)
+	var job Job
+	if err := json.Unmarshal(d.Body(), &job); err != nil {
+		c.giveUp(settle, d, fmt.Errorf("jobs: malformed job: %w", err))
+		return
+	}
+	ctx = logging.WithJobID(ctx, job.ID)
+	settle = logging.WithJobID(settle, job.ID)
+	err := c.run(ctx, job)
+	switch {
+	case err == nil:
+		if err := d.Ack(settle); err != nil {
+			// The job ran, and will run again: at least once.
+			c.logger.WarnContext(settle, "job done too late to acknowledge", "err", err)
+			return
+		}
+		c.done.Add(1)
+	case ctx.Err() != nil:
+		c.nack(settle, d, 0)
+	case retry.IsPermanent(err) || d.Attempt() >= c.cfg.MaxAttempts:
+		c.giveUp(settle, d, err)
+	default:
+		delay := retry.Backoff(d.Attempt(), c.cfg.BaseDelay, c.cfg.MaxDelay, rand.Float64)
+		c.logger.WarnContext(settle, "job failed, will retry", "attempt", d.Attempt(), "delay", delay, "err", err)
+		if c.nack(settle, d, delay) {
+			c.retried.Add(1)
+		}
+	}
+}
+
+// run calls the handler, turning a panic into a *PanicError.
+func (c *Consumer) run(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return c.handler(ctx, job)
+}
+
+func (c *Consumer) nack(ctx context.Context, d Delivery, delay time.Duration) bool {
+	if err := d.Nack(ctx, delay); err != nil {
+		c.logger.WarnContext(ctx, "cannot return job to the queue", "err", err)
+		return false
+	}
+	return true
+}
+
+// giveUp moves the delivery to the dead-letter queue, if there is one,
+// and acknowledges it. If the dead-letter queue refuses it, the job
+// goes back to the main queue rather than being lost.
+func (c *Consumer) giveUp(ctx context.Context, d Delivery, err error) {
+	if c.cfg.DeadLetter != nil {
+		if err := c.cfg.DeadLetter.Publish(ctx, d.Body()); err != nil {
+			c.logger.ErrorContext(ctx, "cannot dead-letter job", "err", err)
+			c.nack(ctx, d, c.cfg.MaxDelay)
+			return
+		}
+	}
+	args := []any{"attempts", d.Attempt(), "err", err}
+	var p *PanicError
+	if errors.As(err, &p) {
+		args = append(args, "stack", string(p.Stack))
+	}
+	c.logger.ErrorContext(ctx, "job failed, giving up", args...)
+	if c.cfg.OnGiveUp != nil {
+		c.cfg.OnGiveUp(ctx, d.Body(), err)
+	}
+	if err := d.Ack(ctx); err != nil {
+		c.logger.WarnContext(ctx, "cannot acknowledge given-up job", "err", err)
+	}
+	c.dead.Add(1)
+}
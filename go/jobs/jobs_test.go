@@ -0,0 +1,267 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/tutorial/logging"
+	"example.com/tutorial/retry"
+)
+
+// run consumes q with h until q is empty, then closes it and returns
+// the consumer and its log.
+func run(t *testing.T, q *Memory, h Handler, cfg Config) (*Consumer, string) {
+	t.Helper()
+	var log syncBuffer
+	cfg.Logger = slog.New(logging.ContextHandler(slog.NewTextHandler(&log, nil)))
+	cfg.BaseDelay, cfg.MaxDelay = time.Millisecond, 5*time.Millisecond
+	c := NewConsumer(q, h, cfg)
+	errc := make(chan error, 1)
+	go func() { errc <- c.Run(context.Background()) }()
+	for deadline := time.Now().Add(5 * time.Second); q.Len() > 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d jobs left, stats %+v", q.Len(), c.Stats())
+		}
+	}
+	q.Close()
+	if err := <-errc; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	return c, log.String()
+}
+
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func TestConsumerRetries(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemory(MemoryConfig{})
+	var ids []string
+	for i := 0; i < 10; i++ {
+		id, err := Submit(ctx, q, Job{Text: "flaky job"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	// Every job fails twice before it succeeds.
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	c, log := run(t, q, func(ctx context.Context, job Job) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[job.ID]++
+		if attempts[job.ID] < 3 {
+			return errors.New("unavailable")
+		}
+		return nil
+	}, Config{Workers: 3})
+	for _, id := range ids {
+		if attempts[id] != 3 {
+			t.Errorf("job %s ran %d times", id, attempts[id])
+		}
+	}
+	if s := c.Stats(); s != (Stats{Done: 10, Retried: 20}) {
+		t.Fatalf("stats %+v", s)
+	}
+	if !strings.Contains(log, "job failed, will retry") || !strings.Contains(log, "job_id="+ids[0]) {
+		t.Errorf("log:\n%s", log)
+	}
+}
+
+func TestConsumerDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	q, dead := NewMemory(MemoryConfig{}), NewMemory(MemoryConfig{})
+	Submit(ctx, q, Job{ID: "always", Text: "x"})
+	Submit(ctx, q, Job{ID: "permanent", Text: "x"})
+	Submit(ctx, q, Job{ID: "panics", Text: "x"})
+	Submit(ctx, q, Job{ID: "ok", Text: "x"})
+	q.Publish(ctx, []byte("not json"))
+	var mu sync.Mutex
+	calls := map[string]int{}
+	var gaveUp []string
+	onGiveUp := func(ctx context.Context, body []byte, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		gaveUp = append(gaveUp, err.Error())
+	}
+	c, log := run(t, q, func(ctx context.Context, job Job) error {
+		mu.Lock()
+		calls[job.ID]++
+		mu.Unlock()
+		switch job.ID {
+		case "always":
+			return errors.New("broken")
+		case "permanent":
+			return retry.Permanent(errors.New("bad input"))
+		case "panics":
+			panic("boom")
+		}
+		return nil
+	}, Config{MaxAttempts: 3, DeadLetter: dead, OnGiveUp: onGiveUp})
+	if calls["always"] != 3 || calls["permanent"] != 1 || calls["panics"] != 3 || calls["ok"] != 1 {
+		t.Fatalf("calls %v", calls)
+	}
+	if s := c.Stats(); s != (Stats{Done: 1, Retried: 4, Dead: 4}) {
+		t.Fatalf("stats %+v", s)
+	}
+	var got []string
+	for dead.Len() > 0 {
+		d := receive(t, dead)
+		var job Job
+		if json.Unmarshal(d.Body(), &job) != nil {
+			job.ID = string(d.Body())
+		}
+		got = append(got, job.ID)
+		d.Ack(ctx)
+	}
+	if len(got) != 4 || !strings.Contains(strings.Join(got, ","), "not json") {
+		t.Fatalf("dead letters %v", got)
+	}
+	if got := strings.Join(gaveUp, ","); len(gaveUp) != 4 || !strings.Contains(got, "broken") || !strings.Contains(got, "bad input") || !strings.Contains(got, "malformed job") {
+		t.Errorf("given up with %q", got)
+	}
+	if !strings.Contains(log, "jobs: handler panicked: boom") || !strings.Contains(log, "stack=") {
+		t.Errorf("log:\n%s", log)
+	}
+}
+
+func TestConsumerCancel(t *testing.T) {
+	// Jobs cut short by cancelling Run go straight back to the queue.
+	ctx, cancel := context.WithCancel(context.Background())
+	q := NewMemory(MemoryConfig{})
+	Submit(ctx, q, Job{Text: "x"})
+	started := make(chan struct{})
+	c := NewConsumer(q, func(ctx context.Context, job Job) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}, Config{Workers: 1})
+	errc := make(chan error, 1)
+	go func() { errc <- c.Run(ctx) }()
+	<-started
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run: %v", err)
+	}
+	if d := receive(t, q); d.Attempt() != 2 {
+		t.Fatalf("attempt %d", d.Attempt())
+	}
+	if s := c.Stats(); s != (Stats{}) {
+		t.Fatalf("stats %+v", s)
+	}
+}
+
+type failingQueue struct{ *Memory }
+
+var errBroker = errors.New("broker unreachable")
+
+func (failingQueue) Receive(ctx context.Context) (Delivery, error) { return nil, errBroker }
+
+func TestConsumerReceiveError(t *testing.T) {
+	c := NewConsumer(failingQueue{NewMemory(MemoryConfig{})}, func(context.Context, Job) error { return nil }, Config{})
+	if err := c.Run(context.Background()); !errors.Is(err, errBroker) {
+		t.Fatalf("Run: %v", err)
+	}
+}
+
+func TestConsumerSetWorkers(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemory(MemoryConfig{})
+	var (
+		mu          sync.Mutex
+		active, top int
+	)
+	gate := make(chan struct{})
+	c := NewConsumer(q, func(ctx context.Context, job Job) error {
+		mu.Lock()
+		active++
+		top = max(top, active)
+		mu.Unlock()
+		<-gate
+		time.Sleep(2 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+		return nil
+	}, Config{Workers: 1})
+	waitActive := func(n int) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+			mu.Lock()
+			a := active
+			mu.Unlock()
+			if a == n {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%d jobs running, want %d", a, n)
+			}
+		}
+	}
+	for i := 0; i < 3; i++ {
+		Submit(ctx, q, Job{Text: "job"})
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- c.Run(ctx) }()
+	waitActive(1)
+	c.SetWorkers(3)
+	waitActive(3)
+	if c.Workers() != 3 {
+		t.Fatalf("Workers = %d", c.Workers())
+	}
+
+	// Once fewer are wanted, the workers beyond them stop after their
+	// jobs.
+	c.SetWorkers(1)
+	mu.Lock()
+	top = 0
+	mu.Unlock()
+	close(gate)
+	waitActive(0)
+	for i := 0; i < 5; i++ {
+		Submit(ctx, q, Job{Text: "job"})
+	}
+	for deadline := time.Now().Add(5 * time.Second); q.Len() > 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d jobs left", q.Len())
+		}
+	}
+	q.Close()
+	if err := <-errc; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if top != 1 || c.Stats().Done != 8 {
+		t.Fatalf("%d jobs at once after shrinking, stats %+v", top, c.Stats())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("SetWorkers(0) did not panic")
+		}
+	}()
+	c.SetWorkers(0)
+}
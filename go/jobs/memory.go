@@ -0,0 +1,232 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultAckTimeout is how long Memory waits for a delivery to be
+// settled when MemoryConfig.AckTimeout is zero.
+const DefaultAckTimeout = 30 * time.Second
+
+// MemoryConfig tunes a Memory queue.
+type MemoryConfig struct {
+	// AckTimeout is how long a delivery may go unacknowledged before
+	// the message is delivered again, as when its consumer crashed;
+	// zero means DefaultAckTimeout.
+	AckTimeout time.Duration
+}
+
+// MemoryStats counts what happened to the messages of a Memory queue.
+type MemoryStats struct {
+	Published uint64
+	Delivered uint64 // including redeliveries
+	Acked     uint64
+	Nacked    uint64
+	Expired   uint64 // deliveries not settled within the ack timeout
+}
+
+// Memory is a Queue held in memory, delivering messages in the order
+// they were published, or became due again. It is safe for concurrent
+// use.
+type Memory struct {
+	ackTimeout time.Duration
+	now        func() time.Time
+
+	mu      sync.Mutex
+	ready   []*memoryMessage // due, oldest first
+	waiting []*memoryMessage // delivered or nacked, due later
+	changed chan struct{}    // closed and replaced when either changes
+	closed  bool
+	stats   MemoryStats
+}
+
+type memoryMessage struct {
+	body     []byte
+	attempts int
+	due      time.Time // when a waiting message is ready again
+	// delivery is the number of the current delivery, so that settling
+	// an expired one is refused.
+	delivery int
+	inFlight bool
+}
+
+// NewMemory returns an empty queue.
+func NewMemory(cfg MemoryConfig) *Memory {
+	if cfg.AckTimeout <= 0 {
+		cfg.AckTimeout = DefaultAckTimeout
+	}
+	return &Memory{ackTimeout: cfg.AckTimeout, now: time.Now, changed: make(chan struct{})}
+}
+
+// Publish implements Queue.
+func (q *Memory) Publish(ctx context.Context, body []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	q.ready = append(q.ready, &memoryMessage{body: append([]byte(nil), body...)})
+	q.stats.Published++
+	q.notify()
+	return nil
+}
+
+// Receive implements Queue.
+func (q *Memory) Receive(ctx context.Context) (Delivery, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		q.mu.Lock()
+		if q.closed {
+			q.mu.Unlock()
+			return nil, ErrClosed
+		}
+		now := q.now()
+		next := q.promote(now)
+		if len(q.ready) > 0 {
+			m := q.ready[0]
+			q.ready[0] = nil
+			q.ready = q.ready[1:]
+			m.attempts++
+			m.delivery++
+			m.inFlight = true
+			m.due = now.Add(q.ackTimeout)
+			q.waiting = append(q.waiting, m)
+			q.stats.Delivered++
+			q.mu.Unlock()
+			return &memoryDelivery{q: q, m: m, delivery: m.delivery, attempt: m.attempts}, nil
+		}
+		changed := q.changed
+		q.mu.Unlock()
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if !next.IsZero() {
+			timer = time.NewTimer(next.Sub(now))
+			timeout = timer.C
+		}
+		select {
+		case <-ctx.Done():
+		case <-changed:
+		case <-timeout:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// promote moves the waiting messages due by now to the ready ones and
+// returns when the next of the others is due, or the zero time if none
+// waits.
+func (q *Memory) promote(now time.Time) time.Time {
+	var next time.Time
+	kept := q.waiting[:0]
+	for _, m := range q.waiting {
+		switch {
+		case !now.Before(m.due):
+			if m.inFlight {
+				q.stats.Expired++
+				m.inFlight = false
+			}
+			q.ready = append(q.ready, m)
+		default:
+			if next.IsZero() || m.due.Before(next) {
+				next = m.due
+			}
+			kept = append(kept, m)
+		}
+	}
+	clear(q.waiting[len(kept):])
+	q.waiting = kept
+	return next
+}
+
+// notify wakes the waiting Receive calls.
+func (q *Memory) notify() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+// Len returns the number of messages not yet acknowledged, in flight
+// or not.
+func (q *Memory) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.ready) + len(q.waiting)
+}
+
+// Stats returns the counts so far.
+func (q *Memory) Stats() MemoryStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.stats
+}
+
+// Close makes Publish and Receive fail with ErrClosed, waking the
+// waiting Receive calls. The deliveries in flight may still be settled,
+// and the messages left are dropped.
+func (q *Memory) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		q.closed = true
+		q.notify()
+	}
+}
+
+type memoryDelivery struct {
+	q        *Memory
+	m        *memoryMessage
+	delivery int
+	attempt  int
+}
+
+func (d *memoryDelivery) Body() []byte { return d.m.body }
+func (d *memoryDelivery) Attempt() int { return d.attempt }
+
+// Ack implements Delivery.
+func (d *memoryDelivery) Ack(ctx context.Context) error {
+	return d.settle(func(q *Memory) { q.stats.Acked++ })
+}
+
+// Nack implements Delivery.
+func (d *memoryDelivery) Nack(ctx context.Context, delay time.Duration) error {
+	return d.settle(func(q *Memory) {
+		q.stats.Nacked++
+		if delay <= 0 {
+			q.ready = append(q.ready, d.m)
+			return
+		}
+		d.m.due = q.now().Add(delay)
+		q.waiting = append(q.waiting, d.m)
+	})
+}
+
+// settle takes the message out of flight and calls f, unless the
+// delivery has expired.
+func (d *memoryDelivery) settle(f func(q *Memory)) error {
+	q := d.q
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	m := d.m
+	if !m.inFlight || m.delivery != d.delivery || !q.now().Before(m.due) {
+		return ErrExpired
+	}
+	for i, w := range q.waiting {
+		if w == m {
+			q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+			break
+		}
+	}
+	m.inFlight = false
+	f(q)
+	q.notify()
+	return nil
+}
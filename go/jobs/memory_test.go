@@ -0,0 +1,111 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func receive(t *testing.T, q *Memory) Delivery {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	d, err := q.Receive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestMemoryOrder(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemory(MemoryConfig{})
+	for _, body := range []string{"a", "b", "c"} {
+		q.Publish(ctx, []byte(body))
+	}
+	a, b := receive(t, q), receive(t, q)
+	if string(a.Body()) != "a" || string(b.Body()) != "b" || a.Attempt() != 1 {
+		t.Fatalf("got %q, %q", a.Body(), b.Body())
+	}
+	// A message nacked without delay goes behind those already due.
+	if err := a.Nack(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	b.Ack(ctx)
+	c, again := receive(t, q), receive(t, q)
+	if string(c.Body()) != "c" || string(again.Body()) != "a" || again.Attempt() != 2 {
+		t.Fatalf("got %q, then %q attempt %d", c.Body(), again.Body(), again.Attempt())
+	}
+	c.Ack(ctx)
+	again.Ack(ctx)
+	if q.Len() != 0 {
+		t.Fatalf("%d left", q.Len())
+	}
+	if s := q.Stats(); s != (MemoryStats{Published: 3, Delivered: 4, Acked: 3, Nacked: 1}) {
+		t.Fatalf("stats %+v", s)
+	}
+	if err := a.Ack(ctx); !errors.Is(err, ErrExpired) {
+		t.Fatalf("second settle: %v", err)
+	}
+}
+
+func TestMemoryNackDelay(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemory(MemoryConfig{})
+	q.Publish(ctx, []byte("x"))
+	receive(t, q).Nack(ctx, 50*time.Millisecond)
+
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := q.Receive(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("delayed message delivered early: %v", err)
+	}
+	start := time.Now()
+	if d := receive(t, q); d.Attempt() != 2 || time.Since(start) > time.Second {
+		t.Fatalf("attempt %d after %v", d.Attempt(), time.Since(start))
+	}
+}
+
+func TestMemoryAckTimeout(t *testing.T) {
+	// A consumer that never settles loses the message to another.
+	ctx := context.Background()
+	q := NewMemory(MemoryConfig{AckTimeout: 30 * time.Millisecond})
+	q.Publish(ctx, []byte("x"))
+	lost := receive(t, q)
+	d := receive(t, q)
+	if d.Attempt() != 2 {
+		t.Fatalf("attempt %d", d.Attempt())
+	}
+	if err := lost.Ack(ctx); !errors.Is(err, ErrExpired) {
+		t.Fatalf("expired ack: %v", err)
+	}
+	if err := d.Ack(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if s := q.Stats(); s.Expired != 1 || s.Acked != 1 || q.Len() != 0 {
+		t.Fatalf("stats %+v, %d left", s, q.Len())
+	}
+}
+
+func TestMemoryClose(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemory(MemoryConfig{})
+	q.Publish(ctx, []byte("x"))
+	d := receive(t, q)
+	errc := make(chan error)
+	go func() {
+		_, err := q.Receive(ctx)
+		errc <- err
+	}()
+	q.Close()
+	if err := <-errc; !errors.Is(err, ErrClosed) {
+		t.Fatalf("waiting Receive: %v", err)
+	}
+	if err := q.Publish(ctx, nil); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Publish: %v", err)
+	}
+	if err := d.Ack(ctx); err != nil {
+		t.Fatalf("Ack after Close: %v", err)
+	}
+}
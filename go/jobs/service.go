@@ -0,0 +1,385 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"example.com/tutorial/fileutil"
+	"example.com/tutorial/id"
+)
+
+// ErrUnknownJob is returned by Service.Get for an ID it never handed
+// out.
+var ErrUnknownJob = errors.New("jobs: unknown job")
+
+// State is where a job of a Service stands.
+type State string
+
+// States of a job. A job is Queued until a worker takes it, Running
+// while it is counted, and Queued again if the attempt fails and it is
+// to be retried. Done and Failed are final.
+const (
+	Queued  State = "queued"
+	Running State = "running"
+	Done    State = "done"
+	Failed  State = "failed"
+)
+
+// Status is what a Service knows of a job.
+type Status struct {
+	ID       string `json:"id"`
+	Name     string `json:"name,omitempty"`
+	State    State  `json:"state"`
+	Attempts int    `json:"attempts"`
+	// Error is that of the last failed attempt; a Done job has none.
+	Error string `json:"error,omitempty"`
+	// Result is set once the job is Done.
+	Result  *Result   `json:"result,omitempty"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+// Final reports whether the job will not change any more.
+func (st Status) Final() bool { return st.State == Done || st.State == Failed }
+
+// Defaults used for zero ServiceConfig fields.
+const (
+	DefaultRetention   = 24 * time.Hour
+	DefaultMaxFinished = 1000
+)
+
+// ServiceConfig tunes a Service.
+type ServiceConfig struct {
+	// Config tunes the consumer; its DeadLetter and OnGiveUp are kept
+	// and called after the Service marks the job Failed.
+	Config
+	// AckTimeout is that of the queue, as in MemoryConfig; a job
+	// running longer is delivered again.
+	AckTimeout time.Duration
+	// Dir, if set, keeps a file per job, so that the jobs queued or
+	// running when the process stopped are queued again by the next
+	// NewService on Dir, and the finished ones are still known. Empty
+	// keeps the jobs in memory only.
+	Dir string
+	// Retention is how long a job is kept, in memory and in Dir, once
+	// it is final; zero means DefaultRetention. MaxFinished bounds the
+	// final jobs kept, the oldest forgotten first; zero means
+	// DefaultMaxFinished. Get returns ErrUnknownJob for a job
+	// forgotten.
+	Retention   time.Duration
+	MaxFinished int
+}
+
+// Service runs word-count jobs in the background and keeps their
+// status, for an API where clients submit a job and come back for its
+// result. It is a Consumer over a Memory queue, and as with one, a job
+// may run more than once; only its first result is kept.
+type Service struct {
+	q        *Memory
+	consumer *Consumer
+	cfg      ServiceConfig
+	now      func() time.Time
+
+	// writeMu orders the updates of the files, which are written
+	// outside mu so that Get does not wait for the disk.
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	jobs    map[string]*Status
+	// finished holds the IDs of the final jobs of jobs, in the order
+	// they became final, for prune to forget the oldest first.
+	finished []string
+}
+
+// record is the file of a job: its status and, until it is final, the
+// job itself, to queue again after a restart.
+type record struct {
+	Status
+	Job *Job `json:"job,omitempty"`
+}
+
+// NewService returns a Service, having loaded the jobs of cfg.Dir, if
+// any, and queued those not final again in the order they were
+// submitted. The final jobs past their retention are forgotten. Run
+// starts the workers.
+func NewService(cfg ServiceConfig) (*Service, error) {
+	if cfg.Retention <= 0 {
+		cfg.Retention = DefaultRetention
+	}
+	if cfg.MaxFinished <= 0 {
+		cfg.MaxFinished = DefaultMaxFinished
+	}
+	s := &Service{
+		q:    NewMemory(MemoryConfig{AckTimeout: cfg.AckTimeout}),
+		cfg:  cfg,
+		now:  time.Now,
+		jobs: map[string]*Status{},
+	}
+	ccfg := cfg.Config
+	ccfg.OnGiveUp = s.gaveUp
+	s.consumer = NewConsumer(s.q, s.handle, ccfg)
+	if cfg.Dir == "" {
+		return s, nil
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("jobs: %w", err)
+	}
+	entries, err := os.ReadDir(cfg.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("jobs: %w", err)
+	}
+	// IDs sort in the order they were made, and so do the files.
+	var finished []*Status
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(cfg.Dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("jobs: %w", err)
+		}
+		var rec record
+		if err := json.Unmarshal(b, &rec); err != nil {
+			return nil, fmt.Errorf("jobs: load %s: %w", e.Name(), err)
+		}
+		st := rec.Status
+		if !st.Final() {
+			if rec.Job == nil {
+				return nil, fmt.Errorf("jobs: load %s: unfinished job without its text", e.Name())
+			}
+			st.State = Queued
+			if err := s.publish(*rec.Job); err != nil {
+				return nil, err
+			}
+		} else {
+			finished = append(finished, &st)
+		}
+		s.jobs[st.ID] = &st
+	}
+	sort.SliceStable(finished, func(i, j int) bool { return finished[i].Updated.Before(finished[j].Updated) })
+	for _, st := range finished {
+		s.finished = append(s.finished, st.ID)
+	}
+	s.prune()
+	return s, nil
+}
+
+// Submit queues job under a new ID, which replaces any it has, and
+// returns its status. With a Dir, the job is on disk when Submit
+// returns.
+func (s *Service) Submit(ctx context.Context, job Job) (Status, error) {
+	if err := ctx.Err(); err != nil {
+		return Status{}, err
+	}
+	s.prune()
+	job.ID = id.New()
+	now := s.now()
+	st := Status{ID: job.ID, Name: job.Name, State: Queued, Created: now, Updated: now}
+	if err := s.write(record{st, &job}); err != nil {
+		return Status{}, err
+	}
+	// The map gets a copy of its own: once published, the job's status
+	// changes under the workers while st is returned.
+	cp := st
+	s.mu.Lock()
+	s.jobs[job.ID] = &cp
+	s.mu.Unlock()
+	if err := s.publish(job); err != nil {
+		s.mu.Lock()
+		delete(s.jobs, job.ID)
+		s.mu.Unlock()
+		s.remove(job.ID)
+		return Status{}, err
+	}
+	return st, nil
+}
+
+// publish puts job on the queue.
+func (s *Service) publish(job Job) error {
+	body, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	if err := s.q.Publish(context.Background(), body); err != nil {
+		return fmt.Errorf("jobs: submit %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+// Get returns the status of the job with the given ID.
+func (s *Service) Get(id string) (Status, error) {
+	s.prune()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.jobs[id]
+	if !ok {
+		return Status{}, ErrUnknownJob
+	}
+	return *st, nil
+}
+
+// Len returns the number of jobs not yet final.
+func (s *Service) Len() int { return s.q.Len() }
+
+// Stats returns the counts of the consumer.
+func (s *Service) Stats() Stats { return s.consumer.Stats() }
+
+// SetWorkers changes the number of workers, as Consumer.SetWorkers
+// does.
+func (s *Service) SetWorkers(n int) { s.consumer.SetWorkers(n) }
+
+// Workers returns the number of workers.
+func (s *Service) Workers() int { return s.consumer.Workers() }
+
+// Run runs the workers until Close is called, when it returns nil, or
+// ctx is done, as Consumer.Run does. Jobs cut short stay queued, on
+// disk, for the next Service on the same Dir.
+func (s *Service) Run(ctx context.Context) error { return s.consumer.Run(ctx) }
+
+// Close makes Submit fail and Run return once the running jobs are
+// done.
+func (s *Service) Close() { s.q.Close() }
+
+// handle is the consumer's Handler: it counts the words of job,
+// recording its progress in its status.
+func (s *Service) handle(ctx context.Context, job Job) error {
+	ok, err := s.update(job.ID, func(st *Status) bool {
+		if st.Final() {
+			return false
+		}
+		st.State = Running
+		st.Attempts++
+		return true
+	}, &job)
+	if !ok {
+		// Delivered again after it was done, or never submitted.
+		return err
+	}
+	r, err := Count(job)
+	if err == nil {
+		if _, err = s.update(job.ID, func(st *Status) bool {
+			st.State, st.Error, st.Result = Done, "", &r
+			return true
+		}, nil); err == nil {
+			return nil
+		}
+	}
+	if _, uerr := s.update(job.ID, func(st *Status) bool {
+		st.State, st.Error = Queued, err.Error()
+		return true
+	}, &job); uerr != nil {
+		s.consumer.logger.WarnContext(ctx, "cannot save job status", "err", uerr)
+	}
+	return err
+}
+
+// gaveUp marks a job the consumer gave up as Failed, then passes it on
+// to the configured OnGiveUp.
+func (s *Service) gaveUp(ctx context.Context, body []byte, err error) {
+	var job Job
+	if json.Unmarshal(body, &job) == nil {
+		if _, uerr := s.update(job.ID, func(st *Status) bool {
+			if st.Final() {
+				return false
+			}
+			st.State, st.Error = Failed, err.Error()
+			return true
+		}, nil); uerr != nil {
+			s.consumer.logger.ErrorContext(ctx, "cannot save job status", "err", uerr)
+		}
+	}
+	if s.cfg.OnGiveUp != nil {
+		s.cfg.OnGiveUp(ctx, body, err)
+	}
+}
+
+// update changes the status of the job with the given ID by f and
+// writes its file, with job if it is not final. It reports whether
+// there was a change: none if there is no such job, f returns false or
+// the file cannot be written, when the status is left as it was and
+// the error returned. Get sees the change once the file is written.
+func (s *Service) update(id string, f func(*Status) bool, job *Job) (bool, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.mu.Lock()
+	st, ok := s.jobs[id]
+	var cur Status
+	if ok {
+		cur = *st
+	}
+	s.mu.Unlock()
+	if !ok || !f(&cur) {
+		return false, nil
+	}
+	cur.Updated = s.now()
+	if cur.Final() {
+		job = nil
+	}
+	if err := s.write(record{cur, job}); err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	finished := cur.Final() && !st.Final()
+	*st = cur
+	if finished {
+		s.finished = append(s.finished, id)
+	}
+	s.mu.Unlock()
+	if finished {
+		s.prune()
+	}
+	return true, nil
+}
+
+// prune forgets the final jobs kept longer than cfg.Retention, and the
+// oldest beyond cfg.MaxFinished, and removes their files.
+func (s *Service) prune() {
+	s.mu.Lock()
+	cutoff := s.now().Add(-s.cfg.Retention)
+	n := 0
+	for ; n < len(s.finished); n++ {
+		if len(s.finished)-n <= s.cfg.MaxFinished && s.jobs[s.finished[n]].Updated.After(cutoff) {
+			break
+		}
+	}
+	gone := s.finished[:n]
+	for _, id := range gone {
+		delete(s.jobs, id)
+	}
+	s.finished = s.finished[n:]
+	s.mu.Unlock()
+	for _, id := range gone {
+		s.remove(id)
+	}
+}
+
+// write saves rec to its file, if the Service has a Dir.
+func (s *Service) write(rec record) error {
+	if s.cfg.Dir == "" {
+		return nil
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if err := fileutil.WriteAtomic(s.path(rec.ID), b); err != nil {
+		return fmt.Errorf("jobs: save %s: %w", rec.ID, err)
+	}
+	return nil
+}
+
+// remove deletes the file of the job with the given ID.
+func (s *Service) remove(id string) {
+	if s.cfg.Dir != "" {
+		os.Remove(s.path(id))
+	}
+}
+
+func (s *Service) path(id string) string { return filepath.Join(s.cfg.Dir, id+".json") }
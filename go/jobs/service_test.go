@@ -0,0 +1,218 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitFinal waits for the job with the given ID to be final and
+// returns its status.
+func waitFinal(t *testing.T, s *Service, id string) Status {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		st, err := s.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.Final() {
+			return st
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s still %s", id, st.State)
+		}
+	}
+}
+
+// runService runs s until the test ends.
+func runService(t *testing.T, s *Service) {
+	t.Helper()
+	errc := make(chan error, 1)
+	go func() { errc <- s.Run(context.Background()) }()
+	t.Cleanup(func() {
+		s.Close()
+		if err := <-errc; err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	})
+}
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewService(ServiceConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := s.Submit(ctx, Job{ID: "mine", Name: "doc.txt", Text: "the cat and the hat", Top: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.ID == "" || st.ID == "mine" || st.State != Queued || st.Name != "doc.txt" {
+		t.Fatalf("submitted %+v", st)
+	}
+	runService(t, s)
+	st = waitFinal(t, s, st.ID)
+	if st.State != Done || st.Attempts != 1 || st.Result == nil || st.Result.Words != 5 || len(st.Result.Top) != 1 || st.Result.Top[0].Word != "the" {
+		t.Fatalf("finished %+v", st)
+	}
+	if st.Result.JobID != st.ID || st.Updated.Before(st.Created) {
+		t.Errorf("finished %+v", st)
+	}
+	if _, err := s.Get("nobody"); !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("Get unknown: %v", err)
+	}
+}
+
+func TestServiceRestart(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := NewService(ServiceConfig{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := s.Submit(ctx, Job{Text: "one two two"})
+	second, _ := s.Submit(ctx, Job{Text: "three"})
+	// Stopped before running them: they are still on disk.
+	s.Close()
+	if _, err := s.Submit(ctx, Job{Text: "late"}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Submit after Close: %v", err)
+	}
+
+	s, err = NewService(ServiceConfig{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st, err := s.Get(first.ID); err != nil || st.State != Queued {
+		t.Fatalf("restored %+v, %v", st, err)
+	}
+	if s.Len() != 2 {
+		t.Fatalf("%d jobs queued again, want 2", s.Len())
+	}
+	runService(t, s)
+	if st := waitFinal(t, s, first.ID); st.State != Done || st.Result.Unique != 2 {
+		t.Fatalf("first %+v", st)
+	}
+	waitFinal(t, s, second.ID)
+	b, err := os.ReadFile(filepath.Join(dir, first.ID+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "one two two") {
+		t.Errorf("finished job keeps its text: %s", b)
+	}
+
+	again, err := NewService(ServiceConfig{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st, err := again.Get(first.ID); err != nil || st.State != Done || st.Result == nil || st.Result.Words != 3 {
+		t.Fatalf("restored finished %+v, %v", st, err)
+	}
+	if again.Len() != 0 {
+		t.Errorf("finished jobs queued again: %d", again.Len())
+	}
+}
+
+func TestServiceGiveUp(t *testing.T) {
+	ctx := context.Background()
+	var gaveUp []string
+	s, err := NewService(ServiceConfig{Config: Config{OnGiveUp: func(ctx context.Context, body []byte, err error) {
+		gaveUp = append(gaveUp, err.Error())
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, _ := s.Submit(ctx, Job{Text: "x"})
+	s.gaveUp(ctx, []byte(`{"id":"`+st.ID+`"}`), errors.New("broken"))
+	if st, _ := s.Get(st.ID); st.State != Failed || st.Error != "broken" {
+		t.Fatalf("given up %+v", st)
+	}
+	if len(gaveUp) != 1 {
+		t.Errorf("OnGiveUp called %d times", len(gaveUp))
+	}
+}
+
+func TestServiceBadDir(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "junk.json"), []byte("{"), 0o644)
+	if _, err := NewService(ServiceConfig{Dir: dir}); err == nil || !strings.Contains(err.Error(), "junk.json") {
+		t.Fatalf("damaged job file: %v", err)
+	}
+}
+
+func TestServiceRetention(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := NewService(ServiceConfig{Dir: dir, Retention: time.Hour, MaxFinished: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var clock atomic.Int64
+	start := time.Now()
+	s.now = func() time.Time { return start.Add(time.Duration(clock.Load())) }
+	runService(t, s)
+	exists := func(id string) bool {
+		_, err := os.Stat(filepath.Join(dir, id+".json"))
+		return err == nil
+	}
+	var ids []string
+	for _, text := range []string{"one", "two", "three"} {
+		st, err := s.Submit(ctx, Job{Text: text})
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitFinal(t, s, st.ID)
+		ids = append(ids, st.ID)
+	}
+	// Past MaxFinished, the oldest is forgotten.
+	if _, err := s.Get(ids[0]); !errors.Is(err, ErrUnknownJob) || exists(ids[0]) {
+		t.Fatalf("oldest of 3 finished jobs kept: %v", err)
+	}
+	if _, err := s.Get(ids[1]); err != nil || !exists(ids[1]) {
+		t.Fatalf("second job forgotten: %v", err)
+	}
+
+	// Past Retention, all are.
+	clock.Store(int64(time.Hour + time.Second))
+	for _, id := range ids[1:] {
+		if _, err := s.Get(id); !errors.Is(err, ErrUnknownJob) || exists(id) {
+			t.Errorf("job %s kept past its retention: %v", id, err)
+		}
+	}
+}
+
+func TestServiceRetentionRestart(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := NewService(ServiceConfig{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = func() time.Time { return time.Now().Add(-2 * time.Hour) }
+	runService(t, s)
+	old, _ := s.Submit(ctx, Job{Text: "old"})
+	waitFinal(t, s, old.ID)
+	queued, _ := s.Submit(ctx, Job{Text: "queued"})
+	s.Close()
+
+	// The finished job is past the retention of the next Service, the
+	// queued one is not final and stays.
+	again, err := NewService(ServiceConfig{Dir: dir, Retention: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := again.Get(old.ID); !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("expired job restored: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, old.ID+".json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expired job file: %v", err)
+	}
+	if st, err := again.Get(queued.ID); err != nil || st.State != Queued {
+		t.Errorf("queued job: %+v, %v", st, err)
+	}
+}
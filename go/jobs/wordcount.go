@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"context"
+	"strings"
+
+	"example.com/tutorial/text"
+)
+
+// DefaultTop is the length of a result's table of the most frequent
+// words when the job's Top is zero.
+const DefaultTop = 10
+
+// Result is the word count of a job.
+type Result struct {
+	JobID string `json:"id"`
+	Name  string `json:"name,omitempty"`
+	text.Summary
+}
+
+// Count counts the words of job.
+func Count(job Job) (Result, error) {
+	counts, err := text.WordCountReader(strings.NewReader(job.Text))
+	if err != nil {
+		return Result{}, err
+	}
+	top := job.Top
+	if top == 0 {
+		top = DefaultTop
+	}
+	r := Result{JobID: job.ID, Name: job.Name}
+	r.Unique = len(counts)
+	for _, c := range counts {
+		r.Words += c
+	}
+	r.Top = text.TopWords(counts, top)
+	return r, nil
+}
+
+// WordCount returns a Handler that counts the words of each job and
+// passes the result to deliver, whose error fails the attempt. As a job
+// may run more than once, deliver may get its result more than once.
+func WordCount(deliver func(ctx context.Context, r Result) error) Handler {
+	return func(ctx context.Context, job Job) error {
+		r, err := Count(job)
+		if err != nil {
+			return err
+		}
+		return deliver(ctx, r)
+	}
+}
@@ -0,0 +1,58 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCount(t *testing.T) {
+	r, err := Count(Job{ID: "j", Name: "a.txt", Text: "the cat and the hat", Top: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.JobID != "j" || r.Name != "a.txt" || r.Words != 5 || r.Unique != 4 || len(r.Top) != 1 || r.Top[0].Word != "the" {
+		t.Fatalf("result %+v", r)
+	}
+	if r, _ := Count(Job{Text: "a b c d e f g h i j k l"}); len(r.Top) != DefaultTop {
+		t.Fatalf("default top: %d words", len(r.Top))
+	}
+}
+
+func TestWordCount(t *testing.T) {
+	// Results are stored by job ID, so a job delivered twice is stored
+	// once; the first store fails, and the job is retried.
+	ctx := context.Background()
+	q := NewMemory(MemoryConfig{})
+	docs := map[string]string{"a": "one two two", "b": "three three three"}
+	for name, text := range docs {
+		Submit(ctx, q, Job{ID: name, Name: name, Text: text})
+	}
+	var mu sync.Mutex
+	results := map[string]Result{}
+	failed := false
+	c := NewConsumer(q, WordCount(func(ctx context.Context, r Result) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if !failed {
+			failed = true
+			return errors.New("store down")
+		}
+		results[r.JobID] = r
+		return nil
+	}), Config{BaseDelay: time.Millisecond})
+	go c.Run(ctx)
+	defer q.Close()
+	for deadline := time.Now().Add(5 * time.Second); q.Len() > 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("jobs not done")
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(results) != 2 || results["a"].Words != 3 || results["b"].Top[0].Count != 3 {
+		t.Fatalf("results %+v", results)
+	}
+}
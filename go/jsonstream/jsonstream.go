@@ -0,0 +1,237 @@
+// Package jsonstream writes and reads large JSON result sets an item at
+// a time, so that neither side holds them whole: an ArrayEncoder writes
+// values as the elements of one JSON array as they come, a LineDecoder
+// reads JSON lines (one value per line) and an ArrayDecoder the elements
+// of a JSON array, each handing over one item per call of Next.
+package jsonstream
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ArrayEncoder writes values as the elements of a JSON array, in the
+// compact form of json.Marshal, followed by a newline when closed: the
+// output of json.NewEncoder(w).Encode on a slice of them. Output is
+// buffered; Close writes the rest.
+type ArrayEncoder struct {
+	w   *bufio.Writer
+	n   int
+	err error
+}
+
+// NewArrayEncoder returns an ArrayEncoder writing to w.
+func NewArrayEncoder(w io.Writer) *ArrayEncoder {
+	return &ArrayEncoder{w: bufio.NewWriter(w)}
+}
+
+// Encode writes v as the next element. After an error, later calls do
+// nothing and return it again.
+func (e *ArrayEncoder) Encode(v any) error {
+	if e.err != nil {
+		return e.err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		e.err = fmt.Errorf("jsonstream: element %d: %w", e.n, err)
+		return e.err
+	}
+	sep := byte(',')
+	if e.n == 0 {
+		sep = '['
+	}
+	e.w.WriteByte(sep)
+	_, e.err = e.w.Write(data)
+	e.n++
+	return e.err
+}
+
+// Len returns the number of elements written.
+func (e *ArrayEncoder) Len() int { return e.n }
+
+// Close ends the array, which is "[]" if nothing was encoded, and
+// flushes it. It does not close the underlying writer.
+func (e *ArrayEncoder) Close() error {
+	if e.err != nil {
+		return e.err
+	}
+	if e.n == 0 {
+		e.w.WriteByte('[')
+	}
+	e.w.WriteString("]\n")
+	e.err = e.w.Flush()
+	if e.err == nil {
+		e.err = errClosed
+		return nil
+	}
+	return e.err
+}
+
+var errClosed = errors.New("jsonstream: encoder closed")
+
+// DefaultMaxLineBytes bounds the lines a LineDecoder reads when its
+// MaxLineBytes is zero.
+const DefaultMaxLineBytes = 1 << 20
+
+// ErrLineTooLong is returned for a line longer than the decoder's limit.
+var ErrLineTooLong = errors.New("jsonstream: line too long")
+
+// LineDecoder reads JSON lines: each line holds one JSON value, decoded
+// into a T. Blank lines are skipped. Use it as
+//
+//	d := jsonstream.NewLineDecoder[T](r)
+//	for d.Next() {
+//		use(d.Value())
+//	}
+//	if err := d.Err(); err != nil { ... }
+type LineDecoder[T any] struct {
+	// MaxLineBytes bounds each line; zero means DefaultMaxLineBytes.
+	// Set it before the first Next.
+	MaxLineBytes int
+
+	r     *bufio.Reader
+	line  int
+	value T
+	err   error
+}
+
+// NewLineDecoder returns a LineDecoder reading from r.
+func NewLineDecoder[T any](r io.Reader) *LineDecoder[T] {
+	return &LineDecoder[T]{r: bufio.NewReader(r)}
+}
+
+// Next decodes the next value, reporting false at the end of the input
+// or on an error, which Err then returns. A line that is not a T is an
+// error that stops the decoding.
+func (d *LineDecoder[T]) Next() bool {
+	if d.err != nil {
+		return false
+	}
+	max := d.MaxLineBytes
+	if max <= 0 {
+		max = DefaultMaxLineBytes
+	}
+	for {
+		line, err := d.readLine(max)
+		if err != nil {
+			d.err = err
+			return false
+		}
+		d.line++
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var v T
+		if err := json.Unmarshal(line, &v); err != nil {
+			d.err = fmt.Errorf("jsonstream: line %d: %w", d.line, err)
+			return false
+		}
+		d.value = v
+		return true
+	}
+}
+
+// readLine returns the next line without its newline, or io.EOF at the
+// end of the input.
+func (d *LineDecoder[T]) readLine(max int) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := d.r.ReadSlice('\n')
+		line = append(line, chunk...)
+		if len(line) > max+1 || len(line) > max && line[len(line)-1] != '\n' {
+			return nil, fmt.Errorf("%w: line %d is over %d bytes", ErrLineTooLong, d.line+1, max)
+		}
+		switch {
+		case err == nil:
+			return line[:len(line)-1], nil
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		case err == io.EOF && len(line) > 0:
+			return line, nil
+		default:
+			return nil, err
+		}
+	}
+}
+
+// Value returns the value decoded by the last successful Next.
+func (d *LineDecoder[T]) Value() T { return d.value }
+
+// Line returns the number of the line last read, counting from 1.
+func (d *LineDecoder[T]) Line() int { return d.line }
+
+// Err returns the error that stopped Next, or nil at the end of the
+// input.
+func (d *LineDecoder[T]) Err() error {
+	if d.err == io.EOF {
+		return nil
+	}
+	return d.err
+}
+
+// ArrayDecoder reads the elements of one JSON array, such as the output
+// of an ArrayEncoder, decoding each into a T. It is used like a
+// LineDecoder.
+type ArrayDecoder[T any] struct {
+	dec     *json.Decoder
+	started bool
+	n       int
+	value   T
+	err     error
+}
+
+// NewArrayDecoder returns an ArrayDecoder reading from r.
+func NewArrayDecoder[T any](r io.Reader) *ArrayDecoder[T] {
+	return &ArrayDecoder[T]{dec: json.NewDecoder(r)}
+}
+
+// Next decodes the next element, reporting false after the last one or
+// on an error, which Err then returns.
+func (d *ArrayDecoder[T]) Next() bool {
+	if d.err != nil {
+		return false
+	}
+	if !d.started {
+		d.started = true
+		tok, err := d.dec.Token()
+		if err != nil {
+			d.err = fmt.Errorf("jsonstream: %w", err)
+			return false
+		}
+		if tok != json.Delim('[') {
+			d.err = fmt.Errorf("jsonstream: want an array, found %v", tok)
+			return false
+		}
+	}
+	if !d.dec.More() {
+		if _, err := d.dec.Token(); err != nil {
+			d.err = fmt.Errorf("jsonstream: %w", err)
+		} else {
+			d.err = io.EOF
+		}
+		return false
+	}
+	var v T
+	if err := d.dec.Decode(&v); err != nil {
+		d.err = fmt.Errorf("jsonstream: element %d: %w", d.n, err)
+		return false
+	}
+	d.n++
+	d.value = v
+	return true
+}
+
+// Value returns the element decoded by the last successful Next.
+func (d *ArrayDecoder[T]) Value() T { return d.value }
+
+// Err returns the error that stopped Next, or nil after the whole array.
+func (d *ArrayDecoder[T]) Err() error {
+	if d.err == io.EOF {
+		return nil
+	}
+	return d.err
+}
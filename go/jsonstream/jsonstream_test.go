@@ -0,0 +1,127 @@
+package jsonstream
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+type item struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestArrayEncoder(t *testing.T) {
+	items := []item{{"a", 1}, {"<b>", 2}, {"c", 3}}
+	var b strings.Builder
+	enc := NewArrayEncoder(&b)
+	for _, it := range items {
+		if err := enc.Encode(it); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var want strings.Builder
+	json.NewEncoder(&want).Encode(items)
+	if b.String() != want.String() || enc.Len() != 3 {
+		t.Fatalf("got %q, want %q", b.String(), want.String())
+	}
+	if err := enc.Encode(item{}); err == nil {
+		t.Fatal("Encode after Close succeeded")
+	}
+
+	b.Reset()
+	if err := NewArrayEncoder(&b).Close(); err != nil || b.String() != "[]\n" {
+		t.Fatalf("empty: %q, %v", b.String(), err)
+	}
+}
+
+func TestArrayEncoderError(t *testing.T) {
+	var b strings.Builder
+	enc := NewArrayEncoder(&b)
+	enc.Encode(1)
+	err := enc.Encode(math.Inf(1))
+	if err == nil || !strings.Contains(err.Error(), "element 1") {
+		t.Fatalf("err %v", err)
+	}
+	if enc.Encode(2) != err || enc.Close() != err {
+		t.Fatal("error not sticky")
+	}
+}
+
+func TestLineDecoder(t *testing.T) {
+	in := `{"name":"a","count":1}
+
+{"name":"b","count":2}
+{"name":"c"}`
+	d := NewLineDecoder[item](strings.NewReader(in))
+	var got []item
+	for d.Next() {
+		got = append(got, d.Value())
+	}
+	if err := d.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[1] != (item{"b", 2}) || got[2] != (item{Name: "c"}) || d.Line() != 4 {
+		t.Fatalf("got %+v at line %d", got, d.Line())
+	}
+
+	d = NewLineDecoder[item](strings.NewReader("{\"name\":\"a\"}\n{\"name\":\n"))
+	for d.Next() {
+	}
+	if err := d.Err(); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("bad line: %v", err)
+	}
+}
+
+func TestLineDecoderTooLong(t *testing.T) {
+	long := `{"name":"` + strings.Repeat("x", 10000) + `"}`
+	d := NewLineDecoder[item](strings.NewReader(long + "\n"))
+	if !d.Next() || len(d.Value().Name) != 10000 {
+		t.Fatalf("long line: %v", d.Err())
+	}
+
+	d = NewLineDecoder[item](strings.NewReader(`{"name":"a"}` + "\n" + long))
+	d.MaxLineBytes = 100
+	if !d.Next() || d.Next() || !errors.Is(d.Err(), ErrLineTooLong) {
+		t.Fatalf("err %v", d.Err())
+	}
+	// A line of exactly the limit is allowed, with or without a newline.
+	for _, in := range []string{"12345\n", "12345"} {
+		d := NewLineDecoder[int](strings.NewReader(in))
+		d.MaxLineBytes = 5
+		if !d.Next() || d.Value() != 12345 {
+			t.Fatalf("%q: %v", in, d.Err())
+		}
+	}
+}
+
+func TestArrayDecoder(t *testing.T) {
+	var b strings.Builder
+	enc := NewArrayEncoder(&b)
+	enc.Encode(item{"a", 1})
+	enc.Encode(item{"b", 2})
+	enc.Close()
+
+	d := NewArrayDecoder[item](strings.NewReader(b.String()))
+	var got []item
+	for d.Next() {
+		got = append(got, d.Value())
+	}
+	if err := d.Err(); err != nil || len(got) != 2 || got[1] != (item{"b", 2}) {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+
+	for _, in := range []string{`{"name":"a"}`, `[{"name":1}]`, `[{"name":"a"}`} {
+		d := NewArrayDecoder[item](strings.NewReader(in))
+		for d.Next() {
+		}
+		if d.Err() == nil {
+			t.Errorf("%s: no error", in)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Package logging sets up the log/slog loggers that the program and its
+// packages log through: text or JSON at a chosen level, a logger per
+// component, and attributes carried by a context, such as the ID of the
+// request or job being handled, that are added to everything logged
+// with that context.
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Keys of the attributes this package adds.
+const (
+	KeyComponent = "component"
+	KeyRequestID = "request_id"
+	KeyJobID     = "job_id"
+)
+
+// Format is the encoding of log records.
+type Format int
+
+const (
+	// Text writes key=value pairs, as slog.TextHandler does.
+	Text Format = iota
+	// JSON writes a JSON object per line, as slog.JSONHandler does.
+	JSON
+)
+
+var formatNames = [...]string{Text: "text", JSON: "json"}
+
+// ErrFormat is returned by Format.UnmarshalText for an unknown format.
+var ErrFormat = errors.New("logging: format must be text or json")
+
+func (f Format) String() string {
+	if f < 0 || int(f) >= len(formatNames) {
+		return fmt.Sprintf("Format(%d)", int(f))
+	}
+	return formatNames[f]
+}
+
+// MarshalText returns the name of f, such as "json".
+func (f Format) MarshalText() ([]byte, error) { return []byte(f.String()), nil }
+
+// UnmarshalText sets f from its name, so that a Format can be a flag or
+// a setting.
+func (f *Format) UnmarshalText(b []byte) error {
+	for i, name := range formatNames {
+		if strings.EqualFold(string(b), name) {
+			*f = Format(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w, not %q", ErrFormat, b)
+}
+
+// Options configure a logger made by New.
+type Options struct {
+	// Level is the minimum level logged; nil means slog.LevelInfo.
+	Level  slog.Leveler
+	Format Format
+	// AddSource adds the file and line of the logging call.
+	AddSource bool
+}
+
+// New returns a logger writing to w as opts say whose records get the
+// attributes of their context; see With.
+func New(w io.Writer, opts Options) *slog.Logger {
+	return slog.New(NewHandler(w, opts))
+}
+
+// NewHandler returns the handler of a logger made by New.
+func NewHandler(w io.Writer, opts Options) slog.Handler {
+	ho := &slog.HandlerOptions{Level: opts.Level, AddSource: opts.AddSource}
+	if opts.Format == JSON {
+		return ContextHandler(slog.NewJSONHandler(w, ho))
+	}
+	return ContextHandler(slog.NewTextHandler(w, ho))
+}
+
+// Component returns logger, or slog.Default() if it is nil, with the
+// component attribute set to name, for the logger of a package or
+// subsystem such as "server".
+func Component(logger *slog.Logger, name string) *slog.Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return logger.With(KeyComponent, name)
+}
+
+type attrsKey struct{}
+
+// With returns a context carrying args, alternating keys and values or
+// slog.Attrs as for slog.Logger.With, in addition to those ctx carries;
+// a key ctx already has gets the new value. Handlers made by
+// ContextHandler add them to the records logged with the context.
+func With(ctx context.Context, args ...any) context.Context {
+	if len(args) == 0 {
+		return ctx
+	}
+	r := slog.Record{}
+	r.Add(args...)
+	var added []slog.Attr
+	keys := map[string]bool{}
+	r.Attrs(func(a slog.Attr) bool {
+		added = append(added, a)
+		keys[a.Key] = true
+		return true
+	})
+	var attrs []slog.Attr
+	for _, a := range Attrs(ctx) {
+		if !keys[a.Key] {
+			attrs = append(attrs, a)
+		}
+	}
+	return context.WithValue(ctx, attrsKey{}, append(attrs, added...))
+}
+
+// Attrs returns the attributes that ctx carries.
+func Attrs(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+// WithRequestID returns a context carrying the ID of a request.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return With(ctx, KeyRequestID, id)
+}
+
+// WithJobID returns a context carrying the ID of a job.
+func WithJobID(ctx context.Context, id string) context.Context {
+	return With(ctx, KeyJobID, id)
+}
+
+// RequestID returns the request ID that ctx carries, or "" if none.
+func RequestID(ctx context.Context) string { return stringAttr(ctx, KeyRequestID) }
+
+// JobID returns the job ID that ctx carries, or "" if none.
+func JobID(ctx context.Context) string { return stringAttr(ctx, KeyJobID) }
+
+func stringAttr(ctx context.Context, key string) string {
+	for _, a := range Attrs(ctx) {
+		if a.Key == key {
+			return a.Value.String()
+		}
+	}
+	return ""
+}
+
+type levelKey struct{}
+
+// NewLevelContext returns a context carrying level, the level of the
+// program's logger, for code that changes it while the program runs,
+// such as a server's admin endpoints.
+func NewLevelContext(ctx context.Context, level *slog.LevelVar) context.Context {
+	return context.WithValue(ctx, levelKey{}, level)
+}
+
+// LevelFromContext returns the level NewLevelContext stored in ctx, or
+// nil if there is none.
+func LevelFromContext(ctx context.Context) *slog.LevelVar {
+	level, _ := ctx.Value(levelKey{}).(*slog.LevelVar)
+	return level
+}
+
+// ContextHandler returns a handler that adds the attributes of each
+// record's context, as With set them, before passing it to h. An
+// attribute the record already has is not added again.
+func ContextHandler(h slog.Handler) slog.Handler {
+	if ch, ok := h.(contextHandler); ok {
+		return ch
+	}
+	return contextHandler{h}
+}
+
+type contextHandler struct{ slog.Handler }
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx == nil {
+		return h.Handler.Handle(ctx, r)
+	}
+	attrs := Attrs(ctx)
+	if len(attrs) == 0 {
+		return h.Handler.Handle(ctx, r)
+	}
+	have := map[string]bool{}
+	r.Attrs(func(a slog.Attr) bool {
+		have[a.Key] = true
+		return true
+	})
+	r = r.Clone()
+	for _, a := range attrs {
+		if !have[a.Key] {
+			r.AddAttrs(a)
+			have[a.Key] = true
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,106 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestFormatText(t *testing.T) {
+	for _, f := range []Format{Text, JSON} {
+		b, _ := f.MarshalText()
+		var got Format
+		if err := got.UnmarshalText(b); err != nil || got != f {
+			t.Errorf("%v: round trip gave %v, %v", f, got, err)
+		}
+	}
+	var f Format
+	if err := f.UnmarshalText([]byte("xml")); !errors.Is(err, ErrFormat) {
+		t.Fatalf("xml: %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, Options{Level: slog.LevelWarn, Format: JSON})
+	logger.Info("hidden")
+	Component(logger, "server").Warn("shown", "n", 1)
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("%v: %q", err, buf.String())
+	}
+	if entry["msg"] != "shown" || entry[KeyComponent] != "server" || entry["n"] != 1.0 {
+		t.Fatalf("entry = %v", entry)
+	}
+
+	buf.Reset()
+	New(&buf, Options{}).Debug("hidden")
+	New(&buf, Options{}).Info("text", "k", "v")
+	if got := buf.String(); !strings.Contains(got, "msg=text k=v") || strings.Contains(got, "hidden") {
+		t.Fatalf("text: %q", got)
+	}
+}
+
+func TestContextAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, Options{Format: JSON})
+	ctx := WithRequestID(context.Background(), "req-1")
+	ctx = WithJobID(ctx, "job-1")
+	ctx = With(ctx, "user", 7, KeyJobID, "job-2")
+	if RequestID(ctx) != "req-1" || JobID(ctx) != "job-2" || JobID(context.Background()) != "" {
+		t.Fatalf("IDs %q and %q", RequestID(ctx), JobID(ctx))
+	}
+	if got := len(Attrs(ctx)); got != 3 {
+		t.Fatalf("%d attributes: %v", got, Attrs(ctx))
+	}
+	logger.InfoContext(ctx, "step", KeyRequestID, "own")
+	logger.Info("no context")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var first, second map[string]any
+	json.Unmarshal([]byte(lines[0]), &first)
+	json.Unmarshal([]byte(lines[1]), &second)
+	if first[KeyRequestID] != "own" || first[KeyJobID] != "job-2" || first["user"] != 7.0 {
+		t.Errorf("with context: %s", lines[0])
+	}
+	if strings.Count(lines[0], KeyRequestID) != 1 {
+		t.Errorf("request ID repeated: %s", lines[0])
+	}
+	if _, ok := second[KeyJobID]; ok {
+		t.Errorf("without context: %s", lines[1])
+	}
+	if With(ctx) != ctx {
+		t.Error("With without attributes made a new context")
+	}
+}
+
+func TestLevelContext(t *testing.T) {
+	if LevelFromContext(context.Background()) != nil {
+		t.Fatal("level in an empty context")
+	}
+	level := new(slog.LevelVar)
+	var b bytes.Buffer
+	logger := New(&b, Options{Level: level})
+	LevelFromContext(NewLevelContext(context.Background(), level)).Set(slog.LevelWarn)
+	logger.Info("hidden")
+	logger.Warn("shown")
+	if got := b.String(); strings.Contains(got, "hidden") || !strings.Contains(got, "shown") {
+		t.Fatalf("log %q", got)
+	}
+}
+
+func TestContextHandlerWraps(t *testing.T) {
+	var buf bytes.Buffer
+	h := ContextHandler(slog.NewTextHandler(&buf, nil))
+	if ContextHandler(h) != h {
+		t.Fatal("wrapped twice")
+	}
+	slog.New(h).With("a", 1).WithGroup("g").InfoContext(WithJobID(context.Background(), "j"), "m", "b", 2)
+	if got := buf.String(); !strings.Contains(got, "a=1 g.b=2 g.job_id=j") {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -1,50 +1,351 @@
+// Command tutorial bundles the tools built on the tutorial's packages as
+// subcommands: word counting, a file tree lister, search, an HTTP server
+// and more. Run it without arguments for the list.
 package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
-	"strings"
+	"syscall"
 	"time"
+
+	"example.com/tutorial/assets"
+	"example.com/tutorial/config"
+	"example.com/tutorial/fileutil"
+	"example.com/tutorial/i18n"
+	"example.com/tutorial/logging"
+	"example.com/tutorial/migrate"
+	"example.com/tutorial/output"
+	"example.com/tutorial/profiling"
+	"example.com/tutorial/shutdown"
+	"example.com/tutorial/text"
+	"example.com/tutorial/user/sqlite"
 )
 
-type User struct {
-	ID   int
-	Name string
+// progName is the program name used in usage messages.
+const progName = "tutorial"
+
+// helpSummary describes the help command, which is not in the table.
+const helpSummary = "print this help, or a command's flags"
+
+// profilingGrace bounds how long a command that ends waits for pprof
+// requests still in flight.
+const profilingGrace = 5 * time.Second
+
+// command is a subcommand of the program.
+type command struct {
+	name    string
+	summary string // one line for the command list
+	// run runs the command with the arguments that follow its name and
+	// returns the process exit code: 2 for usage errors, 1 for others.
+	// s holds the defaults of its flags.
+	run func(ctx context.Context, s *settings, args []string, stdin io.Reader, stdout, stderr io.Writer) int
 }
 
-func WordCount(s string) map[string]int {
-	m := map[string]int{}
-	for _, w := range strings.Fields(strings.ToLower(s)) {
-		m[w]++
+// commands returns the subcommands, in the order help lists them.
+func commands() []command {
+	return []command{
+		{"wordcount", "count lines, words and characters, or the most frequent words",
+			func(ctx context.Context, s *settings, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+				return runWordCount(ctx, s.WordCount, args, stdin, stdout, stderr)
+			}},
+		{"report", "render a Markdown or HTML report of the words of files",
+			func(ctx context.Context, s *settings, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+				return runReport(ctx, s.Report, s.DryRun, args, stdin, stdout, stderr)
+			}},
+		{"export", "export the word frequencies of files as partitioned CSV or Parquet",
+			func(ctx context.Context, s *settings, args []string, _ io.Reader, stdout, stderr io.Writer) int {
+				return runExport(ctx, s.Export, s.DryRun, args, stdout, stderr)
+			}},
+		{"compare", "compare the word frequencies of two count results or directories",
+			func(ctx context.Context, s *settings, args []string, _ io.Reader, stdout, stderr io.Writer) int {
+				return runCompare(ctx, s.Compare, args, stdout, stderr)
+			}},
+		{"search", "print the lines of files that match a regular expression",
+			func(ctx context.Context, s *settings, args []string, _ io.Reader, stdout, stderr io.Writer) int {
+				return runSearch(ctx, s.Search, args, stdout, stderr)
+			}},
+		{"tree", "list a directory tree",
+			func(_ context.Context, s *settings, args []string, _ io.Reader, stdout, stderr io.Writer) int {
+				return runTree(s.Tree, args, stdout, stderr)
+			}},
+		{"du", "summarize disk usage of directories",
+			func(ctx context.Context, s *settings, args []string, _ io.Reader, stdout, stderr io.Writer) int {
+				return runDu(ctx, s.Du, args, stdout, stderr)
+			}},
+		{"dedupe", "find files with identical contents",
+			func(ctx context.Context, s *settings, args []string, _ io.Reader, stdout, stderr io.Writer) int {
+				return runDedupe(ctx, s.Dedupe, s.DryRun, args, stdout, stderr)
+			}},
+		{"archive", "create and extract .tar.gz and .zip archives",
+			func(_ context.Context, s *settings, args []string, _ io.Reader, stdout, stderr io.Writer) int {
+				return runArchive(s.Archive, s.DryRun, args, stdout, stderr)
+			}},
+		{"watch", "keep word frequencies of a directory current as files change",
+			func(ctx context.Context, s *settings, args []string, _ io.Reader, stdout, stderr io.Writer) int {
+				return runWatch(ctx, s.Watch, args, stdout, stderr)
+			}},
+		{"serve", "serve the HTTP API",
+			func(ctx context.Context, s *settings, args []string, _ io.Reader, stdout, stderr io.Writer) int {
+				return runServe(ctx, s.Serve, s.Assets, args, stdout, stderr)
+			}},
+		{"users", "list and edit the users of a user file",
+			func(ctx context.Context, s *settings, args []string, _ io.Reader, stdout, stderr io.Writer) int {
+				return runUsers(ctx, s.Users, s.DryRun, args, stdout, stderr)
+			}},
+		{"repl", "load files and users and explore them at a prompt",
+			func(ctx context.Context, s *settings, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+				return runREPL(ctx, s.Repl, s.DryRun, args, stdin, stdout, stderr)
+			}},
+		{"migrate", "apply or roll back the migrations of an SQLite user database",
+			func(ctx context.Context, s *settings, args []string, _ io.Reader, stdout, stderr io.Writer) int {
+				return runMigrate(ctx, s.Migrate, s.Assets, s.DryRun, args, stdout, stderr)
+			}},
+		{"config", "print the effective settings",
+			func(_ context.Context, s *settings, args []string, _ io.Reader, stdout, stderr io.Writer) int {
+				return runConfig(*s, args, stdout, stderr)
+			}},
+		{"completion", "print a bash, zsh or fish completion script",
+			func(ctx context.Context, _ *settings, args []string, _ io.Reader, stdout, stderr io.Writer) int {
+				return runCompletion(ctx, args, stdout, stderr)
+			}},
+		{"diag", "print runtime diagnostics of this process or a server as JSON",
+			func(ctx context.Context, _ *settings, args []string, _ io.Reader, stdout, stderr io.Writer) int {
+				return runDiag(ctx, args, stdout, stderr)
+			}},
+		{"admin", "show or change the runtime settings of a running server",
+			func(ctx context.Context, s *settings, args []string, _ io.Reader, stdout, stderr io.Writer) int {
+				return runAdmin(ctx, s.Serve, args, stdout, stderr)
+			}},
+		{"version", "print the version and build information",
+			func(_ context.Context, _ *settings, args []string, _ io.Reader, stdout, stderr io.Writer) int {
+				return runVersion(args, stdout, stderr)
+			}},
+		{"demo", "run the language and standard library demo",
+			func(ctx context.Context, s *settings, args []string, _ io.Reader, stdout, stderr io.Writer) int {
+				return runDemo(ctx, s.Assets, args, stdout, stderr)
+			}},
 	}
-	return m
 }
 
-func main() {
-	fmt.Println("=== Go demo ===")
+// newExecutor returns the executor of a command's changes to files: nil,
+// which makes them, or for -dry-run one that prints them to w instead.
+func newExecutor(dryRun bool, w io.Writer) *fileutil.Executor {
+	if !dryRun {
+		return nil
+	}
+	return &fileutil.Executor{DryRun: true, Log: w}
+}
 
-	u := User{ID: 1, Name: "Ada"}
-	fmt.Printf("User: %+v\n", u)
+// assetDir returns the subdirectory sub of the -assets directory, whose
+// files override the built-in assets of that name, or "" without
+// -assets.
+func assetDir(dir, sub string) string {
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, sub)
+}
 
-	fmt.Println("WordCount:", WordCount("Go go gophers!"))
+// assetMigrations returns the migrations of the SQLite user database:
+// the files of the migrations directory of -assets in front of the
+// built-in ones.
+func assetMigrations(dir string) ([]migrate.Migration, error) {
+	return migrate.Load(assets.Override(sqlite.MigrationFiles(), assetDir(dir, "migrations")), ".")
+}
 
-	here, _ := os.Getwd()
-	entries, _ := os.ReadDir(filepath.Dir(here))
-	for i, e := range entries {
-		if i >= 3 {
-			break
+func lookup(name string) (command, bool) {
+	for _, c := range commands() {
+		if c.name == name {
+			return c, true
 		}
-		fmt.Println("-", e.Name())
 	}
+	return command{}, false
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
-	defer cancel()
-	select {
-	case <-time.After(1 * time.Millisecond):
-		fmt.Println("timer fired")
-	case <-ctx.Done():
-		fmt.Println("timeout")
+// globalFlags are the flags given before the subcommand, shared by all
+// of them. Those that are settings default to the loaded ones.
+type globalFlags struct {
+	config string
+	dir    string
+}
+
+func (g *globalFlags) register(fs *flag.FlagSet, s *settings) {
+	fs.StringVar(&g.config, "config", g.config, "read settings from the YAML or JSON `file` ($"+envConfig+")")
+	fs.StringVar(&g.dir, "C", "", "change to `dir` before running the command")
+	fs.DurationVar(&s.Timeout, "timeout", s.Timeout, "stop the command after `duration`, 0 for no limit")
+	fs.TextVar(&s.Logging.Level, "log-level", s.Logging.Level, "minimum `level` logged to stderr: debug, info, warn or error")
+	fs.TextVar(&s.Logging.Format, "log-format", s.Logging.Format, "log `format`: text or json")
+	fs.TextVar(&s.Color, "color", s.Color, "color output `when`: auto (on a terminal without $NO_COLOR), always or never")
+	fs.StringVar(&s.Pprof, "pprof", s.Pprof, "serve net/http/pprof on `address` while the command runs (a port alone means localhost)")
+	fs.StringVar(&s.Trace, "trace", s.Trace, "write a runtime execution trace of the command to `file`")
+	fs.BoolVar(&s.DryRun, "dry-run", s.DryRun, "print the files that archive, dedupe -delete, repl and users would change, and change none")
+	fs.StringVar(&s.Lang, "lang", s.Lang, "print messages in the language of `locale`, such as fr, instead of that of $LC_ALL, $LC_MESSAGES or $LANG")
+	fs.StringVar(&s.Assets, "assets", s.Assets, "read the files of `dir`/static, dir/templates, dir/sample, dir/stopwords and dir/migrations in place of the built-in ones")
+}
+
+// usage writes the program's usage: the global flags and the commands.
+func usage(w io.Writer, fs *flag.FlagSet) {
+	fmt.Fprintf(w, "usage: %s [-config file] [-C dir] [-timeout duration] [-log-level level] [-log-format format] [-color when] [-pprof address] [-trace file] [-dry-run] [-lang locale] [-assets dir] command [arguments]\n\n", progName)
+	fmt.Fprintln(w, "Commands:")
+	cmds := commands()
+	width := len("help")
+	for _, c := range cmds {
+		width = max(width, len(c.name))
+	}
+	for _, c := range cmds {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, c.name, c.summary)
+	}
+	fmt.Fprintf(w, "  %-*s  %s\n", width, "help", helpSummary)
+	fmt.Fprintln(w, "\nGlobal flags:")
+	out := fs.Output()
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+	fs.SetOutput(out)
+	fmt.Fprintf(w, "\nRun '%s help command' for the flags of a command and '%s config' for\n", progName, progName)
+	fmt.Fprintf(w, "the settings that a configuration file or $%s_* variables may change.\n", envPrefix)
+}
+
+// run parses the global flags in args, the program's arguments, loads
+// the settings and runs the subcommand that follows them, returning the
+// process exit code. Settings come, each layer overriding the last, from
+// defaultSettings, the -config file, $TUTORIAL_* variables and flags.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	s := defaultSettings()
+	g := globalFlags{config: os.Getenv(envConfig)}
+
+	// A first pass finds the configuration file; the second reports
+	// errors and sets the flags over the loaded settings.
+	pre := flag.NewFlagSet(progName, flag.ContinueOnError)
+	pre.SetOutput(io.Discard)
+	pre.Usage = func() {}
+	preSettings := s
+	g.register(pre, &preSettings)
+	pre.Parse(args)
+	if err := config.Load(&s, config.Options{File: g.config, EnvPrefix: envPrefix}); err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", progName, err)
+		return 2
+	}
+
+	fs := flag.NewFlagSet(progName, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	g.register(fs, &s)
+	fs.Usage = func() { usage(stderr, fs) }
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
 	}
-}
\ No newline at end of file
+	if err := config.Validate(s); err != nil {
+		fmt.Fprintf(stderr, "%s: settings: %v\n", progName, err)
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	name, args := fs.Arg(0), fs.Args()[1:]
+	// The commands print through output, which colors text for a
+	// terminal as -color says, and in the language -lang or the
+	// environment picks, through the i18n.Printer of ctx.
+	stdout, stderr = output.New(stdout, s.Color), output.New(stderr, s.Color)
+	p := i18n.For(s.Lang, i18n.Env(os.Getenv))
+	ctx = i18n.NewContext(ctx, p)
+
+	if name == "help" {
+		if len(args) == 0 {
+			usage(stdout, fs)
+			return 0
+		}
+		c, ok := lookup(args[0])
+		if !ok {
+			fmt.Fprintln(stderr, p.Sprintf("%s help: unknown command %q", progName, args[0]))
+			return 2
+		}
+		// Every command prints its usage on -help, which unlike -h is not
+		// a flag of du or dedupe.
+		c.run(ctx, &s, []string{"-help"}, stdin, stdout, stdout)
+		return 0
+	}
+	c, ok := lookup(name)
+	if !ok {
+		fmt.Fprintln(stderr, p.Sprintf("%s: unknown command %q", progName, name))
+		fmt.Fprintln(stderr, p.Sprintf("Run '%s help' for the list of commands.", progName))
+		return 2
+	}
+
+	if g.dir != "" {
+		if err := os.Chdir(g.dir); err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", progName, err)
+			return 1
+		}
+	}
+	// The stopword lists of -assets replace the built-in ones in every
+	// analyzer before the command counts anything.
+	if s.Assets != "" {
+		if err := text.ReloadStopwords(assets.Override(text.StopwordFiles(), assetDir(s.Assets, "stopwords"))); err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", progName, err)
+			return 1
+		}
+	}
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	// Package log and slog's default logger, which the commands and the
+	// packages they use log through, write to stderr at the chosen level
+	// and in the chosen format.
+	// The level is a slog.LevelVar in ctx, so that serve's admin
+	// endpoints can change it.
+	level := new(slog.LevelVar)
+	level.Set(s.Logging.Level)
+	ctx = logging.NewLevelContext(ctx, level)
+	logger := logging.New(stderr, logging.Options{Level: level, Format: s.Logging.Format})
+	defaultLogger, logFlags, logOut := slog.Default(), log.Flags(), log.Writer()
+	slog.SetDefault(logger)
+	defer func() {
+		slog.SetDefault(defaultLogger)
+		log.SetFlags(logFlags)
+		log.SetOutput(logOut)
+	}()
+
+	prof, err := profiling.Start(profiling.Options{PprofAddr: s.Pprof, TraceFile: s.Trace})
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", progName, err)
+		return 1
+	}
+	if addr := prof.Addr(); addr != nil {
+		logger.Info("serving pprof", "url", "http://"+addr.String()+"/debug/pprof/")
+	}
+	// Profiling stops when the command returns, or as soon as main's
+	// coordinator sees SIGINT or SIGTERM. A profile still being
+	// downloaded gets a moment to finish.
+	sd := shutdown.FromContext(ctx)
+	if sd == nil {
+		sd = shutdown.New()
+	}
+	sd.Add("profiling", profilingGrace, prof.Stop)
+	code := c.run(ctx, &s, args, stdin, stdout, stderr)
+	if err := sd.Shutdown(context.Background()); err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", progName, err)
+		code = max(code, 1)
+	}
+	return code
+}
+
+func main() {
+	sd := shutdown.New()
+	ctx, stop := sd.Notify(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(shutdown.NewContext(ctx, sd), os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
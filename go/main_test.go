@@ -1,10 +1,202 @@
 package main
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
 
-func TestWordCount(t *testing.T) {
-	c := WordCount("Hello hello world")
-	if c["hello"] != 2 || c["world"] != 1 {
-		t.Fatalf("unexpected counts: %#v", c)
+	"example.com/tutorial/shutdown"
+	"example.com/tutorial/text"
+	"example.com/tutorial/workspace"
+)
+
+// writeTree writes files, keyed by slash-separated name, to a workspace
+// removed when the test ends, and returns its root.
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	ws, err := workspace.New(context.Background(), workspace.Config{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	for name, content := range files {
+		if err := ws.WriteFile(name, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ws.Root()
+}
+
+func TestRun(t *testing.T) {
+	t.Setenv("LC_ALL", "C") // English, whatever the locale of the tests
+	tests := []struct {
+		args     []string
+		code     int
+		stdout   string // substring of stdout
+		stderr   string // substring of stderr
+		noStdout bool
+	}{
+		{args: nil, code: 2, stderr: "Commands:\n  wordcount", noStdout: true},
+		{args: []string{"help"}, code: 0, stdout: "Global flags:\n  -C dir"},
+		{args: []string{"help", "tree"}, code: 0, stdout: "usage: tree [-depth n]"},
+		{args: []string{"help", "du"}, code: 0, stdout: "usage: du"}, // du -h is a flag
+		{args: []string{"help", "nope"}, code: 2, stderr: `unknown command "nope"`},
+		{args: []string{"nope"}, code: 2, stderr: "Run 'tutorial help'", noStdout: true},
+		{args: []string{"-lang", "fr", "nope"}, code: 2, stderr: "tutorial : commande inconnue \"nope\"\nLancez « tutorial help »", noStdout: true},
+		{args: []string{"-nope", "tree"}, code: 2, stderr: "flag provided but not defined", noStdout: true},
+		{args: []string{"-timeout", "1m", "wordcount", "-w"}, code: 0, stdout: "       3\n"},
+		{args: []string{"tree", "-x"}, code: 2, stderr: "usage: tree", noStdout: true},
+		{args: []string{"-color", "always", "wordcount", "-top", "1"}, code: 0, stdout: "\x1b[1mWORD  COUNT\x1b[0m\none"},
+		{args: []string{"wordcount", "-top", "1"}, code: 0, stdout: "WORD  COUNT\none"},
+		{args: []string{"-color", "rainbow", "tree"}, code: 2, stderr: "auto, always or never", noStdout: true},
+	}
+	for _, tt := range tests {
+		var out, errOut bytes.Buffer
+		code := run(context.Background(), tt.args, strings.NewReader("one two three"), &out, &errOut)
+		if code != tt.code {
+			t.Errorf("%v: exit %d, want %d (%s)", tt.args, code, tt.code, errOut.String())
+		}
+		if !strings.Contains(out.String(), tt.stdout) || tt.noStdout && out.Len() > 0 {
+			t.Errorf("%v: stdout %q, want it to contain %q", tt.args, out.String(), tt.stdout)
+		}
+		if !strings.Contains(errOut.String(), tt.stderr) {
+			t.Errorf("%v: stderr %q, want it to contain %q", tt.args, errOut.String(), tt.stderr)
+		}
+	}
+}
+
+func TestRunLang(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "fr_CA.UTF-8")
+	dir := writeTree(t, map[string]string{"one.csv": "name\nada\n", "two.csv": "name\ngrace\nalan\n"})
+	path := filepath.Join(dir, "users.json")
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"users", "-file", path, "import", filepath.Join(dir, "one.csv")}, "1 utilisateur ajouté\n"},
+		{[]string{"users", "-file", path, "import", filepath.Join(dir, "two.csv")}, "2 utilisateurs ajoutés\n"},
+		{[]string{"-lang", "en-GB", "users", "-file", path, "import", filepath.Join(dir, "one.csv")}, "1 user added\n"},
+	} {
+		var out, errOut bytes.Buffer
+		if code := run(context.Background(), tt.args, nil, &out, &errOut); code != 0 {
+			t.Fatalf("%v: exit %d: %s", tt.args, code, errOut.String())
+		}
+		if !strings.HasSuffix(out.String(), tt.want) {
+			t.Errorf("%v: stdout %q, want it to end in %q", tt.args, out.String(), tt.want)
+		}
+	}
+}
+
+func TestRunChdir(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	dir := writeTree(t, map[string]string{"a.txt": "x"})
+	var out, errOut bytes.Buffer
+	if code := run(context.Background(), []string{"-C", dir, "tree"}, nil, &out, &errOut); code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+	if got, want := out.String(), ".\n└── a.txt\n\n0 directories, 1 file\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestRunProfiling(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "trace.out")
+	var out, errOut bytes.Buffer
+	args := []string{"-trace", file, "-pprof", "127.0.0.1:0", "wordcount", "-w"}
+	if code := run(context.Background(), args, strings.NewReader("one two three"), &out, &errOut); code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+	if !strings.Contains(errOut.String(), "msg=\"serving pprof\" url=http://127.0.0.1:") {
+		t.Errorf("stderr %q", errOut.String())
+	}
+	if fi, err := os.Stat(file); err != nil || fi.Size() == 0 {
+		t.Fatalf("trace file: %v, %v", fi, err)
+	}
+
+	errOut.Reset()
+	if code := run(context.Background(), []string{"-pprof", "no port", "wordcount"}, strings.NewReader(""), &out, &errOut); code != 1 ||
+		!strings.Contains(errOut.String(), "profiling:") {
+		t.Fatalf("bad address: exit %d: %s", code, errOut.String())
+	}
+}
+
+func TestRunAssets(t *testing.T) {
+	dir := writeTree(t, map[string]string{"sample/extra.txt": "gophers gophers gophers gophers\n"})
+	out := cli(t, "", "-assets", dir, "demo")
+	for _, want := range []string{"aesop.txt", "extra.txt", "{gophers 7}"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("demo output does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunAssetsStopwords(t *testing.T) {
+	t.Cleanup(func() {
+		if err := text.ReloadStopwords(text.StopwordFiles()); err != nil {
+			t.Fatal(err)
+		}
+	})
+	args := []string{"wordcount", "-analyzer", "english", "-top", "-1", "-format", "csv"}
+	if out := cli(t, "the gopher and the go", args...); strings.Contains(out, "the,") || !strings.Contains(out, "gopher,1") {
+		t.Fatalf("built-in stopwords:\n%s", out)
+	}
+	// The English list on disk wins; the others stay built in.
+	dir := writeTree(t, map[string]string{"stopwords/english.txt": "# ours\ngopher\n"})
+	out := cli(t, "the gopher and the go", append([]string{"-assets", dir}, args...)...)
+	if strings.Contains(out, "gopher") || !strings.Contains(out, "the,2") {
+		t.Errorf("stopwords of -assets:\n%s", out)
+	}
+	if !text.FrenchStopwords.Contains("les") {
+		t.Errorf("French list lost")
+	}
+}
+
+func TestRunAssetsMigrations(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"migrations/0004_add_email.up.sql":   "ALTER TABLE users ADD COLUMN email TEXT NOT NULL DEFAULT '';\n",
+		"migrations/0004_add_email.down.sql": "ALTER TABLE users DROP COLUMN email;\n",
+	})
+	db := filepath.Join(t.TempDir(), "users.db")
+	out := cli(t, "", "-assets", dir, "migrate", "-db", db, "up")
+	if !strings.Contains(out, "applied 0003 string_ids\n") || !strings.Contains(out, "applied 0004 add_email\n") {
+		t.Fatalf("migrate up:\n%s", out)
+	}
+	// Without -assets, the binary knows only its own migrations.
+	var stdout, stderr bytes.Buffer
+	if code := run(context.Background(), []string{"migrate", "-db", db, "status"}, nil, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "version 4") {
+		t.Errorf("status without -assets: exit %d: %s", code, stderr.String())
+	}
+}
+
+func TestRunShutdown(t *testing.T) {
+	sd := shutdown.New()
+	ran := false
+	sd.Add("test", time.Second, func(context.Context) error {
+		ran = true
+		return nil
+	})
+	ctx := shutdown.NewContext(context.Background(), sd)
+	var out, errOut bytes.Buffer
+	if code := run(ctx, []string{"wordcount", "-w"}, strings.NewReader("one two three"), &out, &errOut); code != 0 || !ran {
+		t.Fatalf("exit %d, hook ran %t: %s", code, ran, errOut.String())
+	}
+
+	sd = shutdown.New()
+	sd.Add("test", time.Second, func(context.Context) error { return errors.New("flush failed") })
+	errOut.Reset()
+	if code := run(shutdown.NewContext(context.Background(), sd), []string{"wordcount", "-w"}, strings.NewReader(""), &out, &errOut); code != 1 ||
+		!strings.Contains(errOut.String(), "shutdown: test: flush failed") {
+		t.Fatalf("failing hook: exit %d: %s", code, errOut.String())
 	}
-}
\ No newline at end of file
+}
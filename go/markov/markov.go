@@ -0,0 +1,125 @@
+// Package markov builds word-level Markov chains from text and generates
+// new sentences from them.
+package markov
+
+import (
+	"math/rand"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"example.com/tutorial/text"
+)
+
+// Chain is an order-N Markov chain over words: the next word is chosen
+// based on the N words before it. The zero value is not usable; call New.
+type Chain struct {
+	order int
+	opts  []text.Option
+	next  map[string]*successors
+}
+
+// successors records the words seen after one prefix, in first-seen
+// order so that generation from a seeded source is reproducible.
+type successors struct {
+	words  []string
+	counts []int
+	index  map[string]int
+	total  int
+}
+
+func (s *successors) add(w string) {
+	i, ok := s.index[w]
+	if !ok {
+		i = len(s.words)
+		s.index[w] = i
+		s.words = append(s.words, w)
+		s.counts = append(s.counts, 0)
+	}
+	s.counts[i]++
+	s.total++
+}
+
+func (s *successors) pick(rng *rand.Rand) string {
+	n := rng.Intn(s.total)
+	for i, c := range s.counts {
+		if n < c {
+			return s.words[i]
+		}
+		n -= c
+	}
+	panic("unreachable")
+}
+
+// New returns an empty chain of the given order (at least 1). The text
+// options control how input is split into words.
+func New(order int, opts ...text.Option) *Chain {
+	if order < 1 {
+		order = 1
+	}
+	return &Chain{order: order, opts: opts, next: map[string]*successors{}}
+}
+
+// Order returns the number of preceding words each choice depends on.
+func (c *Chain) Order() int { return c.order }
+
+// end marks the end of a sentence in the successor lists. The tokenizer
+// never produces an empty word, so it cannot clash with real input.
+const end = ""
+
+// Add feeds s into the chain. The text is split into sentences at '.',
+// '!' and '?' so that generated sentences start and stop where real ones
+// do.
+func (c *Chain) Add(s string) {
+	for _, sentence := range strings.FieldsFunc(s, isSentenceEnd) {
+		words := text.Words(sentence, c.opts...)
+		if len(words) == 0 {
+			continue
+		}
+		state := make([]string, c.order)
+		for _, w := range append(words, end) {
+			c.successorsOf(state).add(w)
+			state = append(state[1:], w)
+		}
+	}
+}
+
+func (c *Chain) successorsOf(state []string) *successors {
+	key := strings.Join(state, "\x00")
+	s, ok := c.next[key]
+	if !ok {
+		s = &successors{index: map[string]int{}}
+		c.next[key] = s
+	}
+	return s
+}
+
+// Generate produces a sentence of at most maxWords words using rng as its
+// source of randomness; the same chain and seed always yield the same
+// sentence. It returns "" if nothing has been added.
+func (c *Chain) Generate(rng *rand.Rand, maxWords int) string {
+	state := make([]string, c.order)
+	var words []string
+	for len(words) < maxWords {
+		s, ok := c.next[strings.Join(state, "\x00")]
+		if !ok {
+			break
+		}
+		w := s.pick(rng)
+		if w == end {
+			break
+		}
+		words = append(words, w)
+		state = append(state[1:], w)
+	}
+	if len(words) == 0 {
+		return ""
+	}
+	r, size := utf8.DecodeRuneInString(words[0])
+	words[0] = string(unicode.ToUpper(r)) + words[0][size:]
+	return strings.Join(words, " ") + "."
+}
+
+func isSentenceEnd(r rune) bool {
+	return r == '.' || r == '!' || r == '?'
+}
@@ -0,0 +1,69 @@
+package markov
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+const corpus = `The cat sat on the mat. The dog sat on the log!
+The cat ate the fish. Did the dog eat the cat?`
+
+func TestGenerateReproducible(t *testing.T) {
+	c := New(1)
+	c.Add(corpus)
+	a := c.Generate(rand.New(rand.NewSource(42)), 20)
+	b := c.Generate(rand.New(rand.NewSource(42)), 20)
+	if a == "" || a != b {
+		t.Fatalf("same seed gave %q and %q", a, b)
+	}
+	if !strings.HasSuffix(a, ".") || a[0] < 'A' || a[0] > 'Z' {
+		t.Fatalf("not a sentence: %q", a)
+	}
+}
+
+func TestGenerateFollowsChain(t *testing.T) {
+	c := New(2)
+	c.Add(corpus)
+	vocab := map[string]bool{}
+	for _, w := range strings.Fields(strings.ToLower(corpus)) {
+		vocab[strings.Trim(w, ".!?")] = true
+	}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		s := c.Generate(rng, 10)
+		words := strings.Fields(strings.ToLower(strings.TrimSuffix(s, ".")))
+		if len(words) == 0 || len(words) > 10 {
+			t.Fatalf("bad sentence length: %q", s)
+		}
+		for _, w := range words {
+			if !vocab[w] {
+				t.Fatalf("generated unknown word %q in %q", w, s)
+			}
+		}
+		// With order 2 every sentence starts like one of the inputs.
+		if words[0] != "the" && words[0] != "did" {
+			t.Fatalf("unexpected start: %q", s)
+		}
+	}
+}
+
+func TestGenerateDeterministicChain(t *testing.T) {
+	c := New(1)
+	c.Add("one two three.")
+	if got := c.Generate(rand.New(rand.NewSource(7)), 10); got != "One two three." {
+		t.Fatalf("got %q", got)
+	}
+	if got := c.Generate(rand.New(rand.NewSource(7)), 2); got != "One two." {
+		t.Fatalf("maxWords not honoured: %q", got)
+	}
+}
+
+func TestGenerateEmpty(t *testing.T) {
+	if got := New(3).Generate(rand.New(rand.NewSource(1)), 10); got != "" {
+		t.Fatalf("got %q from empty chain", got)
+	}
+	if New(0).Order() != 1 {
+		t.Fatal("order below 1 should be clamped")
+	}
+}
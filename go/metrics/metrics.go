@@ -0,0 +1,197 @@
+// Package metrics is a small registry of counters, gauges and histograms
+// that can be scraped in the Prometheus text exposition format. It covers
+// what the example server reports without pulling in the full client
+// library.
+//
+// Counters, gauges and histograms are updated with atomic operations
+// only, so instrumenting hot paths costs no lock contention; locks are
+// taken only to register metrics, to create the child of a label set the
+// first time it is used, and to scrape.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets are histogram upper bounds suited to request latencies in
+// seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Registry holds metric families for exposition. It is safe for
+// concurrent use.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]family
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{families: map[string]family{}}
+}
+
+// family is a named metric with its children for each label set.
+type family interface {
+	writeText(w io.Writer)
+}
+
+func (r *Registry) register(name string, f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, dup := r.families[name]; dup {
+		panic("metrics: duplicate metric " + name)
+	}
+	r.families[name] = f
+}
+
+// WriteText writes every metric in the text exposition format, ordered
+// by name.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	fams := make([]family, len(names))
+	sort.Strings(names)
+	for i, name := range names {
+		fams[i] = r.families[name]
+	}
+	r.mu.Unlock()
+
+	ew := &errWriter{w: w}
+	for _, f := range fams {
+		f.writeText(ew)
+	}
+	return ew.err
+}
+
+// Handler serves the registry for scraping.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteText(w)
+	})
+}
+
+// errWriter remembers the first write error so callers can check once.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	n, err := e.w.Write(p)
+	e.err = err
+	return n, err
+}
+
+// desc describes a metric family.
+type desc struct {
+	name, help, typ string
+	labels          []string
+}
+
+func (d *desc) header(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, escapeHelp(d.help), d.name, d.typ)
+}
+
+// vec stores the children of a family by label values.
+type vec[M any] struct {
+	desc
+	newChild func() M
+
+	mu       sync.Mutex
+	children map[string]*child[M]
+}
+
+type child[M any] struct {
+	values []string
+	m      M
+}
+
+func newVec[M any](d desc, newChild func() M) *vec[M] {
+	return &vec[M]{desc: d, newChild: newChild, children: map[string]*child[M]{}}
+}
+
+func (v *vec[M]) with(values []string) M {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	c, ok := v.children[key]
+	if !ok {
+		c = &child[M]{values: append([]string(nil), values...), m: v.newChild()}
+		v.children[key] = c
+	}
+	return c.m
+}
+
+// sorted returns the children ordered by label values.
+func (v *vec[M]) sorted() []*child[M] {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	keys := make([]string, 0, len(v.children))
+	for k := range v.children {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]*child[M], len(keys))
+	for i, k := range keys {
+		out[i] = v.children[k]
+	}
+	return out
+}
+
+// labelString formats name="value" pairs, with extra appended, in
+// braces, or returns "" when there are none.
+func labelString(names, values []string, extra ...string) string {
+	if len(names) == 0 && len(extra) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	write := func(i int, name, value string) {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", name, escapeLabel(value))
+	}
+	for i, name := range names {
+		write(i, name, values[i])
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		write(len(names)+i, extra[i], extra[i+1])
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, +1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
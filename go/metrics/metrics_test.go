@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	reqs := r.Counter("http_requests_total", "Requests served.", "method", "code")
+	reqs.With("GET", "200").Add(3)
+	reqs.With("POST", "201").Inc()
+	r.Gauge("in_flight", "Requests in progress.").With().Set(2)
+	lat := r.Histogram("latency_seconds", "Latency.\nIn seconds.", []float64{0.1, 1})
+	for _, v := range []float64{0.05, 0.1, 0.5, 3} {
+		lat.With().Observe(v)
+	}
+	r.Counter("odd", "Odd labels.", "v").With("a\"b\\c\nd").Inc()
+
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP http_requests_total Requests served.
+# TYPE http_requests_total counter
+http_requests_total{method="GET",code="200"} 3
+http_requests_total{method="POST",code="201"} 1
+# HELP in_flight Requests in progress.
+# TYPE in_flight gauge
+in_flight 2
+# HELP latency_seconds Latency.\nIn seconds.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 2
+latency_seconds_bucket{le="1"} 3
+latency_seconds_bucket{le="+Inf"} 4
+latency_seconds_sum 3.65
+latency_seconds_count 4
+# HELP odd Odd labels.
+# TYPE odd counter
+odd{v="a\"b\\c\nd"} 1
+`
+	if got := b.String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("c", "A counter.").With().Inc()
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "\nc 1\n") {
+		t.Fatalf("body = %q", rec.Body)
+	}
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	r := NewRegistry()
+	c := r.Counter("n", "N.", "k")
+	h := r.Histogram("h", "H.", nil)
+	g := r.Gauge("g", "G.")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				c.With("x").Inc()
+				h.With().Observe(0.01)
+				g.With().Inc()
+				g.With().Dec()
+			}
+		}()
+	}
+	wg.Wait()
+	if v := c.With("x").Value(); v != 8000 {
+		t.Fatalf("counter = %v", v)
+	}
+	if n := h.With().Count(); n != 8000 {
+		t.Fatalf("histogram count = %d", n)
+	}
+	if v := g.With().Value(); v != 0 {
+		t.Fatalf("gauge = %v", v)
+	}
+}
+
+func TestFuncMetrics(t *testing.T) {
+	r := NewRegistry()
+	hits := 0.0
+	r.CounterFunc("cache_hits_total", "Cache hits.", func() float64 { return hits })
+	r.GaugeFunc("cache_entries", "Cache entries.", func() float64 { return 7 })
+	hits = 3
+	var b strings.Builder
+	r.WriteText(&b)
+	want := `# HELP cache_entries Cache entries.
+# TYPE cache_entries gauge
+cache_entries 7
+# HELP cache_hits_total Cache hits.
+# TYPE cache_hits_total counter
+cache_hits_total 3
+`
+	if got := b.String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestConcurrentFractionalAdds(t *testing.T) {
+	var c Counter
+	var g Gauge
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				c.Add(0.5)
+				g.Add(-0.25)
+			}
+		}()
+	}
+	wg.Wait()
+	if c.Value() != 4000 || g.Value() != -2000 {
+		t.Fatalf("counter %v, gauge %v", c.Value(), g.Value())
+	}
+}
+
+func TestMisuse(t *testing.T) {
+	for name, f := range map[string]func(){
+		"duplicate":      func() { r := NewRegistry(); r.Counter("a", ""); r.Gauge("a", "") },
+		"label count":    func() { NewRegistry().Counter("a", "", "x").With() },
+		"negative add":   func() { (&Counter{}).Add(-1) },
+		"unsorted bucks": func() { NewRegistry().Histogram("h", "", []float64{2, 1}) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s did not panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
@@ -0,0 +1,203 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync/atomic"
+)
+
+// atomicFloat is a float64 updated without locks.
+type atomicFloat struct{ bits atomic.Uint64 }
+
+func (f *atomicFloat) load() float64   { return math.Float64frombits(f.bits.Load()) }
+func (f *atomicFloat) store(v float64) { f.bits.Store(math.Float64bits(v)) }
+
+func (f *atomicFloat) add(d float64) {
+	for {
+		old := f.bits.Load()
+		if f.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+d)) {
+			return
+		}
+	}
+}
+
+// Counter is a value that only goes up. Updates take no locks.
+type Counter struct{ v atomicFloat }
+
+// Inc adds 1.
+func (c *Counter) Inc() { c.Add(1) }
+
+// Add adds d, which must not be negative.
+func (c *Counter) Add(d float64) {
+	if d < 0 {
+		panic("metrics: counter decreased")
+	}
+	c.v.add(d)
+}
+
+// Value returns the current count.
+func (c *Counter) Value() float64 { return c.v.load() }
+
+// Gauge is a value that can go up and down. Updates take no locks.
+type Gauge struct{ v atomicFloat }
+
+// Set replaces the value.
+func (g *Gauge) Set(v float64) { g.v.store(v) }
+
+// Add adds d, which may be negative.
+func (g *Gauge) Add(d float64) { g.v.add(d) }
+
+// Inc adds 1.
+func (g *Gauge) Inc() { g.Add(1) }
+
+// Dec subtracts 1.
+func (g *Gauge) Dec() { g.Add(-1) }
+
+// Value returns the current value.
+func (g *Gauge) Value() float64 { return g.v.load() }
+
+// Histogram counts observations into buckets with fixed upper bounds.
+// Observations take no locks, so a scrape racing with them may see a
+// sum that is one observation ahead of or behind the buckets.
+type Histogram struct {
+	upper  []float64
+	counts []atomic.Uint64 // per bucket, not cumulative; last is +Inf
+	sum    atomicFloat
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	return &Histogram{upper: buckets, counts: make([]atomic.Uint64, len(buckets)+1)}
+}
+
+// Observe records v.
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.upper, v) // first bound >= v
+	h.counts[i].Add(1)
+	h.sum.add(v)
+}
+
+// Count returns the number of observations.
+func (h *Histogram) Count() uint64 {
+	var n uint64
+	for i := range h.counts {
+		n += h.counts[i].Load()
+	}
+	return n
+}
+
+func checkBuckets(buckets []float64) []float64 {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	if !sort.Float64sAreSorted(buckets) {
+		panic("metrics: histogram buckets not sorted")
+	}
+	out := append([]float64(nil), buckets...)
+	if math.IsInf(out[len(out)-1], +1) {
+		out = out[:len(out)-1] // +Inf is always implied
+	}
+	return out
+}
+
+// CounterVec is a family of counters partitioned by labels.
+type CounterVec struct{ v *vec[*Counter] }
+
+// Counter registers a counter family with the given label names.
+func (r *Registry) Counter(name, help string, labels ...string) *CounterVec {
+	cv := &CounterVec{newVec(desc{name, help, "counter", labels}, func() *Counter { return &Counter{} })}
+	r.register(name, cv)
+	return cv
+}
+
+// With returns the counter for the label values, in label order.
+func (cv *CounterVec) With(values ...string) *Counter { return cv.v.with(values) }
+
+func (cv *CounterVec) writeText(w io.Writer) {
+	cv.v.header(w)
+	for _, c := range cv.v.sorted() {
+		fmt.Fprintf(w, "%s%s %s\n", cv.v.name, labelString(cv.v.labels, c.values), formatFloat(c.m.Value()))
+	}
+}
+
+// GaugeVec is a family of gauges partitioned by labels.
+type GaugeVec struct{ v *vec[*Gauge] }
+
+// Gauge registers a gauge family with the given label names.
+func (r *Registry) Gauge(name, help string, labels ...string) *GaugeVec {
+	gv := &GaugeVec{newVec(desc{name, help, "gauge", labels}, func() *Gauge { return &Gauge{} })}
+	r.register(name, gv)
+	return gv
+}
+
+// With returns the gauge for the label values, in label order.
+func (gv *GaugeVec) With(values ...string) *Gauge { return gv.v.with(values) }
+
+func (gv *GaugeVec) writeText(w io.Writer) {
+	gv.v.header(w)
+	for _, c := range gv.v.sorted() {
+		fmt.Fprintf(w, "%s%s %s\n", gv.v.name, labelString(gv.v.labels, c.values), formatFloat(c.m.Value()))
+	}
+}
+
+// HistogramVec is a family of histograms partitioned by labels.
+type HistogramVec struct{ v *vec[*Histogram] }
+
+// Histogram registers a histogram family with the given bucket upper
+// bounds, which must be sorted; nil means DefBuckets.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	buckets = checkBuckets(buckets)
+	hv := &HistogramVec{newVec(desc{name, help, "histogram", labels}, func() *Histogram { return newHistogram(buckets) })}
+	r.register(name, hv)
+	return hv
+}
+
+// With returns the histogram for the label values, in label order.
+func (hv *HistogramVec) With(values ...string) *Histogram { return hv.v.with(values) }
+
+func (hv *HistogramVec) writeText(w io.Writer) {
+	hv.v.header(w)
+	name, labels := hv.v.name, hv.v.labels
+	for _, c := range hv.v.sorted() {
+		h := c.m
+		var cum uint64
+		for i := range h.counts {
+			cum += h.counts[i].Load()
+			le := math.Inf(+1)
+			if i < len(h.upper) {
+				le = h.upper[i]
+			}
+			fmt.Fprintf(w, "%s_bucket%s %d\n", name, labelString(labels, c.values, "le", formatFloat(le)), cum)
+		}
+		fmt.Fprintf(w, "%s_sum%s %s\n", name, labelString(labels, c.values), formatFloat(h.sum.load()))
+		// The count is the +Inf bucket, as the format requires, even
+		// while observations are being recorded.
+		fmt.Fprintf(w, "%s_count%s %d\n", name, labelString(labels, c.values), cum)
+	}
+}
+
+// funcMetric is a counter or gauge whose value is read from a function
+// at every scrape.
+type funcMetric struct {
+	desc
+	fn func() float64
+}
+
+// CounterFunc registers a counter whose value is fn's result at scrape
+// time, for counts kept elsewhere, such as a cache's hit statistics. fn
+// must be safe for concurrent use and never decrease.
+func (r *Registry) CounterFunc(name, help string, fn func() float64) {
+	r.register(name, &funcMetric{desc{name: name, help: help, typ: "counter"}, fn})
+}
+
+// GaugeFunc registers a gauge whose value is fn's result at scrape time.
+// fn must be safe for concurrent use.
+func (r *Registry) GaugeFunc(name, help string, fn func() float64) {
+	r.register(name, &funcMetric{desc{name: name, help: help, typ: "gauge"}, fn})
+}
+
+func (m *funcMetric) writeText(w io.Writer) {
+	m.header(w)
+	fmt.Fprintf(w, "%s %s\n", m.name, formatFloat(m.fn()))
+}
@@ -0,0 +1,36 @@
+// Package middleware provides composable HTTP middleware: panic recovery,
+// request IDs and request logging, combined with Chain.
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Middleware wraps a handler with extra behaviour.
+type Middleware func(http.Handler) http.Handler
+
+// Chain returns a middleware applying ms in order, so the first one sees
+// the request first and the response last:
+//
+//	Chain(a, b, c)(h) == a(b(c(h)))
+func Chain(ms ...Middleware) Middleware {
+	return func(h http.Handler) http.Handler {
+		for i := len(ms) - 1; i >= 0; i-- {
+			h = ms[i](h)
+		}
+		return h
+	}
+}
+
+// route returns the value of the longest key of routes that prefixes
+// path, or def if none does.
+func route[V any](routes map[string]V, path string, def V) V {
+	v, best := def, -1
+	for prefix, rv := range routes {
+		if strings.HasPrefix(path, prefix) && len(prefix) > best {
+			v, best = rv, len(prefix)
+		}
+	}
+	return v
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// tag appends name to the X-Trace header before and after calling next.
+func tag(name string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Trace", name+">")
+			next.ServeHTTP(w, r)
+			w.Header().Add("X-Trace", "<"+name)
+		})
+	}
+}
+
+func TestChainOrder(t *testing.T) {
+	h := Chain(tag("a"), tag("b"), tag("c"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("X-Trace", "h")
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	got := strings.Join(rec.Header().Values("X-Trace"), " ")
+	if want := "a> b> c> h <c <b <a"; got != want {
+		t.Fatalf("trace = %q, want %q", got, want)
+	}
+}
+
+func TestChainEmpty(t *testing.T) {
+	called := false
+	h := Chain()(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { called = true }))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if !called {
+		t.Fatal("handler not called")
+	}
+}
@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSPolicy says which cross-origin requests a browser may make. The
+// zero value allows none.
+type CORSPolicy struct {
+	// AllowedOrigins lists the origins, such as "https://app.example",
+	// that may call the routes. "*" allows any origin, and a single "*"
+	// in the host, as in "https://*.example.com", allows any subdomain.
+	AllowedOrigins []string
+	// AllowedMethods defaults to GET, HEAD and POST.
+	AllowedMethods []string
+	// AllowedHeaders lists the request headers beyond the CORS-safelisted
+	// ones that may be sent; "*" allows any. JSON endpoints need at
+	// least Content-Type.
+	AllowedHeaders []string
+	// ExposedHeaders lists the response headers scripts may read beyond
+	// the safelisted ones.
+	ExposedHeaders []string
+	// AllowCredentials lets requests carry cookies and HTTP auth. The
+	// origin is then always echoed, never answered with "*".
+	AllowCredentials bool
+	// MaxAge is how long a browser may cache a preflight response; zero
+	// leaves it to the browser.
+	MaxAge time.Duration
+}
+
+// CORSConfig assigns policies to route groups.
+type CORSConfig struct {
+	// Default applies to paths without a route policy.
+	Default CORSPolicy
+	// Routes overrides Default for request paths starting with a key;
+	// the longest matching key wins.
+	Routes map[string]CORSPolicy
+}
+
+// CORS answers preflight requests and adds the Access-Control headers
+// that let browsers read cross-origin responses, per the policy of each
+// request's route group. Preflights for an origin, method or header the
+// policy does not allow get 403 without those headers; other requests
+// from disallowed origins are served without them, so the browser keeps
+// the response from the calling script.
+func CORS(cfg CORSConfig) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := route(cfg.Routes, r.URL.Path, cfg.Default)
+			origin := r.Header.Get("Origin")
+			h := w.Header()
+			if len(p.AllowedOrigins) > 0 && (!p.anyOrigin() || p.AllowCredentials) {
+				h.Add("Vary", "Origin")
+			}
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			allowed := p.allowsOrigin(origin)
+
+			reqMethod := r.Header.Get("Access-Control-Request-Method")
+			if r.Method == http.MethodOptions && reqMethod != "" {
+				h.Add("Vary", "Access-Control-Request-Method")
+				h.Add("Vary", "Access-Control-Request-Headers")
+				reqHeaders := splitList(r.Header.Get("Access-Control-Request-Headers"))
+				if !allowed || !p.allowsMethod(reqMethod) || !p.allowsHeaders(reqHeaders) {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				p.setOrigin(h, origin)
+				h.Set("Access-Control-Allow-Methods", reqMethod)
+				if len(reqHeaders) > 0 {
+					h.Set("Access-Control-Allow-Headers", strings.Join(reqHeaders, ", "))
+				}
+				if p.MaxAge > 0 {
+					h.Set("Access-Control-Max-Age", strconv.Itoa(int(p.MaxAge/time.Second)))
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			if allowed {
+				p.setOrigin(h, origin)
+				if len(p.ExposedHeaders) > 0 {
+					h.Set("Access-Control-Expose-Headers", strings.Join(p.ExposedHeaders, ", "))
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (p CORSPolicy) anyOrigin() bool { return slices.Contains(p.AllowedOrigins, "*") }
+
+func (p CORSPolicy) allowsOrigin(origin string) bool {
+	for _, o := range p.AllowedOrigins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+		if before, after, ok := strings.Cut(o, "*"); ok &&
+			len(origin) > len(before)+len(after) &&
+			strings.HasPrefix(origin, before) && strings.HasSuffix(origin, after) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p CORSPolicy) allowsMethod(method string) bool {
+	methods := p.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+	return slices.Contains(methods, method)
+}
+
+func (p CORSPolicy) allowsHeaders(headers []string) bool {
+	for _, rh := range headers {
+		ok := false
+		for _, ah := range p.AllowedHeaders {
+			if ah == "*" || strings.EqualFold(ah, rh) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (p CORSPolicy) setOrigin(h http.Header, origin string) {
+	if p.anyOrigin() && !p.AllowCredentials {
+		h.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	h.Set("Access-Control-Allow-Origin", origin)
+	if p.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// splitList splits a comma-separated header value, dropping empty items.
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func corsHandler() http.Handler {
+	return CORS(CORSConfig{
+		Default: CORSPolicy{AllowedOrigins: []string{"*"}},
+		Routes: map[string]CORSPolicy{
+			"/users": {
+				AllowedOrigins:   []string{"https://app.example", "https://*.example.org"},
+				AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE"},
+				AllowedHeaders:   []string{"Content-Type", "Authorization"},
+				ExposedHeaders:   []string{"Location"},
+				AllowCredentials: true,
+				MaxAge:           10 * time.Minute,
+			},
+			"/private": {},
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("handled"))
+	}))
+}
+
+func corsDo(method, path string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	corsHandler().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCORSSimpleRequests(t *testing.T) {
+	tests := []struct {
+		path, origin string
+		allowOrigin  string
+		credentials  string
+	}{
+		{"/wordcount", "https://anywhere.test", "*", ""},
+		{"/users/1", "https://app.example", "https://app.example", "true"},
+		{"/users", "https://eu.example.org", "https://eu.example.org", "true"},
+		{"/users", "https://example.org", "", ""},
+		{"/users", "https://evil.test", "", ""},
+		{"/private", "https://app.example", "", ""},
+		{"/users", "", "", ""},
+	}
+	for _, tt := range tests {
+		rec := corsDo("GET", tt.path, "Origin", tt.origin)
+		h := rec.Header()
+		if rec.Body.String() != "handled" {
+			t.Errorf("%s from %q: handler not called", tt.path, tt.origin)
+		}
+		if got := h.Get("Access-Control-Allow-Origin"); got != tt.allowOrigin {
+			t.Errorf("%s from %q: Allow-Origin = %q, want %q", tt.path, tt.origin, got, tt.allowOrigin)
+		}
+		if got := h.Get("Access-Control-Allow-Credentials"); got != tt.credentials {
+			t.Errorf("%s from %q: Allow-Credentials = %q", tt.path, tt.origin, got)
+		}
+	}
+	rec := corsDo("GET", "/users", "Origin", "https://app.example")
+	if rec.Header().Get("Vary") != "Origin" || rec.Header().Get("Access-Control-Expose-Headers") != "Location" {
+		t.Errorf("headers = %v", rec.Header())
+	}
+	if rec := corsDo("GET", "/wordcount", "Origin", "https://x.test"); rec.Header().Get("Vary") != "" {
+		t.Errorf("wildcard policy varies on %q", rec.Header().Get("Vary"))
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	rec := corsDo("OPTIONS", "/users/1",
+		"Origin", "https://app.example",
+		"Access-Control-Request-Method", "PUT",
+		"Access-Control-Request-Headers", "content-type, authorization")
+	h := rec.Header()
+	if rec.Code != http.StatusNoContent || rec.Body.Len() != 0 {
+		t.Fatalf("preflight = %d %q", rec.Code, rec.Body)
+	}
+	want := map[string]string{
+		"Access-Control-Allow-Origin":      "https://app.example",
+		"Access-Control-Allow-Methods":     "PUT",
+		"Access-Control-Allow-Headers":     "content-type, authorization",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Max-Age":           "600",
+	}
+	for k, v := range want {
+		if got := h.Get(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+	if len(h.Values("Vary")) != 3 {
+		t.Errorf("Vary = %q", h.Values("Vary"))
+	}
+
+	denied := [][]string{
+		{"Origin", "https://evil.test", "Access-Control-Request-Method", "GET"},
+		{"Origin", "https://app.example", "Access-Control-Request-Method", "PATCH"},
+		{"Origin", "https://app.example", "Access-Control-Request-Method", "POST", "Access-Control-Request-Headers", "X-Debug"},
+	}
+	for _, hdr := range denied {
+		rec := corsDo("OPTIONS", "/users", hdr...)
+		if rec.Code != http.StatusForbidden || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("preflight %v = %d %v", hdr, rec.Code, rec.Header())
+		}
+	}
+
+	// A plain OPTIONS request is not a preflight and reaches the handler.
+	if rec := corsDo("OPTIONS", "/users", "Origin", "https://app.example"); rec.Body.String() != "handled" {
+		t.Errorf("plain OPTIONS = %d %q", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultGzipMinSize is the smallest response Gzip compresses when no
+// threshold is given; below it the gzip overhead outweighs the saving.
+const DefaultGzipMinSize = 1024
+
+// incompressibleTypes are media type prefixes Gzip leaves alone because
+// they are compressed already or are event streams that must not be
+// held back.
+var incompressibleTypes = []string{
+	"image/", "video/", "audio/",
+	"application/zip", "application/gzip", "application/x-gzip",
+	"application/zstd", "application/octet-stream",
+	"text/event-stream",
+}
+
+var gzipWriters = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+
+// Gzip compresses responses for clients that accept gzip. A response is
+// buffered until minSize bytes have been written (zero means
+// DefaultGzipMinSize); smaller responses are sent as they are, with a
+// Content-Length. Compressed responses lose their Content-Length, and
+// every response gets "Vary: Accept-Encoding" so caches keep the two
+// forms apart. Responses that already have a Content-Encoding, have no
+// body, are partial content or have an incompressible Content-Type are
+// passed through, as are HEAD requests and connection upgrades.
+func Gzip(minSize int) Middleware {
+	if minSize <= 0 {
+		minSize = DefaultGzipMinSize
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			if r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			gw := &gzipWriter{ResponseWriter: w, minSize: minSize}
+			next.ServeHTTP(gw, r)
+			// Not deferred: after a panic, Recover decides what the
+			// client gets.
+			gw.close()
+		})
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip.
+func acceptsGzip(header string) bool {
+	star := false
+	for _, item := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		q := 1.0
+		if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				q = f
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(coding)) {
+		case "gzip", "x-gzip":
+			return q > 0
+		case "*":
+			star = q > 0
+		}
+	}
+	return star
+}
+
+// gzipWriter holds back the start of a response until it knows whether
+// compressing it is worthwhile.
+type gzipWriter struct {
+	http.ResponseWriter
+	minSize int
+
+	code    int    // status set by the handler, 0 until then
+	buf     []byte // body written before the decision
+	decided bool   // headers have been sent
+	gz      *gzip.Writer
+}
+
+func (w *gzipWriter) WriteHeader(code int) {
+	if code < 200 {
+		// Informational responses go straight out.
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.code != 0 {
+		return
+	}
+	w.code = code
+	if !w.compressible() {
+		w.decide(false)
+	}
+}
+
+func (w *gzipWriter) Write(p []byte) (int, error) {
+	if w.code == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.decided {
+		if w.gz != nil {
+			return w.gz.Write(p)
+		}
+		return w.ResponseWriter.Write(p)
+	}
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= w.minSize {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends what has been written so far, compressed if the response
+// qualifies, since a streaming response's final size is unknown.
+func (w *gzipWriter) Flush() {
+	if w.code == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		w.decide(true)
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *gzipWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// compressible reports whether the status and headers set so far allow
+// compression.
+func (w *gzipWriter) compressible() bool {
+	h := w.Header()
+	// Compressing a byte range would make the offsets meaningless.
+	if !bodyAllowed(w.code) || w.code == http.StatusPartialContent || h.Get("Content-Encoding") != "" {
+		return false
+	}
+	if n, err := strconv.Atoi(h.Get("Content-Length")); err == nil && n < w.minSize {
+		return false
+	}
+	ct := strings.ToLower(h.Get("Content-Type"))
+	for _, prefix := range incompressibleTypes {
+		if strings.HasPrefix(ct, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// decide sends the headers and the buffered body, compressed or not.
+func (w *gzipWriter) decide(compress bool) error {
+	w.decided = true
+	h := w.Header()
+	buf := w.buf
+	w.buf = nil
+	if compress {
+		if h.Get("Content-Type") == "" {
+			// Sniff before compressing, as net/http would.
+			h.Set("Content-Type", http.DetectContentType(buf))
+		}
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", "gzip")
+		w.ResponseWriter.WriteHeader(w.code)
+		w.gz = gzipWriters.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+		_, err := w.gz.Write(buf)
+		return err
+	}
+	w.ResponseWriter.WriteHeader(w.code)
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// close finishes the response once the handler has returned.
+func (w *gzipWriter) close() {
+	if !w.decided {
+		if w.code == 0 {
+			w.code = http.StatusOK
+		}
+		if w.Header().Get("Content-Length") == "" && bodyAllowed(w.code) {
+			w.Header().Set("Content-Length", strconv.Itoa(len(w.buf)))
+		}
+		w.decide(false)
+	}
+	if w.gz != nil {
+		w.gz.Close()
+		w.gz.Reset(nil)
+		gzipWriters.Put(w.gz)
+		w.gz = nil
+	}
+}
+
+func bodyAllowed(code int) bool {
+	return code != http.StatusNoContent && code != http.StatusNotModified
+}
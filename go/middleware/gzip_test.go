@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// gzipGet requests path from srv with the given Accept-Encoding, without
+// the transport's own transparent decompression.
+func gzipGet(t *testing.T, srv *httptest.Server, method, path, accept string) (*http.Response, string) {
+	t.Helper()
+	req, _ := http.NewRequest(method, srv.URL+path, nil)
+	if accept != "" {
+		req.Header.Set("Accept-Encoding", accept)
+	}
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body io.Reader = resp.Body
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body = zr
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(b)
+}
+
+func TestGzip(t *testing.T) {
+	big := strings.Repeat("compress me ", 200)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/big", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Length", "2400")
+		w.WriteHeader(http.StatusCreated)
+		for i := 0; i < 200; i++ {
+			io.WriteString(w, "compress me ")
+		}
+	})
+	mux.HandleFunc("/html", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "<html><body>"+big+"</body></html>")
+	})
+	mux.HandleFunc("/small", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "tiny")
+	})
+	mux.HandleFunc("/png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		io.WriteString(w, big)
+	})
+	mux.HandleFunc("/encoded", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		io.WriteString(w, big)
+	})
+	mux.HandleFunc("/empty", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	srv := httptest.NewServer(Gzip(1000)(mux))
+	defer srv.Close()
+
+	tests := []struct {
+		method, path, accept string
+		gzipped              bool
+		code                 int
+		body                 string
+	}{
+		{"GET", "/big", "gzip", true, http.StatusCreated, big},
+		{"GET", "/big", "br, gzip;q=0.5", true, http.StatusCreated, big},
+		{"GET", "/big", "", false, http.StatusCreated, big},
+		{"GET", "/big", "gzip;q=0", false, http.StatusCreated, big},
+		{"GET", "/big", "*", true, http.StatusCreated, big},
+		{"GET", "/html", "gzip", true, http.StatusOK, "<html><body>" + big + "</body></html>"},
+		{"GET", "/small", "gzip", false, http.StatusOK, "tiny"},
+		{"GET", "/png", "gzip", false, http.StatusOK, big},
+		{"GET", "/encoded", "gzip", false, http.StatusOK, big},
+		{"GET", "/empty", "gzip", false, http.StatusNoContent, ""},
+		{"HEAD", "/big", "gzip", false, http.StatusCreated, ""},
+	}
+	for _, tt := range tests {
+		resp, body := gzipGet(t, srv, tt.method, tt.path, tt.accept)
+		name := tt.method + " " + tt.path + " (" + tt.accept + ")"
+		if gz := resp.Header.Get("Content-Encoding") == "gzip"; gz != tt.gzipped {
+			t.Errorf("%s: gzipped = %v", name, gz)
+		}
+		if resp.StatusCode != tt.code || body != tt.body {
+			t.Errorf("%s: %d %.40q, want %d %.40q", name, resp.StatusCode, body, tt.code, tt.body)
+		}
+		if resp.Header.Get("Vary") != "Accept-Encoding" {
+			t.Errorf("%s: Vary = %q", name, resp.Header.Get("Vary"))
+		}
+		// net/http may set the compressed length, never the original.
+		if tt.gzipped && resp.ContentLength == int64(len(tt.body)) {
+			t.Errorf("%s: compressed response kept Content-Length %d", name, resp.ContentLength)
+		}
+	}
+
+	resp, _ := gzipGet(t, srv, "GET", "/small", "gzip")
+	if resp.ContentLength != 4 {
+		t.Errorf("small response Content-Length = %d", resp.ContentLength)
+	}
+	resp, _ = gzipGet(t, srv, "GET", "/html", "gzip")
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("sniffed Content-Type = %q", ct)
+	}
+}
+
+func TestGzipStreaming(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(Gzip(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, "first\n")
+		http.NewResponseController(w).Flush()
+		<-release
+		io.WriteString(w, "second\n")
+	})))
+	defer srv.Close()
+	defer close(release)
+
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatal("flushed response not compressed")
+	}
+	zr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first line arrives before the handler finishes.
+	line, err := bufio.NewReader(zr).ReadString('\n')
+	if err != nil || line != "first\n" {
+		t.Fatalf("first line = %q, %v", line, err)
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := map[string]bool{
+		"":                     false,
+		"gzip":                 true,
+		"GZIP":                 true,
+		"x-gzip":               true,
+		"deflate, br":          false,
+		"gzip;q=0":             false,
+		"gzip; q=0.001":        true,
+		"*":                    true,
+		"*;q=0":                false,
+		"gzip;q=0, *":          false,
+		"identity, *;q=0.5":    true,
+		"br;q=1.0, gzip;q=0.8": true,
+	}
+	for header, want := range tests {
+		if got := acceptsGzip(header); got != want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Logger logs one line per request with its method, path, status,
+// response size, duration in milliseconds and request ID. Server errors
+// are logged at level Error and client errors at Warn, so a logger at
+// Warn shows only failed requests. Place it after RequestID in a chain
+// to get the ID.
+func Logger(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rw, r)
+			level := slog.LevelInfo
+			switch {
+			case rw.status >= 500:
+				level = slog.LevelError
+			case rw.status >= 400:
+				level = slog.LevelWarn
+			}
+			logger.Log(r.Context(), level, "request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", rw.status,
+				"bytes", rw.bytes,
+				"duration_ms", float64(time.Since(start).Microseconds())/1000,
+				"request_id", RequestIDFrom(r.Context()))
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	var logs bytes.Buffer
+	h := Chain(RequestID(), Logger(slog.New(slog.NewJSONHandler(&logs, nil))))(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+			w.Write([]byte("short and stout"))
+		}))
+	req := httptest.NewRequest("POST", "/pot?x=1", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry struct {
+		Msg       string
+		Method    string
+		Path      string
+		Status    int
+		Bytes     int
+		RequestID string `json:"request_id"`
+	}
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("decode %q: %v", logs.String(), err)
+	}
+	if entry.Msg != "request" || entry.Method != "POST" || entry.Path != "/pot" ||
+		entry.Status != http.StatusTeapot || entry.Bytes != 15 || entry.RequestID != "req-1" {
+		t.Fatalf("log entry = %+v", entry)
+	}
+}
+
+func TestLoggerDefaultStatus(t *testing.T) {
+	var logs bytes.Buffer
+	h := Logger(slog.New(slog.NewJSONHandler(&logs, nil)))(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	var entry struct{ Status int }
+	json.Unmarshal(logs.Bytes(), &entry)
+	if entry.Status != http.StatusOK {
+		t.Fatalf("status = %d, want 200", entry.Status)
+	}
+}
+
+func TestLoggerLevels(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	h := Logger(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code, _ := strconv.Atoi(r.URL.Query().Get("code"))
+		w.WriteHeader(code)
+	}))
+	for _, code := range []string{"200", "304", "404", "503"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/?code="+code, nil))
+	}
+	var levels []string
+	dec := json.NewDecoder(&logs)
+	for {
+		var entry struct {
+			Level      string
+			Status     int
+			DurationMS *float64 `json:"duration_ms"`
+		}
+		if dec.Decode(&entry) != nil {
+			break
+		}
+		if entry.DurationMS == nil {
+			t.Errorf("entry for %d has no duration_ms", entry.Status)
+		}
+		levels = append(levels, fmt.Sprint(entry.Status, " ", entry.Level))
+	}
+	if got := strings.Join(levels, ", "); got != "404 WARN, 503 ERROR" {
+		t.Fatalf("logged %q", got)
+	}
+}
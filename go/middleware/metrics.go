@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"example.com/tutorial/metrics"
+)
+
+// Metrics records request counts by method and status code, latencies by
+// method, and the number of requests in progress in reg. Call it once per
+// registry, since it registers the metrics.
+func Metrics(reg *metrics.Registry) Middleware {
+	requests := reg.Counter("http_requests_total", "HTTP requests served, by method and status code.", "method", "code")
+	latency := reg.Histogram("http_request_duration_seconds", "HTTP request latency in seconds, by method.", nil, "method")
+	inFlight := reg.Gauge("http_requests_in_flight", "HTTP requests currently being served.").With()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			inFlight.Inc()
+			defer inFlight.Dec()
+			start := time.Now()
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rw, r)
+			method := methodLabel(r.Method)
+			requests.With(method, strconv.Itoa(rw.status)).Inc()
+			latency.With(method).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// methodLabel folds unknown methods into one label value, so clients
+// cannot create unbounded numbers of series.
+func methodLabel(m string) string {
+	switch m {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return m
+	}
+	return "OTHER"
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/tutorial/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	var inFlight string
+	h := Metrics(reg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var b strings.Builder
+		reg.WriteText(&b)
+		inFlight = b.String()
+		if r.Method == "POST" {
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	for _, m := range []string{"GET", "GET", "POST", "BREW"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(m, "/", nil))
+	}
+	if !strings.Contains(inFlight, "http_requests_in_flight 1\n") {
+		t.Errorf("in-flight gauge not raised during request:\n%s", inFlight)
+	}
+	var b strings.Builder
+	reg.WriteText(&b)
+	out := b.String()
+	for _, want := range []string{
+		`http_requests_total{method="GET",code="200"} 2`,
+		`http_requests_total{method="POST",code="201"} 1`,
+		`http_requests_total{method="OTHER",code="200"} 1`,
+		`http_request_duration_seconds_count{method="GET"} 2`,
+		"http_requests_in_flight 0",
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("missing %s in:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"example.com/tutorial/ratelimit"
+)
+
+// DefaultIdleTimeout is how long Limiters keeps the bucket of a client
+// that has stopped sending requests, when none is configured.
+const DefaultIdleTimeout = 10 * time.Minute
+
+// Limiters is a set of ratelimit.Limiter token buckets, one per client
+// key, that is safe for concurrent use. Each bucket holds up to burst
+// tokens and refills at rate tokens per second; a request takes one
+// token. Buckets idle for longer than the idle timeout are evicted, so
+// the set stays bounded by the number of recently active clients.
+type Limiters struct {
+	rate  float64
+	burst float64
+	idle  time.Duration
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	lim  *ratelimit.Limiter
+	last time.Time // when the client was last seen
+}
+
+// NewLimiters returns an empty set of buckets. rate must be positive and
+// burst at least 1; an idle timeout of zero means DefaultIdleTimeout.
+func NewLimiters(rate float64, burst int, idle time.Duration) *Limiters {
+	if rate <= 0 || burst < 1 {
+		panic("middleware: rate limit needs a positive rate and burst")
+	}
+	if idle <= 0 {
+		idle = DefaultIdleTimeout
+	}
+	return &Limiters{
+		rate:    rate,
+		burst:   float64(burst),
+		idle:    idle,
+		now:     time.Now,
+		buckets: map[string]*bucket{},
+	}
+}
+
+// Allow takes a token from key's bucket. If none is left it returns
+// false and how long until one will be.
+func (l *Limiters) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if now.Sub(l.lastSweep) >= l.idle {
+		l.sweep(now)
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{lim: ratelimit.NewLimiter(l.rate, int(l.burst))}
+		l.buckets[key] = b
+	}
+	b.last = now
+	r := b.lim.ReserveAt(now)
+	if wait := r.Delay(); wait > 0 {
+		r.Cancel()
+		return false, wait
+	}
+	return true, 0
+}
+
+// SetLimit changes the rate and burst of every bucket, those of the
+// clients already seen included, which keep the tokens they have up to
+// the new burst. It panics, as NewLimiters does, on a rate that is not
+// positive or a burst below 1.
+func (l *Limiters) SetLimit(rate float64, burst int) {
+	if rate <= 0 || burst < 1 {
+		panic("middleware: rate limit needs a positive rate and burst")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate, l.burst = rate, float64(burst)
+	for _, b := range l.buckets {
+		b.lim.SetRate(rate)
+		b.lim.SetBurst(burst)
+	}
+}
+
+// Limit returns the rate and burst of the buckets.
+func (l *Limiters) Limit() (rate float64, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate, int(l.burst)
+}
+
+// Len returns the number of buckets currently tracked.
+func (l *Limiters) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// sweep evicts idle buckets. A bucket idle that long is full again, so
+// forgetting it changes nothing for the client.
+func (l *Limiters) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= l.idle {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// ClientIP returns the host part of the request's remote address. It
+// does not trust forwarding headers, which any client can set.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// HeaderKey returns a key function that identifies clients by the value
+// of the named header, such as an API key, and by ClientIP when it is
+// absent.
+func HeaderKey(name string) func(*http.Request) string {
+	return func(r *http.Request) string {
+		if v := r.Header.Get(name); v != "" {
+			return name + ":" + v
+		}
+		return "ip:" + ClientIP(r)
+	}
+}
+
+// RateLimit rejects requests with 429 Too Many Requests once the client
+// identified by key has used up its bucket in l, telling it when to
+// retry in the Retry-After header. A nil key means ClientIP.
+func RateLimit(l *Limiters, key func(*http.Request) string) Middleware {
+	if key == nil {
+		key = ClientIP
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ok, wait := l.Allow(key(r))
+			if !ok {
+				w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
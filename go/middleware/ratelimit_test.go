@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock returns a Limiters whose time only moves when told to.
+func fakeClock(rate float64, burst int, idle time.Duration) (*Limiters, func(time.Duration)) {
+	l := NewLimiters(rate, burst, idle)
+	now := time.Unix(1000, 0)
+	l.now = func() time.Time { return now }
+	return l, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestLimitersBurstAndRefill(t *testing.T) {
+	l, advance := fakeClock(2, 3, 0)
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("request %d within burst rejected", i)
+		}
+	}
+	ok, wait := l.Allow("a")
+	if ok || wait != 500*time.Millisecond {
+		t.Fatalf("over burst: ok=%v wait=%v, want false 500ms", ok, wait)
+	}
+	if ok, _ := l.Allow("b"); !ok {
+		t.Fatal("other client limited")
+	}
+	advance(500 * time.Millisecond)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Fatal("token not refilled")
+	}
+	advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		l.Allow("a")
+	}
+	if ok, _ := l.Allow("a"); ok {
+		t.Fatal("bucket refilled beyond burst")
+	}
+}
+
+func TestLimitersSetLimit(t *testing.T) {
+	l := NewLimiters(0.001, 3, 0)
+	for i := 0; i < 3; i++ {
+		l.Allow("a")
+	}
+	l.SetLimit(0.001, 1)
+	if rate, burst := l.Limit(); rate != 0.001 || burst != 1 {
+		t.Fatalf("Limit = %v, %d", rate, burst)
+	}
+	if ok, _ := l.Allow("a"); ok {
+		t.Fatal("client over its burst allowed")
+	}
+	// A new client gets the new burst.
+	if ok, _ := l.Allow("b"); !ok {
+		t.Fatal("new client rejected")
+	}
+	if ok, _ := l.Allow("b"); ok {
+		t.Fatal("new client given the old burst")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("SetLimit(0, 1) did not panic")
+		}
+	}()
+	l.SetLimit(0, 1)
+}
+
+func TestLimitersEvictIdle(t *testing.T) {
+	l, advance := fakeClock(1, 1, time.Minute)
+	l.Allow("a")
+	l.Allow("b")
+	advance(30 * time.Second)
+	l.Allow("b")
+	advance(40 * time.Second)
+	l.Allow("c") // triggers a sweep: a idle 70s, b 40s
+	if n := l.Len(); n != 2 {
+		t.Fatalf("Len = %d, want 2", n)
+	}
+}
+
+func TestLimitersConcurrent(t *testing.T) {
+	l := NewLimiters(1e-9, 100, 0)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if ok, _ := l.Allow("k"); ok {
+					mu.Lock()
+					allowed++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 100 {
+		t.Fatalf("allowed %d requests, want exactly the burst of 100", allowed)
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	l, _ := fakeClock(0.5, 1, 0)
+	h := RateLimit(l, HeaderKey("X-API-Key"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(apiKey, addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = addr
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := serve("k1", "10.0.0.1:1"); rec.Code != http.StatusOK {
+		t.Fatalf("first request = %d", rec.Code)
+	}
+	rec := serve("k1", "10.0.0.2:2")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "2" {
+		t.Fatalf("second request = %d Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	// A different key, and requests without one from a new address, are
+	// limited separately.
+	for i, c := range [][2]string{{"k2", "10.0.0.1:1"}, {"", "10.0.0.1:3"}} {
+		if rec := serve(c[0], c[1]); rec.Code != http.StatusOK {
+			t.Errorf("client %d = %d", i, rec.Code)
+		}
+	}
+	if rec := serve("", "10.0.0.1:4"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("same IP, other port = %d, want 429", rec.Code)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	for addr, want := range map[string]string{
+		"192.0.2.1:1234":    "192.0.2.1",
+		"[2001:db8::1]:443": "2001:db8::1",
+		"pipe":              "pipe",
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = addr
+		req.Header.Set("X-Forwarded-For", "203.0.113.9")
+		if got := ClientIP(req); got != want {
+			t.Errorf("ClientIP(%q) = %q, want %q", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"example.com/tutorial/id"
+	"example.com/tutorial/logging"
+)
+
+// RequestIDHeader carries the request ID in requests and responses.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the client-supplied IDs that are accepted.
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// RequestID gives every request an ID, available to later handlers via
+// RequestIDFrom, added to what they log with the request's context (see
+// package logging) and echoed in the X-Request-ID response header. A
+// well-formed X-Request-ID request header is reused so that IDs can be
+// followed across services; otherwise a new one is made by id.New, so
+// that IDs in logs sort by the time of their requests.
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reqID := r.Header.Get(RequestIDHeader)
+			if !validRequestID(reqID) {
+				reqID = id.New()
+			}
+			w.Header().Set(RequestIDHeader, reqID)
+			ctx := logging.WithRequestID(context.WithValue(r.Context(), requestIDKey{}, reqID), reqID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequestIDFrom returns the ID stored by RequestID, or "" if there is
+// none.
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// validRequestID accepts non-empty printable ASCII without spaces, so
+// that a client cannot inject anything odd into logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/tutorial/id"
+	"example.com/tutorial/logging"
+)
+
+func serveRequestID(header string) (seen, echoed string) {
+	h := RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFrom(r.Context())
+	}))
+	req := httptest.NewRequest("GET", "/", nil)
+	if header != "" {
+		req.Header.Set(RequestIDHeader, header)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return seen, rec.Header().Get(RequestIDHeader)
+}
+
+func TestRequestIDGenerated(t *testing.T) {
+	a, echoed := serveRequestID("")
+	if len(a) != id.Len || a != echoed {
+		t.Fatalf("id %q, header %q", a, echoed)
+	}
+	if b, _ := serveRequestID(""); b <= a {
+		t.Fatalf("later request got ID %q, not after %q", b, a)
+	}
+}
+
+func TestRequestIDPropagated(t *testing.T) {
+	if seen, echoed := serveRequestID("abc-123"); seen != "abc-123" || echoed != "abc-123" {
+		t.Fatalf("id %q, header %q", seen, echoed)
+	}
+	for _, bad := range []string{"has space", "tab\tid", strings.Repeat("x", maxRequestIDLength+1), "ünï"} {
+		if seen, _ := serveRequestID(bad); seen == bad {
+			t.Errorf("accepted request ID %q", bad)
+		}
+	}
+}
+
+func TestRequestIDFromEmpty(t *testing.T) {
+	if id := RequestIDFrom(context.Background()); id != "" {
+		t.Fatalf("RequestIDFrom = %q", id)
+	}
+}
+
+func TestRequestIDLogged(t *testing.T) {
+	var logs bytes.Buffer
+	logger := logging.New(&logs, logging.Options{})
+	h := RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.InfoContext(r.Context(), "handling")
+	}))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if !strings.Contains(logs.String(), "msg=handling request_id=abc-123") {
+		t.Fatalf("logged %q", logs.String())
+	}
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TimeoutConfig sets the time limits applied by Timeout.
+type TimeoutConfig struct {
+	// Default applies to paths without a route override; zero means no
+	// limit.
+	Default time.Duration
+	// Routes overrides Default for request paths starting with a key; the
+	// longest matching key wins. A zero override removes the limit, which
+	// streaming endpoints such as WebSockets and event streams need.
+	Routes map[string]time.Duration
+}
+
+// limit returns the timeout for path.
+func (c TimeoutConfig) limit(path string) time.Duration {
+	return route(c.Routes, path, c.Default)
+}
+
+// timeoutBody is the response sent when a handler runs out of time, in
+// the same shape as the server's other JSON errors.
+const timeoutBody = `{"error":"request timed out"}` + "\n"
+
+// Timeout runs each handler with a request context that expires after
+// the configured limit. If the handler has not finished by then the
+// client gets 504 Gateway Timeout with a JSON error body, and whatever
+// the handler writes afterwards is discarded. Responses are buffered
+// until the handler returns, so streaming routes need a zero override.
+// A panic in the handler is re-raised on the calling goroutine, where
+// Recover can handle it.
+func Timeout(cfg TimeoutConfig) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			d := cfg.limit(r.URL.Path)
+			if d <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			r = r.WithContext(ctx)
+
+			tw := &timeoutWriter{h: http.Header{}}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if v := recover(); v != nil {
+						panicked <- v
+					}
+				}()
+				next.ServeHTTP(tw, r)
+				close(done)
+			}()
+			select {
+			case v := <-panicked:
+				panic(v)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				dst := w.Header()
+				for k, vv := range tw.h {
+					dst[k] = vv
+				}
+				if tw.code == 0 {
+					tw.code = http.StatusOK
+				}
+				w.WriteHeader(tw.code)
+				w.Write(tw.buf.Bytes())
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.timedOut = true
+				if ctx.Err() == context.DeadlineExceeded {
+					w.Header().Set("Content-Type", "application/json; charset=utf-8")
+					w.WriteHeader(http.StatusGatewayTimeout)
+					w.Write([]byte(timeoutBody))
+				}
+				// Otherwise the client went away and there is no one
+				// to answer.
+			}
+		})
+	}
+}
+
+// timeoutWriter buffers a response until the handler returns, and
+// rejects writes once the request has timed out.
+type timeoutWriter struct {
+	h http.Header
+
+	mu       sync.Mutex
+	buf      bytes.Buffer
+	code     int
+	timedOut bool
+}
+
+func (tw *timeoutWriter) Header() http.Header { return tw.h }
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if tw.code == 0 {
+		tw.code = http.StatusOK
+	}
+	return tw.buf.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.code != 0 {
+		return
+	}
+	tw.code = code
+}
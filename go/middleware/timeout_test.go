@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// sleeper waits for the duration in its path's "d" query parameter or
+// for its context, whichever comes first.
+var sleeper = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	d, _ := time.ParseDuration(r.URL.Query().Get("d"))
+	select {
+	case <-time.After(d):
+		w.Header().Set("X-Done", "yes")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("finished"))
+	case <-r.Context().Done():
+		w.Write([]byte("too late"))
+	}
+})
+
+func TestTimeout(t *testing.T) {
+	h := Timeout(TimeoutConfig{
+		Default: 20 * time.Millisecond,
+		Routes: map[string]time.Duration{
+			"/slow":        time.Second,
+			"/slow/strict": 10 * time.Millisecond,
+			"/stream":      0,
+		},
+	})(sleeper)
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/fast?d=1ms", http.StatusAccepted},
+		{"/fast?d=200ms", http.StatusGatewayTimeout},
+		{"/slow?d=50ms", http.StatusAccepted},
+		{"/slow/strict?d=50ms", http.StatusGatewayTimeout},
+		{"/stream?d=50ms", http.StatusAccepted},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.path, rec.Code, tt.want)
+			continue
+		}
+		switch tt.want {
+		case http.StatusAccepted:
+			if rec.Body.String() != "finished" || rec.Header().Get("X-Done") != "yes" {
+				t.Errorf("%s: response %q %v", tt.path, rec.Body, rec.Header())
+			}
+		case http.StatusGatewayTimeout:
+			if rec.Body.String() != timeoutBody || rec.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+				t.Errorf("%s: timeout response %q %v", tt.path, rec.Body, rec.Header())
+			}
+		}
+	}
+}
+
+func TestTimeoutPanicPropagates(t *testing.T) {
+	h := Timeout(TimeoutConfig{Default: time.Second})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	}))
+	defer func() {
+		if v := recover(); v != "boom" {
+			t.Fatalf("recovered %v, want boom", v)
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
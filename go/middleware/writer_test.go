@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWrappedWriterHijack(t *testing.T) {
+	h := Chain(RequestID(), Logger(slog.New(slog.NewTextHandler(io.Discard, nil))), Recover(slog.New(slog.NewTextHandler(io.Discard, nil))))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, rw, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer conn.Close()
+			rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\nX-Tag: raw\r\n\r\nhijacked")
+			rw.Flush()
+		}))
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.Header.Get("X-Tag") != "raw" || string(body) != "hijacked" {
+		t.Fatalf("response %v %q", resp.Header, body)
+	}
+}
+
+func TestWrappedWriterFlush(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rw := &responseWriter{ResponseWriter: rec}
+	var f http.Flusher = rw
+	f.Flush()
+	if !rec.Flushed || rw.status != http.StatusOK {
+		t.Fatalf("flushed %v, status %d", rec.Flushed, rw.status)
+	}
+}
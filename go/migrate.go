@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"example.com/tutorial/humanize"
+	"example.com/tutorial/migrate"
+	"example.com/tutorial/output"
+	"example.com/tutorial/user/sqlite"
+)
+
+// defaultMigrateDB is the database of the "migrate" subcommand when -db
+// is not given.
+const defaultMigrateDB = "users.db"
+
+// runMigrate implements the "migrate" subcommand, which moves the schema
+// of an SQLite user database between the versions of its migrations, and
+// returns the process exit code. "down" rolls back one migration unless
+// given a count. A dry run prints the migrations it would apply or roll
+// back, working on a copy of the database. The files of
+// assetsDir/migrations, with -assets, add to or replace the built-in
+// migrations.
+func runMigrate(ctx context.Context, cfg migrateSettings, assetsDir string, dryRun bool, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: migrate [-db file] up")
+		fmt.Fprintln(stderr, "       migrate [-db file] down [n]")
+		fmt.Fprintln(stderr, "       migrate [-db file] status")
+		fs.PrintDefaults()
+	}
+	path := fs.String("db", cfg.DB, "the SQLite database `file`, created if missing")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	action, args := fs.Arg(0), fs.Args()[1:]
+	n := 1
+	switch {
+	case action == "down" && len(args) == 1:
+		var err error
+		if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+			fmt.Fprintf(stderr, "migrate: down: %q is not a positive count\n", args[0])
+			return 2
+		}
+	case (action == "up" || action == "down" || action == "status") && len(args) == 0:
+	default:
+		fs.Usage()
+		return 2
+	}
+
+	db, err := openMigrateDB(*path, dryRun)
+	if err != nil {
+		fmt.Fprintln(stderr, "migrate:", err)
+		return 1
+	}
+	defer db.Close()
+	ms, err := assetMigrations(assetsDir)
+	if err != nil {
+		fmt.Fprintln(stderr, "migrate:", err)
+		return 1
+	}
+	m, err := sqlite.MigratorFrom(ctx, db, ms)
+	if err != nil {
+		fmt.Fprintln(stderr, "migrate:", err)
+		return 1
+	}
+
+	var done []migrate.Migration
+	verb := map[string]string{"up": "applied", "down": "rolled back"}[action]
+	switch action {
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			fmt.Fprintln(stderr, "migrate:", err)
+			return 1
+		}
+		if err := writeMigrationStatus(stdout, status); err != nil {
+			fmt.Fprintln(stderr, "migrate:", err)
+			return 1
+		}
+		return 0
+	case "up":
+		if dryRun {
+			done, err = m.Pending(ctx)
+		} else {
+			done, err = m.Up(ctx)
+		}
+	case "down":
+		if dryRun {
+			done, err = m.Rollback(ctx, n)
+		} else {
+			done, err = m.Down(ctx, n)
+		}
+	}
+	if dryRun {
+		verb = map[string]string{"up": "would apply", "down": "would roll back"}[action]
+	}
+	for _, mig := range done {
+		fmt.Fprintln(stdout, verb, mig)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "migrate:", err)
+		return 1
+	}
+	if len(done) == 0 {
+		fmt.Fprintln(stdout, "nothing to do")
+	}
+	return 0
+}
+
+// openMigrateDB opens the database at path or, in a dry run, a copy of
+// it in memory, so that neither the migrations nor their bookkeeping
+// change the file.
+func openMigrateDB(path string, dryRun bool) (*sql.DB, error) {
+	if !dryRun {
+		db, err := sql.Open("sqlite", path)
+		if err != nil {
+			return nil, err
+		}
+		db.SetMaxOpenConns(1)
+		return db, nil
+	}
+	// An in-memory database exists per connection, so the one
+	// connection holds the copy.
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return db, nil
+	}
+	// Attaching and copying each table keeps schema and rows; VACUUM
+	// INTO would need a file of its own.
+	if err := copyDatabase(db, path); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// copyDatabase copies the tables and indexes of the database file at
+// path into db.
+func copyDatabase(db *sql.DB, path string) error {
+	if _, err := db.Exec(`ATTACH DATABASE ? AS src`, path); err != nil {
+		return err
+	}
+	rows, err := db.Query(`SELECT type, name, sql FROM src.sqlite_master WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%' ORDER BY type = 'index'`)
+	if err != nil {
+		return err
+	}
+	type object struct{ kind, name, sql string }
+	var objects []object
+	for rows.Next() {
+		var o object
+		if err := rows.Scan(&o.kind, &o.name, &o.sql); err != nil {
+			rows.Close()
+			return err
+		}
+		objects = append(objects, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, o := range objects {
+		if _, err := db.Exec(o.sql); err != nil {
+			return err
+		}
+		if o.kind == "table" {
+			name := `"` + strings.ReplaceAll(o.name, `"`, `""`) + `"`
+			if _, err := db.Exec(`INSERT INTO main.` + name + ` SELECT * FROM src.` + name); err != nil {
+				return err
+			}
+		}
+	}
+	_, err = db.Exec(`DETACH DATABASE src`)
+	return err
+}
+
+// writeMigrationStatus writes a table of the migrations and when each
+// was applied, and how long ago, its header in bold if w colors text.
+func writeMigrationStatus(w io.Writer, status []migrate.Status) error {
+	now := time.Now()
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED")
+	for _, s := range status {
+		applied := "pending"
+		if s.Applied {
+			applied = s.AppliedAt.Local().Format(time.DateTime) + " (" + humanize.Ago(s.AppliedAt, now) + ")"
+		}
+		fmt.Fprintf(tw, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+	}
+	tw.Flush()
+	return output.For(w).WriteTable(b.String())
+}
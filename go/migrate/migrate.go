@@ -0,0 +1,315 @@
+// Package migrate applies versioned SQL migrations to a database,
+// recording each one applied in a schema_migrations table so that every
+// migration runs once, in version order.
+//
+// Migrations are read from files, typically embedded with embed.FS,
+// named VERSION_NAME.up.sql and VERSION_NAME.down.sql, such as
+// 0002_add_password_hash.up.sql. The up file is required; the down file,
+// which undoes it, is optional, and without it the migration cannot be
+// rolled back. Each migration runs in a transaction together with its
+// row in schema_migrations, so a failed one leaves no trace.
+//
+// The statements Migrator issues are those of SQLite.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Table is the table that records the applied migrations.
+const Table = "schema_migrations"
+
+// Errors returned by Load and Migrator.
+var (
+	ErrBadName        = errors.New("migrate: bad migration file name")
+	ErrDuplicate      = errors.New("migrate: duplicate migration")
+	ErrIrreversible   = errors.New("migrate: migration has no down file")
+	ErrUnknownVersion = errors.New("migrate: database has a migration this program does not")
+	ErrBaseline       = errors.New("migrate: cannot baseline a migrated database")
+)
+
+// Migration is one schema change.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string // empty if the migration cannot be rolled back
+}
+
+func (m Migration) String() string { return fmt.Sprintf("%04d %s", m.Version, m.Name) }
+
+// Load reads the migrations in the directory dir of fsys, sorted by
+// version. Files not ending in .sql are ignored.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+		version, name, up, ok := parseName(e.Name())
+		if !ok {
+			return nil, fmt.Errorf("%w %q, want VERSION_NAME.up.sql or .down.sql", ErrBadName, e.Name())
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("migrate: %w", err)
+		}
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		sql := &m.Down
+		if up {
+			sql = &m.Up
+		}
+		if m.Name != name || *sql != "" {
+			return nil, fmt.Errorf("%w %d: %s", ErrDuplicate, version, e.Name())
+		}
+		*sql = string(data)
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" {
+			return nil, fmt.Errorf("%w: %s has no up file", ErrBadName, m)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// parseName splits "0002_add_password_hash.up.sql".
+func parseName(file string) (version int, name string, up, ok bool) {
+	base := strings.TrimSuffix(file, ".sql")
+	switch {
+	case strings.HasSuffix(base, ".up"):
+		base, up = strings.TrimSuffix(base, ".up"), true
+	case strings.HasSuffix(base, ".down"):
+		base = strings.TrimSuffix(base, ".down")
+	default:
+		return 0, "", false, false
+	}
+	v, name, found := strings.Cut(base, "_")
+	version, err := strconv.Atoi(v)
+	if !found || err != nil || version <= 0 || name == "" {
+		return 0, "", false, false
+	}
+	return version, name, up, true
+}
+
+// Status is the state of one migration.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time // zero if not applied
+}
+
+// Migrator applies migrations to a database.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// New returns a Migrator applying migrations, sorted by version as Load
+// returns them, to db.
+func New(db *sql.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+func (m *Migrator) init(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+Table+` (
+	version    INTEGER PRIMARY KEY,
+	name       TEXT NOT NULL,
+	applied_at TEXT NOT NULL
+)`)
+	if err != nil {
+		return fmt.Errorf("migrate: create %s: %w", Table, err)
+	}
+	return nil
+}
+
+// applied returns the applied versions and when they were applied.
+func (m *Migrator) applied(ctx context.Context) (map[int]time.Time, error) {
+	if err := m.init(ctx); err != nil {
+		return nil, err
+	}
+	rows, err := m.db.QueryContext(ctx, `SELECT version, applied_at FROM `+Table)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	defer rows.Close()
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var at string
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, fmt.Errorf("migrate: %w", err)
+		}
+		applied[version], _ = time.Parse(time.RFC3339, at)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	return applied, nil
+}
+
+// Status returns the state of every migration, in version order. It
+// fails with ErrUnknownVersion if the database has applied a version
+// that is not among the migrations, as when it was migrated by a newer
+// program.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Status, len(m.migrations))
+	for i, mig := range m.migrations {
+		at, ok := applied[mig.Version]
+		list[i] = Status{Migration: mig, Applied: ok, AppliedAt: at}
+		delete(applied, mig.Version)
+	}
+	if len(applied) > 0 {
+		unknown := make([]int, 0, len(applied))
+		for version := range applied {
+			unknown = append(unknown, version)
+		}
+		sort.Ints(unknown)
+		return list, fmt.Errorf("%w: version %d", ErrUnknownVersion, unknown[0])
+	}
+	return list, nil
+}
+
+// Pending returns the migrations Up would apply.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, s := range status {
+		if !s.Applied {
+			pending = append(pending, s.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies the pending migrations in version order and returns those
+// it applied. It stops at the first that fails, whose error names it.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for _, mig := range pending {
+		err := m.inTx(ctx, mig.Up, `INSERT INTO `+Table+` (version, name, applied_at) VALUES (?, ?, ?)`,
+			mig.Version, mig.Name, time.Now().UTC().Format(time.RFC3339))
+		if err != nil {
+			return done, fmt.Errorf("migrate: up %s: %w", mig, err)
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Rollback returns the applied migrations Down(n) would roll back, the
+// latest first.
+func (m *Migrator) Rollback(ctx context.Context, n int) ([]Migration, error) {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var list []Migration
+	for i := len(status) - 1; i >= 0 && len(list) < n; i-- {
+		if status[i].Applied {
+			list = append(list, status[i].Migration)
+		}
+	}
+	return list, nil
+}
+
+// Down rolls back the last n applied migrations, the latest first, and
+// returns those it rolled back. It fails with ErrIrreversible, before
+// rolling back any, if one of them has no down file.
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	list, err := m.Rollback(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+	for _, mig := range list {
+		if strings.TrimSpace(mig.Down) == "" {
+			return nil, fmt.Errorf("%w: %s", ErrIrreversible, mig)
+		}
+	}
+	var done []Migration
+	for _, mig := range list {
+		if err := m.inTx(ctx, mig.Down, `DELETE FROM `+Table+` WHERE version = ?`, mig.Version); err != nil {
+			return done, fmt.Errorf("migrate: down %s: %w", mig, err)
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Baseline records the migrations up to version as applied without
+// running them, for a database whose schema predates its migrations. It
+// fails with ErrBaseline if any migration is recorded already.
+func (m *Migrator) Baseline(ctx context.Context, version int) error {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+	if len(applied) > 0 {
+		return ErrBaseline
+	}
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	defer tx.Rollback()
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, mig := range m.migrations {
+		if mig.Version > version {
+			break
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO `+Table+` (version, name, applied_at) VALUES (?, ?, ?)`, mig.Version, mig.Name, now); err != nil {
+			return fmt.Errorf("migrate: baseline: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("migrate: baseline: %w", err)
+	}
+	return nil
+}
+
+// inTx runs script and then the bookkeeping statement record in one
+// transaction.
+func (m *Migrator) inTx(ctx context.Context, script, record string, args ...any) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
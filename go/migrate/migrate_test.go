@@ -0,0 +1,133 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	_ "modernc.org/sqlite"
+)
+
+var files = fstest.MapFS{
+	"m/0001_create_notes.up.sql":   {Data: []byte("CREATE TABLE notes (id INTEGER PRIMARY KEY);")},
+	"m/0001_create_notes.down.sql": {Data: []byte("DROP TABLE notes;")},
+	"m/0002_add_body.up.sql":       {Data: []byte("ALTER TABLE notes ADD COLUMN body TEXT;\nCREATE INDEX notes_body ON notes (body);")},
+	"m/0002_add_body.down.sql":     {Data: []byte("DROP INDEX notes_body;\nALTER TABLE notes DROP COLUMN body;")},
+	"m/0003_seed.up.sql":           {Data: []byte("INSERT INTO notes (body) VALUES ('hello');")},
+	"m/README":                     {Data: []byte("not a migration")},
+}
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(files, "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 3 || migrations[1].String() != "0002 add_body" || migrations[2].Down != "" {
+		t.Fatalf("migrations = %+v", migrations)
+	}
+	for name, fsys := range map[string]fstest.MapFS{
+		"bad name":  {"m/one_x.up.sql": {}},
+		"no kind":   {"m/0001_x.sql": {}},
+		"duplicate": {"m/0001_x.up.sql": {Data: []byte("x")}, "m/0001_y.up.sql": {Data: []byte("y")}},
+		"no up":     {"m/0001_x.down.sql": {Data: []byte("x")}},
+	} {
+		if _, err := Load(fsys, "m"); !errors.Is(err, ErrBadName) && !errors.Is(err, ErrDuplicate) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestUpDown(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	migrations, _ := Load(files, "m")
+	m := New(db, migrations)
+
+	done, err := m.Up(ctx)
+	if err != nil || len(done) != 3 {
+		t.Fatalf("up = %v, %v", done, err)
+	}
+	var body string
+	if err := db.QueryRow(`SELECT body FROM notes`).Scan(&body); err != nil || body != "hello" {
+		t.Fatalf("seeded %q, %v", body, err)
+	}
+	if done, err := m.Up(ctx); err != nil || len(done) != 0 {
+		t.Fatalf("second up = %v, %v", done, err)
+	}
+
+	// 0003 has no down file, so nothing is rolled back.
+	if _, err := m.Down(ctx, 2); !errors.Is(err, ErrIrreversible) {
+		t.Fatalf("down past 0003: %v", err)
+	}
+	status, _ := m.Status(ctx)
+	if !status[2].Applied || status[2].AppliedAt.IsZero() {
+		t.Fatalf("status = %+v", status)
+	}
+
+	// Without 0003, the others roll back the latest first.
+	m = New(db, migrations[:2])
+	if _, err := m.Status(ctx); !errors.Is(err, ErrUnknownVersion) {
+		t.Fatalf("status with 0003 unknown: %v", err)
+	}
+	db.Exec(`DELETE FROM ` + Table + ` WHERE version = 3`)
+	done, err = m.Down(ctx, 5)
+	if err != nil || len(done) != 2 || done[0].Version != 2 || done[1].Version != 1 {
+		t.Fatalf("down = %v, %v", done, err)
+	}
+	if _, err := db.Exec(`SELECT 1 FROM notes`); err == nil {
+		t.Fatal("notes table survived down")
+	}
+	if pending, err := m.Pending(ctx); err != nil || len(pending) != 2 {
+		t.Fatalf("pending = %v, %v", pending, err)
+	}
+}
+
+func TestUpFailure(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	m := New(db, []Migration{
+		{Version: 1, Name: "ok", Up: "CREATE TABLE a (x INTEGER);"},
+		{Version: 2, Name: "broken", Up: "CREATE TABLE b (x INTEGER); INSERT INTO nowhere VALUES (1);"},
+	})
+	done, err := m.Up(ctx)
+	if err == nil || len(done) != 1 {
+		t.Fatalf("up = %v, %v", done, err)
+	}
+	// The failed migration left neither its table nor its record.
+	if _, err := db.Exec(`SELECT 1 FROM b`); err == nil {
+		t.Error("table b created by a failed migration")
+	}
+	if pending, _ := m.Pending(ctx); len(pending) != 1 || pending[0].Name != "broken" {
+		t.Errorf("pending = %v", pending)
+	}
+}
+
+func TestBaseline(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	db.Exec(`CREATE TABLE notes (id INTEGER PRIMARY KEY)`)
+	migrations, _ := Load(files, "m")
+	m := New(db, migrations)
+	if err := m.Baseline(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if done, err := m.Up(ctx); err != nil || len(done) != 2 || done[0].Version != 2 {
+		t.Fatalf("up = %v, %v", done, err)
+	}
+	if err := m.Baseline(ctx, 1); !errors.Is(err, ErrBaseline) {
+		t.Fatalf("second baseline: %v", err)
+	}
+}
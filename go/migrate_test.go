@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRunMigrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.db")
+	migrate := func(dryRun bool, args ...string) (string, int) {
+		t.Helper()
+		var out, errOut bytes.Buffer
+		code := runMigrate(context.Background(), migrateSettings{DB: path}, "", dryRun, args, &out, &errOut)
+		return out.String() + errOut.String(), code
+	}
+
+	if out, code := migrate(true, "up"); code != 0 || out != "would apply 0001 create_users\nwould apply 0002 add_password_hash\nwould apply 0003 string_ids\n" {
+		t.Fatalf("dry run: exit %d:\n%s", code, out)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("dry run created %s: %v", path, err)
+	}
+	if out, code := migrate(false, "up"); code != 0 || out != "applied 0001 create_users\napplied 0002 add_password_hash\napplied 0003 string_ids\n" {
+		t.Fatalf("up: exit %d:\n%s", code, out)
+	}
+	if out, code := migrate(false, "up"); code != 0 || out != "nothing to do\n" {
+		t.Fatalf("second up: exit %d:\n%s", code, out)
+	}
+
+	if out, code := migrate(true, "down", "2"); code != 0 || out != "would roll back 0003 string_ids\nwould roll back 0002 add_password_hash\n" {
+		t.Fatalf("dry run down: exit %d:\n%s", code, out)
+	}
+	if out, code := migrate(false, "down"); code != 0 || out != "rolled back 0003 string_ids\n" {
+		t.Fatalf("down: exit %d:\n%s", code, out)
+	}
+	out, code := migrate(false, "status")
+	if code != 0 || !strings.HasPrefix(out, "VERSION  NAME               APPLIED\n0001     create_users       2") ||
+		!regexp.MustCompile(`\((just now|\w+ ago)\)\n`).MatchString(out) || !strings.HasSuffix(out, "0003     string_ids         pending\n") {
+		t.Fatalf("status: exit %d:\n%s", code, out)
+	}
+}
+
+func TestRunMigrateLegacy(t *testing.T) {
+	// A database from before migrations is baselined, not recreated.
+	path := filepath.Join(t.TempDir(), "users.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL)`)
+	db.Exec(`INSERT INTO users (name) VALUES ('ann')`)
+	db.Close()
+
+	var out, errOut bytes.Buffer
+	if code := runMigrate(context.Background(), migrateSettings{DB: path}, "", false, []string{"up"}, &out, &errOut); code != 0 || out.String() != "applied 0002 add_password_hash\napplied 0003 string_ids\n" {
+		t.Fatalf("exit %d:\n%s%s", code, out.String(), errOut.String())
+	}
+	db, _ = sql.Open("sqlite", path)
+	defer db.Close()
+	var name string
+	if err := db.QueryRow(`SELECT name FROM users WHERE password_hash = ''`).Scan(&name); err != nil || name != "ann" {
+		t.Fatalf("user %q, %v", name, err)
+	}
+}
+
+func TestRunMigrateErrors(t *testing.T) {
+	cfg := migrateSettings{DB: filepath.Join(t.TempDir(), "users.db")}
+	for _, args := range [][]string{nil, {"sideways"}, {"up", "1"}, {"down", "0"}, {"down", "1", "2"}} {
+		var out, errOut bytes.Buffer
+		if code := runMigrate(context.Background(), cfg, "", false, args, &out, &errOut); code != 2 {
+			t.Errorf("%q: exit %d", args, code)
+		}
+	}
+	var out, errOut bytes.Buffer
+	cfg.DB = t.TempDir() // a directory is no database
+	if code := runMigrate(context.Background(), cfg, "", false, []string{"status"}, &out, &errOut); code != 1 {
+		t.Errorf("directory: exit %d", code)
+	}
+}
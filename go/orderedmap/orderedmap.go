@@ -0,0 +1,228 @@
+// Package orderedmap provides Map, a map that remembers the order its
+// keys were first set in. Listing, ranging over and encoding a Map all
+// follow that order, so output built from one is the same on every run,
+// where a Go map's order changes and encoding/json sorts keys instead.
+package orderedmap
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// Map is a map from K to V in insertion order. Get, Set and Delete take
+// constant time. Create one with New; the zero value is only good for
+// decoding JSON into. A Map is not safe for concurrent use.
+type Map[K comparable, V any] struct {
+	entries map[K]*entry[K, V]
+	root    entry[K, V] // sentinel: root.next is the oldest, root.prev the newest
+}
+
+type entry[K comparable, V any] struct {
+	key        K
+	value      V
+	prev, next *entry[K, V]
+}
+
+// New returns an empty map.
+func New[K comparable, V any]() *Map[K, V] {
+	m := &Map[K, V]{}
+	m.init()
+	return m
+}
+
+func (m *Map[K, V]) init() {
+	m.entries = map[K]*entry[K, V]{}
+	m.root.prev, m.root.next = &m.root, &m.root
+}
+
+// Len returns the number of keys in m.
+func (m *Map[K, V]) Len() int { return len(m.entries) }
+
+// Get returns the value of k and whether m has it.
+func (m *Map[K, V]) Get(k K) (V, bool) {
+	if e, ok := m.entries[k]; ok {
+		return e.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Has reports whether m has the key k.
+func (m *Map[K, V]) Has(k K) bool {
+	_, ok := m.entries[k]
+	return ok
+}
+
+// Set sets the value of k. A new key goes last; a key that is already
+// there keeps its place.
+func (m *Map[K, V]) Set(k K, v V) {
+	if e, ok := m.entries[k]; ok {
+		e.value = v
+		return
+	}
+	e := &entry[K, V]{key: k, value: v, prev: m.root.prev, next: &m.root}
+	e.prev.next, m.root.prev = e, e
+	m.entries[k] = e
+}
+
+// Delete removes k, reporting whether m had it. Setting k again puts it
+// last.
+func (m *Map[K, V]) Delete(k K) bool {
+	e, ok := m.entries[k]
+	if !ok {
+		return false
+	}
+	e.prev.next, e.next.prev = e.next, e.prev
+	e.prev, e.next = nil, nil
+	delete(m.entries, k)
+	return true
+}
+
+// Keys returns the keys of m in order. The result is never nil.
+func (m *Map[K, V]) Keys() []K {
+	keys := make([]K, 0, len(m.entries))
+	for e := m.root.next; e != &m.root; e = e.next {
+		keys = append(keys, e.key)
+	}
+	return keys
+}
+
+// Values returns the values of m in the order of their keys. The result
+// is never nil.
+func (m *Map[K, V]) Values() []V {
+	values := make([]V, 0, len(m.entries))
+	for e := m.root.next; e != &m.root; e = e.next {
+		values = append(values, e.value)
+	}
+	return values
+}
+
+// Range calls f with each key and value of m in order, stopping early if
+// f returns false. f may delete the key it is given, but must not
+// otherwise change m.
+func (m *Map[K, V]) Range(f func(k K, v V) bool) {
+	for e := m.root.next; e != &m.root; {
+		next := e.next
+		if !f(e.key, e.value) {
+			return
+		}
+		e = next
+	}
+}
+
+// MarshalJSON encodes m as a JSON object with its keys in order. Keys
+// are encoded as encoding/json encodes map keys: strings as they are,
+// encoding.TextMarshalers by their text and integers in decimal.
+func (m *Map[K, V]) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return []byte("null"), nil
+	}
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for e := m.root.next; e != &m.root; e = e.next {
+		if e != m.root.next {
+			b.WriteByte(',')
+		}
+		name, err := keyName(e.key)
+		if err != nil {
+			return nil, err
+		}
+		key, _ := json.Marshal(name)
+		b.Write(key)
+		b.WriteByte(':')
+		value, err := json.Marshal(e.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// UnmarshalJSON decodes a JSON object into m, replacing its contents,
+// with the keys in the order the object gives them. A key given twice
+// keeps its first place and its last value, as with Set.
+func (m *Map[K, V]) UnmarshalJSON(data []byte) error {
+	m.init()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil
+	}
+	if tok != json.Delim('{') {
+		return fmt.Errorf("orderedmap: cannot decode %v into a map", tok)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		k, err := parseKey[K](tok.(string))
+		if err != nil {
+			return err
+		}
+		var v V
+		if err := dec.Decode(&v); err != nil {
+			return err
+		}
+		m.Set(k, v)
+	}
+	_, err = dec.Token() // the closing brace
+	return err
+}
+
+// keyName returns the object key of k.
+func keyName(k any) (string, error) {
+	if tm, ok := k.(encoding.TextMarshaler); ok {
+		text, err := tm.MarshalText()
+		return string(text), err
+	}
+	v := reflect.ValueOf(k)
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("orderedmap: unsupported key type %T", k)
+}
+
+// parseKey is the inverse of keyName.
+func parseKey[K comparable](name string) (K, error) {
+	var k K
+	if tu, ok := any(&k).(encoding.TextUnmarshaler); ok {
+		err := tu.UnmarshalText([]byte(name))
+		return k, err
+	}
+	v := reflect.ValueOf(&k).Elem()
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(name)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(name, 10, v.Type().Bits())
+		if err != nil {
+			return k, fmt.Errorf("orderedmap: key %q: %w", name, errors.Unwrap(err))
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(name, 10, v.Type().Bits())
+		if err != nil {
+			return k, fmt.Errorf("orderedmap: key %q: %w", name, errors.Unwrap(err))
+		}
+		v.SetUint(n)
+	default:
+		return k, fmt.Errorf("orderedmap: unsupported key type %T", k)
+	}
+	return k, nil
+}
@@ -0,0 +1,166 @@
+package orderedmap
+
+import (
+	"encoding/json"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOrder(t *testing.T) {
+	m := New[string, int]()
+	for i, k := range []string{"c", "a", "b"} {
+		m.Set(k, i)
+	}
+	m.Set("a", 10) // keeps its place
+	if got := m.Keys(); !reflect.DeepEqual(got, []string{"c", "a", "b"}) {
+		t.Fatalf("Keys = %q", got)
+	}
+	if got := m.Values(); !reflect.DeepEqual(got, []int{0, 10, 2}) {
+		t.Fatalf("Values = %v", got)
+	}
+	if v, ok := m.Get("a"); !ok || v != 10 || !m.Has("b") || m.Has("z") || m.Len() != 3 {
+		t.Fatalf("Get(a) = %d, %v; Len %d", v, ok, m.Len())
+	}
+	if v, ok := m.Get("z"); ok || v != 0 {
+		t.Fatalf("Get(z) = %d, %v", v, ok)
+	}
+
+	if !m.Delete("c") || m.Delete("c") {
+		t.Fatal("Delete")
+	}
+	m.Set("c", 3) // back in, last
+	if got := m.Keys(); !reflect.DeepEqual(got, []string{"a", "b", "c"}) || m.Len() != 3 {
+		t.Fatalf("Keys after delete and set = %q", got)
+	}
+
+	for _, k := range m.Keys() {
+		m.Delete(k)
+	}
+	if m.Len() != 0 || len(m.Keys()) != 0 || m.Keys() == nil || m.Values() == nil {
+		t.Fatal("emptied map is not empty")
+	}
+	m.Set("x", 1)
+	if got := m.Keys(); !reflect.DeepEqual(got, []string{"x"}) {
+		t.Fatalf("Keys after emptying = %q", got)
+	}
+}
+
+func TestRange(t *testing.T) {
+	m := New[int, string]()
+	for _, k := range []int{3, 1, 2, 5} {
+		m.Set(k, strings.Repeat("x", k))
+	}
+	var seen []int
+	m.Range(func(k int, v string) bool {
+		if len(v) != k {
+			t.Fatalf("%d: %q", k, v)
+		}
+		seen = append(seen, k)
+		return k != 2
+	})
+	if !reflect.DeepEqual(seen, []int{3, 1, 2}) {
+		t.Fatalf("Range stopped after %v", seen)
+	}
+
+	// Deleting the current key is allowed.
+	m.Range(func(k int, _ string) bool {
+		if k%2 == 1 {
+			m.Delete(k)
+		}
+		return true
+	})
+	if got := m.Keys(); !reflect.DeepEqual(got, []int{2}) {
+		t.Fatalf("Keys after deleting while ranging = %v", got)
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	words := New[string, int]()
+	words.Set("the", 3)
+	words.Set("cat", 1)
+	words.Set("a\"b", 1)
+	ints := New[int, bool]()
+	ints.Set(10, true)
+	ints.Set(-2, false)
+	addrs := New[netip.Addr, string]()
+	addrs.Set(netip.MustParseAddr("10.0.0.2"), "b")
+	addrs.Set(netip.MustParseAddr("10.0.0.1"), "a")
+	nested := New[string, any]()
+	nested.Set("z", words)
+	nested.Set("a", []int{1})
+	var nilMap *Map[string, int]
+
+	for _, tc := range []struct {
+		in   any
+		want string
+	}{
+		{words, `{"the":3,"cat":1,"a\"b":1}`},
+		{ints, `{"10":true,"-2":false}`},
+		{addrs, `{"10.0.0.2":"b","10.0.0.1":"a"}`},
+		{nested, `{"z":{"the":3,"cat":1,"a\"b":1},"a":[1]}`},
+		{New[string, int](), `{}`},
+		{nilMap, `null`},
+	} {
+		data, err := json.Marshal(tc.in)
+		if err != nil || string(data) != tc.want {
+			t.Errorf("Marshal = %s, %v, want %s", data, err, tc.want)
+		}
+	}
+
+	bad := New[float64, int]()
+	bad.Set(1.5, 1)
+	if _, err := json.Marshal(bad); err == nil || !strings.Contains(err.Error(), "unsupported key type float64") {
+		t.Fatalf("float keys: %v", err)
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	m := New[string, int]()
+	m.Set("old", 1)
+	if err := json.Unmarshal([]byte(`{"z": 1, "a": 2, "m": 3, "a": 4}`), m); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Keys(); !reflect.DeepEqual(got, []string{"z", "a", "m"}) {
+		t.Fatalf("Keys = %q", got)
+	}
+	if v, _ := m.Get("a"); v != 4 || m.Has("old") {
+		t.Fatalf("a = %d, old still there: %v", v, m.Has("old"))
+	}
+
+	// Into the zero value, as a struct field.
+	var doc struct {
+		Counts Map[int, []string] `json:"counts"`
+		Ptr    *Map[string, int]  `json:"ptr"`
+	}
+	if err := json.Unmarshal([]byte(`{"counts":{"2":["a","b"],"1":["c"]},"ptr":{"x":1}}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if got := doc.Counts.Keys(); !reflect.DeepEqual(got, []int{2, 1}) {
+		t.Fatalf("Counts keys = %v", got)
+	}
+	if v, _ := doc.Ptr.Get("x"); v != 1 {
+		t.Fatalf("Ptr = %v", doc.Ptr.Keys())
+	}
+
+	roundTrip := New[netip.Addr, int]()
+	if err := json.Unmarshal([]byte(`{"10.0.0.9":9,"10.0.0.1":1}`), roundTrip); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := json.Marshal(roundTrip); string(data) != `{"10.0.0.9":9,"10.0.0.1":1}` {
+		t.Fatalf("round trip = %s", data)
+	}
+
+	for _, in := range []string{`[1]`, `{"a":"x"}`, `{"a":1`, `"s"`} {
+		if err := json.Unmarshal([]byte(in), New[string, int]()); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded", in)
+		}
+	}
+	if err := json.Unmarshal([]byte(`{"x":1}`), New[int8, int]()); err == nil || !strings.Contains(err.Error(), `key "x"`) {
+		t.Errorf("bad int key: %v", err)
+	}
+	if err := json.Unmarshal([]byte(`{"300":1}`), New[int8, int]()); err == nil {
+		t.Error("out of range key accepted")
+	}
+}
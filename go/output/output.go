@@ -0,0 +1,158 @@
+// Package output writes the text of the command-line tools: with ANSI
+// colors when it goes to a terminal, and as plain text when it is piped or
+// redirected, or when the NO_COLOR convention (https://no-color.org)
+// asks for none.
+package output
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Mode says when a Writer colors its output.
+type Mode int
+
+const (
+	// Auto colors output that goes to a terminal, as Detect decides.
+	Auto Mode = iota
+	// Always colors output wherever it goes.
+	Always
+	// Never writes plain text.
+	Never
+)
+
+var modeNames = [...]string{Auto: "auto", Always: "always", Never: "never"}
+
+// ErrMode is returned by Mode.UnmarshalText for an unknown mode.
+var ErrMode = errors.New("output: mode must be auto, always or never")
+
+func (m Mode) String() string {
+	if m < 0 || int(m) >= len(modeNames) {
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+	return modeNames[m]
+}
+
+// MarshalText returns the name of m, such as "auto".
+func (m Mode) MarshalText() ([]byte, error) { return []byte(m.String()), nil }
+
+// UnmarshalText sets m from its name, so that a Mode can be a flag or a
+// setting.
+func (m *Mode) UnmarshalText(b []byte) error {
+	for i, name := range modeNames {
+		if strings.EqualFold(string(b), name) {
+			*m = Mode(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w, not %q", ErrMode, b)
+}
+
+// Style is an ANSI select graphic rendition code.
+type Style string
+
+// The styles the tools use; Paint combines them.
+const (
+	Bold    Style = "1"
+	Dim     Style = "2"
+	Red     Style = "31"
+	Green   Style = "32"
+	Yellow  Style = "33"
+	Blue    Style = "34"
+	Magenta Style = "35"
+	Cyan    Style = "36"
+)
+
+// Detect reports whether text written to w should be colored: w is a
+// terminal, $NO_COLOR is unset or empty and $TERM is not "dumb". A nil
+// getenv means os.Getenv. For a Writer, Detect returns its decision, and
+// it looks through writers with an Unwrap method for a Writer or a
+// terminal.
+func Detect(w io.Writer, getenv func(string) string) bool {
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	for {
+		switch v := w.(type) {
+		case *Writer:
+			return v.color
+		case *os.File:
+			if getenv("NO_COLOR") != "" || getenv("TERM") == "dumb" {
+				return false
+			}
+			return term.IsTerminal(int(v.Fd()))
+		case interface{ Unwrap() io.Writer }:
+			w = v.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
+// Writer writes to an underlying writer and knows whether the text may
+// be colored.
+type Writer struct {
+	w     io.Writer
+	color bool
+}
+
+// New returns a Writer to w that colors text as m says.
+func New(w io.Writer, m Mode) *Writer {
+	if ow, ok := w.(*Writer); ok {
+		w = ow.w
+	}
+	color := m == Always
+	if m == Auto {
+		color = Detect(w, nil)
+	}
+	return &Writer{w: w, color: color}
+}
+
+// For returns w if it is a Writer and New(w, Auto) otherwise. Code that
+// prints to an io.Writer uses it to color as the Writer it was given, if
+// any, decided.
+func For(w io.Writer) *Writer {
+	if ow, ok := w.(*Writer); ok {
+		return ow
+	}
+	return New(w, Auto)
+}
+
+func (w *Writer) Write(p []byte) (int, error) { return w.w.Write(p) }
+
+// Color reports whether w colors text.
+func (w *Writer) Color() bool { return w.color }
+
+// Unwrap returns the writer that w writes to.
+func (w *Writer) Unwrap() io.Writer { return w.w }
+
+// Paint returns s in the given styles if w colors text, and s unchanged
+// otherwise.
+func (w *Writer) Paint(s string, styles ...Style) string {
+	if !w.color || len(styles) == 0 || s == "" {
+		return s
+	}
+	codes := make([]string, len(styles))
+	for i, st := range styles {
+		codes[i] = string(st)
+	}
+	return "\x1b[" + strings.Join(codes, ";") + "m" + s + "\x1b[0m"
+}
+
+// WriteTable writes table, text whose first line is a header row such as
+// "WORD  COUNT", with the header in bold.
+func (w *Writer) WriteTable(table string) error {
+	if header, rest, found := strings.Cut(table, "\n"); w.color && header != "" {
+		table = w.Paint(header, Bold)
+		if found {
+			table += "\n" + rest
+		}
+	}
+	_, err := io.WriteString(w.w, table)
+	return err
+}
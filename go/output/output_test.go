@@ -0,0 +1,100 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestModeText(t *testing.T) {
+	for _, m := range []Mode{Auto, Always, Never} {
+		b, _ := m.MarshalText()
+		var got Mode
+		if err := got.UnmarshalText(b); err != nil || got != m {
+			t.Errorf("%v: round trip gave %v, %v", m, got, err)
+		}
+	}
+	var m Mode
+	if err := m.UnmarshalText([]byte("NEVER")); err != nil || m != Never {
+		t.Fatalf("NEVER: %v, %v", m, err)
+	}
+	if err := m.UnmarshalText([]byte("sometimes")); !errors.Is(err, ErrMode) {
+		t.Fatalf("sometimes: %v", err)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	env := func(vars ...string) func(string) string {
+		return func(k string) string {
+			for i := 0; i+1 < len(vars); i += 2 {
+				if vars[i] == k {
+					return vars[i+1]
+				}
+			}
+			return ""
+		}
+	}
+	if Detect(&bytes.Buffer{}, env()) {
+		t.Error("a buffer is not a terminal")
+	}
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if Detect(f, env()) {
+		t.Error("a regular file is not a terminal")
+	}
+	if !Detect(New(&bytes.Buffer{}, Always), env("NO_COLOR", "1")) {
+		t.Error("Detect ignored the decision of a Writer")
+	}
+	if !Detect(wrapper{New(&bytes.Buffer{}, Always)}, env()) {
+		t.Error("Detect did not unwrap")
+	}
+	// Without a terminal at hand, check that the variables win over one.
+	if tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0); err == nil {
+		defer tty.Close()
+		if Detect(tty, env("NO_COLOR", "1")) || Detect(tty, env("TERM", "dumb")) {
+			t.Error("NO_COLOR or TERM=dumb ignored")
+		}
+	}
+}
+
+// wrapper is a writer around another, as a progress display's is.
+type wrapper struct{ io.Writer }
+
+func (w wrapper) Unwrap() io.Writer { return w.Writer }
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	plain := New(&buf, Never)
+	if plain.Color() || plain.Paint("x", Red) != "x" {
+		t.Fatal("Never colors")
+	}
+	color := New(plain, Always)
+	if color.Unwrap() != &buf {
+		t.Fatal("New did not unwrap a Writer")
+	}
+	if got := color.Paint("x", Bold, Red); got != "\x1b[1;31mx\x1b[0m" {
+		t.Fatalf("Paint = %q", got)
+	}
+	if color.Paint("", Red) != "" || color.Paint("x") != "x" {
+		t.Fatal("Paint styled nothing")
+	}
+	if For(color) != color || For(&buf).Color() {
+		t.Fatal("For")
+	}
+
+	color.WriteTable("A  B\n1  2\n")
+	plain.WriteTable("A  B\n")
+	color.WriteTable("")
+	if want := "\x1b[1mA  B\x1b[0m\n1  2\nA  B\n"; buf.String() != want {
+		t.Fatalf("WriteTable wrote %q, want %q", buf.String(), want)
+	}
+	if _, err := color.Write([]byte("z")); err != nil || !strings.HasSuffix(buf.String(), "z") {
+		t.Fatal("Write")
+	}
+}
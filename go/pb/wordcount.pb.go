@@ -0,0 +1,243 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        (unknown)
+// source: wordcount.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// WordCount is the result of counting the words of a document, as POST
+// /wordcount returns it.
+type WordCount struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Total number of words.
+	Words int64 `protobuf:"varint,1,opt,name=words,proto3" json:"words,omitempty"`
+	// Number of distinct words.
+	Unique int64 `protobuf:"varint,2,opt,name=unique,proto3" json:"unique,omitempty"`
+	// The most frequent words, by count descending and then alphabetically.
+	Top []*WordFreq `protobuf:"bytes,3,rep,name=top,proto3" json:"top,omitempty"`
+}
+
+func (x *WordCount) Reset() {
+	*x = WordCount{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_wordcount_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WordCount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WordCount) ProtoMessage() {}
+
+func (x *WordCount) ProtoReflect() protoreflect.Message {
+	mi := &file_wordcount_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WordCount.ProtoReflect.Descriptor instead.
+func (*WordCount) Descriptor() ([]byte, []int) {
+	return file_wordcount_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *WordCount) GetWords() int64 {
+	if x != nil {
+		return x.Words
+	}
+	return 0
+}
+
+func (x *WordCount) GetUnique() int64 {
+	if x != nil {
+		return x.Unique
+	}
+	return 0
+}
+
+func (x *WordCount) GetTop() []*WordFreq {
+	if x != nil {
+		return x.Top
+	}
+	return nil
+}
+
+// WordFreq is a word and the number of times it occurs.
+type WordFreq struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Word  string `protobuf:"bytes,1,opt,name=word,proto3" json:"word,omitempty"`
+	Count int64  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+}
+
+func (x *WordFreq) Reset() {
+	*x = WordFreq{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_wordcount_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WordFreq) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WordFreq) ProtoMessage() {}
+
+func (x *WordFreq) ProtoReflect() protoreflect.Message {
+	mi := &file_wordcount_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WordFreq.ProtoReflect.Descriptor instead.
+func (*WordFreq) Descriptor() ([]byte, []int) {
+	return file_wordcount_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *WordFreq) GetWord() string {
+	if x != nil {
+		return x.Word
+	}
+	return ""
+}
+
+func (x *WordFreq) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+var File_wordcount_proto protoreflect.FileDescriptor
+
+var file_wordcount_proto_rawDesc = []byte{
+	0x0a, 0x0f, 0x77, 0x6f, 0x72, 0x64, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x12, 0x15, 0x74, 0x75, 0x74, 0x6f, 0x72, 0x69, 0x61, 0x6c, 0x2e, 0x77, 0x6f, 0x72, 0x64,
+	0x63, 0x6f, 0x75, 0x6e, 0x74, 0x2e, 0x76, 0x31, 0x22, 0x6c, 0x0a, 0x09, 0x57, 0x6f, 0x72, 0x64,
+	0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x77, 0x6f, 0x72, 0x64, 0x73, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x77, 0x6f, 0x72, 0x64, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x75,
+	0x6e, 0x69, 0x71, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x75, 0x6e, 0x69,
+	0x71, 0x75, 0x65, 0x12, 0x31, 0x0a, 0x03, 0x74, 0x6f, 0x70, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x1f, 0x2e, 0x74, 0x75, 0x74, 0x6f, 0x72, 0x69, 0x61, 0x6c, 0x2e, 0x77, 0x6f, 0x72, 0x64,
+	0x63, 0x6f, 0x75, 0x6e, 0x74, 0x2e, 0x76, 0x31, 0x2e, 0x57, 0x6f, 0x72, 0x64, 0x46, 0x72, 0x65,
+	0x71, 0x52, 0x03, 0x74, 0x6f, 0x70, 0x22, 0x34, 0x0a, 0x08, 0x57, 0x6f, 0x72, 0x64, 0x46, 0x72,
+	0x65, 0x71, 0x12, 0x12, 0x0a, 0x04, 0x77, 0x6f, 0x72, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x77, 0x6f, 0x72, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x42, 0x1c, 0x5a, 0x1a,
+	0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x74, 0x75, 0x74, 0x6f,
+	0x72, 0x69, 0x61, 0x6c, 0x2f, 0x70, 0x62, 0x3b, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x33,
+}
+
+var (
+	file_wordcount_proto_rawDescOnce sync.Once
+	file_wordcount_proto_rawDescData = file_wordcount_proto_rawDesc
+)
+
+func file_wordcount_proto_rawDescGZIP() []byte {
+	file_wordcount_proto_rawDescOnce.Do(func() {
+		file_wordcount_proto_rawDescData = protoimpl.X.CompressGZIP(file_wordcount_proto_rawDescData)
+	})
+	return file_wordcount_proto_rawDescData
+}
+
+var file_wordcount_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_wordcount_proto_goTypes = []any{
+	(*WordCount)(nil), // 0: tutorial.wordcount.v1.WordCount
+	(*WordFreq)(nil),  // 1: tutorial.wordcount.v1.WordFreq
+}
+var file_wordcount_proto_depIdxs = []int32{
+	1, // 0: tutorial.wordcount.v1.WordCount.top:type_name -> tutorial.wordcount.v1.WordFreq
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_wordcount_proto_init() }
+func file_wordcount_proto_init() {
+	if File_wordcount_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_wordcount_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*WordCount); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_wordcount_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*WordFreq); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_wordcount_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_wordcount_proto_goTypes,
+		DependencyIndexes: file_wordcount_proto_depIdxs,
+		MessageInfos:      file_wordcount_proto_msgTypes,
+	}.Build()
+	File_wordcount_proto = out.File
+	file_wordcount_proto_rawDesc = nil
+	file_wordcount_proto_goTypes = nil
+	file_wordcount_proto_depIdxs = nil
+}
@@ -0,0 +1,95 @@
+// Package pbcodec converts the core types to and from their protobuf
+// messages in package pb, and encodes them in the protobuf wire format:
+// user.User as pb.User and text.Summary, the result of a word count, as
+// pb.WordCount. The messages are what the gRPC services send; the
+// benchmarks compare their size and speed with the JSON of the HTTP API.
+package pbcodec
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+
+	"example.com/tutorial/pb"
+	"example.com/tutorial/text"
+	"example.com/tutorial/user"
+)
+
+// UserToProto returns the message of u. The password hash is left out:
+// it never leaves the server.
+func UserToProto(u user.User) *pb.User {
+	return &pb.User{Id: u.ID, Name: u.Name}
+}
+
+// UserFromProto returns the user of m; a nil m is the zero User.
+func UserFromProto(m *pb.User) user.User {
+	return user.User{ID: m.GetId(), Name: m.GetName()}
+}
+
+// SummaryToProto returns the message of s.
+func SummaryToProto(s text.Summary) *pb.WordCount {
+	m := &pb.WordCount{Words: int64(s.Words), Unique: int64(s.Unique)}
+	if len(s.Top) > 0 {
+		m.Top = make([]*pb.WordFreq, len(s.Top))
+		for i, wf := range s.Top {
+			m.Top[i] = &pb.WordFreq{Word: wf.Word, Count: int64(wf.Count)}
+		}
+	}
+	return m
+}
+
+// SummaryFromProto returns the summary of m. Top is nil if m has no
+// words; a nil m is the zero Summary.
+func SummaryFromProto(m *pb.WordCount) text.Summary {
+	s := text.Summary{Words: int(m.GetWords()), Unique: int(m.GetUnique())}
+	if top := m.GetTop(); len(top) > 0 {
+		s.Top = make([]text.WordFreq, len(top))
+		for i, wf := range top {
+			s.Top[i] = text.WordFreq{Word: wf.GetWord(), Count: int(wf.GetCount())}
+		}
+	}
+	return s
+}
+
+// MarshalUser encodes u as a pb.User.
+func MarshalUser(u user.User) ([]byte, error) {
+	return marshal(UserToProto(u))
+}
+
+// UnmarshalUser decodes a pb.User.
+func UnmarshalUser(data []byte) (user.User, error) {
+	var m pb.User
+	if err := unmarshal(data, &m); err != nil {
+		return user.User{}, err
+	}
+	return UserFromProto(&m), nil
+}
+
+// MarshalSummary encodes s as a pb.WordCount.
+func MarshalSummary(s text.Summary) ([]byte, error) {
+	return marshal(SummaryToProto(s))
+}
+
+// UnmarshalSummary decodes a pb.WordCount.
+func UnmarshalSummary(data []byte) (text.Summary, error) {
+	var m pb.WordCount
+	if err := unmarshal(data, &m); err != nil {
+		return text.Summary{}, err
+	}
+	return SummaryFromProto(&m), nil
+}
+
+func marshal(m proto.Message) ([]byte, error) {
+	data, err := proto.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("pbcodec: %w", err)
+	}
+	return data, nil
+}
+
+func unmarshal(data []byte, m proto.Message) error {
+	if err := proto.Unmarshal(data, m); err != nil {
+		return fmt.Errorf("pbcodec: %s: %w", m.ProtoReflect().Descriptor().Name(), err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package pbcodec
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"example.com/tutorial/text"
+	"example.com/tutorial/user"
+)
+
+func TestUserRoundTrip(t *testing.T) {
+	u := user.User{ID: "42", Name: "Ada Lovelace", PasswordHash: "$2a$04$hash"}
+	data, err := MarshalUser(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := UnmarshalUser(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (user.User{ID: "42", Name: "Ada Lovelace"}); got != want {
+		t.Fatalf("got %+v, want %+v (without the hash)", got, want)
+	}
+	if UserFromProto(nil) != (user.User{}) {
+		t.Fatal("nil message is not the zero user")
+	}
+}
+
+func TestSummaryRoundTrip(t *testing.T) {
+	for _, s := range []text.Summary{
+		summary(3),
+		{Words: 0, Unique: 0},
+	} {
+		data, err := MarshalSummary(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := UnmarshalSummary(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, s) {
+			t.Fatalf("got %+v, want %+v", got, s)
+		}
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	if _, err := UnmarshalSummary([]byte{0x1a, 0x05, 0x0a}); err == nil {
+		t.Fatal("truncated message decoded")
+	}
+	if _, err := UnmarshalUser([]byte{0xff}); err == nil {
+		t.Fatal("bad tag decoded")
+	}
+}
+
+// summary returns a summary with n top words.
+func summary(n int) text.Summary {
+	s := text.Summary{Top: make([]text.WordFreq, n)}
+	for i := range s.Top {
+		s.Top[i] = text.WordFreq{Word: fmt.Sprintf("word%d", i), Count: n - i}
+		s.Words += n - i
+	}
+	s.Unique = n
+	return s
+}
+
+// BenchmarkSummary compares the protobuf and JSON encodings of a word
+// count of 1000 top words, reporting the size of each.
+func BenchmarkSummary(b *testing.B) {
+	s := summary(1000)
+	codecs := []struct {
+		name      string
+		marshal   func(text.Summary) ([]byte, error)
+		unmarshal func([]byte) (text.Summary, error)
+	}{
+		{"proto", MarshalSummary, UnmarshalSummary},
+		{"json", func(s text.Summary) ([]byte, error) { return json.Marshal(s) }, func(data []byte) (text.Summary, error) {
+			var s text.Summary
+			err := json.Unmarshal(data, &s)
+			return s, err
+		}},
+	}
+	for _, c := range codecs {
+		data, err := c.marshal(s)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(c.name+"/marshal", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				c.marshal(s)
+			}
+			b.ReportMetric(float64(len(data)), "payload-bytes")
+		})
+		b.Run(c.name+"/unmarshal", func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				if _, err := c.unmarshal(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkUser(b *testing.B) {
+	u := user.User{ID: "123456", Name: "Grace Brewster Murray Hopper"}
+	for _, c := range []struct {
+		name    string
+		marshal func(user.User) ([]byte, error)
+	}{
+		{"proto", MarshalUser},
+		{"json", func(u user.User) ([]byte, error) { return json.Marshal(u) }},
+	} {
+		data, _ := c.marshal(u)
+		b.Run(c.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				c.marshal(u)
+			}
+			b.ReportMetric(float64(len(data)), "payload-bytes")
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"example.com/tutorial/pipeline"
+)
+
+func Example() {
+	p := pipeline.New(context.Background())
+	words := pipeline.Generate(p, pipeline.StageConfig{}, func(ctx context.Context, emit pipeline.Emit[string]) error {
+		for _, w := range strings.Fields("the quick brown fox") {
+			if !emit(w) {
+				return nil
+			}
+		}
+		return nil
+	})
+	upper := pipeline.Transform(p, words, pipeline.StageConfig{}, func(ctx context.Context, w string, emit pipeline.Emit[string]) error {
+		emit(strings.ToUpper(w))
+		return nil
+	})
+	pipeline.Sink(p, upper, pipeline.StageConfig{}, func(ctx context.Context, w string) error {
+		fmt.Println(w)
+		return nil
+	})
+	if err := p.Wait(); err != nil {
+		panic(err)
+	}
+	// Output:
+	// THE
+	// QUICK
+	// BROWN
+	// FOX
+}
+
+func ExampleWithWorkers() {
+	// Every Transform without a Workers setting of its own runs on four
+	// goroutines.
+	p := pipeline.New(context.Background(), pipeline.WithWorkers(4))
+	nums := pipeline.Generate(p, pipeline.StageConfig{}, func(ctx context.Context, emit pipeline.Emit[int]) error {
+		for i := 1; i <= 100; i++ {
+			emit(i)
+		}
+		return nil
+	})
+	squares := pipeline.Transform(p, nums, pipeline.StageConfig{}, func(ctx context.Context, n int, emit pipeline.Emit[int]) error {
+		emit(n * n)
+		return nil
+	})
+	sum := 0
+	pipeline.Sink(p, squares, pipeline.StageConfig{}, func(ctx context.Context, n int) error {
+		sum += n // one sink worker, so no lock
+		return nil
+	})
+	if err := p.Wait(); err != nil {
+		panic(err)
+	}
+	fmt.Println(sum)
+	// Output: 338350
+}
+
+func ExampleWithTimeout() {
+	p := pipeline.New(context.Background(), pipeline.WithTimeout(10*time.Millisecond))
+	ticks := pipeline.Generate(p, pipeline.StageConfig{}, func(ctx context.Context, emit pipeline.Emit[int]) error {
+		for i := 0; emit(i); i++ {
+			time.Sleep(time.Millisecond)
+		}
+		return nil
+	})
+	pipeline.Sink(p, ticks, pipeline.StageConfig{}, func(ctx context.Context, n int) error { return nil })
+	fmt.Println(errors.Is(p.Wait(), context.DeadlineExceeded))
+	// Output: true
+}
+
+func ExampleWithLogger() {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	p := pipeline.New(context.Background(), pipeline.WithLogger(logger))
+	nums := pipeline.Generate(p, pipeline.StageConfig{}, func(ctx context.Context, emit pipeline.Emit[int]) error {
+		emit(1)
+		return nil
+	})
+	pipeline.Sink(p, nums, pipeline.StageConfig{}, func(ctx context.Context, n int) error {
+		return fmt.Errorf("cannot store %d", n)
+	})
+	fmt.Println(p.Wait())
+	// Output:
+	// level=ERROR msg="pipeline failed" component=pipeline err="cannot store 1"
+	// cannot store 1
+}
@@ -0,0 +1,190 @@
+// Package pipeline connects processing stages with bounded channels.
+// A generator feeds the first channel, transforms read one channel and
+// write the next, optionally fanning out over several workers, and a
+// sink consumes the last. The first stage to fail cancels the others,
+// and Wait reports its error.
+package pipeline
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"example.com/tutorial/logging"
+)
+
+// Pipeline runs a set of connected stages.
+type Pipeline struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// workers is the default worker count of Transform stages.
+	workers int
+	timeout time.Duration
+	logger  *slog.Logger
+
+	errOnce sync.Once
+	err     error
+}
+
+// Option configures a Pipeline built by New.
+type Option func(*Pipeline)
+
+// WithWorkers sets how many goroutines run each Transform stage whose
+// StageConfig leaves Workers zero. Sinks keep one unless their
+// StageConfig says otherwise, since their functions need not be safe
+// for concurrent use.
+func WithWorkers(n int) Option {
+	return func(p *Pipeline) { p.workers = n }
+}
+
+// WithTimeout stops the stages once d has passed, after which Wait
+// reports context.DeadlineExceeded. Zero means no limit.
+func WithTimeout(d time.Duration) Option {
+	return func(p *Pipeline) { p.timeout = d }
+}
+
+// WithLogger logs the error that stops the pipeline to logger, or to
+// slog.Default() if it is nil, with component "pipeline". Without it
+// the pipeline logs nothing.
+func WithLogger(logger *slog.Logger) Option {
+	return func(p *Pipeline) { p.logger = logging.Component(logger, "pipeline") }
+}
+
+// New returns an empty pipeline whose stages stop when ctx is done.
+func New(ctx context.Context, opts ...Option) *Pipeline {
+	p := &Pipeline{}
+	for _, o := range opts {
+		o(p)
+	}
+	if p.timeout > 0 {
+		p.ctx, p.cancel = context.WithTimeout(ctx, p.timeout)
+	} else {
+		p.ctx, p.cancel = context.WithCancel(ctx)
+	}
+	return p
+}
+
+// StageConfig sizes a stage.
+type StageConfig struct {
+	// Workers is how many goroutines run the stage; zero means the
+	// pipeline's WithWorkers for a Transform, and one otherwise.
+	// With more than one, output order is not preserved.
+	Workers int
+	// Buffer is the capacity of the stage's output channel.
+	Buffer int
+}
+
+// Emit sends a value to the next stage. It returns false once the
+// pipeline is cancelled, when the stage should stop.
+type Emit[T any] func(T) bool
+
+// Wait blocks until every stage has returned and reports the first
+// error, or the context's error if the pipeline was cancelled from
+// outside or ran out of time.
+func (p *Pipeline) Wait() error {
+	p.wg.Wait()
+	err := p.ctx.Err()
+	p.cancel()
+	if p.err != nil {
+		return p.err
+	}
+	return err
+}
+
+// fail records the first error and cancels the stages.
+func (p *Pipeline) fail(err error) {
+	p.errOnce.Do(func() {
+		p.err = err
+		p.cancel()
+		if p.logger != nil {
+			p.logger.Error("pipeline failed", "err", err)
+		}
+	})
+}
+
+// run starts n goroutines for one stage and closes out after the last.
+func run[T any](p *Pipeline, n int, out chan T, body func(emit Emit[T]) error) {
+	emit := func(v T) bool {
+		select {
+		case out <- v:
+			return true
+		case <-p.ctx.Done():
+			return false
+		}
+	}
+	var stage sync.WaitGroup
+	for i := 0; i < max(n, 1); i++ {
+		stage.Add(1)
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			defer stage.Done()
+			if err := body(emit); err != nil {
+				p.fail(err)
+			}
+		}()
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		stage.Wait()
+		close(out)
+	}()
+}
+
+// Generate adds a first stage: gen emits values until it is done or
+// emit returns false.
+func Generate[T any](p *Pipeline, cfg StageConfig, gen func(ctx context.Context, emit Emit[T]) error) <-chan T {
+	out := make(chan T, cfg.Buffer)
+	run(p, 1, out, func(emit Emit[T]) error { return gen(p.ctx, emit) })
+	return out
+}
+
+// Transform adds a middle stage: fn is called for every value from in
+// and may emit any number of values for the next stage.
+func Transform[In, Out any](p *Pipeline, in <-chan In, cfg StageConfig, fn func(ctx context.Context, v In, emit Emit[Out]) error) <-chan Out {
+	out := make(chan Out, cfg.Buffer)
+	workers := cfg.Workers
+	if workers == 0 {
+		workers = p.workers
+	}
+	run(p, workers, out, func(emit Emit[Out]) error {
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					return nil
+				}
+				if err := fn(p.ctx, v, emit); err != nil {
+					return err
+				}
+			case <-p.ctx.Done():
+				return nil
+			}
+		}
+	})
+	return out
+}
+
+// Sink adds a last stage calling fn for every value from in. With more
+// than one worker fn must be safe for concurrent use.
+func Sink[T any](p *Pipeline, in <-chan T, cfg StageConfig, fn func(ctx context.Context, v T) error) {
+	run(p, cfg.Workers, make(chan struct{}), func(Emit[struct{}]) error {
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					return nil
+				}
+				if err := fn(p.ctx, v); err != nil {
+					return err
+				}
+			case <-p.ctx.Done():
+				return nil
+			}
+		}
+	})
+}
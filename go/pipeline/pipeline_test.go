@@ -0,0 +1,176 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// numbers emits 1..n.
+func numbers(n int) func(context.Context, Emit[int]) error {
+	return func(ctx context.Context, emit Emit[int]) error {
+		for i := 1; i <= n; i++ {
+			if !emit(i) {
+				return nil
+			}
+		}
+		return nil
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	p := New(context.Background())
+	nums := Generate(p, StageConfig{Buffer: 4}, numbers(100))
+	// Fan out over four workers; each number becomes two values.
+	doubled := Transform(p, nums, StageConfig{Workers: 4, Buffer: 4}, func(ctx context.Context, n int, emit Emit[int]) error {
+		emit(n)
+		emit(-n)
+		return nil
+	})
+	var sum, count int
+	Sink(p, doubled, StageConfig{}, func(ctx context.Context, n int) error {
+		sum += n
+		count++
+		return nil
+	})
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if sum != 0 || count != 200 {
+		t.Fatalf("sum %d over %d values", sum, count)
+	}
+}
+
+func TestPipelineError(t *testing.T) {
+	boom := errors.New("boom")
+	p := New(context.Background())
+	nums := Generate(p, StageConfig{}, numbers(1_000_000))
+	strs := Transform(p, nums, StageConfig{Workers: 2}, func(ctx context.Context, n int, emit Emit[string]) error {
+		if n == 50 {
+			return boom
+		}
+		emit(strings.Repeat("x", n%3))
+		return nil
+	})
+	seen := 0
+	Sink(p, strs, StageConfig{}, func(context.Context, string) error {
+		seen++
+		return nil
+	})
+	done := make(chan error)
+	go func() { done <- p.Wait() }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, boom) {
+			t.Fatalf("Wait = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("pipeline did not stop after an error")
+	}
+	if seen >= 1_000_000 {
+		t.Fatal("error did not cancel the generator")
+	}
+}
+
+func TestPipelineSinkError(t *testing.T) {
+	full := errors.New("disk full")
+	p := New(context.Background())
+	nums := Generate(p, StageConfig{}, numbers(1_000_000))
+	Sink(p, nums, StageConfig{Workers: 3}, func(ctx context.Context, n int) error {
+		if n > 10 {
+			return full
+		}
+		return nil
+	})
+	if err := p.Wait(); !errors.Is(err, full) {
+		t.Fatalf("Wait = %v", err)
+	}
+}
+
+func TestPipelineCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := New(ctx)
+	forever := Generate(p, StageConfig{}, func(ctx context.Context, emit Emit[int]) error {
+		for i := 0; emit(i); i++ {
+		}
+		return nil
+	})
+	var mu sync.Mutex
+	var got []int
+	Sink(p, forever, StageConfig{Workers: 2}, func(ctx context.Context, n int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, n)
+		if len(got) == 10 {
+			cancel()
+		}
+		return nil
+	})
+	if err := p.Wait(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Wait = %v", err)
+	}
+	sort.Ints(got)
+	if len(got) < 10 || got[0] != 0 {
+		t.Fatalf("got %v", got[:min(len(got), 12)])
+	}
+}
+
+func TestWithWorkers(t *testing.T) {
+	p := New(context.Background(), WithWorkers(4))
+	nums := Generate(p, StageConfig{}, numbers(100))
+	// Each worker blocks on its first value until all four have one, so
+	// the stage finishes only if WithWorkers started four.
+	var started sync.WaitGroup
+	started.Add(4)
+	var next atomic.Int32
+	all := make(chan struct{})
+	go func() { started.Wait(); close(all) }()
+	out := Transform(p, nums, StageConfig{}, func(ctx context.Context, n int, emit Emit[int]) error {
+		if next.Add(1) <= 4 {
+			started.Done()
+			select {
+			case <-all:
+			case <-time.After(5 * time.Second):
+				return errors.New("fewer than four workers")
+			}
+		}
+		emit(n)
+		return nil
+	})
+	count := 0
+	Sink(p, out, StageConfig{}, func(ctx context.Context, n int) error {
+		count++
+		return nil
+	})
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if count != 100 {
+		t.Fatalf("count = %d", count)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	p := New(context.Background(), WithTimeout(20*time.Millisecond))
+	forever := Generate(p, StageConfig{}, func(ctx context.Context, emit Emit[int]) error {
+		for i := 0; emit(i); i++ {
+		}
+		return nil
+	})
+	Sink(p, forever, StageConfig{}, func(ctx context.Context, n int) error { return nil })
+	if err := p.Wait(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait = %v", err)
+	}
+
+	// A pipeline that finishes in time reports no error.
+	p = New(context.Background(), WithTimeout(time.Minute))
+	Sink(p, Generate(p, StageConfig{}, numbers(3)), StageConfig{}, func(ctx context.Context, n int) error { return nil })
+	if err := p.Wait(); err != nil {
+		t.Fatalf("Wait = %v", err)
+	}
+}
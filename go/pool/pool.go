@@ -0,0 +1,233 @@
+// Package pool runs a function over a stream of jobs with a bounded set
+// of worker goroutines.
+package pool
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+
+	"example.com/tutorial/metrics"
+)
+
+// Config tunes a Pool.
+type Config struct {
+	// Workers is the number of goroutines running jobs; zero means
+	// runtime.GOMAXPROCS(0).
+	Workers int
+	// Ordered delivers results in the order the jobs were received
+	// rather than as they finish. At most twice Workers results are
+	// held back waiting for a slow job.
+	Ordered bool
+	// Metrics, if set, receives the pool's activity as it happens.
+	Metrics *Metrics
+}
+
+// Metrics reports pool activity into a metrics registry: jobs run by
+// result, the workers busy, and job durations. Several pools may share
+// one Metrics to be reported together.
+type Metrics struct {
+	jobs     *metrics.CounterVec
+	busy     *metrics.Gauge
+	duration *metrics.Histogram
+}
+
+// NewMetrics registers the pool metrics in reg, with names starting
+// with prefix, such as "wordcount_pool".
+func NewMetrics(reg *metrics.Registry, prefix string) *Metrics {
+	return &Metrics{
+		jobs:     reg.Counter(prefix+"_jobs_total", "Pool jobs run, by result (ok or error).", "result"),
+		busy:     reg.Gauge(prefix+"_busy_workers", "Pool workers running a job.").With(),
+		duration: reg.Histogram(prefix+"_job_duration_seconds", "Pool job duration in seconds.", nil).With(),
+	}
+}
+
+// Result is the outcome of one job.
+type Result[T, R any] struct {
+	Index int // position of the job in the input, from 0
+	Job   T
+	Value R
+	Err   error
+}
+
+// WorkerStats describes the work one worker has done.
+type WorkerStats struct {
+	Worker int
+	Jobs   int           // jobs run, including failed ones
+	Errors int           // jobs that returned an error
+	Busy   time.Duration // time spent running jobs
+}
+
+// Pool runs fn over jobs with a fixed number of workers. A Pool may be
+// used for several runs, one after another or at once; its statistics
+// add up across them.
+type Pool[T, R any] struct {
+	fn  func(context.Context, T) (R, error)
+	cfg Config
+
+	mu    sync.Mutex
+	stats []WorkerStats
+}
+
+// New returns a Pool running fn.
+func New[T, R any](fn func(context.Context, T) (R, error), cfg Config) *Pool[T, R] {
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.GOMAXPROCS(0)
+	}
+	stats := make([]WorkerStats, cfg.Workers)
+	for i := range stats {
+		stats[i].Worker = i
+	}
+	return &Pool[T, R]{fn: fn, cfg: cfg, stats: stats}
+}
+
+// Run reads jobs until the channel is closed and returns the results,
+// one per job, on a channel that is closed once every job is done. A job
+// failing does not stop the others; cancel ctx to stop. After ctx is
+// done no more jobs are started and the results of running ones are
+// dropped. The caller must either drain the results or cancel ctx, or
+// the workers block.
+func (p *Pool[T, R]) Run(ctx context.Context, jobs <-chan T) <-chan Result[T, R] {
+	type job struct {
+		idx int
+		v   T
+	}
+	in := make(chan job)
+	done := make(chan Result[T, R])
+	out := make(chan Result[T, R])
+	// A slot is taken when a job is dispatched and given back when its
+	// result is delivered, bounding the results held back for ordering.
+	slots := make(chan struct{}, 2*p.cfg.Workers)
+
+	go func() {
+		defer close(in)
+		for i := 0; ; i++ {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			var v T
+			var ok bool
+			select {
+			case v, ok = <-jobs:
+				if !ok {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case in <- job{i, v}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < p.cfg.Workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for j := range in {
+				start := time.Now()
+				if m := p.cfg.Metrics; m != nil {
+					m.busy.Inc()
+				}
+				v, err := p.fn(ctx, j.v)
+				p.record(w, time.Since(start), err)
+				select {
+				case done <- Result[T, R]{Index: j.idx, Job: j.v, Value: v, Err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(w)
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	go func() {
+		defer close(out)
+		emit := func(r Result[T, R]) bool {
+			select {
+			case out <- r:
+				<-slots
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		next := 0
+		pending := map[int]Result[T, R]{}
+		for r := range done {
+			if !p.cfg.Ordered {
+				if !emit(r) {
+					return
+				}
+				continue
+			}
+			pending[r.Index] = r
+			for {
+				r, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				next++
+				if !emit(r) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+func (p *Pool[T, R]) record(worker int, busy time.Duration, err error) {
+	if m := p.cfg.Metrics; m != nil {
+		m.busy.Dec()
+		m.duration.Observe(busy.Seconds())
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+		m.jobs.With(result).Inc()
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := &p.stats[worker]
+	s.Jobs++
+	s.Busy += busy
+	if err != nil {
+		s.Errors++
+	}
+}
+
+// Stats returns a snapshot of every worker's statistics.
+func (p *Pool[T, R]) Stats() []WorkerStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]WorkerStats(nil), p.stats...)
+}
+
+// Feed sends items on the returned channel, closing it after the last
+// one or once ctx is done.
+func Feed[T any](ctx context.Context, items []T) <-chan T {
+	ch := make(chan T)
+	go func() {
+		defer close(ch)
+		for _, it := range items {
+			select {
+			case ch <- it:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
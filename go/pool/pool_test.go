@@ -0,0 +1,152 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"example.com/tutorial/metrics"
+)
+
+func square(ctx context.Context, n int) (int, error) {
+	if n < 0 {
+		return 0, errors.New("negative")
+	}
+	// Later jobs finish first, to shuffle the completion order.
+	time.Sleep(time.Duration(10-n%10) * time.Millisecond)
+	return n * n, nil
+}
+
+func TestRunUnordered(t *testing.T) {
+	p := New(square, Config{Workers: 4})
+	jobs := []int{1, 2, 3, -1, 5, 6, 7, 8}
+	var got []int
+	errs := 0
+	for r := range p.Run(context.Background(), Feed(context.Background(), jobs)) {
+		if r.Err != nil {
+			if r.Job != -1 || r.Index != 3 {
+				t.Errorf("error for job %d at %d", r.Job, r.Index)
+			}
+			errs++
+			continue
+		}
+		if r.Value != r.Job*r.Job || jobs[r.Index] != r.Job {
+			t.Errorf("result %+v", r)
+		}
+		got = append(got, r.Value)
+	}
+	sort.Ints(got)
+	if len(got) != 7 || errs != 1 || got[0] != 1 || got[6] != 64 {
+		t.Fatalf("values %v, %d errors", got, errs)
+	}
+
+	stats := p.Stats()
+	jobsRun, errors := 0, 0
+	for i, s := range stats {
+		if s.Worker != i {
+			t.Errorf("stats[%d].Worker = %d", i, s.Worker)
+		}
+		jobsRun += s.Jobs
+		errors += s.Errors
+	}
+	if len(stats) != 4 || jobsRun != 8 || errors != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestRunOrdered(t *testing.T) {
+	p := New(square, Config{Workers: 3, Ordered: true})
+	jobs := make([]int, 40)
+	for i := range jobs {
+		jobs[i] = i
+	}
+	i := 0
+	for r := range p.Run(context.Background(), Feed(context.Background(), jobs)) {
+		if r.Index != i || r.Value != i*i {
+			t.Fatalf("result %d = %+v", i, r)
+		}
+		i++
+	}
+	if i != len(jobs) {
+		t.Fatalf("%d results, want %d", i, len(jobs))
+	}
+}
+
+func TestRunCancel(t *testing.T) {
+	var started atomic.Int32
+	p := New(func(ctx context.Context, n int) (int, error) {
+		started.Add(1)
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}, Config{Workers: 2})
+	ctx, cancel := context.WithCancel(context.Background())
+	jobs := make(chan int) // never closed
+	results := p.Run(ctx, jobs)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	select {
+	case <-drain(results):
+	case <-time.After(time.Second):
+		t.Fatal("results not closed after cancel")
+	}
+	if n := started.Load(); n != 2 {
+		t.Fatalf("%d jobs started with 2 busy workers", n)
+	}
+}
+
+func drain[T any](ch <-chan T) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		for range ch {
+		}
+		close(done)
+	}()
+	return done
+}
+
+func TestDefaultWorkers(t *testing.T) {
+	p := New(square, Config{})
+	if len(p.Stats()) < 1 {
+		t.Fatal("no workers")
+	}
+	for range p.Run(context.Background(), Feed(context.Background(), []int(nil))) {
+		t.Fatal("result for no jobs")
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	p := New(func(_ context.Context, n int) (int, error) {
+		if n%2 == 1 {
+			return 0, errors.New("odd")
+		}
+		return n, nil
+	}, Config{Workers: 2, Metrics: NewMetrics(reg, "test_pool")})
+	for range p.Run(context.Background(), Feed(context.Background(), []int{1, 2, 3, 4, 6})) {
+	}
+	var b strings.Builder
+	reg.WriteText(&b)
+	for _, want := range []string{
+		`test_pool_jobs_total{result="ok"} 3`,
+		`test_pool_jobs_total{result="error"} 2`,
+		"test_pool_busy_workers 0",
+		"test_pool_job_duration_seconds_count 5",
+	} {
+		if !strings.Contains(b.String(), want+"\n") {
+			t.Errorf("missing %s in:\n%s", want, b.String())
+		}
+	}
+}
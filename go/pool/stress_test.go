@@ -0,0 +1,57 @@
+//go:build stress
+
+package pool
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"example.com/tutorial/metrics"
+	"example.com/tutorial/stress"
+)
+
+// TestStress runs an ordered and an unordered pool from many goroutines
+// at once, reading their statistics meanwhile; every fourth run is
+// canceled after its first result.
+func TestStress(t *testing.T) {
+	reg := metrics.NewRegistry()
+	m := NewMetrics(reg, "stress_pool")
+	fn := func(ctx context.Context, n int) (int, error) { return n * n, nil }
+	pools := []*Pool[int, int]{
+		New(fn, Config{Workers: 4, Metrics: m}),
+		New(fn, Config{Workers: 4, Ordered: true, Metrics: m}),
+	}
+	jobs := []int{1, 2, 3, 4, 5, 6, 7, 8}
+	stress.Run(t, stress.Config{}, func(g, i int) {
+		p := pools[(g+i)%2]
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		got := 0
+		for r := range p.Run(ctx, Feed(ctx, jobs)) {
+			if r.Value != r.Job*r.Job {
+				t.Errorf("result %+v", r)
+			}
+			got++
+			if i%4 == 3 {
+				cancel()
+			}
+		}
+		if i%4 != 3 && got != len(jobs) {
+			t.Errorf("%d results for %d jobs", got, len(jobs))
+		}
+		if i%16 == 0 {
+			p.Stats()
+			reg.WriteText(io.Discard)
+		}
+	})
+	total := 0
+	for _, p := range pools {
+		for _, s := range p.Stats() {
+			total += s.Jobs
+		}
+	}
+	if want := stress.Total(t, stress.Config{}) * 3 / 4 * len(jobs); total < want {
+		t.Fatalf("%d jobs run, want at least %d", total, want)
+	}
+}
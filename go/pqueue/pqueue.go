@@ -0,0 +1,117 @@
+// Package pqueue provides Queue, a generic priority queue on
+// container/heap. Push returns a handle to the item it adds, through
+// which the item can later be changed or removed wherever it is in the
+// queue, in O(log n) like Push and Pop.
+package pqueue
+
+import "container/heap"
+
+// Item is an element of a Queue, as Push returns it.
+type Item[T any] struct {
+	value T
+	index int // in the heap, or -1 once popped or removed
+}
+
+// Value returns the item's value.
+func (it *Item[T]) Value() T { return it.value }
+
+// Queued reports whether the item is still in its queue.
+func (it *Item[T]) Queued() bool { return it.index >= 0 }
+
+// Queue is a priority queue of values of type T. Pop returns the value
+// that less orders first: with a less of a < b, the smallest. Values
+// less orders neither way come out in no particular order. The zero
+// value is not usable; create one with New. A Queue is not safe for
+// concurrent use.
+type Queue[T any] struct {
+	h items[T]
+}
+
+// New returns an empty queue ordered by less.
+func New[T any](less func(a, b T) bool) *Queue[T] {
+	return &Queue[T]{h: items[T]{less: less}}
+}
+
+// Len returns the number of items in q.
+func (q *Queue[T]) Len() int { return len(q.h.items) }
+
+// Push adds v to q and returns its item.
+func (q *Queue[T]) Push(v T) *Item[T] {
+	it := &Item[T]{value: v}
+	heap.Push(&q.h, it)
+	return it
+}
+
+// Peek returns the first value, without removing it, and reports
+// whether q had one.
+func (q *Queue[T]) Peek() (T, bool) {
+	if len(q.h.items) == 0 {
+		var zero T
+		return zero, false
+	}
+	return q.h.items[0].value, true
+}
+
+// Pop removes and returns the first value, reporting whether q had one.
+func (q *Queue[T]) Pop() (T, bool) {
+	if len(q.h.items) == 0 {
+		var zero T
+		return zero, false
+	}
+	return heap.Pop(&q.h).(*Item[T]).value, true
+}
+
+// Update replaces the value of it, moving it to its new place in q. It
+// reports false, changing nothing, if it is no longer queued.
+func (q *Queue[T]) Update(it *Item[T], v T) bool {
+	if !q.owns(it) {
+		return false
+	}
+	it.value = v
+	heap.Fix(&q.h, it.index)
+	return true
+}
+
+// Remove takes it out of q wherever it is, reporting false if it was no
+// longer queued.
+func (q *Queue[T]) Remove(it *Item[T]) bool {
+	if !q.owns(it) {
+		return false
+	}
+	heap.Remove(&q.h, it.index)
+	return true
+}
+
+// owns reports whether it is queued in q rather than another queue.
+func (q *Queue[T]) owns(it *Item[T]) bool {
+	return it.index >= 0 && it.index < len(q.h.items) && q.h.items[it.index] == it
+}
+
+// items implements heap.Interface, keeping each item's index current.
+type items[T any] struct {
+	items []*Item[T]
+	less  func(a, b T) bool
+}
+
+func (h *items[T]) Len() int           { return len(h.items) }
+func (h *items[T]) Less(i, j int) bool { return h.less(h.items[i].value, h.items[j].value) }
+
+func (h *items[T]) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.items[i].index, h.items[j].index = i, j
+}
+
+func (h *items[T]) Push(x any) {
+	it := x.(*Item[T])
+	it.index = len(h.items)
+	h.items = append(h.items, it)
+}
+
+func (h *items[T]) Pop() any {
+	n := len(h.items) - 1
+	it := h.items[n]
+	h.items[n] = nil // let the item be collected
+	h.items = h.items[:n]
+	it.index = -1
+	return it
+}
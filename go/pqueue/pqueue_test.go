@@ -0,0 +1,130 @@
+package pqueue
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func intLess(a, b int) bool { return a < b }
+
+// drain pops every value of q in order.
+func drain[T any](q *Queue[T]) []T {
+	out := []T{}
+	for q.Len() > 0 {
+		v, _ := q.Pop()
+		out = append(out, v)
+	}
+	return out
+}
+
+func TestPushPop(t *testing.T) {
+	q := New(intLess)
+	if _, ok := q.Pop(); ok {
+		t.Fatal("Pop on an empty queue")
+	}
+	if _, ok := q.Peek(); ok {
+		t.Fatal("Peek on an empty queue")
+	}
+	r := rand.New(rand.NewSource(1))
+	var want []int
+	for i := 0; i < 200; i++ {
+		v := r.Intn(50)
+		want = append(want, v)
+		q.Push(v)
+	}
+	sort.Ints(want)
+	if v, ok := q.Peek(); !ok || v != want[0] || q.Len() != 200 {
+		t.Fatalf("Peek = %d, %v; Len %d", v, ok, q.Len())
+	}
+	if got := drain(q); !reflect.DeepEqual(got, want) {
+		t.Fatalf("popped %v", got)
+	}
+
+	// A less of greater-than makes a max-queue.
+	q = New(func(a, b int) bool { return a > b })
+	for _, v := range []int{3, 9, 1} {
+		q.Push(v)
+	}
+	if got := drain(q); !reflect.DeepEqual(got, []int{9, 3, 1}) {
+		t.Fatalf("max-queue popped %v", got)
+	}
+}
+
+func TestUpdateRemove(t *testing.T) {
+	type task struct {
+		name     string
+		priority int
+	}
+	q := New(func(a, b task) bool { return a.priority < b.priority })
+	items := map[string]*Item[task]{}
+	for i, name := range []string{"a", "b", "c", "d", "e"} {
+		items[name] = q.Push(task{name, i})
+	}
+	if !q.Update(items["e"], task{"e", -1}) || !q.Update(items["a"], task{"a", 10}) {
+		t.Fatal("Update failed")
+	}
+	if items["e"].Value().priority != -1 {
+		t.Fatalf("Value = %+v", items["e"].Value())
+	}
+	if !q.Remove(items["c"]) || q.Remove(items["c"]) || items["c"].Queued() {
+		t.Fatal("Remove of c")
+	}
+	var names []string
+	for _, tk := range drain(q) {
+		names = append(names, tk.name)
+	}
+	if !reflect.DeepEqual(names, []string{"e", "b", "d", "a"}) {
+		t.Fatalf("popped %q", names)
+	}
+
+	// Popped items and those of other queues are left alone.
+	if items["a"].Queued() || q.Update(items["a"], task{"a", 0}) || q.Remove(items["a"]) {
+		t.Fatal("a popped item was still queued")
+	}
+	other := New(func(a, b task) bool { return a.priority < b.priority })
+	foreign := other.Push(task{"x", 1})
+	q.Push(task{"y", 2})
+	if q.Update(foreign, task{"x", 0}) || q.Remove(foreign) || other.Len() != 1 || q.Len() != 1 {
+		t.Fatal("a queue changed another's item")
+	}
+}
+
+// TestRandomOps checks the queue against a sorted slice through random
+// pushes, updates, removes and pops.
+func TestRandomOps(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	q := New(intLess)
+	var live []*Item[int]
+	for i := 0; i < 2000; i++ {
+		switch op := r.Intn(4); {
+		case op == 0 || len(live) == 0:
+			live = append(live, q.Push(r.Intn(1000)))
+		case op == 1:
+			q.Update(live[r.Intn(len(live))], r.Intn(1000))
+		case op == 2:
+			j := r.Intn(len(live))
+			q.Remove(live[j])
+			live = append(live[:j], live[j+1:]...)
+		default:
+			want := live[0].Value()
+			for _, it := range live {
+				want = min(want, it.Value())
+			}
+			got, _ := q.Pop()
+			if got != want {
+				t.Fatalf("op %d: Pop = %d, want %d", i, got, want)
+			}
+			for j, it := range live {
+				if !it.Queued() {
+					live = append(live[:j], live[j+1:]...)
+					break
+				}
+			}
+		}
+		if q.Len() != len(live) {
+			t.Fatalf("op %d: Len = %d, want %d", i, q.Len(), len(live))
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Package profiling lets a running program be profiled in the field: it
+// serves the net/http/pprof endpoints on a debug address and records a
+// runtime/trace execution trace to a file, both started and stopped
+// together around the work to analyze.
+package profiling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime/trace"
+	"time"
+)
+
+// Options say what to start. The zero value starts nothing.
+type Options struct {
+	// PprofAddr, if set, is the address to serve /debug/pprof/ on. A
+	// port alone, such as ":6060", listens on localhost only, since the
+	// profiles reveal much about the process.
+	PprofAddr string
+	// TraceFile, if set, is the file the execution trace is written to,
+	// for "go tool trace". It is created or truncated.
+	TraceFile string
+}
+
+// Session is running profiling, ended by Stop.
+type Session struct {
+	srv   *http.Server
+	lis   net.Listener
+	trace *os.File
+	done  chan error // the result of srv.Serve
+}
+
+// Start starts what opts ask for. If any of it fails, whatever was
+// started is stopped again and the error returned.
+func Start(opts Options) (*Session, error) {
+	s := &Session{}
+	if opts.TraceFile != "" {
+		f, err := os.Create(opts.TraceFile)
+		if err != nil {
+			return nil, fmt.Errorf("profiling: %w", err)
+		}
+		if err := trace.Start(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("profiling: trace: %w", err)
+		}
+		s.trace = f
+	}
+	if opts.PprofAddr != "" {
+		lis, err := net.Listen("tcp", localhost(opts.PprofAddr))
+		if err != nil {
+			s.Stop(context.Background())
+			return nil, fmt.Errorf("profiling: %w", err)
+		}
+		s.lis = lis
+		s.srv = &http.Server{Handler: Handler(), ReadHeaderTimeout: 10 * time.Second}
+		s.done = make(chan error, 1)
+		go func() { s.done <- s.srv.Serve(lis) }()
+	}
+	return s, nil
+}
+
+// localhost gives addr the host localhost if it has none.
+func localhost(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host != "" {
+		return addr
+	}
+	return net.JoinHostPort("localhost", port)
+}
+
+// Addr returns the address the pprof endpoints are served on, or nil if
+// they are not.
+func (s *Session) Addr() net.Addr {
+	if s.lis == nil {
+		return nil
+	}
+	return s.lis.Addr()
+}
+
+// Stop stops the trace, closing its file, and shuts the pprof server
+// down, waiting until ctx is done for requests in flight, such as a CPU
+// profile being taken, before closing their connections. Stop may be
+// called more than once.
+func (s *Session) Stop(ctx context.Context) error {
+	var errs []error
+	if s.trace != nil {
+		trace.Stop()
+		if err := s.trace.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("profiling: trace: %w", err))
+		}
+		s.trace = nil
+	}
+	if s.srv != nil {
+		if err := s.srv.Shutdown(ctx); err != nil {
+			s.srv.Close()
+			errs = append(errs, fmt.Errorf("profiling: pprof: %w", err))
+		}
+		if err := <-s.done; !errors.Is(err, http.ErrServerClosed) {
+			errs = append(errs, fmt.Errorf("profiling: pprof: %w", err))
+		}
+		s.srv = nil
+	}
+	return errors.Join(errs...)
+}
+
+// Handler returns the net/http/pprof endpoints under /debug/pprof/,
+// without registering them on http.DefaultServeMux.
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
@@ -0,0 +1,74 @@
+package profiling
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStartStop(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "trace.out")
+	s, err := Start(Options{PprofAddr: "127.0.0.1:0", TraceFile: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get("http://" + s.Addr().String() + "/debug/pprof/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "goroutine") {
+		t.Fatalf("index: %d %.100q", resp.StatusCode, body)
+	}
+
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatalf("second Stop: %v", err)
+	}
+	if fi, err := os.Stat(file); err != nil || fi.Size() == 0 {
+		t.Fatalf("trace file: %v, %v", fi, err)
+	}
+	if _, err := http.Get("http://" + s.Addr().String() + "/debug/pprof/"); err == nil {
+		t.Fatal("pprof still served after Stop")
+	}
+}
+
+func TestStartFailureStopsTrace(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "trace.out")
+	if _, err := Start(Options{PprofAddr: "bad address", TraceFile: file}); err == nil {
+		t.Fatal("Start succeeded with a bad address")
+	}
+	// The trace was stopped, so another can start.
+	s, err := Start(Options{TraceFile: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Addr() != nil {
+		t.Errorf("Addr = %v without pprof", s.Addr())
+	}
+	s.Stop(context.Background())
+
+	if _, err := Start(Options{TraceFile: filepath.Join(t.TempDir(), "missing", "trace.out")}); err == nil {
+		t.Fatal("Start succeeded with a trace file it cannot create")
+	}
+}
+
+func TestLocalhost(t *testing.T) {
+	for addr, want := range map[string]string{
+		":6060":          "localhost:6060",
+		"0.0.0.0:6060":   "0.0.0.0:6060",
+		"127.0.0.1:0":    "127.0.0.1:0",
+		"not an address": "not an address",
+	} {
+		if got := localhost(addr); got != want {
+			t.Errorf("localhost(%q) = %q, want %q", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,381 @@
+// Package progress reports how far a long job has got: files processed,
+// bytes read, the rate and, when the totals are known, the time left.
+// Jobs count their work on a Tracker, which the directory walker and the
+// word-counting code feed directly, and a Reporter draws it on a terminal
+// as a bar or a spinner, or writes it as JSON lines for other programs.
+package progress
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/term"
+
+	"example.com/tutorial/humanize"
+)
+
+// Tracker counts the work of a job. It is safe for concurrent use, and a
+// nil *Tracker ignores what it is told, so that code can feed one
+// whether or not anyone is watching.
+type Tracker struct {
+	files, bytes           atomic.Int64
+	totalFiles, totalBytes atomic.Int64
+	start                  time.Time
+	now                    func() time.Time
+}
+
+// NewTracker returns a Tracker whose clock starts now.
+func NewTracker() *Tracker { return newTracker(time.Now) }
+
+func newTracker(now func() time.Time) *Tracker { return &Tracker{start: now(), now: now} }
+
+// AddFiles counts n more files processed.
+func (t *Tracker) AddFiles(n int) {
+	if t != nil {
+		t.files.Add(int64(n))
+	}
+}
+
+// AddBytes counts n more bytes processed.
+func (t *Tracker) AddBytes(n int64) {
+	if t != nil {
+		t.bytes.Add(n)
+	}
+}
+
+// SetTotal sets the files and bytes the job will process when done, so
+// that the fraction done and the time left can be reported. Zero leaves
+// a total unknown.
+func (t *Tracker) SetTotal(files int, bytes int64) {
+	if t != nil {
+		t.totalFiles.Store(int64(files))
+		t.totalBytes.Store(bytes)
+	}
+}
+
+// Reader returns a reader that counts the bytes read from r.
+func (t *Tracker) Reader(r io.Reader) io.Reader {
+	if t == nil {
+		return r
+	}
+	return &reader{r: r, t: t}
+}
+
+type reader struct {
+	r io.Reader
+	t *Tracker
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.t.bytes.Add(int64(n))
+	return n, err
+}
+
+// Stats returns the counts so far.
+func (t *Tracker) Stats() Stats {
+	if t == nil {
+		return Stats{}
+	}
+	return Stats{
+		Files:      t.files.Load(),
+		TotalFiles: t.totalFiles.Load(),
+		Bytes:      t.bytes.Load(),
+		TotalBytes: t.totalBytes.Load(),
+		Elapsed:    t.now().Sub(t.start),
+	}
+}
+
+// Stats is the state of a job at one moment. A zero total is unknown.
+type Stats struct {
+	Files, TotalFiles int64
+	Bytes, TotalBytes int64
+	Elapsed           time.Duration
+}
+
+// Rate returns the bytes processed per second.
+func (s Stats) Rate() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Bytes) / s.Elapsed.Seconds()
+}
+
+// Fraction returns the part of the job done, from 0 to 1, measured in
+// bytes if their total is known and in files otherwise. It reports false
+// if neither total is.
+func (s Stats) Fraction() (float64, bool) {
+	switch {
+	case s.TotalBytes > 0:
+		return min(1, float64(s.Bytes)/float64(s.TotalBytes)), true
+	case s.TotalFiles > 0:
+		return min(1, float64(s.Files)/float64(s.TotalFiles)), true
+	}
+	return 0, false
+}
+
+// ETA estimates the time left at the rate so far. It reports false until
+// some of a job of known size is done.
+func (s Stats) ETA() (time.Duration, bool) {
+	f, ok := s.Fraction()
+	if !ok || f == 0 {
+		return 0, false
+	}
+	return time.Duration(float64(s.Elapsed) * (1 - f) / f), true
+}
+
+// Mode says how a Reporter reports.
+type Mode int
+
+const (
+	// Auto draws on a terminal and reports nothing elsewhere, so that
+	// redirected output is not cluttered.
+	Auto Mode = iota
+	// On draws wherever the output goes.
+	On
+	// Off reports nothing.
+	Off
+	// JSON writes a JSON object per line for each report.
+	JSON
+)
+
+var modeNames = [...]string{Auto: "auto", On: "on", Off: "off", JSON: "json"}
+
+// ErrMode is returned by Mode.UnmarshalText for an unknown mode.
+var ErrMode = errors.New("progress: mode must be auto, on, off or json")
+
+func (m Mode) String() string {
+	if m < 0 || int(m) >= len(modeNames) {
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+	return modeNames[m]
+}
+
+// MarshalText returns the name of m, such as "json".
+func (m Mode) MarshalText() ([]byte, error) { return []byte(m.String()), nil }
+
+// UnmarshalText sets m from its name, so that a Mode can be a flag or a
+// setting.
+func (m *Mode) UnmarshalText(b []byte) error {
+	for i, name := range modeNames {
+		if strings.EqualFold(string(b), name) {
+			*m = Mode(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w, not %q", ErrMode, b)
+}
+
+// DefaultInterval is the time between reports of a Reporter whose
+// Options.Interval is zero.
+const DefaultInterval = 200 * time.Millisecond
+
+// Options configure a Reporter.
+type Options struct {
+	Mode Mode
+	// Interval is the time between reports; zero means DefaultInterval.
+	Interval time.Duration
+	// Label names the job at the start of each line, such as "search".
+	Label string
+}
+
+// Reporter reports a Tracker at intervals until stopped.
+type Reporter struct {
+	w    io.Writer
+	t    *Tracker
+	opts Options
+
+	mu    sync.Mutex
+	drawn bool // a status line is on the screen
+	frame int  // of the spinner
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// Start starts reporting t to w, which for Auto must be a terminal, or a
+// writer around one that has an Unwrap method, for anything to be drawn.
+// Call Stop when the job ends.
+func Start(w io.Writer, t *Tracker, opts Options) *Reporter {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.Mode == Auto {
+		opts.Mode = Off
+		if isTerminal(w) {
+			opts.Mode = On
+		}
+	}
+	r := &Reporter{w: w, t: t, opts: opts, done: make(chan struct{})}
+	if opts.Mode == Off {
+		return r
+	}
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		tick := time.NewTicker(opts.Interval)
+		defer tick.Stop()
+		for {
+			select {
+			case <-r.done:
+				return
+			case <-tick.C:
+				r.report(false)
+			}
+		}
+	}()
+	return r
+}
+
+func isTerminal(w io.Writer) bool {
+	for {
+		switch v := w.(type) {
+		case *os.File:
+			return term.IsTerminal(int(v.Fd()))
+		case interface{ Unwrap() io.Writer }:
+			w = v.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
+// Stop ends the reports. A drawn status line is erased; in JSON mode a
+// last report has "done" set. Stop may be called more than once.
+func (r *Reporter) Stop() {
+	if r.opts.Mode == Off {
+		return
+	}
+	select {
+	case <-r.done:
+		return
+	default:
+	}
+	close(r.done)
+	r.wg.Wait()
+	r.report(true)
+}
+
+// Bypass returns a writer for the job's own output to a writer sharing
+// the screen with r, such as stdout beside stderr: each write first
+// erases the status line, which the next report draws again below it.
+// Whole lines should be written.
+func (r *Reporter) Bypass(w io.Writer) io.Writer {
+	if r.opts.Mode != On {
+		return w
+	}
+	return bypass{r: r, w: w}
+}
+
+type bypass struct {
+	r *Reporter
+	w io.Writer
+}
+
+// Unwrap returns the writer under b, for code that looks for a terminal.
+func (b bypass) Unwrap() io.Writer { return b.w }
+
+func (b bypass) Write(p []byte) (int, error) {
+	b.r.mu.Lock()
+	defer b.r.mu.Unlock()
+	b.r.erase()
+	return b.w.Write(p)
+}
+
+func (r *Reporter) erase() {
+	if r.drawn {
+		io.WriteString(r.w, "\r\x1b[K")
+		r.drawn = false
+	}
+}
+
+func (r *Reporter) report(done bool) {
+	s := r.t.Stats()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.opts.Mode == JSON {
+		line, _ := json.Marshal(toJSON(r.opts.Label, s, done))
+		r.w.Write(append(line, '\n'))
+		return
+	}
+	r.erase()
+	if done {
+		return
+	}
+	io.WriteString(r.w, r.line(s))
+	r.drawn = true
+	r.frame++
+}
+
+const (
+	barWidth = 20
+	spinner  = "⠋⠙⠹⠸⠼⠴⠦⠧⠇⠏"
+)
+
+// line renders s: a bar and the time left for a job of known size, a
+// spinner otherwise.
+func (r *Reporter) line(s Stats) string {
+	var parts []string
+	if r.opts.Label != "" {
+		parts = append(parts, r.opts.Label)
+	}
+	f, sized := s.Fraction()
+	if sized {
+		n := int(f * barWidth)
+		bar := strings.Repeat("=", n)
+		if n < barWidth {
+			bar += ">" + strings.Repeat(" ", barWidth-n-1)
+		}
+		parts = append(parts, "["+bar+"]", fmt.Sprintf("%3.0f%%", f*100))
+	} else {
+		frames := []rune(spinner)
+		parts = append(parts, string(frames[r.frame%len(frames)]))
+	}
+	if s.TotalFiles > 0 {
+		parts = append(parts, fmt.Sprintf("%d/%d files", s.Files, s.TotalFiles))
+	} else {
+		parts = append(parts, fmt.Sprintf("%d files", s.Files))
+	}
+	parts = append(parts, humanize.Bytes(s.Bytes), humanize.ByteRate(s.Bytes, s.Elapsed))
+	if eta, ok := s.ETA(); ok {
+		parts = append(parts, "ETA "+humanize.Duration(eta))
+	}
+	return strings.Join(parts, "  ")
+}
+
+// jsonStats is a report in JSON mode.
+type jsonStats struct {
+	Job         string  `json:"job,omitempty"`
+	Files       int64   `json:"files"`
+	TotalFiles  int64   `json:"total_files,omitempty"`
+	Bytes       int64   `json:"bytes"`
+	TotalBytes  int64   `json:"total_bytes,omitempty"`
+	BytesPerSec float64 `json:"bytes_per_sec"`
+	ElapsedMS   int64   `json:"elapsed_ms"`
+	ETAMS       *int64  `json:"eta_ms,omitempty"`
+	Done        bool    `json:"done"`
+}
+
+func toJSON(label string, s Stats, done bool) jsonStats {
+	j := jsonStats{
+		Job:         label,
+		Files:       s.Files,
+		TotalFiles:  s.TotalFiles,
+		Bytes:       s.Bytes,
+		TotalBytes:  s.TotalBytes,
+		BytesPerSec: s.Rate(),
+		ElapsedMS:   s.Elapsed.Milliseconds(),
+		Done:        done,
+	}
+	if eta, ok := s.ETA(); ok && !done {
+		ms := eta.Milliseconds()
+		j.ETAMS = &ms
+	}
+	return j
+}
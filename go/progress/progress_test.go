@@ -0,0 +1,157 @@
+package progress
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clock is a fake time that tests move by hand.
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+func TestTracker(t *testing.T) {
+	c := &clock{time.Unix(0, 0)}
+	tr := newTracker(c.now)
+	tr.SetTotal(4, 4096)
+	io.Copy(io.Discard, tr.Reader(strings.NewReader(strings.Repeat("x", 1024))))
+	tr.AddFiles(1)
+	c.t = c.t.Add(2 * time.Second)
+
+	s := tr.Stats()
+	want := Stats{Files: 1, TotalFiles: 4, Bytes: 1024, TotalBytes: 4096, Elapsed: 2 * time.Second}
+	if s != want {
+		t.Fatalf("Stats = %+v, want %+v", s, want)
+	}
+	if s.Rate() != 512 {
+		t.Errorf("Rate = %v", s.Rate())
+	}
+	if f, ok := s.Fraction(); !ok || f != 0.25 {
+		t.Errorf("Fraction = %v, %v", f, ok)
+	}
+	if eta, ok := s.ETA(); !ok || eta != 6*time.Second {
+		t.Errorf("ETA = %v, %v", eta, ok)
+	}
+	if _, ok := (Stats{Files: 3}).ETA(); ok {
+		t.Error("ETA without totals")
+	}
+	if f, _ := (Stats{Files: 1, TotalFiles: 2}).Fraction(); f != 0.5 {
+		t.Errorf("Fraction in files = %v", f)
+	}
+
+	// A nil Tracker takes counts and reports none.
+	var none *Tracker
+	none.AddFiles(1)
+	none.AddBytes(1)
+	none.SetTotal(1, 1)
+	r := strings.NewReader("x")
+	if none.Reader(r) != r || none.Stats() != (Stats{}) {
+		t.Fatal("nil Tracker")
+	}
+}
+
+func TestModeText(t *testing.T) {
+	for _, m := range []Mode{Auto, On, Off, JSON} {
+		b, _ := m.MarshalText()
+		var got Mode
+		if err := got.UnmarshalText(b); err != nil || got != m {
+			t.Errorf("%v: round trip gave %v, %v", m, got, err)
+		}
+	}
+	var m Mode
+	if err := m.UnmarshalText([]byte("loud")); !errors.Is(err, ErrMode) {
+		t.Fatalf("loud: %v", err)
+	}
+}
+
+func TestLine(t *testing.T) {
+	r := &Reporter{opts: Options{Label: "job"}}
+	sized := Stats{Files: 1, TotalFiles: 4, Bytes: 1536, TotalBytes: 6144, Elapsed: time.Second}
+	if got, want := r.line(sized), "job  [=====>              ]   25%  1/4 files  1.5 KiB  1.5 KiB/s  ETA 3s"; got != want {
+		t.Errorf("sized:\ngot  %q\nwant %q", got, want)
+	}
+	unsized := Stats{Files: 7, Bytes: 100, Elapsed: time.Second}
+	first := r.line(unsized)
+	if want := "job  ⠋  7 files  100 B  100 B/s"; first != want {
+		t.Errorf("unsized:\ngot  %q\nwant %q", first, want)
+	}
+	r.frame++
+	if r.line(unsized) == first {
+		t.Error("the spinner did not turn")
+	}
+	if got := (&Reporter{}).line(Stats{Files: 2, TotalFiles: 2}); !strings.HasPrefix(got, "[====================]  100%") {
+		t.Errorf("full bar: %q", got)
+	}
+}
+
+func TestReporterDraws(t *testing.T) {
+	var screen, out bytes.Buffer
+	c := &clock{time.Unix(0, 0)}
+	tr := newTracker(c.now)
+	r := Start(&screen, tr, Options{Mode: On, Interval: time.Hour})
+	tr.AddFiles(2)
+	r.report(false)
+	if !strings.Contains(screen.String(), "2 files") {
+		t.Fatalf("drawn %q", screen.String())
+	}
+	io.WriteString(r.Bypass(&out), "result\n")
+	if !strings.HasSuffix(screen.String(), "\r\x1b[K") || out.String() != "result\n" {
+		t.Fatalf("bypass: screen %q, out %q", screen.String(), out.String())
+	}
+	r.report(false)
+	r.Stop()
+	r.Stop()
+	if !strings.HasSuffix(screen.String(), "\r\x1b[K") {
+		t.Fatalf("Stop left %q", screen.String())
+	}
+}
+
+func TestReporterJSON(t *testing.T) {
+	var buf bytes.Buffer
+	c := &clock{time.Unix(0, 0)}
+	tr := newTracker(c.now)
+	tr.SetTotal(2, 0)
+	r := Start(&buf, tr, Options{Mode: JSON, Interval: time.Hour, Label: "count"})
+	tr.AddFiles(1)
+	tr.AddBytes(10)
+	c.t = c.t.Add(time.Second)
+	r.report(false)
+	tr.AddFiles(1)
+	r.Stop()
+	if r.Bypass(&buf) != &buf {
+		t.Error("JSON mode bypasses its writer")
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines: %q", lines)
+	}
+	var first, last map[string]any
+	json.Unmarshal([]byte(lines[0]), &first)
+	json.Unmarshal([]byte(lines[1]), &last)
+	if first["job"] != "count" || first["files"] != 1.0 || first["total_files"] != 2.0 || first["bytes_per_sec"] != 10.0 ||
+		first["eta_ms"] != 1000.0 || first["done"] != false {
+		t.Errorf("first: %s", lines[0])
+	}
+	if last["files"] != 2.0 || last["done"] != true || last["eta_ms"] != nil {
+		t.Errorf("last: %s", lines[1])
+	}
+}
+
+func TestAutoOffWhenPiped(t *testing.T) {
+	var buf bytes.Buffer
+	r := Start(&buf, NewTracker(), Options{Interval: time.Millisecond})
+	time.Sleep(5 * time.Millisecond)
+	r.Stop()
+	if buf.Len() != 0 {
+		t.Fatalf("reported to a buffer: %q", buf.String())
+	}
+	if r.Bypass(&buf) != &buf {
+		t.Fatal("Bypass wrapped a writer with nothing drawn")
+	}
+}
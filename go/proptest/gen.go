@@ -0,0 +1,179 @@
+package proptest
+
+import "math/rand"
+
+// Int generates ints in [lo, hi], shrinking towards lo.
+func Int(lo, hi int) Gen[int] {
+	return Gen[int]{
+		Generate: func(r *rand.Rand, _ int) int { return lo + r.Intn(hi-lo+1) },
+		Shrink: func(v int) []int {
+			if v <= lo {
+				return nil
+			}
+			out := []int{lo}
+			if mid := lo + (v-lo)/2; mid != lo {
+				out = append(out, mid)
+			}
+			if v-1 != out[len(out)-1] {
+				out = append(out, v-1)
+			}
+			return out
+		},
+	}
+}
+
+// Bool generates booleans, shrinking true to false.
+func Bool() Gen[bool] {
+	return Gen[bool]{
+		Generate: func(r *rand.Rand, _ int) bool { return r.Intn(2) == 1 },
+		Shrink: func(v bool) []bool {
+			if v {
+				return []bool{false}
+			}
+			return nil
+		},
+	}
+}
+
+// OneOf generates one of values, shrinking towards the first.
+func OneOf[T comparable](values ...T) Gen[T] {
+	return Gen[T]{
+		Generate: func(r *rand.Rand, _ int) T { return values[r.Intn(len(values))] },
+		Shrink: func(v T) []T {
+			for i, x := range values {
+				if x == v {
+					return values[:i]
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// SliceOf generates slices of up to size elements from g. Slices shrink
+// by dropping runs of elements, halves first, then by shrinking single
+// elements.
+func SliceOf[T any](g Gen[T]) Gen[[]T] {
+	return Gen[[]T]{
+		Generate: func(r *rand.Rand, size int) []T {
+			s := make([]T, r.Intn(size+1))
+			for i := range s {
+				s[i] = g.Generate(r, size)
+			}
+			return s
+		},
+		Shrink: func(s []T) [][]T {
+			var out [][]T
+			for n := len(s); n > 0; n /= 2 {
+				for i := 0; i+n <= len(s); i += n {
+					out = append(out, append(s[:i:i], s[i+n:]...))
+				}
+			}
+			if g.Shrink == nil {
+				return out
+			}
+			for i, v := range s {
+				for _, c := range g.Shrink(v) {
+					t := append([]T(nil), s...)
+					t[i] = c
+					out = append(out, t)
+				}
+			}
+			return out
+		},
+	}
+}
+
+// StringOf generates strings of up to size runes from g, shrinking as
+// SliceOf does.
+func StringOf(g Gen[rune]) Gen[string] {
+	runes := SliceOf(g)
+	return Gen[string]{
+		Generate: func(r *rand.Rand, size int) string { return string(runes.Generate(r, size)) },
+		Shrink: func(s string) []string {
+			cs := runes.Shrink([]rune(s))
+			out := make([]string, len(cs))
+			for i, c := range cs {
+				out[i] = string(c)
+			}
+			return out
+		},
+	}
+}
+
+// Character classes of TextRune, each drawn with its weight.
+var textClasses = []struct {
+	runes  []rune
+	weight int
+}{
+	{[]rune("abcdefghijklmnopqrstuvwxyz"), 50},
+	{[]rune("ABCDEFGHIJKLMNOPQRSTUVWXYZ"), 8},
+	{[]rune("0123456789"), 5},
+	{[]rune(" \n\t"), 20},
+	{[]rune(".,;:!?\"()-'’_/"), 10},
+	{[]rune("éüßñÉİıçø"), 4},
+	{[]rune("東京大阪αβγжя"), 3},
+}
+
+// TextRune generates the runes of Text: mostly lower-case letters, with
+// white space, punctuation, capitals, digits and non-ASCII letters.
+// Runes shrink to 'a'.
+func TextRune() Gen[rune] {
+	total := 0
+	for _, c := range textClasses {
+		total += c.weight
+	}
+	return Gen[rune]{
+		Generate: func(r *rand.Rand, _ int) rune {
+			n := r.Intn(total)
+			for _, c := range textClasses {
+				if n < c.weight {
+					return c.runes[r.Intn(len(c.runes))]
+				}
+				n -= c.weight
+			}
+			panic("unreachable")
+		},
+		Shrink: func(v rune) []rune {
+			if v == 'a' {
+				return nil
+			}
+			return []rune{'a'}
+		},
+	}
+}
+
+// Text generates strings of up to size runes that read like prose in
+// several scripts and exercise a tokenizer: words broken by spaces,
+// newlines and punctuation, capitals, digits and accents.
+func Text() Gen[string] { return StringOf(TextRune()) }
+
+// Pair holds two values, as PairOf generates them.
+type Pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+// PairOf generates pairs from ga and gb, shrinking the first value, then
+// the second.
+func PairOf[A, B any](ga Gen[A], gb Gen[B]) Gen[Pair[A, B]] {
+	return Gen[Pair[A, B]]{
+		Generate: func(r *rand.Rand, size int) Pair[A, B] {
+			return Pair[A, B]{ga.Generate(r, size), gb.Generate(r, size)}
+		},
+		Shrink: func(p Pair[A, B]) []Pair[A, B] {
+			var out []Pair[A, B]
+			if ga.Shrink != nil {
+				for _, a := range ga.Shrink(p.First) {
+					out = append(out, Pair[A, B]{a, p.Second})
+				}
+			}
+			if gb.Shrink != nil {
+				for _, b := range gb.Shrink(p.Second) {
+					out = append(out, Pair[A, B]{p.First, b})
+				}
+			}
+			return out
+		},
+	}
+}
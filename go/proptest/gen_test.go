@@ -0,0 +1,78 @@
+package proptest
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestIntShrink(t *testing.T) {
+	g := Int(2, 100)
+	if got := g.Shrink(10); !reflect.DeepEqual(got, []int{2, 6, 9}) {
+		t.Errorf("Shrink(10) = %v", got)
+	}
+	if got := g.Shrink(3); !reflect.DeepEqual(got, []int{2}) {
+		t.Errorf("Shrink(3) = %v", got)
+	}
+	if got := g.Shrink(2); got != nil {
+		t.Errorf("Shrink(2) = %v", got)
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		if v := g.Generate(r, 0); v < 2 || v > 100 {
+			t.Fatalf("generated %d", v)
+		}
+	}
+}
+
+func TestOneOfAndBool(t *testing.T) {
+	g := OneOf("a", "b", "c")
+	if got := g.Shrink("c"); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("Shrink(c) = %v", got)
+	}
+	if got := Bool().Shrink(true); !reflect.DeepEqual(got, []bool{false}) {
+		t.Errorf("Shrink(true) = %v", got)
+	}
+}
+
+func TestSliceOfShrink(t *testing.T) {
+	got := SliceOf(Bool()).Shrink([]bool{true, false, true})
+	want := [][]bool{
+		{},                                         // all three dropped
+		{false, true}, {true, true}, {true, false}, // one dropped at each position
+		{false, false, true}, {true, false, false}, // one shrunk
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Shrink = %v\nwant     %v", got, want)
+	}
+}
+
+func TestTextGenerate(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	g := Text()
+	seen := map[bool]bool{}
+	for i := 0; i < 200; i++ {
+		s := g.Generate(r, 40)
+		if !utf8.ValidString(s) || utf8.RuneCountInString(s) > 40 {
+			t.Fatalf("generated %q", s)
+		}
+		for _, c := range s {
+			seen[c >= utf8.RuneSelf] = true
+		}
+	}
+	if !seen[true] || !seen[false] {
+		t.Error("text lacks ASCII or non-ASCII runes")
+	}
+	if got := g.Shrink("ab"); !reflect.DeepEqual(got, []string{"", "b", "a", "aa"}) {
+		t.Errorf("Shrink(ab) = %q", got)
+	}
+}
+
+func TestPairOfShrink(t *testing.T) {
+	got := PairOf(Int(0, 9), Bool()).Shrink(Pair[int, bool]{2, true})
+	want := []Pair[int, bool]{{0, true}, {1, true}, {2, false}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Shrink = %v", got)
+	}
+}
@@ -0,0 +1,167 @@
+// Package proptest checks properties of functions against random inputs.
+// A Gen produces the inputs from a seeded source, so that a run can be
+// repeated, and proposes smaller variants of an input that breaks the
+// property; Check shrinks a failing input through them before reporting
+// the smallest one it found and the seed to reproduce it:
+//
+//	proptest.Check(t, proptest.Text(), func(s string) error {
+//		f := strings.Fields(s)
+//		if g := strings.Fields(strings.Join(f, " ")); !slices.Equal(f, g) {
+//			return fmt.Errorf("fields %q, then %q", f, g)
+//		}
+//		return nil
+//	}, proptest.Config{})
+package proptest
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Defaults of Config.
+const (
+	DefaultRuns       = 200
+	DefaultMaxSize    = 100
+	DefaultMaxShrinks = 1000
+)
+
+// SeedEnv names the environment variable that fixes the seed of every
+// Check with a zero Config.Seed, to replay a reported failure.
+const SeedEnv = "PROPTEST_SEED"
+
+// Gen generates random values of a type and shrinks them.
+type Gen[T any] struct {
+	// Generate returns a value of about size, a count of elements for
+	// collections, drawn from r.
+	Generate func(r *rand.Rand, size int) T
+	// Shrink returns values smaller than v, the most aggressive first.
+	// Nil means the values are not shrunk.
+	Shrink func(v T) []T
+}
+
+// Config sets how Check explores the inputs. The zero value is ready to
+// use.
+type Config struct {
+	// Runs is the number of inputs tried; zero means DefaultRuns.
+	Runs int
+	// Seed seeds the inputs; zero means $PROPTEST_SEED, or the time if
+	// that is not set.
+	Seed int64
+	// MaxSize is the size of the last inputs; the sizes grow to it from
+	// zero over the runs. Zero means DefaultMaxSize.
+	MaxSize int
+	// MaxShrinks bounds the calls to the property made while shrinking
+	// a failing input; zero means DefaultMaxShrinks.
+	MaxShrinks int
+}
+
+func (c Config) withDefaults() (Config, error) {
+	if c.Runs <= 0 {
+		c.Runs = DefaultRuns
+	}
+	if c.MaxSize <= 0 {
+		c.MaxSize = DefaultMaxSize
+	}
+	if c.MaxShrinks <= 0 {
+		c.MaxShrinks = DefaultMaxShrinks
+	}
+	if c.Seed == 0 {
+		if env := os.Getenv(SeedEnv); env != "" {
+			seed, err := strconv.ParseInt(env, 10, 64)
+			if err != nil {
+				return c, fmt.Errorf("proptest: bad $%s: %w", SeedEnv, err)
+			}
+			c.Seed = seed
+		} else {
+			c.Seed = time.Now().UnixNano()
+		}
+	}
+	return c, nil
+}
+
+// Failure describes an input that broke a property.
+type Failure[T any] struct {
+	// Seed reproduces the run.
+	Seed int64
+	// Run is the number of the failing input, from 1.
+	Run int
+	// Input is the shrunk input and Original the one generated.
+	Input, Original T
+	// Shrinks is the number of times the input was made smaller.
+	Shrinks int
+	// Err is what the property returned for Input.
+	Err error
+}
+
+func (f *Failure[T]) Error() string {
+	return fmt.Sprintf("proptest: property failed on run %d (seed %d; rerun with %s=%d)\ninput:    %#v\noriginal: %#v (%d shrinks)\nerror: %v",
+		f.Run, f.Seed, SeedEnv, f.Seed, f.Input, f.Original, f.Shrinks, f.Err)
+}
+
+// Check tries prop on cfg.Runs inputs from g and fails t with the
+// smallest failing input it finds. A property fails by returning an
+// error or panicking.
+func Check[T any](t testing.TB, g Gen[T], prop func(T) error, cfg Config) {
+	t.Helper()
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := run(g, prop, cfg); f != nil {
+		t.Fatal(f)
+	}
+}
+
+// run is Check without the testing.TB, returning the failure if any.
+func run[T any](g Gen[T], prop func(T) error, cfg Config) *Failure[T] {
+	r := rand.New(rand.NewSource(cfg.Seed))
+	for i := 0; i < cfg.Runs; i++ {
+		size := i * cfg.MaxSize / max(cfg.Runs-1, 1)
+		v := g.Generate(r, size)
+		if err := call(prop, v); err != nil {
+			f := &Failure[T]{Seed: cfg.Seed, Run: i + 1, Input: v, Original: v, Err: err}
+			shrink(g, prop, cfg.MaxShrinks, f)
+			return f
+		}
+	}
+	return nil
+}
+
+// shrink replaces f.Input with the first of its shrinks that still
+// fails, as long as there is one and the budget of calls lasts.
+func shrink[T any](g Gen[T], prop func(T) error, budget int, f *Failure[T]) {
+	if g.Shrink == nil {
+		return
+	}
+	for {
+		smaller := false
+		for _, c := range g.Shrink(f.Input) {
+			if budget == 0 {
+				return
+			}
+			budget--
+			if err := call(prop, c); err != nil {
+				f.Input, f.Err, smaller = c, err, true
+				f.Shrinks++
+				break
+			}
+		}
+		if !smaller {
+			return
+		}
+	}
+}
+
+// call runs prop on v, turning a panic into an error.
+func call[T any](prop func(T) error, v T) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return prop(v)
+}
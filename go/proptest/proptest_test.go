@@ -0,0 +1,86 @@
+package proptest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckPasses(t *testing.T) {
+	calls := 0
+	Check(t, Text(), func(s string) error {
+		calls++
+		if strings.Count(s, "") != len([]rune(s))+1 {
+			return errors.New("miscounted")
+		}
+		return nil
+	}, Config{Runs: 50, Seed: 1})
+	if calls != 50 {
+		t.Fatalf("property called %d times, want 50", calls)
+	}
+}
+
+func TestRunShrinks(t *testing.T) {
+	// Fails on any string with a digit; the smallest such is one digit.
+	prop := func(s string) error {
+		if strings.ContainsAny(s, "0123456789") {
+			return errors.New("has a digit")
+		}
+		return nil
+	}
+	f := run(Text(), prop, Config{Runs: 500, MaxSize: 100, MaxShrinks: 10000, Seed: 7})
+	if f == nil {
+		t.Fatal("property held")
+	}
+	if len([]rune(f.Input)) != 1 || !strings.ContainsAny(f.Input, "0123456789") || f.Shrinks == 0 {
+		t.Fatalf("shrunk %q to %q in %d steps", f.Original, f.Input, f.Shrinks)
+	}
+	if !strings.Contains(f.Error(), "PROPTEST_SEED=7") {
+		t.Errorf("error does not say how to rerun: %s", f)
+	}
+
+	// The same seed finds the same input.
+	if g := run(Text(), prop, Config{Runs: 500, MaxSize: 100, MaxShrinks: 10000, Seed: 7}); g.Original != f.Original || g.Run != f.Run {
+		t.Fatalf("seed 7 gave run %d %q, then run %d %q", f.Run, f.Original, g.Run, g.Original)
+	}
+}
+
+func TestRunPanics(t *testing.T) {
+	f := run(Int(0, 1000), func(n int) error {
+		if n >= 10 {
+			panic("too big")
+		}
+		return nil
+	}, Config{Runs: 100, Seed: 3, MaxShrinks: 100})
+	if f == nil || f.Input != 10 || f.Err.Error() != "panic: too big" {
+		t.Fatalf("failure %+v", f)
+	}
+}
+
+func TestRunShrinkBudget(t *testing.T) {
+	calls := 0
+	f := run(SliceOf(Int(0, 9)), func(s []int) error {
+		calls++
+		if len(s) > 0 {
+			return errors.New("not empty")
+		}
+		return nil
+	}, Config{Runs: 10, Seed: 5, MaxSize: 100, MaxShrinks: 1})
+	if f == nil || calls != f.Run+1 {
+		t.Fatalf("%d calls for %+v, want one shrink", calls, f)
+	}
+}
+
+func TestConfigSeedEnv(t *testing.T) {
+	t.Setenv(SeedEnv, "42")
+	if c, err := (Config{}).withDefaults(); err != nil || c.Seed != 42 || c.Runs != DefaultRuns {
+		t.Fatalf("%+v, %v", c, err)
+	}
+	if c, _ := (Config{Seed: 9}).withDefaults(); c.Seed != 9 {
+		t.Fatalf("explicit seed replaced: %d", c.Seed)
+	}
+	t.Setenv(SeedEnv, "soon")
+	if _, err := (Config{}).withDefaults(); err == nil {
+		t.Fatal("bad seed accepted")
+	}
+}
@@ -0,0 +1,223 @@
+// Package proxy is a small reverse proxy for a single upstream, built on
+// httputil.ReverseProxy. It strips a path prefix before forwarding, sets
+// the X-Forwarded-* headers, and stops forwarding while periodic health
+// probes of the upstream fail or, optionally, a circuit breaker has seen
+// too many of the forwarded requests fail.
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"example.com/tutorial/breaker"
+	"example.com/tutorial/logging"
+)
+
+// Defaults used for zero Config fields.
+const (
+	DefaultHealthPath     = "/healthz"
+	DefaultHealthInterval = 5 * time.Second
+	DefaultHealthTimeout  = 2 * time.Second
+)
+
+// Config tunes a Proxy.
+type Config struct {
+	// StripPrefix is removed from the request path before forwarding;
+	// requests outside it get 404. "/api" forwards /api/users as
+	// /users.
+	StripPrefix string
+	// HealthPath is probed on the upstream by CheckHealth; any 2xx
+	// response counts as healthy.
+	HealthPath     string
+	HealthInterval time.Duration // between probes in Watch
+	HealthTimeout  time.Duration // per probe
+	// Client sends the probes; nil means http.DefaultClient.
+	Client *http.Client
+	// Transport forwards requests; nil means http.DefaultTransport.
+	Transport http.RoundTripper
+	// Breaker, if set, guards the forwarded requests: transport errors
+	// and 5xx responses count as failures, and while it is open requests
+	// get 503 without reaching the upstream.
+	Breaker *breaker.Breaker
+	// Logger reports upstream errors and health changes; nil means
+	// slog.Default(). Its records have component "proxy".
+	Logger *slog.Logger
+}
+
+// Proxy forwards requests to one upstream. It is an http.Handler and is
+// safe for concurrent use.
+type Proxy struct {
+	target  *url.URL
+	cfg     Config
+	rp      *httputil.ReverseProxy
+	healthy atomic.Bool
+}
+
+// New returns a Proxy forwarding to target, which is considered healthy
+// until a probe says otherwise.
+func New(target *url.URL, cfg Config) *Proxy {
+	if cfg.HealthPath == "" {
+		cfg.HealthPath = DefaultHealthPath
+	}
+	if cfg.HealthInterval <= 0 {
+		cfg.HealthInterval = DefaultHealthInterval
+	}
+	if cfg.HealthTimeout <= 0 {
+		cfg.HealthTimeout = DefaultHealthTimeout
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	cfg.Logger = logging.Component(cfg.Logger, "proxy")
+	cfg.StripPrefix = strings.TrimSuffix(cfg.StripPrefix, "/")
+	p := &Proxy{target: target, cfg: cfg}
+	p.healthy.Store(true)
+	transport := cfg.Transport
+	if cfg.Breaker != nil {
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		transport = &breakerTransport{b: cfg.Breaker, rt: transport}
+	}
+	p.rp = &httputil.ReverseProxy{
+		Rewrite:   p.rewrite,
+		Transport: transport,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if errors.Is(err, breaker.ErrOpen) {
+				writeError(w, http.StatusServiceUnavailable, "upstream unavailable")
+				return
+			}
+			cfg.Logger.WarnContext(r.Context(), "upstream error", "url", r.URL.String(), "err", err)
+			writeError(w, http.StatusBadGateway, "bad gateway")
+		},
+	}
+	return p
+}
+
+// breakerTransport sends requests through rt while b allows it.
+type breakerTransport struct {
+	b  *breaker.Breaker
+	rt http.RoundTripper
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	done, err := t.b.Allow()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.rt.RoundTrip(req)
+	// A client giving up says nothing about the upstream.
+	done(req.Context().Err() == nil && (err != nil || resp.StatusCode >= 500))
+	return resp, err
+}
+
+// ServeHTTP forwards r to the upstream, or answers 503 while it is
+// unhealthy.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := p.strip(r.URL.Path); !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if !p.Healthy() {
+		writeError(w, http.StatusServiceUnavailable, "upstream unavailable")
+		return
+	}
+	p.rp.ServeHTTP(w, r)
+}
+
+// rewrite turns the incoming request into the upstream one. The client's
+// X-Forwarded-For chain is kept and its address appended, so the proxy
+// should only face clients, or proxies, that can be trusted with it.
+func (p *Proxy) rewrite(pr *httputil.ProxyRequest) {
+	pr.Out.URL.Path, _ = p.strip(pr.In.URL.Path)
+	pr.Out.URL.RawPath = ""
+	if raw := pr.In.URL.RawPath; raw != "" {
+		pr.Out.URL.RawPath, _ = p.strip(raw)
+	}
+	pr.SetURL(p.target)
+	if xff := pr.In.Header["X-Forwarded-For"]; xff != nil {
+		pr.Out.Header["X-Forwarded-For"] = xff
+	}
+	pr.SetXForwarded()
+}
+
+// strip removes the configured prefix from path, reporting false if path
+// is outside it.
+func (p *Proxy) strip(path string) (string, bool) {
+	if p.cfg.StripPrefix == "" {
+		return path, true
+	}
+	rest, ok := strings.CutPrefix(path, p.cfg.StripPrefix)
+	if !ok || (rest != "" && rest[0] != '/') {
+		return "", false
+	}
+	if rest == "" {
+		rest = "/"
+	}
+	return rest, true
+}
+
+// Healthy reports the result of the last health probe.
+func (p *Proxy) Healthy() bool { return p.healthy.Load() }
+
+// CheckHealth probes the upstream once and records the result.
+func (p *Proxy) CheckHealth(ctx context.Context) error {
+	err := p.probe(ctx)
+	if was := p.healthy.Swap(err == nil); was != (err == nil) {
+		if err != nil {
+			p.cfg.Logger.Warn("upstream unhealthy", "target", p.target.String(), "err", err)
+		} else {
+			p.cfg.Logger.Info("upstream healthy", "target", p.target.String())
+		}
+	}
+	return err
+}
+
+func (p *Proxy) probe(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.HealthTimeout)
+	defer cancel()
+	u := p.target.JoinPath(p.cfg.HealthPath)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("proxy: health check: %s", resp.Status)
+	}
+	return nil
+}
+
+// Watch probes the upstream immediately and then every HealthInterval
+// until ctx is done.
+func (p *Proxy) Watch(ctx context.Context) {
+	t := time.NewTicker(p.cfg.HealthInterval)
+	defer t.Stop()
+	for {
+		p.CheckHealth(ctx)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
@@ -0,0 +1,143 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"example.com/tutorial/breaker"
+)
+
+// upstream echoes the path and forwarding headers it received, and fails
+// its health check while down is set.
+func upstream(t *testing.T, down *atomic.Bool) *url.URL {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" {
+			if down.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+			return
+		}
+		io.WriteString(w, strings.Join([]string{
+			r.URL.EscapedPath(),
+			r.Header.Get("X-Forwarded-For"),
+			r.Header.Get("X-Forwarded-Host"),
+		}, " "))
+	}))
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	return u
+}
+
+var quiet = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func TestProxyRewrite(t *testing.T) {
+	var down atomic.Bool
+	p := New(upstream(t, &down), Config{StripPrefix: "/api/", Logger: quiet})
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/api/users", 200, "/users 10.0.0.1, 192.0.2.1 front.example"},
+		{"/api", 200, "/ 10.0.0.1, 192.0.2.1 front.example"},
+		{"/api/a%2Fb", 200, "/a%2Fb 10.0.0.1, 192.0.2.1 front.example"},
+		{"/apix", 404, ""},
+		{"/users", 404, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "http://front.example"+tt.path, nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		req.Header.Set("X-Forwarded-For", "10.0.0.1")
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, req)
+		if rec.Code != tt.code || (tt.body != "" && rec.Body.String() != tt.body) {
+			t.Errorf("GET %s = %d %q, want %d %q", tt.path, rec.Code, rec.Body, tt.code, tt.body)
+		}
+	}
+}
+
+func TestProxyHealth(t *testing.T) {
+	var down atomic.Bool
+	p := New(upstream(t, &down), Config{Logger: quiet})
+	get := func() int {
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, httptest.NewRequest("GET", "/x", nil))
+		return rec.Code
+	}
+	if err := p.CheckHealth(context.Background()); err != nil || !p.Healthy() || get() != http.StatusOK {
+		t.Fatalf("healthy upstream: %v", err)
+	}
+	down.Store(true)
+	if err := p.CheckHealth(context.Background()); err == nil || p.Healthy() {
+		t.Fatal("failing probe left proxy healthy")
+	}
+	if code := get(); code != http.StatusServiceUnavailable {
+		t.Fatalf("unhealthy upstream: status %d", code)
+	}
+	down.Store(false)
+	p.CheckHealth(context.Background())
+	if code := get(); code != http.StatusOK {
+		t.Fatalf("recovered upstream: status %d", code)
+	}
+}
+
+func TestProxyUpstreamDown(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	u, _ := url.Parse(srv.URL)
+	srv.Close()
+	p := New(u, Config{Logger: quiet})
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest("GET", "/x", nil))
+	if rec.Code != http.StatusBadGateway || !strings.Contains(rec.Body.String(), `"error"`) {
+		t.Fatalf("status %d %q", rec.Code, rec.Body)
+	}
+	if p.CheckHealth(context.Background()) == nil {
+		t.Fatal("probe of a closed server succeeded")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	var down atomic.Bool
+	down.Store(true)
+	p := New(upstream(t, &down), Config{Logger: quiet})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p.Watch(ctx) // probes once, then returns
+	if p.Healthy() {
+		t.Fatal("Watch did not probe")
+	}
+}
+
+func TestProxyBreaker(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	b := breaker.New(breaker.Config{MinCalls: 2, OpenTimeout: time.Hour})
+	p := New(u, Config{Breaker: b, Logger: quiet})
+
+	for i, want := range []int{500, 500, 503, 503} {
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, httptest.NewRequest("GET", "/x", nil))
+		if rec.Code != want {
+			t.Fatalf("request %d = %d, want %d", i, rec.Code, want)
+		}
+	}
+	if n := hits.Load(); n != 2 {
+		t.Fatalf("upstream hit %d times, want 2 before the breaker opened", n)
+	}
+	if b.State() != breaker.Open {
+		t.Fatalf("breaker %v", b.State())
+	}
+}
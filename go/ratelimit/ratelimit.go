@@ -0,0 +1,208 @@
+// Package ratelimit provides a token-bucket rate limiter. It knows
+// nothing of HTTP: the server's rate-limit middleware keeps one Limiter
+// per client, and batch jobs use one to pace their work.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"example.com/tutorial/clock"
+)
+
+// ErrDeadline is returned by Wait when the context's deadline would pass
+// before a token is available, without waiting for it to do so.
+var ErrDeadline = errors.New("ratelimit: wait would exceed context deadline")
+
+// Inf is a rate that places no limit.
+const Inf = math.MaxFloat64
+
+// Limiter is a token bucket holding up to burst tokens and refilling at
+// rate tokens per second; every event takes one. Tokens can be taken in
+// advance with Reserve, leaving the bucket in debt. A Limiter is safe
+// for concurrent use and its rate and burst may be changed at any time.
+type Limiter struct {
+	clock clock.Clock
+
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time // when tokens was last brought up to date
+}
+
+// NewLimiter returns a full Limiter. rate must be positive and burst at
+// least 1.
+func NewLimiter(rate float64, burst int) *Limiter {
+	return NewLimiterClock(rate, burst, clock.System)
+}
+
+// NewLimiterClock is NewLimiter with the time told, and waited for, by
+// c. A context's deadline is still measured against the real time.
+func NewLimiterClock(rate float64, burst int, c clock.Clock) *Limiter {
+	check(rate, burst)
+	return &Limiter{clock: clock.OrSystem(c), rate: rate, burst: float64(burst), tokens: float64(burst)}
+}
+
+func check(rate float64, burst int) {
+	if rate <= 0 || burst < 1 {
+		panic("ratelimit: need a positive rate and burst")
+	}
+}
+
+// Rate returns the refill rate in tokens per second.
+func (l *Limiter) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// Burst returns the capacity of the bucket.
+func (l *Limiter) Burst() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.burst)
+}
+
+// SetRate changes the refill rate. Tokens accrued so far are kept, but
+// the delays of reservations already made are not recomputed.
+func (l *Limiter) SetRate(rate float64) {
+	check(rate, 1)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.advance(l.clock.Now())
+	l.rate = rate
+}
+
+// SetBurst changes the capacity of the bucket, dropping any tokens above
+// it.
+func (l *Limiter) SetBurst(burst int) {
+	check(1, burst)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.advance(l.clock.Now())
+	l.burst = float64(burst)
+	l.tokens = math.Min(l.tokens, l.burst)
+}
+
+// Tokens returns how many tokens are available now; it is negative while
+// the bucket is in debt to reservations.
+func (l *Limiter) Tokens() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.advance(l.clock.Now())
+	return l.tokens
+}
+
+// advance refills the bucket for the time since it was last updated.
+func (l *Limiter) advance(now time.Time) {
+	if !l.last.IsZero() && now.After(l.last) {
+		if l.rate == Inf {
+			l.tokens = l.burst
+		} else {
+			l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+		}
+	}
+	if now.After(l.last) {
+		l.last = now
+	}
+}
+
+// Allow takes a token if one is available now and reports whether it
+// did.
+func (l *Limiter) Allow() bool {
+	return l.AllowAt(l.clock.Now())
+}
+
+// AllowAt is Allow at time now, for callers keeping their own clock.
+func (l *Limiter) AllowAt(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.advance(now)
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// Reservation is a token taken in advance, to be used after Delay.
+type Reservation struct {
+	lim   *Limiter
+	delay time.Duration
+
+	mu        sync.Mutex
+	cancelled bool
+}
+
+// Delay returns how long after the reservation was made the event may
+// happen; zero means straight away.
+func (r *Reservation) Delay() time.Duration {
+	return r.delay
+}
+
+// Cancel gives the token back, for an event that will not happen after
+// all. Cancelling twice has no further effect.
+func (r *Reservation) Cancel() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancelled {
+		return
+	}
+	r.cancelled = true
+	// Refilling before or after giving the token back comes to the same,
+	// so the bucket need not be brought up to date, which keeps Cancel
+	// right for callers of ReserveAt with their own clock.
+	l := r.lim
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = math.Min(l.burst, l.tokens+1)
+}
+
+// Reserve takes a token whether or not one is available, and returns
+// how long to wait before acting on it.
+func (l *Limiter) Reserve() *Reservation {
+	return l.ReserveAt(l.clock.Now())
+}
+
+// ReserveAt is Reserve at time now, for callers keeping their own clock.
+func (l *Limiter) ReserveAt(now time.Time) *Reservation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.advance(now)
+	l.tokens--
+	r := &Reservation{lim: l}
+	if l.tokens < 0 && l.rate != Inf {
+		r.delay = time.Duration(math.Ceil(-l.tokens / l.rate * float64(time.Second)))
+	}
+	return r
+}
+
+// Wait blocks until a token is available and takes it. It returns ctx's
+// error if ctx is done first, and ErrDeadline straight away if ctx's
+// deadline is too soon; either way no token is taken.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r := l.Reserve()
+	if r.delay == 0 {
+		return nil
+	}
+	if dl, ok := ctx.Deadline(); ok && time.Until(dl) < r.delay {
+		r.Cancel()
+		return ErrDeadline
+	}
+	t := l.clock.NewTimer(r.delay)
+	defer t.Stop()
+	select {
+	case <-t.C():
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ctx.Err()
+	}
+}
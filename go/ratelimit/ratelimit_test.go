@@ -0,0 +1,180 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"example.com/tutorial/clock"
+)
+
+// fakeClock returns a Limiter whose time only moves when told to.
+func fakeClock(rate float64, burst int) (*Limiter, *clock.Fake) {
+	c := clock.NewFake(time.Unix(1000, 0))
+	return NewLimiterClock(rate, burst, c), c
+}
+
+func TestAllowBurstAndRefill(t *testing.T) {
+	l, c := fakeClock(2, 3)
+	for i := 0; i < 3; i++ {
+		if !l.Allow() {
+			t.Fatalf("event %d within burst rejected", i)
+		}
+	}
+	if l.Allow() {
+		t.Fatal("event over burst allowed")
+	}
+	c.Advance(500 * time.Millisecond)
+	if !l.Allow() || l.Allow() {
+		t.Fatal("want exactly one token after 500ms at 2/s")
+	}
+	c.Advance(time.Hour)
+	if got := l.Tokens(); got != 3 {
+		t.Fatalf("Tokens = %v after an hour, want the burst of 3", got)
+	}
+}
+
+func TestReserve(t *testing.T) {
+	l, c := fakeClock(4, 1)
+	if d := l.Reserve().Delay(); d != 0 {
+		t.Fatalf("first Delay = %v", d)
+	}
+	r1 := l.Reserve()
+	r2 := l.Reserve()
+	if r1.Delay() != 250*time.Millisecond || r2.Delay() != 500*time.Millisecond {
+		t.Fatalf("delays %v, %v; want 250ms, 500ms", r1.Delay(), r2.Delay())
+	}
+	if got := l.Tokens(); got != -2 {
+		t.Fatalf("Tokens = %v, want -2", got)
+	}
+	r2.Cancel()
+	r2.Cancel()
+	if got := l.Tokens(); got != -1 {
+		t.Fatalf("Tokens after Cancel = %v, want -1", got)
+	}
+	c.Advance(250 * time.Millisecond)
+	if l.Allow() {
+		t.Fatal("allowed while the bucket is paying for r1")
+	}
+	c.Advance(250 * time.Millisecond)
+	if !l.Allow() {
+		t.Fatal("token not refilled after the debt")
+	}
+}
+
+func TestSetRateAndBurst(t *testing.T) {
+	l, c := fakeClock(1, 2)
+	l.Allow()
+	l.Allow()
+	c.Advance(time.Second) // one token at the old rate
+	l.SetRate(10)
+	if l.Rate() != 10 {
+		t.Fatalf("Rate = %v", l.Rate())
+	}
+	c.Advance(100 * time.Millisecond) // and one at the new
+	if got := l.Tokens(); got != 2 {
+		t.Fatalf("Tokens = %v, want 2", got)
+	}
+	l.SetBurst(1)
+	if l.Burst() != 1 || l.Tokens() != 1 {
+		t.Fatalf("after SetBurst(1): burst %d, tokens %v", l.Burst(), l.Tokens())
+	}
+	l.SetRate(Inf)
+	l.Allow()
+	if d := l.Reserve().Delay(); d != 0 {
+		t.Fatalf("Delay at Inf = %v", d)
+	}
+}
+
+func TestWait(t *testing.T) {
+	l, c := fakeClock(100, 1)
+	ctx := context.Background()
+	done := make(chan error)
+	go func() {
+		for i := 0; i < 3; i++ {
+			if err := l.Wait(ctx); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	// One from the burst, then two at 10ms each.
+	c.BlockUntil(1)
+	c.Advance(10 * time.Millisecond)
+	c.BlockUntil(1)
+	c.Advance(10 * time.Millisecond)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitSystemClock(t *testing.T) {
+	l := NewLimiter(1000, 1)
+	l.Allow()
+	start := time.Now()
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 500*time.Microsecond {
+		t.Fatalf("waited %v, want about 1ms", d)
+	}
+}
+
+func TestWaitDeadline(t *testing.T) {
+	l, _ := fakeClock(1, 1)
+	l.Allow()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); !errors.Is(err, ErrDeadline) {
+		t.Fatalf("err = %v, want ErrDeadline", err)
+	}
+	if got := l.Tokens(); got != 0 {
+		t.Fatalf("Tokens = %v, want the reservation given back", got)
+	}
+}
+
+func TestWaitCancel(t *testing.T) {
+	l, c := fakeClock(0.001, 1)
+	l.Allow()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- l.Wait(ctx) }()
+	c.BlockUntil(1)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if got := l.Tokens(); got != 0 {
+		t.Fatalf("Tokens = %v, want 0", got)
+	}
+	if err := l.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Wait on a done context = %v", err)
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	l := NewLimiter(1e-9, 100)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if l.Allow() {
+					mu.Lock()
+					allowed++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 100 {
+		t.Fatalf("allowed %d events, want exactly the burst of 100", allowed)
+	}
+}
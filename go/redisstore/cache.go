@@ -0,0 +1,81 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"example.com/tutorial/cache"
+	"example.com/tutorial/metrics"
+)
+
+// Cache is a cache.Store of byte values in Redis, under keys starting
+// with a prefix. As a cache it never fails: a command that does, as when
+// the server is down, counts as a miss or is dropped, and is counted in
+// Errors. Entries expire after the TTL; Redis evicts others under its
+// own maxmemory policy.
+type Cache struct {
+	client redis.UniversalClient
+	prefix string
+	ttl    time.Duration
+
+	hits, misses, errs atomic.Uint64
+}
+
+var _ cache.Store[string, []byte] = (*Cache)(nil)
+
+// NewCache returns a cache in client whose keys start with prefix, such
+// as "tutorial:wordcount:", and whose entries live ttl, or until evicted
+// if ttl is zero.
+func NewCache(client redis.UniversalClient, prefix string, ttl time.Duration) *Cache {
+	return &Cache{client: client, prefix: prefix, ttl: ttl}
+}
+
+// Get implements cache.Store.
+func (c *Cache) Get(key string) ([]byte, bool) {
+	b, err := c.client.Get(context.Background(), c.prefix+key).Bytes()
+	switch {
+	case err == nil:
+		c.hits.Add(1)
+		return b, true
+	case !errors.Is(err, redis.Nil):
+		c.errs.Add(1)
+	}
+	c.misses.Add(1)
+	return nil, false
+}
+
+// Set implements cache.Store.
+func (c *Cache) Set(key string, value []byte) {
+	if err := c.client.Set(context.Background(), c.prefix+key, value, c.ttl).Err(); err != nil {
+		c.errs.Add(1)
+	}
+}
+
+// Delete implements cache.Store.
+func (c *Cache) Delete(key string) {
+	if err := c.client.Del(context.Background(), c.prefix+key).Err(); err != nil {
+		c.errs.Add(1)
+	}
+}
+
+// Stats returns the hit and miss counts of this process's lookups.
+// Evictions happen in Redis and are not counted.
+func (c *Cache) Stats() cache.Stats {
+	return cache.Stats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+}
+
+// Errors returns the number of commands that failed.
+func (c *Cache) Errors() uint64 { return c.errs.Load() }
+
+// Register reports the cache's statistics in reg under names starting
+// with prefix, as cache.Cache.Register does, and the failed commands as
+// prefix_errors_total.
+func (c *Cache) Register(reg *metrics.Registry, prefix string) {
+	reg.CounterFunc(prefix+"_hits_total", "Cache lookups that found an entry.", func() float64 { return float64(c.Stats().Hits) })
+	reg.CounterFunc(prefix+"_misses_total", "Cache lookups that found no live entry.", func() float64 { return float64(c.Stats().Misses) })
+	reg.CounterFunc(prefix+"_errors_total", "Cache commands that failed.", func() float64 { return float64(c.Errors()) })
+}
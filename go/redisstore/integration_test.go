@@ -0,0 +1,146 @@
+//go:build redis
+
+// These tests need a Redis server: $REDIS_ADDR, or localhost:6379. Run
+// them with "go test -tags redis ./redisstore". They use keys under a
+// prefix of their own and delete them when done.
+
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"example.com/tutorial/auth"
+)
+
+// openTest connects to the test server and returns a key prefix unique
+// to the test, whose keys are deleted at its end.
+func openTest(t *testing.T) (*redis.Client, string) {
+	t.Helper()
+	ctx := context.Background()
+	client, err := Open(ctx, Config{Addr: os.Getenv("REDIS_ADDR"), PoolSize: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix := "tutorial-test:" + t.Name() + ":" + strconv.FormatInt(time.Now().UnixNano(), 36) + ":"
+	t.Cleanup(func() {
+		keys, _ := client.Keys(ctx, prefix+"*").Result()
+		if len(keys) > 0 {
+			client.Del(ctx, keys...)
+		}
+		client.Close()
+	})
+	return client, prefix
+}
+
+func TestCacheIntegration(t *testing.T) {
+	client, prefix := openTest(t)
+	c := NewCache(client, prefix, time.Minute)
+	if _, ok := c.Get("k"); ok {
+		t.Fatal("hit on an empty cache")
+	}
+	c.Set("k", []byte("value"))
+	if v, ok := c.Get("k"); !ok || string(v) != "value" {
+		t.Fatalf("Get = %q, %v", v, ok)
+	}
+	if ttl := client.TTL(context.Background(), prefix+"k").Val(); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("ttl %v", ttl)
+	}
+	c.Delete("k")
+	if _, ok := c.Get("k"); ok {
+		t.Fatal("hit after Delete")
+	}
+	if s := c.Stats(); s.Hits != 1 || s.Misses != 2 || c.Errors() != 0 {
+		t.Fatalf("stats %+v, %d errors", s, c.Errors())
+	}
+
+	// Caches with the same prefix share their entries.
+	other := NewCache(client, prefix, 0)
+	other.Set("shared", []byte("yes"))
+	if v, ok := c.Get("shared"); !ok || string(v) != "yes" {
+		t.Fatalf("shared Get = %q, %v", v, ok)
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	client, prefix := openTest(t)
+	c := NewCache(client, prefix, 50*time.Millisecond)
+	c.Set("k", []byte("v"))
+	time.Sleep(200 * time.Millisecond)
+	if _, ok := c.Get("k"); ok {
+		t.Fatal("hit after the ttl")
+	}
+}
+
+func TestSessionsIntegration(t *testing.T) {
+	client, prefix := openTest(t)
+	ctx := context.Background()
+	s := NewSessions(client, prefix, time.Hour)
+	sess, err := s.Create(ctx, "42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Lookup(ctx, sess.Token)
+	if err != nil || got.UserID != "42" || got.Token != sess.Token {
+		t.Fatalf("Lookup = %+v, %v", got, err)
+	}
+	if d := got.Expires.Sub(sess.Expires); d > time.Second || d < -time.Second {
+		t.Errorf("expires %v, created with %v", got.Expires, sess.Expires)
+	}
+	if err := s.Revoke(ctx, sess.Token); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Lookup(ctx, sess.Token); !errors.Is(err, auth.ErrNoSession) {
+		t.Fatalf("revoked: %v", err)
+	}
+	if err := s.Revoke(ctx, "unknown"); err != nil {
+		t.Fatalf("revoke unknown: %v", err)
+	}
+	if _, err := s.Lookup(ctx, "unknown"); !errors.Is(err, auth.ErrNoSession) {
+		t.Fatalf("unknown: %v", err)
+	}
+
+	short := NewSessions(client, prefix, 50*time.Millisecond)
+	sess, _ = short.Create(ctx, "1")
+	time.Sleep(200 * time.Millisecond)
+	if _, err := short.Lookup(ctx, sess.Token); !errors.Is(err, auth.ErrNoSession) {
+		t.Fatalf("expired: %v", err)
+	}
+}
+
+func TestSessionsConcurrent(t *testing.T) {
+	// More goroutines than pooled connections wait their turn.
+	client, prefix := openTest(t)
+	ctx := context.Background()
+	s := NewSessions(client, prefix, time.Minute)
+	var wg sync.WaitGroup
+	errs := make(chan error, 32)
+	for i := 1; i <= 32; i++ {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			sess, err := s.Create(ctx, id)
+			if err == nil {
+				var got auth.Session
+				if got, err = s.Lookup(ctx, sess.Token); err == nil && got.UserID != id {
+					err = errors.New("wrong user " + got.UserID)
+				}
+			}
+			errs <- err
+		}(strconv.Itoa(i))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Package redisstore keeps caches and sessions in Redis, so that several
+// server processes share them: Cache implements cache.Store and
+// Sessions implements auth.SessionStore.
+package redisstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"example.com/tutorial/validate"
+)
+
+// Defaults of the zero fields of Config.
+const (
+	DefaultAddr         = "localhost:6379"
+	DefaultPoolSize     = 10
+	DefaultDialTimeout  = 5 * time.Second
+	DefaultReadTimeout  = 3 * time.Second
+	DefaultWriteTimeout = 3 * time.Second
+)
+
+// Config says how to reach a Redis server. Zero fields take the
+// defaults above.
+type Config struct {
+	// Addr is the server's host:port.
+	Addr string
+	// Username and Password authenticate with AUTH; an empty Username
+	// is the default user.
+	Username string
+	Password string
+	// DB selects the database, 0 by default.
+	DB int
+	// PoolSize is the most connections kept open; commands beyond it
+	// wait for one to be free, up to ReadTimeout plus a second.
+	PoolSize int
+	// DialTimeout bounds connecting; ReadTimeout and WriteTimeout bound
+	// each command's reply and request.
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+}
+
+// Validate reports the fields that cannot be used.
+func (c Config) Validate() error {
+	var v validate.Validator
+	v.Check(c.DB >= 0, "db", "must not be negative")
+	v.Check(c.PoolSize >= 0, "pool-size", "must not be negative")
+	v.Check(c.DialTimeout >= 0, "dial-timeout", "must not be negative")
+	v.Check(c.ReadTimeout >= 0, "read-timeout", "must not be negative")
+	v.Check(c.WriteTimeout >= 0, "write-timeout", "must not be negative")
+	return v.Err()
+}
+
+// options returns the client options of c with the defaults filled in.
+func (c Config) options() *redis.Options {
+	o := &redis.Options{
+		Addr:         c.Addr,
+		Username:     c.Username,
+		Password:     c.Password,
+		DB:           c.DB,
+		PoolSize:     c.PoolSize,
+		DialTimeout:  c.DialTimeout,
+		ReadTimeout:  c.ReadTimeout,
+		WriteTimeout: c.WriteTimeout,
+	}
+	if o.Addr == "" {
+		o.Addr = DefaultAddr
+	}
+	if o.PoolSize == 0 {
+		o.PoolSize = DefaultPoolSize
+	}
+	if o.DialTimeout == 0 {
+		o.DialTimeout = DefaultDialTimeout
+	}
+	if o.ReadTimeout == 0 {
+		o.ReadTimeout = DefaultReadTimeout
+	}
+	if o.WriteTimeout == 0 {
+		o.WriteTimeout = DefaultWriteTimeout
+	}
+	return o
+}
+
+// Open connects to the server cfg names and checks that it answers
+// PING. The client pools its connections and is safe for concurrent
+// use; Close it when done.
+func Open(ctx context.Context, cfg Config) (*redis.Client, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("redisstore: %w", err)
+	}
+	client := redis.NewClient(cfg.options())
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("redisstore: %s: %w", client.Options().Addr, err)
+	}
+	return client, nil
+}
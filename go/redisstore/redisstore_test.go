@@ -0,0 +1,66 @@
+package redisstore
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestConfig(t *testing.T) {
+	o := Config{}.options()
+	if o.Addr != DefaultAddr || o.PoolSize != DefaultPoolSize || o.DialTimeout != DefaultDialTimeout ||
+		o.ReadTimeout != DefaultReadTimeout || o.WriteTimeout != DefaultWriteTimeout {
+		t.Errorf("defaults: %+v", o)
+	}
+	o = Config{Addr: "cache:6380", DB: 2, PoolSize: 3, ReadTimeout: time.Second}.options()
+	if o.Addr != "cache:6380" || o.DB != 2 || o.PoolSize != 3 || o.ReadTimeout != time.Second {
+		t.Errorf("set: %+v", o)
+	}
+	err := Config{DB: -1, ReadTimeout: -time.Second}.Validate()
+	if err == nil || !strings.Contains(err.Error(), "db") || !strings.Contains(err.Error(), "read-timeout") {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+// closedAddr returns an address nothing listens on.
+func closedAddr(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lis.Addr().String()
+	lis.Close()
+	return addr
+}
+
+func TestOpenUnreachable(t *testing.T) {
+	addr := closedAddr(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := Open(ctx, Config{Addr: addr, DialTimeout: 100 * time.Millisecond}); err == nil || !strings.Contains(err.Error(), addr) {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, err := Open(ctx, Config{PoolSize: -1}); err == nil || !strings.Contains(err.Error(), "pool-size") {
+		t.Fatalf("Open invalid: %v", err)
+	}
+}
+
+func TestCacheUnreachable(t *testing.T) {
+	// A cache that cannot reach its server misses rather than fails.
+	client := redis.NewClient(&redis.Options{Addr: closedAddr(t), MaxRetries: -1, DialTimeout: 100 * time.Millisecond})
+	defer client.Close()
+	c := NewCache(client, "test:", time.Minute)
+	c.Set("k", []byte("v"))
+	if _, ok := c.Get("k"); ok {
+		t.Fatal("Get hit without a server")
+	}
+	c.Delete("k")
+	if s := c.Stats(); s.Hits != 0 || s.Misses != 1 || c.Errors() != 3 {
+		t.Fatalf("stats %+v, %d errors", s, c.Errors())
+	}
+}
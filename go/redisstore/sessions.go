@@ -0,0 +1,91 @@
+package redisstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"example.com/tutorial/auth"
+	"example.com/tutorial/id"
+)
+
+// Sessions is an auth.SessionStore in Redis, a key per session holding
+// its user ID under a prefix and the token. Redis expires the keys, so
+// nothing needs sweeping.
+type Sessions struct {
+	client redis.UniversalClient
+	prefix string
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+var _ auth.SessionStore = (*Sessions)(nil)
+
+// NewSessions returns a store in client whose keys start with prefix,
+// such as "tutorial:session:", and whose sessions last ttl. The ttl is
+// rounded down to milliseconds and must be at least one.
+func NewSessions(client redis.UniversalClient, prefix string, ttl time.Duration) *Sessions {
+	if ttl < time.Millisecond {
+		panic("redisstore: session ttl under a millisecond")
+	}
+	return &Sessions{client: client, prefix: prefix, ttl: ttl, now: time.Now}
+}
+
+// Create implements auth.SessionStore.
+func (s *Sessions) Create(ctx context.Context, userID string) (auth.Session, error) {
+	token, err := auth.NewToken()
+	if err != nil {
+		return auth.Session{}, err
+	}
+	expires := s.now().Add(s.ttl)
+	// NX keeps a colliding token, however unlikely, from taking over
+	// another user's session.
+	ok, err := s.client.SetNX(ctx, s.prefix+token, userID, s.ttl).Result()
+	if err != nil {
+		return auth.Session{}, fmt.Errorf("redisstore: create session: %w", err)
+	}
+	if !ok {
+		return auth.Session{}, errors.New("redisstore: create session: token collision")
+	}
+	return auth.Session{Token: token, UserID: userID, Expires: expires}, nil
+}
+
+// Lookup implements auth.SessionStore. The session's expiry is read
+// from the key's remaining time to live.
+func (s *Sessions) Lookup(ctx context.Context, token string) (auth.Session, error) {
+	var get *redis.StringCmd
+	var ttl *redis.DurationCmd
+	_, err := s.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		get = p.Get(ctx, s.prefix+token)
+		ttl = p.PTTL(ctx, s.prefix+token)
+		return nil
+	})
+	if errors.Is(err, redis.Nil) {
+		return auth.Session{}, auth.ErrNoSession
+	}
+	if err != nil {
+		return auth.Session{}, fmt.Errorf("redisstore: lookup session: %w", err)
+	}
+	userID := get.Val()
+	if !id.Valid(userID) {
+		return auth.Session{}, fmt.Errorf("redisstore: lookup session: bad user ID %q", userID)
+	}
+	// A key without expiry was not written by Create; give it the
+	// lifetime of a new session rather than none.
+	left := ttl.Val()
+	if left < 0 {
+		left = s.ttl
+	}
+	return auth.Session{Token: token, UserID: userID, Expires: s.now().Add(left)}, nil
+}
+
+// Revoke implements auth.SessionStore.
+func (s *Sessions) Revoke(ctx context.Context, token string) error {
+	if err := s.client.Del(ctx, s.prefix+token).Err(); err != nil {
+		return fmt.Errorf("redisstore: revoke session: %w", err)
+	}
+	return nil
+}
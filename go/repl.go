@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"example.com/tutorial/index"
+	"example.com/tutorial/ingest"
+	"example.com/tutorial/output"
+	"example.com/tutorial/repl"
+	"example.com/tutorial/set"
+	"example.com/tutorial/sliceutil"
+	"example.com/tutorial/text"
+	"example.com/tutorial/user"
+)
+
+// runREPL implements the "repl" subcommand: a prompt at which documents
+// are loaded once and then counted and searched, and users listed and
+// edited, as often as needed. It reads commands from stdin, a script if
+// stdin is not a terminal. In a dry run the users command changes the
+// users in memory only and prints the files it would write.
+func runREPL(ctx context.Context, cfg replSettings, dryRun bool, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("repl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: repl [-users file] [-prompt s] [path ...]")
+		fs.PrintDefaults()
+	}
+	usersFile := fs.String("users", cfg.Users, "JSON user `file` the users command edits; empty keeps users in memory")
+	prompt := fs.String("prompt", cfg.Prompt, "the `prompt` printed before each command")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	var users user.Store = user.NewMemoryStore()
+	if *usersFile != "" {
+		fstore, err := user.OpenFileStoreWithConfig(*usersFile, user.FileStoreConfig{Files: newExecutor(dryRun, stdout)})
+		if err != nil {
+			fmt.Fprintln(stderr, "repl:", err)
+			return 1
+		}
+		users = fstore
+	}
+	c := newCorpus()
+	// The REPL edits lines on the terminal itself, so it is given the
+	// writer under out, and its commands color as out does.
+	out := output.For(stdout)
+	if out.Color() {
+		c.color = output.Always
+	}
+	if fs.NArg() > 0 {
+		if err := c.load(ctx, fs.Args(), stdout); err != nil {
+			fmt.Fprintln(stderr, "repl:", err)
+		}
+	}
+	r := repl.New(c.commands(users)...)
+	r.Prompt = *prompt
+	if err := r.Run(ctx, stdin, out.Unwrap()); err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintln(stderr, "repl:", err)
+		return 1
+	}
+	return 0
+}
+
+// corpus is the state of a REPL session: the loaded documents, their word
+// counts and an index to search them.
+type corpus struct {
+	ix     *index.Index
+	counts map[string]map[string]int // per document
+	total  map[string]int
+	words  int
+	color  output.Mode // of the tables the commands print
+}
+
+func newCorpus() *corpus {
+	return &corpus{ix: index.New(), counts: map[string]map[string]int{}, total: map[string]int{}, color: output.Never}
+}
+
+// add loads the text of one file, replacing the document if it was
+// loaded before.
+func (c *corpus) add(path string) error {
+	r, _, err := ingest.Open(path, ingest.Options{})
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	doc := filepath.ToSlash(path)
+	c.remove(doc)
+	if err := c.ix.Add(doc, bytes.NewReader(data)); err != nil {
+		return err
+	}
+	counts := text.WordCount(string(data))
+	c.counts[doc] = counts
+	for w, n := range counts {
+		c.total[w] += n
+		c.words += n
+	}
+	return nil
+}
+
+func (c *corpus) remove(doc string) bool {
+	counts, ok := c.counts[doc]
+	if !ok {
+		return false
+	}
+	for w, n := range counts {
+		if c.total[w] -= n; c.total[w] == 0 {
+			delete(c.total, w)
+		}
+		c.words -= n
+	}
+	delete(c.counts, doc)
+	c.ix.Remove(doc)
+	return true
+}
+
+// load adds the files at or below paths and reports how many it loaded.
+// It goes on past the files it cannot read and returns their errors
+// joined.
+func (c *corpus) load(ctx context.Context, paths []string, w io.Writer) error {
+	var errs []error
+	loaded := 0
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				errs = append(errs, err)
+				return nil
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			if err := c.add(path); err != nil {
+				errs = append(errs, err)
+				return nil
+			}
+			loaded++
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, err)
+			break
+		}
+	}
+	fmt.Fprintf(w, "loaded %d files; %d files, %d words in all\n", loaded, len(c.counts), c.words)
+	return errors.Join(errs...)
+}
+
+func (c *corpus) docs() []string {
+	docs := make([]string, 0, len(c.counts))
+	for d := range c.counts {
+		docs = append(docs, d)
+	}
+	sort.Strings(docs)
+	return docs
+}
+
+// completeWords offers the loaded words starting with partial, the most
+// frequent first, from the vocabulary the index keeps in a trie.
+func (c *corpus) completeWords(_ []string, partial string) []string {
+	return sliceutil.Map(c.ix.Complete(partial, -1), func(wf text.WordFreq) string { return wf.Word })
+}
+
+// completePath offers the files and directories whose path starts with
+// partial; directories end in a slash.
+func completePath(_ []string, partial string) []string {
+	matches, _ := filepath.Glob(partial + "*")
+	for i, m := range matches {
+		if info, err := os.Stat(m); err == nil && info.IsDir() {
+			matches[i] = m + string(filepath.Separator)
+		}
+	}
+	return matches
+}
+
+func (c *corpus) commands(users user.Store) []repl.Command {
+	return []repl.Command{
+		{Name: "load", Args: "path ...", Summary: "load files, or the files below directories", Complete: completePath,
+			Run: func(ctx context.Context, args []string, w io.Writer) error {
+				if len(args) == 0 {
+					return errors.New("usage: load path ...")
+				}
+				return c.load(ctx, args, w)
+			}},
+		{Name: "unload", Args: "doc ...", Summary: "forget loaded documents",
+			Complete: func([]string, string) []string { return c.docs() },
+			Run: func(_ context.Context, args []string, w io.Writer) error {
+				for _, doc := range args {
+					if !c.remove(doc) {
+						return fmt.Errorf("%s is not loaded", doc)
+					}
+				}
+				return nil
+			}},
+		{Name: "files", Summary: "list the loaded documents and their word counts",
+			Run: func(_ context.Context, _ []string, w io.Writer) error {
+				for _, doc := range c.docs() {
+					n := 0
+					for _, k := range c.counts[doc] {
+						n += k
+					}
+					fmt.Fprintf(w, "%8d %s\n", n, doc)
+				}
+				return nil
+			}},
+		{Name: "count", Args: "[word ...]", Summary: "print the totals, or how often each word occurs", Complete: c.completeWords,
+			Run: func(_ context.Context, args []string, w io.Writer) error {
+				if len(args) == 0 {
+					fmt.Fprintf(w, "%d files, %d words, %d distinct\n", len(c.counts), c.words, len(c.total))
+					return nil
+				}
+				for _, word := range args {
+					fmt.Fprintf(w, "%8d %s\n", c.total[strings.ToLower(word)], word)
+				}
+				return nil
+			}},
+		{Name: "top", Args: "[n]", Summary: "print the n most frequent words, 10 by default",
+			Run: func(_ context.Context, args []string, w io.Writer) error {
+				n := 10
+				if len(args) > 0 {
+					var err error
+					if n, err = strconv.Atoi(args[0]); err != nil {
+						return fmt.Errorf("%q is not a number", args[0])
+					}
+				}
+				return writeFrequencies(output.New(w, c.color), text.TopWords(c.total, n), text.FormatText)
+			}},
+		{Name: "search", Args: "query", Summary: "list the documents matching a query such as 'go AND rust OR gophers'", Complete: c.completeWords,
+			Run: func(_ context.Context, args []string, w io.Writer) error {
+				docs, err := c.ix.Search(strings.Join(args, " "))
+				if err != nil {
+					return err
+				}
+				for _, d := range docs {
+					fmt.Fprintln(w, d)
+				}
+				fmt.Fprintf(w, "%d documents\n", len(docs))
+				return nil
+			}},
+		{Name: "overlap", Args: "doc doc", Summary: "compare the vocabularies of two documents",
+			Complete: func([]string, string) []string { return c.docs() },
+			Run: func(_ context.Context, args []string, w io.Writer) error {
+				if len(args) != 2 {
+					return errors.New("usage: overlap doc doc")
+				}
+				for _, doc := range args {
+					if _, ok := c.counts[doc]; !ok {
+						return fmt.Errorf("%s is not loaded", doc)
+					}
+				}
+				o := text.CompareVocabulary(c.counts[args[0]], c.counts[args[1]])
+				fmt.Fprintf(w, "%d shared, %d only in %s, %d only in %s; similarity %.2f\n",
+					o.Shared.Len(), o.OnlyA.Len(), args[0], o.OnlyB.Len(), args[1], o.Jaccard())
+				if o.Shared.Len() > 0 {
+					fmt.Fprintln(w, strings.Join(set.Sorted(o.Shared), " "))
+				}
+				return nil
+			}},
+		{Name: "users", Args: "list|add|get|delete [arguments]", Summary: "list and edit users, as the users subcommand does",
+			Complete: func(args []string, _ string) []string {
+				if len(args) > 0 {
+					return nil
+				}
+				return []string{"add", "delete", "get", "list"}
+			},
+			Run: func(ctx context.Context, args []string, w io.Writer) error {
+				if len(args) == 0 {
+					return errors.New("usage: users list|add|get|delete [arguments]")
+				}
+				run := map[string]func(context.Context, user.Store, []string, io.Writer, io.Writer) int{
+					"list": runUsersList, "add": runUsersAdd, "get": runUsersGet, "delete": runUsersDelete,
+				}[args[0]]
+				if run == nil {
+					return fmt.Errorf("unknown users action %q", args[0])
+				}
+				// The actions report their own errors.
+				run(ctx, users, args[1:], w, w)
+				return nil
+			}},
+	}
+}
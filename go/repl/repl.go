@@ -0,0 +1,324 @@
+// Package repl runs an interactive command loop: a prompt, line editing
+// with history and tab completion when the input is a terminal, and a
+// plain line-by-line reader for scripts otherwise. Programs supply the
+// commands; help, history and exit are built in.
+package repl
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// DefaultPrompt is the prompt of a REPL whose Prompt is empty.
+const DefaultPrompt = "> "
+
+// DefaultMaxHistory bounds the history of a REPL whose MaxHistory is
+// zero.
+const DefaultMaxHistory = 1000
+
+// ErrExit is returned by a command to end the loop, as exit does.
+var ErrExit = errors.New("repl: exit")
+
+// Command is a command of a REPL.
+type Command struct {
+	Name    string
+	Args    string // synopsis of the arguments, for help
+	Summary string // one line, for help
+	// Run runs the command with the arguments that followed its name,
+	// writing its output to w. An error other than ErrExit is printed and
+	// the loop goes on.
+	Run func(ctx context.Context, args []string, w io.Writer) error
+	// Complete, if not nil, returns the candidates for the argument being
+	// typed, partial, which follows the complete arguments args.
+	Complete func(args []string, partial string) []string
+}
+
+// REPL is a read-eval-print loop over a set of commands.
+type REPL struct {
+	// Prompt is printed before every line read from a terminal; empty
+	// means DefaultPrompt.
+	Prompt string
+	// MaxHistory bounds the lines History keeps; zero means
+	// DefaultMaxHistory.
+	MaxHistory int
+
+	commands map[string]Command
+	history  []string
+}
+
+// New returns a REPL running cmds, which must not use the names of the
+// built-in commands.
+func New(cmds ...Command) *REPL {
+	r := &REPL{commands: map[string]Command{}}
+	for _, c := range r.builtins() {
+		r.commands[c.Name] = c
+	}
+	for _, c := range cmds {
+		if _, dup := r.commands[c.Name]; dup {
+			panic("repl: duplicate command " + c.Name)
+		}
+		r.commands[c.Name] = c
+	}
+	return r
+}
+
+func (r *REPL) builtins() []Command {
+	return []Command{
+		{Name: "help", Args: "[command]", Summary: "list the commands, or describe one", Run: r.help,
+			Complete: func(args []string, partial string) []string {
+				if len(args) > 0 {
+					return nil
+				}
+				return r.names(partial)
+			}},
+		{Name: "history", Summary: "print the lines entered so far", Run: func(_ context.Context, _ []string, w io.Writer) error {
+			for i, line := range r.history {
+				fmt.Fprintf(w, "%4d  %s\n", i+1, line)
+			}
+			return nil
+		}},
+		{Name: "exit", Summary: "leave", Run: func(context.Context, []string, io.Writer) error { return ErrExit }},
+	}
+}
+
+func (r *REPL) help(_ context.Context, args []string, w io.Writer) error {
+	if len(args) > 0 {
+		c, ok := r.commands[args[0]]
+		if !ok {
+			return fmt.Errorf("unknown command %q", args[0])
+		}
+		fmt.Fprintln(w, strings.TrimSpace(c.Name+" "+c.Args))
+		fmt.Fprintf(w, "    %s\n", c.Summary)
+		return nil
+	}
+	names := r.names("")
+	width := 0
+	for _, n := range names {
+		width = max(width, len(n))
+	}
+	for _, n := range names {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, n, r.commands[n].Summary)
+	}
+	return nil
+}
+
+// names returns the sorted names of the commands starting with prefix.
+func (r *REPL) names(prefix string) []string {
+	var names []string
+	for n := range r.commands {
+		if strings.HasPrefix(n, prefix) {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// History returns the lines entered so far, oldest first.
+func (r *REPL) History() []string { return r.history }
+
+func (r *REPL) remember(line string) {
+	limit := r.MaxHistory
+	if limit <= 0 {
+		limit = DefaultMaxHistory
+	}
+	if n := len(r.history); n > 0 && r.history[n-1] == line {
+		return
+	}
+	r.history = append(r.history, line)
+	if len(r.history) > limit {
+		r.history = r.history[len(r.history)-limit:]
+	}
+}
+
+// Exec runs one line of input, writing the output of its command to w.
+// Blank lines and lines starting with # do nothing. It returns ErrExit
+// when the line asks to leave.
+func (r *REPL) Exec(ctx context.Context, line string, w io.Writer) error {
+	if trimmed := strings.TrimSpace(line); trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return nil
+	}
+	r.remember(strings.TrimSpace(line))
+	words, err := Split(line)
+	if err != nil {
+		return err
+	}
+	c, ok := r.commands[words[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q; try help", words[0])
+	}
+	return c.Run(ctx, words[1:], w)
+}
+
+// Split splits a line into words at spaces, except inside single or
+// double quotes, which are removed. A backslash outside single quotes
+// escapes the next character.
+func Split(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord, escaped := false, false
+	var quote rune
+	for _, c := range line {
+		switch {
+		case escaped:
+			word.WriteRune(c)
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				word.WriteRune(c)
+			}
+		case c == '"' || c == '\'':
+			quote, inWord = c, true
+		case c == ' ' || c == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// Complete returns the line with the word before pos completed, and the
+// new cursor position: command names for the first word, the command's
+// candidates for the others. A single candidate is completed in full and
+// followed by a space; several extend the word to their common prefix.
+func (r *REPL) Complete(line string, pos int) (string, int, bool) {
+	head, tail := line[:pos], line[pos:]
+	start := strings.LastIndexAny(head, " \t") + 1
+	partial := head[start:]
+	words := strings.Fields(head[:start])
+
+	var candidates []string
+	if len(words) == 0 {
+		candidates = r.names(partial)
+	} else if c, ok := r.commands[words[0]]; ok && c.Complete != nil {
+		for _, cand := range c.Complete(words[1:], partial) {
+			if strings.HasPrefix(cand, partial) {
+				candidates = append(candidates, cand)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return "", 0, false
+	}
+	completion := candidates[0]
+	if len(candidates) == 1 {
+		if !strings.HasSuffix(completion, "/") {
+			completion += " "
+		}
+	} else {
+		for _, cand := range candidates[1:] {
+			completion = commonPrefix(completion, cand)
+		}
+	}
+	if completion == partial {
+		return "", 0, false
+	}
+	newHead := head[:start] + completion
+	return newHead + tail, len(newHead), true
+}
+
+func commonPrefix(a, b string) string {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return a[:n]
+}
+
+// Run reads lines from in and executes them, writing the output to out,
+// until the input ends, a command returns ErrExit or ctx is done. When in
+// and out are the same terminal, lines are edited in raw mode, with the
+// arrow keys recalling history and Tab completing; otherwise lines are
+// read as they come, without prompts, so that scripts can be piped in.
+func (r *REPL) Run(ctx context.Context, in io.Reader, out io.Writer) error {
+	if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		if o, ok := out.(*os.File); ok && term.IsTerminal(int(o.Fd())) {
+			return r.runTerminal(ctx, f, o)
+		}
+	}
+	sc := bufio.NewScanner(in)
+	for ctx.Err() == nil && sc.Scan() {
+		if err := r.exec(ctx, sc.Text(), out); err != nil {
+			return nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// exec runs line and reports its error, returning ErrExit if the loop
+// should end.
+func (r *REPL) exec(ctx context.Context, line string, w io.Writer) error {
+	err := r.Exec(ctx, line, w)
+	if errors.Is(err, ErrExit) {
+		return ErrExit
+	}
+	if err != nil {
+		fmt.Fprintln(w, "error:", err)
+	}
+	return nil
+}
+
+func (r *REPL) runTerminal(ctx context.Context, in, out *os.File) error {
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return err
+	}
+	defer term.Restore(int(in.Fd()), state)
+	prompt := r.Prompt
+	if prompt == "" {
+		prompt = DefaultPrompt
+	}
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{in, out}, prompt)
+	if w, h, err := term.GetSize(int(out.Fd())); err == nil {
+		t.SetSize(w, h)
+	}
+	t.AutoCompleteCallback = func(line string, pos int, key rune) (string, int, bool) {
+		if key != '\t' {
+			return "", 0, false
+		}
+		return r.Complete(line, pos)
+	}
+	for ctx.Err() == nil {
+		line, err := t.ReadLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil && !errors.Is(err, term.ErrPasteIndicator) {
+			return err
+		}
+		if r.exec(ctx, line, t) != nil {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
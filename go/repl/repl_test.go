@@ -0,0 +1,104 @@
+package repl
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testREPL(t *testing.T) *REPL {
+	t.Helper()
+	return New(
+		Command{Name: "echo", Args: "word ...", Summary: "print the words", Run: func(_ context.Context, args []string, w io.Writer) error {
+			_, err := io.WriteString(w, strings.Join(args, "|")+"\n")
+			return err
+		}},
+		Command{Name: "fail", Summary: "fail", Run: func(context.Context, []string, io.Writer) error {
+			return errors.New("it failed")
+		}},
+		Command{Name: "fruit", Summary: "complete fruit", Run: func(context.Context, []string, io.Writer) error { return nil },
+			Complete: func(args []string, partial string) []string {
+				return []string{"apple", "apricot", "banana", "dir/"}
+			}},
+	)
+}
+
+func TestRun(t *testing.T) {
+	r := testREPL(t)
+	in := "echo a 'b c' \"d\\\"e\"\n\n# comment\nfail\nnope\nhelp echo\nhistory\nexit\necho after exit\n"
+	var out strings.Builder
+	if err := r.Run(context.Background(), strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+	want := "a|b c|d\"e\n" +
+		"error: it failed\n" +
+		"error: unknown command \"nope\"; try help\n" +
+		"echo word ...\n    print the words\n" +
+		"   1  echo a 'b c' \"d\\\"e\"\n   2  fail\n   3  nope\n   4  help echo\n   5  history\n"
+	if out.String() != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+	if got := r.History(); len(got) != 6 || got[5] != "exit" {
+		t.Fatalf("history = %q", got)
+	}
+}
+
+func TestHelp(t *testing.T) {
+	var out strings.Builder
+	if err := testREPL(t).Exec(context.Background(), "help", &out); err != nil {
+		t.Fatal(err)
+	}
+	want := "  echo     print the words\n  exit     leave\n  fail     fail\n  fruit    complete fruit\n" +
+		"  help     list the commands, or describe one\n  history  print the lines entered so far\n"
+	if out.String() != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	got, err := Split(`  a "b c"d 'e\f' g\ h  `)
+	if want := []string{"a", "b cd", `e\f`, "g h"}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("Split = %q, %v", got, err)
+	}
+	if _, err := Split(`a "b`); err == nil {
+		t.Fatal("unterminated quote accepted")
+	}
+}
+
+func TestComplete(t *testing.T) {
+	r := testREPL(t)
+	tests := []struct {
+		line    string
+		pos     int
+		want    string
+		wantPos int
+		ok      bool
+	}{
+		{"ec", 2, "echo ", 5, true},
+		{"h", 1, "h", 0, false}, // help and history share only "h"
+		{"hi", 2, "history ", 8, true},
+		{"fruit ap", 8, "fruit apr", 9, false}, // apple and apricot: nothing to add
+		{"fruit apr", 9, "fruit apricot ", 14, true},
+		{"fruit b x", 7, "fruit banana  x", 13, true},
+		{"fruit d", 7, "fruit dir/", 10, true},
+		{"echo a", 6, "", 0, false},
+		{"help fa", 7, "help fail ", 10, true},
+	}
+	for _, tt := range tests {
+		got, pos, ok := r.Complete(tt.line, tt.pos)
+		if ok != tt.ok || ok && (got != tt.want || pos != tt.wantPos) {
+			t.Errorf("Complete(%q, %d) = %q, %d, %v; want %q, %d, %v", tt.line, tt.pos, got, pos, ok, tt.want, tt.wantPos, tt.ok)
+		}
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := testREPL(t).Run(ctx, strings.NewReader("echo x\n"), io.Discard); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRunREPL(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"a.txt":     "go go gophers",
+		"sub/b.md":  "# Go\n\nrust and *go*",
+		"sub/c.txt": "nothing here",
+	})
+	script := strings.Join([]string{
+		"count",
+		"count go Rust missing",
+		"top 2",
+		"search go AND rust",
+		"overlap " + filepath.ToSlash(filepath.Join(dir, "a.txt")) + " " + filepath.ToSlash(filepath.Join(dir, "sub", "b.md")),
+		"overlap a.txt",
+		"unload " + filepath.ToSlash(filepath.Join(dir, "sub", "c.txt")),
+		"count",
+		"users add ada bob",
+		"users list",
+		"users rename",
+		"top many",
+		"exit",
+		"count",
+	}, "\n")
+	var out, errOut strings.Builder
+	code := runREPL(context.Background(), defaultSettings().Repl, false, []string{dir}, strings.NewReader(script), &out, &errOut)
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+	a := filepath.ToSlash(filepath.Join(dir, "a.txt"))
+	b := filepath.ToSlash(filepath.Join(dir, "sub", "b.md"))
+	want := "loaded 3 files; 3 files, 9 words in all\n" +
+		"3 files, 9 words, 6 distinct\n" +
+		"       4 go\n       1 Rust\n       0 missing\n" +
+		"WORD  COUNT\ngo        4\nand       1\n" +
+		b + "\n1 documents\n" +
+		"1 shared, 1 only in " + a + ", 2 only in " + b + "; similarity 0.25\ngo\n" +
+		"error: usage: overlap doc doc\n" +
+		"2 files, 7 words, 4 distinct\n" +
+		"ID\tada\nID\tbob\n" +
+		"ID\tada\nID\tbob\n" +
+		"error: unknown users action \"rename\"\n" +
+		"error: \"many\" is not a number\n"
+	if got := maskIDs(out.String()); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCorpusCompleteWords(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"a.txt": "go gophers go goroutines",
+		"b.txt": "rust and go",
+	})
+	c := newCorpus()
+	if err := c.load(context.Background(), []string{dir}, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.completeWords(nil, "go"); !reflect.DeepEqual(got, []string{"go", "gophers", "goroutines"}) {
+		t.Fatalf("completeWords(go) = %q", got)
+	}
+	c.remove(filepath.ToSlash(filepath.Join(dir, "a.txt")))
+	if got := c.completeWords(nil, "go"); !reflect.DeepEqual(got, []string{"go"}) {
+		t.Fatalf("after unload = %q", got)
+	}
+	if got := c.completeWords(nil, "x"); len(got) != 0 {
+		t.Fatalf("completeWords(x) = %q", got)
+	}
+}
@@ -0,0 +1,213 @@
+// Package repo provides a generic in-memory repository that entity
+// stores build on, so each entity gets the same CRUD semantics without
+// copying the bookkeeping.
+package repo
+
+import (
+	"sync"
+
+	"example.com/tutorial/errs"
+)
+
+var (
+	// ErrNotFound is returned when no value has the requested key.
+	ErrNotFound = errs.New(errs.ErrNotFound, "repo: not found")
+	// ErrExists is returned when creating a value whose key is taken.
+	ErrExists = errs.New(errs.ErrConflict, "repo: already exists")
+)
+
+// KeyGen hands out keys for values created without one.
+type KeyGen[K comparable] interface {
+	// Next returns a candidate key. The repository calls it until it
+	// gets one that is not in use.
+	Next() K
+	// Observe is told about every key stored, so that later keys can
+	// follow it.
+	Observe(K)
+}
+
+// Integer is the set of types Sequence can count in.
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64
+}
+
+// Sequence is a KeyGen of increasing integers starting at 1. Keys stored
+// explicitly move the sequence past them.
+type Sequence[K Integer] struct {
+	next K
+}
+
+// Next implements KeyGen.
+func (s *Sequence[K]) Next() K {
+	if s.next < 1 {
+		s.next = 1
+	}
+	k := s.next
+	s.next++
+	return k
+}
+
+// Observe implements KeyGen.
+func (s *Sequence[K]) Observe(k K) {
+	if k >= s.next {
+		s.next = k + 1
+	}
+}
+
+// Config describes how a Repository handles its values.
+type Config[T any, K comparable] struct {
+	// Key returns the key of a value. It is required.
+	Key func(T) K
+	// SetKey returns v with its key set to k. Together with Keys it
+	// lets Create assign keys to values whose key is the zero value;
+	// without them such values are stored under the zero key.
+	SetKey func(v T, k K) T
+	Keys   KeyGen[K]
+	// Commit, if set, is called with every stored value after each
+	// change, while the repository is still locked. If it fails the
+	// change is undone and the error returned, which lets callers
+	// persist the contents transactionally.
+	Commit func([]T) error
+}
+
+// Repository is a map of values by key that is safe for concurrent use.
+type Repository[T any, K comparable] struct {
+	cfg   Config[T, K]
+	mu    sync.RWMutex
+	items map[K]T
+}
+
+// New returns an empty repository.
+func New[T any, K comparable](cfg Config[T, K]) *Repository[T, K] {
+	return &Repository[T, K]{cfg: cfg, items: map[K]T{}}
+}
+
+// Create stores v and returns it as stored, with its key assigned if it
+// had none. A key already in use yields ErrExists.
+func (r *Repository[T, K]) Create(v T) (T, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var zero K
+	k := r.cfg.Key(v)
+	if k == zero && r.cfg.Keys != nil && r.cfg.SetKey != nil {
+		for {
+			k = r.cfg.Keys.Next()
+			if _, taken := r.items[k]; !taken {
+				break
+			}
+		}
+		v = r.cfg.SetKey(v, k)
+	}
+	if _, ok := r.items[k]; ok {
+		var none T
+		return none, ErrExists
+	}
+	r.items[k] = v
+	if r.cfg.Keys != nil {
+		r.cfg.Keys.Observe(k)
+	}
+	if err := r.commit(); err != nil {
+		delete(r.items, k)
+		var none T
+		return none, err
+	}
+	return v, nil
+}
+
+// Get returns the value stored under k or ErrNotFound.
+func (r *Repository[T, K]) Get(k K) (T, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	v, ok := r.items[k]
+	if !ok {
+		return v, ErrNotFound
+	}
+	return v, nil
+}
+
+// Update replaces the stored value with the same key as v, or returns
+// ErrNotFound.
+func (r *Repository[T, K]) Update(v T) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k := r.cfg.Key(v)
+	old, ok := r.items[k]
+	if !ok {
+		return ErrNotFound
+	}
+	r.items[k] = v
+	if err := r.commit(); err != nil {
+		r.items[k] = old
+		return err
+	}
+	return nil
+}
+
+// Delete removes the value stored under k, or returns ErrNotFound.
+func (r *Repository[T, K]) Delete(k K) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old, ok := r.items[k]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(r.items, k)
+	if err := r.commit(); err != nil {
+		r.items[k] = old
+		return err
+	}
+	return nil
+}
+
+// All returns every stored value in no particular order.
+func (r *Repository[T, K]) All() []T {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.all()
+}
+
+// Len returns the number of stored values.
+func (r *Repository[T, K]) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.items)
+}
+
+// Restore replaces the contents with vs, for loading previously saved
+// values. It does not call Commit, and fails without changing anything
+// if two values share a key.
+func (r *Repository[T, K]) Restore(vs []T) error {
+	items := make(map[K]T, len(vs))
+	for _, v := range vs {
+		k := r.cfg.Key(v)
+		if _, dup := items[k]; dup {
+			return ErrExists
+		}
+		items[k] = v
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items = items
+	if r.cfg.Keys != nil {
+		for k := range items {
+			r.cfg.Keys.Observe(k)
+		}
+	}
+	return nil
+}
+
+func (r *Repository[T, K]) all() []T {
+	vs := make([]T, 0, len(r.items))
+	for _, v := range r.items {
+		vs = append(vs, v)
+	}
+	return vs
+}
+
+func (r *Repository[T, K]) commit() error {
+	if r.cfg.Commit == nil {
+		return nil
+	}
+	return r.cfg.Commit(r.all())
+}
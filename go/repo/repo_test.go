@@ -0,0 +1,134 @@
+package repo
+
+import (
+	"errors"
+	"sort"
+	"testing"
+)
+
+type item struct {
+	ID   int
+	Name string
+}
+
+func newItems(commit func([]item) error) *Repository[item, int] {
+	return New(Config[item, int]{
+		Key:    func(it item) int { return it.ID },
+		SetKey: func(it item, id int) item { it.ID = id; return it },
+		Keys:   &Sequence[int]{},
+		Commit: commit,
+	})
+}
+
+func TestCreateAssignsKeys(t *testing.T) {
+	r := newItems(nil)
+	a, _ := r.Create(item{Name: "a"})
+	b, _ := r.Create(item{ID: 10, Name: "b"})
+	c, _ := r.Create(item{Name: "c"})
+	if a.ID != 1 || b.ID != 10 || c.ID != 11 {
+		t.Fatalf("IDs = %d, %d, %d; want 1, 10, 11", a.ID, b.ID, c.ID)
+	}
+	if _, err := r.Create(item{ID: 10}); !errors.Is(err, ErrExists) {
+		t.Fatalf("duplicate Create err = %v, want ErrExists", err)
+	}
+	if got, err := r.Get(10); err != nil || got != b {
+		t.Fatalf("Get(10) = %+v, %v", got, err)
+	}
+	if r.Len() != 3 {
+		t.Fatalf("Len = %d, want 3", r.Len())
+	}
+}
+
+func TestUpdateDelete(t *testing.T) {
+	r := newItems(nil)
+	a, _ := r.Create(item{Name: "a"})
+	a.Name = "renamed"
+	if err := r.Update(a); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := r.Get(a.ID); got.Name != "renamed" {
+		t.Fatalf("after Update got %+v", got)
+	}
+	if err := r.Update(item{ID: 99}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Update missing err = %v", err)
+	}
+	if err := r.Delete(a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Get(a.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete err = %v", err)
+	}
+	if err := r.Delete(a.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("second Delete err = %v", err)
+	}
+}
+
+func TestStringKeys(t *testing.T) {
+	r := New(Config[item, string]{Key: func(it item) string { return it.Name }})
+	r.Create(item{ID: 1, Name: "x"})
+	if _, err := r.Create(item{ID: 2, Name: "x"}); !errors.Is(err, ErrExists) {
+		t.Fatalf("err = %v, want ErrExists", err)
+	}
+	if got, _ := r.Get("x"); got.ID != 1 {
+		t.Fatalf("Get = %+v", got)
+	}
+}
+
+func TestCommitFailureRollsBack(t *testing.T) {
+	fail := false
+	var committed []item
+	r := newItems(func(items []item) error {
+		if fail {
+			return errors.New("disk full")
+		}
+		committed = items
+		return nil
+	})
+	a, _ := r.Create(item{Name: "a"})
+	if len(committed) != 1 {
+		t.Fatalf("committed %+v", committed)
+	}
+
+	fail = true
+	if _, err := r.Create(item{Name: "b"}); err == nil {
+		t.Fatal("Create succeeded despite failing commit")
+	}
+	if err := r.Update(item{ID: a.ID, Name: "changed"}); err == nil {
+		t.Fatal("Update succeeded despite failing commit")
+	}
+	if err := r.Delete(a.ID); err == nil {
+		t.Fatal("Delete succeeded despite failing commit")
+	}
+	if all := r.All(); len(all) != 1 || all[0] != a {
+		t.Fatalf("after failed commits All = %+v, want [%+v]", all, a)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	r := newItems(func([]item) error { t.Fatal("Restore called Commit"); return nil })
+	if err := r.Restore([]item{{ID: 3, Name: "c"}, {ID: 7, Name: "g"}}); err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, it := range r.All() {
+		ids = append(ids, it.ID)
+	}
+	sort.Ints(ids)
+	if len(ids) != 2 || ids[0] != 3 || ids[1] != 7 {
+		t.Fatalf("ids = %v", ids)
+	}
+	if err := r.Restore([]item{{ID: 1}, {ID: 1}}); !errors.Is(err, ErrExists) {
+		t.Fatalf("duplicate Restore err = %v", err)
+	}
+	if r.Len() != 2 {
+		t.Fatal("failed Restore changed contents")
+	}
+}
+
+func TestRestoreAdvancesSequence(t *testing.T) {
+	r := newItems(nil)
+	r.Restore([]item{{ID: 5}})
+	if it, _ := r.Create(item{}); it.ID != 6 {
+		t.Fatalf("new ID = %d, want 6", it.ID)
+	}
+}
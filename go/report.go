@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"example.com/tutorial/report"
+)
+
+// defaultReportTitle is the title of a report when -title is not given.
+const defaultReportTitle = "Word report"
+
+// runReport implements the "report" subcommand, which renders a report of
+// the words of files, overall and per file, as Markdown or HTML, and
+// returns the process exit code. With no file arguments it reports on
+// standard input. The format is -format's or else that of the -template
+// file's or -o file's name, Markdown if none tells. Files that cannot be
+// read are listed in the report and make the exit code 1.
+func runReport(ctx context.Context, cfg reportSettings, dryRun bool, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: report [-title s] [-top n] [-sort] [-format markdown|html] [-template file] [-o file] [file ...]")
+		fs.PrintDefaults()
+	}
+	title := fs.String("title", cfg.Title, "the report's `title`")
+	top := fs.Int("top", cfg.Top, "list the `n` most frequent words, overall and per file (-1 for all)")
+	sortFiles := fs.Bool("sort", false, "list the files by descending word count instead of as given")
+	format := fs.String("format", cfg.Format, "output `format`: markdown or html")
+	tmplPath := fs.String("template", cfg.Template, "render through the template `file` instead of the built-in one")
+	out := fs.String("o", "", "write the report to `file` instead of standard output")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	var f report.Format
+	if *format != "" {
+		var err error
+		if f, err = report.ParseFormat(*format); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+	}
+	tmpl, err := reportTemplate(f, *tmplPath, *out)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	status := 0
+	b := report.NewBuilder(*title, *top)
+	if fs.NArg() == 0 {
+		if err := b.Add("stdin", stdin); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+	for _, name := range fs.Args() {
+		if ctx.Err() != nil {
+			fmt.Fprintln(stderr, "report:", ctx.Err())
+			return 1
+		}
+		if err := addReportFile(b, name); err != nil {
+			fmt.Fprintf(stderr, "report: %s: %v\n", name, err)
+			b.AddError(name, err)
+			status = 1
+		}
+	}
+	r := b.Report()
+	if *sortFiles {
+		r.SortFiles()
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, r); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if *out == "" {
+		stdout.Write(buf.Bytes())
+		return status
+	}
+	if err := newExecutor(dryRun, stdout).WriteAtomic(*out, buf.Bytes()); err != nil {
+		fmt.Fprintln(stderr, "report:", err)
+		return 1
+	}
+	return status
+}
+
+// reportTemplate returns the template of the -template file, or the
+// built-in one, of format f or, if f is empty, the format told by the
+// name of the template or else of the output file.
+func reportTemplate(f report.Format, tmplPath, out string) (*report.Template, error) {
+	if tmplPath != "" {
+		return report.ParseFile(f, tmplPath)
+	}
+	if f == "" {
+		var ok bool
+		if f, ok = report.FormatOf(out); !ok {
+			f = report.Markdown
+		}
+	}
+	return report.Default(f), nil
+}
+
+// addReportFile adds the named file to b. Its errors leave out the
+// name, which the report gives beside them.
+func addReportFile(b *report.Builder, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		var pe *fs.PathError
+		if errors.As(err, &pe) {
+			return pe.Err
+		}
+		return err
+	}
+	defer f.Close()
+	if err := b.Add(name, f); err != nil {
+		return errors.Unwrap(err)
+	}
+	return nil
+}
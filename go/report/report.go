@@ -0,0 +1,165 @@
+// Package report renders word-count analyses, the overall figures, the
+// most frequent words and a breakdown per file, through templates:
+// Markdown through text/template and HTML through html/template, with
+// built-in templates for both or ones read from files.
+package report
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"example.com/tutorial/text"
+)
+
+// Format is the markup a template produces.
+type Format string
+
+const (
+	// Markdown is rendered with text/template, without escaping.
+	Markdown Format = "markdown"
+	// HTML is rendered with html/template, which escapes by context.
+	HTML Format = "html"
+)
+
+// Formats lists the supported formats.
+var Formats = []Format{Markdown, HTML}
+
+// ParseFormat converts a format name such as a flag value into a Format.
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("report: unknown format %q (want markdown or html)", s)
+}
+
+// FormatOf returns the format of a file name by its extension: .md and
+// .markdown for Markdown, .html and .htm for HTML.
+func FormatOf(name string) (Format, bool) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".md", ".markdown":
+		return Markdown, true
+	case ".html", ".htm":
+		return HTML, true
+	}
+	return "", false
+}
+
+// File is the analysis of one file.
+type File struct {
+	Name  string
+	Stats text.TextStats
+	// Top lists the file's most frequent words.
+	Top []text.WordFreq
+}
+
+// Report is what templates render.
+type Report struct {
+	Title     string
+	Generated time.Time
+	// Total sums the files' figures; its UniqueWords, AvgWordLen and
+	// UniqueRatio are those of all the files' words together.
+	Total text.TextStats
+	// Top lists the most frequent words of all the files.
+	Top []text.WordFreq
+	// Files are in the order they were added.
+	Files []File
+	// Errors names the files that could not be read, and why.
+	Errors []string
+}
+
+// Builder accumulates files into a Report.
+type Builder struct {
+	title   string
+	top     int
+	now     func() time.Time
+	counts  map[string]int
+	letters float64 // total word length, for the mean
+	report  Report
+}
+
+// NewBuilder returns a builder of a report called title listing the top
+// most frequent words, overall and per file; -1 lists them all.
+func NewBuilder(title string, top int) *Builder {
+	return &Builder{title: title, top: top, now: time.Now, counts: map[string]int{}}
+}
+
+// Add reads r to the end and adds it to the report as the file name.
+// Its text is read once, for both its figures and its words.
+func (b *Builder) Add(name string, r io.Reader) error {
+	pr, pw := io.Pipe()
+	type counted struct {
+		counts map[string]int
+		err    error
+	}
+	done := make(chan counted, 1)
+	go func() {
+		counts, err := text.WordCountReader(pr)
+		// Unblock ReadStats if counting stopped early.
+		pr.CloseWithError(err)
+		done <- counted{counts, err}
+	}()
+	st, err := text.ReadStats(io.TeeReader(r, pw))
+	pw.CloseWithError(err)
+	c := <-done
+	if c.err != nil {
+		err = c.err
+	}
+	if err != nil {
+		return fmt.Errorf("report: %s: %w", name, err)
+	}
+	b.AddCounts(name, st, c.counts)
+	return nil
+}
+
+// AddCounts adds a file counted already: st are its figures and counts
+// its word frequencies.
+func (b *Builder) AddCounts(name string, st text.TextStats, counts map[string]int) {
+	b.report.Files = append(b.report.Files, File{Name: name, Stats: st, Top: text.TopWords(counts, b.top)})
+	t := &b.report.Total
+	t.Words += st.Words
+	t.Lines += st.Lines
+	t.Bytes += st.Bytes
+	t.Chars += st.Chars
+	b.letters += st.AvgWordLen * float64(st.Words)
+	for w, c := range counts {
+		b.counts[w] += c
+	}
+}
+
+// AddError records that the file name could not be analysed.
+func (b *Builder) AddError(name string, err error) {
+	b.report.Errors = append(b.report.Errors, fmt.Sprintf("%s: %v", name, err))
+}
+
+// Report returns the report of the files added so far.
+func (b *Builder) Report() *Report {
+	r := b.report
+	r.Title = b.title
+	r.Generated = b.now()
+	r.Files = append([]File(nil), r.Files...)
+	r.Errors = append([]string(nil), r.Errors...)
+	r.Total.UniqueWords = int64(len(b.counts))
+	if r.Total.Words > 0 {
+		r.Total.AvgWordLen = b.letters / float64(r.Total.Words)
+		r.Total.UniqueRatio = float64(r.Total.UniqueWords) / float64(r.Total.Words)
+	}
+	r.Top = text.TopWords(b.counts, b.top)
+	return &r
+}
+
+// SortFiles orders r's files by descending word count, then by name.
+func (r *Report) SortFiles() {
+	sort.SliceStable(r.Files, func(i, j int) bool {
+		a, b := r.Files[i], r.Files[j]
+		if a.Stats.Words != b.Stats.Words {
+			return a.Stats.Words > b.Stats.Words
+		}
+		return a.Name < b.Name
+	})
+}
@@ -0,0 +1,89 @@
+package report
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestParseFormat(t *testing.T) {
+	for _, f := range Formats {
+		if got, err := ParseFormat(string(f)); err != nil || got != f {
+			t.Errorf("ParseFormat(%q) = %q, %v", f, got, err)
+		}
+	}
+	if _, err := ParseFormat("pdf"); err == nil {
+		t.Error("ParseFormat(pdf) succeeded")
+	}
+	for name, want := range map[string]Format{"a.md": Markdown, "A.MARKDOWN": Markdown, "out.html": HTML, "x.htm": HTML, "a.txt": "", "md": ""} {
+		if got, _ := FormatOf(name); got != want {
+			t.Errorf("FormatOf(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	b := NewBuilder("Words", 2)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return now }
+	if err := b.Add("a.txt", strings.NewReader("the cat and the hat\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Add("b.txt", strings.NewReader("a cat\nthe end\n")); err != nil {
+		t.Fatal(err)
+	}
+	b.AddError("c.txt", errors.New("permission denied"))
+
+	r := b.Report()
+	if r.Title != "Words" || !r.Generated.Equal(now) {
+		t.Errorf("title %q, generated %v", r.Title, r.Generated)
+	}
+	if r.Total.Words != 9 || r.Total.Lines != 3 || r.Total.UniqueWords != 6 {
+		t.Errorf("total %+v", r.Total)
+	}
+	// 3+3+3+3+3 letters in a.txt and 1+3+3+3 in b.txt.
+	if got, want := r.Total.AvgWordLen, 25.0/9; got != want {
+		t.Errorf("AvgWordLen = %v, want %v", got, want)
+	}
+	if len(r.Top) != 2 || r.Top[0].Word != "the" || r.Top[0].Count != 3 || r.Top[1].Word != "cat" {
+		t.Errorf("top %v", r.Top)
+	}
+	if len(r.Files) != 2 || r.Files[0].Name != "a.txt" || r.Files[0].Stats.Words != 5 || len(r.Files[0].Top) != 2 {
+		t.Errorf("files %+v", r.Files)
+	}
+	if len(r.Errors) != 1 || r.Errors[0] != "c.txt: permission denied" {
+		t.Errorf("errors %q", r.Errors)
+	}
+
+	r.SortFiles()
+	if r.Files[0].Name != "a.txt" || b.Report().Files[1].Name != "b.txt" {
+		t.Error("SortFiles: wrong order, or changed the builder's files")
+	}
+}
+
+func TestBuilderReadError(t *testing.T) {
+	b := NewBuilder("", -1)
+	errRead := errors.New("disk on fire")
+	err := b.Add("a.txt", iotest.DataErrReader(iotest.ErrReader(errRead)))
+	if !errors.Is(err, errRead) || !strings.Contains(err.Error(), "a.txt") {
+		t.Fatalf("Add: %v", err)
+	}
+	if r := b.Report(); len(r.Files) != 0 {
+		t.Errorf("failed file added: %+v", r.Files)
+	}
+}
+
+func TestSortFiles(t *testing.T) {
+	r := &Report{Files: []File{{Name: "b"}, {Name: "c"}, {Name: "a"}}}
+	r.Files[1].Stats.Words = 7
+	r.SortFiles()
+	var names []string
+	for _, f := range r.Files {
+		names = append(names, f.Name)
+	}
+	if got := strings.Join(names, ","); got != "c,a,b" {
+		t.Errorf("order %s, want c,a,b", got)
+	}
+}
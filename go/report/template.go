@@ -0,0 +1,155 @@
+package report
+
+import (
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+
+	"example.com/tutorial/humanize"
+	"example.com/tutorial/text"
+)
+
+//go:embed templates
+var templateFiles embed.FS
+
+// defaults are the built-in templates, parsed once. They are embedded,
+// so a failure is a bug caught by the tests.
+var defaults = map[Format]*Template{
+	Markdown: mustParse(Markdown, "templates/report.md.tmpl"),
+	HTML:     mustParse(HTML, "templates/report.html.tmpl"),
+}
+
+func mustParse(f Format, name string) *Template {
+	src, err := templateFiles.ReadFile(name)
+	if err == nil {
+		var t *Template
+		if t, err = Parse(f, filepath.Base(name), string(src)); err == nil {
+			return t
+		}
+	}
+	panic(err)
+}
+
+// funcs are the functions templates may call besides the built-in
+// ones:
+//
+//	percent part whole  part as a percentage of whole, such as "12.5%"
+//	words freqs         a frequency list as "the (3), cat (2)"
+//	size n              a byte count as humanize.Bytes has it, "4.2 MiB"
+//	mdcell s            s escaped for a Markdown table cell
+var funcs = map[string]any{
+	"percent": percent,
+	"words":   words,
+	"size":    humanize.Bytes,
+	"mdcell":  mdcell,
+}
+
+// Template renders a Report. It is safe for concurrent use.
+type Template struct {
+	format Format
+	t      interface {
+		Execute(w io.Writer, data any) error
+	}
+}
+
+// Default returns the built-in template of format f, or nil for an
+// unknown format.
+func Default(f Format) *Template { return defaults[f] }
+
+// Parse parses src as a template of format f called name: with
+// html/template for HTML, so that the report's text is escaped, and
+// text/template for Markdown.
+func Parse(f Format, name, src string) (*Template, error) {
+	t := &Template{format: f}
+	var err error
+	switch f {
+	case Markdown:
+		t.t, err = texttemplate.New(name).Funcs(texttemplate.FuncMap(funcs)).Parse(src)
+	case HTML:
+		t.t, err = htmltemplate.New(name).Funcs(htmltemplate.FuncMap(funcs)).Parse(src)
+	default:
+		return nil, fmt.Errorf("report: unknown format %q", f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("report: %w", err)
+	}
+	return t, nil
+}
+
+// ParseFile parses the template file at path. Its format is f or, if f
+// is empty, that of its extension, ignoring a trailing .tmpl: a file
+// called custom.html.tmpl is an HTML template.
+func ParseFile(f Format, path string) (*Template, error) {
+	if f == "" {
+		var ok bool
+		if f, ok = FormatOf(strings.TrimSuffix(path, ".tmpl")); !ok {
+			return nil, fmt.Errorf("report: %s: cannot tell the format from the name", path)
+		}
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("report: %w", err)
+	}
+	return Parse(f, filepath.Base(path), string(src))
+}
+
+// Format returns the format the template produces.
+func (t *Template) Format() Format { return t.format }
+
+// Execute writes r rendered by t to w.
+func (t *Template) Execute(w io.Writer, r *Report) error {
+	if err := t.t.Execute(w, r); err != nil {
+		return fmt.Errorf("report: %w", err)
+	}
+	return nil
+}
+
+// percent takes any integers, as a word's Count is an int and a
+// total of words an int64.
+func percent(part, whole any) (string, error) {
+	p, err := toFloat(part)
+	if err != nil {
+		return "", err
+	}
+	w, err := toFloat(whole)
+	if err != nil || w == 0 {
+		return "0%", err
+	}
+	return fmt.Sprintf("%.1f%%", 100*p/w), nil
+}
+
+func toFloat(v any) (float64, error) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	}
+	return 0, fmt.Errorf("percent: %T is not a number", v)
+}
+
+func words(freqs []text.WordFreq) string {
+	var b strings.Builder
+	for i, f := range freqs {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%s (%d)", f.Word, f.Count)
+	}
+	return b.String()
+}
+
+// mdcell escapes the pipes of s, which would end the cell, and joins
+// its lines, which would end the row.
+func mdcell(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,132 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/tutorial/text"
+)
+
+func testReport() *Report {
+	b := NewBuilder("Words <&> more", 3)
+	b.now = func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) }
+	b.Add("a|b.txt", strings.NewReader("the cat and the hat"))
+	b.Add("<script>.txt", strings.NewReader("the end"))
+	b.AddError("gone.txt", os.ErrNotExist)
+	return b.Report()
+}
+
+func TestDefaultMarkdown(t *testing.T) {
+	var out strings.Builder
+	if err := Default(Markdown).Execute(&out, testReport()); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# Words <&> more\n",
+		"2 files: 7 words, 5 distinct",
+		"| the | 3 | 42.9% |\n",
+		`| a\|b.txt | 5 | 4 | 0 | 19 B | the (2), and (1), cat (1) |`,
+		"- gone.txt: file does not exist\n",
+		"_Generated 2024-05-01 12:00 UTC._\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "\n\n\n") {
+		t.Errorf("output has blank lines in a row:\n%s", out.String())
+	}
+}
+
+func TestDefaultHTML(t *testing.T) {
+	var out strings.Builder
+	if err := Default(HTML).Execute(&out, testReport()); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<title>Words &lt;&amp;&gt; more</title>",
+		`<tr><td>the</td><td class="n">3</td><td class="n">42.9%</td></tr>`,
+		"<td>&lt;script&gt;.txt</td>",
+		"<li>gone.txt: file does not exist</li>",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "<script>") {
+		t.Error("file name not escaped")
+	}
+}
+
+func TestDefaultEmpty(t *testing.T) {
+	for _, f := range Formats {
+		var out strings.Builder
+		if err := Default(f).Execute(&out, NewBuilder("Nothing", 10).Report()); err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+		if !strings.Contains(out.String(), "No files.") {
+			t.Errorf("%s: output lacks No files.:\n%s", f, out.String())
+		}
+	}
+	if Default("pdf") != nil {
+		t.Error("Default(pdf) is not nil")
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, src string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	src := `{{.Title}}: {{range .Top}}{{.Word}} {{percent .Count $.Total.Words}};{{end}}`
+
+	tmpl, err := ParseFile("", write("custom.html.tmpl", src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.Format() != HTML {
+		t.Errorf("format %q, want html", tmpl.Format())
+	}
+	r := &Report{Title: "a<b", Total: text.TextStats{Words: 4}, Top: []text.WordFreq{{Word: "x", Count: 1}}}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, r); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "a&lt;b: x 25.0%;"; got != want {
+		t.Errorf("html: %q, want %q", got, want)
+	}
+
+	tmpl, err = ParseFile(Markdown, write("custom.tmpl", src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := tmpl.Execute(&out, r); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "a<b: x 25.0%;"; got != want {
+		t.Errorf("markdown: %q, want %q", got, want)
+	}
+
+	for name, path := range map[string]string{
+		"no format":    write("custom.tmpl", src),
+		"missing":      filepath.Join(dir, "missing.md"),
+		"syntax error": write("bad.md", "{{.Title"),
+	} {
+		if _, err := ParseFile("", path); err == nil || !strings.HasPrefix(err.Error(), "report: ") {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+
+	tmpl, _ = ParseFile("", write("field.md", "{{.Nope}}"))
+	if err := tmpl.Execute(&out, r); err == nil {
+		t.Error("unknown field executed")
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunReport(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	os.WriteFile(a, []byte("the cat and the hat\n"), 0o644)
+	os.WriteFile(b, []byte("the end of the story of the cat\n"), 0o644)
+	cfg := defaultSettings().Report
+	run := func(dryRun bool, stdin string, args ...string) (string, string, int) {
+		t.Helper()
+		var out, errOut bytes.Buffer
+		code := runReport(context.Background(), cfg, dryRun, args, strings.NewReader(stdin), &out, &errOut)
+		return out.String(), errOut.String(), code
+	}
+
+	out, _, code := run(false, "", "-title", "Cats", "-top", "2", "-sort", a, b)
+	if code != 0 {
+		t.Fatalf("exit %d", code)
+	}
+	for _, want := range []string{"# Cats\n", "| the | 5 | 38.5% |\n", "| cat | 2 |"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "b.txt") > strings.Index(out, "a.txt") {
+		t.Errorf("-sort: b.txt, with more words, is not first:\n%s", out)
+	}
+
+	if out, _, _ := run(false, "one two two"); !strings.Contains(out, "| stdin | 3 | 2 |") {
+		t.Errorf("stdin:\n%s", out)
+	}
+
+	html := filepath.Join(dir, "report.html")
+	if out, _, code := run(true, "", "-o", html, a); code != 0 || out != "would write "+html+"\n" {
+		t.Fatalf("dry run: exit %d: %q", code, out)
+	}
+	if _, err := os.Stat(html); !os.IsNotExist(err) {
+		t.Fatalf("dry run wrote %s", html)
+	}
+	if out, _, code := run(false, "", "-o", html, a); code != 0 || out != "" {
+		t.Fatalf("-o: exit %d: %q", code, out)
+	}
+	if data, _ := os.ReadFile(html); !strings.HasPrefix(string(data), "<!DOCTYPE html>") {
+		t.Errorf("-o report.html is not HTML:\n%s", data)
+	}
+
+	tmpl := filepath.Join(dir, "words.html.tmpl")
+	os.WriteFile(tmpl, []byte("[{{.Title}}] {{len .Files}}"), 0o644)
+	if out, _, code := run(false, "", "-template", tmpl, "-title", "x<y", a); code != 0 || out != "[x&lt;y] 1" {
+		t.Errorf("template: exit %d: %q", code, out)
+	}
+	if out, _, _ := run(false, "", "-template", tmpl, "-format", "markdown", "-title", "x<y", a); out != "[x<y] 1" {
+		t.Errorf("markdown template: %q", out)
+	}
+}
+
+func TestRunReportErrors(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing.txt")
+	for _, tc := range []struct {
+		args []string
+		code int
+		msg  string
+	}{
+		{[]string{"-format", "pdf"}, 2, `unknown format "pdf"`},
+		{[]string{"-bogus"}, 2, "usage: report"},
+		{[]string{"-template", missing}, 1, "cannot tell the format"},
+		{[]string{"-template", missing + ".md"}, 1, "no such file"},
+		{[]string{missing}, 1, "report: " + missing + ": no such file or directory"},
+		{[]string{"-o", filepath.Join(missing, "out.md")}, 1, "report:"},
+	} {
+		var out, errOut bytes.Buffer
+		code := runReport(context.Background(), defaultSettings().Report, false, tc.args, strings.NewReader(""), &out, &errOut)
+		if code != tc.code || !strings.Contains(errOut.String(), tc.msg) {
+			t.Errorf("%q: exit %d, want %d: %s", tc.args, code, tc.code, errOut.String())
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// Package retry runs operations that may fail transiently, waiting
+// between attempts with capped exponential backoff and full jitter.
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"example.com/tutorial/clock"
+)
+
+// Defaults used for zero Policy fields.
+const (
+	DefaultMaxAttempts = 3
+	DefaultBaseDelay   = 100 * time.Millisecond
+	DefaultMaxDelay    = 5 * time.Second
+)
+
+// Policy says how often and how patiently to retry. The zero value
+// retries any error up to DefaultMaxAttempts times.
+type Policy struct {
+	// MaxAttempts bounds the number of calls, including the first; 1
+	// disables retries.
+	MaxAttempts int
+	// BaseDelay and MaxDelay shape the backoff between attempts; see
+	// Backoff.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Retryable classifies errors; nil means every error not marked
+	// Permanent is worth another attempt.
+	Retryable func(error) bool
+	// OnRetry, if set, is called before each retry with the number of
+	// the attempt that failed, the delay before the next one and why.
+	OnRetry func(attempt int, delay time.Duration, err error)
+
+	// Jitter and Sleep replace rand.Float64 and a timer, so that tests
+	// can make the delays deterministic and instant.
+	Jitter func() float64
+	Sleep  func(ctx context.Context, d time.Duration) error
+	// Clock waits between attempts when Sleep is nil; nil means
+	// clock.System.
+	Clock clock.Clock
+}
+
+// Do calls fn until it succeeds, fails with an error that is not
+// retryable, the attempts run out or ctx is done. It returns nil, the
+// last error from fn, or ctx's error if ctx ended a wait between
+// attempts. An error from fn is returned as is, without a Permanent or
+// After wrapper.
+func Do(ctx context.Context, p Policy, fn func(ctx context.Context) error) error {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultMaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = DefaultBaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = DefaultMaxDelay
+	}
+	if p.Jitter == nil {
+		p.Jitter = rand.Float64
+	}
+	if p.Sleep == nil {
+		p.Sleep = clock.OrSystem(p.Clock).Sleep
+	}
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		var perm *permanent
+		if errors.As(err, &perm) {
+			return perm.err
+		}
+		var hint *after
+		if errors.As(err, &hint) {
+			err = hint.err
+		}
+		if attempt >= p.MaxAttempts || ctx.Err() != nil || (p.Retryable != nil && !p.Retryable(err)) {
+			return err
+		}
+		delay := Backoff(attempt, p.BaseDelay, p.MaxDelay, p.Jitter)
+		if hint != nil && hint.d > delay {
+			delay = min(hint.d, p.MaxDelay)
+		}
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, delay, err)
+		}
+		if err := p.Sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// Backoff returns the delay before retry number attempt (1 for the first
+// retry): a uniformly random duration in [0, min(max, base*2^(attempt-1))),
+// the "full jitter" scheme, which spreads out clients that failed
+// together. rnd returns values in [0, 1), like rand.Float64.
+func Backoff(attempt int, base, max time.Duration, rnd func() float64) time.Duration {
+	ceil := base
+	for i := 1; i < attempt && ceil < max; i++ {
+		ceil *= 2
+	}
+	if ceil > max {
+		ceil = max
+	}
+	return time.Duration(rnd() * float64(ceil))
+}
+
+type permanent struct{ err error }
+
+func (e *permanent) Error() string { return e.err.Error() }
+func (e *permanent) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying, whatever the Policy's
+// Retryable says. Do returns err itself.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanent{err}
+}
+
+// IsPermanent reports whether err, or an error it wraps, was marked by
+// Permanent.
+func IsPermanent(err error) bool {
+	var perm *permanent
+	return errors.As(err, &perm)
+}
+
+type after struct {
+	err error
+	d   time.Duration
+}
+
+func (e *after) Error() string { return e.err.Error() }
+func (e *after) Unwrap() error { return e.err }
+
+// After asks Do to wait at least d, up to the Policy's MaxDelay, before
+// the next attempt, as a server's Retry-After header does.
+func After(err error, d time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &after{err, d}
+}
@@ -0,0 +1,174 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"example.com/tutorial/clock"
+)
+
+// instant returns a Policy that records its delays instead of sleeping,
+// with jitter fixed at half the ceiling.
+func instant(p Policy, delays *[]time.Duration) Policy {
+	p.Jitter = func() float64 { return 0.5 }
+	p.Sleep = func(ctx context.Context, d time.Duration) error {
+		*delays = append(*delays, d)
+		return ctx.Err()
+	}
+	return p
+}
+
+var errTransient = errors.New("transient")
+
+func TestDoSucceedsAfterRetries(t *testing.T) {
+	var delays []time.Duration
+	var retried []int
+	p := instant(Policy{
+		MaxAttempts: 5,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    time.Second,
+		OnRetry:     func(attempt int, _ time.Duration, _ error) { retried = append(retried, attempt) },
+	}, &delays)
+	calls := 0
+	err := Do(context.Background(), p, func(context.Context) error {
+		calls++
+		if calls < 4 {
+			return errTransient
+		}
+		return nil
+	})
+	if err != nil || calls != 4 {
+		t.Fatalf("err = %v after %d calls", err, calls)
+	}
+	want := []time.Duration{50 * time.Millisecond, 100 * time.Millisecond, 200 * time.Millisecond}
+	if len(delays) != 3 || delays[0] != want[0] || delays[1] != want[1] || delays[2] != want[2] {
+		t.Fatalf("delays %v, want %v", delays, want)
+	}
+	if len(retried) != 3 || retried[2] != 3 {
+		t.Fatalf("OnRetry attempts %v", retried)
+	}
+}
+
+func TestDoGivesUp(t *testing.T) {
+	var delays []time.Duration
+	calls := 0
+	err := Do(context.Background(), instant(Policy{}, &delays), func(context.Context) error {
+		calls++
+		return errTransient
+	})
+	if err != errTransient || calls != DefaultMaxAttempts {
+		t.Fatalf("err = %v after %d calls", err, calls)
+	}
+}
+
+func TestDoClassifies(t *testing.T) {
+	var delays []time.Duration
+	errFatal := errors.New("fatal")
+	p := instant(Policy{Retryable: func(err error) bool { return errors.Is(err, errTransient) }}, &delays)
+	calls := 0
+	err := Do(context.Background(), p, func(context.Context) error {
+		calls++
+		return errFatal
+	})
+	if err != errFatal || calls != 1 {
+		t.Fatalf("non-retryable: err = %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = Do(context.Background(), instant(Policy{}, &delays), func(context.Context) error {
+		calls++
+		return Permanent(errFatal)
+	})
+	if err != errFatal || calls != 1 {
+		t.Fatalf("permanent: err = %v after %d calls", err, calls)
+	}
+	if Permanent(nil) != nil || After(nil, time.Second) != nil {
+		t.Fatal("wrapping nil is not nil")
+	}
+	if !IsPermanent(fmt.Errorf("job 3: %w", Permanent(errFatal))) || IsPermanent(errFatal) {
+		t.Fatal("IsPermanent misclassifies")
+	}
+}
+
+func TestDoAfter(t *testing.T) {
+	var delays []time.Duration
+	p := instant(Policy{MaxAttempts: 3, BaseDelay: 10 * time.Millisecond, MaxDelay: time.Second}, &delays)
+	calls := 0
+	err := Do(context.Background(), p, func(context.Context) error {
+		calls++
+		if calls == 1 {
+			return After(errTransient, 300*time.Millisecond)
+		}
+		return After(errTransient, time.Minute)
+	})
+	if err != errTransient {
+		t.Fatalf("err = %v, want the unwrapped error", err)
+	}
+	if len(delays) != 2 || delays[0] != 300*time.Millisecond || delays[1] != time.Second {
+		t.Fatalf("delays %v, want 300ms then capped at 1s", delays)
+	}
+}
+
+func TestDoClock(t *testing.T) {
+	c := clock.NewFake(time.Unix(1000, 0))
+	p := Policy{MaxAttempts: 3, BaseDelay: time.Minute, Jitter: func() float64 { return 0.5 }, Clock: c}
+	calls := 0
+	done := make(chan error)
+	go func() {
+		done <- Do(context.Background(), p, func(context.Context) error {
+			calls++
+			return errTransient
+		})
+	}()
+	c.BlockUntil(1)
+	c.Advance(30 * time.Second)
+	c.BlockUntil(1)
+	c.Advance(time.Minute)
+	if err := <-done; err != errTransient || calls != 3 {
+		t.Fatalf("err = %v after %d calls", err, calls)
+	}
+	if got := c.Now(); !got.Equal(time.Unix(1090, 0)) {
+		t.Fatalf("waited until %v, want 90s of backoff", got)
+	}
+}
+
+func TestDoContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := clock.NewFake(time.Unix(1000, 0))
+	calls := 0
+	done := make(chan error)
+	go func() {
+		done <- Do(ctx, Policy{MaxAttempts: 10, BaseDelay: time.Hour, Clock: c}, func(context.Context) error {
+			calls++
+			return errTransient
+		})
+	}()
+	c.BlockUntil(1)
+	cancel()
+	if err := <-done; err != context.Canceled || calls != 1 {
+		t.Fatalf("err = %v after %d calls, want context.Canceled from the wait", err, calls)
+	}
+
+	// A failure once ctx is done is returned without waiting.
+	calls = 0
+	err := Do(ctx, Policy{}, func(context.Context) error {
+		calls++
+		return errTransient
+	})
+	if err != errTransient || calls != 1 {
+		t.Fatalf("err = %v after %d calls", err, calls)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	one := func() float64 { return 0.999999 }
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 5: time.Second, 50: time.Second} {
+		got := Backoff(attempt, 100*time.Millisecond, time.Second, one)
+		if got > want || got < want*99/100 {
+			t.Errorf("Backoff(%d) = %v, want just under %v", attempt, got, want)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// Package router is a small HTTP request router with path parameters and
+// method matching, for the Go versions whose http.ServeMux has neither.
+//
+// Patterns are slash-separated paths whose segments are either literal,
+// a parameter "{name}" matching any one non-empty segment, or, as the
+// last segment, "{name...}" matching the rest of the path. When several
+// patterns match, the one with literals earliest wins, so "/users/me"
+// takes precedence over "/users/{id}" regardless of registration order.
+package router
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Router dispatches requests to the handler registered for their method
+// and path. The zero value is ready to use.
+type Router struct {
+	// NotFound handles requests whose path matches no route; nil means
+	// http.NotFound.
+	NotFound http.Handler
+	// MethodNotAllowed handles requests whose path matches a route but
+	// not for their method. The Allow header is set before it is called.
+	// nil means a plain-text 405 response.
+	MethodNotAllowed http.Handler
+
+	routes []route
+}
+
+// New returns an empty Router.
+func New() *Router { return &Router{} }
+
+type segKind int
+
+// Kinds in decreasing order of precedence.
+const (
+	segLiteral segKind = iota
+	segParam
+	segRest
+)
+
+type segment struct {
+	kind segKind
+	text string // the literal, or the parameter name
+}
+
+type route struct {
+	method  string
+	pattern string
+	segs    []segment
+	h       http.Handler
+}
+
+// Handle registers h for requests with the given method and a path
+// matching pattern. A GET route also serves HEAD unless HEAD is
+// registered separately. Handle panics if the pattern is malformed or
+// already registered for method.
+func (rt *Router) Handle(method, pattern string, h http.Handler) {
+	segs, err := parsePattern(pattern)
+	if err != nil {
+		panic(fmt.Sprintf("router: %s: %v", pattern, err))
+	}
+	for _, r := range rt.routes {
+		if r.method == method && sameShape(r.segs, segs) {
+			panic(fmt.Sprintf("router: %s %s conflicts with %s", method, pattern, r.pattern))
+		}
+	}
+	rt.routes = append(rt.routes, route{method: method, pattern: pattern, segs: segs, h: h})
+}
+
+// HandleFunc registers f like Handle.
+func (rt *Router) HandleFunc(method, pattern string, f func(http.ResponseWriter, *http.Request)) {
+	rt.Handle(method, pattern, http.HandlerFunc(f))
+}
+
+func parsePattern(pattern string) ([]segment, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		return nil, fmt.Errorf("must start with /")
+	}
+	parts := strings.Split(pattern[1:], "/")
+	segs := make([]segment, len(parts))
+	seen := map[string]bool{}
+	for i, p := range parts {
+		if !strings.HasPrefix(p, "{") {
+			if strings.ContainsAny(p, "{}") {
+				return nil, fmt.Errorf("braces in literal segment %q", p)
+			}
+			segs[i] = segment{kind: segLiteral, text: p}
+			continue
+		}
+		if !strings.HasSuffix(p, "}") {
+			return nil, fmt.Errorf("unterminated parameter %q", p)
+		}
+		name, kind := p[1:len(p)-1], segParam
+		if strings.HasSuffix(name, "...") {
+			if i != len(parts)-1 {
+				return nil, fmt.Errorf("%s must be the last segment", p)
+			}
+			name, kind = strings.TrimSuffix(name, "..."), segRest
+		}
+		if name == "" || strings.ContainsAny(name, "{}") {
+			return nil, fmt.Errorf("bad parameter %q", p)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate parameter %q", name)
+		}
+		seen[name] = true
+		segs[i] = segment{kind: kind, text: name}
+	}
+	return segs, nil
+}
+
+// sameShape reports whether a and b match exactly the same paths.
+func sameShape(a, b []segment) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].kind != b[i].kind || a[i].kind == segLiteral && a[i].text != b[i].text {
+			return false
+		}
+	}
+	return true
+}
+
+// match reports whether path segments parts match segs and returns the
+// parameter values.
+func match(segs []segment, parts []string) (map[string]string, bool) {
+	var params map[string]string
+	set := func(name, value string) {
+		if params == nil {
+			params = map[string]string{}
+		}
+		params[name] = value
+	}
+	for i, s := range segs {
+		if s.kind == segRest {
+			set(s.text, strings.Join(parts[i:], "/"))
+			return params, true
+		}
+		if i >= len(parts) {
+			return nil, false
+		}
+		switch s.kind {
+		case segLiteral:
+			if parts[i] != s.text {
+				return nil, false
+			}
+		case segParam:
+			if parts[i] == "" {
+				return nil, false
+			}
+			set(s.text, parts[i])
+		}
+	}
+	return params, len(parts) == len(segs)
+}
+
+// moreSpecific reports whether a should win over b when both match.
+func moreSpecific(a, b []segment) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i].kind != b[i].kind {
+			return a[i].kind < b[i].kind
+		}
+	}
+	return len(a) > len(b)
+}
+
+// ServeHTTP implements http.Handler.
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+
+	// The best matching route for each method that matches the path.
+	type hit struct {
+		r      *route
+		params map[string]string
+	}
+	best := map[string]hit{}
+	for i := range rt.routes {
+		rte := &rt.routes[i]
+		params, ok := match(rte.segs, parts)
+		if !ok {
+			continue
+		}
+		if b, ok := best[rte.method]; !ok || moreSpecific(rte.segs, b.r.segs) {
+			best[rte.method] = hit{rte, params}
+		}
+	}
+	if len(best) == 0 {
+		if rt.NotFound != nil {
+			rt.NotFound.ServeHTTP(w, r)
+		} else {
+			http.NotFound(w, r)
+		}
+		return
+	}
+
+	h, ok := best[r.Method]
+	if !ok && r.Method == http.MethodHead {
+		h, ok = best[http.MethodGet]
+	}
+	if !ok {
+		w.Header().Set("Allow", allow(best))
+		if rt.MethodNotAllowed != nil {
+			rt.MethodNotAllowed.ServeHTTP(w, r)
+		} else {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}
+		return
+	}
+	if h.params != nil {
+		r = r.WithContext(context.WithValue(r.Context(), paramsKey{}, h.params))
+	}
+	h.r.h.ServeHTTP(w, r)
+}
+
+// allow lists the methods of the matched routes for the Allow header.
+func allow[V any](methods map[string]V) string {
+	list := make([]string, 0, len(methods)+1)
+	for m := range methods {
+		list = append(list, m)
+	}
+	if _, get := methods[http.MethodGet]; get {
+		if _, head := methods[http.MethodHead]; !head {
+			list = append(list, http.MethodHead)
+		}
+	}
+	sort.Strings(list)
+	return strings.Join(list, ", ")
+}
+
+type paramsKey struct{}
+
+// Param returns the value of the named path parameter of the route that
+// matched r, or "" if there is none.
+func Param(r *http.Request, name string) string {
+	params, _ := r.Context().Value(paramsKey{}).(map[string]string)
+	return params[name]
+}
@@ -0,0 +1,131 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// echo responds with the route name and its parameters.
+func echo(name string, params ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, name)
+		for _, p := range params {
+			fmt.Fprintf(w, " %s=%s", p, Param(r, p))
+		}
+	}
+}
+
+func serve(h http.Handler, method, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+func TestRouting(t *testing.T) {
+	rt := New()
+	rt.Handle("GET", "/", echo("root"))
+	rt.Handle("GET", "/users", echo("list"))
+	rt.Handle("POST", "/users", echo("create"))
+	rt.Handle("GET", "/users/{id}", echo("get", "id"))
+	rt.Handle("GET", "/users/me", echo("me"))
+	rt.Handle("DELETE", "/users/{id}", echo("delete", "id"))
+	rt.Handle("GET", "/users/{id}/posts/{post}", echo("post", "id", "post"))
+	rt.Handle("GET", "/static/{path...}", echo("static", "path"))
+
+	tests := []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{"GET", "/", 200, "root"},
+		{"GET", "/users", 200, "list"},
+		{"POST", "/users", 200, "create"},
+		{"GET", "/users/42", 200, "get id=42"},
+		{"GET", "/users/me", 200, "me"},
+		{"DELETE", "/users/me", 200, "delete id=me"},
+		{"GET", "/users/7/posts/x", 200, "post id=7 post=x"},
+		{"GET", "/static/css/site.css", 200, "static path=css/site.css"},
+		{"HEAD", "/users/1", 200, "get id=1"},
+		{"GET", "/users/", 404, ""},
+		{"GET", "/users/1/extra", 404, ""},
+		{"GET", "/nope", 404, ""},
+		{"PUT", "/users", 405, ""},
+		{"PATCH", "/users/1", 405, ""},
+	}
+	for _, tt := range tests {
+		rec := serve(rt, tt.method, tt.path)
+		if rec.Code != tt.code {
+			t.Errorf("%s %s: status %d, want %d", tt.method, tt.path, rec.Code, tt.code)
+			continue
+		}
+		if tt.code == 200 && rec.Body.String() != tt.body {
+			t.Errorf("%s %s: body %q, want %q", tt.method, tt.path, rec.Body, tt.body)
+		}
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	rt := New()
+	rt.HandleFunc("GET", "/users/{id}", echo("get"))
+	rt.HandleFunc("DELETE", "/users/{id}", echo("delete"))
+	rec := serve(rt, "POST", "/users/1")
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status %d", rec.Code)
+	}
+	if got, want := rec.Header().Get("Allow"), "DELETE, GET, HEAD"; got != want {
+		t.Fatalf("Allow = %q, want %q", got, want)
+	}
+
+	rt.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	rt.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+	if rec := serve(rt, "POST", "/users/1"); rec.Code != http.StatusTeapot || rec.Header().Get("Allow") == "" {
+		t.Fatalf("custom 405: %d %q", rec.Code, rec.Header().Get("Allow"))
+	}
+	if rec := serve(rt, "GET", "/other"); rec.Code != http.StatusGone {
+		t.Fatalf("custom 404: %d", rec.Code)
+	}
+}
+
+func TestParamOutsideRoute(t *testing.T) {
+	if got := Param(httptest.NewRequest("GET", "/", nil), "id"); got != "" {
+		t.Fatalf("Param = %q", got)
+	}
+}
+
+func TestBadPatterns(t *testing.T) {
+	for _, p := range []string{
+		"users",
+		"/users/{id",
+		"/users/{}",
+		"/a/{x}/b/{x}",
+		"/files/{rest...}/more",
+		"/a{b}",
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Handle(%q) did not panic", p)
+				}
+			}()
+			New().Handle("GET", p, echo("x"))
+		}()
+	}
+}
+
+func TestConflictingRoutes(t *testing.T) {
+	rt := New()
+	rt.Handle("GET", "/users/{id}", echo("a"))
+	rt.Handle("PUT", "/users/{id}", echo("b")) // different method is fine
+	defer func() {
+		if recover() == nil {
+			t.Fatal("duplicate route did not panic")
+		}
+	}()
+	rt.Handle("GET", "/users/{name}", echo("c"))
+}
@@ -0,0 +1,114 @@
+// Package rpc implements the gRPC services defined in package pb.
+package rpc
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"example.com/tutorial/pb"
+	"example.com/tutorial/pbcodec"
+	"example.com/tutorial/user"
+	"example.com/tutorial/validate"
+)
+
+// UserService implements pb.UserServiceServer on top of a user.Store, so
+// the gRPC and HTTP APIs can share one store.
+type UserService struct {
+	pb.UnimplementedUserServiceServer
+	users user.Store
+}
+
+// NewUserService returns a service backed by users.
+func NewUserService(users user.Store) *UserService {
+	return &UserService{users: users}
+}
+
+// statusError maps store and validation errors to gRPC status codes.
+func statusError(err error) error {
+	var verr validate.Errors
+	switch {
+	case errors.As(err, &verr):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, user.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, user.ErrExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// CreateUser implements pb.UserServiceServer.
+func (s *UserService) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.User, error) {
+	u := user.User{Name: req.GetName()}
+	if req.GetPassword() != "" {
+		if err := u.SetPassword(req.GetPassword()); err != nil {
+			return nil, statusError(err)
+		}
+	}
+	u, err := s.users.Create(ctx, u)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return pbcodec.UserToProto(u), nil
+}
+
+// GetUser implements pb.UserServiceServer.
+func (s *UserService) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.User, error) {
+	u, err := s.users.Get(ctx, req.GetId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return pbcodec.UserToProto(u), nil
+}
+
+// UpdateUser implements pb.UserServiceServer.
+func (s *UserService) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.User, error) {
+	u, err := s.users.Get(ctx, req.GetId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	u.Name = req.GetName()
+	if req.GetPassword() != "" {
+		if err := u.SetPassword(req.GetPassword()); err != nil {
+			return nil, statusError(err)
+		}
+	}
+	if err := s.users.Update(ctx, u); err != nil {
+		return nil, statusError(err)
+	}
+	return pbcodec.UserToProto(u), nil
+}
+
+// DeleteUser implements pb.UserServiceServer.
+func (s *UserService) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest) (*pb.DeleteUserResponse, error) {
+	if err := s.users.Delete(ctx, req.GetId()); err != nil {
+		return nil, statusError(err)
+	}
+	return &pb.DeleteUserResponse{}, nil
+}
+
+// ListUsers implements pb.UserServiceServer.
+func (s *UserService) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	page, err := s.users.List(ctx, user.ListQuery{
+		NamePrefix: req.GetNamePrefix(),
+		Sort:       user.SortField(req.GetSort()),
+		Desc:       req.GetDesc(),
+		Offset:     int(req.GetOffset()),
+		Limit:      int(req.GetLimit()),
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	resp := &pb.ListUsersResponse{Total: int32(page.Total)}
+	for _, u := range page.Users {
+		resp.Users = append(resp.Users, pbcodec.UserToProto(u))
+	}
+	return resp, nil
+}
@@ -0,0 +1,104 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"example.com/tutorial/pb"
+	"example.com/tutorial/user"
+)
+
+// startServer serves a UserService on a loopback port and returns a
+// client connected to it.
+func startServer(t *testing.T, store user.Store) pb.UserServiceClient {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	pb.RegisterUserServiceServer(srv, NewUserService(store))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewUserServiceClient(conn)
+}
+
+func TestUserServiceIntegration(t *testing.T) {
+	ctx := context.Background()
+	store := user.NewMemoryStore()
+	c := startServer(t, store)
+
+	ada, err := c.CreateUser(ctx, &pb.CreateUserRequest{Name: "Ada", Password: "analytical engine"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ada.GetId() == "" || ada.GetName() != "Ada" {
+		t.Fatalf("created %v", ada)
+	}
+	// The store is shared, so writes over gRPC are visible to other APIs.
+	if u, err := store.Get(ctx, ada.GetId()); err != nil || !u.CheckPassword("analytical engine") {
+		t.Fatalf("store has %+v, %v", u, err)
+	}
+	c.CreateUser(ctx, &pb.CreateUserRequest{Name: "Grace"})
+
+	got, err := c.GetUser(ctx, &pb.GetUserRequest{Id: ada.GetId()})
+	if err != nil || got.GetName() != "Ada" {
+		t.Fatalf("GetUser = %v, %v", got, err)
+	}
+	upd, err := c.UpdateUser(ctx, &pb.UpdateUserRequest{Id: ada.GetId(), Name: "Ada Lovelace"})
+	if err != nil || upd.GetName() != "Ada Lovelace" {
+		t.Fatalf("UpdateUser = %v, %v", upd, err)
+	}
+	list, err := c.ListUsers(ctx, &pb.ListUsersRequest{Sort: "name", Desc: true, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.GetTotal() != 2 || len(list.GetUsers()) != 1 || list.GetUsers()[0].GetName() != "Grace" {
+		t.Fatalf("ListUsers = %v", list)
+	}
+	if _, err := c.DeleteUser(ctx, &pb.DeleteUserRequest{Id: ada.GetId()}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetUser(ctx, &pb.GetUserRequest{Id: ada.GetId()}); status.Code(err) != codes.NotFound {
+		t.Fatalf("GetUser after delete: %v", err)
+	}
+}
+
+func TestUserServiceErrors(t *testing.T) {
+	ctx := context.Background()
+	c := startServer(t, user.NewMemoryStore())
+	tests := []struct {
+		name string
+		call func() error
+		want codes.Code
+	}{
+		{"blank name", func() error { _, err := c.CreateUser(ctx, &pb.CreateUserRequest{}); return err }, codes.InvalidArgument},
+		{"short password", func() error {
+			_, err := c.CreateUser(ctx, &pb.CreateUserRequest{Name: "x", Password: "x"})
+			return err
+		}, codes.InvalidArgument},
+		{"missing user", func() error { _, err := c.GetUser(ctx, &pb.GetUserRequest{Id: "42"}); return err }, codes.NotFound},
+		{"update missing", func() error {
+			_, err := c.UpdateUser(ctx, &pb.UpdateUserRequest{Id: "42", Name: "x"})
+			return err
+		}, codes.NotFound},
+		{"bad sort", func() error { _, err := c.ListUsers(ctx, &pb.ListUsersRequest{Sort: "email"}); return err }, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		if got := status.Code(tt.call()); got != tt.want {
+			t.Errorf("%s: code %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Package rungroup runs the long-lived components of a program, such as
+// servers and background workers, together: they start together, and
+// when one fails, or the program is told to stop, they stop together.
+package rungroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultStopTimeout is how long each component gets to stop when
+// Group.StopTimeout is zero.
+const DefaultStopTimeout = 10 * time.Second
+
+// Group is a set of components run by Run. The zero value is an empty
+// group ready to use.
+type Group struct {
+	// StopTimeout bounds the time each component gets to stop; zero
+	// means DefaultStopTimeout.
+	StopTimeout time.Duration
+
+	comps []*component
+}
+
+type component struct {
+	name        string
+	start, stop func(context.Context) error
+	done        chan struct{} // closed when start returns
+}
+
+// Add registers a component. start runs it and may block for as long as
+// it runs, as a server's Serve does, or return at once after starting
+// work in the background; a non-nil error from it fails the group. stop
+// asks the component to finish and should return once it has, or when
+// its context ends; it is called even if start has already returned.
+// A nil stop means the component stops when the context given to start
+// is canceled, which happens after stop in any case.
+//
+// Add must not be called during Run.
+func (g *Group) Add(name string, start, stop func(ctx context.Context) error) {
+	g.comps = append(g.comps, &component{name: name, start: start, stop: stop})
+}
+
+// Run starts every component, in the order added, and waits until ctx
+// is done or a start fails. It then stops the components in reverse
+// order, giving each StopTimeout, and returns.
+//
+// Run returns nil if the group stopped because ctx was done and every
+// component stopped cleanly. Otherwise it returns the first start
+// failure joined with the errors of the components that failed or
+// timed out stopping.
+func (g *Group) Run(ctx context.Context) error {
+	timeout := g.StopTimeout
+	if timeout <= 0 {
+		timeout = DefaultStopTimeout
+	}
+	failed := make(chan error, len(g.comps))
+	cancels := make([]context.CancelFunc, len(g.comps))
+	for i, c := range g.comps {
+		c.done = make(chan struct{})
+		var cctx context.Context
+		cctx, cancels[i] = context.WithCancel(ctx)
+		go func(c *component, ctx context.Context) {
+			defer close(c.done)
+			if err := c.start(ctx); err != nil {
+				failed <- fmt.Errorf("rungroup: %s: %w", c.name, err)
+			}
+		}(c, cctx)
+	}
+
+	var errs []error
+	select {
+	case <-ctx.Done():
+	case err := <-failed:
+		errs = append(errs, err)
+	}
+	for i := len(g.comps) - 1; i >= 0; i-- {
+		if err := g.comps[i].shutdown(cancels[i], timeout); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// shutdown calls c.stop, cancels c's context and waits for start to
+// return, all within timeout.
+func (c *component) shutdown(cancel context.CancelFunc, timeout time.Duration) error {
+	ctx, cancelTimeout := context.WithTimeout(context.Background(), timeout)
+	defer cancelTimeout()
+	stopped := make(chan error, 1)
+	go func() {
+		var err error
+		if c.stop != nil {
+			err = c.stop(ctx)
+		}
+		cancel()
+		stopped <- err
+	}()
+	select {
+	case err := <-stopped:
+		if err != nil {
+			return fmt.Errorf("rungroup: stopping %s: %w", c.name, err)
+		}
+	case <-ctx.Done():
+		cancel()
+		return fmt.Errorf("rungroup: stopping %s: %w", c.name, ctx.Err())
+	}
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("rungroup: stopping %s: %w", c.name, ctx.Err())
+	}
+}
@@ -0,0 +1,162 @@
+package rungroup
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder logs the events of the components it makes.
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) log(e string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func (r *recorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.events, " ")
+}
+
+// blocking returns a component that runs until stopped.
+func (r *recorder) blocking(name string) (start, stop func(context.Context) error) {
+	quit := make(chan struct{})
+	start = func(context.Context) error {
+		<-quit
+		r.log(name + " returned")
+		return nil
+	}
+	stop = func(context.Context) error {
+		r.log("stop " + name)
+		close(quit)
+		return nil
+	}
+	return start, stop
+}
+
+func result(t *testing.T, done <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return")
+		return nil
+	}
+}
+
+func TestStopsInReverseOrder(t *testing.T) {
+	var r recorder
+	var g Group
+	for _, name := range []string{"a", "b", "c"} {
+		start, stop := r.blocking(name)
+		g.Add(name, start, stop)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runAsync(&g, ctx)
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := result(t, done); err != nil {
+		t.Fatalf("Run = %v", err)
+	}
+	if got, want := r.String(), "stop c c returned stop b b returned stop a a returned"; got != want {
+		t.Fatalf("events %q, want %q", got, want)
+	}
+}
+
+func TestFirstFailureStopsTheRest(t *testing.T) {
+	var r recorder
+	var g Group
+	start, stop := r.blocking("server")
+	g.Add("server", start, stop)
+	boom := errors.New("boom")
+	g.Add("worker", func(context.Context) error {
+		time.Sleep(5 * time.Millisecond)
+		return boom
+	}, nil)
+	err := result(t, runAsync(&g, context.Background()))
+	if !errors.Is(err, boom) || !strings.Contains(err.Error(), "rungroup: worker: boom") {
+		t.Fatalf("Run = %v", err)
+	}
+	if got := r.String(); got != "stop server server returned" {
+		t.Fatalf("events %q", got)
+	}
+}
+
+func TestNilStopCancelsContext(t *testing.T) {
+	var g Group
+	canceled := make(chan struct{})
+	g.Add("watcher", func(ctx context.Context) error {
+		<-ctx.Done()
+		close(canceled)
+		return nil
+	}, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runAsync(&g, ctx)
+	cancel()
+	if err := result(t, done); err != nil {
+		t.Fatalf("Run = %v", err)
+	}
+	<-canceled
+}
+
+func TestBackgroundStartReturnsAtOnce(t *testing.T) {
+	var r recorder
+	var g Group
+	g.Add("sched", func(context.Context) error {
+		r.log("started")
+		return nil
+	}, func(context.Context) error {
+		r.log("stopped")
+		return nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := result(t, runAsync(&g, ctx)); err != nil {
+		t.Fatalf("Run = %v", err)
+	}
+	if got := r.String(); got != "started stopped" {
+		t.Fatalf("events %q", got)
+	}
+}
+
+func TestStopTimeout(t *testing.T) {
+	var r recorder
+	g := Group{StopTimeout: 20 * time.Millisecond}
+	start, stop := r.blocking("first")
+	g.Add("first", start, stop)
+	g.Add("stuck", func(context.Context) error { select {} }, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	g.Add("failing", func(context.Context) error { return nil }, func(context.Context) error {
+		return errors.New("flush failed")
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runAsync(&g, ctx)
+	cancel()
+	err := result(t, done)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "stopping stuck") ||
+		!strings.Contains(err.Error(), "stopping failing: flush failed") {
+		t.Fatalf("Run = %v", err)
+	}
+	// The stuck component does not keep the others from stopping.
+	if got := r.String(); got != "stop first first returned" {
+		t.Fatalf("events %q", got)
+	}
+}
+
+func runAsync(g *Group, ctx context.Context) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- g.Run(ctx) }()
+	return done
+}
@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	// Wednesday 2024-01-10 10:17:30 UTC.
+	from := time.Date(2024, 1, 10, 10, 17, 30, 0, time.UTC)
+	for _, tc := range []struct {
+		spec string
+		want string
+	}{
+		{"* * * * *", "2024-01-10 10:18"},
+		{"*/15 * * * *", "2024-01-10 10:30"},
+		{"5 * * * *", "2024-01-10 11:05"},
+		{"0 9-17/4 * * *", "2024-01-10 13:00"},
+		{"0 0 * * 0", "2024-01-14 00:00"},
+		{"0 0 * * 7", "2024-01-14 00:00"},
+		{"30 8 1,15 * *", "2024-01-15 08:30"},
+		{"0 0 29 2 *", "2024-02-29 00:00"},
+		{"0 12 13 * 5", "2024-01-12 12:00"}, // the 13th or a Friday
+		{"0 0 1 */6 *", "2024-07-01 00:00"},
+		{"@daily", "2024-01-11 00:00"},
+		{"@hourly", "2024-01-10 11:00"},
+		{"@yearly", "2025-01-01 00:00"},
+		{"@every 90s", "2024-01-10 10:19"},
+	} {
+		s, err := ParseCron(tc.spec)
+		if err != nil {
+			t.Errorf("ParseCron(%q): %v", tc.spec, err)
+			continue
+		}
+		if got := s.Next(from).Format("2006-01-02 15:04"); got != tc.want {
+			t.Errorf("%q: Next = %s, want %s", tc.spec, got, tc.want)
+		}
+	}
+}
+
+func TestCronNever(t *testing.T) {
+	s, err := ParseCron("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Next(time.Now()); !got.IsZero() {
+		t.Fatalf("Next = %v for February 30th", got)
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for spec, want := range map[string]string{
+		"* * * *":       "want 5 fields",
+		"60 * * * *":    "minute",
+		"* 24 * * *":    "hour",
+		"* * 0 * *":     "day of month",
+		"* * * 13 *":    "month",
+		"* * * * 8":     "day of week",
+		"5-1 * * * *":   "bad range",
+		"*/0 * * * *":   "bad step",
+		"x * * * *":     "not a number",
+		"@every soon":   "bad interval",
+		"@every -1m":    "bad interval",
+		"@fortnightly":  "want 5 fields",
+		"1,,2 * * * * ": "minute",
+	} {
+		_, err := ParseCron(spec)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseCron(%q) = %v, want an error about %s", spec, err, want)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Package scheduler runs jobs in the background on fixed intervals or
+// cron-style schedules.
+//
+// Each job runs in its own goroutine and never overlaps itself: the next
+// run is scheduled from when the previous one ended, so a run that
+// outlasts its interval delays the next rather than piling up. A job
+// that panics is reported like one that failed, and keeps its schedule.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"example.com/tutorial/clock"
+	"example.com/tutorial/logging"
+)
+
+// A Job is the work run on a schedule. Its context is canceled when the
+// scheduler is stopped and the grace period given to Stop runs out.
+type Job func(ctx context.Context) error
+
+var (
+	// ErrDuplicate is returned by Add for a name already in use.
+	ErrDuplicate = errors.New("scheduler: duplicate job name")
+	// ErrStopped is returned by Add after Stop.
+	ErrStopped = errors.New("scheduler: stopped")
+)
+
+// PanicError is reported for a job that panicked.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string { return fmt.Sprintf("scheduler: job panicked: %v", e.Value) }
+
+// Config holds the optional settings of a Scheduler.
+type Config struct {
+	// Jitter delays every run by a random duration below it, so that
+	// jobs sharing a schedule, or replicas sharing a job, do not all
+	// start at the same instant. Zero means no delay.
+	Jitter time.Duration
+	// OnError is called with the error of every failed or panicking
+	// run. Nil means logging it to Logger.
+	OnError func(job string, err error)
+	// Logger receives failed runs when OnError is nil; nil means
+	// slog.Default(). Its records have component "scheduler".
+	Logger *slog.Logger
+	// Clock schedules the runs; nil means clock.System.
+	Clock clock.Clock
+}
+
+// Scheduler runs jobs on their schedules between Start and Stop. It is
+// safe for concurrent use.
+type Scheduler struct {
+	cfg Config
+	rnd func(n int64) int64 // returns a value in [0, n)
+
+	mu       sync.Mutex
+	jobs     map[string]*job
+	ctx      context.Context // of the jobs, set by Start
+	kill     context.CancelFunc
+	stopping chan struct{} // closed by Stop
+	stopped  bool
+	wg       sync.WaitGroup
+}
+
+type job struct {
+	name  string
+	sched Schedule
+	fn    Job
+}
+
+// New returns a scheduler with no jobs.
+func New(cfg Config) *Scheduler {
+	cfg.Clock = clock.OrSystem(cfg.Clock)
+	if cfg.OnError == nil {
+		logger := logging.Component(cfg.Logger, "scheduler")
+		cfg.OnError = func(name string, err error) {
+			args := []any{"job", name, "err", err}
+			var p *PanicError
+			if errors.As(err, &p) {
+				args = append(args, "stack", string(p.Stack))
+			}
+			logger.Error("scheduled job failed", args...)
+		}
+	}
+	return &Scheduler{cfg: cfg, rnd: rand.Int63n, jobs: map[string]*job{}, stopping: make(chan struct{})}
+}
+
+// Add registers fn to run on sched under name. Jobs added after Start
+// are scheduled at once.
+func (s *Scheduler) Add(name string, sched Schedule, fn Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return ErrStopped
+	}
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("%w %q", ErrDuplicate, name)
+	}
+	j := &job{name: name, sched: sched, fn: fn}
+	s.jobs[name] = j
+	if s.ctx != nil {
+		s.start(j)
+	}
+	return nil
+}
+
+// Start begins running the jobs, with contexts derived from ctx. Calls
+// after the first, or after Stop, do nothing.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx != nil || s.stopped {
+		return
+	}
+	s.ctx, s.kill = context.WithCancel(ctx)
+	for _, j := range s.jobs {
+		s.start(j)
+	}
+}
+
+// start runs j's loop; s.mu must be held.
+func (s *Scheduler) start(j *job) {
+	s.wg.Add(1)
+	go s.loop(j)
+}
+
+func (s *Scheduler) loop(j *job) {
+	defer s.wg.Done()
+	for {
+		now := s.cfg.Clock.Now()
+		at := j.sched.Next(now)
+		if at.IsZero() {
+			return
+		}
+		if s.cfg.Jitter > 0 {
+			at = at.Add(time.Duration(s.rnd(int64(s.cfg.Jitter))))
+		}
+		t := s.cfg.Clock.NewTimer(at.Sub(now))
+		select {
+		case <-s.stopping:
+			t.Stop()
+			return
+		case <-s.ctx.Done():
+			t.Stop()
+			return
+		case <-t.C():
+		}
+		if err := s.run(j); err != nil {
+			s.cfg.OnError(j.name, err)
+		}
+	}
+}
+
+// run calls j.fn, turning a panic into a *PanicError. Its context names
+// the job to what it logs.
+func (s *Scheduler) run(j *job) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return j.fn(logging.With(s.ctx, "job", j.name))
+}
+
+// Stop stops scheduling runs and waits for the running ones to finish.
+// If ctx ends first, it cancels their contexts and returns ctx.Err()
+// without waiting any longer. Later calls of Stop only wait.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.stopped {
+		s.stopped = true
+		close(s.stopping)
+	}
+	kill := s.kill
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		if kill != nil {
+			kill()
+		}
+		return nil
+	case <-ctx.Done():
+		if kill != nil {
+			kill()
+		}
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,276 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"example.com/tutorial/clock"
+	"example.com/tutorial/logging"
+)
+
+// errs collects the errors a scheduler reports.
+type errs struct {
+	mu   sync.Mutex
+	list []error
+}
+
+func (e *errs) add(_ string, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.list = append(e.list, err)
+}
+
+func (e *errs) get() []error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]error(nil), e.list...)
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not reached")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// tick waits for the scheduler to set its timer, then moves the clock
+// on by d.
+func tick(c *clock.Fake, d time.Duration) {
+	c.BlockUntil(1)
+	c.Advance(d)
+}
+
+func TestRunsOnInterval(t *testing.T) {
+	c := clock.NewFake(time.Unix(1000, 0))
+	s := New(Config{Clock: c})
+	var runs atomic.Int32
+	if err := s.Add("tick", Every(5*time.Second), func(context.Context) error {
+		runs.Add(1)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add("tick", Every(time.Second), nil); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("duplicate Add = %v", err)
+	}
+	s.Start(context.Background())
+	tick(c, 4*time.Second)
+	tick(c, time.Second)
+	tick(c, 5*time.Second)
+	tick(c, 5*time.Second)
+	c.BlockUntil(1)
+	if n := runs.Load(); n != 3 {
+		t.Fatalf("%d runs in 15s at 5s intervals", n)
+	}
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	c.Advance(time.Hour)
+	if n := runs.Load(); n != 3 {
+		t.Fatal("ran after Stop")
+	}
+	if err := s.Add("late", Every(time.Second), nil); err != ErrStopped {
+		t.Fatalf("Add after Stop = %v", err)
+	}
+}
+
+func TestNoOverlap(t *testing.T) {
+	c := clock.NewFake(time.Unix(1000, 0))
+	s := New(Config{Clock: c})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var running, overlaps, runs atomic.Int32
+	s.Add("slow", Every(time.Second), func(context.Context) error {
+		if running.Add(1) > 1 {
+			overlaps.Add(1)
+		}
+		if runs.Add(1) == 1 {
+			close(started)
+			<-release
+		}
+		running.Add(-1)
+		return nil
+	})
+	s.Start(context.Background())
+	defer s.Stop(context.Background())
+	tick(c, time.Second)
+	<-started
+	// Intervals passing while the run lasts start nothing.
+	c.Advance(10 * time.Second)
+	close(release)
+	tick(c, time.Second)
+	c.BlockUntil(1)
+	if n := runs.Load(); n != 2 {
+		t.Fatalf("%d runs, want 2", n)
+	}
+	if n := overlaps.Load(); n != 0 {
+		t.Fatalf("%d overlapping runs", n)
+	}
+}
+
+func TestPanicIsolated(t *testing.T) {
+	var reported errs
+	s := New(Config{OnError: reported.add})
+	var runs, other atomic.Int32
+	s.Add("bad", Every(2*time.Millisecond), func(context.Context) error {
+		runs.Add(1)
+		panic("boom")
+	})
+	s.Add("failing", Every(2*time.Millisecond), func(context.Context) error { return errors.New("nope") })
+	s.Add("good", Every(2*time.Millisecond), func(context.Context) error {
+		other.Add(1)
+		return nil
+	})
+	s.Start(context.Background())
+	defer s.Stop(context.Background())
+	waitFor(t, func() bool { return runs.Load() >= 2 && other.Load() >= 2 && len(reported.get()) >= 4 })
+	var panics, failures int
+	for _, err := range reported.get() {
+		var p *PanicError
+		switch {
+		case errors.As(err, &p):
+			if p.Value != "boom" || len(p.Stack) == 0 {
+				t.Fatalf("panic error %+v", p)
+			}
+			panics++
+		case err.Error() == "nope":
+			failures++
+		}
+	}
+	if panics == 0 || failures == 0 {
+		t.Fatalf("%d panics and %d failures reported", panics, failures)
+	}
+}
+
+func TestStopWaitsForRunningJob(t *testing.T) {
+	s := New(Config{})
+	started := make(chan struct{})
+	var finished atomic.Bool
+	s.Add("job", Every(time.Millisecond), func(ctx context.Context) error {
+		if finished.Load() {
+			return nil
+		}
+		close(started)
+		time.Sleep(20 * time.Millisecond)
+		finished.Store(ctx.Err() == nil)
+		return nil
+	})
+	s.Start(context.Background())
+	<-started
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !finished.Load() {
+		t.Fatal("Stop returned before the run finished, or canceled it")
+	}
+}
+
+func TestStopGraceRunsOut(t *testing.T) {
+	s := New(Config{})
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	s.Add("stuck", Every(time.Millisecond), func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		close(canceled)
+		return ctx.Err()
+	})
+	s.Start(context.Background())
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Stop(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Stop = %v", err)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job context not canceled")
+	}
+}
+
+func TestJitter(t *testing.T) {
+	c := clock.NewFake(time.Unix(1000, 0))
+	s := New(Config{Jitter: time.Hour, Clock: c})
+	var asked atomic.Int64
+	s.rnd = func(n int64) int64 {
+		asked.Store(n)
+		return n - 1
+	}
+	var runs atomic.Int32
+	s.Add("job", Every(time.Second), func(context.Context) error {
+		runs.Add(1)
+		return nil
+	})
+	s.Start(context.Background())
+	defer s.Stop(context.Background())
+
+	// A draw just below the jitter delays the run by most of an hour.
+	tick(c, time.Hour)
+	c.BlockUntil(1)
+	if asked.Load() != int64(time.Hour) {
+		t.Fatalf("jitter drawn below %v", time.Duration(asked.Load()))
+	}
+	if runs.Load() != 0 {
+		t.Fatal("ran despite an hour of jitter")
+	}
+	c.Advance(time.Second)
+	c.BlockUntil(1)
+	if runs.Load() != 1 {
+		t.Fatal("did not run at its interval plus the jitter")
+	}
+}
+
+func TestAddAfterStart(t *testing.T) {
+	s := New(Config{})
+	s.Start(context.Background())
+	defer s.Stop(context.Background())
+	ran := make(chan struct{}, 1)
+	s.Add("late", Every(time.Millisecond), func(context.Context) error {
+		select {
+		case ran <- struct{}{}:
+		default:
+		}
+		return nil
+	})
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job added after Start never ran")
+	}
+}
+
+func TestJobContextNamesJob(t *testing.T) {
+	var logs bytes.Buffer
+	logger := logging.New(&logs, logging.Options{})
+	s := New(Config{})
+	done := make(chan struct{})
+	var once sync.Once
+	s.Add("sweep", Every(time.Millisecond), func(ctx context.Context) error {
+		once.Do(func() {
+			logger.InfoContext(ctx, "sweeping")
+			close(done)
+		})
+		return nil
+	})
+	s.Start(context.Background())
+	defer s.Stop(context.Background())
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job never ran")
+	}
+	if !strings.Contains(logs.String(), "msg=sweeping job=sweep") {
+		t.Fatalf("logged %q", logs.String())
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"example.com/tutorial/grep"
+	"example.com/tutorial/output"
+	"example.com/tutorial/progress"
+)
+
+// runSearch implements the "search" subcommand and returns the process
+// exit code, as grep(1) does: 0 if a line matched, 1 if none did and 2
+// on errors. It searches the current directory when given no path.
+func runSearch(ctx context.Context, cfg searchSettings, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: search [-i] [-w] [-C n] [-count] [-include glob] [-exclude glob] [-progress mode] regexp [path ...]")
+		fs.PrintDefaults()
+	}
+	opts := grep.Options{Include: slices.Clone(cfg.Include), Exclude: slices.Clone(cfg.Exclude)}
+	fs.Var((*patternList)(&opts.Include), "include", "search only files matching `glob` (repeatable)")
+	fs.Var((*patternList)(&opts.Exclude), "exclude", "skip files and directories matching `glob` (repeatable)")
+	fs.IntVar(&opts.Context, "C", cfg.Context, "print `n` lines of context around each match")
+	fs.BoolVar(&opts.Words, "w", false, "match whole words only")
+	fold := fs.Bool("i", cfg.IgnoreCase, "ignore case")
+	count := fs.Bool("count", false, "print only the number of matching lines per file")
+	mode := cfg.Progress
+	fs.TextVar(&mode, "progress", cfg.Progress, progressUsage)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	expr := fs.Arg(0)
+	if *fold {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		fmt.Fprintln(stderr, "search:", err)
+		return 2
+	}
+	paths := fs.Args()[1:]
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	opts.Progress = progress.NewTracker()
+	rep := progress.Start(stderr, opts.Progress, progress.Options{Mode: mode, Label: "search"})
+	defer rep.Stop()
+	out := output.For(rep.Bypass(stdout))
+	status := 1
+	failed, printed := false, false
+	for _, p := range paths {
+		files, err := grep.Search(ctx, p, re, opts)
+		if err != nil {
+			fmt.Fprintln(stderr, "search:", err)
+			failed = true
+		}
+		for _, f := range files {
+			status = 0
+			if *count {
+				fmt.Fprintf(out, "%s%s%d\n", out.Paint(f.Path, output.Magenta), out.Paint(":", output.Cyan), f.Matches)
+				continue
+			}
+			if opts.Context > 0 && printed {
+				fmt.Fprintln(out, out.Paint("--", output.Cyan))
+			}
+			printed = true
+			if err := writeMatches(out, &f, re); err != nil {
+				fmt.Fprintln(stderr, "search:", err)
+				return 2
+			}
+		}
+	}
+	if failed {
+		return 2
+	}
+	return status
+}
+
+// writeMatches writes the lines of f as f.WriteText does or, if out
+// colors text, in the colors of grep(1): the path magenta, line numbers
+// green, separators cyan and the text that re matches bold red.
+func writeMatches(out *output.Writer, f *grep.File, re *regexp.Regexp) error {
+	if !out.Color() {
+		return f.WriteText(out)
+	}
+	var b strings.Builder
+	for i, l := range f.Lines {
+		if i > 0 && l.Num != f.Lines[i-1].Num+1 {
+			b.WriteString(out.Paint("--", output.Cyan) + "\n")
+		}
+		sep, line := "-", l.Text
+		if l.Match {
+			sep = ":"
+			line = re.ReplaceAllStringFunc(line, func(m string) string { return out.Paint(m, output.Bold, output.Red) })
+		}
+		sep = out.Paint(sep, output.Cyan)
+		fmt.Fprintf(&b, "%s%s%s%s%s\n", out.Paint(f.Path, output.Magenta), sep, out.Paint(fmt.Sprint(l.Num), output.Green), sep, line)
+	}
+	_, err := io.WriteString(out, b.String())
+	return err
+}
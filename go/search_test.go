@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"example.com/tutorial/output"
+)
+
+func searchDir(t *testing.T) string {
+	t.Helper()
+	return writeTree(t, map[string]string{
+		"a.go":      "package a\n\n// TODO: tidy\nfunc A() {}\n",
+		"b.txt":     "todo list\nmilk\n",
+		"skip/c.go": "// TODO\n",
+		"sub/d.go":  "x\ny\nTODO TODO\n",
+	})
+}
+
+func TestRunSearch(t *testing.T) {
+	dir := searchDir(t)
+	a, b, d := filepath.Join(dir, "a.go"), filepath.Join(dir, "b.txt"), filepath.Join(dir, "sub", "d.go")
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-exclude", "skip", "TODO", dir}, a + ":3:// TODO: tidy\n" + d + ":3:TODO TODO\n"},
+		{[]string{"-i", "-count", "-include", "*.txt", "todo", dir}, b + ":1\n"},
+		{[]string{"-C", "1", "-exclude", "skip", "TODO", dir},
+			a + "-2-\n" + a + ":3:// TODO: tidy\n" + a + "-4-func A() {}\n--\n" + d + "-2-y\n" + d + ":3:TODO TODO\n"},
+		{[]string{"-w", "-i", "todo", b}, b + ":1:todo list\n"},
+	} {
+		var out, errOut bytes.Buffer
+		if code := runSearch(context.Background(), defaultSettings().Search, tc.args, &out, &errOut); code != 0 {
+			t.Fatalf("%v: exit %d: %s", tc.args, code, errOut.String())
+		}
+		if got := out.String(); got != tc.want {
+			t.Errorf("%v:\ngot  %q\nwant %q", tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestRunSearchExitCodes(t *testing.T) {
+	dir := searchDir(t)
+	var out, errOut bytes.Buffer
+	if code := runSearch(context.Background(), defaultSettings().Search, []string{"-w", "tid", dir}, &out, &errOut); code != 1 || out.Len() != 0 {
+		t.Fatalf("no match: exit %d: %s", code, out.String())
+	}
+	if code := runSearch(context.Background(), defaultSettings().Search, nil, &out, &errOut); code != 2 {
+		t.Fatalf("no pattern: exit %d", code)
+	}
+	if code := runSearch(context.Background(), defaultSettings().Search, []string{"(", dir}, &out, &errOut); code != 2 {
+		t.Fatalf("bad regexp: exit %d", code)
+	}
+	if code := runSearch(context.Background(), defaultSettings().Search, []string{"x", filepath.Join(dir, "missing")}, &out, &errOut); code != 2 {
+		t.Fatalf("missing path: exit %d", code)
+	}
+}
+
+func TestRunSearchColor(t *testing.T) {
+	d := filepath.Join(searchDir(t), "sub", "d.go")
+	var buf, errOut bytes.Buffer
+	out := output.New(&buf, output.Always)
+	if code := runSearch(context.Background(), defaultSettings().Search, []string{"-C", "1", "TODO", d}, out, &errOut); code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+	path, colon, dash := out.Paint(d, output.Magenta), out.Paint(":", output.Cyan), out.Paint("-", output.Cyan)
+	todo := out.Paint("TODO", output.Bold, output.Red)
+	want := path + dash + out.Paint("2", output.Green) + dash + "y\n" +
+		path + colon + out.Paint("3", output.Green) + colon + todo + " " + todo + "\n"
+	if buf.String() != want {
+		t.Fatalf("got  %q\nwant %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,128 @@
+// Package semaphore provides a weighted semaphore, for bounding the total
+// size of work in progress, such as the bytes of documents being counted
+// at once, rather than just the number of goroutines.
+package semaphore
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// Weighted is a semaphore with a fixed capacity from which callers take
+// and give back any number of units. Waiters are served strictly in the
+// order they arrived: a large request at the head of the queue holds up
+// smaller ones behind it even when they would fit, so it cannot be
+// starved by a stream of small ones. A Weighted is safe for concurrent
+// use.
+type Weighted struct {
+	size int64
+
+	mu      sync.Mutex
+	cur     int64
+	waiters list.List // of *waiter
+}
+
+type waiter struct {
+	n     int64
+	ready chan struct{} // closed when the units are granted
+}
+
+// NewWeighted returns a semaphore with n units, n > 0.
+func NewWeighted(n int64) *Weighted {
+	if n <= 0 {
+		panic("semaphore: size must be positive")
+	}
+	return &Weighted{size: n}
+}
+
+// Acquire takes n units, blocking until they are free or ctx is done. On
+// failure it returns ctx's error and takes nothing; a request for more
+// than the capacity fails only that way, as it can never be met.
+func (s *Weighted) Acquire(ctx context.Context, n int64) error {
+	s.mu.Lock()
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		s.mu.Unlock()
+		return nil
+	}
+	if n > s.size {
+		s.mu.Unlock()
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	w := &waiter{n: n, ready: make(chan struct{})}
+	elem := s.waiters.PushBack(w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		select {
+		case <-w.ready:
+			// Granted just as ctx was done; give the units back.
+			s.cur -= n
+			s.notify()
+		default:
+			front := s.waiters.Front() == elem
+			s.waiters.Remove(elem)
+			// Leaving from the head may let the next waiters in.
+			if front {
+				s.notify()
+			}
+		}
+		return ctx.Err()
+	}
+}
+
+// TryAcquire takes n units if they are free now and nobody is waiting,
+// and reports whether it did.
+func (s *Weighted) TryAcquire(n int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		return true
+	}
+	return false
+}
+
+// Release gives back n units taken by Acquire or TryAcquire. Releasing
+// more than are held is a bug and panics.
+func (s *Weighted) Release(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cur -= n
+	if s.cur < 0 {
+		panic("semaphore: released more than held")
+	}
+	s.notify()
+}
+
+// InUse returns the number of units currently held.
+func (s *Weighted) InUse() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cur
+}
+
+// notify grants units to waiters from the head of the queue for as long
+// as they fit.
+func (s *Weighted) notify() {
+	for {
+		e := s.waiters.Front()
+		if e == nil {
+			return
+		}
+		w := e.Value.(*waiter)
+		if s.size-s.cur < w.n {
+			return
+		}
+		s.cur += w.n
+		s.waiters.Remove(e)
+		close(w.ready)
+	}
+}
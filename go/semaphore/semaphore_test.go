@@ -0,0 +1,152 @@
+package semaphore
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// acquired reports whether ch is closed or receives within a short wait.
+func acquired(ch <-chan error) bool {
+	select {
+	case <-ch:
+		return true
+	case <-time.After(20 * time.Millisecond):
+		return false
+	}
+}
+
+func acquire(ctx context.Context, s *Weighted, n int64) <-chan error {
+	ch := make(chan error, 1)
+	go func() { ch <- s.Acquire(ctx, n) }()
+	return ch
+}
+
+func TestAcquireRelease(t *testing.T) {
+	s := NewWeighted(10)
+	ctx := context.Background()
+	if err := s.Acquire(ctx, 7); err != nil {
+		t.Fatal(err)
+	}
+	if !s.TryAcquire(3) || s.TryAcquire(1) {
+		t.Fatal("TryAcquire does not match the free units")
+	}
+	if s.InUse() != 10 {
+		t.Fatalf("InUse = %d", s.InUse())
+	}
+	ch := acquire(ctx, s, 5)
+	if acquired(ch) {
+		t.Fatal("acquired beyond capacity")
+	}
+	s.Release(7)
+	if !acquired(ch) {
+		t.Fatal("waiter not woken by Release")
+	}
+	if s.InUse() != 8 {
+		t.Fatalf("InUse = %d, want 8", s.InUse())
+	}
+}
+
+func TestFIFO(t *testing.T) {
+	s := NewWeighted(4)
+	ctx := context.Background()
+	s.Acquire(ctx, 4)
+	big := acquire(ctx, s, 4)
+	time.Sleep(10 * time.Millisecond) // big is queued first
+	small := acquire(ctx, s, 1)
+	if s.TryAcquire(1) {
+		t.Fatal("TryAcquire jumped the queue")
+	}
+	s.Release(1)
+	if acquired(small) {
+		t.Fatal("small waiter overtook the big one at the head")
+	}
+	s.Release(3)
+	if !acquired(big) {
+		t.Fatal("big waiter not served")
+	}
+	s.Release(4)
+	if !acquired(small) {
+		t.Fatal("small waiter not served after the big one")
+	}
+}
+
+func TestCancelWhileQueued(t *testing.T) {
+	s := NewWeighted(4)
+	s.Acquire(context.Background(), 3)
+	ctx, cancel := context.WithCancel(context.Background())
+	big := acquire(ctx, s, 4)
+	time.Sleep(10 * time.Millisecond)
+	small := acquire(context.Background(), s, 1)
+	if acquired(small) {
+		t.Fatal("small waiter overtook the queued big one")
+	}
+	// Cancelling the head lets the waiter behind it in, and takes nothing.
+	cancel()
+	if err := <-big; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Acquire = %v, want context.Canceled", err)
+	}
+	if !acquired(small) {
+		t.Fatal("waiter behind the cancelled one not served")
+	}
+	if s.InUse() != 4 {
+		t.Fatalf("InUse = %d, want 4", s.InUse())
+	}
+}
+
+func TestAcquireTooLarge(t *testing.T) {
+	s := NewWeighted(2)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Acquire(ctx, 3); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire = %v", err)
+	}
+	if !s.TryAcquire(2) {
+		t.Fatal("oversized request left units taken")
+	}
+}
+
+func TestReleaseTooMuch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("no panic")
+		}
+	}()
+	NewWeighted(1).Release(1)
+}
+
+func TestBoundsConcurrentWeight(t *testing.T) {
+	s := NewWeighted(10)
+	var cur, peak atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func(n int64) {
+			defer wg.Done()
+			if err := s.Acquire(context.Background(), n); err != nil {
+				t.Error(err)
+				return
+			}
+			defer s.Release(n)
+			c := cur.Add(n)
+			for {
+				p := peak.Load()
+				if c <= p || peak.CompareAndSwap(p, c) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			cur.Add(-n)
+		}(int64(i%4 + 1))
+	}
+	wg.Wait()
+	if p := peak.Load(); p > 10 {
+		t.Fatalf("peak weight %d exceeds 10", p)
+	}
+	if s.InUse() != 0 {
+		t.Fatalf("InUse = %d after all released", s.InUse())
+	}
+}
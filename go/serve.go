@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+
+	"example.com/tutorial/assets"
+	"example.com/tutorial/auth"
+	"example.com/tutorial/jobs"
+	"example.com/tutorial/logging"
+	"example.com/tutorial/metrics"
+	"example.com/tutorial/middleware"
+	"example.com/tutorial/redisstore"
+	"example.com/tutorial/scheduler"
+	"example.com/tutorial/server"
+	"example.com/tutorial/shutdown"
+	"example.com/tutorial/snapshot"
+	"example.com/tutorial/user"
+	"example.com/tutorial/user/sqlite"
+)
+
+// runServe implements the "serve" subcommand: the HTTP API over the
+// users of a user file, an SQLite database or memory, until ctx is done
+// or the process is interrupted. examples/http_server.go shows the full
+// deployment, with gRPC, metrics and scheduled re-indexing.
+func runServe(ctx context.Context, cfg serveSettings, assetsDir string, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: serve [-addr address] [-users file | -db file] [-corpus dir] [-reindex spec] [-tls] [-cert file -key file] [-grace d] [-cache memory|redis] [-redis address] [-snapshot dir [-snapshot-every d]] [-jobs dir] [-job-workers n] [-rate r [-burst n]] [-jwt-ttl d]")
+		fs.PrintDefaults()
+	}
+	serve := cfg.serveConfig()
+	fs.StringVar(&serve.Addr, "addr", serve.Addr, "HTTP listen `address`")
+	fs.BoolVar(&serve.TLS, "tls", serve.TLS, "serve HTTPS, with a self-signed certificate unless -cert and -key are given")
+	fs.StringVar(&serve.CertFile, "cert", serve.CertFile, "TLS certificate `file` (PEM)")
+	fs.StringVar(&serve.KeyFile, "key", serve.KeyFile, "TLS private key `file` (PEM)")
+	fs.DurationVar(&serve.ShutdownGrace, "grace", serve.ShutdownGrace, "how long to wait for in-flight requests on shutdown")
+	usersFile := fs.String("users", cfg.Users, "JSON user `file` to serve, created if missing; empty keeps users in memory")
+	dbFile := fs.String("db", cfg.DB, "SQLite user database `file` to serve instead of -users, created and migrated if missing")
+	corpusDir := fs.String("corpus", cfg.Corpus.Dir, "`directory` of text files searchable with GET /search")
+	reindex := fs.String("reindex", cfg.Corpus.Reindex, "cron `spec` on which the -corpus directory is re-indexed; empty indexes it once")
+	fs.StringVar(&cfg.Cache.Backend, "cache", cfg.Cache.Backend, "where word-count results are cached: `backend` memory or redis")
+	fs.StringVar(&cfg.Redis.Addr, "redis", cfg.Redis.Addr, "Redis `address` of -cache redis")
+	fs.StringVar(&cfg.Snapshot.Dir, "snapshot", cfg.Snapshot.Dir, "`directory` to keep snapshots of the in-memory users and corpus index in, restored at startup")
+	fs.DurationVar(&cfg.Snapshot.Interval, "snapshot-every", cfg.Snapshot.Interval, "how often to save the -snapshot files, and at shutdown")
+	fs.StringVar(&cfg.Jobs.Dir, "jobs", cfg.Jobs.Dir, "`directory` to keep the jobs of POST /jobs in, so that queued ones survive a restart; empty keeps them in memory")
+	fs.IntVar(&cfg.Jobs.Workers, "job-workers", cfg.Jobs.Workers, "how many POST /jobs jobs run at once")
+	fs.Float64Var(&cfg.RateLimit.Rate, "rate", cfg.RateLimit.Rate, "how many requests per second each client may make; 0 for any number")
+	fs.IntVar(&cfg.RateLimit.Burst, "burst", cfg.RateLimit.Burst, "how many requests of a client -rate lets through at once")
+	fs.DurationVar(&cfg.JWT.TTL, "jwt-ttl", cfg.JWT.TTL, "lifetime of the tokens issued by POST /login")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+	if *usersFile != "" && *dbFile != "" {
+		fmt.Fprintln(stderr, "serve: -users and -db are exclusive")
+		return 2
+	}
+	if err := errors.Join(serve.Validate(), cfg.Cache.Validate(), cfg.Snapshot.Validate(), cfg.Jobs.Validate(), cfg.RateLimit.Validate(), cfg.JWT.Validate()); err != nil {
+		fmt.Fprintln(stderr, "serve:", err)
+		return 2
+	}
+	var reindexSched scheduler.Schedule
+	if *reindex != "" {
+		var err error
+		if reindexSched, err = scheduler.ParseCron(*reindex); err != nil {
+			fmt.Fprintln(stderr, "serve:", err)
+			return 2
+		}
+	}
+
+	snaps := serveSnapshots{dir: cfg.Snapshot.Dir}
+	mem := user.NewMemoryStore()
+	var users user.Store = mem
+	switch {
+	case *usersFile != "":
+		fstore, err := user.OpenFileStore(*usersFile)
+		if err != nil {
+			fmt.Fprintln(stderr, "serve:", err)
+			return 1
+		}
+		users = fstore
+	case *dbFile != "":
+		ms, err := assetMigrations(assetsDir)
+		if err != nil {
+			fmt.Fprintln(stderr, "serve:", err)
+			return 1
+		}
+		db, err := sqlite.OpenFrom(ctx, *dbFile, ms)
+		if err != nil {
+			fmt.Fprintln(stderr, "serve:", err)
+			return 1
+		}
+		defer db.Close()
+		users = db
+	default:
+		snaps.users = mem
+	}
+	// -assets may override the built-in static files and templates.
+	templates, err := server.ParseTemplates(assets.Override(server.TemplateFiles(), assetDir(assetsDir, "templates")))
+	if err != nil {
+		fmt.Fprintln(stderr, "serve:", err)
+		return 1
+	}
+	queue, err := jobs.NewService(jobs.ServiceConfig{
+		Dir:         cfg.Jobs.Dir,
+		Config:      jobs.Config{Workers: cfg.Jobs.Workers},
+		Retention:   cfg.Jobs.Retention,
+		MaxFinished: cfg.Jobs.MaxFinished,
+	})
+	if err != nil {
+		fmt.Fprintln(stderr, "serve:", err)
+		return 1
+	}
+	// Changing or deleting a user takes a token of POST /login, signed
+	// with serve.jwt.secret or a random key that makes it invalid after
+	// a restart.
+	secret := []byte(cfg.JWT.Secret)
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			fmt.Fprintln(stderr, "serve:", err)
+			return 1
+		}
+		fmt.Fprintln(stderr, "serve: serve.jwt.secret is not set; the tokens of POST /login will not survive a restart")
+	}
+	apiCfg := server.Config{
+		Auth:      auth.NewJWT(secret, cfg.JWT.TTL, "tutorial"),
+		CorpusDir: *corpusDir,
+		Static:    assets.Override(server.Assets(), assetDir(assetsDir, "static")),
+		Templates: templates,
+		Jobs:      queue,
+		// The admin command changes the log level, the rate limits, the
+		// result cache and the job workers through /admin/settings.
+		AdminToken: cfg.Admin.Token,
+		LogLevel:   logging.LevelFromContext(ctx),
+	}
+	if cfg.Cache.Backend == cacheRedis {
+		client, err := redisstore.Open(ctx, cfg.Redis.redisConfig())
+		if err != nil {
+			fmt.Fprintln(stderr, "serve:", err)
+			return 1
+		}
+		defer client.Close()
+		results := redisstore.NewCache(client, "tutorial:wordcount:", cfg.Cache.TTL)
+		apiCfg.Metrics = metrics.NewRegistry()
+		results.Register(apiCfg.Metrics, "wordcount_result_cache")
+		apiCfg.ResultCache = results
+	}
+	logger := logging.Component(nil, "http")
+	mws := []middleware.Middleware{
+		middleware.RequestID(),
+		middleware.Logger(logger),
+		middleware.Recover(logger),
+	}
+	if cfg.RateLimit.Rate > 0 {
+		apiCfg.RateLimit = middleware.NewLimiters(cfg.RateLimit.Rate, cfg.RateLimit.Burst, 0)
+		mws = append(mws, middleware.RateLimit(apiCfg.RateLimit, nil))
+	}
+	api := server.New(users, server.WithConfig(apiCfg), server.WithMiddleware(mws...))
+	sched := scheduler.New(scheduler.Config{})
+	if *corpusDir != "" {
+		snaps.api = api
+	}
+	restored, err := snaps.restore()
+	if err != nil {
+		fmt.Fprintln(stderr, "serve:", err)
+		return 1
+	}
+	if *corpusDir != "" {
+		if !restored {
+			if err := api.Reindex(ctx); err != nil {
+				fmt.Fprintln(stderr, "serve:", err)
+				return 1
+			}
+		}
+		if reindexSched != nil {
+			sched.Add("reindex", reindexSched, api.Reindex)
+		}
+	}
+	if snaps.dir != "" {
+		sched.Add("snapshot", scheduler.Every(cfg.Snapshot.Interval), snaps.save)
+	}
+
+	lis, err := net.Listen("tcp", serve.Addr)
+	if err != nil {
+		fmt.Fprintln(stderr, "serve:", err)
+		return 1
+	}
+	scheme := "HTTP"
+	if serve.UseTLS() {
+		tlsCfg, err := server.TLSConfig(serve.CertFile, serve.KeyFile)
+		if err != nil {
+			lis.Close()
+			fmt.Fprintln(stderr, "serve:", err)
+			return 1
+		}
+		lis = tls.NewListener(lis, tlsCfg)
+		scheme = "HTTPS"
+	}
+	srv := serve.HTTPServer(api)
+	srv.RegisterOnShutdown(api.Close)
+	fmt.Fprintf(stderr, "serving %s on %s\n", scheme, lis.Addr())
+	// Once the HTTP server has drained, the scheduler stops and then the
+	// snapshots are saved a last time, each given the grace period.
+	sd := shutdown.New()
+	if snaps.dir != "" {
+		sd.Add("snapshot", serve.ShutdownGrace, snaps.save)
+	}
+	sd.Add("scheduler", serve.ShutdownGrace, sched.Stop)
+	// The jobs run on past ctx, until the HTTP server has drained, then
+	// are given the grace period to finish; those cut short stay queued
+	// in the -jobs directory.
+	jobsCtx, stopJobs := context.WithCancel(context.WithoutCancel(ctx))
+	defer stopJobs()
+	jobsDone := make(chan error, 1)
+	go func() { jobsDone <- queue.Run(jobsCtx) }()
+	sd.Add("jobs", serve.ShutdownGrace, func(ctx context.Context) error {
+		queue.Close()
+		select {
+		case err := <-jobsDone:
+			return err
+		case <-ctx.Done():
+			stopJobs()
+			<-jobsDone
+			return ctx.Err()
+		}
+	})
+	sched.Start(ctx)
+	err = server.Run(ctx, srv, lis, serve.ShutdownGrace)
+	err = errors.Join(err, sd.Shutdown(context.Background()))
+	if err != nil {
+		fmt.Fprintln(stderr, "serve:", err)
+		return 1
+	}
+	return 0
+}
+
+// serveSnapshots keeps the state serve holds in memory, the users when
+// there is no user file and the corpus index, in snapshot files of dir,
+// so that a restarted server picks up where it stopped.
+type serveSnapshots struct {
+	dir   string
+	users *user.MemoryStore // nil if the users are kept in a file or database
+	api   *server.Server    // nil without a corpus
+}
+
+func (s serveSnapshots) usersPath() string  { return filepath.Join(s.dir, "users.gob") }
+func (s serveSnapshots) corpusPath() string { return filepath.Join(s.dir, "corpus.gob") }
+
+// restore loads the snapshots there are, and reports whether one was of
+// the corpus index, which then needs no indexing. Missing snapshots are
+// not an error: the first run has none.
+func (s serveSnapshots) restore() (corpus bool, err error) {
+	if s.dir == "" {
+		return false, nil
+	}
+	if s.users != nil {
+		if err := snapshot.Load(s.usersPath(), s.users); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return false, err
+		}
+	}
+	if s.api != nil {
+		err := snapshot.Load(s.corpusPath(), snapshot.RestoreFunc(s.api.RestoreCorpus))
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// save writes the snapshots, each replacing its file atomically. The
+// corpus is skipped until it has been indexed.
+func (s serveSnapshots) save(context.Context) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	var errs []error
+	if s.users != nil {
+		errs = append(errs, snapshot.Save(s.usersPath(), s.users))
+	}
+	if s.api != nil && s.api.Indexed() {
+		errs = append(errs, snapshot.Save(s.corpusPath(), snapshot.Func(s.api.SnapshotCorpus)))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/tutorial/user/sqlite"
+)
+
+func TestRunServe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	usersFile := filepath.Join(t.TempDir(), "users.json")
+	addr, done, stderr := startServe(t, ctx, "-users", usersFile)
+	adaID := createUser(t, addr, "ada")
+
+	cancel()
+	select {
+	case code := <-done:
+		if code != 0 {
+			t.Fatalf("exit %d: %s", code, stderr.String())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop")
+	}
+	var out, errOut strings.Builder
+	if code := runUsers(context.Background(), defaultSettings().Users, false, []string{"-file", usersFile, "list"}, &out, &errOut); code != 0 || out.String() != adaID+"\tada\n" {
+		t.Fatalf("served user not saved: exit %d, %q, %s", code, out.String(), errOut.String())
+	}
+}
+
+func TestRunServeAuth(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := defaultSettings().Serve
+	cfg.JWT.Secret = "k3y"
+	addr, _, stderr := startServeWith(t, ctx, cfg)
+	if strings.Contains(stderr.String(), "jwt.secret") {
+		t.Errorf("warns of a secret that is set: %s", stderr.String())
+	}
+	send := func(method, path, token, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, "http://"+addr+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+	var ada struct {
+		ID string `json:"id"`
+	}
+	if resp := send(http.MethodPost, "/users", "", `{"name":"ada","password":"analytical engine"}`); resp.StatusCode != http.StatusCreated || json.NewDecoder(resp.Body).Decode(&ada) != nil {
+		t.Fatalf("POST /users: %s", resp.Status)
+	}
+	if resp := send(http.MethodPut, "/users/"+ada.ID, "", `{"name":"Ada"}`); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("PUT without a token: %s", resp.Status)
+	}
+	var login struct {
+		Token string `json:"token"`
+	}
+	if resp := send(http.MethodPost, "/login", "", `{"name":"ada","password":"analytical engine"}`); resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&login) != nil {
+		t.Fatalf("POST /login: %s", resp.Status)
+	}
+	if resp := send(http.MethodPut, "/users/"+ada.ID, login.Token, `{"name":"Ada"}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT with a token: %s", resp.Status)
+	}
+	if resp := send(http.MethodDelete, "/users/"+ada.ID, "", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("DELETE without a token: %s", resp.Status)
+	}
+
+	// Without a secret, serve signs with a key of its own, and says so.
+	_, _, stderr = startServe(t, ctx)
+	if !strings.Contains(stderr.String(), "serve.jwt.secret is not set") {
+		t.Errorf("no warning of the missing secret: %s", stderr.String())
+	}
+}
+
+func TestRunServeDB(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dbFile := filepath.Join(t.TempDir(), "users.db")
+	addr, done, stderr := startServe(t, ctx, "-db", dbFile)
+	adaID := createUser(t, addr, "ada")
+	cancel()
+	if code := <-done; code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+
+	db, err := sqlite.Open(context.Background(), dbFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if u, err := db.Get(context.Background(), adaID); err != nil || u.Name != "ada" {
+		t.Fatalf("served user not stored: %+v, %v", u, err)
+	}
+}
+
+// createUser adds a user called name through the server at addr and
+// returns its ID.
+func createUser(t *testing.T, addr, name string) string {
+	t.Helper()
+	resp, err := http.Post("http://"+addr+"/users", "application/json", strings.NewReader(`{"name":"`+name+`"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var u struct {
+		ID string `json:"id"`
+	}
+	if resp.StatusCode != http.StatusCreated || json.NewDecoder(resp.Body).Decode(&u) != nil {
+		t.Fatalf("POST /users: %s", resp.Status)
+	}
+	return u.ID
+}
+
+// startServe runs serve on a free port with args until ctx is done, and
+// returns its address, a channel of its exit code and its stderr.
+func startServe(t *testing.T, ctx context.Context, args ...string) (string, <-chan int, *syncBuffer) {
+	t.Helper()
+	return startServeWith(t, ctx, defaultSettings().Serve, args...)
+}
+
+// startServeWith is startServe with the settings cfg.
+func startServeWith(t *testing.T, ctx context.Context, cfg serveSettings, args ...string) (string, <-chan int, *syncBuffer) {
+	t.Helper()
+	stderr := new(syncBuffer)
+	done := make(chan int, 1)
+	go func() {
+		done <- runServe(ctx, cfg, "", append([]string{"-addr", "127.0.0.1:0"}, args...), io.Discard, stderr)
+	}()
+	addrRE := regexp.MustCompile(`serving HTTP on (\S+)\n`)
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		if m := addrRE.FindStringSubmatch(stderr.String()); m != nil {
+			return m[1], done, stderr
+		}
+		select {
+		case code := <-done:
+			t.Fatalf("server exited %d: %s", code, stderr.String())
+		default:
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not start: %s", stderr.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunServeSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	snapDir := filepath.Join(dir, "snapshots")
+	corpus := filepath.Join(dir, "corpus")
+	os.Mkdir(corpus, 0o755)
+	os.WriteFile(filepath.Join(corpus, "go.txt"), []byte("gophers love goroutines"), 0o644)
+	args := []string{"-snapshot", snapDir, "-snapshot-every", "1h", "-corpus", corpus}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	addr, done, stderr := startServe(t, ctx, args...)
+	adaID := createUser(t, addr, "ada")
+	cancel()
+	if code := <-done; code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	for _, name := range []string{"users.gob", "corpus.gob"} {
+		if _, err := os.Stat(filepath.Join(snapDir, name)); err != nil {
+			t.Fatalf("no snapshot saved at shutdown: %v", err)
+		}
+	}
+
+	// The restarted server has the user, and the index without the
+	// files.
+	os.Remove(filepath.Join(corpus, "go.txt"))
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	addr, done, stderr = startServe(t, ctx, args...)
+	for path, want := range map[string]string{"/users/" + adaID: `"name":"ada"`, "/search?q=gophers": `"go.txt"`} {
+		resp, err := http.Get("http://" + addr + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), want) {
+			t.Errorf("GET %s after restart: %s %s", path, resp.Status, body)
+		}
+	}
+	cancel()
+	if code := <-done; code != 0 {
+		t.Fatalf("restart: exit %d: %s", code, stderr.String())
+	}
+
+	os.WriteFile(filepath.Join(snapDir, "users.gob"), []byte("junk"), 0o644)
+	var errOut strings.Builder
+	if code := runServe(context.Background(), defaultSettings().Serve, "", append([]string{"-addr", "127.0.0.1:0"}, args...), io.Discard, &errOut); code != 1 || !strings.Contains(errOut.String(), "users.gob") {
+		t.Fatalf("damaged snapshot: exit %d: %s", code, errOut.String())
+	}
+}
+
+func TestRunServeJobs(t *testing.T) {
+	jobsDir := filepath.Join(t.TempDir(), "jobs")
+	ctx, cancel := context.WithCancel(context.Background())
+	addr, done, stderr := startServe(t, ctx, "-jobs", jobsDir)
+	resp, err := http.Post("http://"+addr+"/jobs", "application/json", strings.NewReader(`{"text":"one two two"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	job := resp.Header.Get("Location")
+	if resp.StatusCode != http.StatusAccepted || job == "" {
+		t.Fatalf("POST /jobs: %s, Location %q", resp.Status, job)
+	}
+	getJob := func(addr string) string {
+		t.Helper()
+		resp, err := http.Get("http://" + addr + job)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+	for deadline := time.Now().Add(5 * time.Second); !strings.Contains(getJob(addr), `"state":"done"`); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("job not done: %s", getJob(addr))
+		}
+	}
+	cancel()
+	if code := <-done; code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+
+	// The restarted server still has the result.
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	addr, done, stderr = startServe(t, ctx, "-jobs", jobsDir)
+	if body := getJob(addr); !strings.Contains(body, `"state":"done"`) || !strings.Contains(body, `"words":3`) {
+		t.Errorf("GET %s after restart: %s", job, body)
+	}
+	cancel()
+	if code := <-done; code != 0 {
+		t.Fatalf("restart: exit %d: %s", code, stderr.String())
+	}
+}
+
+func TestRunServeBadTemplates(t *testing.T) {
+	dir := writeTree(t, map[string]string{"templates/layout.html": "{{template"})
+	var errOut strings.Builder
+	if code := runServe(context.Background(), defaultSettings().Serve, dir, []string{"-addr", "127.0.0.1:0"}, io.Discard, &errOut); code != 1 || !strings.Contains(errOut.String(), "templates") {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+}
+
+func TestRunServeBadFlags(t *testing.T) {
+	for _, args := range [][]string{{"-addr", "nowhere"}, {"-cert", "c.pem"}, {"-cache", "disk"}, {"-snapshot-every", "0"}, {"-job-workers", "0"}, {"-rate", "-1"}, {"-rate", "5", "-burst", "0"}, {"-jwt-ttl", "0"}, {"-users", "u.json", "-db", "u.db"}, {"extra"}} {
+		if code := runServe(context.Background(), defaultSettings().Serve, "", args, io.Discard, io.Discard); code != 2 {
+			t.Errorf("%v: exit %d, want 2", args, code)
+		}
+	}
+}
+
+func TestRunServeRedisUnreachable(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lis.Addr().String()
+	lis.Close()
+	var stderr strings.Builder
+	if code := runServe(context.Background(), defaultSettings().Serve, "", []string{"-addr", "127.0.0.1:0", "-cache", "redis", "-redis", addr}, io.Discard, &stderr); code != 1 || !strings.Contains(stderr.String(), addr) {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+}
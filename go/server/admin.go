@@ -0,0 +1,158 @@
+package server
+
+import (
+	"crypto/sha256"
+	"log/slog"
+	"net/http"
+
+	"example.com/tutorial/cache"
+	"example.com/tutorial/validate"
+)
+
+// runtimeSettings are the settings an operator can change while the
+// server runs, as GET and PATCH /admin/settings and /debug/info report
+// them. A setting the server does not have, such as the job workers of
+// a server without Config.Jobs, is left out; in a PATCH body, one left
+// out stays as it is.
+type runtimeSettings struct {
+	LogLevel   *slog.Level        `json:"log_level,omitempty"`
+	RateLimit  *rateLimitSettings `json:"rate_limit,omitempty"`
+	CacheBytes *int64             `json:"cache_bytes,omitempty"`
+	JobWorkers *int               `json:"job_workers,omitempty"`
+}
+
+// rateLimitSettings are those of Config.RateLimit. In a PATCH body,
+// zero keeps the current value.
+type rateLimitSettings struct {
+	Rate  float64 `json:"rate,omitempty"` // requests per second and client
+	Burst int     `json:"burst,omitempty"`
+}
+
+// attrs returns the settings rs has, as arguments of slog.Logger.Info.
+func (rs runtimeSettings) attrs() []any {
+	var args []any
+	if rs.LogLevel != nil {
+		args = append(args, "log_level", *rs.LogLevel)
+	}
+	if rs.RateLimit != nil {
+		args = append(args, "rate", rs.RateLimit.Rate, "burst", rs.RateLimit.Burst)
+	}
+	if rs.CacheBytes != nil {
+		args = append(args, "cache_bytes", *rs.CacheBytes)
+	}
+	if rs.JobWorkers != nil {
+		args = append(args, "job_workers", *rs.JobWorkers)
+	}
+	return args
+}
+
+// resultCache returns the server's own cache of POST /wordcount
+// results, or nil if it has none or shares Config.ResultCache.
+func (s *Server) resultCache() *cache.Cache[[sha256.Size]byte, countResult] {
+	c, _ := s.results.(*cache.Cache[[sha256.Size]byte, countResult])
+	return c
+}
+
+// runtimeSettings returns the current settings. The caller holds
+// adminMu, so that they are not caught halfway through a change.
+func (s *Server) runtimeSettings() runtimeSettings {
+	var rs runtimeSettings
+	if s.logLevel != nil {
+		level := s.logLevel.Level()
+		rs.LogLevel = &level
+	}
+	if s.limiters != nil {
+		rate, burst := s.limiters.Limit()
+		rs.RateLimit = &rateLimitSettings{Rate: rate, Burst: burst}
+	}
+	if c := s.resultCache(); c != nil {
+		_, maxBytes := c.Limits()
+		rs.CacheBytes = &maxBytes
+	}
+	if s.jobQueue != nil {
+		workers := s.jobQueue.Workers()
+		rs.JobWorkers = &workers
+	}
+	return rs
+}
+
+// settings returns the runtime settings for /debug/info.
+func (s *Server) settings() any {
+	s.adminMu.Lock()
+	defer s.adminMu.Unlock()
+	return s.runtimeSettings()
+}
+
+// validateSettings checks that the server has every setting of rs and
+// that their values are in range.
+func (s *Server) validateSettings(rs runtimeSettings) error {
+	const missing = "cannot be changed on this server"
+	var v validate.Validator
+	if rs.LogLevel != nil {
+		v.Check(s.logLevel != nil, "log_level", missing)
+	}
+	if rs.RateLimit != nil {
+		v.Check(s.limiters != nil, "rate_limit", missing)
+		v.Check(rs.RateLimit.Rate >= 0, "rate_limit.rate", "must not be negative")
+		v.Check(rs.RateLimit.Burst >= 0, "rate_limit.burst", "must not be negative")
+	}
+	if rs.CacheBytes != nil {
+		v.Check(s.resultCache() != nil, "cache_bytes", missing)
+		v.Check(*rs.CacheBytes > 0, "cache_bytes", "must be positive")
+	}
+	if rs.JobWorkers != nil {
+		v.Check(s.jobQueue != nil, "job_workers", missing)
+		v.Check(*rs.JobWorkers > 0, "job_workers", "must be positive")
+	}
+	return v.Err()
+}
+
+// getSettings answers the runtime settings.
+func (s *Server) getSettings(w http.ResponseWriter, r *http.Request) {
+	s.adminMu.Lock()
+	rs := s.runtimeSettings()
+	s.adminMu.Unlock()
+	writeJSON(w, http.StatusOK, rs)
+}
+
+// patchSettings changes the runtime settings of a JSON body such as
+// {"log_level":"debug","job_workers":8} and answers them all. The body
+// is checked whole first, so that either every setting in it changes
+// or, with 422, none does; changes are made one at a time, and the
+// requests to /admin/settings take turns.
+func (s *Server) patchSettings(w http.ResponseWriter, r *http.Request) {
+	var in runtimeSettings
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+	s.adminMu.Lock()
+	defer s.adminMu.Unlock()
+	if err := s.validateSettings(in); err != nil {
+		writeError(w, r, http.StatusUnprocessableEntity, err)
+		return
+	}
+	if in.LogLevel != nil {
+		s.logLevel.Set(*in.LogLevel)
+	}
+	if in.RateLimit != nil {
+		rate, burst := s.limiters.Limit()
+		if in.RateLimit.Rate > 0 {
+			rate = in.RateLimit.Rate
+		}
+		if in.RateLimit.Burst > 0 {
+			burst = in.RateLimit.Burst
+		}
+		s.limiters.SetLimit(rate, burst)
+	}
+	if in.CacheBytes != nil {
+		c := s.resultCache()
+		maxEntries, _ := c.Limits()
+		c.Resize(maxEntries, *in.CacheBytes)
+	}
+	if in.JobWorkers != nil {
+		s.jobQueue.SetWorkers(*in.JobWorkers)
+	}
+	rs := s.runtimeSettings()
+	s.logger.InfoContext(r.Context(), "runtime settings changed", rs.attrs()...)
+	writeJSON(w, http.StatusOK, rs)
+}
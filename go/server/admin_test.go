@@ -0,0 +1,115 @@
+package server
+
+import (
+	"crypto/sha256"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/tutorial/cache"
+	"example.com/tutorial/jobs"
+	"example.com/tutorial/middleware"
+)
+
+func TestAdminSettings(t *testing.T) {
+	svc, err := jobs.NewService(jobs.ServiceConfig{Config: jobs.Config{Workers: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer svc.Close()
+	level := new(slog.LevelVar)
+	limiters := middleware.NewLimiters(10, 20, time.Minute)
+	h := New(nil, WithConfig(Config{
+		AdminToken:       "s3cret",
+		LogLevel:         level,
+		RateLimit:        limiters,
+		Jobs:             svc,
+		ResultCacheBytes: 1 << 20,
+	}))
+	authz := []string{"Authorization", "Bearer s3cret"}
+
+	rec := do(t, h, "GET", "/admin/settings", "", authz...)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET status %d: %s", rec.Code, rec.Body)
+	}
+	if got, want := strings.TrimSpace(rec.Body.String()), `{"log_level":"INFO","rate_limit":{"rate":10,"burst":20},"cache_bytes":1048576,"job_workers":2}`; got != want {
+		t.Fatalf("GET = %s, want %s", got, want)
+	}
+
+	rec = do(t, h, "PATCH", "/admin/settings", `{"log_level":"debug","rate_limit":{"burst":5},"cache_bytes":4096,"job_workers":6}`, authz...)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PATCH status %d: %s", rec.Code, rec.Body)
+	}
+	if got, want := strings.TrimSpace(rec.Body.String()), `{"log_level":"DEBUG","rate_limit":{"rate":10,"burst":5},"cache_bytes":4096,"job_workers":6}`; got != want {
+		t.Fatalf("PATCH = %s, want %s", got, want)
+	}
+	_, maxBytes := h.results.(*cache.Cache[[sha256.Size]byte, countResult]).Limits()
+	if level.Level() != slog.LevelDebug || svc.Workers() != 6 || maxBytes != 4096 {
+		t.Fatalf("level %v, workers %d, cache %d", level.Level(), svc.Workers(), maxBytes)
+	}
+	if rate, burst := limiters.Limit(); rate != 10 || burst != 5 {
+		t.Fatalf("rate limit %v, %d", rate, burst)
+	}
+	if rec := do(t, h, "GET", "/debug/info", ""); !strings.Contains(rec.Body.String(), `"job_workers": 6`) {
+		t.Errorf("/debug/info: %s", rec.Body)
+	}
+
+	// A zero rate or burst keeps the current one; a negative one is an
+	// error.
+	rec = do(t, h, "PATCH", "/admin/settings", `{"rate_limit":{"rate":0,"burst":0}}`, authz...)
+	if rate, burst := limiters.Limit(); rec.Code != http.StatusOK || rate != 10 || burst != 5 {
+		t.Fatalf("zero rate limit: %d, rate %v, burst %d: %s", rec.Code, rate, burst, rec.Body)
+	}
+	rec = do(t, h, "PATCH", "/admin/settings", `{"rate_limit":{"burst":-1}}`, authz...)
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), `{"field":"rate_limit.burst","message":"must not be negative"}`) {
+		t.Fatalf("negative burst: %d %s", rec.Code, rec.Body)
+	}
+
+	// A body with one bad setting changes none.
+	rec = do(t, h, "PATCH", "/admin/settings", `{"log_level":"error","job_workers":0}`, authz...)
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "job_workers") {
+		t.Fatalf("bad workers: %d %s", rec.Code, rec.Body)
+	}
+	if level.Level() != slog.LevelDebug {
+		t.Fatalf("level changed to %v", level.Level())
+	}
+	for _, tc := range []struct {
+		name, method, body string
+		header             []string
+		want               int
+	}{
+		{"no token", "GET", "", nil, http.StatusUnauthorized},
+		{"wrong token", "PATCH", `{"job_workers":1}`, []string{"Authorization", "Bearer nope"}, http.StatusUnauthorized},
+		{"bad level", "PATCH", `{"log_level":"loud"}`, authz, http.StatusBadRequest},
+		{"unknown setting", "PATCH", `{"timeout":"1s"}`, authz, http.StatusBadRequest},
+		{"bad rate", "PATCH", `{"rate_limit":{"rate":-1}}`, authz, http.StatusUnprocessableEntity},
+	} {
+		if rec := do(t, h, tc.method, "/admin/settings", tc.body, tc.header...); rec.Code != tc.want {
+			t.Errorf("%s: status %d, want %d: %s", tc.name, rec.Code, tc.want, rec.Body)
+		}
+	}
+}
+
+func TestAdminSettingsMissing(t *testing.T) {
+	// Without a token there are no admin endpoints.
+	if rec := do(t, New(nil), "GET", "/admin/settings", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("status %d", rec.Code)
+	}
+
+	h := New(nil, WithConfig(Config{AdminToken: "s3cret", ResultCacheBytes: -1}))
+	authz := []string{"Authorization", "Bearer s3cret"}
+	if rec := do(t, h, "GET", "/admin/settings", "", authz...); strings.TrimSpace(rec.Body.String()) != "{}" {
+		t.Fatalf("GET = %s", rec.Body)
+	}
+	rec := do(t, h, "PATCH", "/admin/settings", `{"log_level":"warn","rate_limit":{"rate":1},"cache_bytes":1,"job_workers":1}`, authz...)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status %d", rec.Code)
+	}
+	for _, field := range []string{"log_level", "rate_limit", "cache_bytes", "job_workers"} {
+		if !strings.Contains(rec.Body.String(), `"`+field+`"`) {
+			t.Errorf("no %s in %s", field, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package server_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/tutorial/auth"
+	"example.com/tutorial/metrics"
+	"example.com/tutorial/middleware"
+	"example.com/tutorial/server"
+	"example.com/tutorial/testsupport"
+	"github.com/gorilla/websocket"
+)
+
+// TestAPI runs every endpoint through each middleware stack, which must
+// not change what the handlers answer.
+func TestAPI(t *testing.T) {
+	for name, stack := range testsupport.Stacks {
+		stack := stack
+		t.Run(name, func(t *testing.T) {
+			newHarness := func(t *testing.T, opts testsupport.Options) *testsupport.Harness {
+				opts.Stack = stack
+				return testsupport.New(t, opts)
+			}
+			t.Run("Probes", func(t *testing.T) { testProbes(t, newHarness) })
+			t.Run("Users", func(t *testing.T) { testUsers(t, newHarness) })
+			t.Run("Auth", func(t *testing.T) { testAuth(t, newHarness) })
+			t.Run("WordCount", func(t *testing.T) { testWordCount(t, newHarness) })
+			t.Run("WordCountEvents", func(t *testing.T) { testWordCountEvents(t, newHarness) })
+			t.Run("Upload", func(t *testing.T) { testUpload(t, newHarness) })
+			t.Run("Search", func(t *testing.T) { testSearch(t, newHarness) })
+			t.Run("Pages", func(t *testing.T) { testPages(t, newHarness) })
+			t.Run("WebSocket", func(t *testing.T) { testWebSocket(t, newHarness) })
+			t.Run("StoreDown", func(t *testing.T) { testStoreDown(t, newHarness) })
+		})
+	}
+}
+
+type newHarnessFunc func(*testing.T, testsupport.Options) *testsupport.Harness
+
+func testProbes(t *testing.T, newHarness newHarnessFunc) {
+	h := newHarness(t, testsupport.Options{})
+	h.Get("/").ExpectStatus(http.StatusOK).ExpectBodyContains("ok")
+	h.Get("/healthz").ExpectStatus(http.StatusOK).ExpectJSON(`{"status":"ok","checks":{}}`)
+	h.Get("/readyz").ExpectStatus(http.StatusOK).ExpectJSONFields(`{"status":"ok","checks":{"store":{"status":"ok"}}}`)
+	h.Get("/version").ExpectStatus(http.StatusOK).ExpectContentType("application/json")
+	h.Get("/debug/info").ExpectStatus(http.StatusOK).ExpectBodyContains(`"goroutines"`)
+	h.Get("/metrics").ExpectStatus(http.StatusOK).ExpectBodyContains("wordcount_jobs_total")
+	h.Get("/nowhere").ExpectError(http.StatusNotFound, "not found")
+	h.Delete("/healthz").ExpectError(http.StatusMethodNotAllowed, "method not allowed")
+}
+
+func testUsers(t *testing.T, newHarness newHarnessFunc) {
+	h := newHarness(t, testsupport.Options{})
+	var created struct {
+		ID string `json:"id"`
+	}
+	resp := h.Post("/users", map[string]string{"name": "Ada", "password": "analytical engine"}).
+		ExpectStatus(http.StatusCreated).
+		ExpectJSONFields(`{"name":"Ada"}`).
+		DecodeJSON(&created)
+	ada := created.ID
+	resp.ExpectHeader("Location", "/users/"+ada)
+	grace := h.CreateUser("Grace", "")
+	if ada == "" || grace <= ada {
+		t.Fatalf("created IDs %q and %q, want increasing", ada, grace)
+	}
+	h.Post("/users", `{"name":""}`).
+		ExpectStatus(http.StatusUnprocessableEntity).
+		ExpectJSON(`{"error":"validation failed","fields":[{"field":"name","message":"is required"}]}`)
+	h.Post("/users", `{"name":`).ExpectStatus(http.StatusBadRequest)
+
+	h.Get("/users").ExpectStatus(http.StatusOK).
+		ExpectJSON(fmt.Sprintf(`{"users":[{"id":%q,"name":"Ada"},{"id":%q,"name":"Grace"}],"total":2}`, ada, grace))
+	var page struct {
+		NextCursor string `json:"next_cursor"`
+	}
+	h.Get("/users?sort=name&order=desc&limit=1").ExpectStatus(http.StatusOK).
+		ExpectJSONFields(fmt.Sprintf(`{"users":[{"id":%q,"name":"Grace"}],"total":2}`, grace)).
+		DecodeJSON(&page)
+	h.Get("/users?sort=name&order=desc&limit=1&cursor=" + page.NextCursor).ExpectStatus(http.StatusOK).
+		ExpectJSON(fmt.Sprintf(`{"users":[{"id":%q,"name":"Ada"}],"total":2}`, ada))
+	h.Get("/users?limit=1&cursor="+page.NextCursor).ExpectError(http.StatusBadRequest, "invalid cursor")
+	h.Get("/users", testsupport.Header("Accept", "application/x-ndjson")).ExpectStatus(http.StatusOK).
+		ExpectContentType("application/x-ndjson").
+		ExpectBodyContains(fmt.Sprintf("{\"id\":%q,\"name\":\"Ada\"}\n{\"id\":%q,\"name\":\"Grace\"}\n", ada, grace))
+	h.Get("/users?limit=many").ExpectStatus(http.StatusBadRequest)
+	h.Get("/users/" + ada).ExpectStatus(http.StatusOK).ExpectJSON(fmt.Sprintf(`{"id":%q,"name":"Ada"}`, ada))
+	h.Get("/users/nobody").ExpectError(http.StatusNotFound, "get user nobody: user: not found")
+	h.Get("/users/x.y").ExpectError(http.StatusNotFound, "no such user")
+
+	h.Put("/users/"+ada, map[string]string{"name": "Ada Lovelace"}).ExpectStatus(http.StatusOK).
+		ExpectJSON(fmt.Sprintf(`{"id":%q,"name":"Ada Lovelace"}`, ada))
+	h.Put("/users/nobody", map[string]string{"name": "Nobody"}).ExpectStatus(http.StatusNotFound)
+	h.Delete("/users/" + grace).ExpectStatus(http.StatusNoContent)
+	h.Delete("/users/"+grace).ExpectError(http.StatusNotFound, "delete user "+grace+": user: not found")
+	h.Get("/users").ExpectJSONFields(`{"total":1}`)
+}
+
+func testAuth(t *testing.T, newHarness newHarnessFunc) {
+	j := auth.NewJWT([]byte("0123456789abcdef0123456789abcdef"), time.Hour, "")
+	h := newHarness(t, testsupport.Options{Config: server.Config{Auth: j}})
+	ada := h.CreateUser("Ada", "analytical engine")
+	grace := h.CreateUser("Grace", "compiler")
+	token := h.Login("Ada", "analytical engine")
+	h.Post("/login", map[string]string{"name": "Ada", "password": "wrong"}).ExpectStatus(http.StatusUnauthorized)
+
+	path := "/users/" + ada
+	h.Put(path, map[string]string{"name": "Ada L"}).ExpectStatus(http.StatusUnauthorized)
+	h.Put(path, map[string]string{"name": "Ada L"}, testsupport.Bearer("junk")).ExpectStatus(http.StatusUnauthorized)
+	h.Put("/users/"+grace, map[string]string{"name": "Grace H"}, testsupport.Bearer(token)).ExpectStatus(http.StatusForbidden)
+	h.Put(path, map[string]string{"name": "Ada L", "password": "analytical engine"}, testsupport.Bearer(token)).
+		ExpectStatus(http.StatusOK).
+		ExpectJSONFields(`{"name":"Ada L"}`)
+	h.Delete("/users/"+grace, testsupport.Bearer(token)).ExpectStatus(http.StatusForbidden)
+	h.Delete(path, testsupport.Bearer(token)).ExpectStatus(http.StatusNoContent)
+}
+
+func testWordCount(t *testing.T, newHarness newHarnessFunc) {
+	shared := testsupport.NewMapCache()
+	h := newHarness(t, testsupport.Options{Config: server.Config{ResultCache: shared}})
+	const doc = "the cat and the hat"
+	h.Post("/wordcount?top=1", strings.NewReader(doc)).
+		ExpectStatus(http.StatusOK).
+		ExpectHeader("X-Cache", "MISS").
+		ExpectJSONFields(`{"words":5,"unique":4,"top":[{"word":"the","count":2}]}`)
+	h.Post("/wordcount?top=1", strings.NewReader(doc)).ExpectHeader("X-Cache", "HIT")
+	if hits, sets := shared.Stats(); hits != 1 || sets != 1 {
+		t.Errorf("shared cache: %d hits, %d sets; want 1 and 1", hits, sets)
+	}
+	h.Post("/wordcount", testsupport.Multipart{{Name: "doc.txt", Content: "one two two"}}).
+		ExpectStatus(http.StatusOK).
+		ExpectJSONFields(`{"words":3,"unique":2}`)
+	h.Post("/wordcount?top=lots", strings.NewReader(doc)).ExpectStatus(http.StatusBadRequest)
+	h.Get("/metrics").ExpectBodyContains(`wordcount_jobs_total{result="ok"} 3`)
+}
+
+func testWordCountEvents(t *testing.T, newHarness newHarnessFunc) {
+	h := newHarness(t, testsupport.Options{})
+	h.Post("/wordcount/events?top=1", strings.NewReader("go went gone go")).
+		ExpectStatus(http.StatusOK).
+		ExpectContentType("text/event-stream").
+		ExpectBodyContains("event: result\n", `"top":[{"word":"go","count":2}]`)
+
+	// GET /wordcount/events streams the jobs finished after it was
+	// opened, so it needs a connection of its own.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", h.URL()+"/wordcount/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	h.Post("/wordcount", strings.NewReader("one more job"))
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		if sc.Text() == "event: job" {
+			return
+		}
+	}
+	t.Fatalf("no job event: %v", sc.Err())
+}
+
+func testUpload(t *testing.T, newHarness newHarnessFunc) {
+	h := newHarness(t, testsupport.Options{})
+	h.Post("/upload?top=1", testsupport.Multipart{
+		{Name: "a.txt", Content: "red green red"},
+		{Name: "b.md", Type: "text/markdown", Content: "# red"},
+	}).ExpectStatus(http.StatusOK).ExpectJSONFields(`{
+		"files":[{"name":"a.txt","words":3},{"name":"b.md","words":1}],
+		"words":4,"unique":2,"top":[{"word":"red","count":3}]}`)
+	h.Post("/upload", testsupport.Multipart{{Name: "x.bin", Type: "image/png", Content: "\x89PNG"}}).
+		ExpectStatus(http.StatusUnsupportedMediaType)
+	h.Post("/upload", testsupport.Multipart{}).ExpectError(http.StatusBadRequest, "no files")
+	h.Post("/upload", "{}").ExpectStatus(http.StatusBadRequest)
+}
+
+func testSearch(t *testing.T, newHarness newHarnessFunc) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.txt"), []byte("gophers love goroutines"), 0o644)
+	os.WriteFile(filepath.Join(dir, "rust.txt"), []byte("crabs love ownership"), 0o644)
+	h := newHarness(t, testsupport.Options{Config: server.Config{CorpusDir: dir}})
+	if err := h.Server.Reindex(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	h.Get("/search?q=gophers").ExpectStatus(http.StatusOK).ExpectJSON(`{"query":"gophers","docs":["go.txt"]}`)
+	h.Get("/search?q=love").ExpectJSONFields(`{"docs":["go.txt","rust.txt"]}`)
+	h.Get("/search").ExpectStatus(http.StatusOK).ExpectJSON(`{"query":"","docs":[]}`)
+
+	newHarness(t, testsupport.Options{}).Get("/search?q=gophers").ExpectStatus(http.StatusNotFound)
+}
+
+func testPages(t *testing.T, newHarness newHarnessFunc) {
+	h := newHarness(t, testsupport.Options{})
+	h.CreateUser("Ada", "")
+	h.Get("/dashboard").ExpectStatus(http.StatusOK).ExpectContentType("text/html").ExpectBodyContains("<td>Ada</td>")
+	h.Post("/dashboard", url.Values{"text": {"the cat and the hat"}}).
+		ExpectStatus(http.StatusOK).
+		ExpectBodyContains("5 words, 4 distinct")
+	h.Get("/static/style.css").ExpectStatus(http.StatusOK).ExpectContentType("text/css")
+	h.Get("/static/missing.css").ExpectStatus(http.StatusNotFound)
+}
+
+func testWebSocket(t *testing.T, newHarness newHarnessFunc) {
+	h := newHarness(t, testsupport.Options{})
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(h.URL(), "http")+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.WriteMessage(websocket.TextMessage, []byte("hello"))
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, data, err := conn.ReadMessage(); err != nil || string(data) != "hello" {
+		t.Fatalf("echo = %q, %v", data, err)
+	}
+}
+
+func testStoreDown(t *testing.T, newHarness newHarnessFunc) {
+	h := newHarness(t, testsupport.Options{Users: testsupport.FailingStore{}})
+	h.Get("/readyz").ExpectStatus(http.StatusServiceUnavailable).ExpectJSONFields(`{"checks":{"store":{"status":"fail"}}}`)
+	h.Get("/users").ExpectError(http.StatusInternalServerError, "store down")
+	h.Post("/users", map[string]string{"name": "Ada"}).ExpectStatus(http.StatusInternalServerError)
+	h.Get("/dashboard").ExpectStatus(http.StatusInternalServerError)
+	// Word counts need no store.
+	h.Post("/wordcount", strings.NewReader("still counting")).ExpectStatus(http.StatusOK)
+}
+
+func TestAPIRequestID(t *testing.T) {
+	h := testsupport.New(t, testsupport.Options{})
+	res := h.Get("/healthz")
+	id := res.Header().Get(middleware.RequestIDHeader)
+	if id == "" {
+		t.Fatal("no request ID")
+	}
+	if logs := h.Logs.String(); !strings.Contains(logs, "request_id="+id) || !strings.Contains(logs, "path=/healthz") {
+		t.Errorf("request not logged with its ID:\n%s", logs)
+	}
+	h.Get("/healthz", testsupport.Header(middleware.RequestIDHeader, "trace-42")).
+		ExpectHeader(middleware.RequestIDHeader, "trace-42")
+
+	testsupport.New(t, testsupport.Options{Stack: testsupport.Bare}).Get("/healthz").
+		ExpectHeader(middleware.RequestIDHeader, "")
+}
+
+func TestAPIFullStack(t *testing.T) {
+	h := testsupport.New(t, testsupport.Options{Stack: testsupport.Full})
+
+	words := make([]string, 500)
+	for i := range words {
+		words[i] = "word" + strconv.Itoa(i)
+	}
+	h.Post("/wordcount?top=-1", strings.NewReader(strings.Join(words, " ")), testsupport.AcceptGzip()).
+		ExpectStatus(http.StatusOK).
+		ExpectHeader("Content-Encoding", "gzip").
+		ExpectJSONFields(`{"words":500,"unique":500}`)
+	h.Get("/healthz", testsupport.AcceptGzip()).ExpectHeader("Content-Encoding", "")
+
+	h.Request(http.MethodOptions, "/users", nil,
+		testsupport.Origin(testsupport.FullCORSOrigin),
+		testsupport.Header("Access-Control-Request-Method", "PUT"),
+		testsupport.Header("Access-Control-Request-Headers", "Content-Type, Authorization"),
+	).ExpectStatus(http.StatusNoContent).ExpectHeader("Access-Control-Allow-Origin", testsupport.FullCORSOrigin)
+	h.Get("/users", testsupport.Origin(testsupport.FullCORSOrigin)).
+		ExpectStatus(http.StatusOK).
+		ExpectHeader("Access-Control-Allow-Origin", testsupport.FullCORSOrigin)
+	h.Get("/users", testsupport.Origin("https://evil.example")).
+		ExpectStatus(http.StatusOK).
+		ExpectHeader("Access-Control-Allow-Origin", "")
+
+	h.Get("/metrics").ExpectBodyContains(`http_requests_total{method="GET",code="200"}`)
+}
+
+func TestAPIRateLimit(t *testing.T) {
+	limited := func(logger *slog.Logger, reg *metrics.Registry) middleware.Middleware {
+		return middleware.Chain(
+			testsupport.Serve(logger, reg),
+			middleware.RateLimit(middleware.NewLimiters(0.001, 2, time.Minute), nil),
+		)
+	}
+	h := testsupport.New(t, testsupport.Options{Stack: limited})
+	h.Get("/healthz").ExpectStatus(http.StatusOK)
+	h.Get("/users").ExpectStatus(http.StatusOK)
+	res := h.Get("/healthz").ExpectStatus(http.StatusTooManyRequests)
+	if res.Header().Get("Retry-After") == "" {
+		t.Error("no Retry-After")
+	}
+	h.Get("/healthz", func(r *http.Request) { r.RemoteAddr = "198.51.100.7:1234" }).ExpectStatus(http.StatusOK)
+}
+
+func TestAPITimeout(t *testing.T) {
+	timed := func(logger *slog.Logger, reg *metrics.Registry) middleware.Middleware {
+		return middleware.Chain(
+			testsupport.Serve(logger, reg),
+			middleware.Timeout(middleware.TimeoutConfig{Default: 20 * time.Millisecond}),
+		)
+	}
+	h := testsupport.New(t, testsupport.Options{Stack: timed})
+	h.Post("/wordcount", &slowReader{r: strings.NewReader("slow words"), delay: 200 * time.Millisecond}).
+		ExpectError(http.StatusGatewayTimeout, "timed out")
+	h.Get("/healthz").ExpectStatus(http.StatusOK)
+}
+
+// slowReader waits delay before its first read.
+type slowReader struct {
+	r     io.Reader
+	delay time.Duration
+	slept bool
+}
+
+func (s *slowReader) Read(p []byte) (int, error) {
+	if !s.slept {
+		s.slept = true
+		time.Sleep(s.delay)
+	}
+	return s.r.Read(p)
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"net/http"
+
+	"example.com/tutorial/text"
+)
+
+// compareInput is the body of POST /compare: two results of POST
+// /wordcount, or any summaries of that shape.
+type compareInput struct {
+	Before text.Summary `json:"before"`
+	After  text.Summary `json:"after"`
+}
+
+// handleCompare answers how the word frequencies of after differ from
+// those of before, as text.DiffSummaries computes them, the largest
+// changes first. The top query parameter bounds the changes listed as
+// it bounds the words of /wordcount; the totals cover them all. The
+// body is bounded as a document is.
+func (s *Server) handleCompare(w http.ResponseWriter, r *http.Request) {
+	top, err := parseTop(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	var in compareInput
+	if !decodeJSONLimit(w, r, &in, s.maxTextBytes) {
+		return
+	}
+	d := text.DiffSummaries(in.Before, in.After)
+	if top >= 0 && top < len(d.Changes) {
+		d.Changes = d.Changes[:top]
+	}
+	writeJSON(w, http.StatusOK, d)
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"example.com/tutorial/text"
+)
+
+func TestCompare(t *testing.T) {
+	h := New(nil)
+	body := `{"before":{"words":5,"unique":3,"top":[{"word":"go","count":3},{"word":"rust","count":1},{"word":"c","count":1}]},` +
+		`"after":{"words":7,"unique":3,"top":[{"word":"go","count":4},{"word":"zig","count":2},{"word":"c","count":1}]}}`
+	rec := do(t, h, "POST", "/compare", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	d := decode[text.CountDiff](t, rec)
+	if d.WordsBefore != 5 || d.WordsAfter != 7 || d.Unchanged != 1 || len(d.Changes) != 3 {
+		t.Fatalf("got %+v", d)
+	}
+	if c := d.Changes[0]; c.Word != "zig" || c.Kind != text.Added || c.Delta != 2 {
+		t.Errorf("largest change %+v", c)
+	}
+
+	rec = do(t, h, "POST", "/compare?top=1", body)
+	if d := decode[text.CountDiff](t, rec); len(d.Changes) != 1 || d.WordsAfter != 7 {
+		t.Errorf("top=1: %+v", d)
+	}
+	for _, tc := range []struct {
+		path, body string
+		want       int
+	}{
+		{"/compare?top=x", body, http.StatusBadRequest},
+		{"/compare", `{"before":{},"later":{}}`, http.StatusBadRequest},
+		{"/compare", `{"before":`, http.StatusBadRequest},
+	} {
+		if rec := do(t, h, "POST", tc.path, tc.body); rec.Code != tc.want {
+			t.Errorf("POST %s %s: status %d, want %d", tc.path, tc.body, rec.Code, tc.want)
+		}
+	}
+}
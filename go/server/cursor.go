@@ -0,0 +1,82 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+
+	"example.com/tutorial/i18n"
+)
+
+// mimeNDJSON is newline-delimited JSON, one value per line, which the
+// list endpoints stream instead of building one large document.
+const mimeNDJSON = "application/x-ndjson"
+
+// errBadCursor answers a cursor parameter that is not one the server
+// handed out for the same query.
+var errBadCursor = i18n.NewError(nil, "invalid cursor")
+
+// encodeCursor makes the opaque cursor parameter for v, the position a
+// page ended at: base64url of its JSON, so that clients pass it back
+// without interpreting it.
+func encodeCursor(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err) // cursors are plain structs
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor reads the cursor parameter of r into v, reporting
+// whether there was one. A cursor that does not decode is errBadCursor.
+func decodeCursor(r *http.Request, v any) (bool, error) {
+	s := r.URL.Query().Get("cursor")
+	if s == "" {
+		return false, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(b, v) != nil {
+		return true, errBadCursor
+	}
+	return true, nil
+}
+
+// streamer writes values as NDJSON, flushing every flushEvery of them so
+// that the client receives them as they are made.
+type streamer struct {
+	rc  *http.ResponseController
+	enc *json.Encoder
+	n   int
+}
+
+// flushEvery is how many values a streamer writes between flushes.
+const flushEvery = 100
+
+// newStreamer starts an NDJSON response of status 200 on w.
+func newStreamer(w http.ResponseWriter) *streamer {
+	w.Header().Set("Content-Type", mimeNDJSON+"; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	return &streamer{rc: http.NewResponseController(w), enc: json.NewEncoder(w)}
+}
+
+// send writes v on a line of its own. An error means the client is
+// gone.
+func (s *streamer) send(v any) error {
+	if err := s.enc.Encode(v); err != nil {
+		return err
+	}
+	if s.n++; s.n%flushEvery == 0 {
+		s.flush()
+	}
+	return nil
+}
+
+// flush sends what has been written so far.
+func (s *streamer) flush() { s.rc.Flush() }
+
+// fail ends the stream with a line {"error": ...} for err, as the status
+// has been sent already.
+func (s *streamer) fail(r *http.Request, err error) {
+	s.enc.Encode(errorBody{Error: i18n.For(r.Header.Get("Accept-Language")).Error(err)})
+	s.flush()
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"example.com/tutorial/errs"
+	"example.com/tutorial/text"
+	"example.com/tutorial/user"
+)
+
+//go:embed templates
+var templateFiles embed.FS
+
+// TemplateFiles returns the HTML templates built into the binary:
+// layout.html, which every page fills in, and the pages dashboard.html
+// and error.html.
+func TemplateFiles() fs.FS {
+	sub, err := fs.Sub(templateFiles, "templates")
+	if err != nil {
+		panic(err) // the directory is embedded, so this cannot happen
+	}
+	return sub
+}
+
+// Templates are the parsed HTML pages of a Server, each parsed once
+// together with the shared layout and then reused for every request.
+type Templates struct {
+	pages map[string]*template.Template
+}
+
+// pageNames are the pages a Templates has.
+var pageNames = []string{"dashboard.html", "error.html"}
+
+// defaultTemplates are those of TemplateFiles. They are embedded, so a
+// failure is a bug caught by the tests.
+var defaultTemplates = mustParseTemplates(TemplateFiles())
+
+// ParseTemplates parses the layout.html of fsys with each of its pages,
+// laid out as in TemplateFiles, typically an assets.Override of it.
+func ParseTemplates(fsys fs.FS) (*Templates, error) {
+	layout, err := template.ParseFS(fsys, "layout.html")
+	if err != nil {
+		return nil, fmt.Errorf("server: templates: %w", err)
+	}
+	t := &Templates{pages: make(map[string]*template.Template, len(pageNames))}
+	for _, name := range pageNames {
+		page := template.Must(layout.Clone())
+		if t.pages[name], err = page.ParseFS(fsys, name); err != nil {
+			return nil, fmt.Errorf("server: templates: %w", err)
+		}
+	}
+	return t, nil
+}
+
+func mustParseTemplates(fsys fs.FS) *Templates {
+	t, err := ParseTemplates(fsys)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// dashboardUsers is how many users the dashboard lists.
+const dashboardUsers = 50
+
+// dashboardData is the view of dashboard.html.
+type dashboardData struct {
+	Text   string
+	Top    int
+	Result *wordCountJSON
+	Users  []user.User
+	Total  int
+}
+
+// errorData is the view of error.html.
+type errorData struct {
+	Status     int
+	StatusText string
+	Message    string
+}
+
+// handleDashboard shows the users and a form for counting words; a POST
+// of the form shows the counts as well.
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	d := dashboardData{Top: defaultTopWords}
+	if r.Method == http.MethodPost {
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxTextBytes)
+		if err := r.ParseForm(); err != nil {
+			s.writeHTMLError(w, uploadStatus(err), err)
+			return
+		}
+		d.Text = r.PostForm.Get("text")
+		if v := r.PostForm.Get("top"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				s.writeHTMLError(w, http.StatusBadRequest, errors.New("top must be a positive integer"))
+				return
+			}
+			d.Top = n
+		}
+		counts, err := text.WordCountReader(strings.NewReader(d.Text))
+		if err != nil {
+			s.writeHTMLError(w, http.StatusBadRequest, err)
+			return
+		}
+		d.Result = &wordCountJSON{Unique: len(counts), Top: text.TopWords(counts, d.Top)}
+		for _, c := range counts {
+			d.Result.Words += c
+		}
+	}
+	if s.users != nil {
+		page, err := s.users.List(r.Context(), user.ListQuery{Sort: user.SortByName, Limit: dashboardUsers})
+		if err != nil {
+			s.writeHTMLError(w, errs.HTTPStatus(err), err)
+			return
+		}
+		d.Users, d.Total = page.Users, page.Total
+	}
+	s.writeHTML(w, http.StatusOK, "dashboard.html", d)
+}
+
+// writeHTML executes a page into a buffer first, so that a template error
+// becomes an error page rather than half a page.
+func (s *Server) writeHTML(w http.ResponseWriter, status int, name string, data any) {
+	var buf bytes.Buffer
+	if err := s.templates.pages[name].Execute(&buf, data); err != nil {
+		if name != "error.html" {
+			s.writeHTMLError(w, http.StatusInternalServerError, err)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
+
+// writeHTMLError shows the HTML error page.
+func (s *Server) writeHTMLError(w http.ResponseWriter, status int, err error) {
+	s.writeHTML(w, status, "error.html", errorData{
+		Status:     status,
+		StatusText: http.StatusText(status),
+		Message:    err.Error(),
+	})
+}
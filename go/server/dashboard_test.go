@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"example.com/tutorial/assets"
+	"example.com/tutorial/user"
+)
+
+func postForm(t *testing.T, h http.Handler, path string, form url.Values) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestDashboard(t *testing.T) {
+	store := user.NewMemoryStore()
+	for _, name := range []string{"<script>", "Ada"} {
+		store.Create(context.Background(), user.User{Name: name})
+	}
+	h := New(store)
+
+	rec := do(t, h, "GET", "/dashboard", "")
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("GET = %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(body, "<td>Ada</td>") || !strings.Contains(body, "&lt;script&gt;") || strings.Contains(body, "<td><script>") {
+		t.Fatalf("user table not rendered or not escaped:\n%s", body)
+	}
+	// "<" sorts before letters.
+	if strings.Index(body, "&lt;script&gt;") > strings.Index(body, "<td>Ada") {
+		t.Fatal("users not sorted by name")
+	}
+
+	rec = postForm(t, h, "/dashboard", url.Values{"text": {"the cat and the hat"}, "top": {"1"}})
+	body = rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, "5 words, 4 distinct") ||
+		!strings.Contains(body, `<td>the</td><td class="num">2</td>`) || strings.Contains(body, "<td>cat</td>") {
+		t.Fatalf("POST = %d:\n%s", rec.Code, body)
+	}
+	if !strings.Contains(body, ">the cat and the hat</textarea>") {
+		t.Fatal("form does not keep the text")
+	}
+
+	if rec := do(t, New(nil), "GET", "/dashboard", ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "No users yet") {
+		t.Fatalf("without a store: %d", rec.Code)
+	}
+}
+
+func TestDashboardErrors(t *testing.T) {
+	s := New(nil)
+	s.maxTextBytes = 32
+	tests := []struct {
+		form url.Values
+		want int
+	}{
+		{url.Values{"text": {"a"}, "top": {"none"}}, http.StatusBadRequest},
+		{url.Values{"text": {strings.Repeat("word ", 10)}}, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		rec := postForm(t, s, "/dashboard", tt.form)
+		if rec.Code != tt.want || !strings.Contains(rec.Body.String(), `<p class="error">`) ||
+			!strings.Contains(rec.Body.String(), http.StatusText(tt.want)) {
+			t.Errorf("%v: %d\n%s", tt.form, rec.Code, rec.Body)
+		}
+	}
+
+	rec := do(t, New(failingStore{}), "GET", "/dashboard", "")
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "disk on fire") {
+		t.Fatalf("store failure: %d\n%s", rec.Code, rec.Body)
+	}
+}
+
+// failingStore fails every List call.
+type failingStore struct{ user.Store }
+
+func (failingStore) List(context.Context, user.ListQuery) (user.Page, error) {
+	return user.Page{}, errors.New("disk on fire")
+}
+
+func TestParseTemplates(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "layout.html"), []byte(`<main class="custom">{{template "content" .}}</main>`), 0o644)
+	tmpl, err := ParseTemplates(assets.Override(TemplateFiles(), dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := do(t, New(nil, WithConfig(Config{Templates: tmpl})), "GET", "/dashboard", "")
+	if body := rec.Body.String(); rec.Code != http.StatusOK || !strings.HasPrefix(body, `<main class="custom">`) || !strings.Contains(body, "No users yet") {
+		t.Fatalf("GET = %d:\n%s", rec.Code, body)
+	}
+
+	os.WriteFile(filepath.Join(dir, "error.html"), []byte(`{{define "content"}}{{.Missing`), 0o644)
+	if _, err := ParseTemplates(assets.Override(TemplateFiles(), dir)); err == nil || !strings.Contains(err.Error(), "error.html") {
+		t.Fatalf("broken page: %v", err)
+	}
+	if _, err := ParseTemplates(fstest.MapFS{}); err == nil {
+		t.Fatal("parsed templates without a layout")
+	}
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"example.com/tutorial/bus"
+	"example.com/tutorial/id"
+	"example.com/tutorial/logging"
+	"example.com/tutorial/text"
+)
+
+// Default timing of the /wordcount/events stream.
+const (
+	defaultProgressInterval = 250 * time.Millisecond
+	defaultHeartbeat        = 15 * time.Second
+)
+
+// progressJSON is the data of a "progress" event.
+type progressJSON struct {
+	Bytes int64 `json:"bytes"`
+	Words int64 `json:"words"`
+}
+
+// progressReader counts the bytes read through it and stops with the
+// context's error once ctx is done.
+type progressReader struct {
+	ctx   context.Context
+	r     io.Reader
+	bytes atomic.Int64
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	if err := p.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := p.r.Read(b)
+	p.bytes.Add(int64(n))
+	return n, err
+}
+
+// handleWordCountEvents counts the words of the request body like
+// POST /wordcount, but answers with a text/event-stream: "progress"
+// events with the bytes and words seen so far whenever they change,
+// comment heartbeats while nothing else is sent, and finally a "result"
+// event with the frequency table or an "error" event. Counting stops
+// when the client goes away.
+func (s *Server) handleWordCountEvents(w http.ResponseWriter, r *http.Request) {
+	top, err := parseTop(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	release, err := s.acquireCounting(r)
+	if err != nil {
+		writeError(w, r, http.StatusServiceUnavailable, err)
+		return
+	}
+	defer release()
+	rc := http.NewResponseController(w)
+	// The body is still being read while events are written; HTTP/1.x
+	// servers need to be told that is intended.
+	rc.EnableFullDuplex()
+
+	ctx := logging.WithJobID(r.Context(), id.New())
+	// The limit is enforced without w: MaxBytesReader would tell it to
+	// close the connection from the counting goroutine, racing with the
+	// events written here.
+	body := &progressReader{ctx: ctx, r: http.MaxBytesReader(nil, r.Body, s.maxTextBytes)}
+	var words atomic.Int64
+	type result struct {
+		counts map[string]int
+		err    error
+	}
+	done := make(chan result, 1)
+	start := time.Now()
+	go func() {
+		counts := map[string]int{}
+		err := text.ScanWords(body, func(word string) {
+			counts[word]++
+			words.Add(1)
+		})
+		done <- result{counts, err}
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+
+	progress := time.NewTicker(s.progressInterval)
+	defer progress.Stop()
+	heartbeat := time.NewTicker(s.heartbeat)
+	defer heartbeat.Stop()
+	var last progressJSON
+	for {
+		select {
+		case <-ctx.Done():
+			// The counting stops at its next read; wait for it, so that
+			// it neither reads the body nor holds its counting slot past
+			// the handler.
+			<-done
+			return
+		case <-progress.C:
+			p := progressJSON{Bytes: body.bytes.Load(), Words: words.Load()}
+			if p == last {
+				continue
+			}
+			writeEvent(w, "progress", p)
+			last = p
+			heartbeat.Reset(s.heartbeat)
+		case <-heartbeat.C:
+			io.WriteString(w, ": heartbeat\n\n")
+		case res := <-done:
+			s.finishJob(ctx, "/wordcount/events", start, body.bytes.Load(), words.Load(), res.err)
+			if res.err != nil {
+				writeEvent(w, "error", errorBody{Error: res.err.Error()})
+			} else {
+				writeEvent(w, "progress", progressJSON{Bytes: body.bytes.Load(), Words: words.Load()})
+				out := wordCountJSON{Words: int(words.Load()), Unique: len(res.counts), Top: text.TopWords(res.counts, top)}
+				if out.Top == nil {
+					out.Top = []text.WordFreq{}
+				}
+				writeEvent(w, "result", out)
+			}
+			rc.Flush()
+			return
+		}
+		rc.Flush()
+	}
+}
+
+// jobEvent is the data of a "job" event on GET /wordcount/events,
+// describing one finished word-count job.
+type jobEvent struct {
+	ID         string  `json:"id"`
+	Endpoint   string  `json:"endpoint"`
+	Bytes      int64   `json:"bytes"`
+	Words      int64   `json:"words"`
+	DurationMS float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// jobsTopic is the bus topic of jobEvents.
+const jobsTopic = "jobs"
+
+// jobEventBuffer is how many events a GET /wordcount/events client may
+// fall behind by before it misses some.
+const jobEventBuffer = 64
+
+// finishJob records a finished job in the metrics and the log, and
+// announces it to the job-event listeners. ctx carries the job ID.
+func (s *Server) finishJob(ctx context.Context, endpoint string, start time.Time, bytes, words int64, err error) {
+	s.jobs.record(start, bytes, words, err)
+	ev := jobEvent{
+		ID:         logging.JobID(ctx),
+		Endpoint:   endpoint,
+		Bytes:      bytes,
+		Words:      words,
+		DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	level := slog.LevelInfo
+	args := []any{"endpoint", endpoint, "bytes", bytes, "words", words, "duration_ms", ev.DurationMS}
+	if err != nil {
+		ev.Error = err.Error()
+		level = slog.LevelWarn
+		args = append(args, "err", err)
+	}
+	s.logger.Log(ctx, level, "job finished", args...)
+	// Listeners drop rather than block, so this never waits, and after
+	// Close there is nobody to tell.
+	s.done.Publish(context.Background(), jobsTopic, ev)
+}
+
+// handleJobEvents streams a "job" event for every word-count job that
+// finishes on any endpoint while the client is connected, with comment
+// heartbeats in between. A client too slow to keep up misses events
+// rather than delaying the jobs; the stream ends when the server closes.
+func (s *Server) handleJobEvents(w http.ResponseWriter, r *http.Request) {
+	sub := s.done.Subscribe(jobsTopic, bus.SubscribeConfig{Buffer: jobEventBuffer, Policy: bus.Drop})
+	defer sub.Unsubscribe()
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+
+	heartbeat := time.NewTicker(s.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-sub.C():
+			if !ok {
+				return
+			}
+			writeEvent(w, "job", ev)
+			heartbeat.Reset(s.heartbeat)
+		case <-heartbeat.C:
+			io.WriteString(w, ": heartbeat\n\n")
+		}
+		rc.Flush()
+	}
+}
+
+// writeEvent writes one server-sent event with v encoded as JSON.
+func writeEvent(w io.Writer, event string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(errorBody{Error: err.Error()})
+		event = "error"
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
@@ -0,0 +1,252 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/tutorial/id"
+	"example.com/tutorial/logging"
+)
+
+type sseEvent struct {
+	name string // "" for a comment
+	data string
+}
+
+// readEvents parses a text/event-stream into events, sending each as
+// soon as it is complete.
+func readEvents(r io.Reader) <-chan sseEvent {
+	ch := make(chan sseEvent)
+	go func() {
+		defer close(ch)
+		sc := bufio.NewScanner(r)
+		var ev sseEvent
+		comment := false
+		for sc.Scan() {
+			line := sc.Text()
+			switch {
+			case line == "":
+				if ev.name != "" || comment {
+					ch <- ev
+				}
+				ev, comment = sseEvent{}, false
+			case strings.HasPrefix(line, ":"):
+				comment = true
+			case strings.HasPrefix(line, "event: "):
+				ev.name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				ev.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return ch
+}
+
+func next(t *testing.T, events <-chan sseEvent) sseEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		if !ok {
+			t.Fatal("stream ended")
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+	}
+	return sseEvent{}
+}
+
+func TestWordCountEvents(t *testing.T) {
+	s := New(nil)
+	s.progressInterval = 10 * time.Millisecond
+	s.heartbeat = 20 * time.Millisecond
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	// Feed the body in two halves with a pause, so progress is visible
+	// while it is still being read.
+	pr, pw := io.Pipe()
+	req, _ := http.NewRequest("POST", ts.URL+"/wordcount/events?top=1", pr)
+	req.Header.Set("Content-Type", "text/plain")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	events := readEvents(resp.Body)
+
+	io.WriteString(pw, "one two two ")
+	for {
+		ev := next(t, events)
+		var p progressJSON
+		if ev.name == "progress" && json.Unmarshal([]byte(ev.data), &p) == nil && p.Words == 3 {
+			if p.Bytes != 12 {
+				t.Fatalf("progress = %+v", p)
+			}
+			break
+		}
+	}
+	// While the client is idle the stream stays alive.
+	for ev := next(t, events); ev.name != ""; ev = next(t, events) {
+	}
+	io.WriteString(pw, "three two")
+	pw.Close()
+
+	for {
+		ev := next(t, events)
+		if ev.name != "result" {
+			continue
+		}
+		var res wordCountJSON
+		if err := json.Unmarshal([]byte(ev.data), &res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Words != 5 || res.Unique != 3 || len(res.Top) != 1 || res.Top[0].Word != "two" {
+			t.Fatalf("result = %+v", res)
+		}
+		break
+	}
+}
+
+func TestWordCountEventsTooLarge(t *testing.T) {
+	s := New(nil)
+	s.maxTextBytes = 8
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	resp, err := http.Post(ts.URL+"/wordcount/events", "text/plain", strings.NewReader("far too many words for the limit"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	events := readEvents(resp.Body)
+	for ev := next(t, events); ; ev = next(t, events) {
+		if ev.name == "error" {
+			break
+		}
+		if ev.name == "result" {
+			t.Fatalf("oversized body counted: %s", ev.data)
+		}
+	}
+}
+
+func TestWordCountEventsClientDisconnect(t *testing.T) {
+	s := New(nil)
+	s.progressInterval = 5 * time.Millisecond
+	finished := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.ServeHTTP(w, r)
+		close(finished)
+	}))
+	defer ts.Close()
+
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "POST", ts.URL+"/wordcount/events", pr)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(pw, "some words ")
+	next(t, readEvents(resp.Body))
+	cancel()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler still running after the client went away")
+	}
+}
+
+func TestWordCountEventsBadTop(t *testing.T) {
+	if rec := do(t, New(nil), "POST", "/wordcount/events?top=-2", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("status %d", rec.Code)
+	}
+}
+
+func TestJobEvents(t *testing.T) {
+	s := New(nil)
+	s.heartbeat = 10 * time.Millisecond
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	defer s.Close()
+
+	resp, err := http.Get(ts.URL + "/wordcount/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	// The headers are sent once the listener is subscribed.
+	events := readEvents(resp.Body)
+
+	postText(t, s, "/wordcount", "text/plain", []byte("one two three"))
+	s.maxTextBytes = 4
+	postText(t, s, "/wordcount", "text/plain", []byte("far too long"))
+
+	var got []jobEvent
+	for len(got) < 2 {
+		ev := next(t, events)
+		if ev.name != "job" {
+			continue
+		}
+		var j jobEvent
+		if err := json.Unmarshal([]byte(ev.data), &j); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, j)
+	}
+	if got[0].Endpoint != "/wordcount" || got[0].Words != 3 || got[0].Error != "" || got[0].ID == "" || got[0].ID == got[1].ID {
+		t.Errorf("first job = %+v", got[0])
+	}
+	if got[1].Error == "" {
+		t.Errorf("second job = %+v, want an error", got[1])
+	}
+
+	// Closing the server ends the stream.
+	s.Close()
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("stream still open after Close")
+		}
+	}
+}
+
+func TestJobLog(t *testing.T) {
+	var logs bytes.Buffer
+	s := NewWithConfig(nil, Config{Logger: logging.New(&logs, logging.Options{Format: logging.JSON})})
+	defer s.Close()
+	postText(t, s, "/wordcount", "text/plain", []byte("one two three"))
+
+	var entry struct {
+		Msg       string
+		Component string
+		JobID     string `json:"job_id"`
+		Endpoint  string
+		Words     int
+	}
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("decode %q: %v", logs.String(), err)
+	}
+	if entry.Msg != "job finished" || entry.Component != "server" || len(entry.JobID) != id.Len ||
+		entry.Endpoint != "/wordcount" || entry.Words != 3 {
+		t.Fatalf("log entry = %+v", entry)
+	}
+}
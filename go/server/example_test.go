@@ -0,0 +1,77 @@
+package server_test
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"time"
+
+	"example.com/tutorial/health"
+	"example.com/tutorial/middleware"
+	"example.com/tutorial/server"
+	"example.com/tutorial/user"
+)
+
+func ExampleNew() {
+	s := server.New(user.NewMemoryStore(),
+		server.WithConfig(server.Config{StaticListings: true}),
+		server.WithTimeout(30*time.Second))
+
+	req := httptest.NewRequest("POST", "/users", strings.NewReader(`{"name":"Ada"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	fmt.Println(rec.Code, strings.HasPrefix(rec.Header().Get("Location"), "/users/"))
+	// Output: 201 true
+}
+
+func ExampleWithTimeout() {
+	slow := health.CheckerFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	s := server.New(nil,
+		server.WithConfig(server.Config{Checks: map[string]health.Checker{"slow": slow}}),
+		server.WithTimeout(10*time.Millisecond))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+	fmt.Print(rec.Code, " ", rec.Body)
+	// Output: 504 {"error":"request timed out"}
+}
+
+func ExampleWithMiddleware() {
+	poweredBy := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Powered-By", "gophers")
+			next.ServeHTTP(w, r)
+		})
+	}
+	s := server.New(nil, server.WithMiddleware(middleware.RequestID(), poweredBy))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	fmt.Println(rec.Code, rec.Header().Get("X-Powered-By"), len(rec.Header().Get(middleware.RequestIDHeader)) > 0)
+	// Output: 200 gophers true
+}
+
+func ExampleWithLogger() {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		// Leave out what changes from run to run.
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "duration_ms" {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	s := server.New(nil, server.WithLogger(logger))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("POST", "/wordcount", strings.NewReader("to be or not to be")))
+	// Output: level=INFO msg="job finished" component=server endpoint=/wordcount bytes=18 words=6
+}
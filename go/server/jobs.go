@@ -0,0 +1,73 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"example.com/tutorial/errs"
+	"example.com/tutorial/i18n"
+	"example.com/tutorial/id"
+	"example.com/tutorial/jobs"
+	"example.com/tutorial/router"
+	"example.com/tutorial/validate"
+)
+
+// errNoSuchJob answers GET /jobs/{id} for an ID the job service never
+// handed out.
+var errNoSuchJob = i18n.NewError(errs.ErrNotFound, "no such job")
+
+// jobInput is the body of POST /jobs.
+type jobInput struct {
+	Name string `json:"name"`
+	Text string `json:"text"`
+	// Top is as in jobs.Job: 0 means jobs.DefaultTop and -1 every word.
+	Top int `json:"top"`
+}
+
+func (in jobInput) Validate() error {
+	var v validate.Validator
+	v.Required("text", in.Text)
+	v.Check(in.Top >= -1, "top", "must be -1 or more")
+	return v.Err()
+}
+
+// submitJob queues a word count of the text of a JSON body such as
+// {"name":"doc.txt","text":"...","top":10} and answers 202 at once with
+// the job's status, which GET /jobs/{id}, in the Location header, then
+// follows to the result. The text is bounded as for /wordcount.
+func (s *Server) submitJob(w http.ResponseWriter, r *http.Request) {
+	var in jobInput
+	if !decodeJSONLimit(w, r, &in, s.maxTextBytes) {
+		return
+	}
+	if err := in.Validate(); err != nil {
+		writeError(w, r, http.StatusUnprocessableEntity, err)
+		return
+	}
+	st, err := s.jobQueue.Submit(r.Context(), jobs.Job{Name: in.Name, Text: in.Text, Top: in.Top})
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, jobs.ErrClosed) {
+			status = http.StatusServiceUnavailable
+		}
+		writeError(w, r, status, err)
+		return
+	}
+	w.Header().Set("Location", "/jobs/"+st.ID)
+	writeJSON(w, http.StatusAccepted, st)
+}
+
+// getJob answers the status of a job, with its result once it is done.
+func (s *Server) getJob(w http.ResponseWriter, r *http.Request) {
+	jobID := router.Param(r, "id")
+	if !id.Valid(jobID) {
+		fail(w, r, errNoSuchJob)
+		return
+	}
+	st, err := s.jobQueue.Get(jobID)
+	if err != nil {
+		fail(w, r, errNoSuchJob)
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"example.com/tutorial/jobs"
+)
+
+func TestJobs(t *testing.T) {
+	svc, err := jobs.NewService(jobs.ServiceConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- svc.Run(context.Background()) }()
+	defer func() {
+		svc.Close()
+		if err := <-errc; err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	}()
+	h := New(nil, WithConfig(Config{Jobs: svc}))
+
+	rec := do(t, h, "POST", "/jobs", `{"name":"doc.txt","text":"the cat and the hat","top":1}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("POST status %d: %s", rec.Code, rec.Body)
+	}
+	st := decode[jobs.Status](t, rec)
+	if st.ID == "" || st.State != jobs.Queued || rec.Header().Get("Location") != "/jobs/"+st.ID {
+		t.Fatalf("submitted %+v, Location %q", st, rec.Header().Get("Location"))
+	}
+	for deadline := time.Now().Add(5 * time.Second); !st.Final(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("job still %s", st.State)
+		}
+		rec := do(t, h, "GET", "/jobs/"+st.ID, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET status %d: %s", rec.Code, rec.Body)
+		}
+		st = decode[jobs.Status](t, rec)
+	}
+	if st.State != jobs.Done || st.Name != "doc.txt" || st.Result == nil || st.Result.Words != 5 || len(st.Result.Top) != 1 {
+		t.Fatalf("finished %+v", st)
+	}
+
+	for _, tc := range []struct {
+		name, method, path, body string
+		header                   []string
+		want                     int
+	}{
+		{"no text", "POST", "/jobs", `{"name":"x"}`, nil, http.StatusUnprocessableEntity},
+		{"bad top", "POST", "/jobs", `{"text":"x","top":-2}`, nil, http.StatusUnprocessableEntity},
+		{"unknown field", "POST", "/jobs", `{"text":"x","id":"mine"}`, nil, http.StatusBadRequest},
+		{"not JSON", "POST", "/jobs", `text`, []string{"Content-Type", "text/plain"}, http.StatusUnsupportedMediaType},
+		{"unknown job", "GET", "/jobs/nobody", "", nil, http.StatusNotFound},
+		{"bad ID", "GET", "/jobs/x.y", "", nil, http.StatusNotFound},
+	} {
+		if rec := do(t, h, tc.method, tc.path, tc.body, tc.header...); rec.Code != tc.want {
+			t.Errorf("%s: status %d, want %d: %s", tc.name, rec.Code, tc.want, rec.Body)
+		}
+	}
+
+	svc.Close()
+	if rec := do(t, h, "POST", "/jobs", `{"text":"x"}`); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("closed queue: status %d", rec.Code)
+	}
+	if rec := do(t, New(nil), "GET", "/jobs/"+st.ID, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("without a job service: status %d", rec.Code)
+	}
+}
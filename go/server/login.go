@@ -0,0 +1,60 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"example.com/tutorial/auth"
+	"example.com/tutorial/i18n"
+)
+
+// loginInput is the body accepted by POST /login.
+type loginInput struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+// tokenJSON is the response of POST /login.
+type tokenJSON struct {
+	Token     string    `json:"token"`
+	UserID    string    `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// handleLogin exchanges a name and password for a bearer token.
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var in loginInput
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+	u, err := auth.Authenticate(r.Context(), s.users, in.Name, in.Password)
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		writeError(w, r, http.StatusUnauthorized, err)
+		return
+	}
+	if err != nil {
+		fail(w, r, err)
+		return
+	}
+	token, claims, err := s.auth.Issue(u.ID, u.Name)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tokenJSON{Token: token, UserID: u.ID, ExpiresAt: time.Unix(claims.ExpiresAt, 0).UTC()})
+}
+
+// authorized reports whether the caller may change user id, answering
+// 403 itself if not. Without authentication configured anyone may.
+func (s *Server) authorized(w http.ResponseWriter, r *http.Request, id string) bool {
+	if s.auth == nil {
+		return true
+	}
+	c, _ := auth.ClaimsFrom(r.Context())
+	if sub, err := c.UserID(); err != nil || sub != id {
+		writeError(w, r, http.StatusForbidden, i18n.NewError(nil, "a user may only change their own account"))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"example.com/tutorial/auth"
+	"example.com/tutorial/user"
+)
+
+func TestLoginProtectsMutations(t *testing.T) {
+	j := auth.NewJWT([]byte("0123456789abcdef0123456789abcdef"), time.Hour, "")
+	h := NewWithConfig(user.NewMemoryStore(), Config{Auth: j})
+	var ids []string
+	for _, body := range []string{`{"name":"ada","password":"analytical engine"}`, `{"name":"bob","password":"difference engine"}`} {
+		rec := do(t, h, "POST", "/users", body)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("sign-up without a token: %d %s", rec.Code, rec.Body)
+		}
+		ids = append(ids, decode[userJSON](t, rec).ID)
+	}
+	ada, bob := "/users/"+ids[0], "/users/"+ids[1]
+
+	if rec := do(t, h, "POST", "/login", `{"name":"ada","password":"wrong"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("bad password: %d", rec.Code)
+	}
+	rec := do(t, h, "POST", "/login", `{"name":"ada","password":"analytical engine"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login: %d %s", rec.Code, rec.Body)
+	}
+	tok := decode[tokenJSON](t, rec)
+	if tok.UserID != ids[0] || tok.Token == "" || time.Until(tok.ExpiresAt) <= 0 {
+		t.Fatalf("token = %+v", tok)
+	}
+	bearer := "Bearer " + tok.Token
+
+	tests := []struct {
+		method, path, body string
+		authz              string
+		want               int
+	}{
+		{"PUT", ada, `{"name":"Ada"}`, "", http.StatusUnauthorized},
+		{"DELETE", ada, "", "Bearer forged.token.value", http.StatusUnauthorized},
+		{"PUT", bob, `{"name":"Bobby"}`, bearer, http.StatusForbidden},
+		{"DELETE", bob, "", bearer, http.StatusForbidden},
+		{"GET", bob, "", "", http.StatusOK},
+		{"PUT", ada, `{"name":"Ada"}`, bearer, http.StatusOK},
+		{"DELETE", ada, "", bearer, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		var hdr []string
+		if tt.authz != "" {
+			hdr = []string{"Authorization", tt.authz}
+		}
+		if rec := do(t, h, tt.method, tt.path, tt.body, hdr...); rec.Code != tt.want {
+			t.Errorf("%s %s (%q): %d, want %d (%s)", tt.method, tt.path, tt.authz, rec.Code, tt.want, rec.Body)
+		}
+	}
+
+	if rec := do(t, New(user.NewMemoryStore()), "POST", "/login", `{"name":"ada"}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("login without auth configured: %d", rec.Code)
+	}
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"time"
+
+	"example.com/tutorial/metrics"
+)
+
+// jobMetrics records the word-count jobs run by /wordcount and
+// /wordcount/events.
+type jobMetrics struct {
+	jobs     *metrics.CounterVec
+	words    *metrics.Counter
+	bytes    *metrics.Counter
+	duration *metrics.Histogram
+	shared   *metrics.Counter
+}
+
+func newJobMetrics(reg *metrics.Registry) *jobMetrics {
+	return &jobMetrics{
+		jobs:     reg.Counter("wordcount_jobs_total", "Word-count jobs run, by result (ok or error).", "result"),
+		words:    reg.Counter("wordcount_words_total", "Words counted by successful jobs.").With(),
+		bytes:    reg.Counter("wordcount_bytes_total", "Bytes read by word-count jobs.").With(),
+		duration: reg.Histogram("wordcount_job_duration_seconds", "Word-count job duration in seconds.", nil).With(),
+		shared:   reg.Counter("wordcount_shared_total", "POST /wordcount requests answered by a count run for an identical concurrent request.").With(),
+	}
+}
+
+// record reports one finished job.
+func (m *jobMetrics) record(start time.Time, bytes, words int64, err error) {
+	m.duration.Observe(time.Since(start).Seconds())
+	m.bytes.Add(float64(bytes))
+	if err != nil {
+		m.jobs.With("error").Inc()
+		return
+	}
+	m.jobs.With("ok").Inc()
+	m.words.Add(float64(words))
+}
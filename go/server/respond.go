@@ -0,0 +1,149 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"example.com/tutorial/errs"
+	"example.com/tutorial/i18n"
+	"example.com/tutorial/validate"
+)
+
+const (
+	mimeJSON = "application/json"
+	mimeText = "text/plain"
+)
+
+// errorBody is the JSON shape of every error response.
+type errorBody struct {
+	Error  string                `json:"error"`
+	Fields []validate.FieldError `json:"fields,omitempty"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", mimeJSON+"; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// errNotFound answers requests for paths and files that do not exist.
+var errNotFound = i18n.NewError(errs.ErrNotFound, "not found")
+
+// writeError reports err with the given status, in the language the
+// request's Accept-Language header prefers if it is one of i18n's.
+// Validation errors are always reported as 422 with the offending fields
+// listed.
+func writeError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	p := i18n.For(r.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", p.Tag().String())
+	body := errorBody{Error: p.Error(err)}
+	var verr validate.Errors
+	if errors.As(err, &verr) {
+		status = http.StatusUnprocessableEntity
+		body.Error = p.Sprintf("validation failed")
+		body.Fields = verr
+	}
+	writeJSON(w, status, body)
+}
+
+// fail reports err with the status of its kind, as errs.HTTPStatus
+// maps it: store, validation and lookup errors all say what went wrong.
+func fail(w http.ResponseWriter, r *http.Request, err error) {
+	writeError(w, r, errs.HTTPStatus(err), err)
+}
+
+// negotiate picks the offer the client prefers according to its Accept
+// header, or "" if none is acceptable. A missing header accepts anything,
+// and ties go to the earlier offer.
+func negotiate(r *http.Request, offers ...string) string {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return offers[0]
+	}
+	type rng struct {
+		typ string
+		q   float64
+	}
+	var ranges []rng
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		ranges = append(ranges, rng{mt, q})
+	}
+	// More specific ranges take precedence over wildcards.
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return strings.Count(ranges[i].typ, "*") < strings.Count(ranges[j].typ, "*")
+	})
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		for _, rg := range ranges {
+			if matchMedia(rg.typ, offer) {
+				if rg.q > bestQ {
+					best, bestQ = offer, rg.q
+				}
+				break
+			}
+		}
+	}
+	return best
+}
+
+func matchMedia(pattern, typ string) bool {
+	if pattern == "*/*" || pattern == typ {
+		return true
+	}
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(typ, strings.TrimSuffix(pattern, "*"))
+	}
+	return false
+}
+
+// requireJSON rejects request bodies that are not declared as JSON.
+func requireJSON(w http.ResponseWriter, r *http.Request) bool {
+	mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mt != mimeJSON {
+		writeError(w, r, http.StatusUnsupportedMediaType, i18n.NewError(nil, "request body must be application/json"))
+		return false
+	}
+	return true
+}
+
+// maxBodyBytes bounds the size of JSON request bodies.
+const maxBodyBytes = 1 << 20
+
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	return decodeJSONLimit(w, r, v, maxBodyBytes)
+}
+
+// decodeJSONLimit is decodeJSON for bodies of up to limit bytes, such as
+// those carrying documents, which are bounded as documents are.
+func decodeJSONLimit(w http.ResponseWriter, r *http.Request, v any, limit int64) bool {
+	if !requireJSON(w, r) {
+		return false
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, limit))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeError(w, r, status, i18n.NewError(nil, "invalid JSON body: %v", err))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+
+	"example.com/tutorial/ctxutil"
+	"example.com/tutorial/shutdown"
+)
+
+// DefaultGracePeriod is how long Run waits for in-flight requests when
+// given a zero grace period.
+const DefaultGracePeriod = 10 * time.Second
+
+// Run serves srv on lis until ctx is canceled or the process receives
+// SIGINT or SIGTERM. It then stops accepting connections and waits up to
+// grace for in-flight requests to finish before closing the rest; a
+// second signal in the meantime exits the process at once, as
+// shutdown.Coordinator.Notify does.
+//
+// Run returns nil after a clean shutdown, the error that stopped the
+// server if it failed on its own, or an error wrapping
+// context.DeadlineExceeded if requests were still running when the grace
+// period ran out.
+func Run(ctx context.Context, srv *http.Server, lis net.Listener, grace time.Duration) error {
+	if grace <= 0 {
+		grace = DefaultGracePeriod
+	}
+	sd := shutdown.New()
+	sd.Add("http", grace, func(ctx context.Context) error {
+		if err := srv.Shutdown(ctx); err != nil {
+			srv.Close()
+			return err
+		}
+		return nil
+	})
+	ctx, stop := sd.Notify(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(lis) }()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+	// A signal has already begun the shutdown; this waits for it.
+	if err := sd.Shutdown(ctxutil.Detach(ctx)); err != nil {
+		return fmt.Errorf("server: %w", err)
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// startRun runs h under Run in the background and returns its address
+// and a channel delivering Run's result.
+func startRun(t *testing.T, ctx context.Context, h http.Handler, grace time.Duration) (string, <-chan error) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- Run(ctx, &http.Server{Handler: h}, lis, grace) }()
+	return "http://" + lis.Addr().String(), done
+}
+
+func wait(t *testing.T, done <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return")
+		return nil
+	}
+}
+
+func TestRunDrainsInFlightRequests(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "done")
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	url, done := startRun(t, ctx, h, time.Second)
+
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+	<-started
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	if err := wait(t, done); err != nil {
+		t.Fatalf("Run = %v, want nil", err)
+	}
+	if got := <-body; got != "done" {
+		t.Fatalf("in-flight request got %q", got)
+	}
+}
+
+func TestRunGracePeriodExpires(t *testing.T) {
+	started := make(chan struct{})
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	url, done := startRun(t, ctx, h, 50*time.Millisecond)
+	go http.Get(url)
+	<-started
+	cancel()
+	if err := wait(t, done); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Run = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestRunStopsOnSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("cannot send SIGINT to self on windows")
+	}
+	url, done := startRun(t, context.Background(), New(nil), time.Second)
+	// Once a request succeeds Run has installed its signal handler.
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	p, _ := os.FindProcess(os.Getpid())
+	if err := p.Signal(os.Interrupt); err != nil {
+		t.Fatal(err)
+	}
+	if err := wait(t, done); err != nil {
+		t.Fatalf("Run = %v, want nil", err)
+	}
+}
+
+func TestRunReturnsServeError(t *testing.T) {
+	lis, _ := net.Listen("tcp", "127.0.0.1:0")
+	lis.Close()
+	err := Run(context.Background(), &http.Server{}, lis, time.Second)
+	if err == nil || errors.Is(err, http.ErrServerClosed) {
+		t.Fatalf("Run on closed listener = %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"example.com/tutorial/errs"
+	"example.com/tutorial/i18n"
+	"example.com/tutorial/index"
+	"example.com/tutorial/text"
+	"example.com/tutorial/watch"
+)
+
+// searchJSON is the response of GET /search.
+type searchJSON struct {
+	Query string   `json:"query"`
+	Docs  []string `json:"docs"`
+}
+
+// completeJSON is the response of GET /search/complete.
+type completeJSON struct {
+	Prefix string          `json:"prefix"`
+	Words  []text.WordFreq `json:"words"`
+}
+
+// Reindex brings the index of the corpus directory up to date with
+// index.Index.Update, reading only the files that changed since it was
+// last indexed, or builds it the first time. GET /search keeps
+// answering meanwhile, and waits only while the changes are applied.
+// It does nothing if the server has no corpus directory.
+func (s *Server) Reindex(ctx context.Context) error {
+	if s.corpusDir == "" {
+		return nil
+	}
+	start := time.Now()
+	// Diff only reads the index, alongside searches, while the other
+	// writers wait.
+	s.corpusWriteMu.Lock()
+	defer s.corpusWriteMu.Unlock()
+	s.corpusMu.RLock()
+	ix := s.corpus
+	s.corpusMu.RUnlock()
+	if ix == nil {
+		ix = index.New()
+	}
+	changes, err := ix.Diff(ctx, s.corpusDir)
+	if err != nil {
+		return err
+	}
+	s.corpusMu.Lock()
+	ix.Apply(changes)
+	s.corpus = ix
+	s.corpusMu.Unlock()
+	s.logger.InfoContext(ctx, "corpus indexed",
+		"dir", s.corpusDir,
+		"docs", len(ix.Docs()),
+		"added", len(changes.Added),
+		"modified", len(changes.Modified),
+		"removed", len(changes.Removed),
+		"duration_ms", float64(time.Since(start).Microseconds())/1000)
+	return nil
+}
+
+// errNotIndexed is returned while the corpus has no index yet.
+var errNotIndexed = i18n.NewError(nil, "corpus not indexed yet")
+
+// Indexed reports whether the corpus has an index, from Reindex or
+// RestoreCorpus.
+func (s *Server) Indexed() bool {
+	s.corpusMu.RLock()
+	defer s.corpusMu.RUnlock()
+	return s.corpus != nil
+}
+
+// SnapshotCorpus writes the corpus index to w with index.Index.Snapshot,
+// for RestoreCorpus to read back after a restart. It fails if the corpus
+// has not been indexed.
+func (s *Server) SnapshotCorpus(w io.Writer) error {
+	// A write lock, as ApplyChanges changes the index in place.
+	s.corpusMu.Lock()
+	defer s.corpusMu.Unlock()
+	if s.corpus == nil {
+		return errNotIndexed
+	}
+	return s.corpus.Snapshot(w)
+}
+
+// RestoreCorpus swaps in the corpus index of a snapshot written by
+// SnapshotCorpus, standing in for Reindex at startup.
+func (s *Server) RestoreCorpus(r io.Reader) error {
+	ix := index.New()
+	if err := ix.Restore(r); err != nil {
+		return err
+	}
+	s.corpusWriteMu.Lock()
+	defer s.corpusWriteMu.Unlock()
+	s.corpusMu.Lock()
+	s.corpus = ix
+	s.corpusMu.Unlock()
+	return nil
+}
+
+// ApplyChanges updates the corpus index with the changes a
+// watch.Watcher of the corpus directory reports, reindexing only the
+// files they name. Files that cannot be read are reported in the
+// returned error, an errs.Multi, and do not stop the others.
+// Changes reported before the first Reindex are ignored, since it reads
+// the whole directory anyway.
+func (s *Server) ApplyChanges(events []watch.Event) error {
+	s.corpusWriteMu.Lock()
+	defer s.corpusWriteMu.Unlock()
+	s.corpusMu.Lock()
+	defer s.corpusMu.Unlock()
+	if s.corpus == nil {
+		return nil
+	}
+	var failed errs.Multi
+	for _, ev := range events {
+		if ev.Op == watch.Delete {
+			s.corpus.Remove(ev.Path)
+			continue
+		}
+		err := s.corpus.AddFile(ev.Path, filepath.Join(s.corpusDir, filepath.FromSlash(ev.Path)))
+		if errors.Is(err, fs.ErrNotExist) {
+			s.corpus.Remove(ev.Path) // deleted since; the next batch says so
+		} else if err != nil {
+			failed.Add(errs.Wrap(err, "index", ev.Path))
+		}
+	}
+	s.logger.Debug("corpus updated", "changes", len(events), "failed", failed.Len())
+	return failed.Err()
+}
+
+// handleSearch answers the boolean query in the q parameter, in the
+// syntax of index.Search, with the matching corpus documents. Terms
+// ending in '*' match by prefix.
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	s.corpusMu.RLock()
+	defer s.corpusMu.RUnlock()
+	if s.corpus == nil {
+		writeError(w, r, http.StatusServiceUnavailable, errNotIndexed)
+		return
+	}
+	q := r.URL.Query().Get("q")
+	docs, err := s.corpus.Search(q)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if docs == nil {
+		docs = []string{}
+	}
+	writeJSON(w, http.StatusOK, searchJSON{Query: q, Docs: docs})
+}
+
+// handleComplete suggests the corpus words that start with the prefix
+// parameter, the most frequent first, for completing search terms. The
+// top parameter sets how many, as for POST /wordcount.
+func (s *Server) handleComplete(w http.ResponseWriter, r *http.Request) {
+	top, err := parseTop(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	s.corpusMu.RLock()
+	defer s.corpusMu.RUnlock()
+	if s.corpus == nil {
+		writeError(w, r, http.StatusServiceUnavailable, errNotIndexed)
+		return
+	}
+	prefix := r.URL.Query().Get("prefix")
+	// The corpus is indexed in lower case.
+	words := s.corpus.Complete(strings.ToLower(prefix), top)
+	writeJSON(w, http.StatusOK, completeJSON{Prefix: prefix, Words: words})
+}
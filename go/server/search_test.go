@@ -0,0 +1,186 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"example.com/tutorial/text"
+	"example.com/tutorial/watch"
+)
+
+func TestSearch(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("go.txt", "gophers love goroutines")
+	write("rust.txt", "crabs love ownership")
+	s := NewWithConfig(nil, Config{CorpusDir: dir})
+
+	if rec := do(t, s, "GET", "/search?q=love", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("before indexing: status %d", rec.Code)
+	}
+	if err := s.Reindex(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	rec := do(t, s, "GET", "/search?q=love", "")
+	if got := decode[searchJSON](t, rec); !reflect.DeepEqual(got, searchJSON{Query: "love", Docs: []string{"go.txt", "rust.txt"}}) {
+		t.Fatalf("search = %+v", got)
+	}
+
+	// New files show up after the next Reindex only.
+	write("more.txt", "goroutines everywhere")
+	if got := decode[searchJSON](t, do(t, s, "GET", "/search?q=goroutines", "")); !reflect.DeepEqual(got.Docs, []string{"go.txt"}) {
+		t.Fatalf("before Reindex: %v", got.Docs)
+	}
+	s.Reindex(context.Background())
+	if got := decode[searchJSON](t, do(t, s, "GET", "/search?q=goroutines", "")); !reflect.DeepEqual(got.Docs, []string{"go.txt", "more.txt"}) {
+		t.Fatalf("after Reindex: %v", got.Docs)
+	}
+	// Reindex updates the index in place, and a failed one leaves it.
+	os.Remove(filepath.Join(dir, "rust.txt"))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.Reindex(ctx); err == nil {
+		t.Fatal("canceled Reindex succeeded")
+	}
+	if got := decode[searchJSON](t, do(t, s, "GET", "/search?q=love", "")); len(got.Docs) != 2 {
+		t.Fatalf("after a failed Reindex: %v", got.Docs)
+	}
+	s.Reindex(context.Background())
+	if got := decode[searchJSON](t, do(t, s, "GET", "/search?q=love", "")); !reflect.DeepEqual(got.Docs, []string{"go.txt"}) {
+		t.Fatalf("after removing a file: %v", got.Docs)
+	}
+	if got := decode[searchJSON](t, do(t, s, "GET", "/search?q=nothing", "")); got.Docs == nil || len(got.Docs) != 0 {
+		t.Fatalf("no match: %#v", got.Docs)
+	}
+	if rec := do(t, s, "GET", "/search?q=love+OR", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad query: status %d", rec.Code)
+	}
+
+	if got := decode[searchJSON](t, do(t, s, "GET", "/search?q=gop*+OR+every*", "")); !reflect.DeepEqual(got.Docs, []string{"go.txt", "more.txt"}) {
+		t.Fatalf("prefix search: %v", got.Docs)
+	}
+
+	if rec := do(t, New(nil), "GET", "/search?q=love", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("without a corpus: status %d", rec.Code)
+	}
+	if err := New(nil).Reindex(context.Background()); err != nil {
+		t.Fatalf("Reindex without a corpus = %v", err)
+	}
+}
+
+func TestApplyChanges(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.txt", "gophers")
+	write("b.txt", "gophers")
+	s := NewWithConfig(nil, Config{CorpusDir: dir})
+	search := func(q string) []string {
+		t.Helper()
+		return decode[searchJSON](t, do(t, s, "GET", "/search?q="+q, "")).Docs
+	}
+
+	if err := s.ApplyChanges([]watch.Event{{Path: "a.txt", Op: watch.Modify}}); err != nil {
+		t.Fatalf("before Reindex: %v", err)
+	}
+	if err := s.Reindex(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	write("a.txt", "crabs")
+	write("c.txt", "gophers and crabs")
+	os.Remove(filepath.Join(dir, "b.txt"))
+	err := s.ApplyChanges([]watch.Event{
+		{Path: "a.txt", Op: watch.Modify},
+		{Path: "b.txt", Op: watch.Delete},
+		{Path: "c.txt", Op: watch.Create},
+		{Path: "gone.txt", Op: watch.Create},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := search("gophers"); !reflect.DeepEqual(got, []string{"c.txt"}) {
+		t.Fatalf("gophers: %v", got)
+	}
+	if got := search("crabs"); !reflect.DeepEqual(got, []string{"a.txt", "c.txt"}) {
+		t.Fatalf("crabs: %v", got)
+	}
+}
+
+func TestCorpusSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.txt"), []byte("gophers love goroutines"), 0o644)
+	s := NewWithConfig(nil, Config{CorpusDir: dir})
+	var buf bytes.Buffer
+	if err := s.SnapshotCorpus(&buf); err == nil || s.Indexed() {
+		t.Fatal("snapshot before indexing succeeded")
+	}
+	if err := s.Reindex(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SnapshotCorpus(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// A restarted server answers from the snapshot without indexing,
+	// even if the files are gone.
+	os.Remove(filepath.Join(dir, "go.txt"))
+	restarted := NewWithConfig(nil, Config{CorpusDir: dir})
+	if err := restarted.RestoreCorpus(&buf); err != nil || !restarted.Indexed() {
+		t.Fatalf("RestoreCorpus: %v", err)
+	}
+	if got := decode[searchJSON](t, do(t, restarted, "GET", "/search?q=gophers", "")); !reflect.DeepEqual(got.Docs, []string{"go.txt"}) {
+		t.Fatalf("search after restore: %v", got.Docs)
+	}
+	if err := restarted.RestoreCorpus(bytes.NewReader([]byte("junk"))); err == nil {
+		t.Fatal("restored junk")
+	}
+}
+
+func TestComplete(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"go.txt":   "gophers love goroutines and goroutines love channels",
+		"more.txt": "go go go",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := NewWithConfig(nil, Config{CorpusDir: dir})
+	if rec := do(t, s, "GET", "/search/complete?prefix=go", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("before indexing: status %d", rec.Code)
+	}
+	if err := s.Reindex(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	got := decode[completeJSON](t, do(t, s, "GET", "/search/complete?prefix=Go&top=2", ""))
+	want := completeJSON{Prefix: "Go", Words: []text.WordFreq{{Word: "go", Count: 3}, {Word: "goroutines", Count: 2}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("complete = %+v", got)
+	}
+	if got := decode[completeJSON](t, do(t, s, "GET", "/search/complete?prefix=go", "")); len(got.Words) != 3 {
+		t.Fatalf("default top: %+v", got.Words)
+	}
+	if got := decode[completeJSON](t, do(t, s, "GET", "/search/complete?prefix=x", "")); got.Words == nil || len(got.Words) != 0 {
+		t.Fatalf("no match: %#v", got.Words)
+	}
+	if rec := do(t, s, "GET", "/search/complete?prefix=go&top=many", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad top: status %d", rec.Code)
+	}
+}
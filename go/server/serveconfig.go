@@ -0,0 +1,163 @@
+package server
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"example.com/tutorial/validate"
+)
+
+// ServeConfig holds the listener and timeout settings of the example
+// server. Values come from DefaultServeConfig, then environment
+// variables (FromEnv), then command-line flags (RegisterFlags), each
+// overriding the last.
+type ServeConfig struct {
+	Addr     string // HTTP listen address
+	GRPCAddr string // gRPC listen address
+
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	// WriteTimeout is zero by default because it would cut off
+	// event streams.
+	WriteTimeout  time.Duration
+	IdleTimeout   time.Duration
+	ShutdownGrace time.Duration
+
+	TLS      bool   // serve HTTPS
+	CertFile string // PEM certificate; with KeyFile, implies TLS
+	KeyFile  string // PEM private key
+	// H2C serves HTTP/2 without TLS, by prior knowledge or Upgrade,
+	// next to HTTP/1.1. Over TLS HTTP/2 is negotiated anyway.
+	H2C bool
+}
+
+// DefaultServeConfig returns the settings used when nothing is
+// configured.
+func DefaultServeConfig() ServeConfig {
+	return ServeConfig{
+		Addr:              ":8080",
+		GRPCAddr:          ":9090",
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+		ShutdownGrace:     DefaultGracePeriod,
+	}
+}
+
+// Environment variables read by FromEnv.
+const (
+	EnvAddr              = "SERVER_ADDR"
+	EnvGRPCAddr          = "SERVER_GRPC_ADDR"
+	EnvReadHeaderTimeout = "SERVER_READ_HEADER_TIMEOUT"
+	EnvReadTimeout       = "SERVER_READ_TIMEOUT"
+	EnvWriteTimeout      = "SERVER_WRITE_TIMEOUT"
+	EnvIdleTimeout       = "SERVER_IDLE_TIMEOUT"
+	EnvShutdownGrace     = "SERVER_SHUTDOWN_GRACE"
+	EnvTLS               = "SERVER_TLS"
+	EnvCertFile          = "SERVER_TLS_CERT"
+	EnvKeyFile           = "SERVER_TLS_KEY"
+	EnvH2C               = "SERVER_H2C"
+)
+
+// FromEnv overrides c with the variables that getenv (usually os.Getenv)
+// reports as set. Durations use time.ParseDuration syntax and SERVER_TLS
+// and SERVER_H2C strconv.ParseBool syntax; every malformed value is
+// reported.
+func (c *ServeConfig) FromEnv(getenv func(string) string) error {
+	var v validate.Validator
+	str := func(key string, dst *string) {
+		if s := getenv(key); s != "" {
+			*dst = s
+		}
+	}
+	dur := func(key string, dst *time.Duration) {
+		if s := getenv(key); s != "" {
+			d, err := time.ParseDuration(s)
+			v.Check(err == nil, key, "must be a duration such as 10s")
+			*dst = d
+		}
+	}
+	str(EnvAddr, &c.Addr)
+	str(EnvGRPCAddr, &c.GRPCAddr)
+	dur(EnvReadHeaderTimeout, &c.ReadHeaderTimeout)
+	dur(EnvReadTimeout, &c.ReadTimeout)
+	dur(EnvWriteTimeout, &c.WriteTimeout)
+	dur(EnvIdleTimeout, &c.IdleTimeout)
+	dur(EnvShutdownGrace, &c.ShutdownGrace)
+	boolean := func(key string, dst *bool) {
+		if s := getenv(key); s != "" {
+			b, err := strconv.ParseBool(s)
+			v.Check(err == nil, key, "must be true or false")
+			*dst = b
+		}
+	}
+	boolean(EnvTLS, &c.TLS)
+	str(EnvCertFile, &c.CertFile)
+	str(EnvKeyFile, &c.KeyFile)
+	boolean(EnvH2C, &c.H2C)
+	return v.Err()
+}
+
+// RegisterFlags defines flags on fs for every field, defaulting to the
+// current values so that flags override the environment.
+func (c *ServeConfig) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Addr, "addr", c.Addr, "HTTP listen `address` ($"+EnvAddr+")")
+	fs.StringVar(&c.GRPCAddr, "grpc-addr", c.GRPCAddr, "gRPC listen `address` ($"+EnvGRPCAddr+")")
+	fs.DurationVar(&c.ReadHeaderTimeout, "read-header-timeout", c.ReadHeaderTimeout, "time allowed to read request headers ($"+EnvReadHeaderTimeout+")")
+	fs.DurationVar(&c.ReadTimeout, "read-timeout", c.ReadTimeout, "time allowed to read a whole request ($"+EnvReadTimeout+")")
+	fs.DurationVar(&c.WriteTimeout, "write-timeout", c.WriteTimeout, "time allowed to write a response, 0 for none ($"+EnvWriteTimeout+")")
+	fs.DurationVar(&c.IdleTimeout, "idle-timeout", c.IdleTimeout, "how long idle keep-alive connections stay open ($"+EnvIdleTimeout+")")
+	fs.DurationVar(&c.ShutdownGrace, "grace", c.ShutdownGrace, "how long to wait for in-flight requests on shutdown ($"+EnvShutdownGrace+")")
+	fs.BoolVar(&c.TLS, "tls", c.TLS, "serve HTTPS, with a self-signed certificate unless -cert and -key are given ($"+EnvTLS+")")
+	fs.StringVar(&c.CertFile, "cert", c.CertFile, "TLS certificate `file` (PEM) ($"+EnvCertFile+")")
+	fs.StringVar(&c.KeyFile, "key", c.KeyFile, "TLS private key `file` (PEM) ($"+EnvKeyFile+")")
+	fs.BoolVar(&c.H2C, "h2c", c.H2C, "also serve HTTP/2 over plaintext connections ($"+EnvH2C+")")
+}
+
+// Validate reports every problem with c as a validate.Errors value.
+func (c ServeConfig) Validate() error {
+	var v validate.Validator
+	checkAddr := func(field, addr string) {
+		_, port, err := net.SplitHostPort(addr)
+		if err == nil {
+			_, err = strconv.ParseUint(port, 10, 16)
+		}
+		v.Check(err == nil, field, fmt.Sprintf("%q is not a host:port address", addr))
+	}
+	checkAddr("addr", c.Addr)
+	checkAddr("grpc-addr", c.GRPCAddr)
+	v.Check(c.ReadHeaderTimeout >= 0, "read-header-timeout", "must not be negative")
+	v.Check(c.ReadTimeout >= 0, "read-timeout", "must not be negative")
+	v.Check(c.WriteTimeout >= 0, "write-timeout", "must not be negative")
+	v.Check(c.IdleTimeout >= 0, "idle-timeout", "must not be negative")
+	v.Check(c.ShutdownGrace > 0, "grace", "must be positive")
+	v.Check((c.CertFile == "") == (c.KeyFile == ""), "cert", "cert and key must be given together")
+	v.Check(!c.H2C || !c.UseTLS(), "h2c", "is for plaintext; HTTP/2 is negotiated over TLS")
+	return v.Err()
+}
+
+// UseTLS reports whether the server should serve HTTPS.
+func (c ServeConfig) UseTLS() bool { return c.TLS || c.CertFile != "" }
+
+// HTTPServer returns an http.Server for h with c's timeouts, accepting
+// h2c when configured. The address is left to the listener passed to
+// Run.
+func (c ServeConfig) HTTPServer(h http.Handler) *http.Server {
+	if c.H2C && !c.UseTLS() {
+		h = h2c.NewHandler(h, &http2.Server{IdleTimeout: c.IdleTimeout})
+	}
+	return &http.Server{
+		Handler:           h,
+		ReadHeaderTimeout: c.ReadHeaderTimeout,
+		ReadTimeout:       c.ReadTimeout,
+		WriteTimeout:      c.WriteTimeout,
+		IdleTimeout:       c.IdleTimeout,
+	}
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+
+	"example.com/tutorial/validate"
+)
+
+func TestServeConfigPrecedence(t *testing.T) {
+	env := map[string]string{
+		EnvAddr:          ":7000",
+		EnvReadTimeout:   "1m",
+		EnvShutdownGrace: "3s",
+		EnvTLS:           "true",
+	}
+	cfg := DefaultServeConfig()
+	if err := cfg.FromEnv(func(k string) string { return env[k] }); err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg.RegisterFlags(fs)
+	if err := fs.Parse([]string{"-addr", "127.0.0.1:7001", "-write-timeout", "5s"}); err != nil {
+		t.Fatal(err)
+	}
+	want := DefaultServeConfig()
+	want.Addr = "127.0.0.1:7001"   // flag beats env
+	want.ReadTimeout = time.Minute // env beats default
+	want.ShutdownGrace = 3 * time.Second
+	want.WriteTimeout = 5 * time.Second // flag beats default
+	want.TLS = true
+	if cfg != want {
+		t.Fatalf("config = %+v\nwant %+v", cfg, want)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.UseTLS() {
+		t.Fatal("UseTLS = false")
+	}
+	srv := cfg.HTTPServer(nil)
+	if srv.ReadTimeout != time.Minute || srv.WriteTimeout != 5*time.Second || srv.ReadHeaderTimeout != 5*time.Second {
+		t.Fatalf("http.Server timeouts = %v %v %v", srv.ReadTimeout, srv.WriteTimeout, srv.ReadHeaderTimeout)
+	}
+}
+
+func TestServeConfigFromEnvErrors(t *testing.T) {
+	env := map[string]string{EnvIdleTimeout: "forever", EnvTLS: "maybe"}
+	cfg := DefaultServeConfig()
+	err := cfg.FromEnv(func(k string) string { return env[k] })
+	var verr validate.Errors
+	if !errors.As(err, &verr) || len(verr) != 2 || verr[0].Field != EnvIdleTimeout || verr[1].Field != EnvTLS {
+		t.Fatalf("FromEnv = %v", err)
+	}
+}
+
+func TestServeConfigValidate(t *testing.T) {
+	cfg := DefaultServeConfig()
+	cfg.Addr = "8080"
+	cfg.GRPCAddr = ":99999"
+	cfg.ReadTimeout = -time.Second
+	cfg.ShutdownGrace = 0
+	cfg.CertFile = "cert.pem"
+	err := cfg.Validate()
+	var verr validate.Errors
+	if !errors.As(err, &verr) {
+		t.Fatalf("Validate = %v", err)
+	}
+	var fields []string
+	for _, fe := range verr {
+		fields = append(fields, fe.Field)
+	}
+	want := []string{"addr", "grpc-addr", "read-timeout", "grace", "cert"}
+	if len(fields) != len(want) {
+		t.Fatalf("fields = %v, want %v", fields, want)
+	}
+	for i := range want {
+		if fields[i] != want[i] {
+			t.Fatalf("fields = %v, want %v", fields, want)
+		}
+	}
+	if err := DefaultServeConfig().Validate(); err != nil {
+		t.Fatalf("defaults invalid: %v", err)
+	}
+}
+
+func TestServeConfigFlagsUsage(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	cfg := DefaultServeConfig()
+	cfg.RegisterFlags(fs)
+	if err := fs.Parse([]string{"-grace", "soon"}); err == nil {
+		t.Fatal("bad duration flag accepted")
+	}
+}
+
+func TestServeConfigH2C(t *testing.T) {
+	cfg := DefaultServeConfig()
+	cfg.H2C = true
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := cfg.HTTPServer(New(nil))
+	go srv.Serve(lis)
+	defer srv.Close()
+	url := "http://" + lis.Addr().String() + "/"
+
+	// Prior knowledge: the client speaks HTTP/2 from the first byte.
+	h2 := &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}
+	defer h2.CloseIdleConnections()
+	// Several requests share the one connection.
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := (&http.Client{Transport: h2}).Get(url)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+			if resp.Proto != "HTTP/2.0" {
+				t.Errorf("h2c proto = %s", resp.Proto)
+			}
+		}()
+	}
+	wg.Wait()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.Proto != "HTTP/1.1" {
+		t.Fatalf("HTTP/1.1 client got %s", resp.Proto)
+	}
+
+	cfg.TLS = true
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("h2c with TLS accepted")
+	}
+}
@@ -0,0 +1,322 @@
+// Package server implements the HTTP API of the example server.
+package server
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"example.com/tutorial/auth"
+	"example.com/tutorial/buildinfo"
+	"example.com/tutorial/bus"
+	"example.com/tutorial/cache"
+	"example.com/tutorial/dedup"
+	"example.com/tutorial/diag"
+	"example.com/tutorial/health"
+	"example.com/tutorial/i18n"
+	"example.com/tutorial/index"
+	"example.com/tutorial/jobs"
+	"example.com/tutorial/logging"
+	"example.com/tutorial/metrics"
+	"example.com/tutorial/middleware"
+	"example.com/tutorial/router"
+	"example.com/tutorial/semaphore"
+	"example.com/tutorial/user"
+)
+
+// Server routes requests to the API handlers.
+type Server struct {
+	users  user.Store
+	auth   *auth.JWT
+	logger *slog.Logger
+	mux    *router.Router
+	// handler is mux wrapped in the middleware of WithMiddleware and
+	// WithTimeout.
+	handler http.Handler
+	hub     *hub
+	jobs    *jobMetrics
+	// jobQueue, if not nil, runs the analyses of POST /jobs.
+	jobQueue *jobs.Service
+	// logLevel and limiters, if not nil, are the log level and rate
+	// limits /admin/settings changes. adminMu makes its requests take
+	// turns.
+	logLevel *slog.LevelVar
+	limiters *middleware.Limiters
+	adminMu  sync.Mutex
+	// templates render /dashboard and its error pages.
+	templates *Templates
+	// done carries a jobEvent for every finished word-count job to the
+	// GET /wordcount/events listeners.
+	done *bus.Bus[jobEvent]
+
+	// maxTextBytes bounds documents posted to /wordcount.
+	maxTextBytes int64
+	// counting, if not nil, holds maxCountingBytes shared by the
+	// documents being counted at once.
+	counting         *semaphore.Weighted
+	maxCountingBytes int64
+	// results, if not nil, caches POST /wordcount counts by content
+	// hash.
+	results cache.Store[[sha256.Size]byte, countResult]
+	// counts shares the counting of identical documents posted at once.
+	counts dedup.Group[[sha256.Size]byte, countResult]
+	// corpus indexes corpusDir for GET /search; Reindex and
+	// ApplyChanges update it in place under corpusMu. They hold
+	// corpusWriteMu throughout, so that Reindex can read the files with
+	// only that, leaving searches free to run.
+	corpusDir     string
+	corpusMu      sync.RWMutex
+	corpusWriteMu sync.Mutex
+	corpus        *index.Index
+	// maxUploadBytes and uploadTypes limit POST /upload.
+	maxUploadBytes int64
+	uploadTypes    []string
+	// progressInterval and heartbeat pace /wordcount/events.
+	progressInterval time.Duration
+	heartbeat        time.Duration
+}
+
+// Config holds the optional settings of a Server, given to New with
+// WithConfig. The zero value is the configuration New uses without it.
+type Config struct {
+	// Static is the file tree served under /static/. Nil means the
+	// files returned by Assets.
+	Static fs.FS
+	// StaticListings enables listings of directories without an
+	// index.html under /static/.
+	StaticListings bool
+	// StaticMaxAge is the Cache-Control max-age of static files; zero
+	// means DefaultStaticMaxAge.
+	StaticMaxAge time.Duration
+	// Templates are the HTML pages of /dashboard; nil means those of
+	// TemplateFiles.
+	Templates *Templates
+	// Checks are run by /readyz in addition to the "store" check that is
+	// added when the server has a user store.
+	Checks map[string]health.Checker
+	// Metrics receives the server's metrics and is served on /metrics.
+	// Nil means a registry of the server's own; pass a shared one to
+	// expose middleware metrics on the same endpoint.
+	Metrics *metrics.Registry
+	// MaxUploadBytes bounds the request body of POST /upload; zero
+	// means DefaultMaxUploadBytes.
+	MaxUploadBytes int64
+	// UploadTypes lists the media types POST /upload accepts; empty
+	// means DefaultUploadTypes.
+	UploadTypes []string
+	// MaxCountingBytes bounds the total size of the documents POST
+	// /wordcount and /wordcount/events count at once; requests beyond it
+	// wait their turn. Zero means no bound.
+	MaxCountingBytes int64
+	// ResultCacheBytes bounds the estimated memory of the cache of POST
+	// /wordcount results, keyed by the document's content hash. Zero
+	// means DefaultResultCacheBytes; negative disables the cache.
+	ResultCacheBytes int64
+	// ResultCache, if set, holds the POST /wordcount results instead of
+	// the server's own cache, encoded as JSON under the hex content
+	// hash, so that servers sharing it, such as a redisstore.Cache,
+	// share their results. ResultCacheBytes is then ignored.
+	ResultCache cache.Store[string, []byte]
+	// CorpusDir, if set, is a directory of text files searchable with
+	// GET /search?q=. It is indexed by Reindex, which the caller should
+	// run at startup and whenever the files change; each run after the
+	// first reads only the files that changed.
+	CorpusDir string
+	// Logger receives the server's logs, such as one record per
+	// finished word-count job; nil means slog.Default(). Its records
+	// have component "server".
+	Logger *slog.Logger
+	// Auth, if set, protects the user-mutation endpoints: PUT and DELETE
+	// /users/{id} need a bearer token for that user, issued by POST
+	// /login. POST /users stays open so that people can sign up.
+	Auth *auth.JWT
+	// Jobs, if set, runs the word counts submitted to POST /jobs, whose
+	// status and result GET /jobs/{id} returns. The caller runs it, and
+	// gives it a Dir for the queued jobs to survive a restart.
+	Jobs *jobs.Service
+	// AdminToken, if set, is the bearer token of the /admin/settings
+	// endpoints, with which an operator reads and changes the settings
+	// below, the size of the server's own result cache and the workers
+	// of Jobs while the server runs. /debug/info reports them too.
+	AdminToken string
+	// LogLevel, if set, is the level of the logger of the program, which
+	// /admin/settings may change.
+	LogLevel *slog.LevelVar
+	// RateLimit, if set, is the rate limiter of the server's middleware,
+	// whose rate and burst /admin/settings may change.
+	RateLimit *middleware.Limiters
+}
+
+// Option configures a Server built by New. Options apply in order, so
+// WithConfig goes first if the others are to change its settings.
+type Option func(*options)
+
+type options struct {
+	cfg        Config
+	timeout    time.Duration
+	middleware []middleware.Middleware
+}
+
+// WithConfig replaces every setting of Config with those of cfg.
+func WithConfig(cfg Config) Option {
+	return func(o *options) { o.cfg = cfg }
+}
+
+// WithLogger sets Config.Logger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) { o.cfg.Logger = logger }
+}
+
+// WithTimeout answers 504 to requests still running after d, as
+// middleware.Timeout does. The endpoints that stream, GET /ws and
+// /wordcount/events, have no limit. Zero means none at all.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) { o.timeout = d }
+}
+
+// WithMiddleware wraps the server's routes in ms, the first seeing the
+// request first as with middleware.Chain. Middleware added by repeated
+// calls runs in the order of the calls, and all of it before the limit
+// of WithTimeout, so that it sees the 504 of a request that times out.
+func WithMiddleware(ms ...middleware.Middleware) Option {
+	return func(o *options) { o.middleware = append(o.middleware, ms...) }
+}
+
+// New returns a Server whose /users endpoints are backed by users,
+// configured by opts. POST /wordcount needs no store.
+func New(users user.Store, opts ...Option) *Server {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	s := newServer(users, o.cfg)
+	ms := o.middleware
+	if o.timeout > 0 {
+		ms = append(ms, middleware.Timeout(middleware.TimeoutConfig{
+			Default: o.timeout,
+			Routes:  map[string]time.Duration{"/ws": 0, "/wordcount/events": 0},
+		}))
+	}
+	s.handler = middleware.Chain(ms...)(s.mux)
+	return s
+}
+
+// NewWithConfig is like New with settings from cfg. It is the same as
+// New(users, WithConfig(cfg)).
+func NewWithConfig(users user.Store, cfg Config) *Server {
+	return New(users, WithConfig(cfg))
+}
+
+func newServer(users user.Store, cfg Config) *Server {
+	if cfg.Static == nil {
+		cfg.Static = Assets()
+	}
+	if cfg.Templates == nil {
+		cfg.Templates = defaultTemplates
+	}
+	if cfg.StaticMaxAge == 0 {
+		cfg.StaticMaxAge = DefaultStaticMaxAge
+	}
+	if cfg.MaxUploadBytes <= 0 {
+		cfg.MaxUploadBytes = DefaultMaxUploadBytes
+	}
+	if len(cfg.UploadTypes) == 0 {
+		cfg.UploadTypes = DefaultUploadTypes
+	}
+	if cfg.Metrics == nil {
+		cfg.Metrics = metrics.NewRegistry()
+	}
+	s := &Server{
+		users:            users,
+		auth:             cfg.Auth,
+		logger:           logging.Component(cfg.Logger, "server"),
+		templates:        cfg.Templates,
+		mux:              router.New(),
+		hub:              newHub(),
+		jobs:             newJobMetrics(cfg.Metrics),
+		jobQueue:         cfg.Jobs,
+		logLevel:         cfg.LogLevel,
+		limiters:         cfg.RateLimit,
+		done:             bus.New[jobEvent](),
+		maxTextBytes:     defaultMaxTextBytes,
+		results:          newResultCache(cfg),
+		maxUploadBytes:   cfg.MaxUploadBytes,
+		uploadTypes:      cfg.UploadTypes,
+		corpusDir:        cfg.CorpusDir,
+		progressInterval: defaultProgressInterval,
+		heartbeat:        defaultHeartbeat,
+	}
+	if cfg.MaxCountingBytes > 0 {
+		s.counting = semaphore.NewWeighted(cfg.MaxCountingBytes)
+		s.maxCountingBytes = cfg.MaxCountingBytes
+	}
+	s.mux.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fail(w, r, errNotFound)
+	})
+	s.mux.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, r, http.StatusMethodNotAllowed, i18n.NewError(nil, "method not allowed"))
+	})
+	s.mux.HandleFunc(http.MethodGet, "/", s.handleRoot)
+	var checks health.Registry
+	if users != nil {
+		checks.Register("store", health.StoreReachable(users))
+	}
+	for name, c := range cfg.Checks {
+		checks.Register(name, c)
+	}
+	s.mux.Handle(http.MethodGet, "/healthz", health.Live())
+	s.mux.Handle(http.MethodGet, "/readyz", checks.Ready())
+	s.mux.Handle(http.MethodGet, "/metrics", cfg.Metrics.Handler())
+	s.mux.Handle(http.MethodGet, "/version", buildinfo.Handler())
+	s.mux.Handle(http.MethodGet, "/debug/info", diag.NewHandler(s.settings))
+	s.mux.HandleFunc(http.MethodGet, "/users", s.listUsers)
+	s.mux.HandleFunc(http.MethodPost, "/users", s.createUser)
+	s.mux.HandleFunc(http.MethodGet, "/users/{id}", s.getUser)
+	protect := func(h http.HandlerFunc) http.Handler { return h }
+	if cfg.Auth != nil {
+		protect = func(h http.HandlerFunc) http.Handler { return auth.RequireJWT(cfg.Auth)(h) }
+		s.mux.HandleFunc(http.MethodPost, "/login", s.handleLogin)
+	}
+	s.mux.Handle(http.MethodPut, "/users/{id}", protect(s.updateUser))
+	s.mux.Handle(http.MethodDelete, "/users/{id}", protect(s.deleteUser))
+	s.mux.HandleFunc(http.MethodPost, "/wordcount", s.handleWordCount)
+	s.mux.HandleFunc(http.MethodPost, "/wordcount/events", s.handleWordCountEvents)
+	s.mux.HandleFunc(http.MethodGet, "/wordcount/events", s.handleJobEvents)
+	s.mux.HandleFunc(http.MethodPost, "/upload", s.handleUpload)
+	s.mux.HandleFunc(http.MethodPost, "/compare", s.handleCompare)
+	if cfg.Jobs != nil {
+		s.mux.HandleFunc(http.MethodPost, "/jobs", s.submitJob)
+		s.mux.HandleFunc(http.MethodGet, "/jobs/{id}", s.getJob)
+	}
+	if cfg.CorpusDir != "" {
+		s.mux.HandleFunc(http.MethodGet, "/search", s.handleSearch)
+		s.mux.HandleFunc(http.MethodGet, "/search/complete", s.handleComplete)
+	}
+	if cfg.AdminToken != "" {
+		admin := auth.RequireToken(cfg.AdminToken)
+		s.mux.Handle(http.MethodGet, "/admin/settings", admin(http.HandlerFunc(s.getSettings)))
+		s.mux.Handle(http.MethodPatch, "/admin/settings", admin(http.HandlerFunc(s.patchSettings)))
+	}
+	s.mux.HandleFunc(http.MethodGet, "/dashboard", s.handleDashboard)
+	s.mux.HandleFunc(http.MethodPost, "/dashboard", s.handleDashboard)
+	s.mux.HandleFunc(http.MethodGet, "/ws", s.handleWebSocket)
+	s.mux.Handle(http.MethodGet, "/static/{path...}", &staticHandler{
+		fsys:     cfg.Static,
+		listings: cfg.StaticListings,
+		maxAge:   cfg.StaticMaxAge,
+	})
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintln(w, "ok")
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"example.com/tutorial/router"
+)
+
+//go:embed static
+var staticFiles embed.FS
+
+// Assets returns the files built into the binary that /static serves by
+// default.
+func Assets() fs.FS {
+	sub, err := fs.Sub(staticFiles, "static")
+	if err != nil {
+		panic(err) // the directory is embedded, so this cannot happen
+	}
+	return sub
+}
+
+// DefaultStaticMaxAge is the Cache-Control max-age of static files when
+// Config.StaticMaxAge is zero.
+const DefaultStaticMaxAge = time.Hour
+
+// staticHandler serves the files of fsys. Names are validated with
+// fs.ValidPath, so ".." and other escapes from the root are impossible,
+// and dot files are never served.
+type staticHandler struct {
+	fsys     fs.FS
+	listings bool
+	maxAge   time.Duration
+
+	etags sync.Map // etagKey -> string
+}
+
+type etagKey struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+// ServeHTTP serves the file named by the {path} parameter. A directory
+// is served by its index.html, by a listing if enabled, or not at all.
+func (h *staticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := router.Param(r, "path")
+	wantDir := strings.HasSuffix(r.URL.Path, "/")
+	name = strings.TrimSuffix(name, "/")
+	if name == "" {
+		name = "."
+	}
+	if !fs.ValidPath(name) || hidden(name) {
+		staticNotFound(w, r)
+		return
+	}
+	f, err := h.fsys.Open(name)
+	if err != nil {
+		staticNotFound(w, r)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		staticNotFound(w, r)
+		return
+	}
+
+	if info.IsDir() {
+		if !wantDir {
+			// Relative links in the page resolve against the slash.
+			http.Redirect(w, r, r.URL.Path+"/", http.StatusMovedPermanently)
+			return
+		}
+		index := path.Join(name, "index.html")
+		if fi, err := fs.Stat(h.fsys, index); err == nil && !fi.IsDir() {
+			h.serveFile(w, r, index)
+			return
+		}
+		if !h.listings {
+			staticNotFound(w, r)
+			return
+		}
+		h.serveListing(w, r, name)
+		return
+	}
+	if wantDir {
+		staticNotFound(w, r)
+		return
+	}
+	h.serveOpen(w, r, name, f, info)
+}
+
+func (h *staticHandler) serveFile(w http.ResponseWriter, r *http.Request, name string) {
+	f, err := h.fsys.Open(name)
+	if err != nil {
+		staticNotFound(w, r)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		staticNotFound(w, r)
+		return
+	}
+	h.serveOpen(w, r, name, f, info)
+}
+
+// serveOpen sends f with caching headers. http.ServeContent answers
+// conditional and range requests from the ETag and modification time.
+func (h *staticHandler) serveOpen(w http.ResponseWriter, r *http.Request, name string, f fs.File, info fs.FileInfo) {
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, errors.New("file is not seekable"))
+		return
+	}
+	etag, err := h.etag(name, info, rs)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.maxAge.Seconds())))
+	http.ServeContent(w, r, info.Name(), info.ModTime(), rs)
+}
+
+// etag returns a strong ETag from the content hash. Hashes are cached per
+// size and modification time, which is all that changes for embedded
+// files (whose times are zero) only when the binary does.
+func (h *staticHandler) etag(name string, info fs.FileInfo, rs io.ReadSeeker) (string, error) {
+	key := etagKey{name, info.Size(), info.ModTime()}
+	if v, ok := h.etags.Load(key); ok {
+		return v.(string), nil
+	}
+	sum := sha256.New()
+	if _, err := io.Copy(sum, rs); err != nil {
+		return "", err
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := `"` + hex.EncodeToString(sum.Sum(nil)[:12]) + `"`
+	h.etags.Store(key, etag)
+	return etag, nil
+}
+
+func (h *staticHandler) serveListing(w http.ResponseWriter, r *http.Request, dir string) {
+	entries, err := fs.ReadDir(h.fsys, dir)
+	if err != nil {
+		staticNotFound(w, r)
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	title := html.EscapeString(r.URL.Path)
+	fmt.Fprintf(w, "<!doctype html>\n<title>%s</title>\n<h1>%s</h1>\n<ul>\n", title, title)
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		name := e.Name()
+		if e.IsDir() {
+			name += "/"
+		}
+		fmt.Fprintf(w, "<li><a href=\"%s\">%s</a></li>\n", (&url.URL{Path: name}).EscapedPath(), html.EscapeString(name))
+	}
+	fmt.Fprintln(w, "</ul>")
+}
+
+// hidden reports whether any element of name starts with a dot.
+func hidden(name string) bool {
+	for _, elem := range strings.Split(name, "/") {
+		if strings.HasPrefix(elem, ".") && elem != "." {
+			return true
+		}
+	}
+	return false
+}
+
+func staticNotFound(w http.ResponseWriter, r *http.Request) {
+	fail(w, r, errNotFound)
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+var testFiles = fstest.MapFS{
+	"hello.txt":          {Data: []byte("hello, world\n"), ModTime: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+	"docs/a <b>.txt":     {Data: []byte("a")},
+	"docs/sub/c.txt":     {Data: []byte("c")},
+	"site/index.html":    {Data: []byte("<p>index</p>")},
+	".secret":            {Data: []byte("password")},
+	"docs/.hidden/x.txt": {Data: []byte("x")},
+}
+
+func staticServer(listings bool) *Server {
+	return NewWithConfig(nil, Config{Static: testFiles, StaticListings: listings, StaticMaxAge: time.Minute})
+}
+
+func TestStaticFile(t *testing.T) {
+	s := staticServer(false)
+	rec := do(t, s, "GET", "/static/hello.txt", "")
+	if rec.Code != http.StatusOK || rec.Body.String() != "hello, world\n" {
+		t.Fatalf("GET = %d %q", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "public, max-age=60" {
+		t.Errorf("Cache-Control = %q", cc)
+	}
+	if lm := rec.Header().Get("Last-Modified"); lm != "Tue, 02 Jan 2024 03:04:05 GMT" {
+		t.Errorf("Last-Modified = %q", lm)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag")
+	}
+	if rec := do(t, s, "GET", "/static/hello.txt", "", "If-None-Match", etag); rec.Code != http.StatusNotModified {
+		t.Errorf("conditional GET = %d, want 304", rec.Code)
+	}
+	if rec := do(t, s, "GET", "/static/hello.txt", "", "Range", "bytes=0-4"); rec.Code != http.StatusPartialContent || rec.Body.String() != "hello" {
+		t.Errorf("range GET = %d %q", rec.Code, rec.Body)
+	}
+}
+
+func TestStaticRejectsEscapes(t *testing.T) {
+	s := staticServer(true)
+	for _, p := range []string{
+		"/static/../server.go",
+		"/static/docs/../../x",
+		"/static/.secret",
+		"/static/docs/.hidden/x.txt",
+		"/static//hello.txt",
+		"/static/missing.txt",
+		"/static/hello.txt/",
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.URL.Path = p // bypass cleaning by the request parser
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want 404", p, rec.Code)
+		}
+	}
+}
+
+func TestStaticDirectories(t *testing.T) {
+	if rec := do(t, staticServer(false), "GET", "/static/site/", ""); rec.Body.String() != "<p>index</p>" {
+		t.Errorf("index.html not served: %d %q", rec.Code, rec.Body)
+	}
+	if rec := do(t, staticServer(false), "GET", "/static/site", ""); rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/static/site/" {
+		t.Errorf("directory without slash = %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec := do(t, staticServer(false), "GET", "/static/docs/", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("listing while disabled = %d", rec.Code)
+	}
+
+	rec := do(t, staticServer(true), "GET", "/static/docs/", "")
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != "no-cache" {
+		t.Fatalf("listing = %d %v", rec.Code, rec.Header())
+	}
+	for _, want := range []string{`href="a%20%3Cb%3E.txt">a &lt;b&gt;.txt</a>`, `href="sub/">sub/</a>`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("listing lacks %s:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, ".hidden") {
+		t.Errorf("listing shows hidden entry:\n%s", body)
+	}
+}
+
+func TestStaticDefaultAssets(t *testing.T) {
+	rec := do(t, New(nil), "GET", "/static/", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<title>tutorial server</title>") {
+		t.Fatalf("GET /static/ = %d %q", rec.Code, rec.Body)
+	}
+	if rec := do(t, New(nil), "GET", "/static/style.css", ""); !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/css") {
+		t.Fatalf("style.css Content-Type = %q", rec.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunTLSHandshake(t *testing.T) {
+	cfg, err := TLSConfig("", "", "example.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf := cfg.Certificates[0].Leaf
+	if err := leaf.VerifyHostname("example.test"); err != nil {
+		t.Fatalf("certificate does not cover extra host: %v", err)
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(ctx, &http.Server{Handler: New(nil), ErrorLog: log.New(io.Discard, "", 0)}, tls.NewListener(lis, cfg), time.Second)
+	}()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	resp, err := client.Get("https://" + lis.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.TLS == nil || !resp.TLS.HandshakeComplete || string(body) != "ok\n" {
+		t.Fatalf("response over TLS: %+v %q", resp.TLS, body)
+	}
+
+	// A client that does not trust the certificate must fail.
+	if _, err := http.Get("https://" + lis.Addr().String() + "/"); err == nil {
+		t.Fatal("untrusted self-signed certificate accepted")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run = %v", err)
+	}
+}
+
+func TestTLSConfigFiles(t *testing.T) {
+	cert, err := SelfSignedCert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600)
+
+	cfg, err := TLSConfig(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Certificates[0].Certificate[0]; string(got) != string(cert.Certificate[0]) {
+		t.Fatal("loaded a different certificate")
+	}
+	if _, err := TLSConfig(certFile, ""); err == nil {
+		t.Fatal("certificate without key accepted")
+	}
+	if _, err := TLSConfig(filepath.Join(dir, "missing.pem"), keyFile); err == nil {
+		t.Fatal("missing certificate file accepted")
+	}
+}
+
+func TestHTTP2OverTLS(t *testing.T) {
+	cfg, err := TLSConfig("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := DefaultServeConfig().HTTPServer(New(nil))
+	go srv.Serve(tls.NewListener(lis, cfg))
+	defer srv.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cfg.Certificates[0].Leaf)
+	for _, tt := range []struct {
+		h2   bool
+		want string
+	}{{true, "HTTP/2.0"}, {false, "HTTP/1.1"}} {
+		tr := &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}, ForceAttemptHTTP2: tt.h2}
+		resp, err := (&http.Client{Transport: tr}).Get("https://" + lis.Addr().String() + "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.Proto != tt.want || resp.TLS.NegotiatedProtocol != map[bool]string{true: "h2"}[tt.h2] {
+			t.Errorf("ForceAttemptHTTP2=%v: proto %s, ALPN %q", tt.h2, resp.Proto, resp.TLS.NegotiatedProtocol)
+		}
+		tr.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"slices"
+	"time"
+
+	"example.com/tutorial/i18n"
+	"example.com/tutorial/id"
+	"example.com/tutorial/ingest"
+	"example.com/tutorial/logging"
+	"example.com/tutorial/text"
+)
+
+// DefaultMaxUploadBytes bounds the request body of POST /upload when
+// Config.MaxUploadBytes is zero.
+const DefaultMaxUploadBytes = 32 << 20
+
+// DefaultUploadTypes are the media types POST /upload accepts when
+// Config.UploadTypes is empty.
+var DefaultUploadTypes = []string{"text/plain", "text/markdown", "text/csv", "text/html", "text/xml", "application/xml"}
+
+// sniffLen is how much of an untyped upload is read to guess its type,
+// as http.DetectContentType considers.
+const sniffLen = 512
+
+// uploadJSON is the response of POST /upload: one entry per file and
+// the totals across all of them.
+type uploadJSON struct {
+	Files []uploadFileJSON `json:"files"`
+	wordCountJSON
+}
+
+type uploadFileJSON struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Bytes int64  `json:"bytes"`
+	wordCountJSON
+}
+
+// errUnsupportedType is returned for uploads of a type not accepted.
+type errUnsupportedType struct{ name, mediaType string }
+
+func (e errUnsupportedType) Error() string {
+	return fmt.Sprintf("%s: unsupported media type %q", e.name, e.mediaType)
+}
+
+// handleUpload counts the words of every file in a multipart/form-data
+// upload. Each file is streamed through the counter as it arrives, so
+// only the counts are held in memory. Files must have one of the
+// accepted media types, taken from the part's Content-Type or, when that
+// is missing or application/octet-stream, sniffed from its first bytes.
+// The top query parameter sizes the tables as for /wordcount.
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	top, err := parseTop(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxUploadBytes)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+
+	out := uploadJSON{Files: []uploadFileJSON{}}
+	total := map[string]int{}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeError(w, r, uploadStatus(err), err)
+			return
+		}
+		if part.FileName() == "" {
+			continue // an ordinary form field
+		}
+		f, counts, err := s.countPart(r.Context(), part, top)
+		if err != nil {
+			writeError(w, r, uploadStatus(err), err)
+			return
+		}
+		out.Files = append(out.Files, f)
+		for word, c := range counts {
+			total[word] += c
+		}
+		out.Words += f.Words
+	}
+	if len(out.Files) == 0 {
+		writeError(w, r, http.StatusBadRequest, i18n.NewError(nil, "upload has no files"))
+		return
+	}
+	out.Unique = len(total)
+	out.Top = text.TopWords(total, top)
+	if out.Top == nil {
+		out.Top = []text.WordFreq{}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// countPart checks the type of one uploaded file and counts the words of
+// its text, extracted as package ingest does for the format its name or
+// type stands for.
+func (s *Server) countPart(ctx context.Context, part *multipart.Part, top int) (uploadFileJSON, map[string]int, error) {
+	f := uploadFileJSON{Name: part.FileName()}
+	br := bufio.NewReaderSize(part, sniffLen)
+	mt, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+	if mt == "" || mt == "application/octet-stream" {
+		head, err := br.Peek(sniffLen)
+		if err != nil && err != io.EOF {
+			return f, nil, err
+		}
+		mt, _, _ = mime.ParseMediaType(http.DetectContentType(head))
+	}
+	f.Type = mt
+	if !slices.Contains(s.uploadTypes, mt) {
+		return f, nil, errUnsupportedType{f.Name, mt}
+	}
+
+	start := time.Now()
+	ctx = logging.WithJobID(ctx, id.New())
+	format, ok := ingest.FormatOf(f.Name)
+	if !ok {
+		format, _ = ingest.ForMediaType(mt)
+	}
+	pr := &progressReader{ctx: ctx, r: br}
+	plain := ingest.NewReader(pr, format, ingest.Options{})
+	defer plain.Close()
+	counts, err := text.WordCountReader(plain)
+	f.Bytes = pr.bytes.Load()
+	for _, c := range counts {
+		f.Words += c
+	}
+	s.finishJob(ctx, "/upload", start, f.Bytes, int64(f.Words), err)
+	if err != nil {
+		return f, nil, fmt.Errorf("%s: %w", f.Name, err)
+	}
+	f.Unique = len(counts)
+	f.Top = text.TopWords(counts, top)
+	if f.Top == nil {
+		f.Top = []text.WordFreq{}
+	}
+	return f, counts, nil
+}
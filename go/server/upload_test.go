@@ -0,0 +1,115 @@
+package server
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"example.com/tutorial/text"
+)
+
+// uploadBody builds a multipart body with one part per file; an empty
+// type leaves the part's Content-Type out.
+func uploadBody(t *testing.T, files ...[3]string) (string, []byte) {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("comment", "not a file")
+	for _, f := range files {
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Disposition", `form-data; name="files"; filename="`+f[0]+`"`)
+		if f[1] != "" {
+			h.Set("Content-Type", f[1])
+		}
+		pw, err := mw.CreatePart(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pw.Write([]byte(f[2]))
+	}
+	mw.Close()
+	return mw.FormDataContentType(), body.Bytes()
+}
+
+func TestUpload(t *testing.T) {
+	ct, body := uploadBody(t,
+		[3]string{"a.txt", "text/plain; charset=utf-8", "the cat and the hat"},
+		[3]string{"b.md", "", "the end"},
+		[3]string{"c.csv", "application/octet-stream", "cat,hat"},
+	)
+	rec := postText(t, New(nil), "/upload?top=1", ct, body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	got := decode[uploadJSON](t, rec)
+	if len(got.Files) != 3 {
+		t.Fatalf("files = %+v", got.Files)
+	}
+	a, b, c := got.Files[0], got.Files[1], got.Files[2]
+	if a.Name != "a.txt" || a.Type != "text/plain" || a.Bytes != 19 || a.Words != 5 || a.Unique != 4 ||
+		a.Top[0] != (text.WordFreq{Word: "the", Count: 2}) {
+		t.Errorf("a.txt = %+v", a)
+	}
+	if b.Type != "text/plain" || b.Words != 2 || c.Type != "text/plain" || c.Words != 2 {
+		t.Errorf("sniffed files = %+v, %+v", b, c)
+	}
+	if got.Words != 9 || got.Unique != 5 || len(got.Top) != 1 || got.Top[0] != (text.WordFreq{Word: "the", Count: 3}) {
+		t.Errorf("totals = %+v", got.wordCountJSON)
+	}
+}
+
+func TestUploadExtractsText(t *testing.T) {
+	ct, body := uploadBody(t,
+		[3]string{"notes.md", "text/markdown", "# Cats\n\nSee [the hat](http://example.com/hat).\n"},
+		[3]string{"data", "application/json", `{"title": "cat", "tags": ["hat", "bat"], "n": 3}`},
+		[3]string{"feed", "", `<?xml version="1.0"?><feed><entry lang="en">cat <b>hat</b></entry></feed>`},
+	)
+	s := NewWithConfig(nil, Config{UploadTypes: []string{"text/markdown", "application/json", "text/xml"}})
+	rec := postText(t, s, "/upload", ct, body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	got := decode[uploadJSON](t, rec)
+	if md := got.Files[0]; md.Words != 4 || md.Bytes != 47 {
+		t.Errorf("markdown = %+v", md)
+	}
+	if js := got.Files[1]; js.Words != 3 || js.Unique != 3 {
+		t.Errorf("json = %+v", js)
+	}
+	if x := got.Files[2]; x.Type != "text/xml" || x.Words != 2 {
+		t.Errorf("xml = %+v", x)
+	}
+}
+
+func TestUploadErrors(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	tests := []struct {
+		name  string
+		files [][3]string
+		want  int
+	}{
+		{"no files", nil, http.StatusBadRequest},
+		{"declared type", [][3]string{{"x.json", "application/json", "{}"}}, http.StatusUnsupportedMediaType},
+		{"sniffed type", [][3]string{{"x.png", "", png}}, http.StatusUnsupportedMediaType},
+		{"too large", [][3]string{{"big.txt", "text/plain", strings.Repeat("word ", 100)}}, http.StatusRequestEntityTooLarge},
+	}
+	s := NewWithConfig(nil, Config{MaxUploadBytes: 400})
+	for _, tt := range tests {
+		ct, body := uploadBody(t, tt.files...)
+		if rec := postText(t, s, "/upload", ct, body); rec.Code != tt.want {
+			t.Errorf("%s: status %d, want %d (%s)", tt.name, rec.Code, tt.want, rec.Body)
+		}
+	}
+	if rec := postText(t, s, "/upload", "text/plain", []byte("a")); rec.Code != http.StatusBadRequest {
+		t.Errorf("not multipart: status %d", rec.Code)
+	}
+
+	s = NewWithConfig(nil, Config{UploadTypes: []string{"application/json"}})
+	ct, body := uploadBody(t, [3]string{"x.json", "application/json", `{"a": "b"}`})
+	if rec := postText(t, s, "/upload", ct, body); rec.Code != http.StatusOK {
+		t.Errorf("configured type: status %d (%s)", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,284 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"example.com/tutorial/errs"
+	"example.com/tutorial/i18n"
+	"example.com/tutorial/id"
+	"example.com/tutorial/router"
+	"example.com/tutorial/sliceutil"
+	"example.com/tutorial/user"
+)
+
+// userJSON is the API representation of a user. The password hash is
+// never sent to clients.
+type userJSON struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func toJSON(u user.User) userJSON { return userJSON{ID: u.ID, Name: u.Name} }
+
+// userInput is the body accepted by POST /users and PUT /users/{id}.
+type userInput struct {
+	Name     string `json:"name"`
+	Password string `json:"password,omitempty"`
+}
+
+type pageJSON struct {
+	Users []userJSON `json:"users"`
+	Total int        `json:"total"`
+	// NextCursor, if set, is the cursor parameter for the next page.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// userCursor is the cursor of a page of GET /users: the last user on
+// it, and the query it belongs to, so that it is not used with another.
+type userCursor struct {
+	ID     string         `json:"id"`
+	Name   string         `json:"name,omitempty"` // when sorted by name
+	Sort   user.SortField `json:"sort,omitempty"`
+	Desc   bool           `json:"desc,omitempty"`
+	Prefix string         `json:"prefix,omitempty"`
+}
+
+// newUserCursor returns the cursor of the page of q that ends with u.
+func newUserCursor(q user.ListQuery, u user.User) userCursor {
+	c := userCursor{ID: u.ID, Sort: sortField(q.Sort), Desc: q.Desc, Prefix: q.NamePrefix}
+	if c.Sort == user.SortByName {
+		c.Name = u.Name
+	}
+	return c
+}
+
+// sortField returns f, with the default made explicit.
+func sortField(f user.SortField) user.SortField {
+	if f == "" {
+		return user.SortByID
+	}
+	return f
+}
+
+// streamPageSize is how many users a GET /users stream reads from the
+// store at a time.
+const streamPageSize = 500
+
+// errNoSuchUser answers requests for user IDs that cannot exist.
+var errNoSuchUser = i18n.NewError(errs.ErrNotFound, "no such user")
+
+// entity names the user with the given ID in errors.
+func entity(id string) string { return "user " + id }
+
+// userID parses the {id} path parameter, answering 404 itself if it is
+// not a valid ID.
+func userID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	s := router.Param(r, "id")
+	if !id.Valid(s) {
+		fail(w, r, errNoSuchUser)
+		return "", false
+	}
+	return s, true
+}
+
+func (s *Server) listUsers(w http.ResponseWriter, r *http.Request) {
+	q, err := parseListQuery(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	ct := negotiate(r, mimeJSON, mimeText, mimeNDJSON)
+	if ct == "" {
+		writeError(w, r, http.StatusNotAcceptable, i18n.NewError(nil, "supported types: %s", "application/json, text/plain, application/x-ndjson"))
+		return
+	}
+	if ct == mimeNDJSON {
+		s.streamUsers(w, r, q)
+		return
+	}
+	// One more than the limit tells whether there is a next page.
+	limit := q.Limit
+	if limit > 0 {
+		q.Limit++
+	}
+	page, err := s.users.List(r.Context(), q)
+	if err != nil {
+		fail(w, r, errs.Wrap(err, "list", "users"))
+		return
+	}
+	var next string
+	if limit > 0 && len(page.Users) > limit {
+		page.Users = page.Users[:limit]
+		next = encodeCursor(newUserCursor(q, page.Users[limit-1]))
+	}
+	if ct == mimeText {
+		w.Header().Set("Content-Type", mimeText+"; charset=utf-8")
+		for _, u := range page.Users {
+			fmt.Fprintf(w, "%s\t%s\n", u.ID, u.Name)
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, pageJSON{Users: sliceutil.Map(page.Users, toJSON), Total: page.Total, NextCursor: next})
+}
+
+// streamUsers answers GET /users with NDJSON, a user to a line, reading
+// the store a page at a time, so that neither it nor the server holds
+// every user at once. The limit parameter, if any, bounds the users sent
+// in all. A store error after the first page ends the stream with an
+// error line.
+func (s *Server) streamUsers(w http.ResponseWriter, r *http.Request, q user.ListQuery) {
+	remaining := q.Limit
+	var st *streamer
+	for {
+		q.Limit = streamPageSize
+		if remaining > 0 {
+			q.Limit = min(remaining, streamPageSize)
+		}
+		page, err := s.users.List(r.Context(), q)
+		if err != nil {
+			err = errs.Wrap(err, "list", "users")
+			if st == nil {
+				fail(w, r, err)
+			} else {
+				st.fail(r, err)
+			}
+			return
+		}
+		if st == nil {
+			st = newStreamer(w)
+		}
+		for _, u := range page.Users {
+			if st.send(toJSON(u)) != nil {
+				return
+			}
+		}
+		if remaining > 0 {
+			if remaining -= len(page.Users); remaining == 0 {
+				break
+			}
+		}
+		if len(page.Users) < q.Limit {
+			break
+		}
+		last := page.Users[len(page.Users)-1]
+		q.After, q.Offset = &last, 0
+	}
+	st.flush()
+}
+
+// parseListQuery reads limit, offset, sort, order, prefix and cursor
+// parameters. A cursor continues the page it came from, so it must come
+// with the same sort, order and prefix, and without an offset.
+func parseListQuery(r *http.Request) (user.ListQuery, error) {
+	v := r.URL.Query()
+	q := user.ListQuery{
+		NamePrefix: v.Get("prefix"),
+		Sort:       user.SortField(v.Get("sort")),
+	}
+	switch v.Get("order") {
+	case "", "asc":
+	case "desc":
+		q.Desc = true
+	default:
+		return q, i18n.NewError(nil, "order must be asc or desc")
+	}
+	for name, dst := range map[string]*int{"limit": &q.Limit, "offset": &q.Offset} {
+		if s := v.Get(name); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				return q, i18n.NewError(nil, "%s must be an integer", name)
+			}
+			*dst = n
+		}
+	}
+	var c userCursor
+	if ok, err := decodeCursor(r, &c); err != nil {
+		return q, err
+	} else if ok {
+		if c.Sort != sortField(q.Sort) || c.Desc != q.Desc || c.Prefix != q.NamePrefix || v.Has("offset") {
+			return q, errBadCursor
+		}
+		q.After = &user.User{ID: c.ID, Name: c.Name}
+	}
+	return q, q.Validate()
+}
+
+func (s *Server) createUser(w http.ResponseWriter, r *http.Request) {
+	var in userInput
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+	u := user.User{Name: in.Name}
+	if in.Password != "" {
+		if err := u.SetPassword(in.Password); err != nil {
+			writeError(w, r, http.StatusBadRequest, err)
+			return
+		}
+	}
+	u, err := s.users.Create(r.Context(), u)
+	if err != nil {
+		fail(w, r, errs.Wrap(err, "create", "user"))
+		return
+	}
+	w.Header().Set("Location", "/users/"+u.ID)
+	writeJSON(w, http.StatusCreated, toJSON(u))
+}
+
+func (s *Server) getUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := userID(w, r)
+	if !ok {
+		return
+	}
+	if negotiate(r, mimeJSON) == "" {
+		writeError(w, r, http.StatusNotAcceptable, i18n.NewError(nil, "supported types: %s", "application/json"))
+		return
+	}
+	u, err := s.users.Get(r.Context(), id)
+	if err != nil {
+		fail(w, r, errs.Wrap(err, "get", entity(id)))
+		return
+	}
+	writeJSON(w, http.StatusOK, toJSON(u))
+}
+
+func (s *Server) updateUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := userID(w, r)
+	if !ok || !s.authorized(w, r, id) {
+		return
+	}
+	var in userInput
+	if !decodeJSON(w, r, &in) {
+		return
+	}
+	u, err := s.users.Get(r.Context(), id)
+	if err != nil {
+		fail(w, r, errs.Wrap(err, "get", entity(id)))
+		return
+	}
+	u.Name = in.Name
+	if in.Password != "" {
+		if err := u.SetPassword(in.Password); err != nil {
+			writeError(w, r, http.StatusBadRequest, err)
+			return
+		}
+	}
+	if err := s.users.Update(r.Context(), u); err != nil {
+		fail(w, r, errs.Wrap(err, "update", entity(id)))
+		return
+	}
+	writeJSON(w, http.StatusOK, toJSON(u))
+}
+
+func (s *Server) deleteUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := userID(w, r)
+	if !ok || !s.authorized(w, r, id) {
+		return
+	}
+	if err := s.users.Delete(r.Context(), id); err != nil {
+		fail(w, r, errs.Wrap(err, "delete", entity(id)))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,332 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/tutorial/buildinfo"
+	"example.com/tutorial/health"
+	"example.com/tutorial/user"
+)
+
+func do(t *testing.T, h http.Handler, method, path, body string, header ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	var rd io.Reader
+	if body != "" {
+		rd = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, path, rd)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func decode[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+	return v
+}
+
+func TestUsersCRUD(t *testing.T) {
+	store := user.NewMemoryStore()
+	h := New(store)
+
+	rec := do(t, h, "POST", "/users", `{"name":"Ada","password":"analytical engine"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("POST status %d: %s", rec.Code, rec.Body)
+	}
+	created := decode[userJSON](t, rec)
+	if created.Name != "Ada" || created.ID == "" || rec.Header().Get("Location") != "/users/"+created.ID {
+		t.Fatalf("created %+v, Location %q", created, rec.Header().Get("Location"))
+	}
+	if strings.Contains(rec.Body.String(), "password") {
+		t.Fatalf("response leaks password: %s", rec.Body)
+	}
+	stored, _ := store.Get(context.Background(), created.ID)
+	if !stored.CheckPassword("analytical engine") {
+		t.Fatal("password not stored")
+	}
+
+	path := "/users/" + created.ID
+	rec = do(t, h, "GET", path, "")
+	if rec.Code != http.StatusOK || decode[userJSON](t, rec) != created {
+		t.Fatalf("GET status %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = do(t, h, "PUT", path, `{"name":"Ada Lovelace"}`)
+	if rec.Code != http.StatusOK || decode[userJSON](t, rec).Name != "Ada Lovelace" {
+		t.Fatalf("PUT status %d: %s", rec.Code, rec.Body)
+	}
+	if stored, _ := store.Get(context.Background(), created.ID); !stored.CheckPassword("analytical engine") {
+		t.Fatal("PUT without password cleared it")
+	}
+
+	rec = do(t, h, "DELETE", path, "")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE status %d: %s", rec.Code, rec.Body)
+	}
+	for _, m := range []string{"GET", "DELETE"} {
+		if rec := do(t, h, m, path, ""); rec.Code != http.StatusNotFound {
+			t.Fatalf("%s after delete: status %d", m, rec.Code)
+		}
+	}
+	if rec := do(t, h, "PUT", path, `{"name":"x"}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("PUT after delete: status %d", rec.Code)
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	h := New(user.NewMemoryStore())
+	ids := map[string]string{}
+	for _, name := range []string{"carol", "alice", "bob"} {
+		rec := do(t, h, "POST", "/users", `{"name":"`+name+`"}`)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("POST status %d", rec.Code)
+		}
+		ids[name] = decode[userJSON](t, rec).ID
+	}
+	rec := do(t, h, "GET", "/users?sort=name&limit=2", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	page := decode[pageJSON](t, rec)
+	if page.Total != 3 || len(page.Users) != 2 || page.Users[0].Name != "alice" || page.Users[1].Name != "bob" {
+		t.Fatalf("page = %+v", page)
+	}
+
+	rec = do(t, h, "GET", "/users?order=desc", "", "Accept", "text/plain")
+	if got := rec.Body.String(); rec.Code != http.StatusOK || got != ids["bob"]+"\tbob\n"+ids["alice"]+"\talice\n"+ids["carol"]+"\tcarol\n" {
+		t.Fatalf("text status %d: %q", rec.Code, got)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	for _, q := range []string{"limit=x", "order=up", "sort=email", "offset=-1"} {
+		if rec := do(t, h, "GET", "/users?"+q, ""); rec.Code != http.StatusBadRequest && rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("GET /users?%s: status %d", q, rec.Code)
+		}
+	}
+}
+
+func TestListUsersCursor(t *testing.T) {
+	h := New(user.NewMemoryStore())
+	for _, name := range []string{"carol", "alice", "bob", "dave", "erin"} {
+		do(t, h, "POST", "/users", `{"name":"`+name+`"}`)
+	}
+	var names []string
+	path := "/users?sort=name&order=desc&limit=2"
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatalf("more than 3 pages: %v", names)
+		}
+		rec := do(t, h, "GET", path, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status %d: %s", path, rec.Code, rec.Body)
+		}
+		page := decode[pageJSON](t, rec)
+		if page.Total != 5 {
+			t.Errorf("GET %s: total %d", path, page.Total)
+		}
+		for _, u := range page.Users {
+			names = append(names, u.Name)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		path = "/users?sort=name&order=desc&limit=2&cursor=" + page.NextCursor
+	}
+	if got := strings.Join(names, " "); got != "erin dave carol bob alice" {
+		t.Fatalf("paged names %q", got)
+	}
+
+	rec := do(t, h, "GET", "/users?limit=5", "")
+	if page := decode[pageJSON](t, rec); len(page.Users) != 5 || page.NextCursor != "" {
+		t.Errorf("whole list as one page: %+v", page)
+	}
+
+	rec = do(t, h, "GET", "/users?sort=name&limit=2", "")
+	cursor := decode[pageJSON](t, rec).NextCursor
+	for _, q := range []string{"cursor=junk", "cursor=" + cursor, "sort=name&prefix=a&cursor=" + cursor, "sort=name&offset=1&cursor=" + cursor} {
+		rec := do(t, h, "GET", "/users?"+q, "")
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid cursor") {
+			t.Errorf("GET /users?%s: status %d: %s", q, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestListUsersStream(t *testing.T) {
+	store := user.NewMemoryStore()
+	const n = 2*streamPageSize + 3
+	for i := 0; i < n; i++ {
+		if _, err := store.Create(context.Background(), user.User{Name: fmt.Sprintf("u%04d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := New(store)
+	lines := func(path string) []userJSON {
+		t.Helper()
+		rec := do(t, h, "GET", path, "", "Accept", mimeNDJSON)
+		if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), mimeNDJSON) {
+			t.Fatalf("GET %s: status %d, Content-Type %q", path, rec.Code, rec.Header().Get("Content-Type"))
+		}
+		var us []userJSON
+		dec := json.NewDecoder(rec.Body)
+		for dec.More() {
+			var u userJSON
+			if err := dec.Decode(&u); err != nil {
+				t.Fatalf("GET %s: %v", path, err)
+			}
+			us = append(us, u)
+		}
+		return us
+	}
+
+	us := lines("/users?sort=name")
+	if len(us) != n {
+		t.Fatalf("streamed %d users, want %d", len(us), n)
+	}
+	for i, u := range us {
+		if want := fmt.Sprintf("u%04d", i); u.Name != want {
+			t.Fatalf("user %d is %q, want %q", i, u.Name, want)
+		}
+	}
+	us = lines("/users?sort=name&order=desc&offset=2&limit=" + fmt.Sprint(streamPageSize+1))
+	if len(us) != streamPageSize+1 || us[0].Name != fmt.Sprintf("u%04d", n-3) || us[len(us)-1].Name != fmt.Sprintf("u%04d", n-3-streamPageSize) {
+		t.Fatalf("streamed %d users, %v to %v", len(us), us[0], us[len(us)-1])
+	}
+}
+
+func TestUsersErrors(t *testing.T) {
+	h := New(user.NewMemoryStore())
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		header []string
+		want   int
+	}{
+		{"blank name", "POST", "/users", `{"name":" "}`, nil, http.StatusUnprocessableEntity},
+		{"short password", "POST", "/users", `{"name":"Ada","password":"x"}`, nil, http.StatusUnprocessableEntity},
+		{"bad json", "POST", "/users", `{"name":`, nil, http.StatusBadRequest},
+		{"unknown field", "POST", "/users", `{"nom":"Ada"}`, nil, http.StatusBadRequest},
+		{"wrong content type", "POST", "/users", `{"name":"Ada"}`, []string{"Content-Type", "text/plain"}, http.StatusUnsupportedMediaType},
+		{"not acceptable", "GET", "/users", "", []string{"Accept", "application/xml"}, http.StatusNotAcceptable},
+		{"bad id", "GET", "/users/a.b", "", nil, http.StatusNotFound},
+		{"collection method", "DELETE", "/users", "", nil, http.StatusMethodNotAllowed},
+		{"item method", "POST", "/users/1", "", nil, http.StatusMethodNotAllowed},
+		{"unknown path", "GET", "/nope", "", nil, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := do(t, h, tt.method, tt.path, tt.body, tt.header...)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status %d, want %d (%s)", tt.name, rec.Code, tt.want, rec.Body)
+		}
+	}
+	rec := do(t, h, "POST", "/users", `{"name":""}`)
+	body := decode[errorBody](t, rec)
+	if len(body.Fields) != 1 || body.Fields[0].Field != "name" {
+		t.Fatalf("validation body = %+v", body)
+	}
+	if rec := do(t, h, "PATCH", "/users", ""); rec.Header().Get("Allow") == "" {
+		t.Fatal("405 without Allow header")
+	}
+}
+
+func TestLocalizedErrors(t *testing.T) {
+	h := New(user.NewMemoryStore())
+	tests := []struct {
+		name, method, path, body string
+		header                   []string
+		want, wantLang           string
+	}{
+		{"english by default", "GET", "/nope", "", nil, "not found", "en"},
+		{"french", "GET", "/nope", "", []string{"Accept-Language", "fr-FR,fr;q=0.9"}, "introuvable", "fr"},
+		{"unsupported", "GET", "/nope", "", []string{"Accept-Language", "ja"}, "not found", "en"},
+		{"with args", "GET", "/users", "", []string{"Accept-Language", "fr", "Accept", "application/xml"}, "types acceptés : application/json, text/plain, application/x-ndjson", "fr"},
+		{"validation", "POST", "/users", `{"name":""}`, []string{"Accept-Language", "fr"}, "validation échouée", "fr"},
+		{"weights", "DELETE", "/users", "", []string{"Accept-Language", "fr;q=0.5, de"}, "méthode non autorisée", "fr"},
+		// Store errors carry context the catalogs do not have.
+		{"wrapped", "GET", "/users/9", "", []string{"Accept-Language", "fr"}, "get user 9: user: not found", "fr"},
+	}
+	for _, tt := range tests {
+		rec := do(t, h, tt.method, tt.path, tt.body, tt.header...)
+		if got := decode[errorBody](t, rec).Error; got != tt.want {
+			t.Errorf("%s: error %q, want %q", tt.name, got, tt.want)
+		}
+		if got := rec.Header().Get("Content-Language"); got != tt.wantLang {
+			t.Errorf("%s: Content-Language %q, want %q", tt.name, got, tt.wantLang)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", mimeJSON},
+		{"*/*", mimeJSON},
+		{"text/plain", mimeText},
+		{"text/*", mimeText},
+		{"text/plain;q=0.5, application/json;q=0.9", mimeJSON},
+		{"application/json;q=0, */*", mimeText},
+		{"image/png", ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		if got := negotiate(r, mimeJSON, mimeText); got != tt.want {
+			t.Errorf("negotiate(%q) = %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
+
+func TestRoot(t *testing.T) {
+	rec := do(t, New(user.NewMemoryStore()), "GET", "/", "")
+	if rec.Code != http.StatusOK || rec.Body.String() != "ok\n" {
+		t.Fatalf("GET / = %d %q", rec.Code, rec.Body)
+	}
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	failing := health.CheckerFunc(func(context.Context) error { return errors.New("disk full") })
+	h := NewWithConfig(user.NewMemoryStore(), Config{Checks: map[string]health.Checker{"disk": failing}})
+	if rec := do(t, h, "GET", "/healthz", ""); rec.Code != http.StatusOK {
+		t.Fatalf("healthz = %d", rec.Code)
+	}
+	rec := do(t, h, "GET", "/readyz", "")
+	rep := decode[health.Report](t, rec)
+	if rec.Code != http.StatusServiceUnavailable || rep.Checks["store"].Status != health.StatusOK || rep.Checks["disk"].Error != "disk full" {
+		t.Fatalf("readyz = %d %+v", rec.Code, rep)
+	}
+	if rec := do(t, New(user.NewMemoryStore()), "GET", "/readyz", ""); rec.Code != http.StatusOK {
+		t.Fatalf("readyz without failing checks = %d", rec.Code)
+	}
+}
+
+func TestVersionEndpoint(t *testing.T) {
+	rec := do(t, New(user.NewMemoryStore()), "GET", "/version", "")
+	if info := decode[buildinfo.Info](t, rec); rec.Code != http.StatusOK || info != buildinfo.Get() {
+		t.Fatalf("version = %d %+v", rec.Code, info)
+	}
+}
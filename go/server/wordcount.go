@@ -0,0 +1,328 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"example.com/tutorial/cache"
+	"example.com/tutorial/i18n"
+	"example.com/tutorial/id"
+	"example.com/tutorial/logging"
+	"example.com/tutorial/text"
+)
+
+// defaultMaxTextBytes bounds the documents accepted by POST /wordcount.
+const defaultMaxTextBytes = 10 << 20
+
+// defaultTopWords is the table size when the top parameter is absent.
+const defaultTopWords = 10
+
+// DefaultResultCacheBytes is the size of the POST /wordcount result
+// cache when Config.ResultCacheBytes is zero.
+const DefaultResultCacheBytes = 32 << 20
+
+// countResult is a cached word count, keyed by the SHA-256 of the
+// document.
+type countResult struct {
+	counts map[string]int
+	words  int
+}
+
+// resultSize estimates the memory held by r: each word and its count
+// plus map overhead.
+func resultSize(r countResult) int64 {
+	var n int64
+	for w := range r.counts {
+		n += int64(len(w)) + 32
+	}
+	return n
+}
+
+// newResultCache returns the result cache sized by cfg, reporting into
+// cfg.Metrics, or nil if it is disabled. A shared cfg.ResultCache is
+// used as is.
+func newResultCache(cfg Config) cache.Store[[sha256.Size]byte, countResult] {
+	if cfg.ResultCache != nil {
+		return sharedResults{cfg.ResultCache}
+	}
+	if cfg.ResultCacheBytes < 0 {
+		return nil
+	}
+	if cfg.ResultCacheBytes == 0 {
+		cfg.ResultCacheBytes = DefaultResultCacheBytes
+	}
+	c := cache.New[[sha256.Size]byte](cache.Config{MaxBytes: cfg.ResultCacheBytes}, resultSize)
+	c.Register(cfg.Metrics, "wordcount_result_cache")
+	return c
+}
+
+// sharedResults keeps results in a cache shared with other servers,
+// which takes bytes under string keys.
+type sharedResults struct {
+	store cache.Store[string, []byte]
+}
+
+// resultJSON is a countResult as sharedResults stores it.
+type resultJSON struct {
+	Counts map[string]int `json:"counts"`
+	Words  int            `json:"words"`
+}
+
+func (c sharedResults) Get(key [sha256.Size]byte) (countResult, bool) {
+	b, ok := c.store.Get(hex.EncodeToString(key[:]))
+	if !ok {
+		return countResult{}, false
+	}
+	var r resultJSON
+	// Anything else under the key, as from another version, is a miss.
+	if err := json.Unmarshal(b, &r); err != nil || r.Counts == nil {
+		return countResult{}, false
+	}
+	return countResult{counts: r.Counts, words: r.Words}, true
+}
+
+func (c sharedResults) Set(key [sha256.Size]byte, res countResult) {
+	b, err := json.Marshal(resultJSON{Counts: res.counts, Words: res.words})
+	if err == nil {
+		c.store.Set(hex.EncodeToString(key[:]), b)
+	}
+}
+
+func (c sharedResults) Delete(key [sha256.Size]byte) {
+	c.store.Delete(hex.EncodeToString(key[:]))
+}
+
+// wordCountJSON is the response of POST /wordcount.
+type wordCountJSON = text.Summary
+
+// wordCountPageJSON is a page of the response of POST /wordcount.
+type wordCountPageJSON struct {
+	wordCountJSON
+	// NextCursor, if set, is the cursor parameter for the next page.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// wordCursor is the cursor of a page of POST /wordcount: the last word
+// on it.
+type wordCursor struct {
+	Word  string `json:"w"`
+	Count int    `json:"c"`
+}
+
+// handleWordCount counts the words of the request body, or of the "file"
+// part of a multipart/form-data upload, and returns the top-N table.
+// The top query parameter sets N; -1 returns every word. Counts are
+// cached by the document's content hash, and the X-Cache header says
+// whether this one was a HIT or a MISS.
+//
+// The table is paged: next_cursor, passed back as the cursor parameter
+// with the same document, returns the N words ranked after this page.
+// With Accept: application/x-ndjson the words are streamed instead, one
+// to a line, and the totals and next cursor sent as the X-Words,
+// X-Unique-Words and X-Next-Cursor headers.
+func (s *Server) handleWordCount(w http.ResponseWriter, r *http.Request) {
+	top, err := parseTop(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	var after *text.WordFreq
+	var c wordCursor
+	if ok, err := decodeCursor(r, &c); err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	} else if ok {
+		after = &text.WordFreq{Word: c.Word, Count: c.Count}
+	}
+	release, err := s.acquireCounting(r)
+	if err != nil {
+		writeError(w, r, http.StatusServiceUnavailable, err)
+		return
+	}
+	defer release()
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxTextBytes)
+
+	body := io.Reader(r.Body)
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "multipart/form-data" {
+		part, err := uploadedFile(r)
+		if err != nil {
+			writeError(w, r, uploadStatus(err), err)
+			return
+		}
+		body = part
+	}
+	start := time.Now()
+	ctx := logging.WithJobID(r.Context(), id.New())
+	pr := &progressReader{ctx: ctx, r: body}
+	res, err := s.countCached(ctx, w, pr)
+	s.finishJob(ctx, "/wordcount", start, pr.bytes.Load(), int64(res.words), err)
+	if err != nil {
+		writeError(w, r, uploadStatus(err), err)
+		return
+	}
+	freqs, next := rankPage(res.counts, after, top)
+	if negotiate(r, mimeJSON, mimeNDJSON) == mimeNDJSON {
+		w.Header().Set("X-Words", strconv.Itoa(res.words))
+		w.Header().Set("X-Unique-Words", strconv.Itoa(len(res.counts)))
+		if next != "" {
+			w.Header().Set("X-Next-Cursor", next)
+		}
+		st := newStreamer(w)
+		for _, wf := range freqs {
+			if st.send(wf) != nil {
+				return
+			}
+		}
+		st.flush()
+		return
+	}
+	writeJSON(w, http.StatusOK, wordCountPageJSON{
+		wordCountJSON: wordCountJSON{Words: res.words, Unique: len(res.counts), Top: freqs},
+		NextCursor:    next,
+	})
+}
+
+// rankPage returns the n words of counts that rank after after, or from
+// the first if it is nil, and the cursor of the page after them, empty
+// if there is none. An n of -1 returns all of them.
+func rankPage(counts map[string]int, after *text.WordFreq, n int) ([]text.WordFreq, string) {
+	// One more than n tells whether there is a next page.
+	want := n
+	if n >= 0 {
+		want = n + 1
+	}
+	freqs := text.TopWordsFunc(func(yield func(text.WordFreq)) {
+		for w, c := range counts {
+			wf := text.WordFreq{Word: w, Count: c}
+			if after == nil || text.RanksBefore(*after, wf) {
+				yield(wf)
+			}
+		}
+	}, want)
+	var next string
+	if n >= 0 && len(freqs) > n {
+		freqs = freqs[:n]
+		if n > 0 {
+			next = encodeCursor(wordCursor{Word: freqs[n-1].Word, Count: freqs[n-1].Count})
+		}
+	}
+	return freqs, next
+}
+
+// countCached counts the words read from r, or looks them up in the
+// result cache when the same document was counted before. Identical
+// documents posted at the same time are counted once, for all of them.
+// The document is read whole first, to hash it; it is already bounded
+// by maxTextBytes.
+func (s *Server) countCached(ctx context.Context, w http.ResponseWriter, r io.Reader) (countResult, error) {
+	doc, err := io.ReadAll(r)
+	if err != nil {
+		return countResult{}, err
+	}
+	key := sha256.Sum256(doc)
+	if s.results != nil {
+		if res, ok := s.results.Get(key); ok {
+			w.Header().Set("X-Cache", "HIT")
+			return res, nil
+		}
+		w.Header().Set("X-Cache", "MISS")
+	}
+	res, err, shared := s.counts.Do(ctx, key, func(ctx context.Context) (countResult, error) {
+		counts, err := text.WordCountReader(&progressReader{ctx: ctx, r: bytes.NewReader(doc)})
+		if err != nil {
+			return countResult{}, err
+		}
+		res := newCountResult(counts)
+		if s.results != nil {
+			s.results.Set(key, res)
+		}
+		return res, nil
+	})
+	if shared {
+		s.jobs.shared.Inc()
+	}
+	return res, err
+}
+
+func newCountResult(counts map[string]int) countResult {
+	res := countResult{counts: counts}
+	for _, c := range counts {
+		res.words += c
+	}
+	return res
+}
+
+// acquireCounting takes r's share of the counting budget: its declared
+// length, or the most a document may be when that is unknown, and never
+// more than the whole budget. It waits while the budget is in use and
+// fails if the client goes away meanwhile; release gives the share back.
+func (s *Server) acquireCounting(r *http.Request) (release func(), err error) {
+	if s.counting == nil {
+		return func() {}, nil
+	}
+	n := s.maxTextBytes
+	if r.ContentLength > 0 {
+		n = min(n, r.ContentLength)
+	}
+	n = min(n, s.maxCountingBytes)
+	if err := s.counting.Acquire(r.Context(), n); err != nil {
+		return nil, fmt.Errorf("waiting to count: %w", err)
+	}
+	return func() { s.counting.Release(n) }, nil
+}
+
+// parseTop reads the top query parameter, defaulting to defaultTopWords.
+func parseTop(r *http.Request) (int, error) {
+	v := r.URL.Query().Get("top")
+	if v == "" {
+		return defaultTopWords, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < -1 {
+		return 0, i18n.NewError(nil, "top must be a non-negative integer or -1")
+	}
+	return n, nil
+}
+
+// uploadedFile returns the reader of the multipart part named "file".
+func uploadedFile(r *http.Request) (io.Reader, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, i18n.NewError(nil, `multipart body has no "file" part`)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" {
+			return part, nil
+		}
+	}
+}
+
+// uploadStatus maps errors reading an upload to a status code.
+func uploadStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	var badType errUnsupportedType
+	if errors.As(err, &badType) {
+		return http.StatusUnsupportedMediaType
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,284 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/tutorial/cache"
+	"example.com/tutorial/text"
+)
+
+func postText(t *testing.T, s *Server, path, contentType string, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("POST", path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestWordCountRawBody(t *testing.T) {
+	rec := postText(t, New(nil), "/wordcount?top=2", "text/plain", []byte("the cat and the hat and the bat"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	got := decode[wordCountJSON](t, rec)
+	want := wordCountJSON{Words: 8, Unique: 5, Top: []text.WordFreq{{Word: "the", Count: 3}, {Word: "and", Count: 2}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestWordCountMultipart(t *testing.T) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("note", "ignored words here")
+	fw, _ := mw.CreateFormFile("file", "doc.txt")
+	fw.Write([]byte("Go go GO gopher"))
+	mw.Close()
+
+	rec := postText(t, New(nil), "/wordcount", mw.FormDataContentType(), body.Bytes())
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	got := decode[wordCountJSON](t, rec)
+	if got.Words != 4 || got.Unique != 2 || got.Top[0] != (text.WordFreq{Word: "go", Count: 3}) {
+		t.Fatalf("got %+v", got)
+	}
+}
+
+func TestWordCountErrors(t *testing.T) {
+	s := New(nil)
+	s.maxTextBytes = 16
+
+	if rec := postText(t, s, "/wordcount", "text/plain", []byte(strings.Repeat("word ", 10))); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body: status %d", rec.Code)
+	}
+	if rec := postText(t, s, "/wordcount?top=x", "text/plain", []byte("a")); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad top: status %d", rec.Code)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("other", "x")
+	mw.Close()
+	if rec := postText(t, New(nil), "/wordcount", mw.FormDataContentType(), body.Bytes()); rec.Code != http.StatusBadRequest {
+		t.Errorf("multipart without file: status %d", rec.Code)
+	}
+	if rec := do(t, s, "GET", "/wordcount", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: status %d", rec.Code)
+	}
+}
+
+func TestWordCountCursor(t *testing.T) {
+	s := New(nil)
+	const doc = "a b b c c c d d d d e"
+	var got []text.WordFreq
+	path := "/wordcount?top=2"
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatalf("more than 3 pages: %v", got)
+		}
+		rec := postText(t, s, path, "text/plain", []byte(doc))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("POST %s: status %d: %s", path, rec.Code, rec.Body)
+		}
+		page := decode[wordCountPageJSON](t, rec)
+		if page.Words != 11 || page.Unique != 5 {
+			t.Errorf("POST %s: totals %+v", path, page.wordCountJSON)
+		}
+		got = append(got, page.Top...)
+		if page.NextCursor == "" {
+			break
+		}
+		path = "/wordcount?top=2&cursor=" + page.NextCursor
+	}
+	want := text.TopWords(map[string]int{"a": 1, "b": 2, "c": 3, "d": 4, "e": 1}, -1)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("paged %v, want %v", got, want)
+	}
+
+	if rec := postText(t, s, "/wordcount?cursor=junk", "text/plain", []byte(doc)); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad cursor: status %d", rec.Code)
+	}
+}
+
+func TestWordCountStream(t *testing.T) {
+	req := httptest.NewRequest("POST", "/wordcount?top=2", strings.NewReader("the cat and the hat and the bat"))
+	req.Header.Set("Accept", mimeNDJSON)
+	rec := httptest.NewRecorder()
+	New(nil).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), mimeNDJSON) {
+		t.Fatalf("status %d, Content-Type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if got := rec.Body.String(); got != `{"word":"the","count":3}`+"\n"+`{"word":"and","count":2}`+"\n" {
+		t.Errorf("body %q", got)
+	}
+	if rec.Header().Get("X-Words") != "8" || rec.Header().Get("X-Unique-Words") != "5" || rec.Header().Get("X-Next-Cursor") == "" {
+		t.Errorf("headers %v", rec.Header())
+	}
+}
+
+func TestWordCountEmpty(t *testing.T) {
+	rec := postText(t, New(nil), "/wordcount", "text/plain", nil)
+	if got := rec.Body.String(); got != `{"words":0,"unique":0,"top":[]}`+"\n" {
+		t.Fatalf("body = %q", got)
+	}
+}
+
+func TestWordCountMetrics(t *testing.T) {
+	s := New(nil)
+	postText(t, s, "/wordcount", "text/plain", []byte("one two three"))
+	s.maxTextBytes = 4
+	postText(t, s, "/wordcount", "text/plain", []byte("far too long"))
+
+	out := do(t, s, "GET", "/metrics", "").Body.String()
+	for _, want := range []string{
+		`wordcount_jobs_total{result="ok"} 1`,
+		`wordcount_jobs_total{result="error"} 1`,
+		"wordcount_words_total 3",
+		"wordcount_job_duration_seconds_count 2",
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("missing %s in:\n%s", want, out)
+		}
+	}
+}
+
+func TestWordCountResultCache(t *testing.T) {
+	s := New(nil)
+	doc := []byte("the cat and the hat")
+	var first wordCountJSON
+	for i, want := range []string{"MISS", "HIT"} {
+		rec := postText(t, s, "/wordcount?top=1", "text/plain", doc)
+		if got := rec.Header().Get("X-Cache"); got != want {
+			t.Fatalf("post %d: X-Cache %q, want %q", i, got, want)
+		}
+		if i == 0 {
+			first = decode[wordCountJSON](t, rec)
+		} else if got := decode[wordCountJSON](t, rec); !reflect.DeepEqual(got, first) {
+			t.Fatalf("cached result %+v, want %+v", got, first)
+		}
+	}
+	// The same document with another top is served from the cache too.
+	rec := postText(t, s, "/wordcount?top=-1", "text/plain", doc)
+	if rec.Header().Get("X-Cache") != "HIT" || len(decode[wordCountJSON](t, rec).Top) != 4 {
+		t.Fatalf("X-Cache %q, body %s", rec.Header().Get("X-Cache"), rec.Body)
+	}
+	if rec := postText(t, s, "/wordcount", "text/plain", []byte("another document")); rec.Header().Get("X-Cache") != "MISS" {
+		t.Fatal("different document served from the cache")
+	}
+	out := do(t, s, "GET", "/metrics", "").Body.String()
+	for _, want := range []string{
+		"wordcount_result_cache_hits_total 2",
+		"wordcount_result_cache_misses_total 2",
+		"wordcount_result_cache_entries 2",
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Errorf("missing %s in:\n%s", want, out)
+		}
+	}
+
+	off := NewWithConfig(nil, Config{ResultCacheBytes: -1})
+	for i := 0; i < 2; i++ {
+		if rec := postText(t, off, "/wordcount", "text/plain", doc); rec.Header().Get("X-Cache") != "" || rec.Code != http.StatusOK {
+			t.Fatalf("disabled cache: status %d, X-Cache %q", rec.Code, rec.Header().Get("X-Cache"))
+		}
+	}
+}
+
+func TestWordCountSharedResultCache(t *testing.T) {
+	// Two servers with one store serve each other's results.
+	shared := cache.New[string, []byte](cache.Config{}, nil)
+	a := NewWithConfig(nil, Config{ResultCache: shared})
+	b := NewWithConfig(nil, Config{ResultCache: shared})
+	doc := []byte("the cat and the hat")
+	first := postText(t, a, "/wordcount?top=-1", "text/plain", doc)
+	rec := postText(t, b, "/wordcount?top=-1", "text/plain", doc)
+	if first.Header().Get("X-Cache") != "MISS" || rec.Header().Get("X-Cache") != "HIT" {
+		t.Fatalf("X-Cache %q then %q", first.Header().Get("X-Cache"), rec.Header().Get("X-Cache"))
+	}
+	if got, want := decode[wordCountJSON](t, rec), decode[wordCountJSON](t, first); !reflect.DeepEqual(got, want) {
+		t.Fatalf("shared result %+v, want %+v", got, want)
+	}
+	if shared.Len() != 1 {
+		t.Fatalf("%d entries", shared.Len())
+	}
+
+	// A value the server cannot read is a miss, and is replaced.
+	key := sha256.Sum256([]byte("other"))
+	shared.Set(hex.EncodeToString(key[:]), []byte("not json"))
+	if rec := postText(t, b, "/wordcount", "text/plain", []byte("other")); rec.Header().Get("X-Cache") != "MISS" || rec.Code != http.StatusOK {
+		t.Fatalf("bad entry: status %d, X-Cache %q", rec.Code, rec.Header().Get("X-Cache"))
+	}
+	if rec := postText(t, a, "/wordcount", "text/plain", []byte("other")); rec.Header().Get("X-Cache") != "HIT" {
+		t.Fatalf("replaced entry: X-Cache %q", rec.Header().Get("X-Cache"))
+	}
+}
+
+func TestWordCountSharesConcurrentCounts(t *testing.T) {
+	s := NewWithConfig(nil, Config{ResultCacheBytes: -1})
+	doc := []byte("counted only once")
+	// Hold a count of doc in flight; the request joins it rather than
+	// counting again.
+	release := make(chan struct{})
+	go s.counts.Do(context.Background(), sha256.Sum256(doc), func(context.Context) (countResult, error) {
+		<-release
+		return countResult{counts: map[string]int{"joined": 1}, words: 1}, nil
+	})
+	for s.counts.InFlight() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	done := make(chan *httptest.ResponseRecorder, 1)
+	go func() { done <- postText(t, s, "/wordcount", "text/plain", doc) }()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	rec := <-done
+	if got := decode[wordCountJSON](t, rec); got.Words != 1 || got.Top[0].Word != "joined" {
+		t.Fatalf("got %+v, want the in-flight result", got)
+	}
+	if out := do(t, s, "GET", "/metrics", "").Body.String(); !strings.Contains(out, "wordcount_shared_total 1\n") {
+		t.Errorf("shared count missing in:\n%s", out)
+	}
+}
+
+func TestWordCountCountingBudget(t *testing.T) {
+	s := NewWithConfig(nil, Config{MaxCountingBytes: 16})
+	// Hold the whole budget, as a large document being counted would.
+	if err := s.counting.Acquire(context.Background(), 16); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest("POST", "/wordcount", strings.NewReader("queued and given up")).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status %d while the budget is taken, want 503", rec.Code)
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- postText(t, s, "/wordcount", "text/plain", []byte("waits its turn")) }()
+	select {
+	case <-done:
+		t.Fatal("counted while the budget is taken")
+	case <-time.After(20 * time.Millisecond):
+	}
+	s.counting.Release(16)
+	if rec := <-done; rec.Code != http.StatusOK {
+		t.Fatalf("status %d after release: %s", rec.Code, rec.Body)
+	}
+	if n := s.counting.InUse(); n != 0 {
+		t.Fatalf("%d bytes of budget still in use", n)
+	}
+}
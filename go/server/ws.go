@@ -0,0 +1,175 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebSocket connection tuning.
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = wsPongWait * 9 / 10
+	wsMaxMessage = 64 << 10
+	// wsSendBuffer is how many outgoing messages a client may fall
+	// behind by before it is disconnected.
+	wsSendBuffer = 16
+)
+
+var upgrader = websocket.Upgrader{ReadBufferSize: 4096, WriteBufferSize: 4096}
+
+type wsMessage struct {
+	typ  int
+	data []byte
+}
+
+// wsClient is one WebSocket connection. Only its write loop writes to
+// conn; everyone else queues messages on send.
+type wsClient struct {
+	conn *websocket.Conn
+	send chan wsMessage
+	// closeCode is sent to the peer when send is closed.
+	closeCode int
+}
+
+// hub relays every message received on /ws to all connected clients,
+// the sender included, so a single client sees its messages echoed and
+// several clients see each other's.
+type hub struct {
+	mu      sync.Mutex
+	clients map[*wsClient]struct{}
+	closed  bool
+}
+
+func newHub() *hub { return &hub{clients: map[*wsClient]struct{}{}} }
+
+func (h *hub) add(c *wsClient) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return false
+	}
+	h.clients[c] = struct{}{}
+	return true
+}
+
+// remove disconnects c with the given close code. It is safe to call
+// more than once.
+func (h *hub) remove(c *wsClient, code int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeLocked(c, code)
+}
+
+func (h *hub) removeLocked(c *wsClient, code int) {
+	if _, ok := h.clients[c]; !ok {
+		return
+	}
+	delete(h.clients, c)
+	c.closeCode = code
+	close(c.send)
+}
+
+// broadcast queues m for every client, dropping clients too slow to
+// keep up rather than letting them block the others.
+func (h *hub) broadcast(m wsMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		select {
+		case c.send <- m:
+		default:
+			h.removeLocked(c, websocket.ClosePolicyViolation)
+		}
+	}
+}
+
+func (h *hub) len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+// close disconnects every client with "going away" and refuses new ones.
+func (h *hub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for c := range h.clients {
+		h.removeLocked(c, websocket.CloseGoingAway)
+	}
+}
+
+// handleWebSocket upgrades the connection and joins it to the hub.
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // Upgrade has already replied with an error
+	}
+	c := &wsClient{conn: conn, send: make(chan wsMessage, wsSendBuffer)}
+	if !s.hub.add(c) {
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(wsWriteWait))
+		conn.Close()
+		return
+	}
+	go c.writeLoop()
+	c.readLoop(s.hub)
+}
+
+// readLoop forwards incoming messages to the hub until the connection
+// fails or the peer closes it.
+func (c *wsClient) readLoop(h *hub) {
+	defer h.remove(c, websocket.CloseNormalClosure)
+	c.conn.SetReadLimit(wsMaxMessage)
+	c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+	for {
+		typ, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		h.broadcast(wsMessage{typ, data})
+	}
+}
+
+// writeLoop sends queued messages and keep-alive pings, and closes the
+// connection once send is closed.
+func (c *wsClient) writeLoop() {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+	for {
+		select {
+		case m, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(c.closeCode, ""))
+				return
+			}
+			if err := c.conn.WriteMessage(m.typ, m.data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Close disconnects the long-lived WebSocket and job-event clients,
+// which http.Server.Shutdown does not track. Register it with
+// http.Server.RegisterOnShutdown.
+func (s *Server) Close() {
+	s.hub.close()
+	s.done.Close()
+}
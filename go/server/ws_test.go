@@ -0,0 +1,108 @@
+package server
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func dialWS(t *testing.T, ts *httptest.Server) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func readWS(t *testing.T, conn *websocket.Conn) string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// waitClients waits for the hub to reach n clients, since joining and
+// leaving happen on the server's goroutines.
+func waitClients(t *testing.T, s *Server, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for s.hub.len() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("hub has %d clients, want %d", s.hub.len(), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWebSocketEcho(t *testing.T) {
+	s := New(nil)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	conn := dialWS(t, ts)
+	conn.WriteMessage(websocket.TextMessage, []byte("hello"))
+	if got := readWS(t, conn); got != "hello" {
+		t.Fatalf("echo = %q", got)
+	}
+}
+
+func TestWebSocketBroadcast(t *testing.T) {
+	s := New(nil)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	a, b := dialWS(t, ts), dialWS(t, ts)
+	waitClients(t, s, 2)
+
+	a.WriteMessage(websocket.TextMessage, []byte("from a"))
+	if got := readWS(t, b); got != "from a" {
+		t.Fatalf("b got %q", got)
+	}
+	if got := readWS(t, a); got != "from a" {
+		t.Fatalf("a got %q", got)
+	}
+
+	// A client that says goodbye leaves the hub.
+	b.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "bye"))
+	waitClients(t, s, 1)
+	a.WriteMessage(websocket.TextMessage, []byte("still here"))
+	if got := readWS(t, a); got != "still here" {
+		t.Fatalf("a got %q", got)
+	}
+}
+
+func TestWebSocketServerClose(t *testing.T) {
+	s := New(nil)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	conn := dialWS(t, ts)
+	waitClients(t, s, 1)
+
+	s.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err := conn.ReadMessage()
+	var ce *websocket.CloseError
+	if !errors.As(err, &ce) || ce.Code != websocket.CloseGoingAway {
+		t.Fatalf("read after Close = %v, want going away", err)
+	}
+
+	// New connections are turned away once the server is closing.
+	late := dialWS(t, ts)
+	late.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, _, err := late.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Fatalf("late client read = %v", err)
+	}
+}
+
+func TestWebSocketRequiresUpgrade(t *testing.T) {
+	if rec := do(t, New(nil), "GET", "/ws", ""); rec.Code != 400 {
+		t.Fatalf("plain GET /ws = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,187 @@
+// Package set provides Set, a generic set of comparable values, with
+// the usual algebra: union, intersection and difference. A Set is a map
+// underneath, so ranging over one visits its elements in no particular
+// order; Sorted and SortedFunc list them in a fixed one, and so does its
+// JSON encoding.
+package set
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// Set is a set of values of type T. The zero value is an empty set that
+// can be read but not added to; use New or Of, or make(Set[T]).
+type Set[T comparable] map[T]struct{}
+
+// New returns an empty set with room for n elements.
+func New[T comparable](n int) Set[T] {
+	return make(Set[T], n)
+}
+
+// Of returns a set containing vs.
+func Of[T comparable](vs ...T) Set[T] {
+	s := make(Set[T], len(vs))
+	s.Add(vs...)
+	return s
+}
+
+// Add adds vs to s.
+func (s Set[T]) Add(vs ...T) {
+	for _, v := range vs {
+		s[v] = struct{}{}
+	}
+}
+
+// Remove removes vs from s; values that are not in s are ignored.
+func (s Set[T]) Remove(vs ...T) {
+	for _, v := range vs {
+		delete(s, v)
+	}
+}
+
+// Contains reports whether v is in s.
+func (s Set[T]) Contains(v T) bool {
+	_, ok := s[v]
+	return ok
+}
+
+// Len returns the number of elements in s.
+func (s Set[T]) Len() int { return len(s) }
+
+// Clone returns a copy of s. The copy of a nil set is an empty one.
+func (s Set[T]) Clone() Set[T] {
+	c := make(Set[T], len(s))
+	for v := range s {
+		c[v] = struct{}{}
+	}
+	return c
+}
+
+// Equal reports whether s and o have the same elements.
+func (s Set[T]) Equal(o Set[T]) bool {
+	return len(s) == len(o) && s.SubsetOf(o)
+}
+
+// SubsetOf reports whether every element of s is in o.
+func (s Set[T]) SubsetOf(o Set[T]) bool {
+	for v := range s {
+		if !o.Contains(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Union returns a new set of the elements in s, o or both.
+func (s Set[T]) Union(o Set[T]) Set[T] {
+	u := make(Set[T], max(len(s), len(o)))
+	for v := range s {
+		u[v] = struct{}{}
+	}
+	for v := range o {
+		u[v] = struct{}{}
+	}
+	return u
+}
+
+// Intersection returns a new set of the elements in both s and o.
+func (s Set[T]) Intersection(o Set[T]) Set[T] {
+	if len(o) < len(s) {
+		s, o = o, s // walk the smaller set
+	}
+	n := make(Set[T])
+	for v := range s {
+		if o.Contains(v) {
+			n[v] = struct{}{}
+		}
+	}
+	return n
+}
+
+// Difference returns a new set of the elements in s that are not in o.
+func (s Set[T]) Difference(o Set[T]) Set[T] {
+	d := make(Set[T])
+	for v := range s {
+		if !o.Contains(v) {
+			d[v] = struct{}{}
+		}
+	}
+	return d
+}
+
+// Slice returns the elements of s in no particular order, which may
+// change from call to call. It is the cheapest way to list a set whose
+// order does not matter. The result is never nil.
+func (s Set[T]) Slice() []T {
+	out := make([]T, 0, len(s))
+	for v := range s {
+		out = append(out, v)
+	}
+	return out
+}
+
+// SortedFunc returns the elements of s ordered by cmp, which returns a
+// negative number when a comes before b, a positive one when it comes
+// after and zero otherwise, as slices.SortFunc expects.
+func (s Set[T]) SortedFunc(cmp func(a, b T) int) []T {
+	out := s.Slice()
+	slices.SortFunc(out, cmp)
+	return out
+}
+
+// Sorted returns the elements of s in ascending order.
+func Sorted[T cmp.Ordered](s Set[T]) []T {
+	out := s.Slice()
+	slices.Sort(out)
+	return out
+}
+
+// MarshalJSON encodes s as a JSON array. Strings, numbers and booleans
+// come out in ascending order, other elements by their fmt form, so that
+// the same set always encodes the same way.
+func (s Set[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.SortedFunc(compareAny[T]))
+}
+
+// UnmarshalJSON decodes a JSON array into s, replacing its elements.
+// Repeated elements are kept once, and null decodes as an empty set.
+func (s *Set[T]) UnmarshalJSON(data []byte) error {
+	var vs []T
+	if err := json.Unmarshal(data, &vs); err != nil {
+		return err
+	}
+	*s = Of(vs...)
+	return nil
+}
+
+// compareAny orders values of any comparable type: basic kinds by value
+// and everything else by its fmt form.
+func compareAny[T comparable](a, b T) int {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.IsValid() && vb.IsValid() && va.Kind() == vb.Kind() {
+		switch va.Kind() {
+		case reflect.String:
+			return cmp.Compare(va.String(), vb.String())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return cmp.Compare(va.Int(), vb.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return cmp.Compare(va.Uint(), vb.Uint())
+		case reflect.Float32, reflect.Float64:
+			return cmp.Compare(va.Float(), vb.Float())
+		case reflect.Bool:
+			return cmp.Compare(boolInt(va.Bool()), boolInt(vb.Bool()))
+		}
+	}
+	return cmp.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,138 @@
+package set
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAddRemoveContains(t *testing.T) {
+	s := New[string](2)
+	s.Add("a", "b", "a")
+	if s.Len() != 2 || !s.Contains("a") || !s.Contains("b") || s.Contains("c") {
+		t.Fatalf("after Add: %v", s)
+	}
+	s.Remove("a", "zzz")
+	if s.Len() != 1 || s.Contains("a") {
+		t.Fatalf("after Remove: %v", s)
+	}
+
+	var zero Set[int]
+	if zero.Contains(1) || zero.Len() != 0 || len(zero.Slice()) != 0 {
+		t.Fatal("the zero set is not empty")
+	}
+	zero.Remove(1) // no panic
+}
+
+func TestAlgebra(t *testing.T) {
+	a, b := Of(1, 2, 3, 4), Of(3, 4, 5)
+	for name, tc := range map[string]struct{ got, want Set[int] }{
+		"union":              {a.Union(b), Of(1, 2, 3, 4, 5)},
+		"intersection":       {a.Intersection(b), Of(3, 4)},
+		"intersection swaps": {b.Intersection(a), Of(3, 4)},
+		"difference":         {a.Difference(b), Of(1, 2)},
+		"difference back":    {b.Difference(a), Of(5)},
+		"with empty":         {a.Intersection(nil), Of[int]()},
+		"union with nil":     {Set[int](nil).Union(b), b},
+	} {
+		if !tc.got.Equal(tc.want) {
+			t.Errorf("%s = %v, want %v", name, Sorted(tc.got), Sorted(tc.want))
+		}
+	}
+	// The operations leave their operands alone.
+	if !a.Equal(Of(1, 2, 3, 4)) || !b.Equal(Of(3, 4, 5)) {
+		t.Fatalf("operands changed: %v %v", a, b)
+	}
+	u := a.Union(nil)
+	u.Add(99)
+	if a.Contains(99) {
+		t.Fatal("Union shares its map with an operand")
+	}
+}
+
+func TestEqualSubset(t *testing.T) {
+	if !Of(1, 2).Equal(Of(2, 1)) || Of(1, 2).Equal(Of(1, 3)) || Of(1).Equal(Of(1, 2)) {
+		t.Fatal("Equal")
+	}
+	if !Of[int]().Equal(nil) {
+		t.Fatal("an empty set differs from nil")
+	}
+	if !Of(1).SubsetOf(Of(1, 2)) || Of(1, 3).SubsetOf(Of(1, 2)) || !Set[int](nil).SubsetOf(nil) {
+		t.Fatal("SubsetOf")
+	}
+}
+
+func TestClone(t *testing.T) {
+	s := Of("x")
+	c := s.Clone()
+	c.Add("y")
+	if s.Contains("y") || !c.Contains("x") {
+		t.Fatalf("Clone = %v, original %v", c, s)
+	}
+	if c := Set[string](nil).Clone(); c == nil || c.Len() != 0 {
+		t.Fatalf("Clone(nil) = %#v", c)
+	}
+}
+
+func TestOrder(t *testing.T) {
+	s := Of("pear", "apple", "fig")
+	if got := Sorted(s); !reflect.DeepEqual(got, []string{"apple", "fig", "pear"}) {
+		t.Fatalf("Sorted = %q", got)
+	}
+	byLen := func(a, b string) int { return len(a) - len(b) }
+	if got := s.SortedFunc(byLen); !reflect.DeepEqual(got, []string{"fig", "pear", "apple"}) {
+		t.Fatalf("SortedFunc = %q", got)
+	}
+	got := s.Slice()
+	if len(got) != 3 || !Of(got...).Equal(s) {
+		t.Fatalf("Slice = %q", got)
+	}
+	if got := Set[int](nil).Slice(); got == nil {
+		t.Fatal("Slice(nil) is nil")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	type point struct{ X, Y int }
+	for _, tc := range []struct {
+		in   any
+		want string
+	}{
+		{Of("b", "c", "a"), `["a","b","c"]`},
+		{Of(10, 9, -1), `[-1,9,10]`},
+		{Of(2.5, 1.5), `[1.5,2.5]`},
+		{Of(true, false), `[false,true]`},
+		{Of(point{2, 1}, point{1, 2}), `[{"X":1,"Y":2},{"X":2,"Y":1}]`},
+		{Of[string](), `[]`},
+		{struct {
+			Tags Set[string] `json:"tags"`
+		}{Of("go", "cli")}, `{"tags":["cli","go"]}`},
+	} {
+		data, err := json.Marshal(tc.in)
+		if err != nil || string(data) != tc.want {
+			t.Errorf("Marshal(%v) = %s, %v, want %s", tc.in, data, err, tc.want)
+		}
+	}
+
+	var s Set[string]
+	if err := json.Unmarshal([]byte(`["go","rust","go"]`), &s); err != nil || !s.Equal(Of("go", "rust")) {
+		t.Fatalf("Unmarshal = %v, %v", s, err)
+	}
+	// Decoding replaces what was there.
+	if err := json.Unmarshal([]byte(`["c"]`), &s); err != nil || !s.Equal(Of("c")) {
+		t.Fatalf("Unmarshal over a set = %v, %v", s, err)
+	}
+	if err := json.Unmarshal([]byte(`null`), &s); err != nil || s == nil || s.Len() != 0 {
+		t.Fatalf("Unmarshal(null) = %#v, %v", s, err)
+	}
+	if err := json.Unmarshal([]byte(`[1]`), &s); err == nil || !strings.Contains(err.Error(), "number") {
+		t.Fatalf("Unmarshal of the wrong type: %v", err)
+	}
+
+	var round Set[int]
+	data, _ := json.Marshal(Of(3, 1, 2))
+	if err := json.Unmarshal(data, &round); err != nil || !round.Equal(Of(1, 2, 3)) {
+		t.Fatalf("round trip = %v, %v", round, err)
+	}
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"example.com/tutorial/analysis"
+	"example.com/tutorial/archive"
+	"example.com/tutorial/columnar"
+	"example.com/tutorial/humanize"
+	"example.com/tutorial/jobs"
+	"example.com/tutorial/logging"
+	"example.com/tutorial/output"
+	"example.com/tutorial/progress"
+	"example.com/tutorial/redisstore"
+	"example.com/tutorial/repl"
+	"example.com/tutorial/report"
+	"example.com/tutorial/scheduler"
+	"example.com/tutorial/server"
+	"example.com/tutorial/text"
+	"example.com/tutorial/validate"
+	"example.com/tutorial/watch"
+)
+
+// envPrefix prefixes the environment variables of the settings, such as
+// TUTORIAL_SERVE_ADDR for serve.addr and TUTORIAL_SERVE_TLS_CERT for
+// serve.tls.cert.
+const envPrefix = "TUTORIAL"
+
+// envConfig names the configuration file when -config is not given.
+const envConfig = envPrefix + "_CONFIG"
+
+// settings are the defaults of the global flags and of the flags of
+// every command. They are loaded with package config from
+// defaultSettings, the configuration file and the environment, and a
+// flag given on the command line overrides its setting. Glob patterns
+// given as flags add to the configured ones.
+type settings struct {
+	Timeout   time.Duration     `config:"timeout"`
+	Logging   loggingSettings   `config:"logging"`
+	Color     output.Mode       `config:"color"`
+	Pprof     string            `config:"pprof"`
+	Trace     string            `config:"trace"`
+	DryRun    bool              `config:"dry-run"`
+	Lang      string            `config:"lang"`
+	Assets    string            `config:"assets"`
+	WordCount wordCountSettings `config:"wordcount"`
+	Search    searchSettings    `config:"search"`
+	Tree      treeSettings      `config:"tree"`
+	Du        duSettings        `config:"du"`
+	Dedupe    dedupeSettings    `config:"dedupe"`
+	Archive   archiveSettings   `config:"archive"`
+	Watch     watchSettings     `config:"watch"`
+	Serve     serveSettings     `config:"serve"`
+	Users     usersSettings     `config:"users"`
+	Repl      replSettings      `config:"repl"`
+	Migrate   migrateSettings   `config:"migrate"`
+	Report    reportSettings    `config:"report"`
+	Export    exportSettings    `config:"export"`
+	Compare   compareSettings   `config:"compare"`
+}
+
+func defaultSettings() settings {
+	serve := server.DefaultServeConfig()
+	return settings{
+		Logging:   loggingSettings{Level: slog.LevelInfo},
+		WordCount: wordCountSettings{Format: "text", MaxExtract: 1 << 30},
+		Dedupe:    dedupeSettings{MinSize: 1},
+		Archive: archiveSettings{
+			MaxFileSize: archive.DefaultMaxFileBytes,
+			MaxSize:     archive.DefaultMaxTotalBytes,
+			MaxFiles:    archive.DefaultMaxFiles,
+		},
+		Watch: watchSettings{Interval: watch.DefaultInterval, Debounce: watch.DefaultDebounce, Top: 10, Format: "text"},
+		Serve: serveSettings{
+			Addr:     serve.Addr,
+			Grace:    serve.ShutdownGrace,
+			Cache:    cacheSettings{Backend: cacheMemory, TTL: time.Hour},
+			Redis:    redisSettings{Addr: redisstore.DefaultAddr},
+			Snapshot: snapshotSettings{Interval: 5 * time.Minute},
+			Jobs: jobsSettings{
+				Workers:     jobs.DefaultWorkers,
+				Retention:   jobs.DefaultRetention,
+				MaxFinished: jobs.DefaultMaxFinished,
+			},
+			RateLimit: rateLimitSettings{Burst: 10},
+			JWT:       jwtSettings{TTL: time.Hour},
+		},
+		Users:   usersSettings{File: defaultUsersFile},
+		Repl:    replSettings{Prompt: repl.DefaultPrompt},
+		Migrate: migrateSettings{DB: defaultMigrateDB},
+		Report:  reportSettings{Title: defaultReportTitle, Top: 10},
+		Export:  exportSettings{Format: string(columnar.CSV), Top: -1, Dir: "export", MaxExtract: 1 << 30},
+		Compare: compareSettings{Format: "text", Top: -1},
+	}
+}
+
+func (s settings) Validate() error {
+	var v validate.Validator
+	v.Check(s.Timeout >= 0, "timeout", "must not be negative")
+	return v.Err()
+}
+
+// loggingSettings are the defaults of -log-level and -log-format.
+type loggingSettings struct {
+	Level  slog.Level     `config:"level"`
+	Format logging.Format `config:"format"`
+}
+
+type wordCountSettings struct {
+	Format     string        `config:"format"`
+	MaxExtract humanize.Size `config:"max-extract"`
+	// MaxMemory bounds the word counts -top holds in memory; beyond it
+	// they spill to temporary files. Zero means no bound.
+	MaxMemory humanize.Size `config:"max-memory"`
+	Progress  progress.Mode `config:"progress"`
+	// Analyzer names the analysis analyzer that splits the input into
+	// words; empty means text.Tokenizer.
+	Analyzer string `config:"analyzer"`
+}
+
+func (s wordCountSettings) Validate() error {
+	var v validate.Validator
+	_, err := text.ParseFormat(s.Format)
+	v.Check(err == nil, "format", "must be text, json or csv")
+	v.Check(s.MaxExtract > 0, "max-extract", "must be positive")
+	v.Check(s.MaxMemory >= 0, "max-memory", "must not be negative")
+	if s.Analyzer != "" {
+		_, err := analysis.Lookup(s.Analyzer)
+		v.Check(err == nil, "analyzer", "must be one of "+strings.Join(analysis.Names(), ", "))
+	}
+	return v.Err()
+}
+
+type searchSettings struct {
+	Include    []string      `config:"include"`
+	Exclude    []string      `config:"exclude"`
+	Context    int           `config:"context"`
+	IgnoreCase bool          `config:"ignore-case"`
+	Progress   progress.Mode `config:"progress"`
+}
+
+func (s searchSettings) Validate() error {
+	var v validate.Validator
+	v.Check(s.Context >= 0, "context", "must not be negative")
+	return v.Err()
+}
+
+type treeSettings struct {
+	Depth   int      `config:"depth"`
+	Include []string `config:"include"`
+	Exclude []string `config:"exclude"`
+	Sizes   bool     `config:"sizes"`
+}
+
+func (s treeSettings) Validate() error {
+	var v validate.Validator
+	v.Check(s.Depth >= 0, "depth", "must not be negative")
+	return v.Err()
+}
+
+type duSettings struct {
+	Human          bool `config:"human"`
+	FollowSymlinks bool `config:"follow-symlinks"`
+}
+
+type dedupeSettings struct {
+	MinSize  humanize.Size `config:"min-size"`
+	Human    bool          `config:"human"`
+	Progress progress.Mode `config:"progress"`
+}
+
+type archiveSettings struct {
+	Include     []string      `config:"include"`
+	Exclude     []string      `config:"exclude"`
+	MaxFileSize humanize.Size `config:"max-file-size"`
+	MaxSize     humanize.Size `config:"max-size"`
+	MaxFiles    int           `config:"max-files"`
+}
+
+func (s archiveSettings) Validate() error {
+	var v validate.Validator
+	v.Check(s.MaxFileSize > 0, "max-file-size", "must be positive")
+	v.Check(s.MaxSize > 0, "max-size", "must be positive")
+	v.Check(s.MaxFiles > 0, "max-files", "must be positive")
+	return v.Err()
+}
+
+type watchSettings struct {
+	Interval time.Duration `config:"interval"`
+	Debounce time.Duration `config:"debounce"`
+	Top      int           `config:"top"`
+	Format   string        `config:"format"`
+}
+
+func (s watchSettings) Validate() error {
+	var v validate.Validator
+	v.Check(s.Interval > 0, "interval", "must be positive")
+	v.Check(s.Debounce >= 0, "debounce", "must not be negative")
+	_, err := text.ParseFormat(s.Format)
+	v.Check(err == nil, "format", "must be text, json or csv")
+	return v.Err()
+}
+
+type serveSettings struct {
+	Addr      string            `config:"addr"`
+	Grace     time.Duration     `config:"grace"`
+	Users     string            `config:"users"`
+	DB        string            `config:"db"`
+	TLS       tlsSettings       `config:"tls"`
+	Corpus    corpusSettings    `config:"corpus"`
+	Cache     cacheSettings     `config:"cache"`
+	Redis     redisSettings     `config:"redis"`
+	Snapshot  snapshotSettings  `config:"snapshot"`
+	Jobs      jobsSettings      `config:"jobs"`
+	RateLimit rateLimitSettings `config:"rate-limit"`
+	Admin     adminSettings     `config:"admin"`
+	JWT       jwtSettings       `config:"jwt"`
+}
+
+// serveConfig returns the server settings of s, the others at their
+// defaults.
+func (s serveSettings) serveConfig() server.ServeConfig {
+	c := server.DefaultServeConfig()
+	c.Addr, c.ShutdownGrace = s.Addr, s.Grace
+	c.TLS, c.CertFile, c.KeyFile = s.TLS.Enabled, s.TLS.Cert, s.TLS.Key
+	return c
+}
+
+func (s serveSettings) Validate() error { return s.serveConfig().Validate() }
+
+type tlsSettings struct {
+	Enabled bool   `config:"enabled"`
+	Cert    string `config:"cert"`
+	Key     string `config:"key"`
+}
+
+// corpusSettings configure the searchable corpus of serve. An empty
+// Reindex indexes Dir once, at startup.
+type corpusSettings struct {
+	Dir     string `config:"dir"`
+	Reindex string `config:"reindex"`
+}
+
+func (s corpusSettings) Validate() error {
+	var v validate.Validator
+	if s.Reindex != "" {
+		_, err := scheduler.ParseCron(s.Reindex)
+		v.Check(err == nil, "reindex", "must be a cron spec such as @every 5m")
+	}
+	return v.Err()
+}
+
+// snapshotSettings configure the snapshots of serve's in-memory state.
+// An empty Dir takes none.
+type snapshotSettings struct {
+	Dir      string        `config:"dir"`
+	Interval time.Duration `config:"interval"`
+}
+
+func (s snapshotSettings) Validate() error {
+	var v validate.Validator
+	v.Check(s.Interval > 0, "interval", "must be positive")
+	return v.Err()
+}
+
+// jobsSettings configure the job queue of serve's POST /jobs. An empty
+// Dir keeps the jobs in memory, losing the queued ones on restart. The
+// finished jobs are kept for Retention, MaxFinished of them at most.
+type jobsSettings struct {
+	Dir         string        `config:"dir"`
+	Workers     int           `config:"workers"`
+	Retention   time.Duration `config:"retention"`
+	MaxFinished int           `config:"max-finished"`
+}
+
+func (s jobsSettings) Validate() error {
+	var v validate.Validator
+	v.Check(s.Workers > 0, "workers", "must be positive")
+	v.Check(s.Retention > 0, "retention", "must be positive")
+	v.Check(s.MaxFinished > 0, "max-finished", "must be positive")
+	return v.Err()
+}
+
+// rateLimitSettings bound how many requests per second each client of
+// serve makes, in bursts of up to Burst. A zero Rate leaves them
+// unbounded.
+type rateLimitSettings struct {
+	Rate  float64 `config:"rate"`
+	Burst int     `config:"burst"`
+}
+
+func (s rateLimitSettings) Validate() error {
+	var v validate.Validator
+	v.Check(s.Rate >= 0, "rate", "must not be negative")
+	v.Check(s.Rate == 0 || s.Burst >= 1, "burst", "must be positive")
+	return v.Err()
+}
+
+// adminSettings configure the /admin endpoints of serve, and how the
+// admin command reaches them. An empty Token leaves them out. It has no
+// flag, so that it does not show in the process list: set it in the
+// config file or TUTORIAL_SERVE_ADMIN_TOKEN.
+type adminSettings struct {
+	Token string `config:"token,secret"`
+}
+
+// jwtSettings configure the tokens POST /login issues, which PUT and
+// DELETE /users/{id} require. An empty Secret makes serve sign them
+// with a random key, so that they do not survive a restart; like
+// adminSettings.Token, it has no flag.
+type jwtSettings struct {
+	Secret string        `config:"secret,secret"`
+	TTL    time.Duration `config:"ttl"`
+}
+
+func (s jwtSettings) Validate() error {
+	var v validate.Validator
+	v.Check(s.TTL > 0, "ttl", "must be positive")
+	return v.Err()
+}
+
+// Cache backends of serve: the server's own memory, or Redis as
+// redisSettings configure it.
+const (
+	cacheMemory = "memory"
+	cacheRedis  = "redis"
+)
+
+// cacheSettings choose where serve caches word-count results. TTL
+// bounds how long they live in Redis, where zero leaves them until
+// evicted.
+type cacheSettings struct {
+	Backend string        `config:"backend"`
+	TTL     time.Duration `config:"ttl"`
+}
+
+func (s cacheSettings) Validate() error {
+	var v validate.Validator
+	v.Check(s.Backend == cacheMemory || s.Backend == cacheRedis, "backend", "must be memory or redis")
+	v.Check(s.TTL >= 0, "ttl", "must not be negative")
+	return v.Err()
+}
+
+// redisSettings reach the Redis server of serve, when a backend uses
+// it. Zero fields take the redisstore defaults.
+type redisSettings struct {
+	Addr         string        `config:"addr"`
+	Username     string        `config:"username"`
+	Password     string        `config:"password,secret"`
+	DB           int           `config:"db"`
+	PoolSize     int           `config:"pool-size"`
+	DialTimeout  time.Duration `config:"dial-timeout"`
+	ReadTimeout  time.Duration `config:"read-timeout"`
+	WriteTimeout time.Duration `config:"write-timeout"`
+}
+
+func (s redisSettings) redisConfig() redisstore.Config {
+	return redisstore.Config{
+		Addr:         s.Addr,
+		Username:     s.Username,
+		Password:     s.Password,
+		DB:           s.DB,
+		PoolSize:     s.PoolSize,
+		DialTimeout:  s.DialTimeout,
+		ReadTimeout:  s.ReadTimeout,
+		WriteTimeout: s.WriteTimeout,
+	}
+}
+
+func (s redisSettings) Validate() error { return s.redisConfig().Validate() }
+
+type usersSettings struct {
+	File string `config:"file"`
+}
+
+func (s usersSettings) Validate() error {
+	var v validate.Validator
+	v.Required("file", s.File)
+	return v.Err()
+}
+
+// replSettings configures the repl subcommand. An empty Users keeps the
+// session's users in memory.
+type replSettings struct {
+	Users  string `config:"users"`
+	Prompt string `config:"prompt"`
+}
+
+type migrateSettings struct {
+	DB string `config:"db"`
+}
+
+// reportSettings are the defaults of report's flags. An empty Format is
+// told by the file names; an empty Template is the built-in one.
+type reportSettings struct {
+	Title    string `config:"title"`
+	Top      int    `config:"top"`
+	Format   string `config:"format"`
+	Template string `config:"template"`
+}
+
+func (s reportSettings) Validate() error {
+	var v validate.Validator
+	if s.Format != "" {
+		_, err := report.ParseFormat(s.Format)
+		v.Check(err == nil, "format", "must be markdown or html")
+	}
+	v.Check(s.Top >= -1, "top", "must be -1, for all words, or more")
+	return v.Err()
+}
+
+// exportSettings are the defaults of export's flags.
+type exportSettings struct {
+	Format     string        `config:"format"`
+	Top        int           `config:"top"`
+	Dir        string        `config:"dir"`
+	MaxExtract humanize.Size `config:"max-extract"`
+}
+
+func (s exportSettings) Validate() error {
+	var v validate.Validator
+	_, err := columnar.ParseFormat(s.Format)
+	v.Check(err == nil, "format", "must be csv or parquet")
+	v.Check(s.Top >= -1, "top", "must be -1, for all words, or more")
+	v.Check(s.Dir != "", "dir", "must not be empty")
+	v.Check(s.MaxExtract > 0, "max-extract", "must be positive")
+	return v.Err()
+}
+
+// compareSettings are the defaults of compare's flags.
+type compareSettings struct {
+	Format   string `config:"format"`
+	Top      int    `config:"top"`
+	Analyzer string `config:"analyzer"`
+}
+
+func (s compareSettings) Validate() error {
+	var v validate.Validator
+	_, err := text.ParseFormat(s.Format)
+	v.Check(err == nil, "format", "must be text, json or csv")
+	v.Check(s.Top >= -1, "top", "must be -1, for all changes, or more")
+	if s.Analyzer != "" {
+		_, err := analysis.Lookup(s.Analyzer)
+		v.Check(err == nil, "analyzer", "must be one of "+strings.Join(analysis.Names(), ", "))
+	}
+	return v.Err()
+}
+
+// progressUsage describes the -progress flag of the commands that report
+// their progress on stderr.
+const progressUsage = "report progress on stderr: `mode` auto (on a terminal), on, off or json lines"
@@ -0,0 +1,184 @@
+// Package shutdown coordinates the cleanup a program does on its way
+// out. Components register hooks, such as draining a server or flushing
+// a file, each with a timeout of its own; the hooks then run once, in
+// reverse order of registration, when the program is told to stop by
+// SIGINT or SIGTERM or when it calls Shutdown itself.
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"example.com/tutorial/ctxutil"
+	"example.com/tutorial/logging"
+)
+
+// DefaultTimeout bounds a hook added with a zero timeout.
+const DefaultTimeout = 10 * time.Second
+
+// ForcedExitCode is the status the process exits with when a second
+// signal arrives while the hooks are still running.
+const ForcedExitCode = 1
+
+// Coordinator runs a program's shutdown hooks. The zero value has no
+// hooks and is ready to use.
+type Coordinator struct {
+	mu      sync.Mutex
+	hooks   []hook
+	started bool
+	done    chan struct{} // closed when the hooks have run
+	err     error
+
+	exit func(code int) // os.Exit, replaced by tests
+}
+
+type hook struct {
+	name    string
+	timeout time.Duration
+	fn      func(context.Context) error
+}
+
+// New returns a Coordinator with no hooks.
+func New() *Coordinator {
+	return &Coordinator{}
+}
+
+// Add registers fn to run on shutdown under name, which is used in
+// errors. fn gets a context that ends after timeout, or DefaultTimeout
+// if timeout is zero, and should return by then; Shutdown moves on to
+// the next hook if it does not. Hooks run one at a time, the last added
+// first, as deferred calls do, so a component added after those it
+// depends on is stopped before them.
+//
+// Hooks added once shutdown has begun are not run.
+func (c *Coordinator) Add(name string, timeout time.Duration, fn func(ctx context.Context) error) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hooks = append(c.hooks, hook{name: name, timeout: timeout, fn: fn})
+}
+
+// Shutdown runs the hooks and returns their errors joined, each prefixed
+// with its hook's name. Only the first call runs them; later and
+// concurrent calls wait for it to finish and return the same error, or
+// ctx's error if ctx ends first. Canceling ctx also cuts short the hook
+// running at the time and skips the rest.
+func (c *Coordinator) Shutdown(ctx context.Context) error {
+	c.mu.Lock()
+	if c.done == nil {
+		c.done = make(chan struct{})
+	}
+	done, first := c.done, !c.started
+	c.started = true
+	hooks := c.hooks
+	c.mu.Unlock()
+
+	if !first {
+		select {
+		case <-done:
+			return c.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i].run(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	c.err = errors.Join(errs...)
+	close(done)
+	return c.err
+}
+
+// run calls h.fn and waits for it to return or for its timeout.
+func (h hook) run(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	returned := make(chan error, 1)
+	go func() { returned <- h.fn(ctx) }()
+	var err error
+	select {
+	case err = <-returned:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		return fmt.Errorf("shutdown: %s: %w", h.name, err)
+	}
+	return nil
+}
+
+// Notify returns a context that is canceled, with a ctxutil.SignalError
+// as its cause, when the process receives one of signals, or os.Interrupt
+// if none are given. The signal also starts Shutdown in the background.
+// If another signal arrives before the hooks have finished the process
+// exits at once with ForcedExitCode, so a stuck hook cannot keep it
+// alive.
+//
+// Calling stop stops catching the signals, after which they have their
+// default effect again; it does not run the hooks.
+func (c *Coordinator) Notify(ctx context.Context, signals ...os.Signal) (_ context.Context, stop context.CancelFunc) {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt}
+	}
+	exit := c.exit
+	if exit == nil {
+		exit = os.Exit
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	stopped := make(chan struct{})
+	ch := make(chan os.Signal, 2)
+	signal.Notify(ch, signals...)
+	go func() {
+		defer signal.Stop(ch)
+		var sig os.Signal
+		select {
+		case sig = <-ch:
+		case <-stopped:
+			return
+		}
+		cancel(ctxutil.SignalError{Signal: sig})
+		finished := make(chan struct{})
+		go func() {
+			c.Shutdown(context.Background())
+			close(finished)
+		}()
+		select {
+		case sig = <-ch:
+			logging.Component(nil, "shutdown").Warn("forcing exit", "signal", sig.String())
+			exit(ForcedExitCode)
+		case <-finished:
+		case <-stopped:
+		}
+	}()
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			close(stopped)
+			cancel(context.Canceled)
+		})
+	}
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx that carries c, so that code far down
+// the call tree can add hooks to the program's coordinator.
+func NewContext(ctx context.Context, c *Coordinator) context.Context {
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// FromContext returns the Coordinator ctx carries, or nil.
+func FromContext(ctx context.Context) *Coordinator {
+	c, _ := ctx.Value(contextKey{}).(*Coordinator)
+	return c
+}
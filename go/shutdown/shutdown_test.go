@@ -0,0 +1,168 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"example.com/tutorial/ctxutil"
+)
+
+func TestShutdownRunsHooksInReverseOnce(t *testing.T) {
+	c := New()
+	var order []string
+	for _, name := range []string{"store", "scheduler", "http"} {
+		name := name
+		c.Add(name, 0, func(context.Context) error {
+			order = append(order, name)
+			return nil
+		})
+	}
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(order, ","); got != "http,scheduler,store" {
+		t.Fatalf("hooks ran as %s", got)
+	}
+}
+
+func TestShutdownErrors(t *testing.T) {
+	var c Coordinator
+	boom := errors.New("boom")
+	ran := false
+	c.Add("store", time.Second, func(context.Context) error {
+		ran = true
+		return nil
+	})
+	c.Add("stuck", 10*time.Millisecond, func(ctx context.Context) error {
+		select {} // ignores ctx
+	})
+	c.Add("cache", time.Second, func(context.Context) error { return boom })
+
+	err := c.Shutdown(context.Background())
+	if !errors.Is(err, boom) || !errors.Is(err, context.DeadlineExceeded) || !ran {
+		t.Fatalf("Shutdown = %v, store ran %t", err, ran)
+	}
+	for _, want := range []string{"shutdown: cache: boom", "shutdown: stuck: context deadline exceeded"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("%q does not mention %q", err, want)
+		}
+	}
+	if again := c.Shutdown(context.Background()); again != err {
+		t.Fatalf("second Shutdown = %v, want %v", again, err)
+	}
+}
+
+func TestShutdownWaitsForFirstCall(t *testing.T) {
+	c := New()
+	release := make(chan struct{})
+	c.Add("slow", time.Second, func(context.Context) error {
+		<-release
+		return errors.New("slow failed")
+	})
+	first := make(chan error, 1)
+	go func() { first <- c.Shutdown(context.Background()) }()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := c.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown with short context = %v", err)
+	}
+	close(release)
+	err := <-first
+	if err == nil || c.Shutdown(context.Background()) != err {
+		t.Fatalf("Shutdown = %v, then %v", err, c.Shutdown(context.Background()))
+	}
+}
+
+func TestNotify(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no SIGUSR1 on windows")
+	}
+	c := New()
+	ran := make(chan struct{})
+	c.Add("hook", time.Second, func(context.Context) error {
+		close(ran)
+		return nil
+	})
+	ctx, stop := c.Notify(context.Background(), syscall.SIGUSR1)
+	defer stop()
+	syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("signal did not run the hooks")
+	}
+	<-ctx.Done()
+	var sigErr ctxutil.SignalError
+	if !errors.As(context.Cause(ctx), &sigErr) || sigErr.Signal != syscall.SIGUSR1 {
+		t.Fatalf("cause = %v", context.Cause(ctx))
+	}
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNotifySecondSignalForcesExit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no SIGUSR1 on windows")
+	}
+	c := New()
+	exited := make(chan int, 1)
+	c.exit = func(code int) { exited <- code }
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	c.Add("stuck", time.Minute, func(context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	_, stop := c.Notify(context.Background(), syscall.SIGUSR1)
+	defer stop()
+
+	syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	<-started
+	syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	select {
+	case code := <-exited:
+		if code != ForcedExitCode {
+			t.Fatalf("exit code %d", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("second signal did not force an exit")
+	}
+}
+
+func TestNotifyStop(t *testing.T) {
+	c := New()
+	c.Add("hook", time.Second, func(context.Context) error {
+		t.Error("stop ran the hooks")
+		return nil
+	})
+	ctx, stop := c.Notify(context.Background())
+	stop()
+	stop()
+	if !errors.Is(context.Cause(ctx), context.Canceled) {
+		t.Fatalf("cause = %v", context.Cause(ctx))
+	}
+}
+
+func TestContext(t *testing.T) {
+	if FromContext(context.Background()) != nil {
+		t.Fatal("empty context carries a coordinator")
+	}
+	c := New()
+	if FromContext(NewContext(context.Background(), c)) != c {
+		t.Fatal("coordinator lost")
+	}
+}
@@ -0,0 +1,80 @@
+// Package sliceutil has the generic slice helpers that the standard
+// slices package lacks: Map, Filter and Reduce, Chunk, Unique and
+// GroupBy. None of them changes the slice it is given, and the slices
+// they return never share elements with it, except those of Chunk.
+package sliceutil
+
+// Map returns f applied to every element of s, in order. The result is
+// never nil, so that it encodes as an empty JSON array rather than null.
+func Map[S ~[]E, E, R any](s S, f func(E) R) []R {
+	out := make([]R, len(s))
+	for i, v := range s {
+		out[i] = f(v)
+	}
+	return out
+}
+
+// Filter returns the elements of s for which keep is true, in order.
+// The result is never nil.
+func Filter[S ~[]E, E any](s S, keep func(E) bool) S {
+	out := S{}
+	for _, v := range s {
+		if keep(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Reduce folds s into a value: it calls f with init and the first
+// element, then with each result and the next element, and returns the
+// last result, or init if s is empty.
+func Reduce[S ~[]E, E, A any](s S, init A, f func(A, E) A) A {
+	acc := init
+	for _, v := range s {
+		acc = f(acc, v)
+	}
+	return acc
+}
+
+// Chunk splits s into consecutive slices of n elements, the last one
+// shorter if len(s) is not a multiple of n, and returns nil for an
+// empty s. The chunks share s's array, but each is capped at its own
+// length, so that appending to one cannot overwrite the next. Chunk
+// panics if n is less than 1.
+func Chunk[S ~[]E, E any](s S, n int) []S {
+	if n < 1 {
+		panic("sliceutil: chunk size must be positive")
+	}
+	var out []S
+	for i := 0; i < len(s); i += n {
+		end := min(i+n, len(s))
+		out = append(out, s[i:end:end])
+	}
+	return out
+}
+
+// Unique returns the elements of s without repeats, each where it first
+// occurs. The result is never nil.
+func Unique[S ~[]E, E comparable](s S) S {
+	out := S{}
+	seen := make(map[E]struct{}, len(s))
+	for _, v := range s {
+		if _, ok := seen[v]; !ok {
+			seen[v] = struct{}{}
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// GroupBy returns the elements of s by their key, each group in the
+// order of s. The map is never nil.
+func GroupBy[S ~[]E, E any, K comparable](s S, key func(E) K) map[K]S {
+	out := map[K]S{}
+	for _, v := range s {
+		k := key(v)
+		out[k] = append(out[k], v)
+	}
+	return out
+}
@@ -0,0 +1,115 @@
+package sliceutil
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type names []string
+
+func TestMap(t *testing.T) {
+	if got := Map([]int{1, 2, 3}, strconv.Itoa); !reflect.DeepEqual(got, []string{"1", "2", "3"}) {
+		t.Fatalf("Map = %q", got)
+	}
+	for _, s := range [][]int{nil, {}} {
+		if got := Map(s, strconv.Itoa); got == nil || len(got) != 0 {
+			t.Fatalf("Map(%#v) = %#v, want an empty slice", s, got)
+		}
+	}
+	if got := Map(names{"a", "b"}, strings.ToUpper); !reflect.DeepEqual(got, []string{"A", "B"}) {
+		t.Fatalf("Map over a named slice = %q", got)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	even := func(n int) bool { return n%2 == 0 }
+	in := []int{1, 2, 3, 4, 6}
+	got := Filter(in, even)
+	if !reflect.DeepEqual(got, []int{2, 4, 6}) {
+		t.Fatalf("Filter = %v", got)
+	}
+	got[0] = 99
+	if in[1] != 2 {
+		t.Fatal("Filter shares elements with its input")
+	}
+	for _, s := range [][]int{nil, {}, {1, 3}} {
+		if got := Filter(s, even); got == nil || len(got) != 0 {
+			t.Fatalf("Filter(%#v) = %#v, want an empty slice", s, got)
+		}
+	}
+	long := func(s string) bool { return len(s) > 1 }
+	if got := Filter(names{"a", "bb", "ccc"}, long); !reflect.DeepEqual(got, names{"bb", "ccc"}) {
+		t.Fatalf("Filter over a named slice = %#v", got)
+	}
+}
+
+func TestReduce(t *testing.T) {
+	sum := func(acc, n int) int { return acc + n }
+	if got := Reduce([]int{1, 2, 3, 4}, 0, sum); got != 10 {
+		t.Fatalf("sum = %d", got)
+	}
+	if got := Reduce([]int(nil), 7, sum); got != 7 {
+		t.Fatalf("Reduce(nil) = %d, want the initial value", got)
+	}
+	join := func(acc string, n int) string { return acc + strconv.Itoa(n) }
+	if got := Reduce([]int{1, 2, 3}, ">", join); got != ">123" {
+		t.Fatalf("Reduce in order = %q", got)
+	}
+}
+
+func TestChunk(t *testing.T) {
+	in := []int{1, 2, 3, 4, 5}
+	for n, want := range map[int][][]int{
+		1: {{1}, {2}, {3}, {4}, {5}},
+		2: {{1, 2}, {3, 4}, {5}},
+		5: {{1, 2, 3, 4, 5}},
+		9: {{1, 2, 3, 4, 5}},
+	} {
+		if got := Chunk(in, n); !reflect.DeepEqual(got, want) {
+			t.Errorf("Chunk(%d) = %v, want %v", n, got, want)
+		}
+	}
+	for _, s := range [][]int{nil, {}} {
+		if got := Chunk(s, 3); got != nil {
+			t.Fatalf("Chunk(%#v) = %v, want nil", s, got)
+		}
+	}
+
+	// Appending to a chunk leaves the next one alone.
+	chunks := Chunk(in, 2)
+	_ = append(chunks[0], 99)
+	if chunks[1][0] != 3 || in[2] != 3 {
+		t.Fatal("append to a chunk overwrote the next")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Chunk(0) did not panic")
+		}
+	}()
+	Chunk(in, 0)
+}
+
+func TestUnique(t *testing.T) {
+	if got := Unique([]string{"b", "a", "b", "c", "a"}); !reflect.DeepEqual(got, []string{"b", "a", "c"}) {
+		t.Fatalf("Unique = %q", got)
+	}
+	for _, s := range [][]int{nil, {}} {
+		if got := Unique(s); got == nil || len(got) != 0 {
+			t.Fatalf("Unique(%#v) = %#v, want an empty slice", s, got)
+		}
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	got := GroupBy([]string{"ada", "bob", "al", "bea", "cy"}, func(s string) byte { return s[0] })
+	want := map[byte][]string{'a': {"ada", "al"}, 'b': {"bob", "bea"}, 'c': {"cy"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("GroupBy = %q", got)
+	}
+	if got := GroupBy([]string(nil), func(s string) int { return len(s) }); got == nil || len(got) != 0 {
+		t.Fatalf("GroupBy(nil) = %#v, want an empty map", got)
+	}
+}
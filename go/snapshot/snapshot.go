@@ -0,0 +1,71 @@
+// Package snapshot saves the state of in-memory stores to files and
+// loads it back, so that it survives a restart. The stores encode
+// themselves, as user.MemoryStore and index.Index do with encoding/gob;
+// Save replaces the file atomically, so a crash mid-snapshot leaves the
+// previous one, and Job saves on a scheduler.Scheduler's schedule.
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"example.com/tutorial/fileutil"
+	"example.com/tutorial/scheduler"
+)
+
+// Snapshotter writes its state to w.
+type Snapshotter interface {
+	Snapshot(w io.Writer) error
+}
+
+// Restorer replaces its state with one read from r.
+type Restorer interface {
+	Restore(r io.Reader) error
+}
+
+// Func adapts a function to a Snapshotter, as for state that needs a
+// lock held while it is written.
+type Func func(w io.Writer) error
+
+// Snapshot calls f(w).
+func (f Func) Snapshot(w io.Writer) error { return f(w) }
+
+// RestoreFunc adapts a function to a Restorer.
+type RestoreFunc func(r io.Reader) error
+
+// Restore calls f(r).
+func (f RestoreFunc) Restore(r io.Reader) error { return f(r) }
+
+// Save writes the snapshot of s to the file at path, replacing it
+// atomically. If the snapshot fails the file is left as it was.
+func Save(path string, s Snapshotter) error {
+	pr, pw := io.Pipe()
+	go func() { pw.CloseWithError(s.Snapshot(pw)) }()
+	err := fileutil.WriteAtomicReader(path, pr)
+	pr.CloseWithError(err) // stop the snapshot if writing failed
+	if err != nil {
+		return fmt.Errorf("snapshot: %s: %w", path, err)
+	}
+	return nil
+}
+
+// Load restores r from the file at path. A missing file yields an error
+// matching fs.ErrNotExist, for callers that start empty then.
+func Load(path string, r Restorer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	defer f.Close()
+	if err := r.Restore(f); err != nil {
+		return fmt.Errorf("snapshot: %s: %w", path, err)
+	}
+	return nil
+}
+
+// Job returns a scheduler job saving the snapshot of s to path.
+func Job(path string, s Snapshotter) scheduler.Job {
+	return func(context.Context) error { return Save(path, s) }
+}
@@ -0,0 +1,101 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/tutorial/scheduler"
+)
+
+// state is a Snapshotter and Restorer of a string.
+type state struct{ s string }
+
+func (st *state) Snapshot(w io.Writer) error {
+	_, err := io.WriteString(w, st.s)
+	return err
+}
+
+func (st *state) Restore(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	st.s = string(data)
+	return err
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.gob")
+	var got state
+	if err := Load(path, &got); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Load of a missing file: %v", err)
+	}
+	if err := Save(path, &state{"hello"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Load(path, &got); err != nil || got.s != "hello" {
+		t.Fatalf("Load = %q, %v", got.s, err)
+	}
+
+	// A failed snapshot leaves the previous one.
+	errBroken := errors.New("broken")
+	err := Save(path, Func(func(w io.Writer) error {
+		io.WriteString(w, "half")
+		return errBroken
+	}))
+	if !errors.Is(err, errBroken) {
+		t.Fatalf("Save: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "hello" {
+		t.Fatalf("file after a failed snapshot: %q", data)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Fatalf("temporary file left: %v", entries)
+	}
+
+	if err := Save(filepath.Join(path, "not-a-dir"), &state{"x"}); err == nil || !strings.Contains(err.Error(), "snapshot: ") {
+		t.Fatalf("Save into a file: %v", err)
+	}
+}
+
+func TestLoadRestoreError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state")
+	os.WriteFile(path, []byte("data"), 0o644)
+	errBad := errors.New("bad snapshot")
+	err := Load(path, restorerFunc(func(io.Reader) error { return errBad }))
+	if !errors.Is(err, errBad) || !strings.Contains(err.Error(), path) {
+		t.Fatalf("Load: %v", err)
+	}
+}
+
+type restorerFunc func(io.Reader) error
+
+func (f restorerFunc) Restore(r io.Reader) error { return f(r) }
+
+func TestJob(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state")
+	st := &state{"v1"}
+	s := scheduler.New(scheduler.Config{})
+	if err := s.Add("snapshot", scheduler.Every(time.Millisecond), Job(path, st)); err != nil {
+		t.Fatal(err)
+	}
+	s.Start(context.Background())
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if data, _ := os.ReadFile(path); bytes.Equal(data, []byte("v1")) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no snapshot saved")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
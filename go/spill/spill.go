@@ -0,0 +1,355 @@
+// Package spill counts words in bounded memory, for corpora whose
+// vocabulary does not fit in RAM. A Counter counts in a map until the
+// map's estimated size passes a threshold, then writes the counts to a
+// temporary file sorted by word (a run) and starts over. At the end the
+// runs are merged, summing the counts of each word, and the merged
+// counts streamed out in word order, so memory stays near the threshold
+// however large the input.
+package spill
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"example.com/tutorial/pqueue"
+	"example.com/tutorial/text"
+)
+
+// entryOverhead estimates the bytes a map entry takes beyond its word:
+// the string header, the count and the map's own bookkeeping.
+const entryOverhead = 48
+
+// maxWordLen bounds the words read back from a run, so that a damaged
+// one cannot claim a huge allocation.
+const maxWordLen = 1 << 20
+
+// DefaultMaxFanIn is the number of runs merged at once when Config
+// leaves it unset.
+const DefaultMaxFanIn = 64
+
+// Config tunes a Counter. The zero value counts in memory without limit.
+type Config struct {
+	// MaxMemory is the estimated size in bytes of the counts held in
+	// memory before they are spilled to a run; zero or less never
+	// spills.
+	MaxMemory int64
+	// Dir is the directory for the runs; empty means os.TempDir.
+	Dir string
+	// MaxFanIn bounds the runs open at once while merging, and so the
+	// file descriptors and buffers the merge needs. With more runs than
+	// that, groups of them are first merged into larger runs.
+	MaxFanIn int
+}
+
+// Counter counts words, spilling to runs as Config says. Its methods
+// must not be called concurrently. Close removes the runs.
+//
+// An error writing a run is kept and returned by Each, Top and Err;
+// words added after it are dropped.
+type Counter struct {
+	cfg    Config
+	counts map[string]int
+	size   int64    // estimated bytes of counts
+	runs   []string // paths of the runs, oldest first
+	err    error
+}
+
+// New returns an empty Counter.
+func New(cfg Config) *Counter {
+	if cfg.MaxFanIn < 2 {
+		cfg.MaxFanIn = DefaultMaxFanIn
+	}
+	return &Counter{cfg: cfg, counts: map[string]int{}}
+}
+
+// Add counts one occurrence of word. It has the signature of the
+// callback of text.ScanWords.
+func (c *Counter) Add(word string) { c.AddN(word, 1) }
+
+// AddN counts n occurrences of word.
+func (c *Counter) AddN(word string, n int) {
+	if c.err != nil {
+		return
+	}
+	if old, ok := c.counts[word]; ok {
+		c.counts[word] = old + n
+		return
+	}
+	c.counts[word] = n
+	c.size += int64(len(word)) + entryOverhead
+	if c.cfg.MaxMemory > 0 && c.size > c.cfg.MaxMemory {
+		c.err = c.spill()
+	}
+}
+
+// Spills returns the number of runs written so far.
+func (c *Counter) Spills() int { return len(c.runs) }
+
+// Err returns the first error writing a run, if any.
+func (c *Counter) Err() error { return c.err }
+
+// spill writes the counts in memory to a new run and empties them.
+func (c *Counter) spill() error {
+	if len(c.counts) == 0 {
+		return nil
+	}
+	path, err := c.writeRun(func(yield func(text.WordFreq) error) error {
+		return eachSorted(c.counts, yield)
+	})
+	if err != nil {
+		return err
+	}
+	c.runs = append(c.runs, path)
+	c.counts, c.size = map[string]int{}, 0
+	return nil
+}
+
+// eachSorted calls yield for the words of counts in order.
+func eachSorted(counts map[string]int, yield func(text.WordFreq) error) error {
+	for _, w := range newMapSource(counts).words {
+		if err := yield(text.WordFreq{Word: w, Count: counts[w]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeRun writes the words that each yields, which must come in word
+// order, to a new temporary file and returns its path.
+func (c *Counter) writeRun(each func(yield func(text.WordFreq) error) error) (string, error) {
+	f, err := os.CreateTemp(c.cfg.Dir, "spill-*.run")
+	if err != nil {
+		return "", fmt.Errorf("spill: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	var buf [binary.MaxVarintLen64]byte
+	err = each(func(wf text.WordFreq) error {
+		w.Write(buf[:binary.PutUvarint(buf[:], uint64(len(wf.Word)))])
+		w.WriteString(wf.Word)
+		_, err := w.Write(buf[:binary.PutUvarint(buf[:], uint64(wf.Count))])
+		return err
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("spill: write run: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// Each calls fn for every word counted, in word order, with its total
+// count, merging the runs with the counts still in memory. It stops at
+// the first error, from fn or reading the runs, and returns it. Each
+// may be called more than once, and more words added in between.
+func (c *Counter) Each(fn func(text.WordFreq) error) error {
+	if c.err != nil {
+		return c.err
+	}
+	if len(c.runs) == 0 {
+		return eachSorted(c.counts, fn)
+	}
+	// Leave room among the open files for the counts in memory.
+	for len(c.runs) >= c.cfg.MaxFanIn {
+		if err := c.compact(c.cfg.MaxFanIn); err != nil {
+			return err
+		}
+	}
+	srcs, closeAll, err := openRuns(c.runs)
+	defer closeAll()
+	if err != nil {
+		return err
+	}
+	srcs = append(srcs, newMapSource(c.counts))
+	return merge(srcs, fn)
+}
+
+// compact merges the oldest n runs into one.
+func (c *Counter) compact(n int) error {
+	srcs, closeAll, err := openRuns(c.runs[:n])
+	if err == nil {
+		var path string
+		path, err = c.writeRun(func(yield func(text.WordFreq) error) error { return merge(srcs, yield) })
+		if err == nil {
+			for _, p := range c.runs[:n] {
+				os.Remove(p)
+			}
+			c.runs = append([]string{path}, c.runs[n:]...)
+		}
+	}
+	closeAll()
+	return err
+}
+
+// Top returns the n most frequent words counted, as text.TopWords
+// ranks them, holding no more than n of them in memory beyond what Add
+// does. A negative n returns them all, which needs room for the whole
+// vocabulary.
+func (c *Counter) Top(n int) ([]text.WordFreq, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if len(c.runs) == 0 {
+		return text.TopWords(c.counts, n), nil
+	}
+	var err error
+	freqs := text.TopWordsFunc(func(yield func(text.WordFreq)) {
+		err = c.Each(func(wf text.WordFreq) error { yield(wf); return nil })
+	}, n)
+	if err != nil {
+		return nil, err
+	}
+	return freqs, nil
+}
+
+// Close removes the runs and empties the Counter.
+func (c *Counter) Close() error {
+	var errs []error
+	for _, p := range c.runs {
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("spill: %w", err))
+		}
+	}
+	c.runs, c.counts, c.size = nil, map[string]int{}, 0
+	return errors.Join(errs...)
+}
+
+// source yields words in order; next returns io.EOF after the last.
+type source interface {
+	next() (text.WordFreq, error)
+}
+
+// openRuns opens the runs at paths as sources. closeAll closes those that
+// were opened, even when err is not nil.
+func openRuns(paths []string) (srcs []source, closeAll func(), err error) {
+	var files []*os.File
+	closeAll = func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	for _, p := range paths {
+		f, err := os.Open(p)
+		if err != nil {
+			return nil, closeAll, fmt.Errorf("spill: %w", err)
+		}
+		files = append(files, f)
+		srcs = append(srcs, &runSource{r: bufio.NewReader(f), path: p})
+	}
+	return srcs, closeAll, nil
+}
+
+// runSource reads a run written by writeRun.
+type runSource struct {
+	r    *bufio.Reader
+	path string
+}
+
+func (s *runSource) next() (text.WordFreq, error) {
+	n, err := binary.ReadUvarint(s.r)
+	if err == io.EOF {
+		return text.WordFreq{}, io.EOF
+	}
+	if err == nil && n > maxWordLen {
+		err = errors.New("word too long")
+	}
+	var word []byte
+	if err == nil {
+		word = make([]byte, n)
+		_, err = io.ReadFull(s.r, word)
+	}
+	var count uint64
+	if err == nil {
+		count, err = binary.ReadUvarint(s.r)
+	}
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return text.WordFreq{}, fmt.Errorf("spill: read %s: %w", s.path, err)
+	}
+	return text.WordFreq{Word: string(word), Count: int(count)}, nil
+}
+
+// mapSource yields the counts of a map in word order.
+type mapSource struct {
+	counts map[string]int
+	words  []string
+}
+
+func newMapSource(counts map[string]int) *mapSource {
+	words := make([]string, 0, len(counts))
+	for w := range counts {
+		words = append(words, w)
+	}
+	sort.Strings(words)
+	return &mapSource{counts, words}
+}
+
+func (s *mapSource) next() (text.WordFreq, error) {
+	if len(s.words) == 0 {
+		return text.WordFreq{}, io.EOF
+	}
+	w := s.words[0]
+	s.words = s.words[1:]
+	return text.WordFreq{Word: w, Count: s.counts[w]}, nil
+}
+
+// head is the next word of a source in a merge.
+type head struct {
+	wf  text.WordFreq
+	src source
+}
+
+// merge calls fn for the words of srcs, each in word order, in word
+// order, summing the counts of a word that several have.
+func merge(srcs []source, fn func(text.WordFreq) error) error {
+	q := pqueue.New(func(a, b head) bool { return a.wf.Word < b.wf.Word })
+	advance := func(src source) error {
+		wf, err := src.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		q.Push(head{wf, src})
+		return nil
+	}
+	for _, src := range srcs {
+		if err := advance(src); err != nil {
+			return err
+		}
+	}
+	for q.Len() > 0 {
+		h, _ := q.Pop()
+		cur := h.wf
+		if err := advance(h.src); err != nil {
+			return err
+		}
+		for {
+			next, ok := q.Peek()
+			if !ok || next.wf.Word != cur.Word {
+				break
+			}
+			q.Pop()
+			cur.Count += next.wf.Count
+			if err := advance(next.src); err != nil {
+				return err
+			}
+		}
+		if err := fn(cur); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package spill
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"reflect"
+	"testing"
+
+	"example.com/tutorial/text"
+)
+
+// words returns n words drawn from a vocabulary of size vocab, with the
+// low ones more frequent, and their counts.
+func words(n, vocab int) ([]string, map[string]int) {
+	rnd := rand.New(rand.NewSource(1))
+	ws := make([]string, n)
+	counts := map[string]int{}
+	for i := range ws {
+		ws[i] = fmt.Sprintf("w%d", rnd.Intn(rnd.Intn(vocab)+1))
+		counts[ws[i]]++
+	}
+	return ws, counts
+}
+
+func TestCounterSpills(t *testing.T) {
+	ws, want := words(20000, 3000)
+	dir := t.TempDir()
+	c := New(Config{MaxMemory: 4096, Dir: dir, MaxFanIn: 4})
+	for _, w := range ws {
+		c.Add(w)
+	}
+	if c.Spills() < 4 {
+		t.Fatalf("%d spills, want several", c.Spills())
+	}
+	got := map[string]int{}
+	prev := ""
+	err := c.Each(func(wf text.WordFreq) error {
+		if wf.Word <= prev {
+			return fmt.Errorf("%q after %q", wf.Word, prev)
+		}
+		prev = wf.Word
+		got[wf.Word] = wf.Count
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("Each: %v; %d words, want %d", err, len(got), len(want))
+	}
+	if c.Spills() >= 4 {
+		t.Errorf("%d runs left after merging with a fan-in of 4", c.Spills())
+	}
+	for _, n := range []int{0, 10, -1} {
+		top, err := c.Top(n)
+		if err != nil || !reflect.DeepEqual(top, text.TopWords(want, n)) {
+			t.Errorf("Top(%d) = %v, %v", n, top[:min(len(top), 5)], err)
+		}
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Close left %d files", len(entries))
+	}
+}
+
+func TestCounterInMemory(t *testing.T) {
+	ws, want := words(1000, 100)
+	c := New(Config{Dir: t.TempDir()})
+	defer c.Close()
+	for _, w := range ws {
+		c.Add(w)
+	}
+	c.AddN("w1", 5)
+	want["w1"] += 5
+	if top, err := c.Top(3); c.Spills() != 0 || err != nil || !reflect.DeepEqual(top, text.TopWords(want, 3)) {
+		t.Fatalf("Top = %v, %v after %d spills", top, err, c.Spills())
+	}
+}
+
+func TestCounterErrors(t *testing.T) {
+	c := New(Config{MaxMemory: 1, Dir: "/nonexistent/dir"})
+	c.Add("a")
+	c.Add("b")
+	if c.Err() == nil {
+		t.Fatal("no error spilling to a missing directory")
+	}
+	if _, err := c.Top(1); err != c.Err() {
+		t.Errorf("Top: %v", err)
+	}
+
+	c = New(Config{MaxMemory: 1, Dir: t.TempDir()})
+	defer c.Close()
+	c.Add("a")
+	c.Add("b")
+	for _, damaged := range [][]byte{{5, 'a'}, {0xff, 0xff, 0xff, 0x7f}} {
+		os.WriteFile(c.runs[0], damaged, 0o600)
+		if err := c.Each(func(text.WordFreq) error { return nil }); err == nil {
+			t.Errorf("no error reading the damaged run %q", damaged)
+		}
+	}
+	stop := errors.New("stop")
+	os.Remove(c.runs[0])
+	c.runs = c.runs[1:]
+	if err := c.Each(func(text.WordFreq) error { return stop }); err != stop {
+		t.Errorf("Each = %v, want the error of fn", err)
+	}
+}
@@ -0,0 +1,108 @@
+// Package stress runs an operation from many goroutines at once, for
+// tests that look for data races under go test -race. The stress tests
+// of the other packages are built with the "stress" tag:
+//
+//	go test -race -tags stress ./pool ./cache ./bus ./user
+//
+// $STRESS_GOROUTINES and $STRESS_ITERATIONS scale them up or down, and
+// -short divides the iterations by ten.
+package stress
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// Defaults of Config.
+const (
+	DefaultGoroutines = 32
+	DefaultIterations = 1000
+)
+
+// Environment variables overriding the Config of every Run.
+const (
+	GoroutinesEnv = "STRESS_GOROUTINES"
+	IterationsEnv = "STRESS_ITERATIONS"
+)
+
+// Config sets how hard Run hammers. The zero value uses the defaults,
+// or the environment.
+type Config struct {
+	// Goroutines is the number of goroutines started; zero means
+	// $STRESS_GOROUTINES, or DefaultGoroutines.
+	Goroutines int
+	// Iterations is the number of calls made by each goroutine; zero
+	// means $STRESS_ITERATIONS, or DefaultIterations.
+	Iterations int
+}
+
+func (c Config) withDefaults(short bool) (Config, error) {
+	var err error
+	if c.Goroutines <= 0 {
+		if c.Goroutines, err = fromEnv(GoroutinesEnv, DefaultGoroutines); err != nil {
+			return c, err
+		}
+	}
+	if c.Iterations <= 0 {
+		if c.Iterations, err = fromEnv(IterationsEnv, DefaultIterations); err != nil {
+			return c, err
+		}
+		if short {
+			c.Iterations = max(c.Iterations/10, 1)
+		}
+	}
+	return c, nil
+}
+
+func fromEnv(name string, def int) (int, error) {
+	env := os.Getenv(name)
+	if env == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(env)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("stress: $%s must be a positive number, not %q", name, env)
+	}
+	return n, nil
+}
+
+// Run calls op(g, i) for i from 0 to cfg.Iterations-1 in each of
+// cfg.Goroutines goroutines, numbered g from 0, and returns when they
+// are all done. The goroutines are released together, so that their
+// calls overlap as much as possible. op may call t.Error, but not
+// t.Fatal.
+func Run(t testing.TB, cfg Config, op func(g, i int)) {
+	t.Helper()
+	cfg, err := cfg.withDefaults(testing.Short())
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for g := 0; g < cfg.Goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			<-start
+			for i := 0; i < cfg.Iterations; i++ {
+				op(g, i)
+			}
+		}(g)
+	}
+	close(start)
+	wg.Wait()
+}
+
+// Total returns the number of calls Run makes with cfg across all its
+// goroutines, for tests that check counts afterwards.
+func Total(t testing.TB, cfg Config) int {
+	t.Helper()
+	cfg, err := cfg.withDefaults(testing.Short())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg.Goroutines * cfg.Iterations
+}
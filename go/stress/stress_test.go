@@ -0,0 +1,47 @@
+package stress
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	cfg := Config{Goroutines: 8, Iterations: 50}
+	var mu sync.Mutex
+	seen := map[[2]int]bool{}
+	Run(t, cfg, func(g, i int) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen[[2]int{g, i}] = true
+	})
+	if len(seen) != 400 || !seen[[2]int{7, 49}] || Total(t, cfg) != 400 {
+		t.Fatalf("%d distinct calls, want 400", len(seen))
+	}
+}
+
+func TestConfigDefaults(t *testing.T) {
+	t.Setenv(GoroutinesEnv, "")
+	t.Setenv(IterationsEnv, "")
+	cfg, err := Config{}.withDefaults(false)
+	if err != nil || cfg != (Config{DefaultGoroutines, DefaultIterations}) {
+		t.Fatalf("defaults %+v, %v", cfg, err)
+	}
+	if cfg, _ := (Config{}).withDefaults(true); cfg.Iterations != DefaultIterations/10 {
+		t.Fatalf("short iterations %d", cfg.Iterations)
+	}
+
+	t.Setenv(GoroutinesEnv, "3")
+	t.Setenv(IterationsEnv, "5")
+	cfg, err = Config{Goroutines: 2}.withDefaults(true)
+	if err != nil || cfg != (Config{2, 1}) {
+		t.Fatalf("from the environment %+v, %v", cfg, err)
+	}
+	if cfg, _ := (Config{}).withDefaults(false); cfg != (Config{3, 5}) {
+		t.Fatalf("from the environment %+v", cfg)
+	}
+
+	t.Setenv(IterationsEnv, "many")
+	if _, err := (Config{}).withDefaults(false); err == nil {
+		t.Fatal("bad $STRESS_ITERATIONS accepted")
+	}
+}
@@ -0,0 +1,207 @@
+package testsupport
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Response is a recorded response with assertions that fail the test,
+// each returning the response so that they chain. A gzip-compressed body
+// is decompressed for the assertions; the recorder's Body keeps it as
+// sent.
+type Response struct {
+	*httptest.ResponseRecorder
+	t       testing.TB
+	request string // "GET /path", for messages
+	body    []byte
+	decoded bool
+}
+
+// Bytes returns the body, decompressed if it was gzipped.
+func (r *Response) Bytes() []byte {
+	r.t.Helper()
+	if !r.decoded {
+		r.decoded = true
+		r.body = r.ResponseRecorder.Body.Bytes()
+		if r.Header().Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(bytes.NewReader(r.body))
+			if err == nil {
+				r.body, err = io.ReadAll(zr)
+			}
+			if err != nil {
+				r.t.Fatalf("%s: gunzip body: %v", r.request, err)
+			}
+		}
+	}
+	return r.body
+}
+
+// Text returns the body as a string, decompressed if it was gzipped.
+func (r *Response) Text() string {
+	r.t.Helper()
+	return string(r.Bytes())
+}
+
+// ExpectStatus checks the status code.
+func (r *Response) ExpectStatus(want int) *Response {
+	r.t.Helper()
+	if r.Code != want {
+		r.t.Fatalf("%s: status %d, want %d: %s", r.request, r.Code, want, r.Text())
+	}
+	return r
+}
+
+// ExpectHeader checks a response header; an empty want checks that it
+// is missing.
+func (r *Response) ExpectHeader(key, want string) *Response {
+	r.t.Helper()
+	if got := r.Header().Get(key); got != want {
+		r.t.Errorf("%s: %s = %q, want %q", r.request, key, got, want)
+	}
+	return r
+}
+
+// ExpectContentType checks the media type of the response, ignoring
+// parameters such as the charset.
+func (r *Response) ExpectContentType(want string) *Response {
+	r.t.Helper()
+	got, _, _ := strings.Cut(r.Header().Get("Content-Type"), ";")
+	if strings.TrimSpace(got) != want {
+		r.t.Errorf("%s: Content-Type %q, want %q", r.request, r.Header().Get("Content-Type"), want)
+	}
+	return r
+}
+
+// ExpectBodyContains checks that the body holds each of subs.
+func (r *Response) ExpectBodyContains(subs ...string) *Response {
+	r.t.Helper()
+	body := r.Text()
+	for _, sub := range subs {
+		if !strings.Contains(body, sub) {
+			r.t.Errorf("%s: body lacks %q:\n%s", r.request, sub, body)
+		}
+	}
+	return r
+}
+
+// DecodeJSON decodes the body into v.
+func (r *Response) DecodeJSON(v any) *Response {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Bytes(), v); err != nil {
+		r.t.Fatalf("%s: decode %q: %v", r.request, r.Text(), err)
+	}
+	return r
+}
+
+// ExpectJSON checks that the body is the JSON value want, whatever the
+// spacing and the order of object keys.
+func (r *Response) ExpectJSON(want string) *Response {
+	r.t.Helper()
+	w, g := r.parse(want)
+	if !reflect.DeepEqual(w, g) {
+		r.t.Errorf("%s: body %s, want %s", r.request, strings.TrimSpace(r.Text()), want)
+	}
+	return r
+}
+
+// ExpectJSONFields checks that the body has the values of want, where
+// objects may have more keys than want gives: `{"user":{"name":"ada"}}`
+// matches any object whose user has that name. Arrays must have as many
+// elements as in want, each matched the same way.
+func (r *Response) ExpectJSONFields(want string) *Response {
+	r.t.Helper()
+	w, g := r.parse(want)
+	for _, diff := range matchJSON("$", w, g) {
+		r.t.Errorf("%s: %s\nbody: %s", r.request, diff, strings.TrimSpace(r.Text()))
+	}
+	return r
+}
+
+// ExpectError checks the status and that the body is a JSON error whose
+// message contains msg.
+func (r *Response) ExpectError(status int, msg string) *Response {
+	r.t.Helper()
+	r.ExpectStatus(status)
+	var e struct {
+		Error string `json:"error"`
+	}
+	r.DecodeJSON(&e)
+	if !strings.Contains(e.Error, msg) {
+		r.t.Errorf("%s: error %q, want one containing %q", r.request, e.Error, msg)
+	}
+	return r
+}
+
+// parse decodes want and the body.
+func (r *Response) parse(want string) (w, g any) {
+	r.t.Helper()
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		r.t.Fatalf("testsupport: bad expected JSON %q: %v", want, err)
+	}
+	if err := json.Unmarshal(r.Bytes(), &g); err != nil {
+		r.t.Fatalf("%s: body is not JSON: %v\n%s", r.request, err, r.Text())
+	}
+	return w, g
+}
+
+// Decode decodes the JSON body of r as a T.
+func Decode[T any](r *Response) T {
+	r.t.Helper()
+	var v T
+	r.DecodeJSON(&v)
+	return v
+}
+
+// matchJSON returns how got differs from want at path, where objects in
+// got may have keys want lacks.
+func matchJSON(path string, want, got any) []string {
+	switch w := want.(type) {
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s is %s, want an object", path, jsonString(got))}
+		}
+		keys := make([]string, 0, len(w))
+		for k := range w {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var diffs []string
+		for _, k := range keys {
+			gv, ok := g[k]
+			if !ok {
+				diffs = append(diffs, fmt.Sprintf("%s.%s is missing", path, k))
+				continue
+			}
+			diffs = append(diffs, matchJSON(path+"."+k, w[k], gv)...)
+		}
+		return diffs
+	case []any:
+		g, ok := got.([]any)
+		if !ok || len(g) != len(w) {
+			return []string{fmt.Sprintf("%s is %s, want %d elements", path, jsonString(got), len(w))}
+		}
+		var diffs []string
+		for i := range w {
+			diffs = append(diffs, matchJSON(fmt.Sprintf("%s[%d]", path, i), w[i], g[i])...)
+		}
+		return diffs
+	}
+	if !reflect.DeepEqual(want, got) {
+		return []string{fmt.Sprintf("%s is %s, want %s", path, jsonString(got), jsonString(want))}
+	}
+	return nil
+}
+
+func jsonString(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,53 @@
+package testsupport
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMatchJSON(t *testing.T) {
+	for _, tt := range []struct {
+		want, got string
+		diffs     []string
+	}{
+		{`{"a":1}`, `{"a":1,"b":2}`, nil},
+		{`{"a":{"b":"x"}}`, `{"a":{"b":"x","c":true}}`, nil},
+		{`{"a":1,"b":2}`, `{"a":2}`, []string{"$.a is 2, want 1", "$.b is missing"}},
+		{`[{"id":1}]`, `[{"id":1,"name":"ada"}]`, nil},
+		{`[1,2]`, `[1]`, []string{"$ is [1], want 2 elements"}},
+		{`{"a":[{"b":1}]}`, `{"a":[{"b":3}]}`, []string{"$.a[0].b is 3, want 1"}},
+		{`{"a":{}}`, `{"a":"x"}`, []string{`$.a is "x", want an object`}},
+		{`null`, `null`, nil},
+	} {
+		var w, g any
+		json.Unmarshal([]byte(tt.want), &w)
+		json.Unmarshal([]byte(tt.got), &g)
+		if diffs := matchJSON("$", w, g); !reflect.DeepEqual(diffs, tt.diffs) {
+			t.Errorf("matchJSON(%s, %s) = %q, want %q", tt.want, tt.got, diffs, tt.diffs)
+		}
+	}
+}
+
+func TestResponseGzip(t *testing.T) {
+	h := New(t, Options{Stack: Full})
+	// Too small to compress: the body is read as sent.
+	h.Post("/wordcount", strings.NewReader(""), AcceptGzip()).
+		ExpectHeader("Content-Encoding", "").
+		ExpectJSON(`{"words":0,"unique":0,"top":[]}`)
+
+	words := make([]string, 300)
+	for i := range words {
+		words[i] = "w" + strconv.Itoa(i)
+	}
+	res := h.Post("/wordcount?top=-1", strings.NewReader(strings.Join(words, " ")), AcceptGzip()).
+		ExpectStatus(http.StatusOK).
+		ExpectHeader("Content-Encoding", "gzip").
+		ExpectJSONFields(`{"words":300,"unique":300}`)
+	if res.ResponseRecorder.Body.String() == res.Text() {
+		t.Fatal("body not decompressed")
+	}
+}
@@ -0,0 +1,96 @@
+package testsupport
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"example.com/tutorial/cache"
+	"example.com/tutorial/user"
+)
+
+// ErrStoreDown is the error of a FailingStore without an Err of its own.
+var ErrStoreDown = errors.New("testsupport: store down")
+
+// FailingStore is a user.Store whose every call fails, as a store whose
+// database is unreachable does.
+type FailingStore struct {
+	// Err is returned by every call; nil means ErrStoreDown.
+	Err error
+}
+
+var _ user.Store = FailingStore{}
+
+func (s FailingStore) err() error {
+	if s.Err == nil {
+		return ErrStoreDown
+	}
+	return s.Err
+}
+
+// Create implements user.Store.
+func (s FailingStore) Create(context.Context, user.User) (user.User, error) {
+	return user.User{}, s.err()
+}
+
+// Get implements user.Store.
+func (s FailingStore) Get(context.Context, string) (user.User, error) { return user.User{}, s.err() }
+
+// Update implements user.Store.
+func (s FailingStore) Update(context.Context, user.User) error { return s.err() }
+
+// Delete implements user.Store.
+func (s FailingStore) Delete(context.Context, string) error { return s.err() }
+
+// List implements user.Store.
+func (s FailingStore) List(context.Context, user.ListQuery) (user.Page, error) {
+	return user.Page{}, s.err()
+}
+
+// MapCache is a cache.Store in a map that counts its calls, standing in
+// for a cache shared between servers, such as a redisstore.Cache, in
+// server.Config.ResultCache. It is safe for concurrent use.
+type MapCache struct {
+	mu         sync.Mutex
+	m          map[string][]byte
+	hits, sets int
+}
+
+var _ cache.Store[string, []byte] = (*MapCache)(nil)
+
+// NewMapCache returns an empty cache.
+func NewMapCache() *MapCache { return &MapCache{m: map[string][]byte{}} }
+
+// Get implements cache.Store.
+func (c *MapCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.m[key]
+	if ok {
+		c.hits++
+	}
+	return v, ok
+}
+
+// Set implements cache.Store.
+func (c *MapCache) Set(key string, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.m[key] = value
+	c.sets++
+}
+
+// Delete implements cache.Store.
+func (c *MapCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.m, key)
+}
+
+// Stats returns how many Gets found a value and how many Sets there
+// were.
+func (c *MapCache) Stats() (hits, sets int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.sets
+}
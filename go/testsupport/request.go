@@ -0,0 +1,125 @@
+package testsupport
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"testing"
+)
+
+// A RequestOption changes a request built by NewRequest.
+type RequestOption func(*http.Request)
+
+// Header sets a request header.
+func Header(key, value string) RequestOption {
+	return func(r *http.Request) { r.Header.Set(key, value) }
+}
+
+// Bearer authorizes the request with token, as issued by POST /login.
+func Bearer(token string) RequestOption {
+	return Header("Authorization", "Bearer "+token)
+}
+
+// AcceptGzip asks for a gzip-compressed response, which Response
+// decompresses again.
+func AcceptGzip() RequestOption {
+	return Header("Accept-Encoding", "gzip")
+}
+
+// Origin makes the request cross-origin, from origin.
+func Origin(origin string) RequestOption {
+	return Header("Origin", origin)
+}
+
+// File is a file part of a Multipart body.
+type File struct {
+	Field, Name, Type string
+	Content           string
+}
+
+// Multipart is a multipart/form-data request body of files, as POST
+// /upload and /wordcount take.
+type Multipart []File
+
+// NewRequest returns a request for path, which may have a query, with
+// body encoded by its type:
+//
+//   - nil sends no body;
+//   - a string or []byte is sent as it is, as JSON;
+//   - an io.Reader is sent as it is, without a Content-Type;
+//   - url.Values are sent as a form;
+//   - Multipart is sent as multipart/form-data;
+//   - anything else is encoded as JSON.
+//
+// The options are applied last, so they can replace the Content-Type.
+func NewRequest(t testing.TB, method, path string, body any, opts ...RequestOption) *http.Request {
+	t.Helper()
+	var (
+		rd          io.Reader
+		contentType string
+	)
+	switch b := body.(type) {
+	case nil:
+	case string:
+		rd, contentType = bytes.NewBufferString(b), "application/json"
+	case []byte:
+		rd, contentType = bytes.NewReader(b), "application/json"
+	case io.Reader:
+		rd = b
+	case url.Values:
+		rd, contentType = bytes.NewBufferString(b.Encode()), "application/x-www-form-urlencoded"
+	case Multipart:
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		for _, f := range b {
+			if err := writePart(mw, f); err != nil {
+				t.Fatalf("testsupport: multipart body: %v", err)
+			}
+		}
+		if err := mw.Close(); err != nil {
+			t.Fatalf("testsupport: multipart body: %v", err)
+		}
+		rd, contentType = &buf, mw.FormDataContentType()
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			t.Fatalf("testsupport: encode %T: %v", body, err)
+		}
+		rd, contentType = bytes.NewReader(data), "application/json"
+	}
+	req := httptest.NewRequest(method, path, rd)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for _, opt := range opts {
+		opt(req)
+	}
+	return req
+}
+
+// writePart adds f to mw; an empty Field means "file" and an empty Type
+// text/plain.
+func writePart(mw *multipart.Writer, f File) error {
+	field, typ := f.Field, f.Type
+	if field == "" {
+		field = "file"
+	}
+	if typ == "" {
+		typ = "text/plain"
+	}
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{"name": field, "filename": f.Name}))
+	h.Set("Content-Type", typ)
+	w, err := mw.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, f.Content)
+	return err
+}
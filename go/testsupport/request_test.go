@@ -0,0 +1,64 @@
+package testsupport
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestNewRequest(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		body        any
+		opts        []RequestOption
+		contentType string
+		want        string
+	}{
+		{"nil", nil, nil, "", ""},
+		{"string", `{"a":1}`, nil, "application/json", `{"a":1}`},
+		{"bytes", []byte(`[]`), nil, "application/json", `[]`},
+		{"reader", strings.NewReader("plain text"), nil, "", "plain text"},
+		{"form", url.Values{"text": {"a b"}}, nil, "application/x-www-form-urlencoded", "text=a+b"},
+		{"value", map[string]int{"top": 3}, nil, "application/json", `{"top":3}`},
+		{"override", "<doc/>", []RequestOption{Header("Content-Type", "application/xml")}, "application/xml", "<doc/>"},
+	} {
+		req := NewRequest(t, "POST", "/x?top=1", tt.body, tt.opts...)
+		body, _ := io.ReadAll(req.Body)
+		if got := req.Header.Get("Content-Type"); got != tt.contentType || string(body) != tt.want {
+			t.Errorf("%s: Content-Type %q, body %q; want %q, %q", tt.name, got, body, tt.contentType, tt.want)
+		}
+	}
+
+	req := NewRequest(t, "GET", "/users", nil, Bearer("tok"), Origin("https://a.example"))
+	if req.Header.Get("Authorization") != "Bearer tok" || req.Header.Get("Origin") != "https://a.example" || req.URL.Path != "/users" {
+		t.Errorf("options not applied: %v", req.Header)
+	}
+}
+
+func TestNewRequestMultipart(t *testing.T) {
+	req := NewRequest(t, "POST", "/upload", Multipart{
+		{Name: "a.txt", Content: "alpha"},
+		{Field: "doc", Name: "b.md", Type: "text/markdown", Content: "# beta"},
+	})
+	mr, err := req.MultipartReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(part)
+		got = append(got, part.FormName()+" "+part.FileName()+" "+part.Header.Get("Content-Type")+" "+string(data))
+	}
+	want := []string{"file a.txt text/plain alpha", "doc b.md text/markdown # beta"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("parts %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,65 @@
+package testsupport
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"example.com/tutorial/metrics"
+	"example.com/tutorial/middleware"
+)
+
+// A Stack builds the middleware a Harness wraps the server in, given the
+// server's logger and metrics registry.
+type Stack func(logger *slog.Logger, reg *metrics.Registry) middleware.Middleware
+
+// Bare serves the router without middleware.
+func Bare(*slog.Logger, *metrics.Registry) middleware.Middleware {
+	return func(h http.Handler) http.Handler { return h }
+}
+
+// Serve is the middleware of the serve command: request IDs, request
+// logging and panic recovery.
+func Serve(logger *slog.Logger, _ *metrics.Registry) middleware.Middleware {
+	return middleware.Chain(
+		middleware.RequestID(),
+		middleware.Logger(logger),
+		middleware.Recover(logger),
+	)
+}
+
+// FullCORSOrigin is the origin the CORS policy of Full allows.
+const FullCORSOrigin = "https://app.example"
+
+// FullTimeout is the request time limit of Full, which the streaming
+// routes are exempt from.
+const FullTimeout = 5 * time.Second
+
+// Full adds every other middleware to Serve: metrics, CORS for
+// FullCORSOrigin, a rate limit too generous for tests to reach, the
+// FullTimeout limit and gzip compression.
+func Full(logger *slog.Logger, reg *metrics.Registry) middleware.Middleware {
+	return middleware.Chain(
+		Serve(logger, reg),
+		middleware.Metrics(reg),
+		middleware.CORS(middleware.CORSConfig{Default: middleware.CORSPolicy{
+			AllowedOrigins: []string{FullCORSOrigin},
+			AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
+			AllowedHeaders: []string{"Content-Type", "Authorization"},
+			ExposedHeaders: []string{middleware.RequestIDHeader},
+		}}),
+		middleware.RateLimit(middleware.NewLimiters(1000, 1000, time.Minute), nil),
+		middleware.Timeout(middleware.TimeoutConfig{
+			Default: FullTimeout,
+			Routes:  map[string]time.Duration{"/ws": 0, "/wordcount/events": 0},
+		}),
+		middleware.Gzip(0),
+	)
+}
+
+// Stacks names the stacks, for tests that run against each of them.
+var Stacks = map[string]Stack{
+	"bare":  Bare,
+	"serve": Serve,
+	"full":  Full,
+}
@@ -0,0 +1,179 @@
+// Package testsupport runs the HTTP API of package server in tests: the
+// full router over fake stores, wrapped in the middleware of the serve
+// command or another stack, with helpers that build requests and check
+// JSON responses.
+//
+//	h := testsupport.New(t, testsupport.Options{})
+//	h.Post("/users", map[string]string{"name": "ada"}).
+//		ExpectStatus(http.StatusCreated).
+//		ExpectJSONFields(`{"id":1,"name":"ada"}`)
+package testsupport
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"example.com/tutorial/metrics"
+	"example.com/tutorial/server"
+	"example.com/tutorial/user"
+)
+
+// Options configure a Harness. The zero value serves a fresh
+// user.MemoryStore behind the middleware of the serve command.
+type Options struct {
+	// Users backs the /users endpoints; nil means a new
+	// user.MemoryStore.
+	Users user.Store
+	// Config is passed to server.NewWithConfig. A nil Logger means one
+	// writing to the harness's Logs, and a nil Metrics the harness's
+	// registry, which the stack's middleware share.
+	Config server.Config
+	// Stack wraps the server; nil means Serve.
+	Stack Stack
+}
+
+// Harness serves the API in process. Its methods fail the test on
+// errors of their own, such as an unencodable body, rather than
+// returning them.
+type Harness struct {
+	// Users is the store behind the server.
+	Users user.Store
+	// Server is the router, without the middleware.
+	Server *server.Server
+	// Handler is Server wrapped in the stack's middleware; requests
+	// made through the harness go to it.
+	Handler http.Handler
+	// Metrics is the registry served on /metrics.
+	Metrics *metrics.Registry
+	// Logs holds what the server and the middleware logged, as text.
+	Logs *Buffer
+
+	t     testing.TB
+	start sync.Once
+	ts    *httptest.Server
+}
+
+// New returns a harness for opts. A server started by URL is closed when
+// the test ends.
+func New(t testing.TB, opts Options) *Harness {
+	t.Helper()
+	h := &Harness{Users: opts.Users, Logs: new(Buffer), t: t}
+	if h.Users == nil {
+		h.Users = user.NewMemoryStore()
+	}
+	cfg := opts.Config
+	if cfg.Logger == nil {
+		cfg.Logger = slog.New(slog.NewTextHandler(h.Logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+	if cfg.Metrics == nil {
+		cfg.Metrics = metrics.NewRegistry()
+	}
+	h.Metrics = cfg.Metrics
+	stack := opts.Stack
+	if stack == nil {
+		stack = Serve
+	}
+	h.Server = server.NewWithConfig(h.Users, cfg)
+	h.Handler = stack(cfg.Logger, cfg.Metrics)(h.Server)
+	return h
+}
+
+// URL starts a real HTTP server for the handler on first use and returns
+// its base URL, for clients that need a connection, such as WebSocket
+// dialers and streaming readers.
+func (h *Harness) URL() string {
+	h.start.Do(func() {
+		h.ts = httptest.NewServer(h.Handler)
+		h.t.Cleanup(h.ts.Close)
+	})
+	return h.ts.URL
+}
+
+// Do serves req and returns the recorded response.
+func (h *Harness) Do(req *http.Request) *Response {
+	h.t.Helper()
+	rec := httptest.NewRecorder()
+	h.Handler.ServeHTTP(rec, req)
+	return &Response{ResponseRecorder: rec, t: h.t, request: req.Method + " " + req.URL.RequestURI()}
+}
+
+// Request builds a request with NewRequest and serves it.
+func (h *Harness) Request(method, path string, body any, opts ...RequestOption) *Response {
+	h.t.Helper()
+	return h.Do(NewRequest(h.t, method, path, body, opts...))
+}
+
+// Get serves a GET request for path.
+func (h *Harness) Get(path string, opts ...RequestOption) *Response {
+	h.t.Helper()
+	return h.Request(http.MethodGet, path, nil, opts...)
+}
+
+// Post serves a POST request for path with body, as NewRequest encodes
+// it.
+func (h *Harness) Post(path string, body any, opts ...RequestOption) *Response {
+	h.t.Helper()
+	return h.Request(http.MethodPost, path, body, opts...)
+}
+
+// Put serves a PUT request for path with body.
+func (h *Harness) Put(path string, body any, opts ...RequestOption) *Response {
+	h.t.Helper()
+	return h.Request(http.MethodPut, path, body, opts...)
+}
+
+// Delete serves a DELETE request for path.
+func (h *Harness) Delete(path string, opts ...RequestOption) *Response {
+	h.t.Helper()
+	return h.Request(http.MethodDelete, path, nil, opts...)
+}
+
+// CreateUser signs up a user through POST /users and returns its ID.
+func (h *Harness) CreateUser(name, password string) string {
+	h.t.Helper()
+	var u struct {
+		ID string `json:"id"`
+	}
+	h.Post("/users", map[string]string{"name": name, "password": password}).
+		ExpectStatus(http.StatusCreated).
+		DecodeJSON(&u)
+	return u.ID
+}
+
+// Login exchanges name and password for a bearer token through POST
+// /login, which needs Config.Auth.
+func (h *Harness) Login(name, password string) string {
+	h.t.Helper()
+	var tok struct {
+		Token string `json:"token"`
+	}
+	h.Post("/login", map[string]string{"name": name, "password": password}).
+		ExpectStatus(http.StatusOK).
+		DecodeJSON(&tok)
+	return tok.Token
+}
+
+// Buffer is a bytes buffer safe for concurrent use, which loggers on the
+// server's goroutines can write while a test reads it.
+type Buffer struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+// Write implements io.Writer.
+func (b *Buffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	return len(p), nil
+}
+
+// String returns what has been written so far.
+func (b *Buffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
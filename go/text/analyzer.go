@@ -0,0 +1,71 @@
+package text
+
+import (
+	"bufio"
+	"io"
+)
+
+// Analyzer splits text into words in place of the Tokenizer, for
+// languages or formats the Tokenizer does not suit. Package analysis
+// keeps the analyzers that can be selected by name.
+type Analyzer interface {
+	// Analyze returns the words of s, in order.
+	Analyze(s string) []string
+}
+
+// AnalyzerFunc adapts an ordinary function to the Analyzer interface.
+type AnalyzerFunc func(s string) []string
+
+// Analyze calls f(s).
+func (f AnalyzerFunc) Analyze(s string) []string { return f(s) }
+
+// Selector is an Analyzer that picks the analyzer for each document
+// from its text, as one detecting languages does. The functions that
+// read a stream call Select with its first 8 KiB and analyze every line
+// with the Analyzer it returns, rather than have Analyze choose again
+// for each line.
+type Selector interface {
+	Analyzer
+	Select(sample string) Analyzer
+}
+
+// selectSample is how much of a stream a Selector is given.
+const selectSample = 8 << 10
+
+// maxAnalyzedLine bounds the lines that ScanWords and ReadStats read
+// for an Analyzer, which sees the input a line at a time.
+const maxAnalyzedLine = 16 << 20
+
+// WithAnalyzer splits text with a instead of the Tokenizer; stopwords
+// and normalizers still apply to its words. Functions that read a
+// stream give a the input a line at a time, so that its words cannot
+// span lines, and fail on lines longer than 16 MiB.
+func WithAnalyzer(a Analyzer) Option {
+	return func(c *config) { c.analyzer = a }
+}
+
+// scanReader calls fn with each word read from r to count, in order.
+func (c *config) scanReader(r io.Reader, fn func(word string)) error {
+	if sel, ok := c.analyzer.(Selector); ok {
+		br := bufio.NewReaderSize(r, selectSample)
+		sample, _ := br.Peek(selectSample) // a read error recurs below
+		local := *c
+		local.analyzer = sel.Select(string(sample))
+		c, r = &local, br
+	}
+	sc := bufio.NewScanner(r)
+	if c.analyzer != nil {
+		sc.Buffer(nil, maxAnalyzedLine)
+		for sc.Scan() {
+			c.scan(sc.Text(), fn)
+		}
+		return sc.Err()
+	}
+	sc.Split(c.tok.Split)
+	for sc.Scan() {
+		if w, ok := c.process(c.tok.Normalize(sc.Text())); ok {
+			fn(w)
+		}
+	}
+	return sc.Err()
+}
@@ -0,0 +1,74 @@
+package text
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWithAnalyzer(t *testing.T) {
+	fields := WithAnalyzer(AnalyzerFunc(strings.Fields))
+	if got := Words("Go, go... C++ and go-kit", fields); !reflect.DeepEqual(got, []string{"Go,", "go...", "C++", "and", "go-kit"}) {
+		t.Fatalf("Words = %q", got)
+	}
+	// Stopwords and normalizers still apply to the analyzer's words.
+	got := WordCount("The Go and the go", fields, WithStopwords(NewStopwords("the", "and")), WithNormalizers(Lowercase))
+	if !reflect.DeepEqual(got, map[string]int{"go": 2}) {
+		t.Fatalf("WordCount = %v", got)
+	}
+
+	// Streams are analyzed a line at a time.
+	var streamed []string
+	if err := ScanWords(strings.NewReader("a b\nc-d\n\ne"), func(w string) { streamed = append(streamed, w) }, fields); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(streamed, []string{"a", "b", "c-d", "e"}) {
+		t.Fatalf("ScanWords = %q", streamed)
+	}
+	st, err := ReadStats(strings.NewReader("x-y z\nx-y\n"), fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Lines != 2 || st.Words != 3 || st.UniqueWords != 2 {
+		t.Fatalf("ReadStats = %+v", st)
+	}
+
+	if err := ScanWords(strings.NewReader(strings.Repeat("a", maxAnalyzedLine+1)), func(string) {}, fields); err == nil {
+		t.Fatal("ScanWords read a line longer than the limit")
+	}
+}
+
+// firstWord is a Selector that analyzes the words of a text with
+// strings.Fields, upper-cased if the text starts with "UP".
+type firstWord struct{}
+
+func (s firstWord) Analyze(text string) []string { return s.Select(text).Analyze(text) }
+
+func (firstWord) Select(sample string) Analyzer {
+	if strings.HasPrefix(sample, "UP") {
+		return AnalyzerFunc(func(s string) []string { return strings.Fields(strings.ToUpper(s)) })
+	}
+	return AnalyzerFunc(strings.Fields)
+}
+
+func TestSelector(t *testing.T) {
+	sel := WithAnalyzer(firstWord{})
+	if got := Words("UP go\ngo", sel); !reflect.DeepEqual(got, []string{"UP", "GO", "GO"}) {
+		t.Fatalf("Words = %q", got)
+	}
+	// A stream is analyzed as its start selects, not line by line.
+	got, err := WordCountReader(strings.NewReader("UP go\ngo\n"), sel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, map[string]int{"UP": 1, "GO": 2}) {
+		t.Fatalf("WordCountReader = %v", got)
+	}
+	st, err := ReadStats(strings.NewReader("go\nUP go\n"), sel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Words != 3 || st.UniqueWords != 2 || st.Lines != 2 {
+		t.Fatalf("ReadStats = %+v", st)
+	}
+}
@@ -0,0 +1,177 @@
+package text
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// benchSizes are the input sizes of BenchmarkWordCount.
+var benchSizes = []struct {
+	name string
+	n    int
+}{
+	{"small", 100},
+	{"medium", 10 << 10},
+	{"large", 1 << 20},
+}
+
+// benchText returns about n bytes of prose, mostly ASCII with some
+// accented and non-Latin words and typographic apostrophes, as the
+// files counted usually are.
+func benchText(n int) string {
+	words := strings.Fields(`The quick brown fox jumps over the lazy dog. Don’t
+		count the Café’s chairs, count the words: naïve résumé, Zürich and Москва,
+		東京 too! It's 2024; numbers like 42 and v1.21 are words as well.`)
+	var b strings.Builder
+	for i := 0; b.Len() < n; i++ {
+		b.WriteString(words[i%len(words)])
+		if i%12 == 11 {
+			b.WriteByte('\n')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	return b.String()
+}
+
+// wordCounters are the implementations BenchmarkWordCount compares.
+// All but fields find the same words; fields, which splits on white
+// space and trims punctuation, is the simplest approach, for scale.
+var wordCounters = []struct {
+	name  string
+	count func(s string) map[string]int
+}{
+	{"fields", fieldsWordCount},
+	{"split", splitWordCount},
+	{"scanner", func(s string) map[string]int {
+		m, _ := WordCountReader(strings.NewReader(s))
+		return m
+	}},
+	{"bytes", func(s string) map[string]int { return WordCount(s) }},
+}
+
+// fieldsWordCount counts the lower-cased fields of s, trimmed of
+// punctuation.
+func fieldsWordCount(s string) map[string]int {
+	m := map[string]int{}
+	for _, f := range strings.Fields(s) {
+		f = strings.TrimFunc(f, func(r rune) bool { return !isWordRune(r) })
+		if f != "" {
+			m[strings.ToLower(f)]++
+		}
+	}
+	return m
+}
+
+// splitWordCount counts the words Tokenizer.Split yields over s as a
+// byte slice, as WordCount did before it scanned the string in place.
+func splitWordCount(s string) map[string]int {
+	var t Tokenizer
+	m := map[string]int{}
+	data := []byte(s)
+	for len(data) > 0 {
+		adv, tok, _ := t.Split(data, true)
+		if adv == 0 {
+			break
+		}
+		data = data[adv:]
+		if tok != nil {
+			m[t.Normalize(string(tok))]++
+		}
+	}
+	return m
+}
+
+func TestWordCountersAgree(t *testing.T) {
+	s := benchText(10 << 10)
+	want := WordCount(s)
+	for _, wc := range wordCounters[1:] {
+		got := wc.count(s)
+		if len(got) != len(want) {
+			t.Fatalf("%s: %d words, want %d", wc.name, len(got), len(want))
+		}
+		for w, n := range want {
+			if got[w] != n {
+				t.Fatalf("%s: count[%q] = %d, want %d", wc.name, w, got[w], n)
+			}
+		}
+	}
+}
+
+// BenchmarkWordCount compares the word counters on each input size:
+//
+//	go test -run '^$' -bench WordCount -benchmem ./text
+func BenchmarkWordCount(b *testing.B) {
+	for _, size := range benchSizes {
+		s := benchText(size.n)
+		for _, wc := range wordCounters {
+			b.Run(fmt.Sprintf("%s/%s", size.name, wc.name), func(b *testing.B) {
+				b.SetBytes(int64(len(s)))
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					wc.count(s)
+				}
+			})
+		}
+	}
+}
+
+// benchCounts returns the counts of n distinct words, with repeated
+// counts so that ties must be broken alphabetically.
+func benchCounts(n int) map[string]int {
+	counts := make(map[string]int, n)
+	for i := 0; i < n; i++ {
+		counts[fmt.Sprintf("w%07d", (i*7919)%n)] = i%1000 + 1
+	}
+	return counts
+}
+
+// sortTopWords is TopWords by sorting every word, as it was before it
+// kept the best n in a priority queue.
+func sortTopWords(counts map[string]int, n int) []WordFreq {
+	freqs := make([]WordFreq, 0, len(counts))
+	for w, c := range counts {
+		freqs = append(freqs, WordFreq{Word: w, Count: c})
+	}
+	sort.Slice(freqs, func(i, j int) bool { return RanksBefore(freqs[i], freqs[j]) })
+	if n >= 0 && n < len(freqs) {
+		freqs = freqs[:n]
+	}
+	return freqs
+}
+
+func TestTopWordsAgreesWithSort(t *testing.T) {
+	counts := benchCounts(5000)
+	for _, n := range []int{0, 1, 10, 999, 1000, 1001, 4999, 5000, 6000, -1} {
+		if got, want := TopWords(counts, n), sortTopWords(counts, n); !reflect.DeepEqual(got, want) {
+			t.Fatalf("TopWords(%d) differs from sorting: %v... want %v...", n, got[:min(3, len(got))], want[:min(3, len(want))])
+		}
+	}
+}
+
+// BenchmarkTopWords compares ranking the top ten words through the
+// priority queue against sorting every word:
+//
+//	go test -run '^$' -bench TopWords -benchmem ./text
+func BenchmarkTopWords(b *testing.B) {
+	for _, size := range []int{1 << 10, 1 << 16, 1 << 20} {
+		counts := benchCounts(size)
+		for _, impl := range []struct {
+			name string
+			top  func(map[string]int, int) []WordFreq
+		}{
+			{"heap", TopWords},
+			{"sort", sortTopWords},
+		} {
+			b.Run(fmt.Sprintf("%d/%s", size, impl.name), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					impl.top(counts, 10)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package text
+
+import "example.com/tutorial/graph"
+
+// CooccurrenceGraph returns the graph of the words of s that occur near
+// each other: an edge from one word to each different word among the
+// window-1 that follow it, so a window of 2 links each word to the next.
+// Words are nodes in the order they first occur and edges in the order
+// they are first seen, so the graph of the same text is always walked
+// the same way. A window below 2 yields the words without edges.
+func CooccurrenceGraph(s string, window int, opts ...Option) *graph.Graph[string] {
+	c := newConfig(opts)
+	g := graph.New[string]()
+	words := c.tokens(s)
+	for i, w := range words {
+		g.AddNode(w)
+		for _, next := range words[i+1 : min(i+window, len(words))] {
+			if next != w {
+				g.AddEdge(w, next)
+			}
+		}
+	}
+	return g
+}
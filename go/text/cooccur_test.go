@@ -0,0 +1,44 @@
+package text
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCooccurrenceGraph(t *testing.T) {
+	g := CooccurrenceGraph("The cat sat on the mat; the cat ran.", 2, WithStopwords(NewStopwords("on")))
+	if got := g.Nodes(); !reflect.DeepEqual(got, []string{"the", "cat", "sat", "mat", "ran"}) {
+		t.Fatalf("Nodes = %q", got)
+	}
+	for w, want := range map[string][]string{
+		"the": {"cat", "mat"},
+		"cat": {"sat", "ran"},
+		"sat": {"the"},
+		"mat": {"the"},
+		"ran": {},
+	} {
+		if got := g.Successors(w); !reflect.DeepEqual(got, want) {
+			t.Errorf("Successors(%q) = %q, want %q", w, got, want)
+		}
+	}
+	if g.FindCycle() == nil {
+		t.Fatal("no cycle through the repeated words")
+	}
+
+	wide := CooccurrenceGraph("a b a c", 3)
+	if got := wide.Successors("a"); !reflect.DeepEqual(got, []string{"b", "c"}) {
+		t.Fatalf("window 3: Successors(a) = %q", got)
+	}
+	if !wide.HasEdge("b", "a") || !wide.HasEdge("b", "c") || wide.HasEdge("a", "a") {
+		t.Fatal("window 3 edges")
+	}
+
+	flat := CooccurrenceGraph("one two three", 1)
+	if flat.Len() != 3 || flat.Edges() != 0 {
+		t.Fatalf("window 1: %d nodes, %d edges", flat.Len(), flat.Edges())
+	}
+	order, err := CooccurrenceGraph("first second third", 2).TopologicalSort()
+	if err != nil || !reflect.DeepEqual(order, []string{"first", "second", "third"}) {
+		t.Fatalf("TopologicalSort = %q, %v", order, err)
+	}
+}
@@ -0,0 +1,279 @@
+package text
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ChangeKind says how the count of a word changed between two counts.
+type ChangeKind string
+
+const (
+	Added   ChangeKind = "added"   // counted only in the later count
+	Removed ChangeKind = "removed" // counted only in the earlier count
+	Changed ChangeKind = "changed" // counted in both, a different number of times
+)
+
+// WordChange is the change in the count of a word between two counts.
+type WordChange struct {
+	Word   string     `json:"word"`
+	Kind   ChangeKind `json:"change"`
+	Before int        `json:"before"`
+	After  int        `json:"after"`
+	Delta  int        `json:"delta"` // After - Before
+	// Relative is Delta over Before, such as 0.5 for a count up by half
+	// and -1 for a removed word. An added word, with no count to be
+	// relative to, has 0, which JSON leaves out.
+	Relative float64 `json:"relative,omitempty"`
+}
+
+// CountDiff compares a word count with an earlier one: their totals,
+// and the words whose counts changed.
+type CountDiff struct {
+	WordsBefore  int `json:"words_before"`
+	WordsAfter   int `json:"words_after"`
+	UniqueBefore int `json:"unique_before"`
+	UniqueAfter  int `json:"unique_after"`
+	// Unchanged is the number of words counted as often in both.
+	Unchanged int `json:"unchanged"`
+	// Changes are ordered by the size of their Delta, largest first,
+	// and then by word.
+	Changes []WordChange `json:"changes"`
+}
+
+// DiffCounts compares the word counts after with the earlier before.
+// Count both with the same options, or the same word may be spelt
+// differently on each side.
+func DiffCounts(before, after map[string]int) CountDiff {
+	d := CountDiff{UniqueBefore: len(before), UniqueAfter: len(after), Changes: []WordChange{}}
+	for w, n := range before {
+		d.WordsBefore += n
+		if m := after[w]; m != n {
+			d.Changes = append(d.Changes, newWordChange(w, n, m))
+		} else {
+			d.Unchanged++
+		}
+	}
+	for w, m := range after {
+		d.WordsAfter += m
+		if _, ok := before[w]; !ok {
+			d.Changes = append(d.Changes, newWordChange(w, 0, m))
+		}
+	}
+	sort.Slice(d.Changes, func(i, j int) bool {
+		a, b := d.Changes[i], d.Changes[j]
+		if da, db := abs(a.Delta), abs(b.Delta); da != db {
+			return da > db
+		}
+		return a.Word < b.Word
+	})
+	return d
+}
+
+// DiffSummaries is DiffCounts for two summaries, such as the results of
+// POST /wordcount, whose totals it keeps. A word missing from one
+// summary's table counts as 0 there, so with tables cut to their top
+// words, a word that merely fell off the end of one looks added or
+// removed.
+func DiffSummaries(before, after Summary) CountDiff {
+	d := DiffCounts(summaryCounts(before), summaryCounts(after))
+	d.WordsBefore, d.UniqueBefore = before.Words, before.Unique
+	d.WordsAfter, d.UniqueAfter = after.Words, after.Unique
+	return d
+}
+
+func summaryCounts(s Summary) map[string]int {
+	counts := make(map[string]int, len(s.Top))
+	for _, wf := range s.Top {
+		counts[wf.Word] += wf.Count
+	}
+	return counts
+}
+
+func newWordChange(word string, before, after int) WordChange {
+	c := WordChange{Word: word, Kind: Changed, Before: before, After: after, Delta: after - before}
+	switch {
+	case before == 0:
+		c.Kind = Added
+	case after == 0:
+		c.Kind = Removed
+	}
+	if before != 0 {
+		c.Relative = float64(c.Delta) / float64(before)
+	}
+	return c
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// EncodeDiff writes d to w in format f: a table of the changes
+// followed by a line of totals, one JSON object, or CSV of the changes
+// with a header row.
+func EncodeDiff(w io.Writer, d CountDiff, f Format) error {
+	switch f {
+	case FormatText, "":
+		return encodeDiffTable(w, d)
+	case FormatJSON:
+		return json.NewEncoder(w).Encode(d)
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"word", "change", "before", "after", "delta", "relative"})
+		for _, c := range d.Changes {
+			rel := ""
+			if c.Kind != Added {
+				rel = strconv.FormatFloat(c.Relative, 'f', -1, 64)
+			}
+			cw.Write([]string{c.Word, string(c.Kind), strconv.Itoa(c.Before), strconv.Itoa(c.After), strconv.Itoa(c.Delta), rel})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("unknown format %q", f)
+}
+
+// encodeDiffTable writes the changes as a table, the words left-aligned
+// and the numbers right-aligned, relative deltas as percentages.
+func encodeDiffTable(w io.Writer, d CountDiff) error {
+	rows := [][]string{{"WORD", "CHANGE", "BEFORE", "AFTER", "DELTA", "RELATIVE"}}
+	for _, c := range d.Changes {
+		rows = append(rows, []string{c.Word, string(c.Kind), strconv.Itoa(c.Before), strconv.Itoa(c.After), signed(c.Delta), percent(c)})
+	}
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+	var b strings.Builder
+	for _, row := range rows {
+		for i, cell := range row {
+			pad := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+			switch i {
+			case 0:
+				b.WriteString(cell + pad)
+			case 1:
+				b.WriteString("  " + cell + pad)
+			default:
+				b.WriteString("  " + pad + cell)
+			}
+		}
+		b.WriteByte('\n')
+	}
+	var added, removed int
+	for _, c := range d.Changes {
+		switch c.Kind {
+		case Added:
+			added++
+		case Removed:
+			removed++
+		}
+	}
+	fmt.Fprintf(&b, "\nwords %d -> %d, unique %d -> %d: %d added, %d removed, %d changed, %d unchanged\n",
+		d.WordsBefore, d.WordsAfter, d.UniqueBefore, d.UniqueAfter, added, removed, len(d.Changes)-added-removed, d.Unchanged)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func signed(n int) string {
+	if n > 0 {
+		return "+" + strconv.Itoa(n)
+	}
+	return strconv.Itoa(n)
+}
+
+// percent renders the relative delta of c, or "new" for an added word.
+func percent(c WordChange) string {
+	if c.Kind == Added {
+		return "new"
+	}
+	p := math.Round(c.Relative*1000) / 10
+	if p > 0 {
+		return fmt.Sprintf("+%.1f%%", p)
+	}
+	return fmt.Sprintf("%.1f%%", p)
+}
+
+// DecodeFrequencies reads back a frequency table EncodeFrequencies
+// wrote, in any of its formats, telling which from its first bytes, or
+// a Summary as JSON. The totals of a bare table are its sum and length,
+// which for a table cut to the top words are those of the words listed.
+func DecodeFrequencies(r io.Reader) (Summary, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return Summary{}, err
+	}
+	trimmed := strings.TrimSpace(string(b))
+	var s Summary
+	switch {
+	case strings.HasPrefix(trimmed, "{"):
+		if err := json.Unmarshal(b, &s); err != nil {
+			return Summary{}, fmt.Errorf("text: decode summary: %w", err)
+		}
+		return s, nil
+	case strings.HasPrefix(trimmed, "["):
+		err = json.Unmarshal(b, &s.Top)
+	case strings.HasPrefix(trimmed, "word,count"):
+		s.Top, err = decodeCSVFrequencies(trimmed)
+	case strings.HasPrefix(trimmed, "WORD"):
+		s.Top, err = decodeTableFrequencies(trimmed)
+	default:
+		err = errors.New("not a frequency table")
+	}
+	if err != nil {
+		return Summary{}, fmt.Errorf("text: decode frequencies: %w", err)
+	}
+	for _, wf := range s.Top {
+		s.Words += wf.Count
+	}
+	s.Unique = len(s.Top)
+	return s, nil
+}
+
+func decodeCSVFrequencies(s string) ([]WordFreq, error) {
+	recs, err := csv.NewReader(strings.NewReader(s)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	freqs := make([]WordFreq, 0, len(recs)-1)
+	for i, rec := range recs[1:] {
+		if len(rec) != 2 {
+			return nil, fmt.Errorf("line %d: want word,count", i+2)
+		}
+		n, err := strconv.Atoi(rec[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+2, err)
+		}
+		freqs = append(freqs, WordFreq{Word: rec[0], Count: n})
+	}
+	return freqs, nil
+}
+
+func decodeTableFrequencies(s string) ([]WordFreq, error) {
+	lines := strings.Split(s, "\n")
+	freqs := make([]WordFreq, 0, len(lines)-1)
+	for i, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: want a word and its count", i+2)
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+2, err)
+		}
+		freqs = append(freqs, WordFreq{Word: fields[0], Count: n})
+	}
+	return freqs, nil
+}
@@ -0,0 +1,103 @@
+package text
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffCounts(t *testing.T) {
+	before := map[string]int{"go": 4, "gopher": 2, "rust": 1, "same": 3}
+	after := map[string]int{"go": 6, "gopher": 1, "zig": 2, "same": 3}
+	d := DiffCounts(before, after)
+	want := CountDiff{
+		WordsBefore: 10, WordsAfter: 12, UniqueBefore: 4, UniqueAfter: 4, Unchanged: 1,
+		Changes: []WordChange{
+			{Word: "go", Kind: Changed, Before: 4, After: 6, Delta: 2, Relative: 0.5},
+			{Word: "zig", Kind: Added, After: 2, Delta: 2},
+			{Word: "gopher", Kind: Changed, Before: 2, After: 1, Delta: -1, Relative: -0.5},
+			{Word: "rust", Kind: Removed, Before: 1, Delta: -1, Relative: -1},
+		},
+	}
+	if !reflect.DeepEqual(d, want) {
+		t.Fatalf("got %+v\nwant %+v", d, want)
+	}
+
+	if d := DiffCounts(nil, nil); d.Changes == nil || len(d.Changes) != 0 {
+		t.Errorf("empty diff %+v", d)
+	}
+}
+
+func TestDiffSummaries(t *testing.T) {
+	before := Summary{Words: 100, Unique: 40, Top: []WordFreq{{"the", 10}, {"cat", 5}}}
+	after := Summary{Words: 120, Unique: 45, Top: []WordFreq{{"the", 12}, {"cat", 5}}}
+	d := DiffSummaries(before, after)
+	if d.WordsBefore != 100 || d.WordsAfter != 120 || d.UniqueBefore != 40 || d.UniqueAfter != 45 || d.Unchanged != 1 || len(d.Changes) != 1 {
+		t.Fatalf("got %+v", d)
+	}
+}
+
+func TestEncodeDiff(t *testing.T) {
+	d := DiffCounts(map[string]int{"go": 4, "rust": 1}, map[string]int{"go": 6, "zig": 2})
+	var b strings.Builder
+	if err := EncodeDiff(&b, d, FormatText); err != nil {
+		t.Fatal(err)
+	}
+	want := "" +
+		"WORD  CHANGE   BEFORE  AFTER  DELTA  RELATIVE\n" +
+		"go    changed       4      6     +2    +50.0%\n" +
+		"zig   added         0      2     +2       new\n" +
+		"rust  removed       1      0     -1   -100.0%\n" +
+		"\n" +
+		"words 5 -> 8, unique 2 -> 2: 1 added, 1 removed, 1 changed, 0 unchanged\n"
+	if b.String() != want {
+		t.Errorf("text:\n%s\nwant:\n%s", b.String(), want)
+	}
+
+	b.Reset()
+	EncodeDiff(&b, d, FormatCSV)
+	if want := "word,change,before,after,delta,relative\ngo,changed,4,6,2,0.5\nzig,added,0,2,2,\nrust,removed,1,0,-1,-1\n"; b.String() != want {
+		t.Errorf("csv:\n%s", b.String())
+	}
+
+	b.Reset()
+	EncodeDiff(&b, d, FormatJSON)
+	var got CountDiff
+	if err := json.Unmarshal([]byte(b.String()), &got); err != nil || !reflect.DeepEqual(got, d) {
+		t.Errorf("json %s: %v", b.String(), err)
+	}
+	if !strings.Contains(b.String(), `{"word":"zig","change":"added","before":0,"after":2,"delta":2}`) {
+		t.Errorf("json %s", b.String())
+	}
+
+	if err := EncodeDiff(&b, d, "xml"); err == nil {
+		t.Error("unknown format: no error")
+	}
+}
+
+func TestDecodeFrequencies(t *testing.T) {
+	freqs := []WordFreq{{"the", 3}, {"cat", 1}}
+	for _, f := range Formats {
+		var b bytes.Buffer
+		EncodeFrequencies(&b, freqs, f)
+		s, err := DecodeFrequencies(&b)
+		if err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+		if want := (Summary{Words: 4, Unique: 2, Top: freqs}); !reflect.DeepEqual(s, want) {
+			t.Errorf("%s: got %+v", f, s)
+		}
+	}
+
+	s, err := DecodeFrequencies(strings.NewReader(`{"words":10,"unique":7,"top":[{"word":"the","count":3}]}`))
+	if err != nil || s.Words != 10 || s.Unique != 7 || len(s.Top) != 1 {
+		t.Errorf("summary: %+v, %v", s, err)
+	}
+	for _, in := range []string{"", "hello world", "WORD  COUNT\nthe three\n", "word,count\nthe\n", `[{"word":`} {
+		if _, err := DecodeFrequencies(strings.NewReader(in)); err == nil {
+			t.Errorf("%q: no error", in)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package text_test
+
+import (
+	"fmt"
+	"strings"
+
+	"example.com/tutorial/text"
+)
+
+func ExampleWordCount() {
+	counts := text.WordCount("The cat sat on the mat. The end!")
+	// fmt prints maps sorted by key.
+	fmt.Println(counts)
+	// Output: map[cat:1 end:1 mat:1 on:1 sat:1 the:3]
+}
+
+func ExampleWordCount_options() {
+	counts := text.WordCount("The runners were running; a runner runs.",
+		text.WithStopwords(text.NewStopwords("the", "a", "were")),
+		text.WithNormalizers(text.PorterStemmer))
+	fmt.Println(counts)
+	// Output: map[run:2 runner:2]
+}
+
+func ExampleWordCountReader() {
+	counts, err := text.WordCountReader(strings.NewReader("to be\nor not to be"))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(counts["to"], counts["be"], counts["or"], len(counts))
+	// Output: 2 2 1 4
+}
+
+func ExampleWords() {
+	fmt.Printf("%q\n", text.Words("Don't panic, it's only 42 words—mostly."))
+	// Output: ["don't" "panic" "it's" "only" "42" "words" "mostly"]
+}
+
+func ExampleTopWords() {
+	counts := text.WordCount("b a c b a b d")
+	// Equal counts come out alphabetically, so the table is the same on
+	// every run.
+	for _, f := range text.TopWords(counts, 3) {
+		fmt.Printf("%s %d\n", f.Word, f.Count)
+	}
+	// Output:
+	// b 3
+	// a 2
+	// c 1
+}
+
+func ExampleRenderHistogram() {
+	counts := text.WordCount("go go go gophers go channels channels")
+	fmt.Print(text.RenderHistogram(counts, 24))
+	// Output:
+	// go       █████████████ 4
+	// channels ██████        2
+	// gophers  ███           1
+}
@@ -0,0 +1,86 @@
+package text
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime/trace"
+
+	"example.com/tutorial/pool"
+	"example.com/tutorial/progress"
+)
+
+// CountFiles counts the words of every file in paths using up to workers
+// goroutines and returns the merged counts. The first error (including
+// cancellation of ctx) stops the remaining work and is returned. In an
+// execution trace the call is a task with a region for each file.
+func CountFiles(ctx context.Context, paths []string, workers int, opts ...Option) (map[string]int, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	parent := ctx
+	ctx, task := trace.NewTask(ctx, "text.CountFiles")
+	defer task.End()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	t := newConfig(opts).progress
+	p := pool.New(func(ctx context.Context, path string) (map[string]int, error) {
+		m, err := countFile(ctx, path, t, opts)
+		if err == nil {
+			t.AddFiles(1)
+		}
+		return m, err
+	}, pool.Config{Workers: workers})
+	total := map[string]int{}
+	var firstErr error
+	for res := range p.Run(ctx, pool.Feed(ctx, paths)) {
+		if res.Err != nil {
+			if firstErr == nil {
+				firstErr = res.Err
+				cancel()
+			}
+			continue
+		}
+		for w, n := range res.Value {
+			total[w] += n
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := parent.Err(); err != nil {
+		return nil, err
+	}
+	return total, nil
+}
+
+func countFile(ctx context.Context, path string, t *progress.Tracker, opts []Option) (map[string]int, error) {
+	defer trace.StartRegion(ctx, "countFile").End()
+	trace.Log(ctx, "path", path)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, err := WordCountReader(ctxReader{ctx, t.Reader(f)}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("count %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// ctxReader aborts reads once its context is done, so a worker stuck on a
+// large file notices cancellation between chunks.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
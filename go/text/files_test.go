@@ -0,0 +1,71 @@
+package text
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"example.com/tutorial/progress"
+)
+
+func writeFiles(t *testing.T, contents ...string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	paths := make([]string, len(contents))
+	for i, c := range contents {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("doc%d.txt", i))
+		if err := os.WriteFile(paths[i], []byte(c), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return paths
+}
+
+func TestCountFiles(t *testing.T) {
+	var contents []string
+	for i := 0; i < 20; i++ {
+		contents = append(contents, "go gophers go")
+	}
+	paths := writeFiles(t, contents...)
+	for _, workers := range []int{0, 1, 4, 50} {
+		got, err := CountFiles(context.Background(), paths, workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got["go"] != 40 || got["gophers"] != 20 || len(got) != 2 {
+			t.Fatalf("workers=%d: unexpected counts %v", workers, got)
+		}
+	}
+}
+
+func TestCountFilesMissingFile(t *testing.T) {
+	paths := append(writeFiles(t, "a b", "c"), filepath.Join(t.TempDir(), "missing.txt"))
+	_, err := CountFiles(context.Background(), paths, 2)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("err = %v, want os.ErrNotExist", err)
+	}
+}
+
+func TestCountFilesCancelled(t *testing.T) {
+	paths := writeFiles(t, "a b", "c d")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := CountFiles(ctx, paths, 2)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestCountFilesProgress(t *testing.T) {
+	paths := writeFiles(t, "go gophers go", "rust", "")
+	tr := progress.NewTracker()
+	if _, err := CountFiles(context.Background(), paths, 2, WithProgress(tr)); err != nil {
+		t.Fatal(err)
+	}
+	if s := tr.Stats(); s.Files != 3 || s.Bytes != 17 {
+		t.Fatalf("counted %d files, %d bytes", s.Files, s.Bytes)
+	}
+}
@@ -0,0 +1,86 @@
+package text
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"example.com/tutorial/jsonstream"
+)
+
+// Format selects how EncodeFrequencies renders a frequency table.
+type Format string
+
+const (
+	// FormatText is an aligned, human-readable table.
+	FormatText Format = "text"
+	// FormatJSON is a JSON array of {"word", "count"} objects.
+	FormatJSON Format = "json"
+	// FormatCSV is RFC 4180 CSV with a "word,count" header row.
+	FormatCSV Format = "csv"
+)
+
+// Formats lists the supported formats.
+var Formats = []Format{FormatText, FormatJSON, FormatCSV}
+
+// ParseFormat converts a format name such as a flag value into a Format.
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown format %q (want text, json or csv)", s)
+}
+
+// EncodeFrequencies writes freqs to w in the given format, preserving
+// their order.
+func EncodeFrequencies(w io.Writer, freqs []WordFreq, f Format) error {
+	switch f {
+	case FormatText, "":
+		return encodeTable(w, freqs)
+	case FormatJSON:
+		// Streamed an element at a time, as the table may be large.
+		enc := jsonstream.NewArrayEncoder(w)
+		for _, wf := range freqs {
+			if err := enc.Encode(wf); err != nil {
+				return err
+			}
+		}
+		return enc.Close()
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"word", "count"})
+		for _, wf := range freqs {
+			cw.Write([]string{wf.Word, strconv.Itoa(wf.Count)})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("unknown format %q", f)
+}
+
+// encodeTable writes the words left-aligned and the counts right-aligned.
+func encodeTable(w io.Writer, freqs []WordFreq) error {
+	wordW, countW := len("WORD"), len("COUNT")
+	for _, wf := range freqs {
+		wordW = max(wordW, utf8.RuneCountInString(wf.Word))
+		countW = max(countW, len(strconv.Itoa(wf.Count)))
+	}
+	var b strings.Builder
+	row := func(word, count string) {
+		b.WriteString(word)
+		b.WriteString(strings.Repeat(" ", wordW-utf8.RuneCountInString(word)+2+countW-len(count)))
+		b.WriteString(count)
+		b.WriteByte('\n')
+	}
+	row("WORD", "COUNT")
+	for _, wf := range freqs {
+		row(wf.Word, strconv.Itoa(wf.Count))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,52 @@
+package text
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeFrequencies(t *testing.T) {
+	freqs := []WordFreq{{"go", 120}, {"gophers", 7}, {`say "hi"`, 1}}
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{FormatText, "WORD      COUNT\ngo          120\ngophers       7\nsay \"hi\"      1\n"},
+		{FormatJSON, `[{"word":"go","count":120},{"word":"gophers","count":7},{"word":"say \"hi\"","count":1}]` + "\n"},
+		{FormatCSV, "word,count\ngo,120\ngophers,7\n\"say \"\"hi\"\"\",1\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := EncodeFrequencies(&buf, freqs, tt.format); err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", tt.format, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestEncodeFrequenciesEmptyJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeFrequencies(&buf, nil, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "[]\n" {
+		t.Fatalf("got %q", buf.String())
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, f := range Formats {
+		if got, err := ParseFormat(string(f)); err != nil || got != f {
+			t.Errorf("ParseFormat(%q) = %q, %v", f, got, err)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("ParseFormat(xml) succeeded")
+	}
+	var buf bytes.Buffer
+	if err := EncodeFrequencies(&buf, nil, "xml"); err == nil {
+		t.Error("EncodeFrequencies with unknown format succeeded")
+	}
+}
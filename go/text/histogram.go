@@ -0,0 +1,85 @@
+package text
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// HistogramOption configures RenderHistogram.
+type HistogramOption func(*histogramConfig)
+
+type histogramConfig struct {
+	top   int
+	color bool
+}
+
+// HistogramTop limits the chart to the n most frequent words. The default
+// is 10; a negative n charts every word.
+func HistogramTop(n int) HistogramOption {
+	return func(c *histogramConfig) { c.top = n }
+}
+
+// HistogramColor wraps the bars in ANSI color escapes.
+func HistogramColor(on bool) HistogramOption {
+	return func(c *histogramConfig) { c.color = on }
+}
+
+const (
+	barRune   = "█"
+	ansiGreen = "\x1b[32m"
+	ansiReset = "\x1b[0m"
+)
+
+// RenderHistogram draws a horizontal bar chart of the most frequent words
+// in counts, one line per word:
+//
+//	go      ████████████ 12
+//	gophers ██████        6
+//
+// Each line is at most width columns wide (not counting color escapes);
+// bars are scaled so the most frequent word gets the longest bar and
+// every word gets at least one cell.
+func RenderHistogram(counts map[string]int, width int, opts ...HistogramOption) string {
+	cfg := histogramConfig{top: 10}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	top := TopWords(counts, cfg.top)
+	if len(top) == 0 {
+		return ""
+	}
+	labelW, countW := 0, len(fmt.Sprint(top[0].Count))
+	for _, f := range top {
+		if n := utf8.RuneCountInString(f.Word); n > labelW {
+			labelW = n
+		}
+	}
+	barW := width - labelW - countW - 2
+	if barW < 1 {
+		barW = 1
+	}
+	max := top[0].Count
+
+	var b strings.Builder
+	for _, f := range top {
+		n := 0
+		if max > 0 {
+			n = f.Count * barW / max
+		}
+		if n < 1 && f.Count > 0 {
+			n = 1
+		}
+		b.WriteString(f.Word)
+		b.WriteString(strings.Repeat(" ", labelW-utf8.RuneCountInString(f.Word)+1))
+		if cfg.color {
+			b.WriteString(ansiGreen)
+		}
+		b.WriteString(strings.Repeat(barRune, n))
+		if cfg.color {
+			b.WriteString(ansiReset)
+		}
+		fmt.Fprintf(&b, "%s %*d\n", strings.Repeat(" ", barW-n), countW, f.Count)
+	}
+	return b.String()
+}
@@ -0,0 +1,46 @@
+package text
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestRenderHistogram(t *testing.T) {
+	counts := map[string]int{"go": 10, "gophers": 5, "rust": 1}
+	got := RenderHistogram(counts, 30)
+	bar := func(n int) string { return strings.Repeat(barRune, n) }
+	want := "go      " + bar(19) + " 10\n" +
+		"gophers " + bar(9) + "            5\n" +
+		"rust    " + bar(1) + "                    1\n"
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(got, "\n"), "\n") {
+		if n := utf8.RuneCountInString(line); n != 30 {
+			t.Fatalf("line %q is %d columns, want 30", line, n)
+		}
+	}
+}
+
+func TestRenderHistogramOptions(t *testing.T) {
+	counts := map[string]int{"a": 100, "b": 1, "c": 1}
+	got := RenderHistogram(counts, 10, HistogramTop(2), HistogramColor(true))
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), got)
+	}
+	if !strings.Contains(lines[0], ansiGreen) || !strings.Contains(lines[0], ansiReset) {
+		t.Fatalf("missing color escapes: %q", lines[0])
+	}
+	// The smallest bar still gets one cell.
+	if !strings.Contains(lines[1], barRune) {
+		t.Fatalf("missing bar: %q", lines[1])
+	}
+}
+
+func TestRenderHistogramEmpty(t *testing.T) {
+	if got := RenderHistogram(nil, 40); got != "" {
+		t.Fatalf("got %q, want empty", got)
+	}
+}
@@ -0,0 +1,19 @@
+package text
+
+import "strings"
+
+// NGramCount counts the runs of n consecutive words in s, keyed by the
+// words joined with a single space. NGramCount(s, 1) is WordCount(s).
+// Stopwords are removed before grouping. An n below 1 yields an empty map.
+func NGramCount(s string, n int, opts ...Option) map[string]int {
+	c := newConfig(opts)
+	m := map[string]int{}
+	if n < 1 {
+		return m
+	}
+	toks := c.tokens(s)
+	for i := 0; i+n <= len(toks); i++ {
+		m[strings.Join(toks[i:i+n], " ")]++
+	}
+	return m
+}
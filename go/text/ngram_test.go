@@ -0,0 +1,31 @@
+package text
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNGramCount(t *testing.T) {
+	s := "The cat sat. The cat ran!"
+	tests := []struct {
+		n    int
+		want map[string]int
+	}{
+		{0, map[string]int{}},
+		{2, map[string]int{"the cat": 2, "cat sat": 1, "sat the": 1, "cat ran": 1}},
+		{3, map[string]int{"the cat sat": 1, "cat sat the": 1, "sat the cat": 1, "the cat ran": 1}},
+		{7, map[string]int{}},
+	}
+	for _, tt := range tests {
+		if got := NGramCount(s, tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("NGramCount(n=%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestNGramCountUnigramsMatchWordCount(t *testing.T) {
+	s := "Go go, gophers! Don't stop."
+	if got, want := NGramCount(s, 1), WordCount(s); !reflect.DeepEqual(got, want) {
+		t.Fatalf("NGramCount(s, 1) = %v, want %v", got, want)
+	}
+}
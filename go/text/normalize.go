@@ -0,0 +1,64 @@
+package text
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalizer rewrites a token before it is counted, so that different
+// spellings of a word are merged. Returning "" drops the token.
+type Normalizer interface {
+	Normalize(tok string) string
+}
+
+// NormalizerFunc adapts an ordinary function to the Normalizer interface.
+type NormalizerFunc func(tok string) string
+
+// Normalize calls f(tok).
+func (f NormalizerFunc) Normalize(tok string) string { return f(tok) }
+
+// Chain returns a Normalizer that applies ns in order, stopping early if
+// one of them drops the token.
+func Chain(ns ...Normalizer) Normalizer {
+	return NormalizerFunc(func(tok string) string {
+		for _, n := range ns {
+			if tok = n.Normalize(tok); tok == "" {
+				break
+			}
+		}
+		return tok
+	})
+}
+
+// Lowercase maps a token to lower case.
+var Lowercase Normalizer = NormalizerFunc(strings.ToLower)
+
+// ASCIIFold strips diacritics and spells out common non-decomposable
+// Latin letters, so "Ærøskøbing" becomes "AEroskobing". Letters from other
+// scripts are left alone.
+var ASCIIFold Normalizer = NormalizerFunc(asciiFold)
+
+var foldSpecial = strings.NewReplacer(
+	"ß", "ss", "æ", "ae", "Æ", "AE", "œ", "oe", "Œ", "OE",
+	"ø", "o", "Ø", "O", "ł", "l", "Ł", "L", "đ", "d", "Đ", "D",
+	"ð", "d", "Ð", "D", "þ", "th", "Þ", "TH", "ı", "i",
+)
+
+func asciiFold(tok string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, tok)
+	if err != nil {
+		folded = tok
+	}
+	return foldSpecial.Replace(folded)
+}
+
+// PorterStemmer reduces lower-case English words to their stem with the
+// Porter (1980) algorithm, so "running", "runs" and "run" all become
+// "run". Tokens containing anything but ASCII letters a-z are returned
+// unchanged.
+var PorterStemmer Normalizer = NormalizerFunc(porterStem)
@@ -0,0 +1,64 @@
+package text
+
+import "testing"
+
+func TestPorterStemmer(t *testing.T) {
+	tests := map[string]string{
+		"caresses": "caress", "ponies": "poni", "ties": "ti", "cats": "cat",
+		"feed": "feed", "agreed": "agre", "plastered": "plaster", "motoring": "motor",
+		"sing": "sing", "hopping": "hop", "falling": "fall", "hissing": "hiss",
+		"filing": "file", "happy": "happi", "sky": "sky", "relational": "relat",
+		"conditional": "condit", "rational": "ration", "digitizer": "digit",
+		"hopefulness": "hope", "goodness": "good", "adjustment": "adjust",
+		"adoption": "adopt", "effective": "effect", "generalizations": "gener",
+		"oscillators": "oscil", "running": "run", "runs": "run", "run": "run",
+		"controlling": "control", "roll": "roll", "go": "go", "naïve": "naïve",
+	}
+	for in, want := range tests {
+		if got := PorterStemmer.Normalize(in); got != want {
+			t.Errorf("stem(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestASCIIFold(t *testing.T) {
+	tests := map[string]string{
+		"café":       "cafe",
+		"Ærøskøbing": "AEroskobing",
+		"straße":     "strasse",
+		"naïve":      "naive",
+		"東京":         "東京",
+		"plain":      "plain",
+	}
+	for in, want := range tests {
+		if got := ASCIIFold.Normalize(in); got != want {
+			t.Errorf("fold(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	drop := NormalizerFunc(func(string) string { return "" })
+	n := Chain(ASCIIFold, Lowercase, PorterStemmer)
+	if got := n.Normalize("Réplacements"); got != "replac" {
+		t.Fatalf("chain = %q, want %q", got, "replac")
+	}
+	called := false
+	after := NormalizerFunc(func(s string) string { called = true; return s })
+	if got := Chain(drop, after).Normalize("x"); got != "" || called {
+		t.Fatalf("chain continued after drop: %q, called=%v", got, called)
+	}
+}
+
+func TestWordCountWithNormalizers(t *testing.T) {
+	s := "Running runs run; Café cafe"
+	c := WordCount(s, WithNormalizers(ASCIIFold, PorterStemmer))
+	if c["run"] != 3 || c["cafe"] != 2 || len(c) != 2 {
+		t.Fatalf("unexpected counts: %v", c)
+	}
+	keep := WithTokenizer(Tokenizer{KeepCase: true})
+	c = WordCount("The RUNNING", keep, WithStopwords(EnglishStopwords), WithNormalizers(Lowercase, PorterStemmer))
+	if c["run"] != 1 || len(c) != 1 {
+		t.Fatalf("unexpected counts: %v", c)
+	}
+}
@@ -0,0 +1,87 @@
+package text
+
+import "example.com/tutorial/progress"
+
+// Option configures the counting functions in this package.
+type Option func(*config)
+
+type config struct {
+	tok       Tokenizer
+	stopwords Stopwords
+	norm      Normalizer
+	progress  *progress.Tracker
+	analyzer  Analyzer
+}
+
+func newConfig(opts []Option) config {
+	var c config
+	for _, o := range opts {
+		o(&c)
+	}
+	return c
+}
+
+// process turns a token normalized by the tokenizer into the word to
+// count, reporting false if it should be dropped. Stopwords are matched
+// before the normalizers run, so lists need not contain stemmed forms.
+func (c *config) process(tok string) (string, bool) {
+	if c.stopwords.Contains(tok) {
+		return "", false
+	}
+	if c.norm != nil {
+		tok = c.norm.Normalize(tok)
+	}
+	return tok, tok != ""
+}
+
+// tokens splits s into the words to count.
+func (c *config) tokens(s string) []string {
+	var words []string
+	c.scan(s, func(w string) { words = append(words, w) })
+	return words
+}
+
+// scan calls fn with each word of s to count, in order.
+func (c *config) scan(s string, fn func(word string)) {
+	if c.analyzer != nil {
+		for _, tok := range c.analyzer.Analyze(s) {
+			if w, ok := c.process(tok); ok {
+				fn(w)
+			}
+		}
+		return
+	}
+	if len(c.stopwords) == 0 && c.norm == nil {
+		c.tok.scan(s, fn) // process would keep every token as it is
+		return
+	}
+	c.tok.scan(s, func(tok string) {
+		if w, ok := c.process(tok); ok {
+			fn(w)
+		}
+	})
+}
+
+// WithTokenizer selects the Tokenizer used to split the input.
+func WithTokenizer(t Tokenizer) Option {
+	return func(c *config) { c.tok = t }
+}
+
+// WithStopwords drops every word in sw from the counts. Pass
+// EnglishStopwords for a sensible default, or a list from LoadStopwords.
+func WithStopwords(sw Stopwords) Option {
+	return func(c *config) { c.stopwords = sw }
+}
+
+// WithNormalizers runs every token through ns, in order, after the
+// tokenizer and stopword filter, e.g. WithNormalizers(ASCIIFold,
+// PorterStemmer) to merge "Café" and "cafe" or "running" and "run".
+func WithNormalizers(ns ...Normalizer) Option {
+	return func(c *config) { c.norm = Chain(ns...) }
+}
+
+// WithProgress feeds t with the files CountFiles finishes and the bytes
+// it reads. The other functions ignore it.
+func WithProgress(t *progress.Tracker) Option {
+	return func(c *config) { c.progress = t }
+}
@@ -0,0 +1,47 @@
+package text
+
+import "example.com/tutorial/set"
+
+// Vocabulary returns the distinct words of a count map, those counted at
+// least once.
+func Vocabulary(counts map[string]int) set.Set[string] {
+	v := set.New[string](len(counts))
+	for w, n := range counts {
+		if n > 0 {
+			v.Add(w)
+		}
+	}
+	return v
+}
+
+// Overlap compares the vocabularies of two documents, A and B. Encoded
+// as JSON, each set is a sorted array of words.
+type Overlap struct {
+	Shared set.Set[string] `json:"shared"`
+	OnlyA  set.Set[string] `json:"only_a"`
+	OnlyB  set.Set[string] `json:"only_b"`
+}
+
+// CompareVocabulary returns the overlap between the vocabularies of two
+// documents, given their word counts. Count both with the same options,
+// or the same word may be spelt differently on each side.
+func CompareVocabulary(a, b map[string]int) Overlap {
+	va, vb := Vocabulary(a), Vocabulary(b)
+	return Overlap{
+		Shared: va.Intersection(vb),
+		OnlyA:  va.Difference(vb),
+		OnlyB:  vb.Difference(va),
+	}
+}
+
+// Jaccard returns the Jaccard similarity of the two vocabularies: the
+// number of shared words over the number of words in either, from 0 for
+// no words in common to 1 for the same vocabulary. Two empty
+// vocabularies have a similarity of 0.
+func (o Overlap) Jaccard() float64 {
+	union := o.Shared.Len() + o.OnlyA.Len() + o.OnlyB.Len()
+	if union == 0 {
+		return 0
+	}
+	return float64(o.Shared.Len()) / float64(union)
+}
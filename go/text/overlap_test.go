@@ -0,0 +1,54 @@
+package text
+
+import (
+	"encoding/json"
+	"testing"
+
+	"example.com/tutorial/set"
+)
+
+func TestVocabulary(t *testing.T) {
+	v := Vocabulary(map[string]int{"go": 2, "rust": 1, "gone": 0})
+	if !v.Equal(set.Of("go", "rust")) {
+		t.Fatalf("Vocabulary = %v", set.Sorted(v))
+	}
+	if v := Vocabulary(nil); v == nil || v.Len() != 0 {
+		t.Fatalf("Vocabulary(nil) = %#v", v)
+	}
+}
+
+func TestCompareVocabulary(t *testing.T) {
+	a := WordCount("gophers love goroutines and channels", WithStopwords(EnglishStopwords))
+	b := WordCount("crabs love ownership and channels", WithStopwords(EnglishStopwords))
+	o := CompareVocabulary(a, b)
+	if !o.Shared.Equal(set.Of("love", "channels")) ||
+		!o.OnlyA.Equal(set.Of("gophers", "goroutines")) ||
+		!o.OnlyB.Equal(set.Of("crabs", "ownership")) {
+		t.Fatalf("overlap = %+v", o)
+	}
+	if j := o.Jaccard(); j != 2.0/6 {
+		t.Fatalf("Jaccard = %v", j)
+	}
+
+	data, err := json.Marshal(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"shared":["channels","love"],"only_a":["gophers","goroutines"],"only_b":["crabs","ownership"]}`
+	if string(data) != want {
+		t.Fatalf("JSON = %s", data)
+	}
+
+	for _, tc := range []struct {
+		a, b map[string]int
+		want float64
+	}{
+		{a, a, 1},
+		{a, nil, 0},
+		{nil, nil, 0},
+	} {
+		if j := CompareVocabulary(tc.a, tc.b).Jaccard(); j != tc.want {
+			t.Errorf("Jaccard(%v, %v) = %v, want %v", tc.a, tc.b, j, tc.want)
+		}
+	}
+}
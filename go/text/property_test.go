@@ -0,0 +1,96 @@
+package text
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"example.com/tutorial/proptest"
+)
+
+// propOptions generates the option sets the properties hold under.
+var propOptions = proptest.PairOf(proptest.Bool(), proptest.Bool())
+
+func options(o proptest.Pair[bool, bool]) []Option {
+	var opts []Option
+	if o.First {
+		opts = append(opts, WithStopwords(EnglishStopwords))
+	}
+	if o.Second {
+		opts = append(opts, WithNormalizers(PorterStemmer))
+	}
+	return opts
+}
+
+// merged adds up the counts of ms.
+func merged(ms ...map[string]int) map[string]int {
+	total := map[string]int{}
+	for _, m := range ms {
+		for w, n := range m {
+			total[w] += n
+		}
+	}
+	return total
+}
+
+func TestPropertyWordCountConcat(t *testing.T) {
+	type input = proptest.Pair[proptest.Pair[string, string], proptest.Pair[bool, bool]]
+	g := proptest.PairOf(proptest.PairOf(proptest.Text(), proptest.Text()), propOptions)
+	proptest.Check(t, g, func(in input) error {
+		a, b, opts := in.First.First, in.First.Second, options(in.Second)
+		got := WordCount(a+" "+b, opts...)
+		if want := merged(WordCount(a, opts...), WordCount(b, opts...)); !maps.Equal(got, want) {
+			return fmt.Errorf("WordCount(a+\" \"+b) = %v, merged counts %v", got, want)
+		}
+		return nil
+	}, proptest.Config{})
+}
+
+func TestPropertyWordCountSumsWords(t *testing.T) {
+	g := proptest.PairOf(proptest.Text(), propOptions)
+	proptest.Check(t, g, func(in proptest.Pair[string, proptest.Pair[bool, bool]]) error {
+		opts := options(in.Second)
+		words := Words(in.First, opts...)
+		if got, want := WordCount(in.First, opts...), merged(countEach(words)); !maps.Equal(got, want) {
+			return fmt.Errorf("WordCount = %v, Words %q", got, words)
+		}
+		return nil
+	}, proptest.Config{})
+}
+
+func countEach(words []string) map[string]int {
+	m := map[string]int{}
+	for _, w := range words {
+		m[w]++
+	}
+	return m
+}
+
+func TestPropertyReaderMatchesString(t *testing.T) {
+	g := proptest.PairOf(proptest.Text(), propOptions)
+	proptest.Check(t, g, func(in proptest.Pair[string, proptest.Pair[bool, bool]]) error {
+		opts := options(in.Second)
+		got, err := WordCountReader(strings.NewReader(in.First), opts...)
+		if err != nil {
+			return err
+		}
+		if want := WordCount(in.First, opts...); !maps.Equal(got, want) {
+			return fmt.Errorf("WordCountReader = %v, WordCount %v", got, want)
+		}
+		return nil
+	}, proptest.Config{})
+}
+
+func TestPropertyWordsRetokenize(t *testing.T) {
+	// Without stemming, which changes words, the words of a text are
+	// their own words.
+	proptest.Check(t, proptest.Text(), func(s string) error {
+		words := Words(s)
+		if again := Words(strings.Join(words, " ")); !slices.Equal(again, words) {
+			return fmt.Errorf("Words = %q, then %q", words, again)
+		}
+		return nil
+	}, proptest.Config{})
+}
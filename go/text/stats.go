@@ -0,0 +1,64 @@
+package text
+
+import (
+	"bytes"
+	"io"
+	"unicode/utf8"
+)
+
+// TextStats summarizes a text. Lines counts newline characters and Chars
+// counts UTF-8 encoded characters, as wc does; the word figures reflect
+// the tokenizer and any filtering options in effect.
+type TextStats struct {
+	Words       int64
+	Lines       int64
+	Bytes       int64
+	Chars       int64
+	UniqueWords int64
+	// AvgWordLen is the mean word length in characters.
+	AvgWordLen float64
+	// UniqueRatio is UniqueWords / Words, the type-token ratio.
+	UniqueRatio float64
+}
+
+// ReadStats computes TextStats in a single pass over r.
+func ReadStats(r io.Reader, opts ...Option) (TextStats, error) {
+	c := newConfig(opts)
+	var st TextStats
+	seen := map[string]struct{}{}
+	var letters int64
+	err := c.scanReader(&tallyReader{r: r, st: &st}, func(w string) {
+		st.Words++
+		letters += int64(utf8.RuneCountInString(w))
+		seen[w] = struct{}{}
+	})
+	if err != nil {
+		return st, err
+	}
+	st.UniqueWords = int64(len(seen))
+	if st.Words > 0 {
+		st.AvgWordLen = float64(letters) / float64(st.Words)
+		st.UniqueRatio = float64(st.UniqueWords) / float64(st.Words)
+	}
+	return st, nil
+}
+
+// tallyReader counts bytes, newlines and UTF-8 characters as they stream
+// past. Characters are counted by their leading byte, so runes split
+// across reads are still counted once.
+type tallyReader struct {
+	r  io.Reader
+	st *TextStats
+}
+
+func (t *tallyReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	t.st.Bytes += int64(n)
+	t.st.Lines += int64(bytes.Count(p[:n], []byte{'\n'}))
+	for _, b := range p[:n] {
+		if b&0xC0 != 0x80 {
+			t.st.Chars++
+		}
+	}
+	return n, err
+}
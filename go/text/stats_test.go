@@ -0,0 +1,49 @@
+package text
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReadStats(t *testing.T) {
+	in := "Héllo hello, world!\nbye\n"
+	st, err := ReadStats(iotest.HalfReader(strings.NewReader(in)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := TextStats{Words: 4, Lines: 2, Bytes: int64(len(in)), Chars: 24, UniqueWords: 4}
+	got := st
+	got.AvgWordLen, got.UniqueRatio = 0, 0
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	if math.Abs(st.AvgWordLen-(5+5+5+3)/4.0) > 1e-9 {
+		t.Fatalf("AvgWordLen = %v", st.AvgWordLen)
+	}
+	if st.UniqueRatio != 1 {
+		t.Fatalf("UniqueRatio = %v", st.UniqueRatio)
+	}
+}
+
+func TestReadStatsRepeats(t *testing.T) {
+	st, err := ReadStats(strings.NewReader("a a a b The"), WithStopwords(EnglishStopwords))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// "a" and "the" are stopwords.
+	if st.Words != 1 || st.UniqueWords != 1 || st.UniqueRatio != 1 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
+
+func TestReadStatsEmpty(t *testing.T) {
+	st, err := ReadStats(strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st != (TextStats{}) {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
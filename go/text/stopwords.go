@@ -0,0 +1,140 @@
+package text
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"example.com/tutorial/set"
+)
+
+// Stopwords is a set of words to leave out of counts. The words are kept
+// in lower case, so that membership is case-insensitive.
+type Stopwords set.Set[string]
+
+// NewStopwords returns a set containing words.
+func NewStopwords(words ...string) Stopwords {
+	s := set.New[string](len(words))
+	for _, w := range words {
+		s.Add(strings.ToLower(w))
+	}
+	return Stopwords(s)
+}
+
+// Contains reports whether w is a stopword.
+func (s Stopwords) Contains(w string) bool {
+	return s.Set().Contains(strings.ToLower(w))
+}
+
+// Set returns the stopwords as a set, for combining lists: for example
+// NewStopwords("gopher").Set().Union(EnglishStopwords.Set()). The set
+// shares s's map.
+func (s Stopwords) Set() set.Set[string] { return set.Set[string](s) }
+
+// ReadStopwords parses a stopword list with one word per line. Blank
+// lines and lines starting with '#' are ignored.
+func ReadStopwords(r io.Reader) (Stopwords, error) {
+	s := set.New[string](0)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		s.Add(strings.ToLower(line))
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return Stopwords(s), nil
+}
+
+// LoadStopwords reads a stopword list from the file at path; see
+// ReadStopwords for the format.
+func LoadStopwords(path string) (Stopwords, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadStopwords(f)
+}
+
+//go:embed stopwords/*.txt
+var stopwordFiles embed.FS
+
+// StopwordFiles returns the built-in stopword lists: english.txt,
+// french.txt, german.txt and spanish.txt, for a program to put files on
+// disk in front of and pass to ReloadStopwords.
+func StopwordFiles() fs.FS {
+	sub, err := fs.Sub(stopwordFiles, "stopwords")
+	if err != nil {
+		panic(err) // the directory is embedded, so this cannot happen
+	}
+	return sub
+}
+
+// EnglishStopwords is a default list of common English function words,
+// read from stopwords/english.txt, which is built into the binary.
+var EnglishStopwords = mustReadStopwords("english.txt")
+
+// FrenchStopwords, GermanStopwords and SpanishStopwords are the like of
+// EnglishStopwords for those languages, read from stopwords/french.txt,
+// german.txt and spanish.txt.
+var (
+	FrenchStopwords  = mustReadStopwords("french.txt")
+	GermanStopwords  = mustReadStopwords("german.txt")
+	SpanishStopwords = mustReadStopwords("spanish.txt")
+)
+
+func mustReadStopwords(name string) Stopwords {
+	s, err := readStopwordsFS(StopwordFiles(), name)
+	if err != nil {
+		panic(err) // the files are embedded, so this cannot happen
+	}
+	return s
+}
+
+func readStopwordsFS(fsys fs.FS, name string) (Stopwords, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadStopwords(f)
+}
+
+// ReloadStopwords replaces the words of EnglishStopwords and the other
+// default lists with those of the files of the same name in fsys, such
+// as StopwordFiles with a directory of lists in front of it, so that
+// everything built from the lists, the analyzers of package analysis
+// included, drops the new words. If a file cannot be read, no list
+// changes. It is not safe to call while words are counted: a program
+// calls it once, at startup.
+func ReloadStopwords(fsys fs.FS) error {
+	lists := map[string]Stopwords{
+		"english.txt": EnglishStopwords,
+		"french.txt":  FrenchStopwords,
+		"german.txt":  GermanStopwords,
+		"spanish.txt": SpanishStopwords,
+	}
+	loaded := make(map[string]Stopwords, len(lists))
+	for name := range lists {
+		sw, err := readStopwordsFS(fsys, name)
+		if err != nil {
+			return fmt.Errorf("text: stopwords: %w", err)
+		}
+		loaded[name] = sw
+	}
+	for name, sw := range lists {
+		clear(sw)
+		for w := range loaded[name] {
+			sw[w] = struct{}{}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package text
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWordCountWithStopwords(t *testing.T) {
+	s := "The cat and the hat"
+	c := WordCount(s, WithStopwords(EnglishStopwords))
+	if c["cat"] != 1 || c["hat"] != 1 || len(c) != 2 {
+		t.Fatalf("unexpected counts: %v", c)
+	}
+	r, err := WordCountReader(strings.NewReader(s), WithStopwords(EnglishStopwords))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 2 {
+		t.Fatalf("unexpected streamed counts: %v", r)
+	}
+	if c := WordCount(s); c["the"] != 2 {
+		t.Fatalf("stopwords applied without option: %v", c)
+	}
+}
+
+func TestStopwordsKeepCase(t *testing.T) {
+	c := WordCount("The Cat", WithTokenizer(Tokenizer{KeepCase: true}), WithStopwords(EnglishStopwords))
+	if c["Cat"] != 1 || len(c) != 1 {
+		t.Fatalf("unexpected counts: %v", c)
+	}
+}
+
+func TestLoadStopwords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stop.txt")
+	if err := os.WriteFile(path, []byte("# custom list\nGo\n\n  gophers  \n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sw, err := LoadStopwords(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sw) != 2 || !sw.Contains("GO") || !sw.Contains("gophers") {
+		t.Fatalf("unexpected stopwords: %v", sw)
+	}
+	if c := WordCount("go gophers rock", WithStopwords(sw)); len(c) != 1 || c["rock"] != 1 {
+		t.Fatalf("unexpected counts: %v", c)
+	}
+	if _, err := LoadStopwords(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Fatal("expected error for missing file")
+	}
+}
+
+func TestNGramCountWithStopwords(t *testing.T) {
+	c := NGramCount("the cat and the hat", 2, WithStopwords(EnglishStopwords))
+	if c["cat hat"] != 1 || len(c) != 1 {
+		t.Fatalf("unexpected counts: %v", c)
+	}
+}
+
+func TestStopwordsSet(t *testing.T) {
+	sw := Stopwords(NewStopwords("Gopher").Set().Union(EnglishStopwords.Set()))
+	if !sw.Contains("GOPHER") || !sw.Contains("the") || len(sw) != len(EnglishStopwords)+1 {
+		t.Fatalf("combined list has %d words", len(sw))
+	}
+	if EnglishStopwords.Contains("gopher") {
+		t.Fatal("Union changed EnglishStopwords")
+	}
+}
+
+func TestLanguageStopwords(t *testing.T) {
+	for _, tc := range []struct {
+		sw         Stopwords
+		stop, word string
+	}{
+		{FrenchStopwords, "Les", "chat"},
+		{GermanStopwords, "und", "Hund"},
+		{SpanishStopwords, "también", "perro"},
+	} {
+		if !tc.sw.Contains(tc.stop) || tc.sw.Contains(tc.word) {
+			t.Errorf("%q or %q misclassified", tc.stop, tc.word)
+		}
+	}
+}
+
+func TestReloadStopwords(t *testing.T) {
+	t.Cleanup(func() {
+		if err := ReloadStopwords(StopwordFiles()); err != nil {
+			t.Fatal(err)
+		}
+	})
+	fsys := fstest.MapFS{"english.txt": {Data: []byte("# ours\ngopher\n")}}
+	for _, name := range []string{"french.txt", "german.txt", "spanish.txt"} {
+		data, err := fs.ReadFile(StopwordFiles(), name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fsys[name] = &fstest.MapFile{Data: data}
+	}
+	if err := ReloadStopwords(fsys); err != nil {
+		t.Fatal(err)
+	}
+	if !EnglishStopwords.Contains("Gopher") || EnglishStopwords.Contains("the") || !FrenchStopwords.Contains("les") {
+		t.Fatalf("reloaded lists: %d English words", EnglishStopwords.Set().Len())
+	}
+
+	// A missing list changes none.
+	delete(fsys, "german.txt")
+	fsys["english.txt"] = &fstest.MapFile{Data: []byte("rust\n")}
+	if err := ReloadStopwords(fsys); err == nil || !strings.Contains(err.Error(), "german.txt") {
+		t.Fatalf("missing list: %v", err)
+	}
+	if !EnglishStopwords.Contains("gopher") || EnglishStopwords.Contains("rust") {
+		t.Errorf("a failed reload changed the English list")
+	}
+}
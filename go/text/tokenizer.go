@@ -0,0 +1,188 @@
+package text
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Tokenizer splits text into words. A word is a run of Unicode letters,
+// digits and combining marks; everything else (spaces, punctuation,
+// symbols) separates words. An apostrophe between two letters is kept, so
+// "don't" is a single word.
+//
+// The zero value lower-cases tokens and performs no Unicode normalization.
+type Tokenizer struct {
+	// NFC normalizes each token to Unicode Normalization Form C, so
+	// precomposed and decomposed spellings of a word count as one.
+	NFC bool
+	// KeepCase disables lower-casing of tokens.
+	KeepCase bool
+}
+
+// Tokens returns the normalized words of s in order. Tokens that
+// normalization leaves as they are share memory with s, as the fields
+// of strings.Fields do.
+func (t Tokenizer) Tokens(s string) []string {
+	var toks []string
+	t.scan(s, func(tok string) { toks = append(toks, tok) })
+	return toks
+}
+
+// scan calls fn with each normalized word of s, finding the same words
+// as Split but scanning the string in place: ASCII bytes are classified
+// by a table lookup, and ASCII tokens are lower-cased in place or, if
+// they are lower case already, passed on as substrings of s without
+// being copied. BenchmarkWordCount compares it with the alternatives.
+func (t Tokenizer) scan(s string, fn func(tok string)) {
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if asciiClass[c] == asciiSep {
+				i++
+				continue
+			}
+		} else if r, w := utf8.DecodeRuneInString(s[i:]); !isWordRune(r) {
+			i += w
+			continue
+		}
+		start := i
+		ascii, upper := true, false
+		prevLetter := false
+		for i < len(s) {
+			c := s[i]
+			if c < utf8.RuneSelf && asciiClass[c] != asciiSep {
+				upper = upper || asciiClass[c] == asciiUpper
+				prevLetter = asciiClass[c] != asciiDigit
+				i++
+				continue
+			}
+			r, w := rune(c), 1
+			if c >= utf8.RuneSelf {
+				r, w = utf8.DecodeRuneInString(s[i:])
+			}
+			if isWordRune(r) {
+				ascii = false
+				prevLetter = unicode.IsLetter(r)
+				i += w
+				continue
+			}
+			if isApostrophe(r) && prevLetter && i+w < len(s) {
+				if nr, _ := utf8.DecodeRuneInString(s[i+w:]); isLetter(nr) {
+					ascii = ascii && c == '\''
+					prevLetter = false
+					i += w
+					continue
+				}
+			}
+			break
+		}
+		switch tok := s[start:i]; {
+		case !ascii:
+			fn(t.Normalize(tok))
+		case upper && !t.KeepCase:
+			fn(strings.ToLower(tok))
+		default:
+			fn(tok) // NFC and the apostrophes leave ASCII as it is
+		}
+	}
+}
+
+// Classes of ASCII bytes for scan.
+const (
+	asciiSep = iota
+	asciiLower
+	asciiUpper
+	asciiDigit
+)
+
+var asciiClass = func() (class [utf8.RuneSelf]uint8) {
+	for c := 'a'; c <= 'z'; c++ {
+		class[c] = asciiLower
+		class[c-'a'+'A'] = asciiUpper
+	}
+	for c := '0'; c <= '9'; c++ {
+		class[c] = asciiDigit
+	}
+	return class
+}()
+
+// Normalize applies the tokenizer's case and Unicode normalization to a
+// raw token produced by Split. Typographic apostrophes become ASCII ones.
+func (t Tokenizer) Normalize(tok string) string {
+	tok = strings.ReplaceAll(tok, "’", "'")
+	if t.NFC {
+		tok = norm.NFC.String(tok)
+	}
+	if !t.KeepCase {
+		tok = strings.ToLower(tok)
+	}
+	return tok
+}
+
+// Split is a bufio.SplitFunc that yields raw, un-normalized words. Pass
+// each token through Normalize before counting it.
+func (t Tokenizer) Split(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	start := 0
+	for start < len(data) {
+		r, w := utf8.DecodeRune(data[start:])
+		if isWordRune(r) {
+			break
+		}
+		if r == utf8.RuneError && w == 1 && !atEOF && !utf8.FullRune(data[start:]) {
+			return start, nil, nil
+		}
+		start += w
+	}
+	if start == len(data) {
+		return start, nil, nil
+	}
+	prevLetter := false
+	for i := start; i < len(data); {
+		if !atEOF && !utf8.FullRune(data[i:]) {
+			break
+		}
+		r, w := utf8.DecodeRune(data[i:])
+		switch {
+		case isWordRune(r):
+			prevLetter = isLetter(r)
+		case isApostrophe(r) && prevLetter:
+			next := i + w
+			if !atEOF && !utf8.FullRune(data[next:]) {
+				return start, nil, nil
+			}
+			if nr, _ := utf8.DecodeRune(data[next:]); next < len(data) && isLetter(nr) {
+				prevLetter = false
+				break
+			}
+			return i + w, data[start:i], nil
+		default:
+			return i + w, data[start:i], nil
+		}
+		i += w
+	}
+	if atEOF {
+		return len(data), data[start:], nil
+	}
+	// Request more data, discarding the separators we already skipped.
+	return start, nil, nil
+}
+
+func isWordRune(r rune) bool {
+	if r < utf8.RuneSelf {
+		return asciiClass[r] != asciiSep
+	}
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+func isLetter(r rune) bool {
+	if r < utf8.RuneSelf {
+		return asciiClass[r] == asciiLower || asciiClass[r] == asciiUpper
+	}
+	return unicode.IsLetter(r)
+}
+
+func isApostrophe(r rune) bool {
+	return r == '\'' || r == '’'
+}
@@ -0,0 +1,145 @@
+package text
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf8"
+)
+
+func TestTokenizerTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		tok  Tokenizer
+		in   string
+		want []string
+	}{
+		{"punctuation", Tokenizer{}, "Go, go... gophers!", []string{"go", "go", "gophers"}},
+		{"apostrophe", Tokenizer{}, "don't 'quote' rock’n’roll", []string{"don't", "quote", "rock'n'roll"}},
+		{"trailing apostrophe", Tokenizer{}, "gophers' den", []string{"gophers", "den"}},
+		{"hyphen splits", Tokenizer{}, "well-known", []string{"well", "known"}},
+		{"digits", Tokenizer{}, "go1.21 in 2023", []string{"go1", "21", "in", "2023"}},
+		{"non-ascii", Tokenizer{}, "Ärger über Straße — 東京 Москва", []string{"ärger", "über", "straße", "東京", "москва"}},
+		{"keep case", Tokenizer{KeepCase: true}, "Hello World", []string{"Hello", "World"}},
+		{"nfc", Tokenizer{NFC: true}, "cafe\u0301 caf\u00e9", []string{"caf\u00e9", "caf\u00e9"}},
+		{"no nfc", Tokenizer{}, "cafe\u0301", []string{"cafe\u0301"}},
+		{"empty", Tokenizer{}, " \t!? ", nil},
+		{"empty string", Tokenizer{}, "", nil},
+
+		// Unicode.
+		{"greek final sigma", Tokenizer{}, "ΟΔΟΣ", []string{"οδοσ"}},
+		{"turkish dotted i", Tokenizer{}, "İstanbul", []string{"istanbul"}},
+		{"hangul and kana", Tokenizer{}, "한국어 カタカナ", []string{"한국어", "カタカナ"}},
+		{"arabic", Tokenizer{}, "مرحبا بالعالم", []string{"مرحبا", "بالعالم"}},
+		{"devanagari marks", Tokenizer{}, "नमस्ते दुनिया", []string{"नमस्ते", "दुनिया"}},
+		{"leading combining mark", Tokenizer{}, "\u0301a", []string{"\u0301a"}},
+		{"nfc composes", Tokenizer{NFC: true}, "A\u030a", []string{"\u00e5"}},
+		{"non-latin digits", Tokenizer{}, "٣ ४२", []string{"٣", "४२"}},
+		{"emoji separate", Tokenizer{}, "go🚀fast 👍🏽", []string{"go", "fast"}},
+		{"zero-width joiner", Tokenizer{}, "a\u200db", []string{"a", "b"}},
+		{"no-break space", Tokenizer{}, "a\u00a0b\u2003c", []string{"a", "b", "c"}},
+		{"invalid utf-8", Tokenizer{}, "ab\xffcd \xc3", []string{"ab", "cd"}},
+		{"replacement char", Tokenizer{}, "a\ufffdb", []string{"a", "b"}},
+
+		// Punctuation.
+		{"curly quotes", Tokenizer{}, "“Quoted” ‘single’", []string{"quoted", "single"}},
+		{"dashes", Tokenizer{}, "en–dash em—dash", []string{"en", "dash", "em", "dash"}},
+		{"underscore splits", Tokenizer{}, "snake_case", []string{"snake", "case"}},
+		{"symbols split", Tokenizer{}, "a@b.c #tag $5 50%", []string{"a", "b", "c", "tag", "5", "50"}},
+		{"apostrophe after digit", Tokenizer{}, "90's", []string{"90", "s"}},
+		{"apostrophe before digit", Tokenizer{}, "o'9", []string{"o", "9"}},
+		{"double apostrophe", Tokenizer{}, "a''b", []string{"a", "b"}},
+		{"lone apostrophes", Tokenizer{}, "' ’ ''", nil},
+		{"apostrophe at end", Tokenizer{}, "dogs'", []string{"dogs"}},
+		{"typographic apostrophe", Tokenizer{KeepCase: true}, "It’s", []string{"It's"}},
+		{"brackets", Tokenizer{}, "(a)[b]{c}<d>", []string{"a", "b", "c", "d"}},
+
+		// Huge inputs, which Tokens takes whole.
+		{"long word", Tokenizer{}, strings.Repeat("A", 1<<17), []string{strings.Repeat("a", 1<<17)}},
+		{"long separator run", Tokenizer{}, "x" + strings.Repeat(" .", 1<<16) + "y", []string{"x", "y"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.tok.Tokens(tt.in)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Tokens(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenizerSplitStreaming(t *testing.T) {
+	for _, in := range []string{
+		"Ärger, don't cafe\u0301 東京! end",
+		"rock’n’roll it’s",
+		"ab\xffcd \xe6\x9d\xb1\xe4 \xc3",
+		"trailing apostrophe'",
+		"x" + strings.Repeat(" 😀", 1000) + " y",
+	} {
+		tok := Tokenizer{NFC: true}
+		got, err := streamTokens(tok, iotest.OneByteReader(strings.NewReader(in)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := tok.Tokens(in); !reflect.DeepEqual(got, want) {
+			t.Errorf("streamed %q, want %q", got, want)
+		}
+	}
+}
+
+// streamTokens tokenizes r with tok.Split through a bufio.Scanner, as
+// ScanWords does.
+func streamTokens(tok Tokenizer, r io.Reader) ([]string, error) {
+	sc := bufio.NewScanner(r)
+	sc.Split(tok.Split)
+	var toks []string
+	for sc.Scan() {
+		toks = append(toks, tok.Normalize(sc.Text()))
+	}
+	return toks, sc.Err()
+}
+
+func FuzzTokenizer(f *testing.F) {
+	for _, s := range []string{
+		"", "Go, go... gophers!", "don't 'quote' rock’n’roll", "cafe\u0301 caf\u00e9",
+		"Ärger über Straße — 東京 Москва", "ab\xffcd \xc3", "İ ΟΔΟΣ", "a''b o'9 dogs'",
+	} {
+		f.Add(s, false, false)
+	}
+	f.Add("Hello World", true, true)
+	f.Fuzz(func(t *testing.T, s string, nfc, keepCase bool) {
+		tok := Tokenizer{NFC: nfc, KeepCase: keepCase}
+		toks := tok.Tokens(s)
+		for _, w := range toks {
+			if w == "" {
+				t.Fatalf("Tokens(%q) has an empty token: %q", s, toks)
+			}
+			if !utf8.ValidString(w) {
+				t.Fatalf("Tokens(%q) has invalid UTF-8 %q", s, w)
+			}
+			if strings.HasPrefix(w, "'") || strings.HasSuffix(w, "'") {
+				t.Fatalf("Tokens(%q) has %q, with an apostrophe at an end", s, w)
+			}
+			for _, r := range w {
+				if !isWordRune(r) && r != '\'' {
+					t.Fatalf("Tokens(%q) has %q, with separator %q", s, w, r)
+				}
+			}
+		}
+		// Splitting a byte at a time finds the same tokens.
+		streamed, err := streamTokens(tok, iotest.OneByteReader(strings.NewReader(s)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(streamed, toks) {
+			t.Fatalf("streaming %q gave %q, Tokens %q", s, streamed, toks)
+		}
+		// The tokens joined by spaces are themselves.
+		if again := tok.Tokens(strings.Join(toks, " ")); !reflect.DeepEqual(again, toks) {
+			t.Fatalf("Tokens(%q) = %q, but that joined gives %q", s, toks, again)
+		}
+	})
+}
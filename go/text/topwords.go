@@ -0,0 +1,89 @@
+package text
+
+import (
+	"sort"
+
+	"example.com/tutorial/orderedmap"
+	"example.com/tutorial/pqueue"
+)
+
+// WordFreq is a word together with its occurrence count.
+type WordFreq struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+}
+
+// Summary is the word count of a document: its number of words and of
+// distinct words, and its most frequent words as TopWords lists them.
+type Summary struct {
+	Words  int        `json:"words"`
+	Unique int        `json:"unique"`
+	Top    []WordFreq `json:"top"`
+}
+
+// TopWords returns the n most frequent words in counts, ordered by count
+// descending and then alphabetically, so equal counts always come out in
+// the same order. A negative n returns every word. For an n smaller than
+// the vocabulary, the words are ranked through a priority queue of the
+// best n seen so far, in O(len(counts) log n) rather than sorting them
+// all.
+func TopWords(counts map[string]int, n int) []WordFreq {
+	if n >= len(counts) {
+		n = -1
+	}
+	return TopWordsFunc(func(yield func(WordFreq)) {
+		for w, c := range counts {
+			yield(WordFreq{Word: w, Count: c})
+		}
+	}, n)
+}
+
+// TopWordsFunc is TopWords for counts that are not in a map, such as
+// those merged from files: each calls yield once for every word, and
+// TopWordsFunc keeps no more than n of them at a time. A negative n
+// keeps and returns them all.
+func TopWordsFunc(each func(yield func(WordFreq)), n int) []WordFreq {
+	if n < 0 {
+		freqs := []WordFreq{}
+		each(func(wf WordFreq) { freqs = append(freqs, wf) })
+		sort.Slice(freqs, func(i, j int) bool { return RanksBefore(freqs[i], freqs[j]) })
+		return freqs
+	}
+	// The first in the queue is the worst of the best n, the one for a
+	// better word to replace.
+	q := pqueue.New(func(a, b WordFreq) bool { return RanksBefore(b, a) })
+	each(func(wf WordFreq) {
+		if q.Len() < n {
+			q.Push(wf)
+		} else if worst, ok := q.Peek(); ok && RanksBefore(wf, worst) {
+			q.Pop()
+			q.Push(wf)
+		}
+	})
+	freqs := make([]WordFreq, q.Len())
+	for i := len(freqs) - 1; i >= 0; i-- {
+		freqs[i], _ = q.Pop()
+	}
+	return freqs
+}
+
+// RanksBefore reports whether a comes before b in TopWords' order.
+func RanksBefore(a, b WordFreq) bool {
+	if a.Count != b.Count {
+		return a.Count > b.Count
+	}
+	return a.Word < b.Word
+}
+
+// FrequencyMap returns freqs as a map from word to count that keeps
+// their order, as TopWords ranks them. It encodes as a JSON object such
+// as {"the":3,"cat":1} in that order, where a map[string]int would come
+// out alphabetically. A word given twice keeps its first place and its
+// last count.
+func FrequencyMap(freqs []WordFreq) *orderedmap.Map[string, int] {
+	m := orderedmap.New[string, int]()
+	for _, wf := range freqs {
+		m.Set(wf.Word, wf.Count)
+	}
+	return m
+}
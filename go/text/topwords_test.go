@@ -0,0 +1,77 @@
+package text
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestTopWords(t *testing.T) {
+	counts := map[string]int{"go": 3, "gopher": 1, "and": 3, "zebra": 1, "yak": 2}
+	tests := []struct {
+		n    int
+		want []WordFreq
+	}{
+		{0, []WordFreq{}},
+		{2, []WordFreq{{"and", 3}, {"go", 3}}},
+		{4, []WordFreq{{"and", 3}, {"go", 3}, {"yak", 2}, {"gopher", 1}}},
+		{10, []WordFreq{{"and", 3}, {"go", 3}, {"yak", 2}, {"gopher", 1}, {"zebra", 1}}},
+		{-1, []WordFreq{{"and", 3}, {"go", 3}, {"yak", 2}, {"gopher", 1}, {"zebra", 1}}},
+	}
+	for _, tt := range tests {
+		if got := TopWords(counts, tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("TopWords(n=%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestTopWordsFunc(t *testing.T) {
+	sorted := []WordFreq{{"and", 3}, {"go", 3}, {"gopher", 1}, {"yak", 2}, {"zebra", 1}}
+	each := func(yield func(WordFreq)) {
+		for _, wf := range sorted {
+			yield(wf)
+		}
+	}
+	if got, want := TopWordsFunc(each, 3), []WordFreq{{"and", 3}, {"go", 3}, {"yak", 2}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("n=3: %v, want %v", got, want)
+	}
+	if got := TopWordsFunc(each, -1); len(got) != 5 || got[4] != (WordFreq{"zebra", 1}) {
+		t.Errorf("n=-1: %v", got)
+	}
+	if got := TopWordsFunc(func(func(WordFreq)) {}, -1); got == nil || len(got) != 0 {
+		t.Errorf("no words: %#v, want an empty slice", got)
+	}
+}
+
+func TestTopWordsStable(t *testing.T) {
+	counts := map[string]int{}
+	for _, w := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		counts[w] = 1
+	}
+	first := TopWords(counts, 3)
+	for i := 0; i < 50; i++ {
+		if got := TopWords(counts, 3); !reflect.DeepEqual(got, first) {
+			t.Fatalf("ordering changed between calls: %v vs %v", got, first)
+		}
+	}
+}
+
+func TestFrequencyMap(t *testing.T) {
+	counts := map[string]int{"cat": 1, "the": 3, "mat": 1, "sat": 2}
+	for i := 0; i < 5; i++ { // a map would be encoded alphabetically
+		data, err := json.Marshal(FrequencyMap(TopWords(counts, -1)))
+		if err != nil || string(data) != `{"the":3,"sat":2,"cat":1,"mat":1}` {
+			t.Fatalf("JSON = %s, %v", data, err)
+		}
+	}
+	m := FrequencyMap([]WordFreq{{"a", 2}, {"b", 1}, {"a", 5}})
+	if got := m.Keys(); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("Keys = %q", got)
+	}
+	if n, _ := m.Get("a"); n != 5 {
+		t.Fatalf("a = %d", n)
+	}
+	if m := FrequencyMap(nil); m.Len() != 0 {
+		t.Fatalf("FrequencyMap(nil) has %d words", m.Len())
+	}
+}
@@ -0,0 +1,40 @@
+package text
+
+import (
+	"io"
+)
+
+// WordCount returns how often each word occurs in s. It scans s in
+// place, without the copies WordCountReader makes, so its words may
+// share memory with s.
+func WordCount(s string, opts ...Option) map[string]int {
+	c := newConfig(opts)
+	m := map[string]int{}
+	c.scan(s, func(w string) { m[w]++ })
+	return m
+}
+
+// WordCountReader counts words like WordCount but reads r incrementally,
+// so memory use is bounded by the vocabulary rather than the input size.
+func WordCountReader(r io.Reader, opts ...Option) (map[string]int, error) {
+	m := map[string]int{}
+	if err := ScanWords(r, func(w string) { m[w]++ }, opts...); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ScanWords calls fn for each word read from r, in order, applying the
+// same processing as Words without holding the whole input in memory.
+func ScanWords(r io.Reader, fn func(word string), opts ...Option) error {
+	c := newConfig(opts)
+	return c.scanReader(r, fn)
+}
+
+// Words returns the words of s in order, after the tokenizer, stopword and
+// normalizer options have been applied. It is the token stream that
+// WordCount counts.
+func Words(s string, opts ...Option) []string {
+	c := newConfig(opts)
+	return c.tokens(s)
+}
@@ -0,0 +1,241 @@
+package text
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestWordCount(t *testing.T) {
+	c := WordCount("Hello hello world")
+	if c["hello"] != 2 || c["world"] != 1 {
+		t.Fatalf("unexpected counts: %#v", c)
+	}
+}
+
+func TestWordCountReader(t *testing.T) {
+	c, err := WordCountReader(strings.NewReader("Hello hello\nworld\t"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c["hello"] != 2 || c["world"] != 1 || len(c) != 2 {
+		t.Fatalf("unexpected counts: %#v", c)
+	}
+}
+
+// repeatReader yields pattern over and over until n bytes have been read,
+// without ever holding more than one copy of it in memory.
+type repeatReader struct {
+	pattern []byte
+	n       int64
+	off     int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	if r.n <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.n {
+		p = p[:r.n]
+	}
+	n := 0
+	for n < len(p) {
+		c := copy(p[n:], r.pattern[r.off:])
+		n += c
+		r.off = (r.off + c) % len(r.pattern)
+	}
+	r.n -= int64(n)
+	return n, nil
+}
+
+func TestWordCountReaderLargeInput(t *testing.T) {
+	const reps = 1 << 20
+	pattern := []byte("the Quick brown fox ")
+	r := &repeatReader{pattern: pattern, n: int64(len(pattern)) * reps}
+	c, err := WordCountReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range []string{"the", "quick", "brown", "fox"} {
+		if c[w] != reps {
+			t.Fatalf("count[%q] = %d, want %d", w, c[w], reps)
+		}
+	}
+	if len(c) != 4 {
+		t.Fatalf("unexpected vocabulary: %#v", c)
+	}
+}
+
+func TestWordCountReaderMatchesWordCount(t *testing.T) {
+	s := strings.Repeat("Go go gophers and more Gophers\n", 1000)
+	got, err := WordCountReader(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := WordCount(s)
+	if len(got) != len(want) {
+		t.Fatalf("got %#v, want %#v", got, want)
+	}
+	for w, n := range want {
+		if got[w] != n {
+			t.Fatalf("count[%q] = %d, want %d", w, got[w], n)
+		}
+	}
+}
+
+func TestWordCountPunctuation(t *testing.T) {
+	c := WordCount("Go go gophers! Gophers?")
+	if c["go"] != 2 || c["gophers"] != 2 || len(c) != 2 {
+		t.Fatalf("unexpected counts: %#v", c)
+	}
+}
+
+func TestWordCountWithTokenizer(t *testing.T) {
+	c := WordCount("Go go", WithTokenizer(Tokenizer{KeepCase: true}))
+	if c["Go"] != 1 || c["go"] != 1 {
+		t.Fatalf("unexpected counts: %#v", c)
+	}
+}
+
+func TestWords(t *testing.T) {
+	got := Words("The cats, the hats!", WithStopwords(EnglishStopwords), WithNormalizers(PorterStemmer))
+	if len(got) != 2 || got[0] != "cat" || got[1] != "hat" {
+		t.Fatalf("Words = %q", got)
+	}
+}
+
+func TestWordCountTable(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		opts []Option
+		want map[string]int
+	}{
+		{"empty", "", nil, map[string]int{}},
+		{"only punctuation", "... --- !!! ???", nil, map[string]int{}},
+		{"case folds", "Go GO go gO", nil, map[string]int{"go": 4}},
+		{"apostrophes", "don't Don’t dont", nil, map[string]int{"don't": 2, "dont": 1}},
+		{"unicode case", "ÄRGER ärger Ärger", nil, map[string]int{"ärger": 3}},
+		{"nfc merges", "café café", []Option{WithTokenizer(Tokenizer{NFC: true})}, map[string]int{"caf\u00e9": 2}},
+		{"no nfc", "café café", nil, map[string]int{"café": 1, "café": 1}},
+		{"cjk runs", "東京 東京, 大阪", nil, map[string]int{"東京": 2, "大阪": 1}},
+		{"emoji dropped", "👍 ok 👍🏽 ok", nil, map[string]int{"ok": 2}},
+		{"invalid utf-8", "\xffok\xfe ok\xc3", nil, map[string]int{"ok": 2}},
+		{"newlines and tabs", "a\nb\r\nc\td\va\fb", nil, map[string]int{"a": 2, "b": 2, "c": 1, "d": 1}},
+		{"stopwords", "the cat and the hat", []Option{WithStopwords(EnglishStopwords)}, map[string]int{"cat": 1, "hat": 1}},
+		{"stemmed", "running runs ran", []Option{WithNormalizers(PorterStemmer)}, map[string]int{"run": 2, "ran": 1}},
+		{"many distinct", distinctWords(10000), nil, countsOfDistinct(10000)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WordCount(tt.in, tt.opts...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WordCount = %v, want %v", got, tt.want)
+			}
+			got, err := WordCountReader(iotest.HalfReader(strings.NewReader(tt.in)), tt.opts...)
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WordCountReader = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+// distinctWords returns n distinct words, w0 to wn-1, separated by
+// spaces.
+func distinctWords(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "w%d ", i)
+	}
+	return b.String()
+}
+
+func countsOfDistinct(n int) map[string]int {
+	m := make(map[string]int, n)
+	for i := 0; i < n; i++ {
+		m[fmt.Sprintf("w%d", i)] = 1
+	}
+	return m
+}
+
+func TestWordCountReaderHugeInputs(t *testing.T) {
+	// A single line of a million words, with no newline to split on.
+	r := &repeatReader{pattern: []byte("Zürich’s café, "), n: int64(len("Zürich’s café, ")) * 1 << 20}
+	c, err := WordCountReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c["zürich's"] != 1<<20 || c["café"] != 1<<20 || len(c) != 2 {
+		t.Fatalf("unexpected counts: %v", c)
+	}
+
+	// A word of 60 KiB, within bufio.MaxScanTokenSize.
+	long := strings.Repeat("ab", 30<<10)
+	c, err = WordCountReader(strings.NewReader("x " + long + " x"))
+	if err != nil || c[long] != 1 || c["x"] != 2 {
+		t.Fatalf("long word: %d words, %v", len(c), err)
+	}
+
+	// Longer words are refused rather than split or buffered without
+	// bound.
+	if _, err := WordCountReader(strings.NewReader(strings.Repeat("a", bufio.MaxScanTokenSize+1))); !errors.Is(err, bufio.ErrTooLong) {
+		t.Fatalf("oversized word: %v", err)
+	}
+}
+
+func FuzzWordCount(f *testing.F) {
+	for _, s := range []string{
+		"", "Hello hello world", "Go, go... gophers!", "don't Don’t dont",
+		"café café", "東京 東京, 大阪", "\xffok\xfe ok\xc3", "the cat and the hat",
+	} {
+		f.Add(s, false, false)
+	}
+	f.Add("The cats, the hats!", true, true)
+	f.Fuzz(func(t *testing.T, s string, stopwords, stem bool) {
+		var opts []Option
+		if stopwords {
+			opts = append(opts, WithStopwords(EnglishStopwords))
+		}
+		if stem {
+			opts = append(opts, WithNormalizers(PorterStemmer))
+		}
+		words := Words(s, opts...)
+		counts := WordCount(s, opts...)
+		sum := 0
+		for w, n := range counts {
+			if w == "" {
+				t.Fatalf("WordCount(%q) counts the empty word", s)
+			}
+			if n <= 0 {
+				t.Fatalf("WordCount(%q)[%q] = %d", s, w, n)
+			}
+			sum += n
+		}
+		if sum != len(words) {
+			t.Fatalf("WordCount(%q) sums to %d, but Words has %d", s, sum, len(words))
+		}
+		for _, w := range words {
+			if counts[w] == 0 {
+				t.Fatalf("Words(%q) has %q, which WordCount does not count", s, w)
+			}
+		}
+		streamed, err := WordCountReader(iotest.OneByteReader(strings.NewReader(s)), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(streamed, counts) {
+			t.Fatalf("WordCountReader(%q) = %v, WordCount %v", s, streamed, counts)
+		}
+		st, err := ReadStats(strings.NewReader(s), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.Words != int64(sum) || st.UniqueWords != int64(len(counts)) || st.Bytes != int64(len(s)) {
+			t.Fatalf("ReadStats(%q) = %+v, want %d words, %d distinct", s, st, sum, len(counts))
+		}
+	})
+}
@@ -0,0 +1,95 @@
+// Package textutil provides fuzzy string matching helpers for suggesting
+// corrections against a vocabulary such as the keys of text.WordCount.
+package textutil
+
+import "sort"
+
+// Levenshtein returns the edit distance between a and b: the minimum
+// number of single-rune insertions, deletions and substitutions needed to
+// turn one into the other.
+func Levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) < len(rb) {
+		ra, rb = rb, ra
+	}
+	// Two rows of the DP table suffice; keep them as long as the shorter word.
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// PrefixSimilarity returns the length of the common prefix of a and b as a
+// fraction of the longer string, in runes. Two empty strings are
+// identical and score 1.
+func PrefixSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	n := 0
+	for n < len(ra) && n < len(rb) && ra[n] == rb[n] {
+		n++
+	}
+	return ratio(n, max(len(ra), len(rb)))
+}
+
+// SuffixSimilarity is like PrefixSimilarity for the common suffix.
+func SuffixSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	n := 0
+	for n < len(ra) && n < len(rb) && ra[len(ra)-1-n] == rb[len(rb)-1-n] {
+		n++
+	}
+	return ratio(n, max(len(ra), len(rb)))
+}
+
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 1
+	}
+	return float64(n) / float64(d)
+}
+
+// FindClosest returns the vocabulary word with the smallest edit distance
+// to word, together with that distance. Ties go to the candidate sharing
+// the longer prefix with word, then to the alphabetically first, so the
+// result does not depend on the order of vocab. An empty vocab yields ""
+// and -1.
+func FindClosest(word string, vocab []string) (string, int) {
+	best, bestDist, bestPrefix := "", -1, 0.0
+	for _, v := range vocab {
+		d := Levenshtein(word, v)
+		p := PrefixSimilarity(word, v)
+		switch {
+		case bestDist < 0, d < bestDist:
+		case d == bestDist && p > bestPrefix:
+		case d == bestDist && p == bestPrefix && v < best:
+		default:
+			continue
+		}
+		best, bestDist, bestPrefix = v, d, p
+	}
+	return best, bestDist
+}
+
+// Vocabulary returns the words of a count map in sorted order, ready to
+// pass to FindClosest.
+func Vocabulary(counts map[string]int) []string {
+	words := make([]string, 0, len(counts))
+	for w := range counts {
+		words = append(words, w)
+	}
+	sort.Strings(words)
+	return words
+}
@@ -0,0 +1,80 @@
+package textutil
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"example.com/tutorial/text"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"gopher", "gopher", 0},
+		{"straße", "strasse", 2},
+		{"日本語", "日本", 1},
+	}
+	for _, tt := range tests {
+		if got := Levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("Levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := Levenshtein(tt.b, tt.a); got != tt.want {
+			t.Errorf("Levenshtein(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestAffixSimilarity(t *testing.T) {
+	approx := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+	if got := PrefixSimilarity("gopher", "gophers"); !approx(got, 6.0/7) {
+		t.Errorf("PrefixSimilarity = %v", got)
+	}
+	if got := PrefixSimilarity("", ""); got != 1 {
+		t.Errorf("PrefixSimilarity of empty strings = %v", got)
+	}
+	if got := SuffixSimilarity("running", "jumping"); !approx(got, 3.0/7) {
+		t.Errorf("SuffixSimilarity = %v", got)
+	}
+	if got := SuffixSimilarity("abc", "xyz"); got != 0 {
+		t.Errorf("SuffixSimilarity = %v", got)
+	}
+}
+
+func TestFindClosest(t *testing.T) {
+	vocab := Vocabulary(text.WordCount("the quick brown fox jumps over the lazy dog"))
+	if !reflect.DeepEqual(vocab, []string{"brown", "dog", "fox", "jumps", "lazy", "over", "quick", "the"}) {
+		t.Fatalf("Vocabulary = %v", vocab)
+	}
+	tests := []struct {
+		word string
+		want string
+		dist int
+	}{
+		{"quikc", "quick", 2},
+		{"brwn", "brown", 1},
+		{"jumps", "jumps", 0},
+		{"dox", "dog", 1}, // ties with "fox"; "dog" shares the longer prefix
+	}
+	for _, tt := range tests {
+		got, d := FindClosest(tt.word, vocab)
+		if got != tt.want || d != tt.dist {
+			t.Errorf("FindClosest(%q) = %q, %d; want %q, %d", tt.word, got, d, tt.want, tt.dist)
+		}
+	}
+	if got, d := FindClosest("x", nil); got != "" || d != -1 {
+		t.Errorf("FindClosest on empty vocab = %q, %d", got, d)
+	}
+	// Order of the vocabulary must not matter.
+	a, _ := FindClosest("cat", []string{"bat", "hat"})
+	b, _ := FindClosest("cat", []string{"hat", "bat"})
+	if a != "bat" || b != "bat" {
+		t.Errorf("tie-break depends on order: %q vs %q", a, b)
+	}
+}
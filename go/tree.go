@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"example.com/tutorial/fsutil"
+)
+
+// patternList is a flag that may be repeated, or given comma-separated
+// patterns, collecting all of them.
+type patternList []string
+
+func (p *patternList) String() string { return strings.Join(*p, ",") }
+
+func (p *patternList) Set(s string) error {
+	*p = append(*p, strings.Split(s, ",")...)
+	return nil
+}
+
+// runTree implements the "tree" subcommand and returns the process exit
+// code. It lists the current directory when given no argument.
+func runTree(cfg treeSettings, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("tree", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tree [-depth n] [-include glob] [-exclude glob] [-s] [-json] [dir]")
+		fs.PrintDefaults()
+	}
+	opts := fsutil.TreeOptions{Include: slices.Clone(cfg.Include), Exclude: slices.Clone(cfg.Exclude)}
+	fs.IntVar(&opts.MaxDepth, "depth", cfg.Depth, "descend at most `n` levels, 0 for no limit")
+	fs.Var((*patternList)(&opts.Include), "include", "list only files matching `glob` (repeatable)")
+	fs.Var((*patternList)(&opts.Exclude), "exclude", "skip files and directories matching `glob` (repeatable)")
+	sizes := fs.Bool("s", cfg.Sizes, "print human-readable sizes; directories show the total of their files")
+	asJSON := fs.Bool("json", false, "print the tree as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+	n, err := fsutil.Tree(os.DirFS(dir), ".", opts)
+	if err != nil {
+		fmt.Fprintln(stderr, "tree:", err)
+		return 1
+	}
+	n.Name = dir
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(n)
+	} else {
+		err = n.WriteText(stdout, *sizes)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "tree:", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"example.com/tutorial/fsutil"
+)
+
+func treeDir(t *testing.T) string {
+	t.Helper()
+	files := map[string]string{}
+	for name, size := range map[string]int{"a.go": 10, "b.txt": 2000, "sub/c.go": 5, "skip/d.go": 1} {
+		files[name] = strings.Repeat("x", size)
+	}
+	return writeTree(t, files)
+}
+
+func TestRunTree(t *testing.T) {
+	dir := treeDir(t)
+	var out, errOut bytes.Buffer
+	code := runTree(defaultSettings().Tree, []string{"-include", "*.go", "-exclude", "skip", "-s", dir}, &out, &errOut)
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+	want := "[    15]  " + dir + `
+├── [    10]  a.go
+└── [     5]  sub
+    └── [     5]  c.go
+
+1 directory, 2 files
+`
+	if got := out.String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRunTreeJSON(t *testing.T) {
+	dir := treeDir(t)
+	var out, errOut bytes.Buffer
+	if code := runTree(defaultSettings().Tree, []string{"-json", "-depth", "1", dir}, &out, &errOut); code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+	var n fsutil.TreeNode
+	if err := json.Unmarshal(out.Bytes(), &n); err != nil {
+		t.Fatal(err)
+	}
+	if n.Name != dir || !n.Dir || len(n.Children) != 4 || n.Size != 2010 {
+		t.Fatalf("tree %+v", n)
+	}
+	for _, c := range n.Children {
+		if c.Dir && len(c.Children) != 0 {
+			t.Fatalf("%s listed beyond -depth 1", c.Name)
+		}
+	}
+}
+
+func TestRunTreeErrors(t *testing.T) {
+	var out, errOut bytes.Buffer
+	if code := runTree(defaultSettings().Tree, []string{filepath.Join(t.TempDir(), "missing")}, &out, &errOut); code != 1 {
+		t.Fatalf("missing dir: exit %d", code)
+	}
+	if code := runTree(defaultSettings().Tree, []string{"a", "b"}, &out, &errOut); code != 2 {
+		t.Fatalf("two dirs: exit %d", code)
+	}
+	if code := runTree(defaultSettings().Tree, []string{"-exclude", "[", "."}, &out, &errOut); code != 1 || !strings.Contains(errOut.String(), "pattern") {
+		t.Fatalf("bad pattern: exit %d: %s", code, errOut.String())
+	}
+}
@@ -0,0 +1,197 @@
+// Package trie stores a vocabulary, words with their counts, in a radix
+// trie: a tree whose edges are labelled with the common prefixes of the
+// words below them. Looking a word up takes time in its length, and the
+// words with a given prefix are found without looking at the others, in
+// sorted order. BenchmarkMemory compares its size with a map's.
+package trie
+
+import (
+	"sort"
+	"strings"
+)
+
+// Trie maps words to positive counts. The zero value is an empty trie
+// ready to use. A Trie is not safe for concurrent mutation.
+type Trie struct {
+	root node
+	n    int // words
+}
+
+// node is the end of an edge labelled prefix. A word ends at the node if
+// its count is positive.
+type node struct {
+	prefix   string
+	count    int
+	children []*node // sorted by their first byte, which differs
+}
+
+// New returns an empty trie.
+func New() *Trie { return &Trie{} }
+
+// Len returns the number of words in t.
+func (t *Trie) Len() int { return t.n }
+
+// Count returns the count of word, 0 if t does not have it.
+func (t *Trie) Count(word string) int {
+	nd := &t.root
+	for word != "" {
+		_, child := nd.child(word[0])
+		if child == nil || !strings.HasPrefix(word, child.prefix) {
+			return 0
+		}
+		nd, word = child, word[len(child.prefix):]
+	}
+	return nd.count
+}
+
+// Add adds n to the count of word, adding the word if t lacks it. n may
+// be negative: a word whose count falls to zero or below is removed, and
+// so is nothing if t lacks it.
+func (t *Trie) Add(word string, n int) {
+	if n == 0 {
+		return
+	}
+	// path holds the nodes from the root down to the word's parent.
+	path := []*node{&t.root}
+	nd, rest := &t.root, word
+	for rest != "" {
+		i, child := nd.child(rest[0])
+		if child == nil {
+			if n > 0 {
+				nd.insert(i, &node{prefix: strings.Clone(rest), count: n})
+				t.n++
+			}
+			return
+		}
+		common := commonPrefix(rest, child.prefix)
+		if common < len(child.prefix) {
+			if n < 0 {
+				return
+			}
+			// Split the edge where the word leaves it.
+			mid := &node{prefix: child.prefix[:common], children: []*node{child}}
+			child.prefix = child.prefix[common:]
+			nd.children[i] = mid
+			child = mid
+		}
+		path = append(path, child)
+		nd, rest = child, rest[common:]
+	}
+
+	was := nd.count
+	nd.count = max(was+n, 0)
+	switch {
+	case was == 0 && nd.count > 0:
+		t.n++
+	case was > 0 && nd.count == 0:
+		t.n--
+		t.prune(path)
+	}
+}
+
+// Delete removes word, reporting whether t had it.
+func (t *Trie) Delete(word string) bool {
+	n := t.Count(word)
+	if n == 0 {
+		return false
+	}
+	t.Add(word, -n)
+	return true
+}
+
+// prune tidies up after the word at the end of path was removed:
+// a node left without a word or children goes, and a node without a word
+// and with one child is merged into it, so that every node but the root
+// ends a word or branches.
+func (t *Trie) prune(path []*node) {
+	for i := len(path) - 1; i > 0; i-- {
+		nd, parent := path[i], path[i-1]
+		switch {
+		case nd.count > 0 || len(nd.children) > 1:
+			return
+		case len(nd.children) == 1:
+			child := nd.children[0]
+			child.prefix = nd.prefix + child.prefix
+			j, _ := parent.child(nd.prefix[0])
+			parent.children[j] = child
+			return
+		default:
+			j, _ := parent.child(nd.prefix[0])
+			parent.children = append(parent.children[:j], parent.children[j+1:]...)
+		}
+	}
+}
+
+// Walk calls fn with each word that starts with prefix, and its count,
+// in sorted order, until fn returns false. An empty prefix walks every
+// word.
+func (t *Trie) Walk(prefix string, fn func(word string, count int) bool) {
+	nd, at := &t.root, ""
+	for rest := prefix; rest != ""; {
+		_, child := nd.child(rest[0])
+		switch {
+		case child == nil:
+			return
+		case strings.HasPrefix(child.prefix, rest):
+			// The prefix ends within the edge.
+			rest = ""
+		case strings.HasPrefix(rest, child.prefix):
+			rest = rest[len(child.prefix):]
+		default:
+			return
+		}
+		nd, at = child, at+child.prefix
+	}
+	nd.walk([]byte(at), fn)
+}
+
+// walk calls fn with the words at and below nd, whose path from the
+// root spells word, reporting false if fn stopped it.
+func (nd *node) walk(word []byte, fn func(string, int) bool) bool {
+	if nd.count > 0 && !fn(string(word), nd.count) {
+		return false
+	}
+	for _, c := range nd.children {
+		if !c.walk(append(word, c.prefix...), fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// Words returns the words that start with prefix, in sorted order. The
+// result is never nil.
+func (t *Trie) Words(prefix string) []string {
+	words := []string{}
+	t.Walk(prefix, func(w string, _ int) bool {
+		words = append(words, w)
+		return true
+	})
+	return words
+}
+
+// child returns the child whose prefix starts with b, or nil and the
+// index at which to insert one.
+func (nd *node) child(b byte) (int, *node) {
+	i := sort.Search(len(nd.children), func(i int) bool { return nd.children[i].prefix[0] >= b })
+	if i < len(nd.children) && nd.children[i].prefix[0] == b {
+		return i, nd.children[i]
+	}
+	return i, nil
+}
+
+func (nd *node) insert(i int, c *node) {
+	nd.children = append(nd.children, nil)
+	copy(nd.children[i+1:], nd.children[i:])
+	nd.children[i] = c
+}
+
+func commonPrefix(a, b string) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
@@ -0,0 +1,242 @@
+package trie
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestAddCount(t *testing.T) {
+	var tr Trie
+	for _, w := range []string{"go", "gopher", "gophers", "goroutine", "rust", "go", ""} {
+		tr.Add(w, 1)
+	}
+	for w, want := range map[string]int{"go": 2, "gopher": 1, "gophers": 1, "goroutine": 1, "rust": 1, "": 1, "g": 0, "gop": 0, "gophersx": 0, "r": 0, "zebra": 0} {
+		if got := tr.Count(w); got != want {
+			t.Errorf("Count(%q) = %d, want %d", w, got, want)
+		}
+	}
+	if tr.Len() != 6 {
+		t.Fatalf("Len = %d", tr.Len())
+	}
+
+	tr.Add("go", -1)
+	tr.Add("gopher", -5)
+	tr.Add("missing", -1)
+	tr.Add("gopherz", -1)
+	if tr.Count("go") != 1 || tr.Count("gopher") != 0 || tr.Count("gophers") != 1 || tr.Len() != 5 {
+		t.Fatalf("after decrements: go %d, gopher %d, Len %d", tr.Count("go"), tr.Count("gopher"), tr.Len())
+	}
+	if !tr.Delete("gophers") || tr.Delete("gophers") || !tr.Delete("") {
+		t.Fatal("Delete")
+	}
+	if got := tr.Words(""); !reflect.DeepEqual(got, []string{"go", "goroutine", "rust"}) {
+		t.Fatalf("Words = %q", got)
+	}
+	checkShape(t, &tr.root, true)
+}
+
+func TestWalk(t *testing.T) {
+	tr := New()
+	for i, w := range []string{"car", "cart", "carbon", "cat", "dog", "do", "carts"} {
+		tr.Add(w, i+1)
+	}
+	for prefix, want := range map[string][]string{
+		"":        {"car", "carbon", "cart", "carts", "cat", "do", "dog"},
+		"ca":      {"car", "carbon", "cart", "carts", "cat"},
+		"car":     {"car", "carbon", "cart", "carts"},
+		"cart":    {"cart", "carts"},
+		"carb":    {"carbon"},
+		"d":       {"do", "dog"},
+		"cab":     {},
+		"carbonx": {},
+		"x":       {},
+	} {
+		if got := tr.Words(prefix); !reflect.DeepEqual(got, want) {
+			t.Errorf("Words(%q) = %q, want %q", prefix, got, want)
+		}
+	}
+
+	var got []string
+	tr.Walk("car", func(w string, n int) bool {
+		got = append(got, fmt.Sprintf("%s:%d", w, n))
+		return len(got) < 2
+	})
+	if !reflect.DeepEqual(got, []string{"car:1", "carbon:3"}) {
+		t.Fatalf("Walk stopped after %q", got)
+	}
+}
+
+// TestRandom checks the trie against a map through random adds, some of
+// them negative, over words with many shared prefixes.
+func TestRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	tr := New()
+	want := map[string]int{}
+	for i := 0; i < 20000; i++ {
+		w := randomWord(r)
+		n := r.Intn(7) - 3
+		tr.Add(w, n)
+		if want[w] += n; want[w] <= 0 {
+			delete(want, w)
+		}
+	}
+	if tr.Len() != len(want) {
+		t.Fatalf("Len = %d, want %d", tr.Len(), len(want))
+	}
+	var words []string
+	for w, n := range want {
+		words = append(words, w)
+		if got := tr.Count(w); got != n {
+			t.Fatalf("Count(%q) = %d, want %d", w, got, n)
+		}
+	}
+	sort.Strings(words)
+	if got := tr.Words(""); !reflect.DeepEqual(got, words) {
+		t.Fatalf("Words differ from the map's: %d words, want %d", len(got), len(words))
+	}
+	for _, prefix := range []string{"a", "ab", "abc", "ba", "c"} {
+		var wantP []string
+		for _, w := range words {
+			if strings.HasPrefix(w, prefix) {
+				wantP = append(wantP, w)
+			}
+		}
+		if got := tr.Words(prefix); len(got) != len(wantP) || len(got) > 0 && !reflect.DeepEqual(got, wantP) {
+			t.Fatalf("Words(%q): %d words, want %d", prefix, len(got), len(wantP))
+		}
+	}
+	checkShape(t, &tr.root, true)
+
+	for _, w := range words {
+		tr.Delete(w)
+	}
+	if tr.Len() != 0 || len(tr.root.children) != 0 {
+		t.Fatalf("emptied trie has %d words, %d root children", tr.Len(), len(tr.root.children))
+	}
+}
+
+// randomWord returns a word of up to six letters from a small alphabet.
+func randomWord(r *rand.Rand) string {
+	b := make([]byte, 1+r.Intn(6))
+	for i := range b {
+		b[i] = "abc"[r.Intn(3)]
+	}
+	return string(b)
+}
+
+// checkShape fails unless every node but the root ends a word or
+// branches, and children are sorted by distinct first bytes.
+func checkShape(t *testing.T, nd *node, root bool) {
+	t.Helper()
+	if !root && nd.count == 0 && len(nd.children) < 2 {
+		t.Fatalf("node %q neither ends a word nor branches", nd.prefix)
+	}
+	for i, c := range nd.children {
+		if c.prefix == "" || i > 0 && nd.children[i-1].prefix[0] >= c.prefix[0] {
+			t.Fatalf("children of %q out of order", nd.prefix)
+		}
+		checkShape(t, c, false)
+	}
+}
+
+// vocabulary returns n distinct words built like a natural vocabulary:
+// stems with common endings, so that many share prefixes.
+func vocabulary(n int) []string {
+	r := rand.New(rand.NewSource(1))
+	endings := []string{"", "s", "ed", "ing", "er", "ers", "ly", "ness", "tion", "able"}
+	seen := map[string]bool{}
+	words := make([]string, 0, n)
+	for len(words) < n {
+		stem := make([]byte, 3+r.Intn(6))
+		for i := range stem {
+			stem[i] = byte('a' + r.Intn(26))
+		}
+		for _, e := range endings {
+			if w := string(stem) + e; !seen[w] && len(words) < n {
+				seen[w] = true
+				words = append(words, w)
+			}
+		}
+	}
+	return words
+}
+
+// heapBytes returns the bytes build leaves allocated on the heap.
+func heapBytes(build func() any) uint64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	v := build()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(v)
+	return after.HeapAlloc - before.HeapAlloc
+}
+
+// BenchmarkMemory reports the heap a vocabulary takes in a Trie and in
+// a map[string]int, in bytes per word, and how long storing it takes:
+//
+//	go test -run '^$' -bench Memory ./trie
+func BenchmarkMemory(b *testing.B) {
+	for _, size := range []int{1 << 10, 1 << 16, 1 << 20} {
+		words := vocabulary(size)
+		for _, impl := range []struct {
+			name  string
+			build func() any
+		}{
+			{"map", func() any {
+				m := map[string]int{}
+				for _, w := range words {
+					m[strings.Clone(w)]++
+				}
+				return m
+			}},
+			{"trie", func() any {
+				t := New()
+				for _, w := range words {
+					t.Add(w, 1)
+				}
+				return t
+			}},
+		} {
+			b.Run(fmt.Sprintf("%d/%s", size, impl.name), func(b *testing.B) {
+				b.ReportMetric(float64(heapBytes(impl.build))/float64(size), "heap-B/word")
+				for i := 0; i < b.N; i++ {
+					impl.build()
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkPrefix(b *testing.B) {
+	words := vocabulary(1 << 16)
+	tr := New()
+	m := map[string]int{}
+	for _, w := range words {
+		tr.Add(w, 1)
+		m[w]++
+	}
+	prefix := words[len(words)/2][:3]
+	b.Run("map", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var out []string
+			for w := range m {
+				if strings.HasPrefix(w, prefix) {
+					out = append(out, w)
+				}
+			}
+			sort.Strings(out)
+		}
+	})
+	b.Run("trie", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			tr.Words(prefix)
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package user
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheStats counts how CachedStore.Get calls were served.
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// HitRatio returns the fraction of lookups served from the cache, or 0
+// before the first lookup.
+func (s CacheStats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// CachedStore is a Store that remembers the results of Get for a fixed
+// time in front of a slower Store. Writes made through it invalidate the
+// affected user; writes made to the underlying store directly are only
+// seen once the cached copy expires.
+type CachedStore struct {
+	next Store
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	// gen is incremented by every write so that a Get racing with it
+	// does not cache the value it read before the write.
+	gen uint64
+
+	hits, misses atomic.Uint64
+}
+
+type cacheEntry struct {
+	user    User
+	expires time.Time
+}
+
+// NewCachedStore returns a store that caches next's users for ttl.
+func NewCachedStore(next Store, ttl time.Duration) *CachedStore {
+	return &CachedStore{next: next, ttl: ttl, now: time.Now, entries: map[string]cacheEntry{}}
+}
+
+// Stats returns the hit and miss counts so far.
+func (s *CachedStore) Stats() CacheStats {
+	return CacheStats{Hits: s.hits.Load(), Misses: s.misses.Load()}
+}
+
+// Get implements Store. Only found users are cached.
+func (s *CachedStore) Get(ctx context.Context, id string) (User, error) {
+	s.mu.Lock()
+	e, ok := s.entries[id]
+	if ok && s.now().Before(e.expires) {
+		s.mu.Unlock()
+		s.hits.Add(1)
+		return e.user, nil
+	}
+	if ok {
+		delete(s.entries, id)
+	}
+	gen := s.gen
+	s.mu.Unlock()
+
+	s.misses.Add(1)
+	u, err := s.next.Get(ctx, id)
+	if err != nil {
+		return User{}, err
+	}
+	s.mu.Lock()
+	if s.gen == gen {
+		s.entries[id] = cacheEntry{user: u, expires: s.now().Add(s.ttl)}
+	}
+	s.mu.Unlock()
+	return u, nil
+}
+
+// Create implements Store.
+func (s *CachedStore) Create(ctx context.Context, u User) (User, error) {
+	u, err := s.next.Create(ctx, u)
+	if err == nil {
+		s.invalidate(u.ID)
+	}
+	return u, err
+}
+
+// Update implements Store.
+func (s *CachedStore) Update(ctx context.Context, u User) error {
+	defer s.invalidate(u.ID)
+	return s.next.Update(ctx, u)
+}
+
+// Delete implements Store.
+func (s *CachedStore) Delete(ctx context.Context, id string) error {
+	defer s.invalidate(id)
+	return s.next.Delete(ctx, id)
+}
+
+// List implements Store. Listings are not cached.
+func (s *CachedStore) List(ctx context.Context, q ListQuery) (Page, error) {
+	return s.next.List(ctx, q)
+}
+
+// invalidate drops id from the cache. It runs after the write so that a
+// Get started in between cannot re-cache the old value.
+func (s *CachedStore) invalidate(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, id)
+	s.gen++
+}
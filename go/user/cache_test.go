@@ -0,0 +1,73 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// countingStore counts the Get calls that reach it.
+type countingStore struct {
+	Store
+	gets int
+}
+
+func (s *countingStore) Get(ctx context.Context, id string) (User, error) {
+	s.gets++
+	return s.Store.Get(ctx, id)
+}
+
+func TestCachedStoreHitsAndExpiry(t *testing.T) {
+	ctx := context.Background()
+	backend := &countingStore{Store: NewMemoryStore()}
+	ada, _ := backend.Create(ctx, User{Name: "Ada"})
+
+	now := time.Unix(0, 0)
+	s := NewCachedStore(backend, time.Minute)
+	s.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if u, err := s.Get(ctx, ada.ID); err != nil || u != ada {
+			t.Fatalf("Get = %+v, %v", u, err)
+		}
+	}
+	if backend.gets != 1 {
+		t.Fatalf("backend saw %d gets, want 1", backend.gets)
+	}
+	now = now.Add(time.Minute)
+	s.Get(ctx, ada.ID)
+	if backend.gets != 2 {
+		t.Fatalf("expired entry served from cache (backend gets %d)", backend.gets)
+	}
+	if st := s.Stats(); st.Hits != 2 || st.Misses != 2 || st.HitRatio() != 0.5 {
+		t.Fatalf("Stats = %+v, ratio %v", st, st.HitRatio())
+	}
+}
+
+func TestCachedStoreInvalidatesOnWrite(t *testing.T) {
+	ctx := context.Background()
+	s := NewCachedStore(NewMemoryStore(), time.Hour)
+	ada, _ := s.Create(ctx, User{Name: "Ada"})
+	s.Get(ctx, ada.ID)
+
+	ada.Name = "Ada Lovelace"
+	if err := s.Update(ctx, ada); err != nil {
+		t.Fatal(err)
+	}
+	if u, _ := s.Get(ctx, ada.ID); u.Name != "Ada Lovelace" {
+		t.Fatalf("Get after Update = %+v", u)
+	}
+	if err := s.Delete(ctx, ada.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, ada.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete err = %v", err)
+	}
+}
+
+func TestCacheStatsHitRatioEmpty(t *testing.T) {
+	if r := (CacheStats{}).HitRatio(); r != 0 {
+		t.Fatalf("HitRatio = %v", r)
+	}
+}
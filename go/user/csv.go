@@ -0,0 +1,62 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"example.com/tutorial/csvmap"
+)
+
+// RowError reports a problem with one CSV record.
+type RowError = csvmap.RowError
+
+// RowErrors lists every rejected record of an import.
+type RowErrors = csvmap.RowErrors
+
+// ImportUsersCSV parses users from CSV, mapped to their fields by the
+// "csv" tags of User. The first record is a header naming the columns,
+// in any order: "name" is required, "id" and "password_hash" are
+// optional, and anything else is an error. Column names are
+// case-insensitive.
+//
+// Records that fail to parse or validate are skipped and reported
+// together in a RowErrors value, alongside the users that were accepted;
+// a bad header fails the whole import.
+func ImportUsersCSV(r io.Reader) ([]User, error) {
+	d, err := csvmap.NewDecoder[User](r, csvmap.Options{DisallowUnknown: true})
+	if err != nil {
+		return nil, fmt.Errorf("csv import: %w", err)
+	}
+	var users []User
+	var rowErrs RowErrors
+	for {
+		u, err := d.Decode()
+		if err == io.EOF {
+			break
+		}
+		var re *RowError
+		if errors.As(err, &re) {
+			rowErrs = append(rowErrs, re)
+			continue
+		}
+		if err != nil {
+			return users, fmt.Errorf("csv import: %w", err)
+		}
+		if err := u.Validate(); err != nil {
+			rowErrs = append(rowErrs, &RowError{Line: d.Line(), Err: err})
+			continue
+		}
+		users = append(users, u)
+	}
+	if len(rowErrs) > 0 {
+		return users, fmt.Errorf("csv import: %w", rowErrs)
+	}
+	return users, nil
+}
+
+// ExportUsersCSV writes users as CSV with an "id,name,password_hash"
+// header, in a form ImportUsersCSV reads back unchanged.
+func ExportUsersCSV(w io.Writer, users []User) error {
+	return csvmap.EncodeAll(w, users)
+}
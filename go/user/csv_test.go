@@ -0,0 +1,83 @@
+package user
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"example.com/tutorial/validate"
+)
+
+func TestCSVRoundTrip(t *testing.T) {
+	users := []User{
+		{ID: "1", Name: "Ada"},
+		{ID: "2", Name: "Lovelace, Ada", PasswordHash: "$2a$04$hash"},
+		{ID: "3", Name: `Grace "Amazing" Hopper`},
+	}
+	var buf bytes.Buffer
+	if err := ExportUsersCSV(&buf, users); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "id,name,password_hash\n") {
+		t.Fatalf("missing header: %q", buf.String())
+	}
+	got, err := ImportUsersCSV(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, users) {
+		t.Fatalf("round trip = %+v, want %+v", got, users)
+	}
+}
+
+func TestImportUsersCSVColumns(t *testing.T) {
+	got, err := ImportUsersCSV(strings.NewReader("Name, ID\nAda,5\nGrace,\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []User{{ID: "5", Name: "Ada"}, {Name: "Grace"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestImportUsersCSVHeaderErrors(t *testing.T) {
+	for _, in := range []string{"", "id\n1\n", "name,email\nAda,a@b.c\n", "name,name\nA,B\n", "\"name\n"} {
+		if users, err := ImportUsersCSV(strings.NewReader(in)); err == nil || users != nil {
+			t.Errorf("ImportUsersCSV(%q) = %v, %v; want header error", in, users, err)
+		}
+	}
+}
+
+func TestImportUsersCSVRowErrors(t *testing.T) {
+	in := "id,name\n" +
+		"1,Ada\n" +
+		"x/y,Bad ID\n" +
+		"3,\n" +
+		"4,Too,Many\n" +
+		"5,Grace\n"
+	users, err := ImportUsersCSV(strings.NewReader(in))
+	if want := []User{{ID: "1", Name: "Ada"}, {ID: "5", Name: "Grace"}}; !reflect.DeepEqual(users, want) {
+		t.Fatalf("users = %+v, want %+v", users, want)
+	}
+	var rowErrs RowErrors
+	if !errors.As(err, &rowErrs) {
+		t.Fatalf("err = %v, want RowErrors", err)
+	}
+	var lines []int
+	for _, re := range rowErrs {
+		lines = append(lines, re.Line)
+	}
+	if !reflect.DeepEqual(lines, []int{3, 4, 5}) {
+		t.Fatalf("error lines = %v (%v)", lines, err)
+	}
+	var verr validate.Errors
+	if !errors.As(rowErrs[1], &verr) {
+		t.Fatalf("line 4 error = %v, want validation error", rowErrs[1])
+	}
+	if !strings.Contains(err.Error(), "line 3: invalid: id: must be letters") {
+		t.Fatalf("Error() = %q", err.Error())
+	}
+}
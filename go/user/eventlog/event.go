@@ -0,0 +1,183 @@
+package eventlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"example.com/tutorial/repo"
+	"example.com/tutorial/user"
+)
+
+// Event is a change to the set of users. The concrete types are
+// UserCreated, UserRenamed, UserPasswordChanged and UserDeleted.
+type Event interface {
+	// eventType is the name stored in the log for this kind of event.
+	eventType() string
+	// apply makes the change to the replayed state.
+	apply(s *state) error
+}
+
+// UserCreated records a new user.
+type UserCreated struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	PasswordHash string `json:"password_hash,omitempty"`
+}
+
+// UserRenamed records a change of name.
+type UserRenamed struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// UserPasswordChanged records a new password hash; an empty hash means
+// the password was removed.
+type UserPasswordChanged struct {
+	ID           string `json:"id"`
+	PasswordHash string `json:"password_hash"`
+}
+
+// UserDeleted records the removal of a user.
+type UserDeleted struct {
+	ID string `json:"id"`
+}
+
+func (UserCreated) eventType() string         { return "user_created" }
+func (UserRenamed) eventType() string         { return "user_renamed" }
+func (UserPasswordChanged) eventType() string { return "user_password_changed" }
+func (UserDeleted) eventType() string         { return "user_deleted" }
+
+// newEvent returns a zero event of the named type for decoding into.
+func newEvent(typ string) (Event, error) {
+	switch typ {
+	case "user_created":
+		return &UserCreated{}, nil
+	case "user_renamed":
+		return &UserRenamed{}, nil
+	case "user_password_changed":
+		return &UserPasswordChanged{}, nil
+	case "user_deleted":
+		return &UserDeleted{}, nil
+	}
+	return nil, fmt.Errorf("eventlog: unknown event type %q", typ)
+}
+
+// Record is one entry of the log: an event with its position and the
+// time it was appended.
+type Record struct {
+	Seq   uint64
+	Time  time.Time
+	Event Event
+}
+
+// recordJSON is the serialized form of a Record, one per log line.
+type recordJSON struct {
+	Seq  uint64          `json:"seq"`
+	Time time.Time       `json:"time"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r Record) MarshalJSON() ([]byte, error) {
+	if r.Event == nil {
+		return nil, fmt.Errorf("eventlog: record %d has no event", r.Seq)
+	}
+	data, err := json.Marshal(r.Event)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(recordJSON{Seq: r.Seq, Time: r.Time, Type: r.Event.eventType(), Data: data})
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Events are decoded into
+// their value types, so r.Event can be compared with == or a type switch.
+func (r *Record) UnmarshalJSON(data []byte) error {
+	var rj recordJSON
+	if err := json.Unmarshal(data, &rj); err != nil {
+		return err
+	}
+	e, err := newEvent(rj.Type)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(legacyID(rj.Data), e); err != nil {
+		return fmt.Errorf("eventlog: decode %s: %w", rj.Type, err)
+	}
+	// Dereference so callers see the same value types they append.
+	switch e := e.(type) {
+	case *UserCreated:
+		r.Event = *e
+	case *UserRenamed:
+		r.Event = *e
+	case *UserPasswordChanged:
+		r.Event = *e
+	case *UserDeleted:
+		r.Event = *e
+	}
+	r.Seq, r.Time = rj.Seq, rj.Time
+	return nil
+}
+
+// legacyID rewrites the numeric "id" of an event logged before IDs were
+// strings as a string. Anything else is returned as it is.
+func legacyID(data json.RawMessage) json.RawMessage {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(data, &fields) != nil {
+		return data
+	}
+	var n int64
+	if json.Unmarshal(fields["id"], &n) != nil {
+		return data
+	}
+	fields["id"], _ = json.Marshal(strconv.FormatInt(n, 10))
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return data
+	}
+	return out
+}
+
+// state is what replaying the log reconstructs.
+type state struct {
+	users *repo.Repository[user.User, string]
+}
+
+func newState() *state {
+	return &state{users: repo.New(repo.Config[user.User, string]{
+		Key: func(u user.User) string { return u.ID },
+	})}
+}
+
+func (e UserCreated) apply(s *state) error {
+	if _, err := s.users.Create(user.User{ID: e.ID, Name: e.Name, PasswordHash: e.PasswordHash}); err != nil {
+		return fmt.Errorf("create %q: %w", e.ID, err)
+	}
+	return nil
+}
+
+func (e UserRenamed) apply(s *state) error {
+	return s.modify(e.ID, func(u *user.User) { u.Name = e.Name })
+}
+
+func (e UserPasswordChanged) apply(s *state) error {
+	return s.modify(e.ID, func(u *user.User) { u.PasswordHash = e.PasswordHash })
+}
+
+func (e UserDeleted) apply(s *state) error {
+	if err := s.users.Delete(e.ID); err != nil {
+		return fmt.Errorf("delete %q: %w", e.ID, err)
+	}
+	return nil
+}
+
+func (s *state) modify(id string, fn func(*user.User)) error {
+	u, err := s.users.Get(id)
+	if err != nil {
+		return fmt.Errorf("modify %q: %w", id, err)
+	}
+	fn(&u)
+	return s.users.Update(u)
+}
@@ -0,0 +1,69 @@
+package eventlog
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestRecordRoundTrip(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, e := range []Event{
+		UserCreated{ID: "1", Name: "Ada", PasswordHash: "$2a$04$x"},
+		UserCreated{ID: "2", Name: "Grace"},
+		UserRenamed{ID: "1", Name: "Ada L."},
+		UserPasswordChanged{ID: "1"},
+		UserDeleted{ID: "2"},
+	} {
+		data, err := json.Marshal(Record{Seq: 7, Time: at, Event: e})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Record
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", data, err)
+		}
+		if got.Seq != 7 || !got.Time.Equal(at) || got.Event != e {
+			t.Errorf("round trip of %s = %+v", data, got)
+		}
+	}
+}
+
+func TestRecordFormat(t *testing.T) {
+	rec := Record{Seq: 3, Time: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Event: UserRenamed{ID: "4", Name: "Bo"}}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"seq":3,"time":"2024-05-01T00:00:00Z","type":"user_renamed","data":{"id":"4","name":"Bo"}}`
+	if string(data) != want {
+		t.Fatalf("got  %s\nwant %s", data, want)
+	}
+}
+
+func TestRecordLegacyID(t *testing.T) {
+	var rec Record
+	in := `{"seq":1,"time":"2024-05-01T00:00:00Z","type":"user_renamed","data":{"id":4,"name":"Bo"}}`
+	if err := json.Unmarshal([]byte(in), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if want := (UserRenamed{ID: "4", Name: "Bo"}); rec.Event != want {
+		t.Fatalf("event = %+v, want %+v", rec.Event, want)
+	}
+}
+
+func TestRecordDecodeErrors(t *testing.T) {
+	for _, in := range []string{
+		`{"seq":1,"type":"user_promoted","data":{}}`,
+		`{"seq":1,"type":"user_created","data":{"id":true}}`,
+		`{"seq":1`,
+	} {
+		var rec Record
+		if err := json.Unmarshal([]byte(in), &rec); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded: %+v", in, rec)
+		}
+	}
+	if _, err := json.Marshal(Record{Seq: 1}); err == nil {
+		t.Error("Marshal of record without event succeeded")
+	}
+}
@@ -0,0 +1,330 @@
+// Package eventlog implements user.Store as an append-only log of events.
+// The current users are never written in place: every change appends
+// UserCreated, UserRenamed, UserPasswordChanged or UserDeleted records,
+// and opening the store replays them. Periodic snapshots bound the
+// amount of log that has to be replayed.
+package eventlog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"example.com/tutorial/fileutil"
+	"example.com/tutorial/id"
+	"example.com/tutorial/repo"
+	"example.com/tutorial/user"
+)
+
+// File names inside the store's directory.
+const (
+	logFile      = "events.jsonl"
+	snapshotFile = "snapshot.json"
+)
+
+// DefaultSnapshotEvery is the snapshot interval used when
+// Options.SnapshotEvery is zero.
+const DefaultSnapshotEvery = 100
+
+// Options configures Open.
+type Options struct {
+	// SnapshotEvery is the number of events appended between snapshots.
+	// Zero means DefaultSnapshotEvery; a negative value disables
+	// automatic snapshots.
+	SnapshotEvery int
+}
+
+// Store is an event-sourced user.Store that keeps its log and snapshot
+// in a directory.
+type Store struct {
+	dir     string
+	every   int
+	now     func() time.Time
+	mu      sync.RWMutex
+	log     *os.File
+	size    int64 // length of the valid part of the log
+	st      *state
+	seq     uint64 // sequence number of the last record
+	snapSeq uint64 // sequence number covered by the last snapshot
+}
+
+// snapshotJSON is the on-disk form of a snapshot.
+// Snapshots written before IDs were strings also have a "max_id", which
+// is no longer needed and is ignored.
+type snapshotJSON struct {
+	Seq   uint64      `json:"seq"`
+	Users []user.User `json:"users"`
+}
+
+// Open opens the store in dir, creating the directory if needed, and
+// rebuilds the users from the latest snapshot plus the events after it.
+// A partially written last record, left by a crash, is discarded.
+func Open(dir string, opts Options) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &Store{dir: dir, every: opts.SnapshotEvery, now: time.Now, st: newState()}
+	if s.every == 0 {
+		s.every = DefaultSnapshotEvery
+	}
+	if err := s.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, logFile), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.replay(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	s.log = f
+	return s, nil
+}
+
+func (s *Store) loadSnapshot() error {
+	data, err := os.ReadFile(filepath.Join(s.dir, snapshotFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var snap snapshotJSON
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("eventlog: load snapshot: %w", err)
+	}
+	if err := s.st.users.Restore(snap.Users); err != nil {
+		return fmt.Errorf("eventlog: load snapshot: %w", err)
+	}
+	s.seq, s.snapSeq = snap.Seq, snap.Seq
+	return nil
+}
+
+// replay applies the records of f that follow the snapshot and leaves f
+// positioned at the end of the last complete record.
+func (s *Store) replay(f *os.File) error {
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// Anything after the last newline is a torn write.
+			if len(line) > 0 {
+				if err := f.Truncate(s.size); err != nil {
+					return err
+				}
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+		var rec Record
+		if err := json.Unmarshal(bytes.TrimSpace(line), &rec); err != nil {
+			return fmt.Errorf("eventlog: offset %d: %w", s.size, err)
+		}
+		s.size += int64(len(line))
+		if rec.Seq <= s.snapSeq {
+			continue
+		}
+		if rec.Seq != s.seq+1 {
+			return fmt.Errorf("eventlog: record %d follows %d", rec.Seq, s.seq)
+		}
+		if err := rec.Event.apply(s.st); err != nil {
+			return fmt.Errorf("eventlog: record %d: %w", rec.Seq, err)
+		}
+		s.seq = rec.Seq
+	}
+	_, err := f.Seek(s.size, io.SeekStart)
+	return err
+}
+
+// append writes events to the log as one batch, syncs it and then
+// applies them. If writing fails the log is cut back so that none of
+// the batch survives. The caller must hold s.mu.
+func (s *Store) append(events ...Event) error {
+	if s.log == nil {
+		return errors.New("eventlog: store is closed")
+	}
+	var buf bytes.Buffer
+	now := s.now().UTC()
+	for i, e := range events {
+		data, err := json.Marshal(Record{Seq: s.seq + uint64(i) + 1, Time: now, Event: e})
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	if _, err := s.log.Write(buf.Bytes()); err != nil {
+		s.rewind()
+		return err
+	}
+	if err := s.log.Sync(); err != nil {
+		s.rewind()
+		return err
+	}
+	s.size += int64(buf.Len())
+	for _, e := range events {
+		if err := e.apply(s.st); err != nil {
+			// The events were derived from the current state, so this
+			// means a bug rather than bad input.
+			return fmt.Errorf("eventlog: apply %s: %w", e.eventType(), err)
+		}
+		s.seq++
+	}
+	if s.every > 0 && s.seq-s.snapSeq >= uint64(s.every) {
+		// The events are durable; a failed snapshot is simply retried
+		// after the next append.
+		s.snapshot()
+	}
+	return nil
+}
+
+func (s *Store) rewind() {
+	s.log.Truncate(s.size)
+	s.log.Seek(s.size, io.SeekStart)
+}
+
+// Snapshot writes the current users to the snapshot file, so that the
+// next Open only replays events appended after this point.
+func (s *Store) Snapshot() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshot()
+}
+
+func (s *Store) snapshot() error {
+	snap := snapshotJSON{
+		Seq:   s.seq,
+		Users: user.ListQuery{}.Apply(s.st.users.All()).Users,
+	}
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := fileutil.WriteAtomic(filepath.Join(s.dir, snapshotFile), append(data, '\n')); err != nil {
+		return err
+	}
+	s.snapSeq = s.seq
+	return nil
+}
+
+// Close closes the log file. The store must not be used afterwards.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.log == nil {
+		return nil
+	}
+	err := s.log.Close()
+	s.log = nil
+	return err
+}
+
+// Create implements user.Store.
+func (s *Store) Create(ctx context.Context, u user.User) (user.User, error) {
+	if err := u.Validate(); err != nil {
+		return user.User{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u.ID == "" {
+		u.ID = id.New()
+	}
+	if _, err := s.st.users.Get(u.ID); err == nil {
+		return user.User{}, user.ErrExists
+	}
+	if err := s.append(UserCreated{ID: u.ID, Name: u.Name, PasswordHash: u.PasswordHash}); err != nil {
+		return user.User{}, err
+	}
+	return u, nil
+}
+
+// Get implements user.Store.
+func (s *Store) Get(ctx context.Context, id string) (user.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, err := s.st.users.Get(id)
+	if errors.Is(err, repo.ErrNotFound) {
+		return user.User{}, user.ErrNotFound
+	}
+	return u, err
+}
+
+// Update implements user.Store. It appends one event per changed field,
+// and none if u equals the stored user.
+func (s *Store) Update(ctx context.Context, u user.User) error {
+	if err := u.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, err := s.st.users.Get(u.ID)
+	if err != nil {
+		return user.ErrNotFound
+	}
+	var events []Event
+	if u.Name != old.Name {
+		events = append(events, UserRenamed{ID: u.ID, Name: u.Name})
+	}
+	if u.PasswordHash != old.PasswordHash {
+		events = append(events, UserPasswordChanged{ID: u.ID, PasswordHash: u.PasswordHash})
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	return s.append(events...)
+}
+
+// Delete implements user.Store.
+func (s *Store) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.st.users.Get(id); err != nil {
+		return user.ErrNotFound
+	}
+	return s.append(UserDeleted{ID: id})
+}
+
+// List implements user.Store.
+func (s *Store) List(ctx context.Context, q user.ListQuery) (user.Page, error) {
+	if err := q.Validate(); err != nil {
+		return user.Page{}, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return q.Apply(s.st.users.All()), nil
+}
+
+// Records returns every record in the log, including those already
+// covered by a snapshot, for auditing and tests.
+func (s *Store) Records() ([]Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, err := os.ReadFile(filepath.Join(s.dir, logFile))
+	if err != nil {
+		return nil, err
+	}
+	var recs []Record
+	for _, line := range bytes.Split(data[:s.size], []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+	return recs, nil
+}
@@ -0,0 +1,154 @@
+package eventlog
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"example.com/tutorial/user"
+	"example.com/tutorial/user/usertest"
+)
+
+func open(t *testing.T, dir string, opts Options) *Store {
+	t.Helper()
+	s, err := Open(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestStore(t *testing.T) {
+	usertest.TestStore(t, func(t *testing.T) user.Store {
+		return open(t, t.TempDir(), Options{SnapshotEvery: 5})
+	})
+}
+
+func list(t *testing.T, s user.Store) []user.User {
+	t.Helper()
+	page, err := s.List(context.Background(), user.ListQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return page.Users
+}
+
+func TestReplay(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s := open(t, dir, Options{SnapshotEvery: -1})
+	ada, _ := s.Create(ctx, user.User{Name: "Ada"})
+	bob, _ := s.Create(ctx, user.User{Name: "Bob"})
+	ada.Name, ada.PasswordHash = "Ada Lovelace", "hash"
+	if err := s.Update(ctx, ada); err != nil {
+		t.Fatal(err)
+	}
+	s.Update(ctx, ada) // unchanged, so no event
+	s.Delete(ctx, bob.ID)
+	want := list(t, s)
+	s.Close()
+
+	recs, err := open(t, dir, Options{}).Records()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []Event
+	for i, r := range recs {
+		if r.Seq != uint64(i+1) {
+			t.Fatalf("record %d has seq %d", i, r.Seq)
+		}
+		events = append(events, r.Event)
+	}
+	wantEvents := []Event{
+		UserCreated{ID: ada.ID, Name: "Ada"},
+		UserCreated{ID: bob.ID, Name: "Bob"},
+		UserRenamed{ID: ada.ID, Name: "Ada Lovelace"},
+		UserPasswordChanged{ID: ada.ID, PasswordHash: "hash"},
+		UserDeleted{ID: bob.ID},
+	}
+	if !reflect.DeepEqual(events, wantEvents) {
+		t.Fatalf("events = %+v\nwant %+v", events, wantEvents)
+	}
+
+	reopened := open(t, dir, Options{})
+	if got := list(t, reopened); !reflect.DeepEqual(got, want) {
+		t.Fatalf("replayed users = %+v, want %+v", got, want)
+	}
+	// Bob's ID is not reused even though only Ada survives.
+	if u, _ := reopened.Create(ctx, user.User{Name: "Cy"}); u.ID == bob.ID || u.ID <= ada.ID {
+		t.Fatalf("new ID = %q after %q and %q", u.ID, ada.ID, bob.ID)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s := open(t, dir, Options{SnapshotEvery: 3})
+	var last user.User
+	for _, name := range []string{"a", "b", "c", "d"} {
+		last, _ = s.Create(ctx, user.User{Name: name})
+	}
+	s.Delete(ctx, last.ID)
+	want := list(t, s)
+	s.Close()
+
+	if _, err := os.Stat(filepath.Join(dir, snapshotFile)); err != nil {
+		t.Fatalf("no snapshot written: %v", err)
+	}
+	// Events covered by the snapshot are skipped on replay, so removing
+	// the log's first records must not change the result.
+	data, _ := os.ReadFile(filepath.Join(dir, logFile))
+	lines := strings.SplitAfter(string(data), "\n")
+	os.WriteFile(filepath.Join(dir, logFile), []byte(lines[3]+lines[4]), 0o644)
+
+	reopened := open(t, dir, Options{SnapshotEvery: 3})
+	if got := list(t, reopened); !reflect.DeepEqual(got, want) {
+		t.Fatalf("users after snapshot replay = %+v, want %+v", got, want)
+	}
+	if u, _ := reopened.Create(ctx, user.User{Name: "e"}); u.ID == last.ID {
+		t.Fatalf("new user got the deleted ID %q", u.ID)
+	}
+}
+
+func TestTornWrite(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s := open(t, dir, Options{})
+	s.Create(ctx, user.User{Name: "Ada"})
+	s.Close()
+
+	f, _ := os.OpenFile(filepath.Join(dir, logFile), os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString(`{"seq":2,"type":"user_cre`)
+	f.Close()
+
+	reopened := open(t, dir, Options{})
+	if got := list(t, reopened); len(got) != 1 || got[0].Name != "Ada" {
+		t.Fatalf("users = %+v", got)
+	}
+	if _, err := reopened.Create(ctx, user.User{Name: "Bob"}); err != nil {
+		t.Fatal(err)
+	}
+	recs, err := reopened.Records()
+	if err != nil || len(recs) != 2 || recs[1].Seq != 2 {
+		t.Fatalf("records after torn write = %+v, %v", recs, err)
+	}
+}
+
+func TestOpenCorrupt(t *testing.T) {
+	for name, log := range map[string]string{
+		"bad json":  "{nope}\n",
+		"gap":       `{"seq":2,"type":"user_deleted","data":{"id":1}}` + "\n",
+		"bad event": `{"seq":1,"type":"user_deleted","data":{"id":1}}` + "\n",
+	} {
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, logFile), []byte(log), 0o644)
+		if s, err := Open(dir, Options{}); err == nil {
+			s.Close()
+			t.Errorf("%s: Open succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"example.com/tutorial/user"
+)
+
+// Code written against Store works with any of its implementations:
+// memory, files, SQLite or a cache in front of one of them.
+func ExampleStore() {
+	ctx := context.Background()
+	rename := func(s user.Store, id string, name string) error {
+		u, err := s.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		u.Name = name
+		return s.Update(ctx, u)
+	}
+	mem := user.NewMemoryStore()
+	for _, s := range []user.Store{mem, user.NewCachedStore(mem, time.Minute)} {
+		u, _ := s.Create(ctx, user.User{Name: "Ada"})
+		if err := rename(s, u.ID, "Ada Lovelace"); err != nil {
+			panic(err)
+		}
+		u, _ = s.Get(ctx, u.ID)
+		fmt.Println(u.Name)
+	}
+	fmt.Println(rename(mem, "nobody", "Nobody"))
+	// Output:
+	// Ada Lovelace
+	// Ada Lovelace
+	// user: not found
+}
+
+func ExampleMemoryStore() {
+	ctx := context.Background()
+	s := user.NewMemoryStore()
+	// New users get sortable IDs from id.New; an ID may also be given.
+	grace, _ := s.Create(ctx, user.User{Name: "Grace"})
+	for _, u := range []user.User{{Name: "Ada"}, {ID: "alan", Name: "Alan"}} {
+		u, err := s.Create(ctx, u)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println("created", u.Name, u.ID > grace.ID)
+	}
+
+	page, _ := s.List(ctx, user.ListQuery{NamePrefix: "A", Sort: user.SortByName})
+	for _, u := range page.Users {
+		fmt.Println("listed", u.Name)
+	}
+
+	s.Delete(ctx, grace.ID)
+	if _, err := s.Get(ctx, grace.ID); errors.Is(err, user.ErrNotFound) {
+		fmt.Println(err)
+	}
+	_, err := s.Create(ctx, user.User{})
+	fmt.Println(err)
+	// Output:
+	// created Ada true
+	// created Alan true
+	// listed Ada
+	// listed Alan
+	// user: not found
+	// invalid: name: is required
+}
+
+func ExampleCachedStore() {
+	ctx := context.Background()
+	backend := user.NewMemoryStore()
+	ada, _ := backend.Create(ctx, user.User{Name: "Ada"})
+
+	s := user.NewCachedStore(backend, time.Minute)
+	for i := 0; i < 3; i++ {
+		s.Get(ctx, ada.ID)
+	}
+	st := s.Stats()
+	fmt.Printf("%d hits, %d misses\n", st.Hits, st.Misses)
+	// Output: 2 hits, 1 misses
+}
+
+func ExampleUser_SetPassword() {
+	u := user.User{Name: "Ada"}
+	if err := u.SetPassword("analytical engine"); err != nil {
+		panic(err)
+	}
+	// The hash is salted, so it differs on every run; check passwords
+	// against it rather than comparing hashes.
+	fmt.Println(u.CheckPassword("analytical engine"), u.CheckPassword("difference engine"))
+	// Output: true false
+}
@@ -0,0 +1,64 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"example.com/tutorial/fileutil"
+)
+
+// FileStore is a Store that keeps users in memory and rewrites a JSON file
+// after every change. The file is replaced atomically, so a crash leaves
+// either the old or the new contents, and a checksum kept next to it
+// catches later corruption.
+type FileStore struct {
+	MemoryStore
+	path  string
+	files *fileutil.Executor
+}
+
+// FileStoreConfig holds the optional settings of a FileStore.
+type FileStoreConfig struct {
+	// Files writes the file; nil means writing it directly. With a dry
+	// run Executor the changes are made in memory and only reported.
+	Files *fileutil.Executor
+}
+
+// OpenFileStore loads the users saved at path, starting empty if the file
+// does not exist yet.
+func OpenFileStore(path string) (*FileStore, error) {
+	return OpenFileStoreWithConfig(path, FileStoreConfig{})
+}
+
+// OpenFileStoreWithConfig is like OpenFileStore with settings from cfg.
+func OpenFileStoreWithConfig(path string, cfg FileStoreConfig) (*FileStore, error) {
+	s := &FileStore{path: path, files: cfg.Files}
+	s.repo = newRepository(s.save)
+	data, err := fileutil.ReadChecksummed(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("user: load %s: %w", path, err)
+	}
+	var users []User
+	if err := json.Unmarshal(data, &users); err != nil {
+		return nil, fmt.Errorf("user: load %s: %w", path, err)
+	}
+	if err := s.repo.Restore(users); err != nil {
+		return nil, fmt.Errorf("user: load %s: %w", path, repoErr(err))
+	}
+	return s, nil
+}
+
+// save writes users to s.path with WriteChecksummed. The repository
+// calls it after every change.
+func (s *FileStore) save(users []User) error {
+	data, err := json.MarshalIndent(ListQuery{}.Apply(users).Users, "", "  ")
+	if err != nil {
+		return err
+	}
+	return s.files.WriteChecksummed(s.path, append(data, '\n'))
+}
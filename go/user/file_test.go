@@ -0,0 +1,100 @@
+package user_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"example.com/tutorial/fileutil"
+	"example.com/tutorial/user"
+	"example.com/tutorial/user/usertest"
+)
+
+func TestFileStore(t *testing.T) {
+	usertest.TestStore(t, func(t *testing.T) user.Store {
+		s, err := user.OpenFileStore(filepath.Join(t.TempDir(), "users.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	})
+}
+
+func TestFileStorePersists(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "users.json")
+	s, err := user.OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ada, _ := s.Create(ctx, user.User{Name: "Ada"})
+	grace, _ := s.Create(ctx, user.User{Name: "Grace"})
+	s.Delete(ctx, ada.ID)
+
+	reopened, err := user.OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := reopened.List(ctx, user.ListQuery{})
+	if users := page.Users; len(users) != 1 || users[0] != grace {
+		t.Fatalf("reopened store lists %+v", page.Users)
+	}
+	// New IDs sort after the stored ones.
+	u, _ := reopened.Create(ctx, user.User{Name: "Linus"})
+	if u.ID <= grace.ID {
+		t.Fatalf("new ID %q not after %q", u.ID, grace.ID)
+	}
+}
+
+func TestFileStoreDryRun(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "users.json")
+	s, _ := user.OpenFileStore(path)
+	s.Create(ctx, user.User{Name: "Ada"})
+
+	var log bytes.Buffer
+	dry, err := user.OpenFileStoreWithConfig(path, user.FileStoreConfig{Files: &fileutil.Executor{DryRun: true, Log: &log}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dry.Create(ctx, user.User{Name: "Grace"}); err != nil {
+		t.Fatal(err)
+	}
+	if page, _ := dry.List(ctx, user.ListQuery{}); page.Total != 2 {
+		t.Fatalf("dry-run store has %d users", page.Total)
+	}
+	if want := "would write " + path + "\n"; !bytes.HasPrefix(log.Bytes(), []byte(want)) {
+		t.Errorf("log %q, want it to start with %q", log.String(), want)
+	}
+	reopened, _ := user.OpenFileStore(path)
+	if page, _ := reopened.List(ctx, user.ListQuery{}); page.Total != 1 {
+		t.Fatalf("dry run saved: %d users", page.Total)
+	}
+}
+
+func TestOpenFileStoreCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	os.WriteFile(path, []byte("{not json"), 0o644)
+	if _, err := user.OpenFileStore(path); err == nil {
+		t.Fatal("expected error for corrupt file")
+	}
+}
+
+func TestOpenFileStoreChecksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	s, err := user.OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Create(context.Background(), user.User{Name: "Ada"})
+
+	// Still valid JSON, but not what the store wrote.
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, bytes.Replace(data, []byte("Ada"), []byte("Eve"), 1), 0o644)
+	if _, err := user.OpenFileStore(path); !errors.Is(err, fileutil.ErrChecksum) {
+		t.Fatalf("tampered file: %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package user
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// SchemaVersion is the version written into every marshaled User.
+//
+// History:
+//
+//	1: {"ID": 1, "Name": "Ada"} – Go's default encoding, no version field
+//	2: {"version": 2, "id": 1, "name": "Ada"}
+//	3: adds "password_hash", omitted when empty
+//	4: "id" is a string, such as "01HQ3K5V9ZJ8X4N2WMRT6PBC7E"; older
+//	   numeric IDs become their decimal form
+const SchemaVersion = 4
+
+type userV1 struct {
+	ID   int
+	Name string
+}
+
+type userV2 struct {
+	Version int    `json:"version"`
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+}
+
+type userV3 struct {
+	Version      int    `json:"version"`
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	PasswordHash string `json:"password_hash,omitempty"`
+}
+
+type userV4 struct {
+	Version      int    `json:"version"`
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	PasswordHash string `json:"password_hash,omitempty"`
+}
+
+// MarshalJSON encodes u in the current schema version.
+func (u User) MarshalJSON() ([]byte, error) {
+	return json.Marshal(userV4{Version: SchemaVersion, ID: u.ID, Name: u.Name, PasswordHash: u.PasswordHash})
+}
+
+// UnmarshalJSON decodes any supported schema version, migrating older
+// payloads to the current User. Unknown fields are ignored so that files
+// written by newer code with extra fields can still be read.
+func (u *User) UnmarshalJSON(data []byte) error {
+	var probe struct {
+		Version *int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return err
+	}
+	version := 1
+	if probe.Version != nil {
+		version = *probe.Version
+	}
+	switch version {
+	case 1:
+		var v1 userV1
+		if err := json.Unmarshal(data, &v1); err != nil {
+			return err
+		}
+		*u = migrateV1(v1)
+	case 2:
+		var v2 userV2
+		if err := json.Unmarshal(data, &v2); err != nil {
+			return err
+		}
+		*u = migrateV2(v2)
+	case 3:
+		var v3 userV3
+		if err := json.Unmarshal(data, &v3); err != nil {
+			return err
+		}
+		*u = migrateV3(v3)
+	case 4:
+		var v4 userV4
+		if err := json.Unmarshal(data, &v4); err != nil {
+			return err
+		}
+		*u = User{ID: v4.ID, Name: v4.Name, PasswordHash: v4.PasswordHash}
+	default:
+		return fmt.Errorf("user: unsupported schema version %d", version)
+	}
+	return nil
+}
+
+func migrateV1(v1 userV1) User {
+	return User{ID: legacyID(v1.ID), Name: v1.Name}
+}
+
+func migrateV2(v2 userV2) User {
+	return User{ID: legacyID(v2.ID), Name: v2.Name}
+}
+
+func migrateV3(v3 userV3) User {
+	return User{ID: legacyID(v3.ID), Name: v3.Name, PasswordHash: v3.PasswordHash}
+}
+
+// legacyID converts a numeric ID of schema versions 1 to 3 to a string.
+// Zero meant no ID, and stays so.
+func legacyID(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
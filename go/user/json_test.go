@@ -0,0 +1,98 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUserJSONRoundTrip(t *testing.T) {
+	u := User{ID: "42", Name: "Ada Lovelace"}
+	data, err := json.Marshal(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), `{"version":4,"id":"42","name":"Ada Lovelace"}`; got != want {
+		t.Fatalf("Marshal = %s, want %s", got, want)
+	}
+	var back User
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back != u {
+		t.Fatalf("round trip = %+v, want %+v", back, u)
+	}
+}
+
+func TestUserJSONPasswordHash(t *testing.T) {
+	u := User{ID: "1", Name: "Ada", PasswordHash: "$2a$10$abc"}
+	data, err := json.Marshal(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back User
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back != u {
+		t.Fatalf("round trip = %+v, want %+v", back, u)
+	}
+}
+
+func TestUserJSONCompat(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want User
+	}{
+		{"v1", `{"ID":1,"Name":"Ada"}`, User{ID: "1", Name: "Ada"}},
+		{"explicit v1", `{"version":1,"ID":2,"Name":"Grace"}`, User{ID: "2", Name: "Grace"}},
+		{"v2", `{"version":2,"id":3,"name":"Linus"}`, User{ID: "3", Name: "Linus"}},
+		{"v3 with future fields", `{"version":3,"id":4,"name":"Ken","password_hash":"$2a$x","email":"k@example.com"}`, User{ID: "4", Name: "Ken", PasswordHash: "$2a$x"}},
+		{"v3 without ID", `{"version":3,"id":0,"name":"Ken"}`, User{Name: "Ken"}},
+	}
+	for _, tt := range tests {
+		var u User
+		if err := json.Unmarshal([]byte(tt.in), &u); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if u != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, u, tt.want)
+		}
+	}
+	var u User
+	if err := json.Unmarshal([]byte(`{"version":99,"id":1}`), &u); err == nil {
+		t.Error("expected error for unsupported version")
+	}
+}
+
+func TestFileStoreReadsV1File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	if err := os.WriteFile(path, []byte(`[{"ID":1,"Name":"Ada"},{"ID":5,"Name":"Grace"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if u, err := s.Get(ctx, "5"); err != nil || u.Name != "Grace" {
+		t.Fatalf("Get(5) = %+v, %v", u, err)
+	}
+	// Any write upgrades the whole file to the current version.
+	if _, err := s.Create(ctx, User{Name: "Linus"}); err != nil {
+		t.Fatal(err)
+	}
+	var raw []map[string]any
+	data, _ := os.ReadFile(path)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range raw {
+		if r["version"] != float64(SchemaVersion) {
+			t.Fatalf("record not upgraded: %v", r)
+		}
+	}
+}
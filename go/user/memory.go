@@ -0,0 +1,78 @@
+package user
+
+import (
+	"context"
+	"errors"
+
+	"example.com/tutorial/id"
+	"example.com/tutorial/repo"
+)
+
+// MemoryStore is a Store that keeps users in memory.
+type MemoryStore struct {
+	repo *repo.Repository[User, string]
+}
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{repo: newRepository(nil)}
+}
+
+// newRepository returns the generic repository backing the in-memory
+// stores, giving new users IDs from id.New.
+func newRepository(commit func([]User) error) *repo.Repository[User, string] {
+	return repo.New(repo.Config[User, string]{
+		Key:    func(u User) string { return u.ID },
+		SetKey: func(u User, id string) User { u.ID = id; return u },
+		Keys:   id.Keys{},
+		Commit: commit,
+	})
+}
+
+// Create implements Store.
+func (s *MemoryStore) Create(ctx context.Context, u User) (User, error) {
+	if err := u.Validate(); err != nil {
+		return User{}, err
+	}
+	u, err := s.repo.Create(u)
+	return u, repoErr(err)
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(ctx context.Context, id string) (User, error) {
+	u, err := s.repo.Get(id)
+	return u, repoErr(err)
+}
+
+// Update implements Store.
+func (s *MemoryStore) Update(ctx context.Context, u User) error {
+	if err := u.Validate(); err != nil {
+		return err
+	}
+	return repoErr(s.repo.Update(u))
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	return repoErr(s.repo.Delete(id))
+}
+
+// List implements Store.
+func (s *MemoryStore) List(ctx context.Context, q ListQuery) (Page, error) {
+	if err := q.Validate(); err != nil {
+		return Page{}, err
+	}
+	return q.Apply(s.repo.All()), nil
+}
+
+// repoErr translates the repo package's sentinel errors into this
+// package's.
+func repoErr(err error) error {
+	switch {
+	case errors.Is(err, repo.ErrNotFound):
+		return ErrNotFound
+	case errors.Is(err, repo.ErrExists):
+		return ErrExists
+	}
+	return err
+}
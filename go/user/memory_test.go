@@ -0,0 +1,21 @@
+package user_test
+
+import (
+	"testing"
+	"time"
+
+	"example.com/tutorial/user"
+	"example.com/tutorial/user/usertest"
+)
+
+func TestMemoryStore(t *testing.T) {
+	usertest.TestStore(t, func(t *testing.T) user.Store {
+		return user.NewMemoryStore()
+	})
+}
+
+func TestCachedStore(t *testing.T) {
+	usertest.TestStore(t, func(t *testing.T) user.Store {
+		return user.NewCachedStore(user.NewMemoryStore(), time.Minute)
+	})
+}
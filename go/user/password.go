@@ -0,0 +1,45 @@
+package user
+
+import (
+	"golang.org/x/crypto/bcrypt"
+
+	"example.com/tutorial/validate"
+)
+
+// MinPasswordLength is the shortest accepted password, in characters.
+const MinPasswordLength = 8
+
+// maxPasswordBytes is bcrypt's input limit; longer passwords would be
+// silently truncated, so they are rejected instead.
+const maxPasswordBytes = 72
+
+// bcryptCost is a variable so tests can lower it.
+var bcryptCost = bcrypt.DefaultCost
+
+// SetPassword hashes password with bcrypt and stores the hash in u. The
+// plaintext is never retained. Passwords that are too short or too long
+// are rejected with a validate.Errors value.
+func (u *User) SetPassword(password string) error {
+	var v validate.Validator
+	v.Length("password", password, MinPasswordLength, 0)
+	v.Check(len(password) <= maxPasswordBytes, "password", "must be at most 72 bytes")
+	if err := v.Err(); err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = string(hash)
+	return nil
+}
+
+// CheckPassword reports whether password matches the stored hash. It is
+// always false for users without a password.
+func (u User) CheckPassword(password string) bool {
+	if u.PasswordHash == "" {
+		return false
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password))
+	return err == nil
+}
@@ -0,0 +1,45 @@
+package user
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"example.com/tutorial/validate"
+)
+
+func init() { bcryptCost = bcrypt.MinCost }
+
+func TestSetPassword(t *testing.T) {
+	u := User{Name: "Ada"}
+	if u.CheckPassword("") {
+		t.Fatal("user without password accepted empty password")
+	}
+	if err := u.SetPassword("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if u.PasswordHash == "" || strings.Contains(u.PasswordHash, "correct horse") {
+		t.Fatalf("bad hash %q", u.PasswordHash)
+	}
+	if !u.CheckPassword("correct horse") {
+		t.Fatal("correct password rejected")
+	}
+	if u.CheckPassword("wrong horse") {
+		t.Fatal("wrong password accepted")
+	}
+}
+
+func TestSetPasswordRejects(t *testing.T) {
+	for _, pw := range []string{"short", strings.Repeat("x", 73)} {
+		u := User{Name: "Ada"}
+		var verr validate.Errors
+		if err := u.SetPassword(pw); !errors.As(err, &verr) {
+			t.Errorf("SetPassword(len %d) error = %v, want validate.Errors", len(pw), err)
+		}
+		if u.PasswordHash != "" {
+			t.Errorf("hash set for rejected password")
+		}
+	}
+}
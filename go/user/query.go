@@ -0,0 +1,87 @@
+package user
+
+import (
+	"sort"
+	"strings"
+
+	"example.com/tutorial/sliceutil"
+	"example.com/tutorial/validate"
+)
+
+// SortField names a User field that List can order by.
+type SortField string
+
+const (
+	SortByID   SortField = "id"
+	SortByName SortField = "name"
+)
+
+// ListQuery selects, orders and pages the users returned by List. The
+// zero value lists every user ordered by ID.
+type ListQuery struct {
+	// NamePrefix keeps only users whose name starts with it
+	// (case-sensitive).
+	NamePrefix string
+	// Sort is the field to order by; empty means SortByID. Ties are
+	// broken by ID so pages never overlap.
+	Sort SortField
+	// Desc reverses the order.
+	Desc bool
+	// After, if set, keeps only the users that come after it in the
+	// order, by its ID and, when sorting by name, its Name: pass the
+	// last user of a page to get the next. Unlike with Offset, pages
+	// then do not shift as users before them are added or deleted.
+	After *User
+	// Offset skips that many matching users, after After.
+	Offset int
+	// Limit caps the number of users returned; 0 means no limit.
+	Limit int
+}
+
+// Validate reports problems with q as a validate.Errors value.
+func (q ListQuery) Validate() error {
+	var v validate.Validator
+	v.Check(q.Sort == "" || q.Sort == SortByID || q.Sort == SortByName, "sort", "must be id or name")
+	v.Check(q.Offset >= 0, "offset", "must not be negative")
+	v.Check(q.Limit >= 0, "limit", "must not be negative")
+	return v.Err()
+}
+
+// Page is one page of List results.
+type Page struct {
+	Users []User
+	// Total is the number of users matching the query before After,
+	// Offset and Limit were applied.
+	Total int
+}
+
+// Apply filters, sorts and pages users in memory, for stores that cannot
+// evaluate q natively. users is left as it is.
+func (q ListQuery) Apply(users []User) Page {
+	matched := sliceutil.Filter(users, func(u User) bool { return strings.HasPrefix(u.Name, q.NamePrefix) })
+	less := func(a, b User) bool { return a.ID < b.ID }
+	if q.Sort == SortByName {
+		less = func(a, b User) bool {
+			if a.Name != b.Name {
+				return a.Name < b.Name
+			}
+			return a.ID < b.ID
+		}
+	}
+	before := less
+	if q.Desc {
+		before = func(a, b User) bool { return less(b, a) }
+	}
+	sort.Slice(matched, func(i, j int) bool { return before(matched[i], matched[j]) })
+
+	p := Page{Total: len(matched)}
+	if q.After != nil {
+		matched = matched[sort.Search(len(matched), func(i int) bool { return before(*q.After, matched[i]) }):]
+	}
+	matched = matched[min(q.Offset, len(matched)):]
+	if q.Limit > 0 && q.Limit < len(matched) {
+		matched = matched[:q.Limit]
+	}
+	p.Users = matched
+	return p
+}
@@ -0,0 +1,79 @@
+package user
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"io"
+)
+
+// snapshotVersion numbers the layout of memorySnapshot. Version 1 had
+// numeric IDs, which Restore converts as UnmarshalJSON does.
+const snapshotVersion = 2
+
+// memorySnapshot is what Snapshot encodes with gob.
+type memorySnapshot struct {
+	Version int
+	Users   []User
+}
+
+// memorySnapshotV1 is the layout of version 1 snapshots.
+type memorySnapshotV1 struct {
+	Version int
+	Users   []struct {
+		ID           int
+		Name         string
+		PasswordHash string
+	}
+}
+
+// Snapshot writes the users of s to w with encoding/gob, in ID order,
+// for Restore to read back; package snapshot saves it to a file.
+func (s *MemoryStore) Snapshot(w io.Writer) error {
+	users := ListQuery{}.Apply(s.repo.All()).Users
+	if err := gob.NewEncoder(w).Encode(memorySnapshot{Version: snapshotVersion, Users: users}); err != nil {
+		return fmt.Errorf("user: snapshot: %w", err)
+	}
+	return nil
+}
+
+// Restore replaces the users of s with those of a snapshot written by
+// Snapshot. Nothing changes if the snapshot cannot be read. A
+// FileStore's file is not rewritten until its next change.
+func (s *MemoryStore) Restore(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("user: restore: %w", err)
+	}
+	// gob skips the fields the destination lacks, so the version is
+	// read on its own first to choose the layout.
+	var head struct{ Version int }
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&head); err != nil {
+		return fmt.Errorf("user: restore: %w", err)
+	}
+	var snap memorySnapshot
+	switch head.Version {
+	case 1:
+		var v1 memorySnapshotV1
+		err = gob.NewDecoder(bytes.NewReader(data)).Decode(&v1)
+		for _, u := range v1.Users {
+			snap.Users = append(snap.Users, User{ID: legacyID(u.ID), Name: u.Name, PasswordHash: u.PasswordHash})
+		}
+	case snapshotVersion:
+		err = gob.NewDecoder(bytes.NewReader(data)).Decode(&snap)
+	default:
+		return fmt.Errorf("user: restore: unsupported snapshot version %d", head.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("user: restore: %w", err)
+	}
+	for _, u := range snap.Users {
+		if err := u.Validate(); err != nil {
+			return fmt.Errorf("user: restore: user %q: %w", u.ID, err)
+		}
+	}
+	if err := s.repo.Restore(snap.Users); err != nil {
+		return fmt.Errorf("user: restore: %w", repoErr(err))
+	}
+	return nil
+}
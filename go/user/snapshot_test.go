@@ -0,0 +1,71 @@
+package user_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"testing"
+
+	"example.com/tutorial/user"
+)
+
+func TestMemoryStoreSnapshot(t *testing.T) {
+	ctx := context.Background()
+	s := user.NewMemoryStore()
+	var created []user.User
+	for _, name := range []string{"ann", "bob", "cy"} {
+		u, err := s.Create(ctx, user.User{Name: name, PasswordHash: "$2a$" + name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		created = append(created, u)
+	}
+	s.Delete(ctx, created[1].ID)
+	var buf bytes.Buffer
+	if err := s.Snapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := user.NewMemoryStore()
+	restored.Create(ctx, user.User{Name: "stale"})
+	if err := restored.Restore(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	page, _ := restored.List(ctx, user.ListQuery{})
+	if page.Total != 2 || page.Users[0] != created[0] || page.Users[1].Name != "cy" {
+		t.Fatalf("restored %+v", page.Users)
+	}
+	dee, err := restored.Create(ctx, user.User{Name: "dee"})
+	if err != nil || dee.ID <= created[2].ID {
+		t.Fatalf("create after restore: %+v, %v", dee, err)
+	}
+
+	if err := restored.Restore(bytes.NewReader([]byte("garbage"))); err == nil {
+		t.Fatal("restored garbage")
+	}
+	if _, err := restored.Get(ctx, dee.ID); errors.Is(err, user.ErrNotFound) {
+		t.Fatal("failed restore changed the store")
+	}
+}
+
+func TestMemoryStoreRestoreVersion1(t *testing.T) {
+	// Version 1 snapshots had numeric IDs.
+	type userV1 struct {
+		ID   int
+		Name string
+	}
+	var buf bytes.Buffer
+	gob.NewEncoder(&buf).Encode(struct {
+		Version int
+		Users   []userV1
+	}{1, []userV1{{1, "ann"}, {3, "cy"}}})
+
+	s := user.NewMemoryStore()
+	if err := s.Restore(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if u, err := s.Get(context.Background(), "3"); err != nil || u.Name != "cy" {
+		t.Fatalf("Get(3) = %+v, %v", u, err)
+	}
+}
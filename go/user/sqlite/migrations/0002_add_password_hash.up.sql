@@ -0,0 +1,2 @@
+-- Users gained passwords; existing users have none and cannot log in.
+ALTER TABLE users ADD COLUMN password_hash TEXT NOT NULL DEFAULT '';
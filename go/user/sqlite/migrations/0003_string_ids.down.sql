@@ -0,0 +1,12 @@
+-- Only users whose IDs are numbers fit the old table; the others are
+-- dropped.
+CREATE TABLE users_old (
+	id            INTEGER PRIMARY KEY,
+	name          TEXT NOT NULL,
+	password_hash TEXT NOT NULL DEFAULT ''
+);
+INSERT INTO users_old (id, name, password_hash)
+	SELECT CAST(id AS INTEGER), name, password_hash FROM users
+	WHERE id NOT GLOB '*[^0-9]*' AND id NOT GLOB '0*';
+DROP TABLE users;
+ALTER TABLE users_old RENAME TO users;
@@ -0,0 +1,12 @@
+-- User IDs became strings, such as those of id.New; existing users keep
+-- their numbers in decimal. SQLite cannot change a column's type, so the
+-- table is copied.
+CREATE TABLE users_new (
+	id            TEXT PRIMARY KEY,
+	name          TEXT NOT NULL,
+	password_hash TEXT NOT NULL DEFAULT ''
+);
+INSERT INTO users_new (id, name, password_hash)
+	SELECT CAST(id AS TEXT), name, password_hash FROM users;
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
@@ -0,0 +1,373 @@
+// Package sqlite implements user.Store on top of an SQLite database using
+// database/sql and the pure-Go modernc.org/sqlite driver.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+
+	"example.com/tutorial/id"
+	"example.com/tutorial/migrate"
+	"example.com/tutorial/retry"
+	"example.com/tutorial/user"
+
+	driver "modernc.org/sqlite" // also registers the "sqlite" driver
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// migrationFiles holds the schema of the store as migrations, applied
+// by New or by the "migrate" command.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// MigrationFiles returns the files of the migrations of the store's
+// schema, for a program to put files on disk in front of and read with
+// migrate.Load(fsys, ".").
+func MigrationFiles() fs.FS {
+	sub, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		panic(err) // the directory is embedded, so this cannot happen
+	}
+	return sub
+}
+
+// Migrations returns the migrations of the store's schema.
+func Migrations() []migrate.Migration {
+	m, err := migrate.Load(MigrationFiles(), ".")
+	if err != nil {
+		panic(err) // the embedded files are fixed at build time
+	}
+	return m
+}
+
+// Migrator returns a Migrator for the store's schema in db. Databases
+// created before the schema had migrations are baselined first: the
+// migrations their tables already reflect are recorded as applied.
+func Migrator(ctx context.Context, db *sql.DB) (*migrate.Migrator, error) {
+	return MigratorFrom(ctx, db, Migrations())
+}
+
+// MigratorFrom is Migrator with the migrations ms, such as those of
+// MigrationFiles with a directory of them in front, in place of
+// Migrations.
+func MigratorFrom(ctx context.Context, db *sql.DB, ms []migrate.Migration) (*migrate.Migrator, error) {
+	m := migrate.New(db, ms)
+	cols, err := userColumns(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	status, err := m.Status(ctx)
+	if err != nil || len(cols) == 0 {
+		return m, err
+	}
+	for _, st := range status {
+		if st.Applied {
+			return m, nil
+		}
+	}
+	version := 1
+	if cols["password_hash"] {
+		version = 2
+	}
+	return m, m.Baseline(ctx, version)
+}
+
+// busyRetry is how a Store retries statements that found the database
+// busy or locked by another connection, as happens when processes share
+// the file.
+var busyRetry = retry.Policy{
+	MaxAttempts: 5,
+	BaseDelay:   10 * time.Millisecond,
+	MaxDelay:    250 * time.Millisecond,
+	Retryable:   transient,
+}
+
+// transient reports whether err is SQLite saying the database is busy
+// or locked, which clears once the other connection is done with it.
+func transient(err error) bool {
+	var se *driver.Error
+	if !errors.As(err, &se) {
+		return false
+	}
+	switch se.Code() & 0xff { // the primary code of an extended one
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+		return true
+	}
+	return false
+}
+
+// Store is a user.Store backed by SQLite.
+type Store struct {
+	db     *sql.DB
+	ownsDB bool
+	retry  retry.Policy
+
+	insert *sql.Stmt
+	get    *sql.Stmt
+	update *sql.Stmt
+	del    *sql.Stmt
+}
+
+// Open opens (creating if needed) the SQLite database at path and returns
+// a store for it. Use ":memory:" for a throwaway database.
+func Open(ctx context.Context, path string) (*Store, error) {
+	return OpenFrom(ctx, path, Migrations())
+}
+
+// OpenFrom is Open migrating the database with ms, as MigratorFrom
+// does.
+func OpenFrom(ctx context.Context, path string, ms []migrate.Migration) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite serializes writers anyway, and an in-memory database exists
+	// per connection, so a single connection keeps both cases correct.
+	db.SetMaxOpenConns(1)
+	s, err := newStore(ctx, db, ms)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	s.ownsDB = true
+	return s, nil
+}
+
+// New migrates the schema of db to the current version, creating the
+// users table if it is missing, and prepares the store's statements.
+// Close releases the statements but not db.
+func New(ctx context.Context, db *sql.DB) (*Store, error) {
+	return newStore(ctx, db, Migrations())
+}
+
+func newStore(ctx context.Context, db *sql.DB, ms []migrate.Migration) (*Store, error) {
+	m, err := MigratorFrom(ctx, db, ms)
+	if err == nil {
+		_, err = m.Up(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: %w", err)
+	}
+	s := &Store{db: db, retry: busyRetry}
+	stmts := []struct {
+		dst   **sql.Stmt
+		query string
+	}{
+		{&s.insert, `INSERT INTO users (id, name, password_hash) VALUES (?, ?, ?)`},
+		{&s.get, `SELECT id, name, password_hash FROM users WHERE id = ?`},
+		{&s.update, `UPDATE users SET name = ?, password_hash = ? WHERE id = ?`},
+		{&s.del, `DELETE FROM users WHERE id = ?`},
+	}
+	for _, st := range stmts {
+		stmt, err := db.PrepareContext(ctx, st.query)
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("sqlite: prepare %q: %w", st.query, err)
+		}
+		*st.dst = stmt
+	}
+	return s, nil
+}
+
+// userColumns returns the columns of the users table, none if there is
+// no such table.
+func userColumns(ctx context.Context, db *sql.DB) (map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, `SELECT name FROM pragma_table_info('users')`)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: %w", err)
+	}
+	defer rows.Close()
+	cols := map[string]bool{}
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			return nil, fmt.Errorf("sqlite: %w", err)
+		}
+		cols[col] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sqlite: %w", err)
+	}
+	return cols, nil
+}
+
+// DB returns the underlying database handle.
+func (s *Store) DB() *sql.DB { return s.db }
+
+// Close releases the prepared statements. If the store was created by
+// Open it also closes the database.
+func (s *Store) Close() error {
+	for _, st := range []*sql.Stmt{s.insert, s.get, s.update, s.del} {
+		if st != nil {
+			st.Close()
+		}
+	}
+	if s.ownsDB {
+		return s.db.Close()
+	}
+	return nil
+}
+
+// Create implements user.Store.
+func (s *Store) Create(ctx context.Context, u user.User) (user.User, error) {
+	if err := u.Validate(); err != nil {
+		return user.User{}, err
+	}
+	if u.ID == "" {
+		u.ID = id.New() // before the transaction, which may be retried
+	}
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := getUser(ctx, tx.StmtContext(ctx, s.get), u.ID); err == nil {
+			return user.ErrExists
+		} else if !errors.Is(err, user.ErrNotFound) {
+			return err
+		}
+		_, err := tx.StmtContext(ctx, s.insert).ExecContext(ctx, u.ID, u.Name, u.PasswordHash)
+		return err
+	})
+	if err != nil {
+		return user.User{}, err
+	}
+	return u, nil
+}
+
+// Get implements user.Store.
+func (s *Store) Get(ctx context.Context, id string) (user.User, error) {
+	var u user.User
+	err := retry.Do(ctx, s.retry, func(ctx context.Context) (err error) {
+		u, err = getUser(ctx, s.get, id)
+		return err
+	})
+	return u, err
+}
+
+func getUser(ctx context.Context, stmt *sql.Stmt, id string) (user.User, error) {
+	var u user.User
+	err := stmt.QueryRowContext(ctx, id).Scan(&u.ID, &u.Name, &u.PasswordHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return user.User{}, user.ErrNotFound
+	}
+	return u, err
+}
+
+// Update implements user.Store. The existence check and the write run in
+// one transaction.
+func (s *Store) Update(ctx context.Context, u user.User) error {
+	if err := u.Validate(); err != nil {
+		return err
+	}
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.StmtContext(ctx, s.update).ExecContext(ctx, u.Name, u.PasswordHash, u.ID)
+		if err != nil {
+			return err
+		}
+		return requireRow(res)
+	})
+}
+
+// Delete implements user.Store.
+func (s *Store) Delete(ctx context.Context, id string) error {
+	return retry.Do(ctx, s.retry, func(ctx context.Context) error {
+		res, err := s.del.ExecContext(ctx, id)
+		if err != nil {
+			return err
+		}
+		return requireRow(res)
+	})
+}
+
+// List implements user.Store. The count and the page are read in one
+// transaction so Total matches the returned rows.
+func (s *Store) List(ctx context.Context, q user.ListQuery) (user.Page, error) {
+	if err := q.Validate(); err != nil {
+		return user.Page{}, err
+	}
+	// Only whitelisted identifiers are interpolated; values are bound.
+	where := `WHERE substr(name, 1, length(?1)) = ?1`
+	order := "id"
+	if q.Sort == user.SortByName {
+		order = "name, id"
+	}
+	if q.Desc {
+		order = strings.ReplaceAll(order, ",", " DESC,") + " DESC"
+	}
+	limit := -1
+	if q.Limit > 0 {
+		limit = q.Limit
+	}
+	// The page continues after q.After in the same order.
+	after := ""
+	var afterID, afterName string
+	if q.After != nil {
+		op := ">"
+		if q.Desc {
+			op = "<"
+		}
+		after = ` AND id ` + op + ` ?4`
+		if q.Sort == user.SortByName {
+			after = ` AND (name ` + op + ` ?5 OR (name = ?5 AND id ` + op + ` ?4))`
+		}
+		afterID, afterName = q.After.ID, q.After.Name
+	}
+
+	page := user.Page{Users: []user.User{}}
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM users `+where, q.NamePrefix).Scan(&page.Total); err != nil {
+			return err
+		}
+		rows, err := tx.QueryContext(ctx,
+			`SELECT id, name, password_hash FROM users `+where+after+` ORDER BY `+order+` LIMIT ?2 OFFSET ?3`,
+			q.NamePrefix, limit, q.Offset, afterID, afterName)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var u user.User
+			if err := rows.Scan(&u.ID, &u.Name, &u.PasswordHash); err != nil {
+				return err
+			}
+			page.Users = append(page.Users, u)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return user.Page{}, err
+	}
+	return page, nil
+}
+
+// withTx runs fn in a transaction, committing if it returns nil. The
+// whole transaction is run again if the database was busy, so fn must
+// not keep state from a failed attempt.
+func (s *Store) withTx(ctx context.Context, fn func(*sql.Tx) error) error {
+	return retry.Do(ctx, s.retry, func(ctx context.Context) error {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if err := fn(tx); err != nil {
+			tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	})
+}
+
+func requireRow(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return user.ErrNotFound
+	}
+	return nil
+}
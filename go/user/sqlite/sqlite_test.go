@@ -0,0 +1,201 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"example.com/tutorial/migrate"
+	"example.com/tutorial/user"
+	"example.com/tutorial/user/usertest"
+)
+
+func TestStore(t *testing.T) {
+	usertest.TestStore(t, func(t *testing.T) user.Store {
+		s, err := Open(context.Background(), ":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Close() })
+		return s
+	})
+}
+
+func TestStorePersists(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "users.db")
+	s, err := Open(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := s.Create(ctx, user.User{Name: "Ada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = Open(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	got, err := s.Get(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != u {
+		t.Fatalf("Get = %+v, want %+v", got, u)
+	}
+}
+
+func TestUpgradeAddsPasswordColumn(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "old.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+		INSERT INTO users (id, name) VALUES (1, 'Ada')`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	s, err := Open(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	u, err := s.Get(ctx, "1")
+	if err != nil || u != (user.User{ID: "1", Name: "Ada"}) {
+		t.Fatalf("Get = %+v, %v", u, err)
+	}
+	u.PasswordHash = "hash"
+	if err := s.Update(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "users.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// A database of the schema before migrations, passwords included.
+	if _, err := db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, password_hash TEXT NOT NULL DEFAULT '')`); err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	m, err := Migrator(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := m.Status(ctx)
+	if err != nil || len(status) != 3 || !status[0].Applied || !status[1].Applied || !status[2].Applied {
+		t.Fatalf("status = %+v, %v", status, err)
+	}
+
+	done, err := m.Down(ctx, 2)
+	if err != nil || len(done) != 2 || done[1].Name != "add_password_hash" {
+		t.Fatalf("down = %v, %v", done, err)
+	}
+	if cols, _ := userColumns(ctx, db); cols["password_hash"] || !cols["name"] {
+		t.Fatalf("columns after down: %v", cols)
+	}
+	// Reopening applies it again.
+	s, err = New(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if cols, _ := userColumns(ctx, db); !cols["password_hash"] {
+		t.Fatalf("columns after up: %v", cols)
+	}
+}
+
+func TestOpenFrom(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"0004_add_email.up.sql":   {Data: []byte("ALTER TABLE users ADD COLUMN email TEXT NOT NULL DEFAULT '';")},
+		"0004_add_email.down.sql": {Data: []byte("ALTER TABLE users DROP COLUMN email;")},
+	}
+	for _, name := range []string{"0001_create_users.up.sql", "0001_create_users.down.sql", "0002_add_password_hash.up.sql", "0002_add_password_hash.down.sql", "0003_string_ids.up.sql", "0003_string_ids.down.sql"} {
+		data, err := fs.ReadFile(MigrationFiles(), name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fsys[name] = &fstest.MapFile{Data: data}
+	}
+	ms, err := migrate.Load(fsys, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := OpenFrom(ctx, ":memory:", ms)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if cols, _ := userColumns(ctx, s.DB()); !cols["email"] || !cols["password_hash"] {
+		t.Fatalf("columns: %v", cols)
+	}
+	if _, err := s.Create(ctx, user.User{Name: "ada"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRetriesWhileLocked(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "shared.db")
+	a, err := Open(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := Open(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	var retries int
+	b.retry.OnRetry = func(int, time.Duration, error) { retries++ }
+
+	// Another connection holds the write lock for a moment.
+	tx, err := a.DB().BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec(`INSERT INTO users (name) VALUES ('Ada')`); err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(30*time.Millisecond, func() { tx.Commit() })
+
+	u, err := b.Create(ctx, user.User{Name: "Grace"})
+	if err != nil {
+		t.Fatalf("Create while locked: %v", err)
+	}
+	if retries == 0 {
+		t.Fatal("Create succeeded without meeting the lock")
+	}
+	if got, err := a.Get(ctx, u.ID); err != nil || got.Name != "Grace" {
+		t.Fatalf("Get = %+v, %v", got, err)
+	}
+
+	// Errors other than a busy database are not retried.
+	retries = 0
+	if err := b.Delete(ctx, "999"); err != user.ErrNotFound || retries != 0 {
+		t.Fatalf("Delete = %v after %d retries", err, retries)
+	}
+}
@@ -0,0 +1,88 @@
+//go:build stress
+
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"example.com/tutorial/stress"
+)
+
+// TestStress has many goroutines create, read, rename, list and delete
+// users in the in-memory store, straight and through a CachedStore, and
+// then checks that the store holds exactly the users that were not
+// deleted.
+func TestStress(t *testing.T) {
+	for name, newStore := range map[string]func() Store{
+		"memory": func() Store { return NewMemoryStore() },
+		"cached": func() Store { return NewCachedStore(NewMemoryStore(), time.Minute) },
+	} {
+		t.Run(name, func(t *testing.T) { stressStore(t, newStore()) })
+	}
+}
+
+func stressStore(t *testing.T, s Store) {
+	ctx := context.Background()
+	var created, deleted atomic.Int64
+	// The last user each goroutine created, which it renames and
+	// deletes and its neighbour reads.
+	var lastIDs sync.Map // goroutine => user ID
+	stress.Run(t, stress.Config{}, func(g, i int) {
+		switch i % 5 {
+		case 0:
+			u, err := s.Create(ctx, User{Name: fmt.Sprintf("user-%d-%d", g, i)})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			created.Add(1)
+			lastIDs.Store(g, u.ID)
+		case 1, 2:
+			id, ok := lastIDs.Load(g ^ 1)
+			if !ok {
+				return
+			}
+			if _, err := s.Get(ctx, id.(string)); err != nil && !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get(%d) = %v", id, err)
+			}
+		case 3:
+			id, ok := lastIDs.Load(g)
+			if !ok {
+				return
+			}
+			err := s.Update(ctx, User{ID: id.(string), Name: fmt.Sprintf("renamed-%d-%d", g, i)})
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				t.Errorf("Update(%d) = %v", id, err)
+			}
+		case 4:
+			if i%20 == 4 {
+				if _, err := s.List(ctx, ListQuery{NamePrefix: "renamed", Limit: 10}); err != nil {
+					t.Error(err)
+				}
+				return
+			}
+			id, ok := lastIDs.LoadAndDelete(g)
+			if !ok {
+				return
+			}
+			if err := s.Delete(ctx, id.(string)); err != nil {
+				t.Errorf("Delete(%d) = %v", id, err)
+				return
+			}
+			deleted.Add(1)
+		}
+	})
+	page, err := s.List(ctx, ListQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := created.Load() - deleted.Load(); int64(page.Total) != want {
+		t.Fatalf("%d users stored, want %d created less %d deleted", page.Total, created.Load(), deleted.Load())
+	}
+}
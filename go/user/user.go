@@ -0,0 +1,57 @@
+// Package user defines the User entity and the stores that persist it.
+package user
+
+import (
+	"context"
+
+	"example.com/tutorial/errs"
+	"example.com/tutorial/id"
+	"example.com/tutorial/validate"
+)
+
+// User is a registered user of the service.
+type User struct {
+	ID   string `csv:"id"`
+	Name string `csv:"name,required"`
+	// PasswordHash is the bcrypt hash set by SetPassword; empty if the
+	// user has no password and cannot log in.
+	PasswordHash string `csv:"password_hash"`
+}
+
+// MaxNameLength is the longest accepted user name, in characters.
+const MaxNameLength = 100
+
+// Validate reports every problem with u as a validate.Errors value, or
+// returns nil if u may be stored.
+func (u User) Validate() error {
+	var v validate.Validator
+	v.Check(u.ID == "" || id.Valid(u.ID), "id", "must be letters, digits, - and _")
+	v.Required("name", u.Name)
+	v.Length("name", u.Name, 0, MaxNameLength)
+	return v.Err()
+}
+
+var (
+	// ErrNotFound is returned when no user has the requested ID.
+	ErrNotFound = errs.New(errs.ErrNotFound, "user: not found")
+	// ErrExists is returned when creating a user whose ID is taken.
+	ErrExists = errs.New(errs.ErrConflict, "user: already exists")
+)
+
+// Store persists users. Implementations must be safe for concurrent use.
+type Store interface {
+	// Create adds u and returns it as stored. An empty ID is replaced by
+	// a new one from id.New; an ID already in use yields ErrExists. Invalid
+	// users are rejected with the error from Validate.
+	Create(ctx context.Context, u User) (User, error)
+	// Get returns the user with the given ID or ErrNotFound.
+	Get(ctx context.Context, id string) (User, error)
+	// Update replaces the stored user with u.ID, or returns ErrNotFound.
+	// Invalid users are rejected like in Create.
+	Update(ctx context.Context, u User) error
+	// Delete removes the user with the given ID, or returns ErrNotFound.
+	Delete(ctx context.Context, id string) error
+	// List returns the page of users selected by q. An invalid query is
+	// rejected with the error from ListQuery.Validate.
+	List(ctx context.Context, q ListQuery) (Page, error)
+}
@@ -0,0 +1,41 @@
+package user
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"example.com/tutorial/validate"
+)
+
+func TestUserValidate(t *testing.T) {
+	tests := []struct {
+		u      User
+		fields []string
+	}{
+		{User{ID: "1", Name: "Ada"}, nil},
+		{User{Name: "Ada"}, nil},
+		{User{ID: "a b", Name: ""}, []string{"id", "name"}},
+		{User{Name: strings.Repeat("x", MaxNameLength+1)}, []string{"name"}},
+	}
+	for _, tt := range tests {
+		err := tt.u.Validate()
+		if tt.fields == nil {
+			if err != nil {
+				t.Errorf("Validate(%+v) = %v", tt.u, err)
+			}
+			continue
+		}
+		var verr validate.Errors
+		if !errors.As(err, &verr) {
+			t.Fatalf("Validate(%+v) = %v, want validate.Errors", tt.u, err)
+		}
+		var got []string
+		for _, fe := range verr {
+			got = append(got, fe.Field)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.fields, ",") {
+			t.Errorf("Validate(%.20v) fields = %v, want %v", tt.u, got, tt.fields)
+		}
+	}
+}
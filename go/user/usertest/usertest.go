@@ -0,0 +1,265 @@
+// Package usertest provides a conformance suite for user.Store
+// implementations.
+package usertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"example.com/tutorial/user"
+	"example.com/tutorial/validate"
+)
+
+// TestStore runs the conformance suite against stores returned by
+// newStore, which must return a fresh, empty store on every call.
+func TestStore(t *testing.T, newStore func(t *testing.T) user.Store) {
+	t.Run("CreateGet", func(t *testing.T) { testCreateGet(t, newStore(t)) })
+	t.Run("CreateExplicitID", func(t *testing.T) { testCreateExplicitID(t, newStore(t)) })
+	t.Run("UpdateDelete", func(t *testing.T) { testUpdateDelete(t, newStore(t)) })
+	t.Run("RejectInvalid", func(t *testing.T) { testRejectInvalid(t, newStore(t)) })
+	t.Run("List", func(t *testing.T) { testList(t, newStore(t)) })
+	t.Run("ListQuery", func(t *testing.T) { testListQuery(t, newStore(t)) })
+	t.Run("Concurrent", func(t *testing.T) { testConcurrent(t, newStore(t)) })
+}
+
+func testCreateGet(t *testing.T, s user.Store) {
+	ctx := context.Background()
+	a, err := s.Create(ctx, user.User{Name: "Ada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.Create(ctx, user.User{Name: "Grace", PasswordHash: "$2a$04$notarealhash"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.ID == "" || b.ID == "" || a.ID >= b.ID {
+		t.Fatalf("bad assigned IDs: %q, %q", a.ID, b.ID)
+	}
+	got, err := s.Get(ctx, a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != a {
+		t.Fatalf("Get = %+v, want %+v", got, a)
+	}
+	if got, _ := s.Get(ctx, b.ID); got != b {
+		t.Fatalf("Get = %+v, want %+v", got, b)
+	}
+	if _, err := s.Get(ctx, "missing"); !errors.Is(err, user.ErrNotFound) {
+		t.Fatalf("Get(missing) error = %v, want ErrNotFound", err)
+	}
+}
+
+func testCreateExplicitID(t *testing.T, s user.Store) {
+	ctx := context.Background()
+	u, err := s.Create(ctx, user.User{ID: "7", Name: "Ada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != "7" {
+		t.Fatalf("ID = %q, want 7", u.ID)
+	}
+	if _, err := s.Create(ctx, user.User{ID: "7", Name: "Bob"}); !errors.Is(err, user.ErrExists) {
+		t.Fatalf("duplicate Create error = %v, want ErrExists", err)
+	}
+	next, err := s.Create(ctx, user.User{Name: "Grace"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.ID == "7" {
+		t.Fatal("assigned an ID that is already taken")
+	}
+}
+
+func testUpdateDelete(t *testing.T, s user.Store) {
+	ctx := context.Background()
+	u, err := s.Create(ctx, user.User{Name: "Ada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.Name = "Ada Lovelace"
+	if err := s.Update(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.Get(ctx, u.ID); got.Name != "Ada Lovelace" {
+		t.Fatalf("after Update, Get = %+v", got)
+	}
+	if err := s.Update(ctx, user.User{ID: "missing", Name: "x"}); !errors.Is(err, user.ErrNotFound) {
+		t.Fatalf("Update(missing) error = %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(ctx, u.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, u.ID); !errors.Is(err, user.ErrNotFound) {
+		t.Fatalf("Get after Delete error = %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(ctx, u.ID); !errors.Is(err, user.ErrNotFound) {
+		t.Fatalf("second Delete error = %v, want ErrNotFound", err)
+	}
+}
+
+func testRejectInvalid(t *testing.T, s user.Store) {
+	ctx := context.Background()
+	var verr validate.Errors
+	if _, err := s.Create(ctx, user.User{Name: " "}); !errors.As(err, &verr) {
+		t.Fatalf("Create(blank name) error = %v, want validate.Errors", err)
+	}
+	if _, err := s.Create(ctx, user.User{ID: "../7", Name: "Ada"}); !errors.As(err, &verr) {
+		t.Fatalf("Create(bad ID) error = %v, want validate.Errors", err)
+	}
+	u, err := s.Create(ctx, user.User{Name: "Ada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.Name = ""
+	if err := s.Update(ctx, u); !errors.As(err, &verr) {
+		t.Fatalf("Update(blank name) error = %v, want validate.Errors", err)
+	}
+	if got, _ := s.Get(ctx, u.ID); got.Name != "Ada" {
+		t.Fatalf("invalid update was stored: %+v", got)
+	}
+	if page, _ := s.List(ctx, user.ListQuery{}); page.Total != 1 {
+		t.Fatalf("invalid create was stored: %+v", page.Users)
+	}
+}
+
+func testList(t *testing.T, s user.Store) {
+	ctx := context.Background()
+	page, err := s.List(ctx, user.ListQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Users) != 0 || page.Total != 0 {
+		t.Fatalf("new store lists %+v", page)
+	}
+	var want []user.User
+	for _, id := range []string{"5", "2", "9"} {
+		u, err := s.Create(ctx, user.User{ID: id, Name: fmt.Sprint("user", id)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, u)
+	}
+	want[0], want[1] = want[1], want[0]
+	if got, _ := s.List(ctx, user.ListQuery{}); !reflect.DeepEqual(got.Users, want) || got.Total != 3 {
+		t.Fatalf("List = %+v, want %+v", got, want)
+	}
+}
+
+func testListQuery(t *testing.T, s user.Store) {
+	ctx := context.Background()
+	for _, name := range []string{"bob", "alice", "anna", "carol", "alan", "anna"} {
+		if _, err := s.Create(ctx, user.User{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	names := func(p user.Page) []string {
+		out := []string{}
+		for _, u := range p.Users {
+			out = append(out, u.Name)
+		}
+		return out
+	}
+	tests := []struct {
+		q     user.ListQuery
+		want  []string
+		total int
+	}{
+		{user.ListQuery{}, []string{"bob", "alice", "anna", "carol", "alan", "anna"}, 6},
+		{user.ListQuery{Limit: 2}, []string{"bob", "alice"}, 6},
+		{user.ListQuery{Offset: 4, Limit: 5}, []string{"alan", "anna"}, 6},
+		{user.ListQuery{Offset: 10}, []string{}, 6},
+		{user.ListQuery{Sort: user.SortByName}, []string{"alan", "alice", "anna", "anna", "bob", "carol"}, 6},
+		{user.ListQuery{Sort: user.SortByName, Desc: true, Limit: 3}, []string{"carol", "bob", "anna"}, 6},
+		{user.ListQuery{NamePrefix: "a", Sort: user.SortByName, Offset: 1, Limit: 2}, []string{"alice", "anna"}, 4},
+		{user.ListQuery{NamePrefix: "an", Desc: true}, []string{"anna", "anna"}, 2},
+		{user.ListQuery{NamePrefix: "A"}, []string{}, 0},
+	}
+	for _, tt := range tests {
+		page, err := s.List(ctx, tt.q)
+		if err != nil {
+			t.Fatalf("List(%+v): %v", tt.q, err)
+		}
+		if got := names(page); !reflect.DeepEqual(got, tt.want) || page.Total != tt.total {
+			t.Errorf("List(%+v) = %v (total %d), want %v (total %d)", tt.q, got, page.Total, tt.want, tt.total)
+		}
+	}
+	// Equal names come out in ID order, so descending is a true reversal.
+	asc, _ := s.List(ctx, user.ListQuery{NamePrefix: "anna"})
+	desc, _ := s.List(ctx, user.ListQuery{NamePrefix: "anna", Desc: true})
+	if len(asc.Users) != 2 || asc.Users[0] != desc.Users[1] || asc.Users[1] != desc.Users[0] {
+		t.Errorf("ascending %+v is not the reverse of descending %+v", asc.Users, desc.Users)
+	}
+	// Paging with After visits every user once, in order, in each order.
+	for _, q := range []user.ListQuery{{}, {Desc: true}, {Sort: user.SortByName}, {Sort: user.SortByName, Desc: true, NamePrefix: "a"}} {
+		all, _ := s.List(ctx, q)
+		var got []user.User
+		for page := q; ; {
+			page.Limit = 2
+			p, err := s.List(ctx, page)
+			if err != nil || p.Total != all.Total {
+				t.Fatalf("List(%+v) = %+v, %v", page, p, err)
+			}
+			got = append(got, p.Users...)
+			if len(p.Users) < 2 || len(got) > all.Total {
+				break
+			}
+			page.After = &p.Users[len(p.Users)-1]
+		}
+		if len(got) == 0 {
+			got = []user.User{}
+		}
+		if !reflect.DeepEqual(got, all.Users) {
+			t.Errorf("paging %+v with After gave %v, want %v", q, names(user.Page{Users: got}), names(all))
+		}
+	}
+	// After need not name a stored user.
+	page, err := s.List(ctx, user.ListQuery{Sort: user.SortByName, After: &user.User{ID: "", Name: "anna"}})
+	if err != nil || !reflect.DeepEqual(names(page), []string{"anna", "anna", "bob", "carol"}) {
+		t.Errorf("List after a name alone = %v, %v", names(page), err)
+	}
+	var verr validate.Errors
+	for _, q := range []user.ListQuery{{Sort: "email"}, {Offset: -1}, {Limit: -1}} {
+		if _, err := s.List(ctx, q); !errors.As(err, &verr) {
+			t.Errorf("List(%+v) error = %v, want validate.Errors", q, err)
+		}
+	}
+}
+
+func testConcurrent(t *testing.T, s user.Store) {
+	ctx := context.Background()
+	const n = 20
+	var wg sync.WaitGroup
+	ids := make(chan string, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			u, err := s.Create(ctx, user.User{Name: fmt.Sprint("user", i)})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := s.Get(ctx, u.ID); err != nil {
+				t.Error(err)
+			}
+			ids <- u.ID
+		}(i)
+	}
+	wg.Wait()
+	close(ids)
+	seen := map[string]bool{}
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("ID %q assigned twice", id)
+		}
+		seen[id] = true
+	}
+	if page, _ := s.List(ctx, user.ListQuery{}); page.Total != n {
+		t.Fatalf("List returned %d users, want %d", page.Total, n)
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"example.com/tutorial/i18n"
+	"example.com/tutorial/id"
+	"example.com/tutorial/jsonstream"
+	"example.com/tutorial/user"
+)
+
+// defaultUsersFile is the user file of the "users" subcommand when -file
+// is not given; "serve -users" serves the same format.
+const defaultUsersFile = "users.json"
+
+// runUsers implements the "users" subcommand, with its "list", "add",
+// "get", "delete", "import" and "export" actions on a user file, and
+// returns the process exit code. A dry run makes the changes to the
+// users in memory and prints the files it would write.
+func runUsers(ctx context.Context, cfg usersSettings, dryRun bool, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("users", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: users [-file path] list [-prefix s] [-sort id|name] [-desc] [-offset n] [-limit n] [-json]")
+		fmt.Fprintln(stderr, "       users [-file path] add name ...")
+		fmt.Fprintln(stderr, "       users [-file path] get|delete id ...")
+		fmt.Fprintln(stderr, "       users [-file path] import file.csv|file.jsonl")
+		fmt.Fprintln(stderr, "       users [-file path] export")
+		fs.PrintDefaults()
+	}
+	path := fs.String("file", cfg.File, "the JSON user `file`, created if missing")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	action, args := fs.Arg(0), fs.Args()[1:]
+	var run func(ctx context.Context, s user.Store, args []string, stdout, stderr io.Writer) int
+	switch action {
+	case "list":
+		run = runUsersList
+	case "add":
+		run = runUsersAdd
+	case "get":
+		run = runUsersGet
+	case "delete":
+		run = runUsersDelete
+	case "import":
+		run = runUsersImport
+	case "export":
+		run = runUsersExport
+	default:
+		fs.Usage()
+		return 2
+	}
+	s, err := user.OpenFileStoreWithConfig(*path, user.FileStoreConfig{Files: newExecutor(dryRun, stdout)})
+	if err != nil {
+		fmt.Fprintln(stderr, "users:", err)
+		return 1
+	}
+	return run(ctx, s, args, stdout, stderr)
+}
+
+func runUsersList(ctx context.Context, s user.Store, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("users list", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var q user.ListQuery
+	fs.StringVar(&q.NamePrefix, "prefix", "", "list only users whose name starts with `s`")
+	fs.StringVar((*string)(&q.Sort), "sort", string(user.SortByID), "order by `field`: id or name")
+	fs.BoolVar(&q.Desc, "desc", false, "reverse the order")
+	fs.IntVar(&q.Offset, "offset", 0, "skip the first `n` users")
+	fs.IntVar(&q.Limit, "limit", 0, "list at most `n` users, 0 for all")
+	asJSON := fs.Bool("json", false, "print the users as a JSON array")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+	if err := q.Validate(); err != nil {
+		fmt.Fprintln(stderr, "users list:", err)
+		return 2
+	}
+	page, err := s.List(ctx, q)
+	if err != nil {
+		fmt.Fprintln(stderr, "users list:", err)
+		return 1
+	}
+	if *asJSON {
+		err = writeUsersJSON(stdout, page.Users)
+	} else {
+		for _, u := range page.Users {
+			fmt.Fprintf(stdout, "%s\t%s\n", u.ID, u.Name)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "users list:", err)
+		return 1
+	}
+	if len(page.Users) < page.Total {
+		fmt.Fprintln(stderr, i18n.FromContext(ctx).Plural("%d of %d users", page.Total, len(page.Users), page.Total))
+	}
+	return 0
+}
+
+// writeUsersJSON streams users to w as a JSON array, in the format of
+// the user file, which "users import" reads back one per line.
+func writeUsersJSON(w io.Writer, users []user.User) error {
+	enc := jsonstream.NewArrayEncoder(w)
+	for _, u := range users {
+		if err := enc.Encode(u); err != nil {
+			return err
+		}
+	}
+	return enc.Close()
+}
+
+func runUsersAdd(ctx context.Context, s user.Store, names []string, stdout, stderr io.Writer) int {
+	if len(names) == 0 {
+		fmt.Fprintln(stderr, "usage: users add name ...")
+		return 2
+	}
+	status := 0
+	for _, name := range names {
+		u, err := s.Create(ctx, user.User{Name: name})
+		if err != nil {
+			fmt.Fprintf(stderr, "users add: %q: %v\n", name, err)
+			status = 1
+			continue
+		}
+		fmt.Fprintf(stdout, "%s\t%s\n", u.ID, u.Name)
+	}
+	return status
+}
+
+// parseIDs parses the user IDs given to get and delete.
+func parseIDs(action string, args []string, stderr io.Writer) ([]string, bool) {
+	if len(args) == 0 {
+		fmt.Fprintf(stderr, "usage: users %s id ...\n", action)
+		return nil, false
+	}
+	for _, a := range args {
+		if !id.Valid(a) {
+			fmt.Fprintf(stderr, "users %s: %q is not a user ID\n", action, a)
+			return nil, false
+		}
+	}
+	return args, true
+}
+
+func runUsersGet(ctx context.Context, s user.Store, args []string, stdout, stderr io.Writer) int {
+	ids, ok := parseIDs("get", args, stderr)
+	if !ok {
+		return 2
+	}
+	status := 0
+	for _, userID := range ids {
+		u, err := s.Get(ctx, userID)
+		if err != nil {
+			fmt.Fprintf(stderr, "users get: %s: %v\n", userID, err)
+			status = 1
+			continue
+		}
+		fmt.Fprintf(stdout, "%s\t%s\n", u.ID, u.Name)
+	}
+	return status
+}
+
+func runUsersDelete(ctx context.Context, s user.Store, args []string, stdout, stderr io.Writer) int {
+	ids, ok := parseIDs("delete", args, stderr)
+	if !ok {
+		return 2
+	}
+	status := 0
+	for _, userID := range ids {
+		if err := s.Delete(ctx, userID); err != nil {
+			fmt.Fprintf(stderr, "users delete: %s: %v\n", userID, err)
+			status = 1
+		}
+	}
+	return status
+}
+
+// runUsersImport adds the users of a CSV file, as read by
+// user.ImportUsersCSV, or of a JSON-lines file (.jsonl or .ndjson) with a
+// user object per line, read and added one at a time. CSV rows that fail
+// to parse or to be stored are reported and the rest are still added; a
+// JSON line that fails to parse ends the import.
+func runUsersImport(ctx context.Context, s user.Store, args []string, stdout, stderr io.Writer) int {
+	if len(args) != 1 {
+		fmt.Fprintln(stderr, "usage: users import file.csv|file.jsonl")
+		return 2
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		fmt.Fprintln(stderr, "users import:", err)
+		return 1
+	}
+	defer f.Close()
+	status, added := 0, 0
+	add := func(u user.User) {
+		if _, err := s.Create(ctx, u); err != nil {
+			fmt.Fprintf(stderr, "users import: %q: %v\n", u.Name, err)
+			status = 1
+			return
+		}
+		added++
+	}
+	switch strings.ToLower(filepath.Ext(args[0])) {
+	case ".jsonl", ".ndjson":
+		d := jsonstream.NewLineDecoder[user.User](f)
+		for d.Next() {
+			add(d.Value())
+		}
+		err = d.Err()
+	default:
+		var users []user.User
+		users, err = user.ImportUsersCSV(f)
+		for _, u := range users {
+			add(u)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "users import:", err)
+		status = 1
+	}
+	fmt.Fprintln(stdout, i18n.FromContext(ctx).Plural("%d users added", added))
+	return status
+}
+
+func runUsersExport(ctx context.Context, s user.Store, args []string, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		fmt.Fprintln(stderr, "usage: users export")
+		return 2
+	}
+	page, err := s.List(ctx, user.ListQuery{})
+	if err == nil {
+		err = user.ExportUsersCSV(stdout, page.Users)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "users export:", err)
+		return 1
+	}
+	return 0
+}
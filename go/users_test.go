@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"example.com/tutorial/jsonstream"
+	"example.com/tutorial/user"
+)
+
+func TestRunUsers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	users := func(args ...string) (string, string, int) {
+		t.Helper()
+		var out, errOut bytes.Buffer
+		code := runUsers(context.Background(), defaultSettings().Users, false, append([]string{"-file", path}, args...), &out, &errOut)
+		return out.String(), errOut.String(), code
+	}
+	out, errOut, code := users("add", "ada", "bob", "")
+	if code != 1 || maskIDs(out) != "ID\tada\nID\tbob\n" || !strings.Contains(errOut, "name") {
+		t.Fatalf("add: exit %d, %q, %q", code, out, errOut)
+	}
+	var ada, bob string
+	fmt.Sscanf(out, "%s\tada\n%s\tbob\n", &ada, &bob)
+	if out, _, code := users("list", "-sort", "name", "-desc", "-limit", "1"); code != 0 || out != bob+"\tbob\n" {
+		t.Fatalf("list: exit %d, %q", code, out)
+	}
+	if out, errOut, code := users("get", ada, "7"); code != 1 || out != ada+"\tada\n" || !strings.Contains(errOut, "not found") {
+		t.Fatalf("get: exit %d, %q, %q", code, out, errOut)
+	}
+	if _, _, code := users("delete", "../1"); code != 2 {
+		t.Fatalf("delete with a bad ID: exit %d", code)
+	}
+	if _, errOut, code := users("delete", bob); code != 0 {
+		t.Fatalf("delete: exit %d, %s", code, errOut)
+	}
+
+	csvPath := filepath.Join(t.TempDir(), "in.csv")
+	os.WriteFile(csvPath, []byte("name\ncy\n\"\"\n"), 0o644)
+	if out, errOut, code := users("import", csvPath); code != 1 || out != "1 user added\n" || !strings.Contains(errOut, "line 3") {
+		t.Fatalf("import: exit %d, %q, %q", code, out, errOut)
+	}
+	if out, _, code := users("export"); code != 0 || maskIDs(out) != "id,name,password_hash\nID,ada,\nID,cy,\n" || !strings.HasPrefix(out, "id,name,password_hash\n"+ada+",") {
+		t.Fatalf("export: exit %d, %q", code, out)
+	}
+	if _, _, code := users("rename"); code != 2 {
+		t.Fatalf("unknown action: exit %d", code)
+	}
+}
+
+// idPattern matches the IDs of id.New.
+var idPattern = regexp.MustCompile(`\b[0-9A-HJKMNP-TV-Z]{26}\b`)
+
+// maskIDs replaces the generated IDs in s with "ID", for comparing
+// output that lists new users.
+func maskIDs(s string) string { return idPattern.ReplaceAllString(s, "ID") }
+
+func TestRunUsersJSON(t *testing.T) {
+	dir := t.TempDir()
+	users := func(args ...string) (string, string, int) {
+		t.Helper()
+		var out, errOut bytes.Buffer
+		code := runUsers(context.Background(), defaultSettings().Users, false, append([]string{"-file", filepath.Join(dir, "users.json")}, args...), &out, &errOut)
+		return out.String(), errOut.String(), code
+	}
+	if out, _, code := users("list", "-json"); code != 0 || out != "[]\n" {
+		t.Fatalf("empty list: exit %d, %q", code, out)
+	}
+
+	in := filepath.Join(dir, "in.jsonl")
+	os.WriteFile(in, []byte(`{"name":"ada"}`+"\n\n"+`{"version":3,"name":"bob"}`+"\n"+`{"name":`+"\n"+`{"name":"cy"}`+"\n"), 0o644)
+	if out, errOut, code := users("import", in); code != 1 || out != "2 users added\n" || !strings.Contains(errOut, "line 4") {
+		t.Fatalf("import: exit %d, %q, %q", code, out, errOut)
+	}
+	out, errOut, code := users("list", "-json")
+	if code != 0 {
+		t.Fatalf("list: exit %d, %s", code, errOut)
+	}
+	d := jsonstream.NewArrayDecoder[user.User](strings.NewReader(out))
+	var names []string
+	for d.Next() {
+		names = append(names, d.Value().Name)
+	}
+	if d.Err() != nil || strings.Join(names, ",") != "ada,bob" {
+		t.Fatalf("list: %q, %v", out, d.Err())
+	}
+}
+
+func TestRunUsersDryRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	var out, errOut bytes.Buffer
+	if code := run(context.Background(), []string{"-dry-run", "users", "-file", path, "add", "ada"}, nil, &out, &errOut); code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+	if got := out.String(); !strings.HasPrefix(got, "would write "+path+"\n") || !strings.HasSuffix(got, "\tada\n") {
+		t.Fatalf("got %q", got)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("dry run wrote %s: %v", path, err)
+	}
+}
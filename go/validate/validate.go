@@ -0,0 +1,89 @@
+// Package validate collects field-level validation errors so callers can
+// report every problem with an input at once rather than the first one.
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+
+	"example.com/tutorial/errs"
+)
+
+// FieldError describes one invalid field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Errors is a list of field errors. It implements error so a whole list
+// can be returned where an error is expected; use errors.As to recover it.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return "invalid: " + strings.Join(msgs, "; ")
+}
+
+// Is reports that e is of kind errs.ErrInvalid.
+func (e Errors) Is(target error) bool { return target == errs.ErrInvalid }
+
+// Validator accumulates field errors. The zero value is ready to use.
+type Validator struct {
+	errs Errors
+}
+
+// Check records msg against field unless ok holds.
+func (v *Validator) Check(ok bool, field, msg string) {
+	if !ok {
+		v.errs = append(v.errs, FieldError{Field: field, Message: msg})
+	}
+}
+
+// Required checks that value is not blank.
+func (v *Validator) Required(field, value string) {
+	v.Check(strings.TrimSpace(value) != "", field, "is required")
+}
+
+// Length checks that value has between min and max characters,
+// inclusive. A max of 0 means no upper bound.
+func (v *Validator) Length(field, value string, min, max int) {
+	n := utf8.RuneCountInString(value)
+	switch {
+	case n < min:
+		v.Check(false, field, fmt.Sprintf("must be at least %d characters", min))
+	case max > 0 && n > max:
+		v.Check(false, field, fmt.Sprintf("must be at most %d characters", max))
+	}
+}
+
+// Email checks that value is a bare address such as "ada@example.com".
+// Empty values pass; combine with Required for mandatory addresses.
+func (v *Validator) Email(field, value string) {
+	if value == "" {
+		return
+	}
+	addr, err := mail.ParseAddress(value)
+	v.Check(err == nil && addr.Address == value && strings.Contains(value[strings.LastIndex(value, "@")+1:], "."),
+		field, "must be a valid email address")
+}
+
+// Valid reports whether no errors have been recorded.
+func (v *Validator) Valid() bool { return len(v.errs) == 0 }
+
+// Err returns the recorded errors as an Errors value, or nil if there
+// are none.
+func (v *Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
@@ -0,0 +1,63 @@
+package validate
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"example.com/tutorial/errs"
+)
+
+func TestValidator(t *testing.T) {
+	var v Validator
+	if !v.Valid() || v.Err() != nil {
+		t.Fatal("zero Validator is not valid")
+	}
+	v.Required("name", "  ")
+	v.Length("name", "", 1, 10)
+	v.Length("bio", "héllo", 1, 4)
+	v.Length("title", "ok", 1, 0)
+	v.Email("email", "not-an-email")
+	v.Check(false, "age", "must be positive")
+
+	err := v.Err()
+	var fe Errors
+	if !errors.As(err, &fe) {
+		t.Fatalf("Err() = %T, want Errors", err)
+	}
+	want := Errors{
+		{"name", "is required"},
+		{"name", "must be at least 1 characters"},
+		{"bio", "must be at most 4 characters"},
+		{"email", "must be a valid email address"},
+		{"age", "must be positive"},
+	}
+	if !reflect.DeepEqual(fe, want) {
+		t.Fatalf("errors = %v, want %v", fe, want)
+	}
+	if got := err.Error(); got != "invalid: name: is required; name: must be at least 1 characters; bio: must be at most 4 characters; email: must be a valid email address; age: must be positive" {
+		t.Fatalf("Error() = %q", got)
+	}
+	if !errors.Is(err, errs.ErrInvalid) || errs.Kind(err) != errs.ErrInvalid {
+		t.Fatal("Errors is not of kind errs.ErrInvalid")
+	}
+}
+
+func TestEmail(t *testing.T) {
+	tests := map[string]bool{
+		"":                 true,
+		"ada@example.com":  true,
+		"a.b+c@sub.ex.org": true,
+		"Ada <ada@ex.com>": false,
+		"ada@localhost":    false,
+		"ada":              false,
+		"@example.com":     false,
+	}
+	for in, ok := range tests {
+		var v Validator
+		v.Email("email", in)
+		if v.Valid() != ok {
+			t.Errorf("Email(%q) valid = %v, want %v", in, v.Valid(), ok)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"example.com/tutorial/buildinfo"
+)
+
+// runVersion implements the "version" subcommand: it prints the version,
+// commit and build date of the binary, and the Go version and platform
+// it was built with.
+func runVersion(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("version", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: version [-json]")
+		fs.PrintDefaults()
+	}
+	asJSON := fs.Bool("json", false, "print the build information as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+	info := buildinfo.Get()
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(info); err != nil {
+			fmt.Fprintln(stderr, "version:", err)
+			return 1
+		}
+		return 0
+	}
+	fmt.Fprintf(stdout, "%s %s\n", progName, info)
+	return 0
+}
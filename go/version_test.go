@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"runtime"
+	"strings"
+	"testing"
+
+	"example.com/tutorial/buildinfo"
+)
+
+func TestRunVersion(t *testing.T) {
+	buildinfo.Version, buildinfo.Commit = "v1.2.3", "0123456789abcdef"
+	defer func() { buildinfo.Version, buildinfo.Commit = "", "" }()
+
+	var out, errOut bytes.Buffer
+	if code := run(context.Background(), []string{"version"}, nil, &out, &errOut); code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+	if got := out.String(); !strings.HasPrefix(got, "tutorial v1.2.3 (commit 0123456789ab") ||
+		!strings.HasSuffix(got, runtime.GOOS+"/"+runtime.GOARCH+"\n") {
+		t.Errorf("got %q", got)
+	}
+
+	out.Reset()
+	if code := run(context.Background(), []string{"version", "-json"}, nil, &out, &errOut); code != 0 {
+		t.Fatalf("-json: exit %d: %s", code, errOut.String())
+	}
+	var info buildinfo.Info
+	if err := json.Unmarshal(out.Bytes(), &info); err != nil {
+		t.Fatalf("%v: %s", err, out.String())
+	}
+	if info.Version != "v1.2.3" || info.Commit != "0123456789abcdef" || info.GoVersion != runtime.Version() {
+		t.Errorf("-json: %+v", info)
+	}
+
+	if code := run(context.Background(), []string{"version", "extra"}, nil, &out, &errOut); code != 2 {
+		t.Errorf("extra argument: exit %d", code)
+	}
+}
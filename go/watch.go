@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"example.com/tutorial/i18n"
+	"example.com/tutorial/text"
+	"example.com/tutorial/watch"
+)
+
+// runWatch implements the "watch" subcommand and returns the process
+// exit code. It counts the words of every file below dir, then recounts
+// the files that change and prints the updated totals after every batch
+// of changes, until ctx is done.
+func runWatch(ctx context.Context, cfg watchSettings, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: watch [-interval d] [-debounce d] [-top n] [-format f] [dir]")
+		fs.PrintDefaults()
+	}
+	interval := fs.Duration("interval", cfg.Interval, "time between scans of the tree")
+	debounce := fs.Duration("debounce", cfg.Debounce, "quiet time before a batch of changes is counted")
+	top := fs.Int("top", cfg.Top, "print the `n` most frequent words (-1 for all)")
+	format := fs.String("format", cfg.Format, "frequency table `format`: text, json or csv")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	f, err := text.ParseFormat(*format)
+	if err != nil {
+		fmt.Fprintln(stderr, "watch:", err)
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w := watch.New(dir, watch.Config{Interval: *interval, Debounce: *debounce, ReportExisting: true})
+	sub := w.Subscribe()
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+
+	p := i18n.FromContext(ctx)
+	perFile := map[string]map[string]int{}
+	total := map[string]int{}
+	words := 0
+	apply := func(counts map[string]int, sign int) {
+		for word, c := range counts {
+			total[word] += sign * c
+			words += sign * c
+			if total[word] == 0 {
+				delete(total, word)
+			}
+		}
+	}
+	for batch := range sub.C() {
+		var ops [4]int
+		for _, ev := range batch {
+			ops[ev.Op]++
+			apply(perFile[ev.Path], -1)
+			delete(perFile, ev.Path)
+			if ev.Op == watch.Delete {
+				continue
+			}
+			counts, err := watchCountFile(filepath.Join(dir, filepath.FromSlash(ev.Path)))
+			if err != nil {
+				fmt.Fprintln(stderr, "watch:", err)
+				continue
+			}
+			perFile[ev.Path] = counts
+			apply(counts, 1)
+		}
+		fmt.Fprintf(stdout, "%s, %s %s\n", p.Plural("%d files", len(perFile)), p.Plural("%d words", words),
+			p.Sprintf("(%d created, %d modified, %d deleted)", ops[watch.Create], ops[watch.Modify], ops[watch.Delete]))
+		if err := writeFrequencies(stdout, text.TopWords(total, *top), f); err != nil {
+			fmt.Fprintln(stderr, "watch:", err)
+			sub.Unsubscribe()
+			return 1
+		}
+	}
+	if err := <-done; err != nil {
+		fmt.Fprintln(stderr, "watch:", err)
+		return 1
+	}
+	return 0
+}
+
+func watchCountFile(name string) (map[string]int, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	counts, err := text.WordCountReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return counts, nil
+}
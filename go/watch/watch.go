@@ -0,0 +1,243 @@
+// Package watch reports changes to the files of a directory tree. It
+// polls, comparing successive snapshots of file sizes and modification
+// times, so it needs no platform support, and it coalesces bursts of
+// changes into batches delivered to any number of subscribers.
+package watch
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"example.com/tutorial/bus"
+	"example.com/tutorial/debounce"
+	"example.com/tutorial/fsutil"
+)
+
+// Defaults for the zero fields of Config.
+const (
+	DefaultInterval = time.Second
+	DefaultDebounce = 200 * time.Millisecond
+)
+
+// Op is the kind of a change.
+type Op int
+
+const (
+	Create Op = iota + 1
+	Modify
+	Delete
+)
+
+func (op Op) String() string {
+	switch op {
+	case Create:
+		return "create"
+	case Modify:
+		return "modify"
+	case Delete:
+		return "delete"
+	}
+	return "unknown"
+}
+
+// Event is a change to one regular file. Path is slash-separated and
+// relative to the watched root.
+type Event struct {
+	Path string
+	Op   Op
+}
+
+// Config tunes a Watcher.
+type Config struct {
+	// Interval is the time between scans; zero means DefaultInterval.
+	Interval time.Duration
+	// Debounce is how long the tree must stay unchanged before a batch
+	// of changes is delivered; zero means DefaultDebounce.
+	Debounce time.Duration
+	// ReportExisting makes the first batch report every file present
+	// when Run starts as created, so subscribers can build their state
+	// from the batches alone.
+	ReportExisting bool
+}
+
+// Watcher watches the regular files below a root directory.
+type Watcher struct {
+	root string
+	cfg  Config
+	out  *bus.Bus[[]Event]
+
+	mu   sync.Mutex
+	last map[string]fileState
+}
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+const topic = "changes"
+
+// New returns a watcher of the tree at root; Run starts it.
+func New(root string, cfg Config) *Watcher {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	if cfg.Debounce <= 0 {
+		cfg.Debounce = DefaultDebounce
+	}
+	return &Watcher{root: root, cfg: cfg, out: bus.New[[]Event]()}
+}
+
+// Subscribe returns a subscription receiving every batch of changes,
+// in path order, with at most one event per file: the net change since
+// the previous batch. The watcher waits for slow subscribers rather
+// than drop batches. The channel is closed when Run returns.
+func (w *Watcher) Subscribe() *bus.Subscription[[]Event] {
+	return w.out.Subscribe(topic, bus.SubscribeConfig{Policy: bus.Block})
+}
+
+// Run scans the tree, then rescans it every Interval and reports the
+// changes, until ctx is done or the root cannot be read. Changes still
+// waiting out the debounce when ctx ends may be lost.
+func (w *Watcher) Run(ctx context.Context) error {
+	defer w.out.Close()
+	snap, err := w.scan(ctx)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.last = snap
+	w.mu.Unlock()
+
+	deliver := debounce.Debounce(w.cfg.Debounce, func(batch []Event) {
+		if events := coalesce(batch); len(events) > 0 {
+			w.out.Publish(ctx, topic, events)
+		}
+	})
+	defer deliver.Stop()
+	if w.cfg.ReportExisting {
+		for _, ev := range diff(nil, snap) {
+			deliver.Call(ev)
+		}
+	}
+	t := time.NewTicker(w.cfg.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+		snap, err := w.scan(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		w.mu.Lock()
+		events := diff(w.last, snap)
+		w.last = snap
+		w.mu.Unlock()
+		for _, ev := range events {
+			deliver.Call(ev)
+		}
+	}
+}
+
+// scan records the state of every regular file below the root.
+func (w *Watcher) scan(ctx context.Context) (map[string]fileState, error) {
+	var mu sync.Mutex
+	snap := map[string]fileState{}
+	err := fsutil.WalkConcurrent(ctx, w.root, func(_ context.Context, path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path != w.root {
+				return nil // removed while scanning; the next scan says so
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		rel, err := filepath.Rel(w.root, path)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		snap[filepath.ToSlash(rel)] = fileState{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	return snap, err
+}
+
+// Files returns the files seen by the latest scan, in path order.
+func (w *Watcher) Files() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	files := make([]string, 0, len(w.last))
+	for f := range w.last {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// diff returns the changes from old to cur, in path order.
+func diff(old, cur map[string]fileState) []Event {
+	var events []Event
+	for p, s := range cur {
+		if o, ok := old[p]; !ok {
+			events = append(events, Event{p, Create})
+		} else if o != s {
+			events = append(events, Event{p, Modify})
+		}
+	}
+	for p := range old {
+		if _, ok := cur[p]; !ok {
+			events = append(events, Event{p, Delete})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Path < events[j].Path })
+	return events
+}
+
+// coalesce reduces a batch to the net change of each file: a file
+// created and then modified was created, one created and deleted again
+// drops out, and one deleted and created again was modified.
+func coalesce(batch []Event) []Event {
+	net := map[string]Op{}
+	for _, ev := range batch {
+		prev, seen := net[ev.Path]
+		switch {
+		case !seen:
+			net[ev.Path] = ev.Op
+		case prev == Create && ev.Op == Delete:
+			delete(net, ev.Path)
+		case prev == Create:
+			// still a creation
+		case prev == Delete && ev.Op == Create:
+			net[ev.Path] = Modify
+		default:
+			net[ev.Path] = ev.Op
+		}
+	}
+	out := make([]Event, 0, len(net))
+	for p, op := range net {
+		out = append(out, Event{p, op})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
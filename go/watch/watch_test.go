@@ -0,0 +1,131 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCoalesce(t *testing.T) {
+	got := coalesce([]Event{
+		{"a", Create}, {"a", Modify},
+		{"b", Create}, {"b", Delete},
+		{"c", Delete}, {"c", Create},
+		{"d", Modify}, {"d", Delete},
+		{"e", Modify}, {"e", Modify},
+	})
+	want := []Event{{"a", Create}, {"c", Modify}, {"d", Delete}, {"e", Modify}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	now := time.Now()
+	old := map[string]fileState{"same": {1, now}, "grown": {1, now}, "touched": {1, now}, "gone": {1, now}}
+	cur := map[string]fileState{"same": {1, now}, "grown": {2, now}, "touched": {1, now.Add(time.Second)}, "new": {1, now}}
+	want := []Event{{"gone", Delete}, {"grown", Modify}, {"new", Create}, {"touched", Modify}}
+	if got := diff(old, cur); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func receive(t *testing.T, ch <-chan []Event) []Event {
+	t.Helper()
+	select {
+	case b, ok := <-ch:
+		if !ok {
+			t.Fatal("channel closed")
+		}
+		return b
+	case <-time.After(5 * time.Second):
+		t.Fatal("no batch delivered")
+		return nil
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		p := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(p), 0o755)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("keep.txt", "one")
+	write("old.txt", "two")
+
+	w := New(dir, Config{Interval: 5 * time.Millisecond, Debounce: 20 * time.Millisecond})
+	sub := w.Subscribe()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+	for len(w.Files()) != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	write("sub/new.txt", "three")
+	write("keep.txt", "one, longer")
+	os.Remove(filepath.Join(dir, "old.txt"))
+	want := []Event{{"keep.txt", Modify}, {"old.txt", Delete}, {"sub/new.txt", Create}}
+	if got := receive(t, sub.C()); !reflect.DeepEqual(got, want) {
+		t.Fatalf("batch %v, want %v", got, want)
+	}
+	if got := w.Files(); !reflect.DeepEqual(got, []string{"keep.txt", "sub/new.txt"}) {
+		t.Fatalf("Files = %v", got)
+	}
+
+	// Two subscribers both see the next batch.
+	other := w.Subscribe()
+	write("late.txt", "four")
+	for _, s := range [](<-chan []Event){sub.C(), other.C()} {
+		if got := receive(t, s); !reflect.DeepEqual(got, []Event{{"late.txt", Create}}) {
+			t.Fatalf("batch %v", got)
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run = %v", err)
+	}
+	if _, ok := <-sub.C(); ok {
+		t.Fatal("subscription open after Run returned")
+	}
+}
+
+func TestWatcherReportExisting(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.txt", "a.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w := New(dir, Config{Interval: time.Hour, Debounce: time.Millisecond, ReportExisting: true})
+	sub := w.Subscribe()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+	if got := receive(t, sub.C()); !reflect.DeepEqual(got, []Event{{"a.txt", Create}, {"b.txt", Create}}) {
+		t.Fatalf("first batch %v", got)
+	}
+}
+
+func TestWatcherMissingRoot(t *testing.T) {
+	w := New(filepath.Join(t.TempDir(), "missing"), Config{})
+	if err := w.Run(context.Background()); err == nil {
+		t.Fatal("Run on a missing root succeeded")
+	}
+}
+
+func TestOpString(t *testing.T) {
+	for op, want := range map[Op]string{Create: "create", Modify: "modify", Delete: "delete", 0: "unknown"} {
+		if op.String() != want {
+			t.Errorf("%d.String() = %q", int(op), op.String())
+		}
+	}
+}
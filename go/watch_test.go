@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe to read while runWatch writes it.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func TestRunWatch(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.txt", "go go gophers")
+	write("b.txt", "go fast")
+
+	var out, errOut syncBuffer
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan int, 1)
+	go func() {
+		done <- runWatch(ctx, defaultSettings().Watch, []string{"-interval", "5ms", "-debounce", "20ms", "-top", "2", "-format", "csv", dir}, &out, &errOut)
+	}()
+	waitFor := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !strings.Contains(out.String(), want) {
+			if time.Now().After(deadline) {
+				t.Fatalf("output lacks %q:\n%s%s", want, out.String(), errOut.String())
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	waitFor("2 files, 5 words (2 created, 0 modified, 0 deleted)\nword,count\ngo,3\nfast,1\n")
+	write("a.txt", "rust rust rust rust")
+	os.Remove(filepath.Join(dir, "b.txt"))
+	write("c.txt", "go")
+	waitFor("2 files, 5 words (1 created, 1 modified, 1 deleted)\nword,count\nrust,4\ngo,1\n")
+
+	cancel()
+	if code := <-done; code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+}
+
+func TestRunWatchErrors(t *testing.T) {
+	var out, errOut bytes.Buffer
+	if code := runWatch(context.Background(), defaultSettings().Watch, []string{filepath.Join(t.TempDir(), "missing")}, &out, &errOut); code != 1 {
+		t.Fatalf("missing dir: exit %d", code)
+	}
+	if code := runWatch(context.Background(), defaultSettings().Watch, []string{"a", "b"}, &out, &errOut); code != 2 {
+		t.Fatalf("two dirs: exit %d", code)
+	}
+	if code := runWatch(context.Background(), defaultSettings().Watch, []string{"-format", "xml", "."}, &out, &errOut); code != 2 {
+		t.Fatalf("bad format: exit %d", code)
+	}
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"example.com/tutorial/analysis"
+	"example.com/tutorial/archive"
+	"example.com/tutorial/output"
+	"example.com/tutorial/progress"
+	"example.com/tutorial/spill"
+	"example.com/tutorial/text"
+	"example.com/tutorial/workspace"
+)
+
+// addStats accumulates the wc-style tallies of o into total.
+func addStats(total *text.TextStats, o text.TextStats) {
+	total.Lines += o.Lines
+	total.Words += o.Words
+	total.Chars += o.Chars
+	total.Bytes += o.Bytes
+}
+
+// runWordCount implements the "wordcount" subcommand and returns the
+// process exit code. With no file arguments it reads standard input.
+// Archives among the files (.tar.gz, .tgz and .zip) are extracted to a
+// temporary workspace, removed when the counting ends or ctx is done,
+// and their files counted as "archive:path".
+func runWordCount(ctx context.Context, cfg wordCountSettings, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("wordcount", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: wordcount [-l] [-w] [-m] [-c] [-top n [-format f]] [-analyzer name] [-languages] [-progress mode] [file ...]")
+		fs.PrintDefaults()
+	}
+	lines := fs.Bool("l", false, "print the newline count")
+	words := fs.Bool("w", false, "print the word count")
+	chars := fs.Bool("m", false, "print the character count")
+	byteCount := fs.Bool("c", false, "print the byte count")
+	top := fs.Int("top", 0, "print the `n` most frequent words across all inputs instead of counts (-1 for all)")
+	format := fs.String("format", cfg.Format, "frequency table `format`: text, json or csv")
+	maxExtract := cfg.MaxExtract
+	fs.TextVar(&maxExtract, "max-extract", cfg.MaxExtract, "extract at most `size` from archives in all, such as 512MiB")
+	maxMemory := cfg.MaxMemory
+	fs.TextVar(&maxMemory, "max-memory", cfg.MaxMemory, "with -top, hold about `size` of counts in memory and spill the rest to temporary files (0 for no limit)")
+	analyzer := fs.String("analyzer", cfg.Analyzer, "split words with the analyzer called `name`: "+strings.Join(analysis.Names(), ", "))
+	languages := fs.Bool("languages", false, "detect the language of each input, count it with that language's analyzer unless -analyzer is set, and print the words per language instead of counts")
+	mode := cfg.Progress
+	fs.TextVar(&mode, "progress", cfg.Progress, progressUsage)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	f, err := text.ParseFormat(*format)
+	if err != nil {
+		fmt.Fprintln(stderr, "wordcount:", err)
+		return 2
+	}
+	if *languages && *top != 0 {
+		fmt.Fprintln(stderr, "wordcount: -languages and -top cannot be combined")
+		return 2
+	}
+	var opts []text.Option
+	if *analyzer != "" {
+		a, err := analysis.Lookup(*analyzer)
+		if err != nil {
+			fmt.Fprintln(stderr, "wordcount:", err)
+			return 2
+		}
+		opts = append(opts, text.WithAnalyzer(a))
+	}
+	inputs, cleanup, status := wcInputs(ctx, fs.Args(), int64(maxExtract), stderr)
+	defer cleanup()
+	t := progress.NewTracker()
+	t.SetTotal(len(inputs), wcSize(inputs))
+	rep := progress.Start(stderr, t, progress.Options{Mode: mode, Label: "wordcount"})
+	defer rep.Stop()
+	stdin, stdout = t.Reader(stdin), rep.Bypass(stdout)
+	if *top != 0 {
+		counts := spill.New(spill.Config{MaxMemory: int64(maxMemory)})
+		defer counts.Close()
+		return max(status, runTopWords(inputs, t, counts, fs.NArg() == 0, *top, f, opts, stdin, stdout, stderr))
+	}
+	if *languages {
+		return max(status, runLanguages(inputs, t, fs.NArg() == 0, opts, stdin, stdout, stderr))
+	}
+	if !*lines && !*words && !*chars && !*byteCount {
+		*lines, *words, *byteCount = true, true, true
+	}
+
+	report := func(c text.TextStats, name string) {
+		if *lines {
+			fmt.Fprintf(stdout, "%8d", c.Lines)
+		}
+		if *words {
+			fmt.Fprintf(stdout, "%8d", c.Words)
+		}
+		if *chars {
+			fmt.Fprintf(stdout, "%8d", c.Chars)
+		}
+		if *byteCount {
+			fmt.Fprintf(stdout, "%8d", c.Bytes)
+		}
+		if name != "" {
+			fmt.Fprintf(stdout, " %s", name)
+		}
+		fmt.Fprintln(stdout)
+	}
+
+	if fs.NArg() == 0 {
+		c, err := text.ReadStats(stdin, opts...)
+		if err != nil {
+			fmt.Fprintln(stderr, "wordcount:", err)
+			return 1
+		}
+		report(c, "")
+		return 0
+	}
+
+	var total text.TextStats
+	for _, in := range inputs {
+		c, err := wcCountFile(in, t, opts)
+		if err != nil {
+			fmt.Fprintln(stderr, "wordcount:", err)
+			status = 1
+			continue
+		}
+		addStats(&total, c)
+		report(c, in.name)
+	}
+	if len(inputs) > 1 {
+		report(total, "total")
+	}
+	return status
+}
+
+// wcInput is a file to count: where it is, and what to call it.
+type wcInput struct {
+	name, path string
+}
+
+// wcInputs lists the files to count for the named ones, extracting
+// archives, at most maxExtract bytes of them, into a workspace that
+// cleanup removes. It reports archives that cannot be extracted and
+// returns exit status 1 if there were any.
+func wcInputs(ctx context.Context, names []string, maxExtract int64, stderr io.Writer) (inputs []wcInput, cleanup func(), status int) {
+	var ws *workspace.Workspace
+	cleanup = func() {
+		if ws != nil {
+			ws.Close()
+		}
+	}
+	for i, name := range names {
+		if _, err := archive.FormatOf(name); err != nil {
+			inputs = append(inputs, wcInput{name, name})
+			continue
+		}
+		var err error
+		if ws == nil {
+			ws, err = workspace.New(ctx, workspace.Config{Pattern: "wordcount-*", Quota: maxExtract})
+		}
+		dir := fmt.Sprintf("archive-%d", i)
+		var dest string
+		if err == nil {
+			dest, err = ws.Path(dir)
+		}
+		if err == nil {
+			err = archive.ExtractFile(name, dest, archive.ExtractOptions{MaxTotalBytes: ws.Remaining()})
+		}
+		if err == nil {
+			err = ws.Track(dir)
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, "wordcount:", err)
+			status = 1
+			continue
+		}
+		for _, f := range ws.Files() {
+			if rel, ok := strings.CutPrefix(f, dir+"/"); ok {
+				inputs = append(inputs, wcInput{name + ":" + rel, filepath.Join(dest, filepath.FromSlash(rel))})
+			}
+		}
+	}
+	return inputs, cleanup, status
+}
+
+// wcSize returns the total size of the inputs, as far as it can tell.
+func wcSize(inputs []wcInput) int64 {
+	var n int64
+	for _, in := range inputs {
+		if info, err := os.Stat(in.path); err == nil && info.Mode().IsRegular() {
+			n += info.Size()
+		}
+	}
+	return n
+}
+
+// wcCountFile counts the input with opts, feeding t with its bytes and
+// itself.
+func wcCountFile(in wcInput, t *progress.Tracker, opts []text.Option) (text.TextStats, error) {
+	f, err := os.Open(in.path)
+	if err != nil {
+		return text.TextStats{}, err
+	}
+	defer f.Close()
+	defer t.AddFiles(1)
+	c, err := text.ReadStats(t.Reader(f), opts...)
+	if err != nil {
+		return c, fmt.Errorf("%s: %w", in.name, err)
+	}
+	return c, nil
+}
+
+// runTopWords prints the merged frequency table of the inputs, or of
+// stdin if useStdin is set, counted with opts into counts, feeding t
+// with the inputs it reads. Words go straight from the input to counts,
+// so that with a memory bound no file is ever counted in memory whole;
+// the words of a file that fails partway are kept.
+func runTopWords(inputs []wcInput, t *progress.Tracker, counts *spill.Counter, useStdin bool, n int, f text.Format, opts []text.Option, stdin io.Reader, stdout, stderr io.Writer) int {
+	status := 0
+	merge := func(r io.Reader, name string) {
+		if err := text.ScanWords(r, counts.Add, opts...); err != nil {
+			fmt.Fprintf(stderr, "wordcount: %s: %v\n", name, err)
+			status = 1
+		}
+	}
+	if useStdin {
+		merge(stdin, "stdin")
+	}
+	for _, in := range inputs {
+		file, err := os.Open(in.path)
+		if err != nil {
+			fmt.Fprintln(stderr, "wordcount:", err)
+			status = 1
+			continue
+		}
+		merge(t.Reader(file), in.name)
+		file.Close()
+		t.AddFiles(1)
+	}
+	freqs, err := counts.Top(n)
+	if err == nil {
+		err = writeFrequencies(stdout, freqs, f)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "wordcount:", err)
+		return 1
+	}
+	return status
+}
+
+// writeFrequencies writes freqs in format f, a text table with its
+// header in bold if w colors text.
+func writeFrequencies(w io.Writer, freqs []text.WordFreq, f text.Format) error {
+	if f != text.FormatText {
+		return text.EncodeFrequencies(w, freqs, f)
+	}
+	var b strings.Builder
+	text.EncodeFrequencies(&b, freqs, f)
+	return output.For(w).WriteTable(b.String())
+}
+
+// langSample is how much of an input runLanguages detects its language
+// from, as analysis.Detect reads no more.
+const langSample = 8 << 10
+
+// runLanguages prints the language analysis.Detect finds for each of
+// the inputs, or stdin if useStdin is set, and its words, counted with
+// opts or, if they select no analyzer, with its language's analyzer;
+// then the files and words of each language.
+func runLanguages(inputs []wcInput, t *progress.Tracker, useStdin bool, opts []text.Option, stdin io.Reader, stdout, stderr io.Writer) int {
+	status := 0
+	type langStats struct{ files, words int64 }
+	perLang := map[string]*langStats{}
+	var files strings.Builder
+	tw := tabwriter.NewWriter(&files, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tLANGUAGE\tCONFIDENCE\tWORDS")
+	tag := func(r io.Reader, name string) {
+		br := bufio.NewReaderSize(r, langSample)
+		sample, _ := br.Peek(langSample) // a read error recurs in ReadStats
+		det := analysis.Detect(string(sample))
+		lang := det.Language
+		if lang == "" {
+			lang = "unknown"
+		}
+		o := opts
+		if len(opts) == 0 {
+			if a, err := analysis.Lookup(det.Language); err == nil {
+				o = []text.Option{text.WithAnalyzer(a)}
+			}
+		}
+		st, err := text.ReadStats(br, o...)
+		if err != nil {
+			fmt.Fprintf(stderr, "wordcount: %s: %v\n", name, err)
+			status = 1
+			return
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%.2f\t%d\n", name, lang, det.Confidence, st.Words)
+		ls := perLang[lang]
+		if ls == nil {
+			ls = &langStats{}
+			perLang[lang] = ls
+		}
+		ls.files++
+		ls.words += st.Words
+	}
+	if useStdin {
+		tag(stdin, "stdin")
+	}
+	for _, in := range inputs {
+		file, err := os.Open(in.path)
+		if err != nil {
+			fmt.Fprintln(stderr, "wordcount:", err)
+			status = 1
+			continue
+		}
+		tag(t.Reader(file), in.name)
+		file.Close()
+		t.AddFiles(1)
+	}
+	tw.Flush()
+
+	langs := make([]string, 0, len(perLang))
+	for lang := range perLang {
+		langs = append(langs, lang)
+	}
+	slices.Sort(langs)
+	var totals strings.Builder
+	tw = tabwriter.NewWriter(&totals, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LANGUAGE\tFILES\tWORDS")
+	for _, lang := range langs {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", lang, perLang[lang].files, perLang[lang].words)
+	}
+	tw.Flush()
+
+	out := output.For(stdout)
+	err := out.WriteTable(files.String())
+	if err == nil {
+		_, err = fmt.Fprintln(out)
+	}
+	if err == nil {
+		err = out.WriteTable(totals.String())
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "wordcount:", err)
+		return 1
+	}
+	return status
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"example.com/tutorial/archive"
+)
+
+func TestRunWordCountStdin(t *testing.T) {
+	var out, errOut bytes.Buffer
+	code := runWordCount(context.Background(), defaultSettings().WordCount, nil, strings.NewReader("héllo, world!\nbye\n"), &out, &errOut)
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+	if got, want := out.String(), "       2       3      19\n"; got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
+
+func TestRunWordCountFlags(t *testing.T) {
+	var out, errOut bytes.Buffer
+	code := runWordCount(context.Background(), defaultSettings().WordCount, []string{"-m", "-w"}, strings.NewReader("héllo world"), &out, &errOut)
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+	if got, want := out.String(), "       2      11\n"; got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+}
+
+func TestRunWordCountFiles(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	os.WriteFile(a, []byte("one two\n"), 0o644)
+	os.WriteFile(b, []byte("three\nfour five\n"), 0o644)
+	missing := filepath.Join(dir, "missing.txt")
+
+	var out, errOut bytes.Buffer
+	code := runWordCount(context.Background(), defaultSettings().WordCount, []string{"-l", "-w", a, missing, b}, nil, &out, &errOut)
+	if code != 1 {
+		t.Fatalf("exit %d, want 1", code)
+	}
+	want := "       1       2 " + a + "\n" +
+		"       2       3 " + b + "\n" +
+		"       3       5 total\n"
+	if out.String() != want {
+		t.Fatalf("output = %q, want %q", out.String(), want)
+	}
+	if !strings.Contains(errOut.String(), "missing.txt") {
+		t.Fatalf("stderr = %q", errOut.String())
+	}
+}
+
+func TestRunWordCountBadFlag(t *testing.T) {
+	var out, errOut bytes.Buffer
+	if code := runWordCount(context.Background(), defaultSettings().WordCount, []string{"-x"}, nil, &out, &errOut); code != 2 {
+		t.Fatalf("exit %d, want 2", code)
+	}
+}
+
+func TestRunWordCountTopFormats(t *testing.T) {
+	in := "go Go gophers, go!"
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-top", "1"}, "WORD  COUNT\ngo        3\n"},
+		{[]string{"-top", "-1", "-format", "csv"}, "word,count\ngo,3\ngophers,1\n"},
+		{[]string{"-top", "2", "-format", "json"}, `[{"word":"go","count":3},{"word":"gophers","count":1}]` + "\n"},
+		// Spilling after every new word gives the same counts.
+		{[]string{"-top", "-1", "-format", "csv", "-max-memory", "1"}, "word,count\ngo,3\ngophers,1\n"},
+		{[]string{"-top", "1", "-max-memory", "1KiB"}, "WORD  COUNT\ngo        3\n"},
+	}
+	for _, tt := range tests {
+		var out, errOut bytes.Buffer
+		if code := runWordCount(context.Background(), defaultSettings().WordCount, tt.args, strings.NewReader(in), &out, &errOut); code != 0 {
+			t.Fatalf("%v: exit %d: %s", tt.args, code, errOut.String())
+		}
+		if out.String() != tt.want {
+			t.Errorf("%v:\ngot  %q\nwant %q", tt.args, out.String(), tt.want)
+		}
+	}
+	var out, errOut bytes.Buffer
+	if code := runWordCount(context.Background(), defaultSettings().WordCount, []string{"-format", "xml"}, strings.NewReader(in), &out, &errOut); code != 2 {
+		t.Fatalf("bad format: exit %d, want 2", code)
+	}
+}
+
+func TestRunWordCountAnalyzer(t *testing.T) {
+	in := "The runners were running.\nC++ and c++"
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-w"}, "       7\n"},
+		{[]string{"-w", "-analyzer", "english"}, "       4\n"},
+		{[]string{"-top", "-1", "-format", "csv", "-analyzer", "english"}, "word,count\nc,2\nrun,1\nrunner,1\n"},
+		{[]string{"-top", "-1", "-format", "csv", "-analyzer", "whitespace"}, "word,count\nC++,1\nThe,1\nand,1\nc++,1\nrunners,1\nrunning.,1\nwere,1\n"},
+	}
+	for _, tt := range tests {
+		var out, errOut bytes.Buffer
+		if code := runWordCount(context.Background(), defaultSettings().WordCount, tt.args, strings.NewReader(in), &out, &errOut); code != 0 {
+			t.Fatalf("%v: exit %d: %s", tt.args, code, errOut.String())
+		}
+		if out.String() != tt.want {
+			t.Errorf("%v:\ngot  %q\nwant %q", tt.args, out.String(), tt.want)
+		}
+	}
+	var out, errOut bytes.Buffer
+	if code := runWordCount(context.Background(), defaultSettings().WordCount, []string{"-analyzer", "klingon"}, strings.NewReader(in), &out, &errOut); code != 2 ||
+		!strings.Contains(errOut.String(), `unknown analyzer "klingon" (have auto, english, french, german, spanish, whitespace)`) {
+		t.Fatalf("unknown analyzer: exit %d: %s", code, errOut.String())
+	}
+}
+
+func TestRunWordCountLanguages(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"en.txt":   "The runners were running to the house, and the dogs were running after them.\n",
+		"fr.txt":   "Les enfants jouent dans le jardin\navec le chien de la voisine.\n",
+		"fr2.txt":  "Nous sommes restés à la maison pour lire le journal.\n",
+		"tiny.txt": "go\n",
+	})
+	var args []string
+	for _, name := range []string{"en.txt", "fr.txt", "fr2.txt", "tiny.txt", "missing.txt"} {
+		args = append(args, filepath.Join(dir, name))
+	}
+	var out, errOut bytes.Buffer
+	code := runWordCount(context.Background(), defaultSettings().WordCount, append([]string{"-languages"}, args...), nil, &out, &errOut)
+	if code != 1 || !strings.Contains(errOut.String(), "missing.txt") {
+		t.Fatalf("exit %d, want 1: %s", code, errOut.String())
+	}
+	files, totals, _ := strings.Cut(out.String(), "\n\n")
+	var got []string
+	for _, line := range strings.Split(files, "\n")[1:] {
+		f := strings.Fields(line) // the file, language, confidence and words
+		got = append(got, filepath.Base(f[0])+" "+f[1]+" "+f[3])
+	}
+	// The words are counted as the language's analyzer splits them.
+	if want := []string{"en.txt english 5", "fr.txt french 5", "fr2.txt french 5", "tiny.txt unknown 1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("files: %q, want %q", got, want)
+	}
+	if want := "LANGUAGE  FILES  WORDS\nenglish   1      5\nfrench    2      10\nunknown   1      1\n"; totals != want {
+		t.Errorf("totals:\n%s\nwant:\n%s", totals, want)
+	}
+
+	out.Reset()
+	code = runWordCount(context.Background(), defaultSettings().WordCount, []string{"-languages", "-analyzer", "whitespace", args[1]}, nil, &out, &errOut)
+	if code != 0 || !strings.Contains(out.String(), "french    1      12\n") {
+		t.Errorf("-analyzer whitespace: exit %d:\n%s", code, out.String())
+	}
+	if code := runWordCount(context.Background(), defaultSettings().WordCount, []string{"-languages", "-top", "3", args[0]}, nil, &out, &errOut); code != 2 {
+		t.Errorf("-languages -top: exit %d, want 2", code)
+	}
+}
+
+func TestRunWordCountArchive(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir()) // where the workspace goes
+	src := writeTree(t, map[string]string{"a.txt": "go go\n", "docs/b.txt": "gophers\n"})
+	zipped := filepath.Join(t.TempDir(), "corpus.zip")
+	if err := archive.CreateFile(zipped, src, archive.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	var out, errOut bytes.Buffer
+	if code := runWordCount(context.Background(), defaultSettings().WordCount, []string{"-w", zipped}, nil, &out, &errOut); code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+	want := "       2 " + zipped + ":a.txt\n       1 " + zipped + ":docs/b.txt\n       3 total\n"
+	if out.String() != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(os.TempDir(), "wordcount-*")); len(matches) != 0 {
+		t.Errorf("workspaces left behind: %v", matches)
+	}
+
+	out.Reset()
+	if code := runWordCount(context.Background(), defaultSettings().WordCount, []string{"-top", "1", "-max-extract", "3", zipped}, nil, &out, &errOut); code != 1 || !strings.Contains(errOut.String(), "size limit") {
+		t.Fatalf("over the limit: exit %d: %s", code, errOut.String())
+	}
+}
+
+func TestRunWordCountProgressJSON(t *testing.T) {
+	dir := writeTree(t, map[string]string{"a.txt": "one two\n", "b.txt": "three\n"})
+	var out, errOut bytes.Buffer
+	args := []string{"-progress", "json", "-w", filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")}
+	if code := runWordCount(context.Background(), defaultSettings().WordCount, args, nil, &out, &errOut); code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+	lines := strings.Split(strings.TrimSpace(errOut.String()), "\n")
+	var last struct {
+		Job        string
+		Files      int64
+		TotalFiles int64 `json:"total_files"`
+		Bytes      int64
+		TotalBytes int64 `json:"total_bytes"`
+		Done       bool
+	}
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &last); err != nil {
+		t.Fatal(err)
+	}
+	if last.Job != "wordcount" || last.Files != 2 || last.TotalFiles != 2 || last.Bytes != 14 || last.TotalBytes != 14 || !last.Done {
+		t.Fatalf("last report: %s", lines[len(lines)-1])
+	}
+	if !strings.HasSuffix(out.String(), "total\n") {
+		t.Fatalf("output = %q", out.String())
+	}
+}
@@ -0,0 +1,286 @@
+// Package workspace gives a job a private temporary directory that is
+// removed when the job ends, however it ends, and that limits how much
+// the job may write to it.
+package workspace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// DefaultPattern names workspace directories when Config.Pattern is
+// empty; see os.MkdirTemp.
+const DefaultPattern = "workspace-*"
+
+// Errors returned by a Workspace.
+var (
+	ErrQuota   = errors.New("workspace: quota exceeded")
+	ErrClosed  = errors.New("workspace: closed")
+	ErrOutside = errors.New("workspace: path outside the workspace")
+)
+
+// Config sets up a Workspace.
+type Config struct {
+	// Dir is the directory the workspace is created in; empty means
+	// os.TempDir().
+	Dir string
+	// Pattern names the workspace directory as for os.MkdirTemp; empty
+	// means DefaultPattern.
+	Pattern string
+	// Quota bounds the bytes of the files in the workspace; zero means
+	// no limit.
+	Quota int64
+}
+
+// Workspace is a temporary directory tree. Its methods take names
+// relative to the root, slash- or OS-separated, and are safe for
+// concurrent use.
+type Workspace struct {
+	root  string
+	quota int64
+	stop  func() bool // unregisters the context callback
+
+	mu     sync.Mutex
+	sizes  map[string]int64 // tracked files by slash-separated name
+	used   int64
+	closed bool
+}
+
+// New creates a workspace that is removed when ctx is done or Close is
+// called, whichever comes first.
+func New(ctx context.Context, cfg Config) (*Workspace, error) {
+	if cfg.Pattern == "" {
+		cfg.Pattern = DefaultPattern
+	}
+	root, err := os.MkdirTemp(cfg.Dir, cfg.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	ws := &Workspace{root: root, quota: cfg.Quota, sizes: map[string]int64{}}
+	ws.stop = context.AfterFunc(ctx, func() { ws.Close() })
+	return ws, nil
+}
+
+// Run calls fn with a new workspace and removes it when fn returns, or
+// panics, in which case the panic continues once the workspace is gone.
+func Run(ctx context.Context, cfg Config, fn func(ws *Workspace) error) error {
+	ws, err := New(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+	return fn(ws)
+}
+
+// Root returns the workspace directory.
+func (ws *Workspace) Root() string { return ws.root }
+
+// Path returns the path of name in the workspace, or an error wrapping
+// ErrOutside if name would lead out of it.
+func (ws *Workspace) Path(name string) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		return "", fmt.Errorf("%w: %q", ErrOutside, name)
+	}
+	return filepath.Join(ws.root, filepath.FromSlash(name)), nil
+}
+
+// key is the name files are tracked under.
+func key(name string) string {
+	return filepath.ToSlash(filepath.Clean(filepath.FromSlash(name)))
+}
+
+// Mkdir creates the directory name, and any parents it lacks.
+func (ws *Workspace) Mkdir(name string) error {
+	path, err := ws.Path(name)
+	if err != nil {
+		return err
+	}
+	if err := ws.check(); err != nil {
+		return err
+	}
+	return os.MkdirAll(path, 0o755)
+}
+
+// Create creates or truncates the file name, and any parent directories
+// it lacks, for writing; the bytes it held no longer count against the
+// quota.
+func (ws *Workspace) Create(name string) (*File, error) {
+	path, err := ws.Path(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := ws.check(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	k := key(name)
+	ws.mu.Lock()
+	ws.used -= ws.sizes[k]
+	ws.sizes[k] = 0
+	ws.mu.Unlock()
+	return &File{f: f, ws: ws, key: k}, nil
+}
+
+// WriteFile writes data to the file name, replacing it, as Create and
+// Write do.
+func (ws *Workspace) WriteFile(name string, data []byte) error {
+	f, err := ws.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Track starts tracking the regular files at or below name, written to
+// the workspace by other means than Create, such as an archive
+// extraction. It fails with ErrQuota, after tracking them, if they take
+// the workspace over its quota.
+func (ws *Workspace) Track(name string) error {
+	path, err := ws.Path(name)
+	if err != nil {
+		return err
+	}
+	found := map[string]int64{}
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(ws.root, p)
+		if err != nil {
+			return err
+		}
+		found[filepath.ToSlash(rel)] = info.Size()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.closed {
+		return ErrClosed
+	}
+	for k, size := range found {
+		ws.used += size - ws.sizes[k]
+		ws.sizes[k] = size
+	}
+	if ws.quota > 0 && ws.used > ws.quota {
+		return fmt.Errorf("%w: %d of %d bytes used", ErrQuota, ws.used, ws.quota)
+	}
+	return nil
+}
+
+// Files returns the names of the tracked files, slash-separated and in
+// order.
+func (ws *Workspace) Files() []string {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	names := make([]string, 0, len(ws.sizes))
+	for k := range ws.sizes {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Used returns the bytes of the tracked files.
+func (ws *Workspace) Used() int64 {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.used
+}
+
+// Remaining returns the bytes that may still be written before the
+// quota is reached, or math.MaxInt64 if there is no quota.
+func (ws *Workspace) Remaining() int64 {
+	if ws.quota <= 0 {
+		return math.MaxInt64
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return max(ws.quota-ws.used, 0)
+}
+
+func (ws *Workspace) check() error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.closed {
+		return ErrClosed
+	}
+	return nil
+}
+
+// Close removes the workspace and everything in it. Later calls do
+// nothing, and the workspace can no longer be written.
+func (ws *Workspace) Close() error {
+	ws.mu.Lock()
+	if ws.closed {
+		ws.mu.Unlock()
+		return nil
+	}
+	ws.closed = true
+	ws.mu.Unlock()
+	ws.stop()
+	return os.RemoveAll(ws.root)
+}
+
+// File is a file being written in a workspace.
+type File struct {
+	f   *os.File
+	ws  *Workspace
+	key string
+}
+
+// Name returns the file's path.
+func (f *File) Name() string { return f.f.Name() }
+
+// Write writes p to the file, unless that would take the workspace over
+// its quota, in which case it writes nothing and returns an error
+// wrapping ErrQuota.
+func (f *File) Write(p []byte) (int, error) {
+	f.ws.mu.Lock()
+	if f.ws.closed {
+		f.ws.mu.Unlock()
+		return 0, ErrClosed
+	}
+	if f.ws.quota > 0 && f.ws.used+int64(len(p)) > f.ws.quota {
+		defer f.ws.mu.Unlock()
+		return 0, fmt.Errorf("%w: writing %d bytes with %d of %d used", ErrQuota, len(p), f.ws.used, f.ws.quota)
+	}
+	f.ws.used += int64(len(p)) // reserved; returned below if not written
+	f.ws.sizes[f.key] += int64(len(p))
+	f.ws.mu.Unlock()
+
+	n, err := f.f.Write(p)
+	if n < len(p) {
+		f.ws.mu.Lock()
+		f.ws.used -= int64(len(p) - n)
+		f.ws.sizes[f.key] -= int64(len(p) - n)
+		f.ws.mu.Unlock()
+	}
+	return n, err
+}
+
+// Close closes the file.
+func (f *File) Close() error { return f.f.Close() }
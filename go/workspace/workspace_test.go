@@ -0,0 +1,140 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func gone(t *testing.T, path string) bool {
+	t.Helper()
+	_, err := os.Stat(path)
+	return errors.Is(err, os.ErrNotExist)
+}
+
+func TestWorkspace(t *testing.T) {
+	ws, err := New(context.Background(), Config{Dir: t.TempDir(), Quota: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ws.WriteFile("a/b.txt", []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(ws.Root(), "a", "b.txt")); string(data) != "hello" {
+		t.Fatalf("contents %q", data)
+	}
+	f, err := ws.Create("c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("1234")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("56")); !errors.Is(err, ErrQuota) {
+		t.Fatalf("write past the quota: %v", err)
+	}
+	f.Close()
+	if ws.Used() != 9 || ws.Remaining() != 1 {
+		t.Fatalf("used %d, remaining %d", ws.Used(), ws.Remaining())
+	}
+
+	// Rewriting a file gives its old bytes back.
+	if err := ws.WriteFile("a/b.txt", []byte("hi")); err != nil {
+		t.Fatal(err)
+	}
+	if ws.Used() != 6 {
+		t.Fatalf("used %d after rewrite", ws.Used())
+	}
+	if got := ws.Files(); !reflect.DeepEqual(got, []string{"a/b.txt", "c"}) {
+		t.Fatalf("Files = %v", got)
+	}
+
+	for _, name := range []string{"../x", "/etc/x", "a/../../x"} {
+		if err := ws.WriteFile(name, nil); !errors.Is(err, ErrOutside) {
+			t.Errorf("WriteFile(%q) = %v", name, err)
+		}
+	}
+
+	if err := ws.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !gone(t, ws.Root()) {
+		t.Fatal("workspace left behind")
+	}
+	if err := ws.WriteFile("d", nil); !errors.Is(err, ErrClosed) {
+		t.Fatalf("write after Close: %v", err)
+	}
+	if err := ws.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+}
+
+func TestTrack(t *testing.T) {
+	ws, err := New(context.Background(), Config{Dir: t.TempDir(), Quota: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	dir, _ := ws.Path("out")
+	os.MkdirAll(filepath.Join(dir, "sub"), 0o755)
+	os.WriteFile(filepath.Join(dir, "x"), []byte("12345"), 0o644)
+	os.WriteFile(filepath.Join(dir, "sub", "y"), []byte("678"), 0o644)
+	if err := ws.Track("out"); err != nil {
+		t.Fatal(err)
+	}
+	if ws.Used() != 8 || !reflect.DeepEqual(ws.Files(), []string{"out/sub/y", "out/x"}) {
+		t.Fatalf("used %d, files %v", ws.Used(), ws.Files())
+	}
+	os.WriteFile(filepath.Join(dir, "z"), []byte("9"), 0o644)
+	if err := ws.Track("out/z"); !errors.Is(err, ErrQuota) {
+		t.Fatalf("Track past the quota: %v", err)
+	}
+}
+
+func TestCleanupOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ws, err := New(ctx, Config{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws.WriteFile("f", []byte("data"))
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for !gone(t, ws.Root()) {
+		if time.Now().After(deadline) {
+			t.Fatal("workspace not removed after cancel")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRunCleansUpOnPanic(t *testing.T) {
+	var root string
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Fatalf("recovered %v", r)
+			}
+		}()
+		Run(context.Background(), Config{Dir: t.TempDir()}, func(ws *Workspace) error {
+			root = ws.Root()
+			ws.WriteFile("f", []byte("data"))
+			panic("boom")
+		})
+	}()
+	if root == "" || !gone(t, root) {
+		t.Fatalf("workspace %q left behind after panic", root)
+	}
+
+	want := errors.New("failed")
+	if err := Run(context.Background(), Config{Dir: t.TempDir()}, func(ws *Workspace) error {
+		root = ws.Root()
+		return want
+	}); err != want || !gone(t, root) {
+		t.Fatalf("Run = %v, left %q", err, root)
+	}
+}